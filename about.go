@@ -0,0 +1,97 @@
+package picodoomsdaymessenger
+
+import (
+	"fmt"
+	"runtime/debug"
+	"strings"
+	"time"
+)
+
+// libraryPath is the module path of this library, which its version is looked up by.
+const libraryPath = "github.com/headblockhead/picoDoomsdayMessenger"
+
+// PacketCounters counts the packets that the Device has sent and received since it started.
+type PacketCounters struct {
+	// Received is every packet heard, including the Rejected ones.
+	Received int
+	// Rejected is every packet that was too long or could not be decoded.
+	Rejected int
+	// Sent is every packet that was given to the radio without an error.
+	Sent int
+	// SendFailed is every packet that the radio could not send.
+	SendFailed int
+}
+
+// LibraryVersion returns the version of this library that the firmware was built with, or "unknown" if it was not built from a tagged module.
+func LibraryVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+	if info.Main.Path == libraryPath && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+	for _, dep := range info.Deps {
+		if dep.Path == libraryPath {
+			return dep.Version
+		}
+	}
+	return "unknown"
+}
+
+// Uptime returns how long the Device has been running.
+func (d *Device) Uptime() time.Duration {
+	return time.Since(d.started)
+}
+
+// AboutText returns the diagnostics shown on the About screen, one per line.
+func (d *Device) AboutText() string {
+	version := d.Version
+	if version == "" {
+		version = "unknown"
+	}
+	lines := []string{
+		"Firmware " + version,
+		"Library " + LibraryVersion(),
+		fmt.Sprintf("ID %d", d.SelfIdentity.ID),
+		"Uptime " + d.Uptime().Truncate(time.Second).String(),
+	}
+	if d.SafeMode {
+		lines = append(lines, "Safe mode")
+	}
+	if d.GetFreeHeap != nil {
+		if free, ok := d.GetFreeHeap(); ok {
+			lines = append(lines, fmt.Sprintf("Free heap %dB", free))
+		}
+	}
+	lines = append(lines,
+		fmt.Sprintf("RX %d (%d bad)", d.Packets.Received, d.Packets.Rejected),
+		fmt.Sprintf("TX %d (%d failed)", d.Packets.Sent, d.Packets.SendFailed),
+	)
+	if queued := d.QueuedPackets(); queued > 0 {
+		lines = append(lines, fmt.Sprintf("TX queue %d", queued))
+	}
+	lines = append(lines,
+		fmt.Sprintf("%.3f MHz", d.Settings.FrequencyMHz),
+		fmt.Sprintf("SF%d %dkHz 4/%d", d.Settings.Modem.SpreadingFactor, d.Settings.Modem.BandwidthHz/1000, d.Settings.Modem.CodingRate),
+	)
+	if n := len(d.MonitoredPackets); n > 0 && d.MonitoredPackets[n-1].RSSI != RSSIUnknown {
+		lines = append(lines, fmt.Sprintf("Last RSSI %ddBm", d.MonitoredPackets[n-1].RSSI))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// processAboutInputEvent scrolls the About screen with up and down. Accept goes back.
+func processAboutInputEvent(d *Device, inputEvent InputEvent) (handled bool, err error) {
+	if inputEvent == InputEventAccept {
+		d.aboutScroll = 0
+		return true, d.GoBackState()
+	}
+	return scrollTextPage(&d.aboutScroll, inputEvent, d.AboutText(), d.Layout()), nil
+}
+
+// drawAbout draws the diagnostics of the Device. They are read again every frame, so the uptime and counters stay up to date.
+func drawAbout(d *Device, img Canvas, layout Layout) (err error) {
+	drawTextPage(img, layout, d.Palette(), "About", d.AboutText(), d.aboutScroll)
+	return nil
+}
@@ -0,0 +1,60 @@
+package picodoomsdaymessenger
+
+import (
+	"image"
+	"strings"
+	"testing"
+)
+
+func TestAbout(t *testing.T) {
+	device, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	device.Version = "v1.2.3"
+	device.GetFreeHeap = func() (bytes int, ok bool) {
+		return 4096, true
+	}
+	device.SendUsingRadio = func(packet []byte) (err error) {
+		return nil
+	}
+	err = device.SendPacket(nil, []byte("doom1\xccA\xcchi"))
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	err = sendQueued(device)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	device.ReceiveFromRadioWithRSSI([]byte("doom1\xccA\xcchi"), -70)
+	device.ReceiveFromRadio([]byte("junk"))
+
+	text := device.AboutText()
+	for _, want := range []string{"Firmware v1.2.3", "Free heap 4096B", "RX 2 (1 bad)", "TX 1 (0 failed)", "SF", "Uptime "} {
+		if !strings.Contains(text, want) {
+			t.Errorf("expected the about screen to show %q, got %q", want, text)
+		}
+	}
+
+	err = SettingsMenuItemAbout.Action(device)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if _, err := GetFrame(image.Rect(0, 0, 128, 64), device); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	err = device.ProcessInputEvent(InputEventDown)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if device.aboutScroll != 1 {
+		t.Errorf("expected down to scroll the about screen")
+	}
+	err = device.ProcessInputEvent(InputEventAccept)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if device.State == &device.StateAbout {
+		t.Errorf("expected accept to go back")
+	}
+}
@@ -0,0 +1,51 @@
+package picodoomsdaymessenger
+
+import (
+	"bytes"
+	"strconv"
+)
+
+// BroadcastID is the destination of a Message that is for every device that hears it.
+const BroadcastID = 0
+
+// destinationMarker is put either side of the destination ID of a Message that is addressed to one device, after the emergency marker. Broadcast messages are sent without it, so they can still be read by older devices.
+const destinationMarker = '>'
+
+// appendDestination adds the destination of a Message to a packet. Messages to the broadcast Conversation are sent with the BroadcastID, and other broadcasts are sent without a destination.
+func appendDestination(packet []byte, m Message) []byte {
+	if m.To == BroadcastID && !m.Broadcast {
+		return packet
+	}
+	packet = append(packet, destinationMarker)
+	packet = strconv.AppendInt(packet, int64(m.To), 10)
+	return append(packet, destinationMarker)
+}
+
+// splitDestination reads the destination from the start of the rest of a packet, and returns what is after it. Packets without a destination are broadcasts, and broadcast is only true for packets sent to the BroadcastID on purpose.
+func splitDestination(input []byte) (to int, broadcast bool, rest []byte, err error) {
+	if len(input) == 0 || input[0] != destinationMarker {
+		return BroadcastID, false, input, nil
+	}
+	end := bytes.IndexByte(input[1:], destinationMarker)
+	if end < 0 {
+		return BroadcastID, false, input, ErrMalformedMessage
+	}
+	to, err = strconv.Atoi(string(input[1 : 1+end]))
+	if err != nil {
+		return BroadcastID, false, input, ErrMalformedMessage
+	}
+	return to, to == BroadcastID, input[end+2:], nil
+}
+
+// Destination returns the ID that messages sent to the Conversation are addressed to. A Conversation with one other Person is addressed to them, and any other is a broadcast.
+func (c *Conversation) Destination() int {
+	if c == nil || len(c.People) != 2 {
+		return BroadcastID
+	}
+	return c.People[1].ID
+}
+
+// AddressedToDevice returns true if a Message is a broadcast, or is addressed to the Device.
+func (d *Device) AddressedToDevice(m Message) bool {
+	return m.To == BroadcastID || m.To == d.SelfIdentity.ID
+}
@@ -0,0 +1,67 @@
+package picodoomsdaymessenger
+
+import "testing"
+
+func TestDestinationRoundTrip(t *testing.T) {
+	d, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	packet, err := d.MesageToBytes(Message{Text: "hello", Person: Person{Name: "Bob", ID: 7}, To: 42, Priority: PriorityEmergency})
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	decoded, err := d.BytesToMessage(packet)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if decoded.To != 42 || decoded.Priority != PriorityEmergency || decoded.Text != "hello" || decoded.Person.ID != 7 {
+		t.Errorf("expected the destination to survive encoding, got %+v", decoded)
+	}
+	if _, err = d.BytesToMessage([]byte("doom>42")); err != ErrMalformedMessage {
+		t.Errorf("expected ErrMalformedMessage for an unterminated destination, got %v", err)
+	}
+}
+
+func TestReceiveAddressedMessages(t *testing.T) {
+	d, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	d.State = &d.StateMainMenu
+	other := d.SelfIdentity.ID + 1
+	receive := func(m Message) error {
+		packet, err := d.MesageToBytes(m)
+		if err != nil {
+			return err
+		}
+		return d.ReceiveFromRadio(packet)
+	}
+	err = receive(Message{Text: "not for you", Person: Person{Name: "Bob", ID: 7}, To: other})
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if len(d.Conversations) != 0 {
+		t.Fatalf("expected a message for another device to be dropped")
+	}
+
+	d.Settings.Overhear = true
+	err = receive(Message{Text: "not for you", Person: Person{Name: "Bob", ID: 7}, To: other})
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if len(d.Conversations) != 1 || !d.Conversations[0].Messages[0].Overheard {
+		t.Fatalf("expected an overheard message to be shown and marked")
+	}
+
+	d.Settings.Overhear = false
+	for _, to := range []int{BroadcastID, d.SelfIdentity.ID} {
+		err = receive(Message{Text: "for you", Person: Person{Name: "Bob", ID: 7}, To: to})
+		if err != nil {
+			t.Fatalf("The error should be nil but is %v", err)
+		}
+	}
+	if n := len(d.Conversations[0].Messages); n != 3 {
+		t.Errorf("expected broadcasts and messages to the device to be received, got %d messages", n)
+	}
+}
@@ -0,0 +1,45 @@
+package picodoomsdaymessenger
+
+import "time"
+
+// AutoReplyInterval is the shortest time between two automatic replies to the same Person.
+var AutoReplyInterval = time.Hour
+
+// DefaultAutoReplyText is the automatic reply of a new Device.
+const DefaultAutoReplyText = "Away from my radio, will reply later"
+
+// autoReply answers a Message received in a Conversation with the AutoReplyText, if away mode is on and the sender has not been answered in the last AutoReplyInterval. Broadcasts are never answered, as the reply would go to everyone, and nothing is answered while the Device is ListeningOnly.
+func (d *Device) autoReply(c *Conversation, from Person, now time.Time) (err error) {
+	if !d.Settings.AutoReply || d.Settings.AutoReplyText == "" || d.ListeningOnly() || c.Broadcast || from.ID == d.SelfIdentity.ID {
+		return nil
+	}
+	if last, ok := d.autoReplied[from.ID]; ok && now.Sub(last) < AutoReplyInterval {
+		return nil
+	}
+	if d.autoReplied == nil {
+		d.autoReplied = make(map[int]time.Time)
+	}
+	d.autoReplied[from.ID] = now
+	return d.SendMessage(c, d.Settings.AutoReplyText)
+}
+
+// processAutoReplyEditorInputEvent types into the draft with the number keys, deletes with left and saves the automatic reply with accept.
+func processAutoReplyEditorInputEvent(d *Device, inputEvent InputEvent) (handled bool, err error) {
+	if d.processTextEntryInputEvent(&d.autoReplyDraft, inputEvent) {
+		return true, nil
+	}
+	if inputEvent != InputEventAccept {
+		return false, nil
+	}
+	// An empty reply leaves the reply as it was.
+	if text := d.finishTextEntry(&d.autoReplyDraft); text != "" {
+		d.Settings.AutoReplyText = text
+	}
+	return true, d.GoBackState()
+}
+
+// drawAutoReplyEditor draws the automatic reply that is being typed.
+func drawAutoReplyEditor(d *Device, img Canvas, layout Layout) (err error) {
+	drawTextPage(img, layout, d.Palette(), "Away Reply", d.autoReplyDraft+d.pendingCharacter()+"_", 0)
+	return nil
+}
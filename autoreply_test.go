@@ -0,0 +1,93 @@
+package picodoomsdaymessenger
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAutoReply(t *testing.T) {
+	device, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	sent := []Message{}
+	device.SendUsingRadio = func(packet []byte) (err error) {
+		message, err := device.BytesToMessage(packet)
+		sent = append(sent, message)
+		return err
+	}
+	err = device.ReceiveFromRadio([]byte("doom7\xccBob\xcchello"))
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	err = sendQueued(device)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if len(sent) != 0 {
+		t.Fatalf("expected no reply while away mode is off, got %+v", sent)
+	}
+
+	err = AwayMenuItemAutoReply.Action(device)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	for _, packet := range []string{"doom7\xccBob\xcchello", "doom7\xccBob\xccare you there?", "doom8\xccAlice\xcchi"} {
+		err = device.ReceiveFromRadio([]byte(packet))
+		if err != nil {
+			t.Fatalf("The error should be nil but is %v", err)
+		}
+	}
+	err = sendQueued(device)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if len(sent) != 2 || sent[0].Text != DefaultAutoReplyText {
+		t.Fatalf("expected one reply to each sender, got %+v", sent)
+	}
+
+	// Bob is answered again once the AutoReplyInterval has passed.
+	err = device.autoReply(device.Conversations[0], Person{Name: "Bob", ID: 7}, time.Now().Add(AutoReplyInterval))
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	err = sendQueued(device)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if len(sent) != 3 {
+		t.Errorf("expected another reply after the interval, got %d", len(sent))
+	}
+}
+
+func TestEditAutoReply(t *testing.T) {
+	device, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	err = AwayMenuItemEditReply.Action(device)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if device.State != &device.StateAutoReplyEditor {
+		t.Fatalf("expected the reply editor, got %q", device.State.Title)
+	}
+	// Delete the last letter of the default reply, and type "x" in its place.
+	for _, inputEvent := range []InputEvent{InputEventLeft, InputEventNumber9, InputEventNumber9} {
+		err = device.ProcessInputEvent(inputEvent)
+		if err != nil {
+			t.Fatalf("The error should be nil but is %v", err)
+		}
+	}
+	if _, err := GetFrame(DisplaySSD1306.Bounds(), device); err != nil {
+		t.Fatalf("The error drawing the editor should be nil but is %v", err)
+	}
+	err = device.ProcessInputEvent(InputEventAccept)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	want := DefaultAutoReplyText[:len(DefaultAutoReplyText)-1] + "x"
+	if device.Settings.AutoReplyText != want {
+		t.Errorf("expected the reply to be %q, got %q", want, device.Settings.AutoReplyText)
+	}
+}
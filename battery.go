@@ -0,0 +1,103 @@
+package picodoomsdaymessenger
+
+import (
+	"image/color"
+	"time"
+)
+
+// LowBatteryPercent is the battery level at or below which the Device starts to save power, and DeviceEventLowBattery first happens.
+var LowBatteryPercent = 15
+
+// BatteryLevel is the charge of the battery, as last reported by the firmware.
+type BatteryLevel struct {
+	Percent   int
+	Valid     bool
+	UpdatedAt time.Time
+}
+
+// BatteryPolicy is how the Device saves power once the battery is at or below a Percent. The Settings are left as they are, so everything goes back to normal once the battery is charged.
+type BatteryPolicy struct {
+	Percent int
+	// LEDBrightness is how brightly the LEDs are lit, out of 255.
+	LEDBrightness uint8
+	// ScreensaverTimeout is the longest that the Device waits without any input before it starts the screensaver, which lights far fewer pixels than a menu.
+	ScreensaverTimeout time.Duration
+	// MaxTxPower is the most power in dBm that the radio sends with.
+	MaxTxPower int
+}
+
+// BatteryPolicies are followed as the battery runs down, from the first to the last. The user is warned with DeviceEventLowBattery each time another one starts to be followed.
+var BatteryPolicies = []BatteryPolicy{
+	{Percent: LowBatteryPercent, LEDBrightness: 96, ScreensaverTimeout: time.Minute, MaxTxPower: 14},
+	{Percent: 5, LEDBrightness: 32, ScreensaverTimeout: 30 * time.Second, MaxTxPower: 10},
+}
+
+// fullPowerPolicy is followed while the battery is above every BatteryPolicy, or its level is not known.
+var fullPowerPolicy = BatteryPolicy{Percent: 100, LEDBrightness: 255}
+
+// BatteryPolicy returns the strictest of the BatteryPolicies that the battery level is at or below.
+func (d *Device) BatteryPolicy() (policy BatteryPolicy) {
+	policy = fullPowerPolicy
+	if !d.Battery.Valid {
+		return policy
+	}
+	for _, p := range BatteryPolicies {
+		if d.Battery.Percent <= p.Percent && p.Percent <= policy.Percent {
+			policy = p
+		}
+	}
+	return policy
+}
+
+// SetBatteryPercent records the charge of the battery. When it drops to the Percent of one of the BatteryPolicies, DeviceEventLowBattery happens and the transmit power is turned down.
+func (d *Device) SetBatteryPercent(percent int) (err error) {
+	previous := d.BatteryPolicy()
+	d.Battery = BatteryLevel{Percent: percent, Valid: true, UpdatedAt: d.Now()}
+	d.MarkDirty()
+	policy := d.BatteryPolicy()
+	if policy == previous {
+		return nil
+	}
+	if d.Radio != nil {
+		err = d.Radio.SetTxPower(d.radioTxPower())
+		if err != nil {
+			return err
+		}
+	}
+	if policy.Percent < previous.Percent {
+		return d.Notify(DeviceEventLowBattery)
+	}
+	return nil
+}
+
+// LEDColors returns the colors that a frame of an LEDAnimation is shown with, dimmed by the BatteryPolicy.
+func (d *Device) LEDColors(frame [6]color.RGBA) (colors [6]color.RGBA) {
+	brightness := uint32(d.BatteryPolicy().LEDBrightness)
+	for i, c := range frame {
+		colors[i] = color.RGBA{
+			R: uint8(uint32(c.R) * brightness / 255),
+			G: uint8(uint32(c.G) * brightness / 255),
+			B: uint8(uint32(c.B) * brightness / 255),
+			A: c.A,
+		}
+	}
+	return colors
+}
+
+// ScreensaverTimeout returns how long the Device waits without any input before it starts the screensaver. It is the ScreensaverTimeout in the Settings, made shorter by the BatteryPolicy.
+func (d *Device) ScreensaverTimeout() time.Duration {
+	timeout := d.Settings.ScreensaverTimeout
+	if limit := d.BatteryPolicy().ScreensaverTimeout; limit > 0 && (timeout <= 0 || timeout > limit) {
+		return limit
+	}
+	return timeout
+}
+
+// radioTxPower returns the power in dBm that the radio is set to, which is the TxPower turned down by the BatteryPolicy.
+func (d *Device) radioTxPower() int {
+	power := d.TxPower()
+	if limit := d.BatteryPolicy().MaxTxPower; limit > 0 && power > limit {
+		return limit
+	}
+	return power
+}
@@ -0,0 +1,94 @@
+package picodoomsdaymessenger
+
+import (
+	"image/color"
+	"testing"
+)
+
+func TestSetBatteryPercentNotifiesOnce(t *testing.T) {
+	device, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	normal := device.LEDAnimation
+	err = device.SetBatteryPercent(50)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if device.LEDAnimation != normal {
+		t.Error("expected no notification above LowBatteryPercent")
+	}
+	err = device.SetBatteryPercent(LowBatteryPercent)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if device.LEDAnimation != LEDNotifications[DeviceEventLowBattery] {
+		t.Error("expected the low battery notification")
+	}
+	device.LEDAnimation = normal
+	err = device.SetBatteryPercent(LowBatteryPercent - 1)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if device.LEDAnimation != normal {
+		t.Error("expected the low battery notification to only play once")
+	}
+}
+
+func TestBatteryPolicy(t *testing.T) {
+	device, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	radio := &testRadio{}
+	err = device.SetRadio(radio)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	events := []DeviceEvent{}
+	device.OnDeviceEvent = func(event DeviceEvent) {
+		events = append(events, event)
+	}
+	full := radio.txPower
+	white := [6]color.RGBA{{255, 255, 255, 255}}
+
+	err = device.SetBatteryPercent(50)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if device.LEDColors(white) != white || device.ScreensaverTimeout() != device.Settings.ScreensaverTimeout {
+		t.Errorf("expected nothing to change with a full battery")
+	}
+
+	for _, percent := range []int{LowBatteryPercent, 5} {
+		err = device.SetBatteryPercent(percent)
+		if err != nil {
+			t.Fatalf("The error should be nil but is %v", err)
+		}
+		policy := device.BatteryPolicy()
+		if policy.Percent != percent {
+			t.Fatalf("expected the policy for %d%%, got %+v", percent, policy)
+		}
+		if radio.txPower != policy.MaxTxPower || device.Settings.TxPower != full {
+			t.Errorf("expected the radio to be turned down to %d dBm without changing the Settings, got %d dBm", policy.MaxTxPower, radio.txPower)
+		}
+		if device.LEDColors(white)[0].R != policy.LEDBrightness {
+			t.Errorf("expected the LEDs to be dimmed to %d, got %v", policy.LEDBrightness, device.LEDColors(white)[0])
+		}
+		if device.ScreensaverTimeout() != policy.ScreensaverTimeout {
+			t.Errorf("expected the screensaver to start after %v, got %v", policy.ScreensaverTimeout, device.ScreensaverTimeout())
+		}
+	}
+	if len(events) != 2 {
+		t.Errorf("expected a warning for each policy, got %v", events)
+	}
+
+	// Once charged, everything goes back to normal without another warning.
+	err = device.SetBatteryPercent(80)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if radio.txPower != full || len(events) != 2 {
+		t.Errorf("expected full power again, got %d dBm", radio.txPower)
+	}
+}
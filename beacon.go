@@ -0,0 +1,146 @@
+package picodoomsdaymessenger
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// ErrInvalidBeacon is returned when a beacon packet cannot be decoded.
+var ErrInvalidBeacon = errors.New("invalid beacon")
+
+// beaconPrefix is the start of every beacon packet, ASCII for "bcon".
+var beaconPrefix = []byte{0x62, 0x63, 0x6F, 0x6E}
+
+// BeaconStatus is the status text sent in the beacons of the Device.
+var BeaconStatus = "OK"
+
+// BeaconIntervals is the list of times between beacons that can be selected.
+var BeaconIntervals = []time.Duration{time.Minute, 5 * time.Minute, 10 * time.Minute, 30 * time.Minute, time.Hour}
+
+// HeardStation is a station that a beacon has been heard from.
+type HeardStation struct {
+	Person    Person
+	Position  Position
+	Status    string
+	LastHeard time.Time
+}
+
+// BeaconToBytes creates a beacon packet with the identity, status and position (if known) of the Device.
+func (d *Device) BeaconToBytes() (output []byte) {
+	seperatorByte := byte(0xcc)
+	output = append(output, beaconPrefix...)
+	output = append(output, []byte(fmt.Sprint(d.SelfIdentity.ID))...)
+	output = append(output, seperatorByte)
+	output = append(output, []byte(d.SelfIdentity.Name)...)
+	output = append(output, seperatorByte)
+	if position, ok := d.CurrentLocation(); ok {
+		output = strconv.AppendFloat(output, position.Latitude, 'f', 5, 64)
+		output = append(output, seperatorByte)
+		output = strconv.AppendFloat(output, position.Longitude, 'f', 5, 64)
+	} else {
+		output = append(output, seperatorByte)
+	}
+	output = append(output, seperatorByte)
+	output = append(output, []byte(BeaconStatus)...)
+	return output
+}
+
+// BytesToHeardStation decodes a beacon packet.
+func BytesToHeardStation(input []byte) (output HeardStation, err error) {
+	if !bytes.HasPrefix(input, beaconPrefix) {
+		return output, ErrInvalidBeacon
+	}
+	fields := bytes.SplitN(input[len(beaconPrefix):], []byte{0xcc}, 5)
+	if len(fields) != 5 {
+		return output, ErrInvalidBeacon
+	}
+	output.Person.ID, err = strconv.Atoi(string(fields[0]))
+	if err != nil {
+		return output, ErrInvalidBeacon
+	}
+	output.Person.Name, _ = truncateString(string(fields[1]), MaxNameLength)
+	if len(fields[2]) > 0 {
+		output.Position.Latitude, err = strconv.ParseFloat(string(fields[2]), 64)
+		if err != nil {
+			return output, ErrInvalidBeacon
+		}
+		output.Position.Longitude, err = strconv.ParseFloat(string(fields[3]), 64)
+		if err != nil {
+			return output, ErrInvalidBeacon
+		}
+		output.Position.Source = "beacon"
+	}
+	output.Status, _ = truncateString(string(fields[4]), MaxNameLength)
+	return output, nil
+}
+
+// receiveBeacon adds a station to the HeardStations, replacing any older beacon from the same station.
+func (d *Device) receiveBeacon(station HeardStation, now time.Time) (err error) {
+	station.LastHeard = now
+	if station.Position.Valid() {
+		station.Position.UpdatedAt = now
+	}
+	err = d.heardGatewayNode(station.Person, now)
+	if err != nil {
+		return err
+	}
+	for i := range d.HeardStations {
+		if d.HeardStations[i].Person.ID == station.Person.ID {
+			d.HeardStations[i] = station
+			return nil
+		}
+	}
+	d.HeardStations = append(d.HeardStations, station)
+	return nil
+}
+
+// sendBeaconIfDue broadcasts a beacon if beacons are turned on, the Device is not ListeningOnly, and the BeaconInterval has passed since the last one.
+func (d *Device) sendBeaconIfDue(now time.Time) (err error) {
+	if !d.Settings.Beacon || d.ListeningOnly() || now.Sub(d.lastBeacon) < d.Settings.BeaconInterval {
+		return nil
+	}
+	d.lastBeacon = now
+	return d.sendPacketWithPriority(nil, d.BeaconToBytes(), txPriorityBeacon, nil)
+}
+
+// UpdateHeardStationsMenu fills the StateHeardStationsMenu with the HeardStations, most recently heard first.
+func (d *Device) UpdateHeardStationsMenu() {
+	sort.SliceStable(d.HeardStations, func(i, j int) bool {
+		return d.HeardStations[i].LastHeard.After(d.HeardStations[j].LastHeard)
+	})
+	d.StateHeardStationsMenu.Content = []MenuItem{GlobalMenuItemGoBack}
+	for _, station := range d.HeardStations {
+		name := station.Person.Name
+		if name == "" {
+			name = fmt.Sprint(station.Person.ID)
+		}
+		d.StateHeardStationsMenu.Content = append(d.StateHeardStationsMenu.Content, MenuItem{
+			Text: fmt.Sprintf("%s %s", name, station.Status),
+			Action: func(d *Device) (err error) {
+				return nil
+			},
+			CursorIcon: CursorIconNone,
+		})
+	}
+	if d.StateHeardStationsMenu.HighlightedItemIndex >= len(d.StateHeardStationsMenu.Content) {
+		d.StateHeardStationsMenu.HighlightedItemIndex = 0
+	}
+}
+
+// beaconIntervalMenuItems creates a MenuItem for every interval in BeaconIntervals.
+func beaconIntervalMenuItems() (items []MenuItem) {
+	names := make([]string, len(BeaconIntervals))
+	for i, interval := range BeaconIntervals {
+		names[i] = fmt.Sprintf("%d min", int(interval.Minutes()))
+	}
+	return choiceMenuItems(names, func(d *Device, i int) bool {
+		return d.Settings.BeaconInterval == BeaconIntervals[i]
+	}, func(d *Device, i int) (err error) {
+		d.Settings.BeaconInterval = BeaconIntervals[i]
+		return nil
+	})
+}
@@ -0,0 +1,120 @@
+package picodoomsdaymessenger
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBeaconBytesConversion(t *testing.T) {
+	// Create a new Machine
+	device, err := NewDevice()
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	device.SelfIdentity = Person{Name: "TestPerson", ID: 1234}
+	station, err := BytesToHeardStation(device.BeaconToBytes())
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	if station.Person != device.SelfIdentity {
+		t.Errorf("The station person is not correct, have: %v want: %v", station.Person, device.SelfIdentity)
+	}
+	if station.Position.Valid() {
+		t.Errorf("The station position should not be valid but is %v", station.Position)
+	}
+	if station.Status != BeaconStatus {
+		t.Errorf("The station status is not correct, have: %v want: %v", station.Status, BeaconStatus)
+	}
+
+	device.SetPosition(51.5, -0.125, SourceSerial)
+	station, err = BytesToHeardStation(device.BeaconToBytes())
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	if station.Position.Latitude != 51.5 || station.Position.Longitude != -0.125 {
+		t.Errorf("The station position is not correct, have: %v, %v want: 51.5, -0.125", station.Position.Latitude, station.Position.Longitude)
+	}
+
+	_, err = BytesToHeardStation([]byte("bconjunk"))
+	if err != ErrInvalidBeacon {
+		t.Errorf("The error should be ErrInvalidBeacon but is %v", err)
+	}
+}
+
+func TestSendBeacon(t *testing.T) {
+	// Create a new Machine
+	device, err := NewDevice()
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	sentPackets := 0
+	device.SendUsingRadio = func(packet []byte) (err error) {
+		sentPackets++
+		return nil
+	}
+	now := time.Unix(100000, 0)
+
+	err = device.Tick(now)
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	if sentPackets != 0 {
+		t.Errorf("No beacons should be sent while turned off but %d were", sentPackets)
+	}
+
+	err = ToolsMenuItemBeacon.Action(device)
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	err = device.Tick(now)
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	err = device.Tick(now.Add(device.Settings.BeaconInterval / 2))
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	if sentPackets != 1 {
+		t.Errorf("1 beacon should have been sent but %d were", sentPackets)
+	}
+	err = device.Tick(now.Add(device.Settings.BeaconInterval))
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	if sentPackets != 2 {
+		t.Errorf("2 beacons should have been sent but %d were", sentPackets)
+	}
+}
+
+func TestReceiveBeacon(t *testing.T) {
+	// Create a new Machine
+	device, err := NewDevice()
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	other, err := NewDevice()
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	other.SelfIdentity = Person{Name: "Other", ID: 99}
+
+	err = device.ReceiveFromRadio(other.BeaconToBytes())
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	err = device.ReceiveFromRadio(other.BeaconToBytes())
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	if len(device.HeardStations) != 1 {
+		t.Errorf("The heard stations list should contain 1 station but contains %v", device.HeardStations)
+	}
+	if len(device.Conversations) != 0 {
+		t.Errorf("Beacons should not create conversations but have: %v", device.Conversations)
+	}
+
+	device.UpdateHeardStationsMenu()
+	if len(device.StateHeardStationsMenu.Content) != 2 || device.StateHeardStationsMenu.Content[1].Text != "Other OK" {
+		t.Errorf("The heard stations menu is not correct, have: %v", device.StateHeardStationsMenu.Content)
+	}
+}
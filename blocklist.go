@@ -0,0 +1,81 @@
+package picodoomsdaymessenger
+
+import "encoding/json"
+
+// blockedStorageKey is the key that the Blocked IDs are saved with.
+const blockedStorageKey = "blocked"
+
+// IsBlocked returns true if the device with an ID is Blocked.
+func (d *Device) IsBlocked(id int) bool {
+	for _, blocked := range d.Blocked {
+		if blocked == id {
+			return true
+		}
+	}
+	return false
+}
+
+// Block adds an ID to the Blocked IDs, and saves them. The broadcast ID and the ID of the Device cannot be blocked.
+func (d *Device) Block(id int) (err error) {
+	if id == BroadcastID || id == d.SelfIdentity.ID || d.IsBlocked(id) {
+		return nil
+	}
+	d.Blocked = append(d.Blocked, id)
+	return d.SaveBlocked()
+}
+
+// Unblock removes an ID from the Blocked IDs, and saves them.
+func (d *Device) Unblock(id int) (err error) {
+	kept := d.Blocked[:0]
+	for _, blocked := range d.Blocked {
+		if blocked != id {
+			kept = append(kept, blocked)
+		}
+	}
+	d.Blocked = kept
+	return d.SaveBlocked()
+}
+
+// ToggleConversationBlocked blocks the Person that a Conversation is with, or unblocks them if they are already Blocked. Broadcast Conversations are not with anyone, so nothing is blocked.
+func (d *Device) ToggleConversationBlocked(c *Conversation) (err error) {
+	if c.Broadcast {
+		return nil
+	}
+	if d.IsBlocked(c.Destination()) {
+		return d.Unblock(c.Destination())
+	}
+	return d.Block(c.Destination())
+}
+
+// SaveBlocked writes the Blocked IDs to the Storage of the Device. It does nothing if the Device has no Storage.
+func (d *Device) SaveBlocked() (err error) {
+	if d.Storage == nil {
+		return nil
+	}
+	data, err := json.Marshal(d.Blocked)
+	if err != nil {
+		return err
+	}
+	return d.Storage.Save(blockedStorageKey, data)
+}
+
+// LoadBlocked reads the Blocked IDs from the Storage of the Device. A Device starts with no one Blocked.
+func (d *Device) LoadBlocked() (err error) {
+	if d.Storage == nil {
+		return nil
+	}
+	data, err := d.Storage.Load(blockedStorageKey)
+	if err == ErrStorageNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	blocked := []int{}
+	err = json.Unmarshal(data, &blocked)
+	if err != nil {
+		return err
+	}
+	d.Blocked = blocked
+	return nil
+}
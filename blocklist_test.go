@@ -0,0 +1,76 @@
+package picodoomsdaymessenger
+
+import "testing"
+
+func TestBlockedSenders(t *testing.T) {
+	device, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	storage := MemoryStorage{}
+	device.Storage = storage
+	device.State = &device.StateMainMenu
+	if err = device.ReceiveFromRadio([]byte("doom7\xccBob\xcchello")); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	device.CurrentConversationIndex = 0
+	device.StateConversationOptionsMenu.HighlightedItemIndex = 2
+	if err = device.ChangeStateWithHistory(&device.StateConversationOptionsMenu); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if err = device.ProcessInputEvent(InputEventAccept); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	device.StateConversationOptionsMenu.HighlightedItemIndex = 0
+	if !device.IsBlocked(7) {
+		t.Fatalf("Block should block the Person that the Conversation is with, have %v", device.Blocked)
+	}
+
+	for _, packet := range []string{"doom7\xccBob\xccanyone?", "doom8\xccEve\xcchi"} {
+		if err = device.ReceiveFromRadio([]byte(packet)); err != nil {
+			t.Fatalf("The error should be nil but is %v", err)
+		}
+	}
+	if len(device.Conversations) != 2 || len(device.Conversations[0].Messages) != 1 {
+		t.Errorf("Messages from a blocked sender should be dropped, have %d conversations and %d messages", len(device.Conversations), len(device.Conversations[0].Messages))
+	}
+	if device.Packets.Rejected != 0 {
+		t.Errorf("Blocked packets should be dropped silently, have %d rejected", device.Packets.Rejected)
+	}
+
+	restored, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	restored.Storage = storage
+	if err = restored.LoadBlocked(); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if !restored.IsBlocked(7) {
+		t.Errorf("The Blocked IDs should be saved, have %v", restored.Blocked)
+	}
+
+	if err = device.ToggleConversationBlocked(device.Conversations[0]); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if err = device.ReceiveFromRadio([]byte("doom7\xccBob\xccback")); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if len(device.Conversations[0].Messages) != 2 {
+		t.Errorf("Unblocking should let messages through again, have %d messages", len(device.Conversations[0].Messages))
+	}
+}
+
+func TestBlockedContactCard(t *testing.T) {
+	device := newSigningDevice(t, 10)
+	device.State = &device.StateMainMenu
+	bob := newSigningDevice(t, 7)
+	bob.SelfIdentity.Name = "Bob"
+	device.Blocked = []int{7}
+	if err := device.ReceiveFromRadio(ContactToBytes(Contact{Person: bob.SelfIdentity, PublicKey: bob.PublicKey()})); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if len(device.ContactOffers()) != 0 {
+		t.Errorf("A contact card for a blocked Person should be dropped, have %v", device.ContactOffers())
+	}
+}
@@ -0,0 +1,126 @@
+package picodoomsdaymessenger
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrNoSuchConversation is returned when a serial command refers to a Conversation that does not exist.
+var ErrNoSuchConversation = errors.New("no such conversation")
+
+// InputEvents is every InputEvent, so that they can be looked up by name.
+var InputEvents = []InputEvent{
+	InputEventUp, InputEventDown, InputEventLeft, InputEventRight, InputEventAccept,
+	InputEventFunction1, InputEventFunction2, InputEventFunction3, InputEventFunction4,
+	InputEventOpenSettings, InputEventOpenPeople, InputEventOpenConversations, InputEventOpenMainMenu,
+	InputEventNumber1, InputEventNumber2, InputEventNumber3, InputEventNumber4, InputEventNumber5,
+	InputEventNumber6, InputEventNumber7, InputEventNumber8, InputEventNumber9, InputEventNumber0,
+	InputEventStar, InputEventPound,
+}
+
+// SendMessage sends text to a Conversation from the Device. The Message is also streamed to the host, if one is syncing.
+func (d *Device) SendMessage(c *Conversation, text string) (err error) {
+	return d.SendMessageWithPriority(c, text, PriorityNormal)
+}
+
+// SendMessageWithPriority sends text to a Conversation from the Device, marked with a Priority.
+// It returns as soon as the Message is queued, without waiting for the radio. The Message is added to the Conversation, and its Status is changed once it has been sent.
+func (d *Device) SendMessageWithPriority(c *Conversation, text string, priority Priority) (err error) {
+	message := d.outgoingMessage(c, text, priority)
+	packet, err := d.EncodeMessage(message)
+	if err != nil {
+		return err
+	}
+	// Emergency messages are sent before anything else that is waiting.
+	txPriority := txPriorityMessage
+	if priority == PriorityEmergency {
+		txPriority = txPriorityUrgent
+	}
+	message.Status = MessageStatusQueued
+	err = d.sendPacketWithPriority(c, d.addHopHeader(packet), txPriority, func(err error) {
+		d.finishMessage(c, message, err)
+	})
+	if err != nil {
+		return err
+	}
+	d.addOwnMessage(c, message)
+	return d.syncMessage(d.conversationIndex(c), message)
+}
+
+// outgoingMessage creates the Message that the Device sends to a Conversation. Text longer than the MessageTextLimit is cut off, so that the Conversation shows what was sent.
+func (d *Device) outgoingMessage(c *Conversation, text string, priority Priority) (message Message) {
+	message = Message{
+		Text:     text,
+		Person:   d.SelfIdentity,
+		TimeSent: d.Now(),
+		Priority: priority,
+		To:       c.Destination(),
+	}
+	if c != nil {
+		message.Broadcast = c.Broadcast
+		message.Channel = c.Channel
+	}
+	message.Text, _ = truncateString(message.Text, d.MessageTextLimit(message))
+	return message
+}
+
+// serialConversations lists the Conversations, one per line, as "<index> <message count> <name>".
+func (d *Device) serialConversations() (response string) {
+	if len(d.Conversations) == 0 {
+		return "none"
+	}
+	lines := make([]string, len(d.Conversations))
+	for i, c := range d.Conversations {
+		lines[i] = fmt.Sprintf("%d %d %s", i, len(c.Messages), c.Name)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// serialConversation returns the Conversation with the index in a serial command.
+func (d *Device) serialConversation(field string) (c *Conversation, err error) {
+	index, err := strconv.Atoi(field)
+	if err != nil {
+		return nil, ErrSerialBadArguments
+	}
+	if index < 0 || index >= len(d.Conversations) {
+		return nil, ErrNoSuchConversation
+	}
+	return d.Conversations[index], nil
+}
+
+// serialMessages lists the Messages of a Conversation, one per line, as "<person ID> <name>: <text>".
+func (d *Device) serialMessages(c *Conversation) (response string) {
+	if len(c.Messages) == 0 {
+		return "none"
+	}
+	lines := make([]string, len(c.Messages))
+	for i, m := range c.Messages {
+		lines[i] = fmt.Sprintf("%d %s: %s", m.Person.ID, m.Person.Name, d.MessageText(m))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// serialInput presses a button on the Device, by the name of its InputEvent.
+func (d *Device) serialInput(name string) (err error) {
+	for _, inputEvent := range InputEvents {
+		if string(inputEvent) == name {
+			return d.ProcessInputEvent(inputEvent)
+		}
+	}
+	return ErrSerialBadArguments
+}
+
+// serialText returns the rest of a serial command after its first n fields, with its spacing kept.
+func serialText(line string, n int) string {
+	line = strings.TrimSpace(line)
+	for i := 0; i < n; i++ {
+		end := strings.IndexFunc(line, func(r rune) bool { return r == ' ' || r == '\t' })
+		if end < 0 {
+			return ""
+		}
+		line = strings.TrimLeft(line[end:], " \t")
+	}
+	return line
+}
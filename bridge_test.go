@@ -0,0 +1,73 @@
+package picodoomsdaymessenger
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSerialBridge(t *testing.T) {
+	device, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	var sent []byte
+	device.SendUsingRadio = func(packet []byte) (err error) {
+		sent = packet
+		return nil
+	}
+
+	response, err := device.ProcessSerialCommand("conversations")
+	if err != nil || response != "none" {
+		t.Errorf("expected no conversations, got %q %v", response, err)
+	}
+
+	err = device.ReceiveFromRadio([]byte("doom7\xccBob\xcchello"))
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	response, err = device.ProcessSerialCommand("conversations")
+	if err != nil || response != "0 1 7" {
+		t.Errorf("expected the conversation with bob, got %q %v", response, err)
+	}
+	response, err = device.ProcessSerialCommand("messages 0")
+	if err != nil || response != "7 Bob: hello" {
+		t.Errorf("expected the message from bob, got %q %v", response, err)
+	}
+	_, err = device.ProcessSerialCommand("messages 3")
+	if err != ErrNoSuchConversation {
+		t.Errorf("expected ErrNoSuchConversation, got %v", err)
+	}
+
+	response, err = device.ProcessSerialCommand("send 0 hi  there")
+	if err != nil || response != "ok" {
+		t.Fatalf("expected the message to be sent, got %q %v", response, err)
+	}
+	err = sendQueued(device)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	message, err := device.BytesToMessage(sent)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if message.Text != "hi  there" || message.Person != device.SelfIdentity {
+		t.Errorf("unexpected message sent: %+v", message)
+	}
+
+	defer func() { device.State.HighlightedItemIndex = 0 }()
+	response, err = device.ProcessSerialCommand("input down")
+	if err != nil || response != "ok" {
+		t.Fatalf("expected the input to be pressed, got %q %v", response, err)
+	}
+	if device.State.HighlightedItemIndex != 1 {
+		t.Errorf("expected the down input to move the highlight, got %v", device.State.HighlightedItemIndex)
+	}
+	_, err = device.ProcessSerialCommand("input sideways")
+	if err != ErrSerialBadArguments {
+		t.Errorf("expected ErrSerialBadArguments, got %v", err)
+	}
+	response, err = device.ProcessSerialCommand("state")
+	if err != nil || !strings.Contains(response, device.State.Title) {
+		t.Errorf("expected the state title, got %q %v", response, err)
+	}
+}
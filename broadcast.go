@@ -0,0 +1,47 @@
+package picodoomsdaymessenger
+
+// BroadcastConversationName is the name of the Conversation that every broadcast message is kept in.
+const BroadcastConversationName = "Broadcast"
+
+// BroadcastConversation returns the index of the Conversation that sends to everyone in range on the Channel in the Settings, and collects every broadcast heard on it, creating it if there is not one yet.
+func (d *Device) BroadcastConversation() (index int) {
+	return d.broadcastConversation(d.Settings.Channel)
+}
+
+// broadcastConversation returns the index of the broadcast Conversation of a Channel, creating it if there is not one yet. The broadcast Conversation of a Channel is named after it.
+func (d *Device) broadcastConversation(channel string) (index int) {
+	for i, c := range d.Conversations {
+		if c.Broadcast && c.Channel == channel {
+			return i
+		}
+	}
+	name := BroadcastConversationName
+	if channel != "" {
+		name = "#" + channel
+	}
+	d.Conversations = append(d.Conversations, &Conversation{
+		Name:      name,
+		People:    []Person{d.SelfIdentity},
+		Broadcast: true,
+		Channel:   channel,
+	})
+	d.UpdateConversationsMenu()
+	return len(d.Conversations) - 1
+}
+
+// NewConversationMenuItemBroadcast is a MenuItem that opens the broadcast Conversation.
+var NewConversationMenuItemBroadcast = MenuItem{
+	Text: BroadcastConversationName,
+	Action: func(d *Device) (err error) {
+		d.CurrentConversationIndex = d.BroadcastConversation()
+		return d.ChangeStateWithoutHistory(&d.StateConversationReader)
+	},
+	CursorIcon: CursorIconRightArrow,
+}
+
+func init() {
+	stateSetups = append(stateSetups, func(s *States) {
+		// The broadcast Conversation is always offered, even before anything has been broadcast.
+		s.StateNewConversation.Content = append(s.StateNewConversation.Content, NewConversationMenuItemBroadcast)
+	})
+}
@@ -0,0 +1,51 @@
+package picodoomsdaymessenger
+
+import "testing"
+
+func TestBroadcastConversation(t *testing.T) {
+	sender, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	receiver, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	receiver.State = &receiver.StateMainMenu
+	sender.SendUsingRadio = receiver.ReceiveFromRadio
+
+	err = NewConversationMenuItemBroadcast.Action(sender)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if sender.State != &sender.StateConversationReader || !sender.Conversations[sender.CurrentConversationIndex].Broadcast {
+		t.Fatalf("expected the broadcast conversation to be opened")
+	}
+	if index := sender.BroadcastConversation(); index != sender.CurrentConversationIndex || len(sender.Conversations) != 1 {
+		t.Errorf("expected the broadcast conversation to be reused")
+	}
+
+	// A message from the same person to the device goes to their own conversation.
+	err = sender.SendMessage(sender.NewConversation(receiver.SelfIdentity), "just you")
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	err = sender.SendMessage(sender.Conversations[0], "everyone")
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	err = sendQueued(sender)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if len(receiver.Conversations) != 2 {
+		t.Fatalf("expected a direct and a broadcast conversation, got %d", len(receiver.Conversations))
+	}
+	broadcast := receiver.Conversations[receiver.BroadcastConversation()]
+	if broadcast.Name != BroadcastConversationName || len(broadcast.Messages) != 1 || broadcast.Messages[0].Text != "everyone" {
+		t.Errorf("expected the broadcast to be kept in the broadcast conversation, got %+v", broadcast.Messages)
+	}
+	if direct := receiver.Conversations[receiver.ConversationWith(sender.SelfIdentity.ID)]; len(direct.Messages) != 1 || direct.Messages[0].Text != "just you" {
+		t.Errorf("expected the direct message to be kept apart, got %+v", direct.Messages)
+	}
+}
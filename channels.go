@@ -0,0 +1,316 @@
+package picodoomsdaymessenger
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// Define channel errors
+var (
+	ErrUnknownChannel = errors.New("packet is for a channel that the device does not have")
+	ErrChannelExists  = errors.New("a channel with that name already exists")
+	ErrNoChannelName  = errors.New("a channel needs a name")
+)
+
+// channelPrefix is the start of a packet that is encrypted for a Channel. It is followed by the hash of the Channel, the nonce, and then the sealed "doom" or "dsig" message.
+var channelPrefix = []byte{0x64, 0x63, 0x68, 0x6E} // ASCII for "dchn"
+
+// channelNonceSize is the length of the random nonce that every encrypted packet is sent with.
+const channelNonceSize = 12
+
+// channelOverhead is how many bytes encrypting a message for a Channel adds to its packet.
+const channelOverhead = len("dchn") + 1 + channelNonceSize + 16
+
+// channelsStorageKey is the key that the Channels are saved with.
+const channelsStorageKey = "channels"
+
+// PublicChannelName is the name shown for messages that are not sent on a Channel, which every device can read.
+const PublicChannelName = "Public"
+
+// Channel is a group of devices that share a key, so that they can talk on the same frequency as everyone else without being read by them.
+type Channel struct {
+	Name string
+	// Key is the AES-256 key that the messages of the Channel are encrypted with.
+	Key []byte
+}
+
+// channelKeyIterations is how many rounds of PBKDF2 a Channel key is made with, so that guessing the passphrase of a recorded packet is slow.
+const channelKeyIterations = 10000
+
+// NewChannel creates a Channel with a key made from a passphrase, so that everyone who is told the name and passphrase has the same Channel.
+// The key is made with PBKDF2-HMAC-SHA256 with the name as the salt, so Channels with the same passphrase but different names have different keys.
+// Keys used to be the SHA-256 of the passphrase alone. Channels that were added before that changed keep their saved key, but cannot talk to the same Channel added again on another device.
+func NewChannel(name, passphrase string) Channel {
+	return Channel{Name: name, Key: pbkdf2SHA256([]byte(passphrase), []byte(name), channelKeyIterations)}
+}
+
+// pbkdf2SHA256 makes a 32 byte key from a password and salt with PBKDF2-HMAC-SHA256, as in RFC 8018. The key is one block long, so only the first block is made.
+func pbkdf2SHA256(password, salt []byte, iterations int) (key []byte) {
+	mac := hmac.New(sha256.New, password)
+	mac.Write(salt)
+	mac.Write(binary.BigEndian.AppendUint32(nil, 1))
+	u := mac.Sum(nil)
+	key = append([]byte{}, u...)
+	for i := 1; i < iterations; i++ {
+		mac.Reset()
+		mac.Write(u)
+		u = mac.Sum(u[:0])
+		for j := range key {
+			key[j] ^= u[j]
+		}
+	}
+	return key
+}
+
+// Hash returns the byte that is sent in front of the packets of the Channel, so that devices only try to decrypt packets for their own Channels.
+// Different Channels can have the same Hash.
+func (c Channel) Hash() byte {
+	sum := sha256.Sum256(append([]byte(c.Name), c.Key...))
+	return sum[0]
+}
+
+// Seal encrypts a packet for the Channel.
+func (c Channel) Seal(packet []byte) (output []byte, err error) {
+	aead, err := c.aead()
+	if err != nil {
+		return nil, err
+	}
+	output = make([]byte, len(channelPrefix)+1+channelNonceSize, len(packet)+channelOverhead)
+	copy(output, channelPrefix)
+	output[len(channelPrefix)] = c.Hash()
+	nonce := output[len(channelPrefix)+1:]
+	_, err = rand.Read(nonce)
+	if err != nil {
+		return nil, err
+	}
+	// The header is authenticated as well, so that the hash cannot be changed to move the packet to another Channel.
+	return aead.Seal(output, nonce, packet, output), nil
+}
+
+// Open decrypts a packet that was sealed for the Channel. It returns ErrUnknownChannel if the packet was sealed for a different Channel.
+func (c Channel) Open(packet []byte) (output []byte, err error) {
+	header := len(channelPrefix) + 1 + channelNonceSize
+	if !bytes.HasPrefix(packet, channelPrefix) || len(packet) < header {
+		return nil, ErrMalformedMessage
+	}
+	if packet[len(channelPrefix)] != c.Hash() {
+		return nil, ErrUnknownChannel
+	}
+	aead, err := c.aead()
+	if err != nil {
+		return nil, err
+	}
+	output, err = aead.Open(nil, packet[len(channelPrefix)+1:header], packet[header:], packet[:header])
+	if err != nil {
+		return nil, ErrUnknownChannel
+	}
+	return output, nil
+}
+
+// aead returns the AES-GCM cipher of the Channel.
+func (c Channel) aead() (aead cipher.AEAD, err error) {
+	block, err := aes.NewCipher(c.Key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// Channel returns the Channel of the Device with a name.
+func (d *Device) Channel(name string) (c Channel, ok bool) {
+	for _, c := range d.Channels {
+		if c.Name == name {
+			return c, true
+		}
+	}
+	return Channel{}, false
+}
+
+// AddChannel adds a Channel to the Device, and saves the Channels.
+func (d *Device) AddChannel(name, passphrase string) (err error) {
+	name = strings.TrimSpace(name)
+	if name == "" || name == PublicChannelName {
+		return ErrNoChannelName
+	}
+	if _, ok := d.Channel(name); ok {
+		return ErrChannelExists
+	}
+	d.Channels = append(d.Channels, NewChannel(name, passphrase))
+	return d.SaveChannels()
+}
+
+// sealForChannel encrypts a message packet for the Channel that the Message is sent on, or with the SessionKey of the Contact that it is addressed to. Other packets are returned as they are.
+// The next counter of the Device is encrypted with the packet, so that it cannot be sent again by someone who recorded it.
+func (d *Device) sealForChannel(packet []byte, m Message) (output []byte, err error) {
+	channel, ok := d.contactSession(m.To)
+	if m.Channel != "" {
+		channel, ok = d.Channel(m.Channel)
+		if !ok {
+			return nil, ErrUnknownChannel
+		}
+	} else if !ok || m.Broadcast {
+		return packet, nil
+	}
+	packet, err = d.appendReplayCounter(packet)
+	if err != nil {
+		return nil, err
+	}
+	return channel.Seal(packet)
+}
+
+// encrypted returns true if a Message will be sealed by sealForChannel.
+func (d *Device) encrypted(m Message) bool {
+	if m.Channel != "" {
+		return true
+	}
+	_, ok := d.contactSession(m.To)
+	return ok && !m.Broadcast
+}
+
+// openChannelMessage decrypts a packet with each Channel that has the same hash, and decodes the message inside it. Packets that are not for a Channel are tried with the SessionKey of each Contact.
+// Packets with a counter that has already been received from their sender are rejected.
+func (d *Device) openChannelMessage(input []byte) (output Message, err error) {
+	for _, channel := range d.Channels {
+		inner, err := channel.Open(input)
+		if err == ErrUnknownChannel {
+			continue
+		}
+		if err != nil {
+			return output, err
+		}
+		counter, inner, err := splitReplayCounter(inner)
+		if err != nil {
+			return output, err
+		}
+		if bytes.HasPrefix(inner, channelPrefix) {
+			return output, ErrMalformedMessage
+		}
+		output, err = d.BytesToMessage(inner)
+		if err != nil {
+			return output, err
+		}
+		output.Channel = channel.Name
+		return output, d.checkReplayCounter(channelReplaySender(channel.Name, output.Person.ID, inner), counter)
+	}
+	return d.openContactMessage(input)
+}
+
+// SaveChannels writes the Channels to the Storage of the Device. It does nothing if the Device has no Storage.
+func (d *Device) SaveChannels() (err error) {
+	if d.Storage == nil {
+		return nil
+	}
+	data, err := json.Marshal(d.Channels)
+	if err != nil {
+		return err
+	}
+	return d.Storage.Save(channelsStorageKey, data)
+}
+
+// LoadChannels reads the Channels from the Storage of the Device. A Device starts with no Channels.
+func (d *Device) LoadChannels() (err error) {
+	if d.Storage == nil {
+		return nil
+	}
+	data, err := d.Storage.Load(channelsStorageKey)
+	if err == ErrStorageNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	channels := []Channel{}
+	err = json.Unmarshal(data, &channels)
+	if err != nil {
+		return err
+	}
+	d.Channels = channels
+	return nil
+}
+
+// channelsState is the Channel that is being added.
+type channelsState struct {
+	// Draft is the text that is being written, first the name and then the passphrase.
+	Draft string
+	// Name is the name of the new Channel, once it has been written.
+	Name string
+}
+
+// The Channel menu is refreshed every time it is shown, so that new Channels are listed.
+func init() {
+	stateSetups = append(stateSetups, func(s *States) {
+		s.StateChannelMenu.LoadAction = func(d *Device) (err error) {
+			d.UpdateChannelMenu()
+			return nil
+		}
+	})
+}
+
+// UpdateChannelMenu lists the public channel and every Channel of the Device to choose from, followed by an item to add a Channel.
+func (d *Device) UpdateChannelMenu() {
+	names := []string{PublicChannelName}
+	for _, c := range d.Channels {
+		names = append(names, c.Name)
+	}
+	d.StateChannelMenu.Content = append([]MenuItem{GlobalMenuItemGoBack}, choiceMenuItems(names, func(d *Device, i int) bool {
+		return d.Settings.Channel == channelName(names[i])
+	}, func(d *Device, i int) (err error) {
+		d.Settings.Channel = channelName(names[i])
+		return nil
+	})...)
+	d.StateChannelMenu.Content = append(d.StateChannelMenu.Content, ChannelMenuItemNew)
+	if d.StateChannelMenu.HighlightedItemIndex >= len(d.StateChannelMenu.Content) {
+		d.StateChannelMenu.HighlightedItemIndex = 0
+	}
+}
+
+// channelName returns the name that a Channel is stored with in the Settings, which is empty for the public channel.
+func channelName(name string) string {
+	if name == PublicChannelName {
+		return ""
+	}
+	return name
+}
+
+// processChannelEditorInputEvent types the name and then the passphrase of a new Channel with the number keys, deletes with left and moves on with accept.
+func processChannelEditorInputEvent(d *Device, inputEvent InputEvent) (handled bool, err error) {
+	if d.processTextEntryInputEvent(&d.channels.Draft, inputEvent) {
+		return true, nil
+	}
+	if inputEvent != InputEventAccept {
+		return false, nil
+	}
+	text := d.finishTextEntry(&d.channels.Draft)
+	if d.channels.Name == "" {
+		d.channels.Name = strings.TrimSpace(text)
+		d.channels.Draft = ""
+		if d.channels.Name == "" {
+			return true, d.GoBackState()
+		}
+		return true, nil
+	}
+	err = d.AddChannel(d.channels.Name, text)
+	d.channels = channelsState{}
+	if err != nil && err != ErrChannelExists {
+		return true, err
+	}
+	return true, d.GoBackState()
+}
+
+// drawChannelEditor draws the name or passphrase of the new Channel with the character that is being typed.
+func drawChannelEditor(d *Device, img Canvas, layout Layout) (err error) {
+	title := "Channel Name"
+	if d.channels.Name != "" {
+		title = "Passphrase"
+	}
+	drawTextPage(img, layout, d.Palette(), title, d.channels.Draft+d.pendingCharacter()+"_", 0)
+	return nil
+}
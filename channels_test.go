@@ -0,0 +1,167 @@
+package picodoomsdaymessenger
+
+import (
+	"bytes"
+	"encoding/hex"
+	"image"
+	"testing"
+)
+
+func TestChannelSealAndOpen(t *testing.T) {
+	camp := NewChannel("camp", "secret")
+	sealed, err := camp.Seal([]byte("doom7\xccBob\xcchello"))
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if len(sealed) != len("doom7\xccBob\xcchello")+channelOverhead {
+		t.Errorf("expected %d bytes of overhead, got %d", channelOverhead, len(sealed)-len("doom7\xccBob\xcchello"))
+	}
+	opened, err := NewChannel("camp", "secret").Open(sealed)
+	if err != nil || string(opened) != "doom7\xccBob\xcchello" {
+		t.Fatalf("expected the packet to open with the same name and passphrase, got %q %v", opened, err)
+	}
+	if _, err = NewChannel("camp", "guess").Open(sealed); err != ErrUnknownChannel {
+		t.Errorf("expected ErrUnknownChannel for the wrong passphrase, got %v", err)
+	}
+	sealed[len(sealed)-1] ^= 1
+	if _, err = camp.Open(sealed); err != ErrUnknownChannel {
+		t.Errorf("expected a changed packet not to open, got %v", err)
+	}
+}
+
+func TestChannelKey(t *testing.T) {
+	// The first block of the PBKDF2-HMAC-SHA256 test vector from RFC 7914.
+	if key := hex.EncodeToString(pbkdf2SHA256([]byte("passwd"), []byte("salt"), 1)); key != "55ac046e56e3089fec1691c22544b605f94185216dde0465e68b9d57c20dacbc" {
+		t.Errorf("The PBKDF2 key should match RFC 7914 but is %s", key)
+	}
+	if key := hex.EncodeToString(NewChannel("camp", "secret").Key); key != "f533859a7862ee800504c7f9b3d667dcf010a119b37cad008f13948eeb895bde" {
+		t.Errorf("The Channel key should be made with channelKeyIterations of PBKDF2 but is %s", key)
+	}
+	if bytes.Equal(NewChannel("camp", "secret").Key, NewChannel("base", "secret").Key) {
+		t.Errorf("Channels with the same passphrase but different names should have different keys")
+	}
+}
+
+func TestSendOnChannel(t *testing.T) {
+	sender, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	member, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	outsider, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	member.State = &member.StateMainMenu
+	outsider.State = &outsider.StateMainMenu
+	for _, d := range []*Device{sender, member} {
+		if err = d.AddChannel("camp", "secret"); err != nil {
+			t.Fatalf("The error should be nil but is %v", err)
+		}
+	}
+	var outsiderErr error
+	sender.SendUsingRadio = func(packet []byte) (err error) {
+		outsiderErr = outsider.ReceiveFromRadio(packet)
+		return member.ReceiveFromRadio(packet)
+	}
+
+	sender.Settings.Channel = "camp"
+	err = sender.SendMessage(sender.Conversations[sender.BroadcastConversation()], "meet at noon")
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	err = sendQueued(sender)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if outsiderErr != ErrUnknownChannel || len(outsider.Conversations) != 0 {
+		t.Errorf("expected a device without the channel to reject the message, got %v", outsiderErr)
+	}
+	if len(member.Conversations) != 1 {
+		t.Fatalf("expected the member to receive the message, got %d conversations", len(member.Conversations))
+	}
+	c := member.Conversations[0]
+	if !c.Broadcast || c.Channel != "camp" || c.Name != "#camp" || c.Messages[0].Text != "meet at noon" {
+		t.Errorf("expected the message in the channel's conversation, got %+v", c)
+	}
+
+	// A public message from the same sender is kept apart from the channel.
+	sender.Settings.Channel = ""
+	err = sender.SendMessage(sender.Conversations[sender.BroadcastConversation()], "hello everyone")
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	err = sendQueued(sender)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if len(member.Conversations) != 2 || member.Conversations[1].Channel != "" {
+		t.Errorf("expected the public broadcast in its own conversation")
+	}
+}
+
+func TestAddChannelFromSettings(t *testing.T) {
+	device, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	storage := MemoryStorage{}
+	device.Storage = storage
+	err = device.ChangeStateWithHistory(&device.StateChannelMenu)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	err = ChannelMenuItemNew.Action(device)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	device.clearPendingCharacter()
+	// Type "hi" as the name and "d" as the passphrase.
+	for _, inputEvent := range []InputEvent{InputEventNumber4, InputEventNumber4, InputEventNumber1, InputEventLeft, InputEventNumber4, InputEventNumber4, InputEventNumber4, InputEventAccept, InputEventNumber3} {
+		err = device.ProcessInputEvent(inputEvent)
+		if err != nil {
+			t.Fatalf("The error should be nil but is %v", err)
+		}
+	}
+	if _, err := GetFrame(image.Rect(0, 0, 128, 64), device); err != nil {
+		t.Fatalf("The error drawing the editor should be nil but is %v", err)
+	}
+	err = device.ProcessInputEvent(InputEventAccept)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if device.State != &device.StateChannelMenu {
+		t.Errorf("expected to go back to the channels, got %q", device.State.Title)
+	}
+	channel, ok := device.Channel("hi")
+	if !ok || string(channel.Key) != string(NewChannel("hi", "d").Key) {
+		t.Fatalf("expected a channel named %q, got %+v", "hi", device.Channels)
+	}
+	// The public channel, the new channel and an item to add another.
+	if len(device.StateChannelMenu.Content) != 4 {
+		t.Fatalf("expected the new channel to be listed, got %d items", len(device.StateChannelMenu.Content))
+	}
+	err = device.StateChannelMenu.Content[2].Action(device)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if device.Settings.Channel != "hi" {
+		t.Errorf("expected the new channel to be chosen, got %q", device.Settings.Channel)
+	}
+
+	restored, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	restored.Storage = storage
+	err = restored.LoadChannels()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if _, ok := restored.Channel("hi"); !ok {
+		t.Errorf("expected the channels to be loaded")
+	}
+}
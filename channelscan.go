@@ -0,0 +1,154 @@
+package picodoomsdaymessenger
+
+import (
+	"fmt"
+	"image"
+	"time"
+)
+
+// ChannelScanDwell is how long the channel scanner listens to each frequency before moving on to the next.
+const ChannelScanDwell = 200 * time.Millisecond
+
+// The RSSIs that the bars of the channel scanner are drawn between, in dBm. Quieter channels have shorter bars.
+const (
+	ChannelScanFloor   = -130
+	ChannelScanCeiling = -60
+)
+
+// channelScanState is the progress of the channel scanner.
+type channelScanState struct {
+	// active is true while the Radio is tuned away from the frequency in the Settings.
+	active bool
+	// Frequencies are the channels that are swept, in MHz.
+	Frequencies []float64
+	// RSSI is the background signal heard on each of the Frequencies, averaged over every sweep, or RSSIUnknown if it has not been sampled yet.
+	RSSI []int
+	// current is the index of the frequency that the Radio is listening to.
+	current int
+	// Cursor is the index of the frequency that would be chosen.
+	Cursor     int
+	lastSample time.Time
+}
+
+// channelScanFrequencies returns the frequencies that the channel scanner sweeps: the channels of the Region, or every FrequencyPreset if no Region is set.
+func (d *Device) channelScanFrequencies() (frequencies []float64) {
+	if region, ok := d.Region(); ok && len(region.Frequencies) > 0 {
+		return append([]float64{}, region.Frequencies...)
+	}
+	for _, preset := range FrequencyPresets {
+		frequencies = append(frequencies, preset.FrequencyMHz)
+	}
+	return frequencies
+}
+
+// StartChannelScan tunes the Radio to the first channel and starts sweeping. The cursor starts on the frequency in the Settings, if it is one of the channels.
+// Nothing is sent while the scanner has the Radio.
+func (d *Device) StartChannelScan(now time.Time) (err error) {
+	frequencies := d.channelScanFrequencies()
+	d.channelScan = channelScanState{
+		Frequencies: frequencies,
+		RSSI:        make([]int, len(frequencies)),
+		lastSample:  now,
+	}
+	for i, frequency := range frequencies {
+		if frequency == d.Settings.FrequencyMHz {
+			d.channelScan.Cursor = i
+		}
+	}
+	if d.Radio == nil || len(frequencies) == 0 {
+		return nil
+	}
+	d.channelScan.active = true
+	return d.Radio.SetFrequency(frequencies[0])
+}
+
+// stopChannelScan tunes the Radio back to the frequency in the Settings. It is run when the scanner is left.
+func (d *Device) stopChannelScan() (err error) {
+	if !d.channelScan.active {
+		return nil
+	}
+	d.channelScan.active = false
+	return d.Radio.SetFrequency(d.Settings.FrequencyMHz)
+}
+
+// tickChannelScan samples the RSSI of the channel that the Radio is listening to once it has listened for ChannelScanDwell, and tunes to the next one.
+func (d *Device) tickChannelScan(now time.Time) (err error) {
+	if !d.channelScan.active {
+		return nil
+	}
+	if now.Sub(d.channelScan.lastSample) < ChannelScanDwell {
+		return nil
+	}
+	d.channelScan.lastSample = now
+	rssi, err := d.Radio.ChannelRSSI()
+	if err != nil {
+		return err
+	}
+	s := &d.channelScan
+	if s.RSSI[s.current] == RSSIUnknown {
+		s.RSSI[s.current] = rssi
+	} else if rssi != RSSIUnknown {
+		s.RSSI[s.current] = (3*s.RSSI[s.current] + rssi) / 4
+	}
+	d.MarkDirty()
+	s.current = (s.current + 1) % len(s.Frequencies)
+	return d.Radio.SetFrequency(s.Frequencies[s.current])
+}
+
+// processChannelScannerInputEvent moves the cursor between the channels with left and right, and changes to the channel under it with accept.
+func processChannelScannerInputEvent(d *Device, inputEvent InputEvent) (handled bool, err error) {
+	s := &d.channelScan
+	switch inputEvent {
+	case InputEventAccept:
+		err = d.stopChannelScan()
+		if err != nil {
+			return true, err
+		}
+		if len(s.Frequencies) > 0 {
+			err = d.SetFrequency(s.Frequencies[s.Cursor])
+			if err != nil {
+				return true, err
+			}
+		}
+		return true, d.GoBackState()
+	case InputEventLeft:
+		if s.Cursor > 0 {
+			s.Cursor--
+		}
+		return true, nil
+	case InputEventRight:
+		if s.Cursor < len(s.Frequencies)-1 {
+			s.Cursor++
+		}
+		return true, nil
+	case InputEventUp, InputEventDown:
+		return true, nil
+	}
+	return false, nil
+}
+
+// drawChannelScanner draws the channel under the cursor and its RSSI, with a bar for the RSSI of every channel below.
+func drawChannelScanner(d *Device, img Canvas, layout Layout) (err error) {
+	s := d.channelScan
+	palette := d.Palette()
+	if len(s.Frequencies) == 0 {
+		drawTextPage(img, layout, palette, "Channel Scan", "No channels", 0)
+		return nil
+	}
+	text := fmt.Sprintf("%.3f MHz %s", s.Frequencies[s.Cursor], formatRSSI(s.RSSI[s.Cursor]))
+	if d.Radio == nil {
+		text = "No radio"
+	}
+	drawTextPage(img, layout, palette, "Channel Scan", text, 0)
+	levels := make([]int, len(s.RSSI))
+	for i, rssi := range s.RSSI {
+		levels[i] = rssi
+		if rssi == RSSIUnknown {
+			levels[i] = ChannelScanFloor
+		}
+	}
+	bounds := img.Bounds()
+	top := layout.TitleHeight + layout.LineHeight + 4
+	drawBarChart(img, image.Rect(bounds.Min.X+2, top, bounds.Max.X-2, bounds.Max.Y-1), levels, ChannelScanFloor, ChannelScanCeiling, s.Cursor, palette.Text, palette.Cursor)
+	return nil
+}
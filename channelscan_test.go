@@ -0,0 +1,114 @@
+package picodoomsdaymessenger
+
+import (
+	"image"
+	"testing"
+	"time"
+)
+
+func TestChannelScanner(t *testing.T) {
+	device, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	radio := &testRadio{rssi: map[float64]int{868.0: -80, 868.1: -120, 868.3: -100, 868.5: -110, 869.525: -90}}
+	err = device.SetRadio(radio)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	sent := 0
+	device.SendUsingRadio = func(packet []byte) (err error) {
+		sent++
+		return nil
+	}
+
+	err = ToolsMenuItemChannelScanner.Action(device)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if device.State != &device.StateChannelScanner || len(device.channelScan.Frequencies) != 5 || device.channelScan.Cursor != 0 {
+		t.Fatalf("expected the channels of the region to be swept from the current one, got %+v", device.channelScan)
+	}
+	now := time.Now()
+	for i := 1; i <= 5; i++ {
+		err = device.Tick(now.Add(time.Duration(i) * ChannelScanDwell))
+		if err != nil {
+			t.Fatalf("The error should be nil but is %v", err)
+		}
+	}
+	want := []int{-80, -120, -100, -110, -90}
+	for i := range want {
+		if device.channelScan.RSSI[i] != want[i] {
+			t.Errorf("expected the RSSI of every channel, got %v", device.channelScan.RSSI)
+			break
+		}
+	}
+	if radio.frequencyMHz != 868.0 {
+		t.Errorf("expected the sweep to start again, got %v MHz", radio.frequencyMHz)
+	}
+
+	// Nothing is sent while the radio is tuned away.
+	err = device.SendPacket(nil, []byte("doom"))
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	err = sendQueued(device)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if sent != 0 {
+		t.Errorf("expected nothing to be sent while scanning, sent %d", sent)
+	}
+	if _, err := GetFrame(image.Rect(0, 0, 128, 64), device); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+
+	// The quietest channel is chosen.
+	for _, inputEvent := range []InputEvent{InputEventRight, InputEventAccept} {
+		err = device.ProcessInputEvent(inputEvent)
+		if err != nil {
+			t.Fatalf("The error should be nil but is %v", err)
+		}
+	}
+	if device.Settings.FrequencyMHz != 868.1 || radio.frequencyMHz != 868.1 || device.State == &device.StateChannelScanner {
+		t.Errorf("expected to change to the chosen channel and go back, got %v MHz and %v MHz", device.Settings.FrequencyMHz, radio.frequencyMHz)
+	}
+	err = sendQueued(device)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if sent != 1 {
+		t.Errorf("expected the packet to be sent once the scanner was left, sent %d", sent)
+	}
+}
+
+func TestChannelScannerLeft(t *testing.T) {
+	device, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	radio := &testRadio{}
+	err = device.SetRadio(radio)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	err = device.SetFrequency(868.3)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	err = ToolsMenuItemChannelScanner.Action(device)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if device.channelScan.Cursor != 2 || radio.frequencyMHz != 868.0 {
+		t.Fatalf("expected the cursor on the current channel and the radio on the first, got %d and %v MHz", device.channelScan.Cursor, radio.frequencyMHz)
+	}
+	// Leaving the scanner any other way tunes the radio back too.
+	err = device.ProcessInputEvent(InputEventOpenMainMenu)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if radio.frequencyMHz != 868.3 || device.channelScan.active {
+		t.Errorf("expected the radio to be tuned back, got %v MHz", radio.frequencyMHz)
+	}
+}
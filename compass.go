@@ -0,0 +1,62 @@
+package picodoomsdaymessenger
+
+import (
+	"fmt"
+	"math"
+)
+
+// compassLetters are the letters drawn around the compass rose, with their bearing.
+var compassLetters = []struct {
+	Letter  string
+	Bearing float64
+}{{"N", 0}, {"E", 90}, {"S", 180}, {"W", 270}}
+
+// processCompassInputEvent leaves the compass with accept, and ignores the other navigation keys.
+func processCompassInputEvent(d *Device, inputEvent InputEvent) (handled bool, err error) {
+	switch inputEvent {
+	case InputEventAccept:
+		return true, d.GoBackState()
+	case InputEventUp, InputEventDown, InputEventLeft, InputEventRight:
+		return true, nil
+	}
+	return false, nil
+}
+
+// drawCompass draws a compass rose that turns so that the top of the screen points the way the Device is facing.
+func drawCompass(d *Device, img Canvas, layout Layout) (err error) {
+	palette := d.Palette()
+	dimensions := img.Bounds()
+	if d.GetHeading == nil {
+		drawTitleBar(img, layout, palette, "Compass")
+		drawTextFace(img, layout.Face, 0, layout.TitleHeight+layout.LineHeight, "No compass", palette.StatusWarning)
+		return nil
+	}
+	heading, ok := d.GetHeading()
+	if !ok {
+		drawTitleBar(img, layout, palette, "Compass")
+		drawTextFace(img, layout.Face, 0, layout.TitleHeight+layout.LineHeight, "No reading", palette.StatusWarning)
+		return nil
+	}
+	heading = math.Mod(heading+360, 360)
+	drawTitleBar(img, layout, palette, fmt.Sprintf("Compass %03.0f %s", heading, CompassPoint(heading)))
+
+	// Fit the rose in the space below the title, with room for the letters outside it.
+	cx := dimensions.Dx() / 2
+	cy := layout.TitleHeight + (dimensions.Dy()-layout.TitleHeight)/2
+	r := (dimensions.Dy()-layout.TitleHeight)/2 - 2
+	drawCircleCol(img, cx, cy, r, palette.Text)
+	for _, l := range compassLetters {
+		angle := (l.Bearing - heading) * math.Pi / 180
+		x := cx + int(math.Round(float64(r+8)*math.Sin(angle)))
+		y := cy - int(math.Round(float64(r+8)*math.Cos(angle)))
+		drawTextCol(img, x-3, y+5, l.Letter, palette.Text)
+		if l.Letter == "N" {
+			nx := cx + int(math.Round(float64(r-2)*math.Sin(angle)))
+			ny := cy - int(math.Round(float64(r-2)*math.Cos(angle)))
+			drawLineCol(img, cx, cy, nx, ny, palette.StatusAlert)
+		}
+	}
+	// Mark the way the Device is facing.
+	drawLineCol(img, cx, cy-r, cx, cy-r+4, palette.Cursor)
+	return nil
+}
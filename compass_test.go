@@ -0,0 +1,70 @@
+package picodoomsdaymessenger
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestDrawLineCol(t *testing.T) {
+	white := color.RGBA{255, 255, 255, 255}
+	img := image.NewRGBA(image.Rect(0, 0, 10, 10))
+	drawLineCol(img, 8, 8, 1, 1, white)
+	for i := 1; i <= 8; i++ {
+		if img.RGBAAt(i, i) != white {
+			t.Errorf("expected the diagonal to be drawn at %d,%d", i, i)
+		}
+	}
+	if img.RGBAAt(0, 0) == white || img.RGBAAt(9, 9) == white {
+		t.Error("expected the line to stop at its ends")
+	}
+}
+
+func TestDrawCircleCol(t *testing.T) {
+	white := color.RGBA{255, 255, 255, 255}
+	img := image.NewRGBA(image.Rect(0, 0, 21, 21))
+	drawCircleCol(img, 10, 10, 5, white)
+	for _, p := range [][2]int{{15, 10}, {5, 10}, {10, 15}, {10, 5}} {
+		if img.RGBAAt(p[0], p[1]) != white {
+			t.Errorf("expected %v to be on the circle", p)
+		}
+	}
+	if img.RGBAAt(10, 10) == white {
+		t.Error("expected the center to be empty")
+	}
+}
+
+func TestCompass(t *testing.T) {
+	device, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	err = MainMenuItemTools.Action(device)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	err = ToolsMenuItemCompass.Action(device)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if _, err := GetFrame(image.Rect(0, 0, 128, 64), device); err != nil {
+		t.Fatalf("The error without a compass should be nil but is %v", err)
+	}
+	device.GetHeading = func() (degrees float64, ok bool) {
+		return 90, true
+	}
+	if _, err := GetFrame(image.Rect(0, 0, 128, 64), device); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	err = device.ProcessInputEvent(InputEventUp)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	err = device.ProcessInputEvent(InputEventAccept)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if device.State != &device.StateToolsMenu {
+		t.Error("expected accept to go back to the tools menu")
+	}
+}
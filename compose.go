@@ -0,0 +1,91 @@
+package picodoomsdaymessenger
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ComposeMode changes what the number keys type. It is cycled with the Star key, and shown at the right of the compose bar.
+// In the compose bar of a Conversation, Star after 123 opens the symbol picker before going back to abc.
+type ComposeMode int
+
+// Define the ComposeModes
+const (
+	// ComposeModeLower types lower case letters with multi-tap. It is the default.
+	ComposeModeLower ComposeMode = iota
+	// ComposeModeUpper types upper case letters with multi-tap.
+	ComposeModeUpper
+	// ComposeModeNumbers types the number on each key straight away, without cycling through its letters.
+	ComposeModeNumbers
+)
+
+// composeModeNames is the label of each ComposeMode, shown in the compose bar.
+var composeModeNames = [...]string{"abc", "ABC", "123"}
+
+// String returns the label of the ComposeMode, such as "abc".
+func (m ComposeMode) String() string {
+	if m < 0 || int(m) >= len(composeModeNames) {
+		return composeModeNames[ComposeModeLower]
+	}
+	return composeModeNames[m]
+}
+
+// numberKeyDigits is the digit that each number key types in ComposeModeNumbers.
+var numberKeyDigits = map[InputEvent]string{
+	InputEventNumber1: "1", InputEventNumber2: "2", InputEventNumber3: "3",
+	InputEventNumber4: "4", InputEventNumber5: "5", InputEventNumber6: "6",
+	InputEventNumber7: "7", InputEventNumber8: "8", InputEventNumber9: "9",
+	InputEventNumber0: "0",
+}
+
+// CycleComposeMode moves on to the next ComposeMode, from abc to ABC to 123 and back to abc. The character that is being typed is added to the buffer first, so that it keeps its case.
+func (d *Device) CycleComposeMode(buffer *string) {
+	*buffer += d.pendingCharacter()
+	d.clearPendingCharacter()
+	d.ComposeMode = (d.ComposeMode + 1) % ComposeMode(len(composeModeNames))
+}
+
+// typeNumberKey types with a number key in the current ComposeMode.
+func (d *Device) typeNumberKey(buffer *string, inputEvent InputEvent) {
+	if d.ComposeMode == ComposeModeNumbers {
+		*buffer += d.pendingCharacter() + numberKeyDigits[inputEvent]
+		d.clearPendingCharacter()
+		return
+	}
+	d.typeKey(buffer, d.KeyboardLayout().Buttons[inputEvent])
+}
+
+// composeModeCase changes the case of a character to match the ComposeMode.
+func (d *Device) composeModeCase(character string) string {
+	if d.ComposeMode == ComposeModeUpper {
+		return strings.ToUpper(character)
+	}
+	return character
+}
+
+// ComposerRemaining returns how many more bytes of text fit in the message in the compose bar of the current Conversation. Anything past that is cut off when it is sent.
+// It is the MessageTextLimit of the message, so it counts the signature, encryption and address that the message is sent with.
+func (d *Device) ComposerRemaining() int {
+	c := d.Conversations[d.CurrentConversationIndex]
+	return d.MessageTextLimit(d.outgoingMessage(c, "", c.Priority)) - len(d.ComposerText())
+}
+
+// ComposerAirtime estimates how long the message in the compose bar of the current Conversation would take to send with the ModemConfig in the Settings.
+func (d *Device) ComposerAirtime() time.Duration {
+	c := d.Conversations[d.CurrentConversationIndex]
+	packet, err := d.EncodeMessage(d.outgoingMessage(c, d.ComposerText(), c.Priority))
+	if err != nil {
+		return 0
+	}
+	return d.packetAirtime(len(d.addHopHeader(packet)))
+}
+
+// ComposerStatus returns the characters that are left and the airtime of the message in the compose bar, such as "201 41ms", which is shown above the compose bar while typing.
+func (d *Device) ComposerStatus() string {
+	airtime := d.ComposerAirtime()
+	if airtime < time.Second {
+		return fmt.Sprintf("%d %dms", d.ComposerRemaining(), airtime.Milliseconds())
+	}
+	return fmt.Sprintf("%d %.1fs", d.ComposerRemaining(), airtime.Seconds())
+}
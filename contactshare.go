@@ -0,0 +1,268 @@
+package picodoomsdaymessenger
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"image/color"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Define contact sharing errors
+var (
+	ErrInvalidContactCard  = errors.New("invalid contact card")
+	ErrContactKeyMismatch  = errors.New("contact has a different key to the one already trusted")
+	ErrNoSuchContact       = errors.New("no such contact")
+	ErrContactCardIsDevice = errors.New("contact card is for this device")
+)
+
+// cardPrefix is the start of every contact card, ASCII for "card".
+var cardPrefix = []byte{0x63, 0x61, 0x72, 0x64}
+
+// DeviceEventContactShared happens when a contact card is received from another device, and is waiting to be added or turned down.
+const DeviceEventContactShared DeviceEvent = "contact shared"
+
+func init() {
+	LEDNotifications[DeviceEventContactShared] = newFlashLEDAnimation(color.RGBA{0, 255, 0, 0}, 2, 150*time.Millisecond)
+}
+
+// MaxContactOffers is how many shared Contacts can wait to be added. Cards that arrive when it is full are ignored.
+const MaxContactOffers = 8
+
+// ContactOfferInterval is how long cards for the same Person are ignored after one has been received, so that a device sending the same card over and over does not keep notifying.
+const ContactOfferInterval = time.Minute
+
+// contactOffersState holds the Contacts that were shared by other devices and are waiting to be added, and when a card for each Person was last received.
+type contactOffersState struct {
+	queue []Contact
+	heard map[int]time.Time
+}
+
+// ContactToBytes creates a contact card, which is the public key of the Contact followed by their ID and name.
+// The SessionKey is never put in a card, as it is only known to the two devices that paired.
+func ContactToBytes(c Contact) (output []byte) {
+	output = append(output, cardPrefix...)
+	output = append(output, c.PublicKey...)
+	output = append(output, []byte(fmt.Sprint(c.Person.ID))...)
+	output = append(output, 0xcc)
+	output = append(output, []byte(c.Person.Name)...)
+	return output
+}
+
+// BytesToContact decodes a contact card.
+func BytesToContact(input []byte) (output Contact, err error) {
+	if !bytes.HasPrefix(input, cardPrefix) {
+		return output, ErrInvalidContactCard
+	}
+	input = input[len(cardPrefix):]
+	if len(input) < ed25519.PublicKeySize {
+		return output, ErrInvalidContactCard
+	}
+	output.PublicKey = append([]byte{}, input[:ed25519.PublicKeySize]...)
+	fields := bytes.SplitN(input[ed25519.PublicKeySize:], []byte{0xcc}, 2)
+	if len(fields) != 2 {
+		return output, ErrInvalidContactCard
+	}
+	output.Person.ID, err = strconv.Atoi(string(fields[0]))
+	if err != nil {
+		return output, ErrInvalidContactCard
+	}
+	output.Person.Name, _ = truncateString(string(fields[1]), MaxNameLength)
+	return output, nil
+}
+
+// ExportContacts writes every Contact as a contact card in base64, one per line.
+func (d *Device) ExportContacts() (output string) {
+	lines := make([]string, len(d.Contacts))
+	for i, c := range d.Contacts {
+		lines[i] = base64.StdEncoding.EncodeToString(ContactToBytes(c))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// ImportContacts adds every contact card in some text, written by ExportContacts, to the Contacts. It stops at the first card that cannot be imported.
+func (d *Device) ImportContacts(text string) (imported int, err error) {
+	for _, line := range strings.Fields(text) {
+		card, err := base64.StdEncoding.DecodeString(line)
+		if err != nil {
+			return imported, ErrInvalidContactCard
+		}
+		contact, err := BytesToContact(card)
+		if err != nil {
+			return imported, err
+		}
+		err = d.ImportContact(contact)
+		if err != nil {
+			return imported, err
+		}
+		imported++
+	}
+	return imported, nil
+}
+
+// ImportContact adds a Contact to the Contacts and trusts their key, or renames them if they are already a Contact.
+// A Contact is not imported if a different key is already trusted for their ID, so that a card cannot take the place of someone who has been paired with.
+func (d *Device) ImportContact(contact Contact) (err error) {
+	if contact.Person.ID == d.SelfIdentity.ID {
+		return ErrContactCardIsDevice
+	}
+	if pinned, ok := d.keys.pinned[contact.Person.ID]; ok && !bytes.Equal(pinned, contact.PublicKey) {
+		return ErrContactKeyMismatch
+	}
+	replaced := false
+	for i := range d.Contacts {
+		if d.Contacts[i].Person.ID == contact.Person.ID {
+			d.Contacts[i].Person.Name = contact.Person.Name
+			d.Contacts[i].PublicKey = contact.PublicKey
+			replaced = true
+		}
+	}
+	if !replaced {
+		contact.SessionKey = nil
+		d.Contacts = append(d.Contacts, contact)
+	}
+	if d.keys.pinned == nil {
+		d.keys.pinned = make(map[int]ed25519.PublicKey)
+	}
+	d.keys.pinned[contact.Person.ID] = contact.PublicKey
+	err = d.SaveKeys()
+	if err != nil {
+		return err
+	}
+	return d.SaveContacts()
+}
+
+// ShareContact sends the contact card of a Contact to every device in range.
+func (d *Device) ShareContact(index int) (err error) {
+	if index < 0 || index >= len(d.Contacts) {
+		return ErrNoSuchContact
+	}
+	return d.SendPacket(nil, ContactToBytes(d.Contacts[index]))
+}
+
+// receiveContactCard queues a Contact that was shared by another device, and notifies the user, who can add it from the people menu. The screen that is shown is left alone, so that a card cannot interrupt a message that is being written.
+// Cards for the Device, for a Contact that is already known with the same key, or for a Person that a card was received for in the last ContactOfferInterval, are ignored.
+func (d *Device) receiveContactCard(contact Contact, now time.Time) (err error) {
+	if contact.Person.ID == d.SelfIdentity.ID {
+		return nil
+	}
+	for _, known := range d.Contacts {
+		if known.Person == contact.Person && bytes.Equal(known.PublicKey, contact.PublicKey) {
+			return nil
+		}
+	}
+	if heard, ok := d.contactOffers.heard[contact.Person.ID]; ok && now.Sub(heard) < ContactOfferInterval {
+		return nil
+	}
+	if d.contactOffers.heard == nil {
+		d.contactOffers.heard = make(map[int]time.Time)
+	}
+	for id, heard := range d.contactOffers.heard {
+		if now.Sub(heard) >= ContactOfferInterval {
+			delete(d.contactOffers.heard, id)
+		}
+	}
+	d.contactOffers.heard[contact.Person.ID] = now
+	for i, offer := range d.contactOffers.queue {
+		if offer.Person.ID == contact.Person.ID {
+			d.contactOffers.queue[i] = contact
+			d.MarkDirty()
+			return nil
+		}
+	}
+	if len(d.contactOffers.queue) >= MaxContactOffers {
+		return nil
+	}
+	d.contactOffers.queue = append(d.contactOffers.queue, contact)
+	if d.State == &d.StatePeopleMenu {
+		d.UpdatePeopleMenu()
+	}
+	return d.Notify(DeviceEventContactShared)
+}
+
+// ContactOffers returns the Contacts that were shared by other devices and are waiting to be added, oldest first.
+func (d *Device) ContactOffers() []Contact {
+	return d.contactOffers.queue
+}
+
+// answerContactOffer takes the oldest shared Contact off the queue, and goes back once there are none left.
+func (d *Device) answerContactOffer() (err error) {
+	if len(d.contactOffers.queue) > 0 {
+		d.contactOffers.queue = d.contactOffers.queue[1:]
+	}
+	if len(d.contactOffers.queue) > 0 {
+		d.MarkDirty()
+		return nil
+	}
+	return d.GoBackState()
+}
+
+// The share menu lists the Contacts, so it is filled when it is shown.
+func init() {
+	stateSetups = append(stateSetups, func(s *States) {
+		s.StateShareContactMenu.LoadAction = func(d *Device) (err error) {
+			d.UpdateShareContactMenu()
+			return nil
+		}
+	})
+}
+
+// UpdateShareContactMenu fills the StateShareContactMenu with the Contacts. Choosing a Contact sends their card and goes back.
+func (d *Device) UpdateShareContactMenu() {
+	d.StateShareContactMenu.Content = []MenuItem{GlobalMenuItemGoBack}
+	for i, contact := range d.Contacts {
+		j := i
+		d.StateShareContactMenu.Content = append(d.StateShareContactMenu.Content, MenuItem{
+			Text: contact.Person.Name,
+			Action: func(d *Device) (err error) {
+				err = d.ShareContact(j)
+				if err != nil {
+					return err
+				}
+				return d.GoBackState()
+			},
+			CursorIcon: CursorIconRightArrow,
+		})
+	}
+	if d.StateShareContactMenu.HighlightedItemIndex >= len(d.StateShareContactMenu.Content) {
+		d.StateShareContactMenu.HighlightedItemIndex = 0
+	}
+}
+
+// processContactOfferInputEvent adds the oldest shared Contact with accept, or turns it down with left. The next one is shown until there are none left.
+func processContactOfferInputEvent(d *Device, inputEvent InputEvent) (handled bool, err error) {
+	switch inputEvent {
+	case InputEventAccept:
+		if len(d.contactOffers.queue) > 0 {
+			err = d.ImportContact(d.contactOffers.queue[0])
+			if err != nil && err != ErrContactKeyMismatch {
+				return true, err
+			}
+		}
+		return true, d.answerContactOffer()
+	case InputEventLeft:
+		return true, d.answerContactOffer()
+	case InputEventUp, InputEventDown, InputEventRight:
+		return true, nil
+	}
+	return false, nil
+}
+
+// drawContactOffer draws the oldest Contact that was shared, and warns if it would replace the key of someone that is already trusted.
+func drawContactOffer(d *Device, img Canvas, layout Layout) (err error) {
+	if len(d.contactOffers.queue) == 0 {
+		drawTextPage(img, layout, d.Palette(), "Shared Contact", "None left", 0)
+		return nil
+	}
+	offer := d.contactOffers.queue[0]
+	text := fmt.Sprintf("%s\nID %d\nOK to add", offer.Person.Name, offer.Person.ID)
+	if pinned, ok := d.keys.pinned[offer.Person.ID]; ok && !bytes.Equal(pinned, offer.PublicKey) {
+		text = fmt.Sprintf("%s\nID %d\nKey does not match!", offer.Person.Name, offer.Person.ID)
+	}
+	drawTextPage(img, layout, d.Palette(), "Shared Contact", text, 0)
+	return nil
+}
@@ -0,0 +1,161 @@
+package picodoomsdaymessenger
+
+import (
+	"image"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExportAndImportContacts(t *testing.T) {
+	bob := newSigningDevice(t, 20)
+	bob.SelfIdentity.Name = "Bob"
+	alice := newSigningDevice(t, 10)
+	alice.Contacts = []Contact{{Person: bob.SelfIdentity, PublicKey: bob.PublicKey(), SessionKey: []byte("secret")}}
+
+	response, err := alice.ProcessSerialCommand("contacts")
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if strings.Contains(response, "\n") {
+		t.Fatalf("expected one card per contact, got %q", response)
+	}
+	carol := newSigningDevice(t, 30)
+	response, err = carol.ProcessSerialCommand("import " + response)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if response != "ok 1" {
+		t.Errorf("expected one contact to be imported, got %q", response)
+	}
+	if len(carol.Contacts) != 1 || carol.Contacts[0].Person != bob.SelfIdentity || carol.Contacts[0].SessionKey != nil {
+		t.Fatalf("expected bob to be imported without the session key, got %+v", carol.Contacts)
+	}
+
+	// A message signed by bob is now verified, but one from someone else using his ID is not.
+	packet, err := bob.MesageToBytes(Message{Text: "hi", Person: bob.SelfIdentity})
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if m, err := carol.BytesToMessage(packet); err != nil || !m.Verified {
+		t.Errorf("expected a message from an imported contact to be verified, got %v", err)
+	}
+	spoofed := Contact{Person: bob.SelfIdentity, PublicKey: alice.PublicKey()}
+	if err = carol.ImportContact(spoofed); err != ErrContactKeyMismatch {
+		t.Errorf("expected ErrContactKeyMismatch for a different key, got %v", err)
+	}
+	if _, err = carol.ImportContacts("not-a-card"); err != ErrInvalidContactCard {
+		t.Errorf("expected ErrInvalidContactCard, got %v", err)
+	}
+}
+
+func TestShareContact(t *testing.T) {
+	bob := newSigningDevice(t, 20)
+	bob.SelfIdentity.Name = "Bob"
+	alice := newSigningDevice(t, 10)
+	alice.Contacts = []Contact{{Person: bob.SelfIdentity, PublicKey: bob.PublicKey()}}
+	carol := newSigningDevice(t, 30)
+	carol.State = &carol.StateMainMenu
+	alice.SendUsingRadio = carol.ReceiveFromRadio
+
+	err := alice.ChangeStateWithHistory(&alice.StatePeopleMenu)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if last := alice.StatePeopleMenu.Content[len(alice.StatePeopleMenu.Content)-1]; last.Text != PeopleMenuItemShare.Text {
+		t.Fatalf("expected the people menu to offer sharing, got %q", last.Text)
+	}
+	err = alice.ChangeStateWithHistory(&alice.StateShareContactMenu)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	err = alice.StateShareContactMenu.Content[1].Action(alice)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	err = sendQueued(alice)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if carol.State != &carol.StateMainMenu || len(carol.ContactOffers()) != 1 || carol.ContactOffers()[0].Person != bob.SelfIdentity {
+		t.Fatalf("expected bob to be queued without leaving the main menu, got %q and %+v", carol.State.Title, carol.ContactOffers())
+	}
+	err = carol.ChangeStateWithHistory(&carol.StatePeopleMenu)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if item := carol.StatePeopleMenu.Content[3]; item.Text != PeopleMenuItemShared.Text {
+		t.Fatalf("expected the people menu to list the shared contacts, got %q", item.Text)
+	}
+	err = carol.StatePeopleMenu.Content[3].Action(carol)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if _, err := GetFrame(image.Rect(0, 0, 128, 64), carol); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	err = carol.ProcessInputEvent(InputEventAccept)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if carol.State != &carol.StatePeopleMenu || len(carol.Contacts) != 1 || len(carol.ContactOffers()) != 0 {
+		t.Errorf("expected bob to be added and the offer closed, got %+v", carol.Contacts)
+	}
+
+	// Sharing the same contact again is ignored.
+	err = alice.ShareContact(0)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if len(carol.ContactOffers()) != 0 {
+		t.Errorf("expected a known contact not to be offered again")
+	}
+}
+
+func TestContactCardsDoNotInterrupt(t *testing.T) {
+	carol := newSigningDevice(t, 30)
+	var now time.Time
+	carol.Clock = func() time.Time { return now }
+	c := carol.NewConversation(Person{Name: "Dave", ID: 40})
+	if err := carol.OpenConversation(len(carol.Conversations) - 1); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	c.KeyboardBuffer = "half written"
+	reading := carol.State
+	events := 0
+	carol.OnDeviceEvent = func(event DeviceEvent) {
+		if event == DeviceEventContactShared {
+			events++
+		}
+	}
+
+	bob := newSigningDevice(t, 20)
+	card := ContactToBytes(Contact{Person: bob.SelfIdentity, PublicKey: bob.PublicKey()})
+	for i := 0; i < 3; i++ {
+		if err := carol.ReceiveFromRadio(card); err != nil {
+			t.Fatalf("The error should be nil but is %v", err)
+		}
+	}
+	if carol.State != reading || c.KeyboardBuffer != "half written" {
+		t.Errorf("expected a contact card not to change the screen, got %q", carol.State.Title)
+	}
+	if events != 1 || len(carol.ContactOffers()) != 1 {
+		t.Errorf("expected repeated cards to notify once, got %d notifications and %d offers", events, len(carol.ContactOffers()))
+	}
+
+	now = now.Add(ContactOfferInterval)
+	if err := carol.ReceiveFromRadio(card); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if events != 1 || len(carol.ContactOffers()) != 1 {
+		t.Errorf("expected a card already waiting to be replaced without notifying, got %d notifications and %d offers", events, len(carol.ContactOffers()))
+	}
+	for id := 100; id < 100+2*MaxContactOffers; id++ {
+		if err := carol.ReceiveFromRadio(ContactToBytes(Contact{Person: Person{Name: "Someone", ID: id}, PublicKey: bob.PublicKey()})); err != nil {
+			t.Fatalf("The error should be nil but is %v", err)
+		}
+	}
+	if len(carol.ContactOffers()) != MaxContactOffers {
+		t.Errorf("expected at most %d offers to wait, got %d", MaxContactOffers, len(carol.ContactOffers()))
+	}
+}
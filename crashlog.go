@@ -0,0 +1,214 @@
+package picodoomsdaymessenger
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// crashLogStorageKey is the key that the CrashLog is saved with.
+const crashLogStorageKey = "crash"
+
+// MaxCrashStackLength is the most bytes of the stack trace of a panic that are kept, so that the CrashLog fits in the Storage.
+const MaxCrashStackLength = 1024
+
+// CrashLog is the last panic of the firmware, kept so that it can be read after the Device is restarted.
+type CrashLog struct {
+	Message string
+	// Time is when the panic happened, by the clock of the Device.
+	Time time.Time
+	// Uptime is how long the Device had been running when it panicked.
+	Uptime time.Duration
+	// Version is the firmware version that panicked.
+	Version string
+	// State is the title of the State that was shown when the Device panicked.
+	State string
+	// Stack is the start of the stack trace of the panic, if the firmware could get one.
+	Stack string
+	// Offered is true once safe mode has been offered for the crash, so that it is only offered on the first restart.
+	Offered bool
+}
+
+// RecordCrash keeps the message and stack trace of a panic as the LastCrash, with what the Device was doing, and saves it to the Storage so that it is still there after a restart.
+func (d *Device) RecordCrash(message string, stack []byte) (err error) {
+	if len(stack) > MaxCrashStackLength {
+		stack = stack[:MaxCrashStackLength]
+	}
+	d.LastCrash = &CrashLog{Message: message, Time: d.Now(), Uptime: d.Uptime(), Version: d.Version, Stack: string(stack)}
+	if d.State != nil {
+		d.LastCrash.State = d.State.Title
+	}
+	return d.saveCrashLog()
+}
+
+// saveCrashLog writes the LastCrash to the Storage of the Device. It does nothing if the Device has no Storage.
+func (d *Device) saveCrashLog() (err error) {
+	if d.Storage == nil {
+		return nil
+	}
+	data, err := json.Marshal(d.LastCrash)
+	if err != nil {
+		return err
+	}
+	return d.Storage.Save(crashLogStorageKey, data)
+}
+
+// LoadCrashLog reads the LastCrash from the Storage of the Device, if there was one.
+func (d *Device) LoadCrashLog() (err error) {
+	if d.Storage == nil {
+		return nil
+	}
+	data, err := d.Storage.Load(crashLogStorageKey)
+	if err == ErrStorageNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	crash := &CrashLog{}
+	err = json.Unmarshal(data, crash)
+	if err != nil {
+		return err
+	}
+	if crash.Message != "" {
+		d.LastCrash = crash
+	}
+	return nil
+}
+
+// ClearCrashLog forgets the LastCrash. An empty CrashLog is saved, as a Storage cannot delete keys.
+func (d *Device) ClearCrashLog() (err error) {
+	d.LastCrash = nil
+	if d.Storage == nil {
+		return nil
+	}
+	data, err := json.Marshal(CrashLog{})
+	if err != nil {
+		return err
+	}
+	return d.Storage.Save(crashLogStorageKey, data)
+}
+
+// crashLogText returns the LastCrash as it is shown in the crash viewer.
+func (d *Device) crashLogText() string {
+	if d.LastCrash == nil {
+		return "No crash"
+	}
+	crash := d.LastCrash
+	text := fmt.Sprintf("%s\nUp %s\n%s", crash.Time.Format("2006-01-02 15:04"), crash.Uptime.Truncate(time.Second), crash.Message)
+	if crash.Version != "" {
+		text += "\nVersion " + crash.Version
+	}
+	if crash.State != "" {
+		text += "\nIn " + crash.State
+	}
+	if crash.Stack != "" {
+		text += "\n" + crash.Stack
+	}
+	return text + "\nLeft to clear"
+}
+
+// The crash viewer updates the Tools menu when the crash is cleared, and the Tools menu leads to the crash viewer, so its InputHandler is set here.
+func init() {
+	stateSetups = append(stateSetups, func(s *States) {
+		s.StateCrashLog.InputHandler = processCrashLogInputEvent
+	})
+}
+
+// processCrashLogInputEvent scrolls the crash with up and down, clears it with left and goes back with accept.
+func processCrashLogInputEvent(d *Device, inputEvent InputEvent) (handled bool, err error) {
+	switch inputEvent {
+	case InputEventAccept:
+		d.crashScroll = 0
+		d.UpdateToolsMenu()
+		return true, d.GoBackState()
+	case InputEventLeft:
+		d.crashScroll = 0
+		err = d.ClearCrashLog()
+		if err != nil {
+			return true, err
+		}
+		d.UpdateToolsMenu()
+		return true, d.GoBackState()
+	}
+	return scrollTextPage(&d.crashScroll, inputEvent, d.crashLogText(), d.Layout()), nil
+}
+
+// drawCrashLog draws the LastCrash.
+func drawCrashLog(d *Device, img Canvas, layout Layout) (err error) {
+	drawTextPage(img, layout, d.Palette(), "Last Crash", d.crashLogText(), d.crashScroll)
+	return nil
+}
+
+// Boot loads everything that is saved in the Storage of the Device. If the Device crashed the last time it was running, safe mode is offered first, and nothing else is loaded until the user has chosen.
+func (d *Device) Boot() (err error) {
+	err = d.LoadCrashLog()
+	if err != nil {
+		return err
+	}
+	if d.LastCrash != nil && !d.LastCrash.Offered {
+		return d.ChangeStateWithoutHistory(&d.StateSafeModeOffer)
+	}
+	return d.LoadState()
+}
+
+// StartSafeMode runs the Device without anything that was saved, in case it caused the crash. The Storage is let go of, so that nothing is saved over until the Device is restarted.
+func (d *Device) StartSafeMode() (err error) {
+	err = d.crashOffered()
+	if err != nil {
+		return err
+	}
+	d.SafeMode = true
+	d.Storage = nil
+	return nil
+}
+
+// StartNormally loads everything that was saved, after safe mode has been turned down.
+func (d *Device) StartNormally() (err error) {
+	err = d.crashOffered()
+	if err != nil {
+		return err
+	}
+	err = d.LoadState()
+	if err != nil {
+		return err
+	}
+	return d.ApplyRadioSettings()
+}
+
+// crashOffered saves that safe mode has been offered for the LastCrash.
+func (d *Device) crashOffered() (err error) {
+	if d.LastCrash == nil {
+		return nil
+	}
+	d.LastCrash.Offered = true
+	return d.saveCrashLog()
+}
+
+// processSafeModeOfferInputEvent starts in safe mode with accept, or normally with left.
+func processSafeModeOfferInputEvent(d *Device, inputEvent InputEvent) (handled bool, err error) {
+	switch inputEvent {
+	case InputEventAccept:
+		err = d.StartSafeMode()
+	case InputEventLeft:
+		err = d.StartNormally()
+	case InputEventUp, InputEventDown, InputEventRight:
+		return true, nil
+	default:
+		return false, nil
+	}
+	if err != nil {
+		return true, err
+	}
+	return true, d.ChangeStateWithoutHistory(&d.StateMainMenu)
+}
+
+// drawSafeModeOffer draws the crash, and asks whether to start in safe mode.
+func drawSafeModeOffer(d *Device, img Canvas, layout Layout) (err error) {
+	message := ""
+	if d.LastCrash != nil {
+		message = d.LastCrash.Message
+	}
+	drawTextPage(img, layout, d.Palette(), "Crashed", message+"\nOK: safe mode\nLeft: start normally", 0)
+	return nil
+}
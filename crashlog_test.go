@@ -0,0 +1,169 @@
+package picodoomsdaymessenger
+
+import (
+	"image"
+	"strings"
+	"testing"
+)
+
+func TestCrashLog(t *testing.T) {
+	device, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	storage := MemoryStorage{}
+	device.Storage = storage
+	device.UpdateToolsMenu()
+	if last := device.StateToolsMenu.Content[len(device.StateToolsMenu.Content)-1]; last.Text == ToolsMenuItemLastCrash.Text {
+		t.Fatalf("expected no crash viewer before a crash")
+	}
+	err = device.RecordCrash("panic: out of memory", nil)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+
+	restarted, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	restarted.Storage = storage
+	err = restarted.LoadCrashLog()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if restarted.LastCrash == nil || restarted.LastCrash.Message != "panic: out of memory" {
+		t.Fatalf("expected the crash to be loaded, got %+v", restarted.LastCrash)
+	}
+	restarted.State = &restarted.StateMainMenu
+	err = MainMenuItemTools.Action(restarted)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	last := restarted.StateToolsMenu.Content[len(restarted.StateToolsMenu.Content)-1]
+	if last.Text != ToolsMenuItemLastCrash.Text {
+		t.Fatalf("expected the crash viewer in the tools menu, got %q", last.Text)
+	}
+	err = last.Action(restarted)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if _, err := GetFrame(image.Rect(0, 0, 128, 64), restarted); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+
+	// Left clears the crash, and it stays cleared after another restart.
+	err = restarted.ProcessInputEvent(InputEventLeft)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if restarted.State != &restarted.StateToolsMenu || restarted.LastCrash != nil {
+		t.Errorf("expected the crash to be cleared")
+	}
+	if device.StateToolsMenu.Content[len(device.StateToolsMenu.Content)-1].Text == ToolsMenuItemLastCrash.Text {
+		t.Errorf("expected the crash viewer to be removed from the tools menu")
+	}
+	device.LastCrash = nil
+	err = device.LoadCrashLog()
+	if err != nil || device.LastCrash != nil {
+		t.Errorf("expected the cleared crash not to be loaded, got %+v", device.LastCrash)
+	}
+}
+
+func TestSafeMode(t *testing.T) {
+	storage := MemoryStorage{}
+	device, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	device.Storage = storage
+	device.Version = "v1.2.3"
+	device.State = &device.StateMainMenu
+	err = device.SetName("Alice")
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	err = device.RecordCrash("panic: bad contact", []byte(strings.Repeat("goroutine 1 [running]\n", 100)))
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if crash := device.LastCrash; crash.State != "Main Menu" || crash.Version != "v1.2.3" || len(crash.Stack) != MaxCrashStackLength {
+		t.Errorf("expected what the device was doing to be kept, got %+v", crash)
+	}
+
+	// Safe mode is offered on the first restart after the crash, before anything else is loaded.
+	restarted, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	restarted.Storage = storage
+	err = restarted.Boot()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if restarted.State != &restarted.StateSafeModeOffer || restarted.SelfIdentity.Name == "Alice" {
+		t.Fatalf("expected safe mode to be offered before the name is loaded, got %q and %q", restarted.State.Title, restarted.SelfIdentity.Name)
+	}
+	if _, err := GetFrame(image.Rect(0, 0, 128, 64), restarted); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	err = restarted.ProcessInputEvent(InputEventAccept)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if !restarted.SafeMode || restarted.Storage != nil || restarted.State != &restarted.StateMainMenu || restarted.SelfIdentity.Name == "Alice" {
+		t.Errorf("expected safe mode to start without the storage")
+	}
+	if !strings.Contains(restarted.AboutText(), "Safe mode") {
+		t.Errorf("expected the about screen to show safe mode")
+	}
+
+	// It is only offered once for each crash.
+	again, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	again.Storage = storage
+	err = again.Boot()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if again.State == &again.StateSafeModeOffer || again.SelfIdentity.Name != "Alice" || again.LastCrash == nil {
+		t.Errorf("expected to start normally and keep the crash, got %q and %q", again.State.Title, again.SelfIdentity.Name)
+	}
+}
+
+func TestStartNormallyAfterCrash(t *testing.T) {
+	storage := MemoryStorage{}
+	device, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	device.Storage = storage
+	err = device.SetName("Alice")
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	err = device.RecordCrash("panic: broken", nil)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	restarted, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	restarted.Storage = storage
+	err = restarted.Boot()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	err = restarted.ProcessInputEvent(InputEventLeft)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if restarted.SafeMode || restarted.SelfIdentity.Name != "Alice" || restarted.State != &restarted.StateMainMenu {
+		t.Errorf("expected everything to be loaded, got %q", restarted.SelfIdentity.Name)
+	}
+	if !restarted.LastCrash.Offered {
+		t.Errorf("expected the crash to be marked as offered")
+	}
+}
@@ -0,0 +1,81 @@
+package picodoomsdaymessenger
+
+import (
+	"image"
+	"image/draw"
+)
+
+// DirtyTracker remembers the last frame that was shown, so that only the parts of the next frame that are different need to be sent to the screen.
+// Changes are reported in bands of BandHeight rows, so with a BandHeight of PageHeight each rectangle is part of one page of an SSD1306.
+type DirtyTracker struct {
+	// BandHeight is the height of the rectangles that changes are reported in.
+	BandHeight int
+	// previous is a copy of the last frame.
+	previous *image.RGBA
+	// converted holds frames that are not already RGBA.
+	converted *image.RGBA
+}
+
+// NewDirtyTracker creates a DirtyTracker that reports changes in bands of rows, such as PageHeight.
+func NewDirtyTracker(bandHeight int) *DirtyTracker {
+	if bandHeight < 1 {
+		bandHeight = 1
+	}
+	return &DirtyTracker{BandHeight: bandHeight}
+}
+
+// Changed returns the rectangles of a frame that are different to the last frame passed to Changed, and remembers the frame for next time.
+// Each rectangle is one band high and as wide as the changes in that band. The first frame, and any frame of a different size, is changed everywhere.
+func (t *DirtyTracker) Changed(frame image.Image) (rects []image.Rectangle) {
+	current := t.rgba(frame)
+	bounds := current.Bounds()
+	if t.previous == nil || t.previous.Bounds() != bounds {
+		t.previous = image.NewRGBA(bounds)
+		copy(t.previous.Pix, current.Pix)
+		for y := bounds.Min.Y; y < bounds.Max.Y; y += t.BandHeight {
+			rects = append(rects, image.Rect(bounds.Min.X, y, bounds.Max.X, y+t.BandHeight).Intersect(bounds))
+		}
+		return rects
+	}
+	for top := bounds.Min.Y; top < bounds.Max.Y; top += t.BandHeight {
+		minX, maxX := bounds.Max.X, bounds.Min.X
+		for y := top; y < top+t.BandHeight && y < bounds.Max.Y; y++ {
+			row := current.PixOffset(bounds.Min.X, y)
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				i := row + (x-bounds.Min.X)*4
+				if current.Pix[i] == t.previous.Pix[i] && current.Pix[i+1] == t.previous.Pix[i+1] && current.Pix[i+2] == t.previous.Pix[i+2] && current.Pix[i+3] == t.previous.Pix[i+3] {
+					continue
+				}
+				if x < minX {
+					minX = x
+				}
+				if x > maxX {
+					maxX = x
+				}
+			}
+		}
+		if minX > maxX {
+			continue
+		}
+		rects = append(rects, image.Rect(minX, top, maxX+1, top+t.BandHeight).Intersect(bounds))
+	}
+	copy(t.previous.Pix, current.Pix)
+	return rects
+}
+
+// Reset forgets the last frame, so that the whole of the next frame is changed. Use it after the screen has been cleared by something else.
+func (t *DirtyTracker) Reset() {
+	t.previous = nil
+}
+
+// rgba returns a frame as an RGBA image, converting it into a reused buffer if it is not one already.
+func (t *DirtyTracker) rgba(frame image.Image) *image.RGBA {
+	if rgba, ok := frame.(*image.RGBA); ok {
+		return rgba
+	}
+	if t.converted == nil || t.converted.Bounds() != frame.Bounds() {
+		t.converted = image.NewRGBA(frame.Bounds())
+	}
+	draw.Draw(t.converted, frame.Bounds(), frame, frame.Bounds().Min, draw.Src)
+	return t.converted
+}
@@ -0,0 +1,52 @@
+package picodoomsdaymessenger
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"reflect"
+	"testing"
+)
+
+func TestDirtyTracker(t *testing.T) {
+	tracker := NewDirtyTracker(PageHeight)
+	frame := image.NewRGBA(image.Rect(0, 0, 128, 64))
+	draw.Draw(frame, frame.Bounds(), image.Black, image.Point{}, draw.Src)
+	if rects := tracker.Changed(frame); len(rects) != 8 || rects[7] != image.Rect(0, 56, 128, 64) {
+		t.Fatalf("expected every page to change the first time, got %v", rects)
+	}
+	if rects := tracker.Changed(frame); len(rects) != 0 {
+		t.Errorf("expected nothing to change, got %v", rects)
+	}
+
+	frame.Set(5, 3, color.White)
+	frame.Set(20, 7, color.White)
+	frame.Set(100, 60, color.White)
+	want := []image.Rectangle{image.Rect(5, 0, 21, 8), image.Rect(100, 56, 101, 64)}
+	if rects := tracker.Changed(frame); !reflect.DeepEqual(rects, want) {
+		t.Errorf("expected %v to change, got %v", want, rects)
+	}
+
+	// Frames that are not RGBA are compared too.
+	gray := image.NewGray(frame.Bounds())
+	gray.Set(100, 60, color.White)
+	if rects := tracker.Changed(gray); !reflect.DeepEqual(rects, []image.Rectangle{image.Rect(5, 0, 21, 8)}) {
+		t.Errorf("expected only the first page to change, got %v", rects)
+	}
+
+	tracker.Reset()
+	if rects := tracker.Changed(frame); len(rects) != 8 {
+		t.Errorf("expected every page to change after a reset, got %v", rects)
+	}
+}
+
+func TestPageBufferDrawImageRect(t *testing.T) {
+	frame := image.NewRGBA(image.Rect(0, 0, 128, 64))
+	frame.Set(5, 3, color.White)
+	frame.Set(50, 3, color.White)
+	screen := NewPageBuffer(128, 64)
+	screen.DrawImageRect(frame, image.Rect(0, 0, 10, 8))
+	if !screen.GetPixel(5, 3) || screen.GetPixel(50, 3) {
+		t.Errorf("expected only the pixels inside the rectangle to be drawn")
+	}
+}
@@ -0,0 +1,170 @@
+package picodoomsdaymessenger
+
+import (
+	"errors"
+	"image"
+	"time"
+
+	"golang.org/x/image/font/basicfont"
+)
+
+// ErrNoDisplay is returned by Render when no Display has been set.
+var ErrNoDisplay = errors.New("no display is set")
+
+// DisplayCapabilities describes what a screen can do, so that frames can be laid out and sent to it in the best way.
+type DisplayCapabilities struct {
+	// Width is the width of the screen in pixels.
+	Width int
+	// Height is the height of the screen in pixels.
+	Height int
+	// ColorDepth is the number of bits per pixel. Monochrome screens have a ColorDepth of 1.
+	ColorDepth int
+	// PartialUpdate is true if the screen can update only the parts of it that have changed.
+	PartialUpdate bool
+	// SlowRefresh is true for screens that take a long time to redraw, such as e-paper. Animations are reduced on these screens.
+	SlowRefresh bool
+	// MinRefreshInterval is the shortest time between frames that the screen should be sent.
+	MinRefreshInterval time.Duration
+}
+
+// Bounds returns the rectangle that frames for the screen should be drawn in.
+func (c DisplayCapabilities) Bounds() image.Rectangle {
+	return image.Rect(0, 0, c.Width, c.Height)
+}
+
+// Display is a screen that a Device can render itself on. See Device.Render.
+type Display interface {
+	// Size returns the width and height of the screen in pixels.
+	Size() (width, height int)
+	// DrawFrame sends a frame to the screen.
+	DrawFrame(frame image.Image) (err error)
+}
+
+// Displayer is a Display that also describes what else it can do.
+type Displayer interface {
+	Display
+	// Capabilities returns what the screen can do.
+	Capabilities() DisplayCapabilities
+}
+
+// Define the capabilities of the supported screens.
+var (
+	// DisplaySSD1306 is a 0.96" 128x64 SSD1306 OLED, the screen that the Device was designed for.
+	DisplaySSD1306 = DisplayCapabilities{Width: 128, Height: 64, ColorDepth: 1, PartialUpdate: true}
+	// DisplaySSD1306x32 is a 0.91" 128x32 SSD1306 OLED, which gets a Compact Layout.
+	DisplaySSD1306x32 = DisplayCapabilities{Width: 128, Height: 32, ColorDepth: 1, PartialUpdate: true}
+	// DisplaySH1106 is a 1.3" 128x64 SH1106 OLED.
+	DisplaySH1106 = DisplayCapabilities{Width: 128, Height: 64, ColorDepth: 1, PartialUpdate: true}
+	// DisplayST7789 is a 1.3" 240x240 ST7789 color LCD.
+	DisplayST7789 = DisplayCapabilities{Width: 240, Height: 240, ColorDepth: 16, PartialUpdate: true}
+	// DisplayEPaper is a 2.13" 250x122 e-paper screen, which takes a few seconds to refresh.
+	DisplayEPaper = DisplayCapabilities{Width: 250, Height: 122, ColorDepth: 1, SlowRefresh: true, MinRefreshInterval: 2 * time.Second}
+)
+
+// SetDisplay attaches the screen that Render draws on. The Display capabilities of the Device are taken from it if it is a Displayer, or from its Size if it is not.
+func (d *Device) SetDisplay(display Display) {
+	d.screen = display
+	if displayer, ok := display.(Displayer); ok {
+		d.Display = displayer.Capabilities()
+		return
+	}
+	d.Display.Width, d.Display.Height = display.Size()
+}
+
+// Render draws the state of the Device and sends it to the Display set with SetDisplay.
+// The same frame is drawn over every time, as there is not enough memory on the Pico to allocate a new one for each frame.
+// Monochrome screens get a MonoImage, which is an eighth of the size and can be sent to the screen as it is.
+func (d *Device) Render() (err error) {
+	if d.screen == nil {
+		return ErrNoDisplay
+	}
+	if d.frame == nil || d.frame.Bounds() != d.Display.Bounds() {
+		if d.Display.ColorDepth == 1 {
+			d.frame = NewMonoImage(d.Display.Bounds())
+		} else {
+			d.frame = image.NewRGBA(d.Display.Bounds())
+		}
+	}
+	err = RenderFrame(d.frame, d)
+	if err != nil {
+		return err
+	}
+	return d.screen.DrawFrame(d.frame)
+}
+
+// MarkDirty records that something shown on the screen has changed, so the next frame has to be drawn.
+func (d *Device) MarkDirty() {
+	d.dirty = true
+}
+
+// NeedsRedraw returns true if anything has changed since the last frame was drawn by RenderFrame or GetFrame.
+// Screens drawn by a State's Draw function can show live values such as the clock or a game, so they always need redrawing.
+func (d *Device) NeedsRedraw() bool {
+	return d.dirty || d.State.Draw != nil
+}
+
+// ReducedAnimation returns true if the screen of the Device is slow to refresh, so anything that changes quickly should not be drawn.
+func (d *Device) ReducedAnimation() bool {
+	return d.Display.SlowRefresh
+}
+
+// Layout holds the positions of the parts of a frame, worked out from the size of the screen and the font face.
+type Layout struct {
+	// Face is the font face that text is drawn in.
+	Face *basicfont.Face
+	// Columns is the number of characters that fit across the screen.
+	Columns int
+	// TextHeight is the height of text above its baseline, which the boxes behind highlighted lines and messages cover.
+	TextHeight int
+	// TitleHeight is the height of the title bar at the top of the screen.
+	TitleHeight int
+	// TitleBaseline is the y position of the bottom of the title text.
+	TitleBaseline int
+	// LineHeight is the distance between lines of a menu or conversation.
+	LineHeight int
+	// HighlightBaseline is the y position of the bottom of the text of the highlighted line, which is kept in the middle of the space below the title.
+	HighlightBaseline int
+	// ComposerTop is the y position of the line above the compose bar.
+	ComposerTop int
+	// ComposerBaseline is the y position of the bottom of the compose bar text.
+	ComposerBaseline int
+	// MessageBaseline is the y position of the bottom of the highlighted message of a conversation, above the compose bar.
+	MessageBaseline int
+	// Compact is true if the screen is too short to show a title, a message and the compose bar at once. The conversation reader leaves out its title.
+	Compact bool
+}
+
+// NewLayout works out the Layout of frames for a screen size, with text in the 7x13 font. On a 128x64 screen the highlighted line is at 43 and the compose bar starts at 48.
+// Screens shorter than 48 pixels, such as 128x32 OLEDs, get a Compact layout with a thinner title bar and a compose bar at the very bottom.
+func NewLayout(dimensions image.Rectangle) Layout {
+	return NewFaceLayout(dimensions, FaceNormal)
+}
+
+// NewFaceLayout works out the Layout of frames for a screen size, with every position worked out from the size of a font face.
+func NewFaceLayout(dimensions image.Rectangle, face *basicfont.Face) Layout {
+	l := Layout{
+		Face:          face,
+		Columns:       dimensions.Dx() / face.Advance,
+		TextHeight:    face.Ascent - 1,
+		TitleHeight:   face.Height + 3,
+		TitleBaseline: face.Ascent + 2,
+		LineHeight:    face.Ascent + 1,
+	}
+	if dimensions.Dy() < 3*face.Height+9 {
+		l.Compact = true
+		l.TitleHeight = face.Height - 1
+		l.TitleBaseline = face.Ascent - 1
+	}
+	l.HighlightBaseline = l.TitleHeight + (dimensions.Dy()-l.TitleHeight)/2 + face.Ascent/2 - 2
+	l.ComposerTop = (dimensions.Dy() * 75) / 100
+	if l.Compact {
+		l.ComposerTop = dimensions.Dy() - face.Height - 1
+	}
+	l.ComposerBaseline = l.ComposerTop + face.Height
+	// The newest message sits on the highlighted line, unless the compose bar would cover it.
+	l.MessageBaseline = l.HighlightBaseline
+	if l.MessageBaseline > l.ComposerTop-face.Descent-3 {
+		l.MessageBaseline = l.ComposerTop - face.Descent - 3
+	}
+	return l
+}
@@ -0,0 +1,163 @@
+package picodoomsdaymessenger
+
+import (
+	"image"
+	"testing"
+)
+
+func TestNewLayout(t *testing.T) {
+	layout := NewLayout(DisplaySSD1306.Bounds())
+	if layout.HighlightBaseline != 43 || layout.ComposerTop != 48 || layout.ComposerBaseline != 61 {
+		t.Errorf("The 128x64 layout is not correct, have: %+v", layout)
+	}
+	layout = NewLayout(DisplayEPaper.Bounds())
+	if layout.HighlightBaseline != 72 || layout.ComposerTop != 91 {
+		t.Errorf("The 250x122 layout is not correct, have: %+v", layout)
+	}
+	layout = NewLayout(image.Rect(0, 0, 256, 64))
+	expected := NewLayout(DisplaySSD1306.Bounds())
+	expected.Columns = 256 / characterWidth
+	if layout != expected {
+		t.Errorf("A wider screen should have the same layout as a 128x64 screen, have: %+v", layout)
+	}
+	layout = NewLayout(DisplaySSD1306x32.Bounds())
+	if !layout.Compact || layout.ComposerBaseline > 32 || layout.MessageBaseline > layout.ComposerTop || layout.HighlightBaseline-10 < layout.TitleHeight {
+		t.Errorf("The 128x32 layout is not correct, have: %+v", layout)
+	}
+}
+
+func TestGetErrorFrameWraps(t *testing.T) {
+	frame, err := GetErrorFrame(image.Rect(0, 0, 256, 64), nil, "abc")
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	// "FATAL ERR: abc" fits on one line of a wide screen, so the second line is empty.
+	for x := 0; x < 256; x++ {
+		for y := 14; y < 27; y++ {
+			if r, _, _, _ := frame.At(x, y).RGBA(); r != 0 {
+				t.Fatalf("The error should be on one line, but (%d, %d) is lit", x, y)
+			}
+		}
+	}
+}
+
+func TestGetFrameSizes(t *testing.T) {
+	// Create a new Machine
+	device, err := NewDevice()
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	for _, display := range []DisplayCapabilities{DisplaySSD1306, DisplaySH1106, DisplayEPaper, DisplaySSD1306x32, {Width: 256, Height: 64, ColorDepth: 1}} {
+		device.Display = display
+		frame, err := GetFrame(display.Bounds(), device)
+		if err != nil {
+			t.Errorf("The error should be nil but is %v", err)
+		}
+		if frame.Bounds() != image.Rect(0, 0, display.Width, display.Height) {
+			t.Errorf("The frame should be %vx%v but is %v", display.Width, display.Height, frame.Bounds())
+		}
+	}
+}
+
+func TestReducedAnimationHidesKeyboardHint(t *testing.T) {
+	// Create a new Machine
+	device, err := NewDevice()
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	device.Conversations = []*Conversation{{Name: "Test"}}
+	device.State = &device.StateConversationReader
+	device.CurrentKeyboardButton = &KeyboardButton{Characters: []string{"a", "b", "c"}}
+	device.Display = DisplayEPaper
+	frame, err := GetFrame(DisplayEPaper.Bounds(), device)
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	layout := NewLayout(DisplayEPaper.Bounds())
+	r, _, _, _ := frame.At(0, layout.ComposerTop-14).RGBA()
+	if r != 0 {
+		t.Errorf("The keyboard hint should not be drawn on a slow display")
+	}
+}
+
+type fakeDisplay struct {
+	frames []image.Image
+}
+
+func (f *fakeDisplay) Size() (width, height int) {
+	return 128, 32
+}
+
+func (f *fakeDisplay) DrawFrame(frame image.Image) (err error) {
+	f.frames = append(f.frames, frame)
+	return nil
+}
+
+func TestRender(t *testing.T) {
+	// Create a new Machine
+	device, err := NewDevice()
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	if err = device.Render(); err != ErrNoDisplay {
+		t.Errorf("Render without a display should return ErrNoDisplay, got %v", err)
+	}
+	display := &fakeDisplay{}
+	device.SetDisplay(display)
+	if device.Display.Width != 128 || device.Display.Height != 32 {
+		t.Errorf("The display size should be taken from Size, have %dx%d", device.Display.Width, device.Display.Height)
+	}
+	for i := 0; i < 2; i++ {
+		if err = device.Render(); err != nil {
+			t.Errorf("The error should be nil but is %v", err)
+		}
+	}
+	if len(display.frames) != 2 {
+		t.Fatalf("Expected 2 frames, got %d", len(display.frames))
+	}
+	if display.frames[0] != display.frames[1] {
+		t.Errorf("The frame should be reused between renders")
+	}
+	if display.frames[0].Bounds() != image.Rect(0, 0, 128, 32) {
+		t.Errorf("The frame should be 128x32 but is %v", display.frames[0].Bounds())
+	}
+}
+
+func TestNeedsRedraw(t *testing.T) {
+	device, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	device.State = &device.StateMainMenu
+	defer func() { device.StateMainMenu.HighlightedItemIndex = 0 }()
+	if !device.NeedsRedraw() {
+		t.Error("A new Device should need drawing")
+	}
+	if _, err = GetFrame(DisplaySSD1306.Bounds(), device); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if device.NeedsRedraw() {
+		t.Error("A Device should not need drawing straight after a frame")
+	}
+	if err = device.ProcessInputEvent(InputEventDown); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if !device.NeedsRedraw() {
+		t.Error("A Device should need drawing after input")
+	}
+	if _, err = GetFrame(DisplaySSD1306.Bounds(), device); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	// A received packet does not change the State, but can be shown, even if it is rejected.
+	_ = device.ReceiveFromRadio([]byte("not a packet"))
+	if !device.NeedsRedraw() {
+		t.Error("A Device should need drawing after a packet is received")
+	}
+	if _, err = GetFrame(DisplaySSD1306.Bounds(), device); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	device.MarkDirty()
+	if !device.NeedsRedraw() {
+		t.Error("A Device should need drawing after MarkDirty")
+	}
+}
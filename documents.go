@@ -0,0 +1,99 @@
+package picodoomsdaymessenger
+
+// Document is a read-only text that is compiled into the firmware.
+type Document struct {
+	Title string
+	Text  string
+}
+
+// documentState is the Document that is open and how far through it has been scrolled.
+type documentState struct {
+	Current int
+	Scroll  int
+}
+
+// Documents is the list of Documents that can be read from the Survival Guide. Firmware can add its own to it.
+var Documents = []Document{
+	{
+		Title: "First Aid",
+		Text: "Check for danger before helping anyone.\n" +
+			"Response: talk to them and gently shake their shoulders.\n" +
+			"Airway: tilt the head back and lift the chin.\n" +
+			"Breathing: look, listen and feel for 10 seconds.\n" +
+			"Not breathing: give 30 chest compressions in the centre of the chest, 5-6cm deep, 100-120 per minute, then 2 rescue breaths. Keep going until help arrives.\n" +
+			"Breathing: put them in the recovery position and keep checking.\n" +
+			"Bleeding: press firmly on the wound with a clean pad and raise it above the heart. If blood soaks through, add more padding on top.\n" +
+			"Burns: cool under clean running water for 20 minutes, then cover loosely with cling film or a clean plastic bag.\n" +
+			"Shock: lay them down, raise their legs and keep them warm.",
+	},
+	{
+		Title: "Knots",
+		Text: "Reef knot: joins two ropes of the same size. Right over left and under, then left over right and under.\n" +
+			"Bowline: a fixed loop that will not slip. Make a small loop, pass the end up through it, around the standing part, and back down through the loop.\n" +
+			"Clove hitch: ties a rope to a post. Wrap the post, cross over the first turn, wrap again and tuck the end under the cross.\n" +
+			"Sheet bend: joins two ropes of different sizes. Make a bight in the thicker rope, pass the thinner end up through it, around both sides and under itself.\n" +
+			"Taut-line hitch: an adjustable loop for guy lines. Wrap the end twice around the standing part inside the loop, then once outside.",
+	},
+	{
+		Title: "Water",
+		Text: "Choose the clearest water you can find, flowing rather than still.\n" +
+			"Filter cloudy water through a clean cloth first.\n" +
+			"Boiling: bring to a rolling boil for at least 1 minute, or 3 minutes above 2000m.\n" +
+			"Bleach: use plain unscented household bleach. Add 2 drops per litre, stir and wait 30 minutes. It should smell slightly of chlorine; if not, repeat and wait another 15 minutes.\n" +
+			"Tablets: follow the instructions on the packet.\n" +
+			"Sunlight: fill clear plastic bottles with clear water and leave in full sun for 6 hours, or 2 days if cloudy.\n" +
+			"An adult needs about 3 litres a day, more in the heat.",
+	},
+}
+
+// documentMenuItems creates a MenuItem for every Document in Documents.
+func documentMenuItems() (items []MenuItem) {
+	for i := range Documents {
+		j := i
+		items = append(items, MenuItem{
+			Text: Documents[j].Title,
+			Action: func(d *Device) (err error) {
+				d.document = documentState{Current: j}
+				return d.ChangeStateWithHistory(&d.StateDocumentViewer)
+			},
+			CursorIcon: CursorIconRightArrow,
+		})
+	}
+	return items
+}
+
+// documentPageLines is the number of lines of a Document that fit on the screen at once.
+func (d *Device) documentPageLines() int {
+	layout := d.Layout()
+	lines := (d.Display.Bounds().Dy() - layout.TitleHeight) / layout.LineHeight
+	if lines < 1 {
+		return 1
+	}
+	return lines
+}
+
+// processDocumentViewerInputEvent scrolls the open Document a line at a time with up and down, and a page at a time with left and right. Accept goes back.
+func processDocumentViewerInputEvent(d *Device, inputEvent InputEvent) (handled bool, err error) {
+	text := Documents[d.document.Current].Text
+	switch inputEvent {
+	case InputEventAccept:
+		return true, d.GoBackState()
+	case InputEventLeft, InputEventRight:
+		step := InputEventUp
+		if inputEvent == InputEventRight {
+			step = InputEventDown
+		}
+		for i := 0; i < d.documentPageLines(); i++ {
+			scrollTextPage(&d.document.Scroll, step, text, d.Layout())
+		}
+		return true, nil
+	}
+	return scrollTextPage(&d.document.Scroll, inputEvent, text, d.Layout()), nil
+}
+
+// drawDocumentViewer draws the open Document.
+func drawDocumentViewer(d *Device, img Canvas, layout Layout) (err error) {
+	document := Documents[d.document.Current]
+	drawTextPage(img, layout, d.Palette(), document.Title, document.Text, d.document.Scroll)
+	return nil
+}
@@ -0,0 +1,66 @@
+package picodoomsdaymessenger
+
+import (
+	"image"
+	"testing"
+)
+
+func TestDocumentViewer(t *testing.T) {
+	device, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	err = ToolsMenuItemSurvivalGuide.Action(device)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if len(device.StateSurvivalGuideMenu.Content) != len(Documents)+1 {
+		t.Fatalf("expected every document to be listed, got %d items", len(device.StateSurvivalGuideMenu.Content))
+	}
+	err = device.StateSurvivalGuideMenu.Content[1].Action(device)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if device.State != &device.StateDocumentViewer {
+		t.Fatalf("expected the document to be open")
+	}
+
+	err = device.ProcessInputEvent(InputEventUp)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if device.document.Scroll != 0 {
+		t.Errorf("expected scrolling up at the top to do nothing, got %d", device.document.Scroll)
+	}
+	err = device.ProcessInputEvent(InputEventDown)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if device.document.Scroll != 1 {
+		t.Errorf("expected to scroll down a line, got %d", device.document.Scroll)
+	}
+	err = device.ProcessInputEvent(InputEventRight)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if device.document.Scroll != 1+device.documentPageLines() {
+		t.Errorf("expected to scroll down a page, got %d", device.document.Scroll)
+	}
+	for i := 0; i < 100; i++ {
+		device.ProcessInputEvent(InputEventRight)
+	}
+	lines := wrapText(Documents[0].Text, 128/characterWidth)
+	if device.document.Scroll != len(lines)-1 {
+		t.Errorf("expected to stop at the last line %d, got %d", len(lines)-1, device.document.Scroll)
+	}
+	if _, err := GetFrame(image.Rect(0, 0, 128, 64), device); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	err = device.ProcessInputEvent(InputEventAccept)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if device.State != &device.StateSurvivalGuideMenu {
+		t.Errorf("expected accept to go back to the list of documents")
+	}
+}
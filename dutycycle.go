@@ -0,0 +1,127 @@
+package picodoomsdaymessenger
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrDutyCycleExceeded is returned when sending a packet would use more airtime in the last hour than the DutyCycle allows.
+var ErrDutyCycleExceeded = errors.New("duty cycle exceeded")
+
+// DutyCycleWindow is the length of time that the DutyCycle is measured over.
+const DutyCycleWindow = time.Hour
+
+// DutyCycleLimits are the choices of DutyCycle in the Settings. The first is no limit.
+var DutyCycleLimits = []float64{0, 0.001, 0.01, 0.1}
+
+// airtimeRecord is a packet that was sent, and how long it took to send.
+type airtimeRecord struct {
+	at      time.Time
+	airtime time.Duration
+}
+
+// DutyCycleBudget returns how much airtime the DutyCycle allows in every DutyCycleWindow, or zero if there is no limit.
+func (d *Device) DutyCycleBudget() time.Duration {
+	return time.Duration(d.Settings.DutyCycle * float64(DutyCycleWindow))
+}
+
+// AirtimeUsed returns how long the radio has sent for in the DutyCycleWindow before now. Older packets are forgotten.
+func (d *Device) AirtimeUsed(now time.Time) (used time.Duration) {
+	kept := d.airtime[:0]
+	for _, record := range d.airtime {
+		if now.Sub(record.at) < DutyCycleWindow {
+			kept = append(kept, record)
+			used += record.airtime
+		}
+	}
+	d.airtime = kept
+	return used
+}
+
+// DutyCycleWait returns how long to wait before a packet that takes some airtime can be sent without going over the DutyCycle, or zero if it can be sent now.
+// It returns -1 if the packet takes more airtime than the whole budget, so it can never be sent.
+func (d *Device) DutyCycleWait(airtime time.Duration, now time.Time) (wait time.Duration) {
+	budget := d.DutyCycleBudget()
+	if budget == 0 {
+		return 0
+	}
+	if airtime > budget {
+		return -1
+	}
+	over := d.AirtimeUsed(now) + airtime - budget
+	// Each packet that falls out of the window gives its airtime back.
+	for _, record := range d.airtime {
+		if over <= 0 {
+			break
+		}
+		wait = record.at.Add(DutyCycleWindow).Sub(now)
+		over -= record.airtime
+	}
+	return wait
+}
+
+// recordAirtime counts a packet that was sent against the DutyCycle.
+func (d *Device) recordAirtime(airtime time.Duration, now time.Time) {
+	d.airtime = append(d.airtime, airtimeRecord{at: now, airtime: airtime})
+}
+
+// dutyCycleName returns the name of a DutyCycle, such as "1%".
+func dutyCycleName(dutyCycle float64) string {
+	if dutyCycle == 0 {
+		return "Off"
+	}
+	return fmt.Sprintf("%g%%", dutyCycle*100)
+}
+
+// processDutyCycleInputEvent changes the DutyCycle to the next or previous of the DutyCycleLimits with right and left, and goes back with accept.
+func processDutyCycleInputEvent(d *Device, inputEvent InputEvent) (handled bool, err error) {
+	step := 0
+	switch inputEvent {
+	case InputEventAccept:
+		return true, d.GoBackState()
+	case InputEventLeft:
+		step = -1
+	case InputEventRight:
+		step = 1
+	case InputEventUp, InputEventDown:
+		return true, nil
+	default:
+		return false, nil
+	}
+	current := 0
+	for i, limit := range DutyCycleLimits {
+		if limit == d.Settings.DutyCycle {
+			current = i
+		}
+	}
+	d.Settings.DutyCycle = DutyCycleLimits[(current+step+len(DutyCycleLimits))%len(DutyCycleLimits)]
+	return true, nil
+}
+
+// drawDutyCycle draws the DutyCycle, how much of it has been used, and a gauge that fills up as it is used. The gauge turns red when it is nearly full.
+func drawDutyCycle(d *Device, img Canvas, layout Layout) (err error) {
+	palette := d.Palette()
+	used, budget := d.AirtimeUsed(time.Now()), d.DutyCycleBudget()
+	text := fmt.Sprintf("Limit %s\nUsed %.1fs", dutyCycleName(d.Settings.DutyCycle), used.Seconds())
+	if budget > 0 {
+		text += fmt.Sprintf(" of %.0fs", budget.Seconds())
+	}
+	drawTextPage(img, layout, palette, "Duty Cycle", text, 0)
+	if budget == 0 {
+		return nil
+	}
+	width := img.Bounds().Dx() - 1
+	top := layout.TitleHeight + 2*layout.LineHeight + 3
+	fill := int(int64(width) * int64(used) / int64(budget))
+	if fill > width {
+		fill = width
+	}
+	col := palette.Text
+	if used*10 >= budget*9 {
+		col = palette.StatusAlert
+	}
+	drawBoxOutline(img, 0, top, width, top+6, palette.Text)
+	drawFilledBox(img, 0, top, fill, top+6, col)
+	return nil
+}
@@ -0,0 +1,105 @@
+package picodoomsdaymessenger
+
+import (
+	"image"
+	"testing"
+	"time"
+)
+
+func TestDutyCycle(t *testing.T) {
+	device, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	sent := 0
+	device.SendUsingRadio = func(packet []byte) (err error) {
+		sent++
+		return nil
+	}
+	if device.DutyCycleBudget() != 36*time.Second {
+		t.Errorf("The default duty cycle should be 1%% of an hour, have %v", device.DutyCycleBudget())
+	}
+	device.Settings.DutyCycle = 0.001
+	now := time.Now()
+	device.recordAirtime(2*time.Second, now.Add(-50*time.Minute))
+	device.recordAirtime(time.Second, now.Add(-10*time.Minute))
+	device.recordAirtime(time.Second, now.Add(-2*time.Hour))
+	if used := device.AirtimeUsed(now); used != 3*time.Second {
+		t.Errorf("Only the airtime in the last hour should be counted, have %v", used)
+	}
+	if wait := device.DutyCycleWait(500*time.Millisecond, now); wait != 0 {
+		t.Errorf("A packet that fits in the budget should not wait, have %v", wait)
+	}
+	if wait := device.DutyCycleWait(time.Second, now); wait != 10*time.Minute {
+		t.Errorf("A packet should wait for the oldest airtime to leave the window, have %v", wait)
+	}
+	if wait := device.DutyCycleWait(time.Hour, now); wait != -1 {
+		t.Errorf("A packet that is longer than the budget can never be sent, have %v", wait)
+	}
+
+	device.recordAirtime(600*time.Millisecond, now)
+	if err = device.SendPacket(nil, []byte("doom")); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if sent != 0 || device.QueuedPackets() != 1 || device.Packets.SendFailed != 0 {
+		t.Errorf("A packet over the duty cycle should wait in the queue, have %d sent, %d queued and %d failed", sent, device.QueuedPackets(), device.Packets.SendFailed)
+	}
+	if err = device.Tick(now.Add(10*time.Minute + time.Second)); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if sent != 1 || device.QueuedPackets() != 0 {
+		t.Errorf("The packet should be sent once there is airtime for it, have %d sent and %d queued", sent, device.QueuedPackets())
+	}
+
+	device.Settings.DutyCycle = 0.00001
+	if err = device.SendPacket(nil, make([]byte, 200)); err != ErrDutyCycleExceeded {
+		t.Errorf("A packet that is longer than the whole duty cycle should be rejected, have %v", err)
+	}
+	if device.QueuedPackets() != 0 || device.Packets.SendFailed != 1 {
+		t.Errorf("A rejected packet should not be queued, have %d queued and %d failed", device.QueuedPackets(), device.Packets.SendFailed)
+	}
+
+	device.Settings.DutyCycle = 0
+	used := device.AirtimeUsed(time.Now())
+	if err = device.SendPacket(nil, []byte("doom")); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if err = sendQueued(device); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if sent != 2 || device.AirtimeUsed(time.Now()) <= used {
+		t.Errorf("A sent packet should be counted against the duty cycle, have %d sent and %v used", sent, device.AirtimeUsed(time.Now()))
+	}
+}
+
+func TestDutyCycleGauge(t *testing.T) {
+	device, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if err = device.ChangeStateWithHistory(&device.StateDutyCycle); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	device.recordAirtime(40*time.Second, time.Now())
+	if _, err = GetFrame(image.Rect(0, 0, 128, 64), device); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if err = device.ProcessInputEvent(InputEventRight); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if device.Settings.DutyCycle != DutyCycleLimits[3] {
+		t.Errorf("Right should choose the next duty cycle, have %v", device.Settings.DutyCycle)
+	}
+	if err = device.ProcessInputEvent(InputEventRight); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if device.Settings.DutyCycle != 0 {
+		t.Errorf("The duty cycle should wrap around to no limit, have %v", device.Settings.DutyCycle)
+	}
+	if err = device.ProcessInputEvent(InputEventAccept); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if device.State == &device.StateDutyCycle {
+		t.Errorf("Accept should leave the duty cycle gauge")
+	}
+}
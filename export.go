@@ -0,0 +1,131 @@
+package picodoomsdaymessenger
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+)
+
+// Define export errors
+var (
+	ErrExportUnknownFormat = errors.New("unknown export format")
+	ErrExportNoSerial      = errors.New("no WriteSerial is defined")
+)
+
+// ExportFormat is how the message history is written by ExportHistory.
+type ExportFormat string
+
+const (
+	// ExportJSON writes every Conversation as a JSON array of ExportedConversations.
+	ExportJSON ExportFormat = "json"
+	// ExportCSV writes one row for every Message, after a header row.
+	ExportCSV ExportFormat = "csv"
+)
+
+// ExportFormats is every ExportFormat, in the order they are shown in the export menu.
+var ExportFormats = []ExportFormat{ExportJSON, ExportCSV}
+
+// ExportedConversation is a Conversation as it is written by ExportHistory.
+type ExportedConversation struct {
+	Name     string            `json:"name"`
+	Channel  string            `json:"channel,omitempty"`
+	Messages []ExportedMessage `json:"messages"`
+}
+
+// ExportedMessage is a Message as it is written by ExportHistory. Time is in unix seconds.
+type ExportedMessage struct {
+	Time      int64  `json:"time"`
+	PersonID  int    `json:"id"`
+	Name      string `json:"name"`
+	Text      string `json:"text"`
+	Own       bool   `json:"own,omitempty"`
+	Verified  bool   `json:"verified,omitempty"`
+	Emergency bool   `json:"emergency,omitempty"`
+}
+
+// exportedConversations converts the Conversations of the Device to how they are exported.
+func (d *Device) exportedConversations() (conversations []ExportedConversation) {
+	conversations = make([]ExportedConversation, len(d.Conversations))
+	for i, c := range d.Conversations {
+		conversations[i] = ExportedConversation{Name: c.Name, Channel: c.Channel, Messages: make([]ExportedMessage, len(c.Messages))}
+		for j, m := range c.Messages {
+			exported := ExportedMessage{
+				PersonID:  m.Person.ID,
+				Name:      m.Person.Name,
+				Text:      m.Text,
+				Own:       m.Person == d.SelfIdentity,
+				Verified:  m.Verified,
+				Emergency: m.Priority == PriorityEmergency,
+			}
+			if !m.TimeSent.IsZero() {
+				exported.Time = m.TimeSent.Unix()
+			}
+			conversations[i].Messages[j] = exported
+		}
+	}
+	return conversations
+}
+
+// ExportHistory writes every Conversation and its Messages in a format that can be backed up or read on a computer.
+func (d *Device) ExportHistory(format ExportFormat) (output []byte, err error) {
+	conversations := d.exportedConversations()
+	switch format {
+	case ExportJSON:
+		return json.Marshal(conversations)
+	case ExportCSV:
+		buffer := &bytes.Buffer{}
+		w := csv.NewWriter(buffer)
+		w.Write([]string{"conversation", "time", "id", "name", "text", "own", "verified", "emergency"})
+		for _, c := range conversations {
+			for _, m := range c.Messages {
+				w.Write([]string{
+					c.Name,
+					time.Unix(m.Time, 0).UTC().Format(time.RFC3339),
+					strconv.Itoa(m.PersonID),
+					m.Name,
+					m.Text,
+					strconv.FormatBool(m.Own),
+					strconv.FormatBool(m.Verified),
+					strconv.FormatBool(m.Emergency),
+				})
+			}
+		}
+		w.Flush()
+		return buffer.Bytes(), w.Error()
+	}
+	return nil, ErrExportUnknownFormat
+}
+
+// ExportHistoryToSerial writes the message history to the serial port in a format.
+func (d *Device) ExportHistoryToSerial(format ExportFormat) (err error) {
+	if d.WriteSerial == nil {
+		return ErrExportNoSerial
+	}
+	output, err := d.ExportHistory(format)
+	if err != nil {
+		return err
+	}
+	return d.WriteSerial(append(output, '\n'))
+}
+
+// exportMenuItems creates a MenuItem for every ExportFormat, which writes the message history to the serial port and goes back.
+func exportMenuItems() (items []MenuItem) {
+	for _, format := range ExportFormats {
+		f := format
+		items = append(items, MenuItem{
+			Text: "As " + string(bytes.ToUpper([]byte(f))),
+			Action: func(d *Device) (err error) {
+				err = d.ExportHistoryToSerial(f)
+				if err != nil {
+					return err
+				}
+				return d.GoBackState()
+			},
+			CursorIcon: CursorIconRightArrow,
+		})
+	}
+	return items
+}
@@ -0,0 +1,95 @@
+package picodoomsdaymessenger
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newExportDevice(t *testing.T) *Device {
+	device, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	c := device.NewConversation(Person{Name: "Bob", ID: 7})
+	c.Name = "Bob"
+	c.Messages = []Message{
+		{Text: "hello, world", Person: Person{Name: "Bob", ID: 7}, TimeSent: time.Unix(1700000000, 0), Priority: PriorityEmergency},
+		{Text: "hi", Person: device.SelfIdentity, TimeSent: time.Unix(1700000060, 0)},
+	}
+	return device
+}
+
+func TestExportHistory(t *testing.T) {
+	device := newExportDevice(t)
+	output, err := device.ExportHistory(ExportJSON)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	conversations := []ExportedConversation{}
+	err = json.Unmarshal(output, &conversations)
+	if err != nil {
+		t.Fatalf("expected valid JSON, got %v", err)
+	}
+	if len(conversations) != 1 || len(conversations[0].Messages) != 2 {
+		t.Fatalf("expected one conversation with two messages, got %+v", conversations)
+	}
+	if m := conversations[0].Messages[0]; m.Text != "hello, world" || m.Time != 1700000000 || !m.Emergency || m.Own {
+		t.Errorf("expected the first message to be exported, got %+v", m)
+	}
+	if !conversations[0].Messages[1].Own {
+		t.Errorf("expected the second message to be marked as sent by the device")
+	}
+
+	output, err = device.ExportHistory(ExportCSV)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	rows, err := csv.NewReader(strings.NewReader(string(output))).ReadAll()
+	if err != nil {
+		t.Fatalf("expected valid CSV, got %v", err)
+	}
+	if len(rows) != 3 || rows[1][0] != "Bob" || rows[1][1] != "2023-11-14T22:13:20Z" || rows[1][4] != "hello, world" {
+		t.Errorf("expected a header and a row for each message, got %q", rows)
+	}
+
+	if _, err = device.ExportHistory("xml"); err != ErrExportUnknownFormat {
+		t.Errorf("expected ErrExportUnknownFormat, got %v", err)
+	}
+}
+
+func TestExportHistoryOverSerial(t *testing.T) {
+	device := newExportDevice(t)
+	response, err := device.ProcessSerialCommand("export csv")
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if !strings.HasPrefix(response, "conversation,time,") || strings.Count(response, "\n") != 2 {
+		t.Errorf("expected the history as CSV, got %q", response)
+	}
+
+	err = device.ChangeStateWithHistory(&device.StateExportMenu)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if err = device.StateExportMenu.Content[1].Action(device); err != ErrExportNoSerial {
+		t.Errorf("expected ErrExportNoSerial without a serial port, got %v", err)
+	}
+	written := ""
+	device.WriteSerial = func(data []byte) (err error) {
+		written += string(data)
+		return nil
+	}
+	err = device.StateExportMenu.Content[1].Action(device)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if !strings.HasPrefix(written, "[{") || !strings.HasSuffix(written, "\n") {
+		t.Errorf("expected the history as JSON on the serial port, got %q", written)
+	}
+	if device.State == &device.StateExportMenu {
+		t.Errorf("expected to go back after exporting")
+	}
+}
@@ -0,0 +1,19 @@
+package firmware
+
+// lipoCurve is the resting voltage of a single LiPo cell at each 10% of charge, from empty to full.
+var lipoCurve = [11]float64{3.27, 3.61, 3.69, 3.71, 3.73, 3.75, 3.77, 3.79, 3.80, 3.82, 4.20}
+
+// LiPoPercent estimates the charge of a single cell LiPo battery from its voltage.
+func LiPoPercent(volts float64) (percent int) {
+	if volts <= lipoCurve[0] {
+		return 0
+	}
+	for i := 1; i < len(lipoCurve); i++ {
+		if volts < lipoCurve[i] {
+			// Interpolate between the two points on the curve.
+			fraction := (volts - lipoCurve[i-1]) / (lipoCurve[i] - lipoCurve[i-1])
+			return (i-1)*10 + int(fraction*10)
+		}
+	}
+	return 100
+}
@@ -0,0 +1,437 @@
+// Package firmware runs a picodoomsdaymessenger Device on a Board.
+// The Board holds the hardware of a particular build of the messenger, so the main function of the firmware only needs to assign pins and call RunFirmware.
+// Nothing in this package depends on the machine package, so it can be tested on a computer with fake hardware.
+package firmware
+
+import (
+	"errors"
+	"fmt"
+	"image/color"
+	"reflect"
+	"runtime"
+	"runtime/debug"
+	"time"
+
+	picodoomsdaymessenger "github.com/headblockhead/picoDoomsdayMessenger"
+)
+
+// ErrNoDisplay is returned by New when the Board has no Display. It is the same error that the Device returns when it has no Display to render to.
+var ErrNoDisplay = picodoomsdaymessenger.ErrNoDisplay
+
+// ErrNoInput is returned by New when the Board has no Input.
+var ErrNoInput = errors.New("board has no input")
+
+// InputScanner reads the buttons of a Board.
+type InputScanner interface {
+	// Scan returns the InputEvents of the buttons that are held down.
+	Scan() (events []picodoomsdaymessenger.InputEvent)
+}
+
+// LEDStrip shows the colors of the LED array of a Board.
+type LEDStrip interface {
+	ShowLEDs(colors [6]color.RGBA) (err error)
+}
+
+// Radio is a LoRa radio that can send packets, and calls a handler with the packets that it receives.
+type Radio interface {
+	picodoomsdaymessenger.Radio
+	Send(packet []byte) (err error)
+	// SetReceiveHandler sets the function that is called with every packet that is received, and its RSSI in dBm or picodoomsdaymessenger.RSSIUnknown. It may be called from an interrupt.
+	SetReceiveHandler(handler func(packet []byte, rssi int))
+}
+
+// Buzzer plays notes on a piezo buzzer.
+type Buzzer interface {
+	// PlayTone starts playing a note for a duration, and returns without waiting for it to finish.
+	PlayTone(frequencyHz int, duration time.Duration) (err error)
+}
+
+// Battery measures the charge of the battery of a Board.
+type Battery interface {
+	Percent() (percent int, err error)
+}
+
+// Serial is a serial port, such as USB serial, that commands are read from and logs are written to.
+type Serial interface {
+	Buffered() int
+	ReadByte() (b byte, err error)
+	Write(data []byte) (n int, err error)
+}
+
+// Board is the hardware that the firmware runs on. Only the Display and Input are required, anything else that is nil is not used.
+type Board struct {
+	Display picodoomsdaymessenger.Displayer
+	Input   InputScanner
+	LEDs    LEDStrip
+	Buzzer  Buzzer
+	Radio   Radio
+	Battery Battery
+	Storage picodoomsdaymessenger.Storage
+	Serial  Serial
+	// StatusLED turns the single LED that shows errors on or off.
+	StatusLED func(on bool)
+}
+
+// Options changes how the firmware runs. Zero values are replaced with the defaults.
+type Options struct {
+	// FrameInterval is the shortest time between frames sent to the display.
+	FrameInterval time.Duration
+	// BatteryInterval is the time between battery measurements.
+	BatteryInterval time.Duration
+	// ErrorDuration is the time that an error is left on the display.
+	ErrorDuration time.Duration
+	// Version is the version of the firmware, which is shown on the splash screen.
+	Version string
+	// Setup is called with the Device once the Board has been attached to it.
+	Setup func(d *picodoomsdaymessenger.Device) (err error)
+}
+
+// DefaultOptions is used for any Options that are not set.
+var DefaultOptions = Options{
+	FrameInterval:   50 * time.Millisecond,
+	BatteryInterval: time.Minute,
+	ErrorDuration:   2 * time.Second,
+}
+
+// withDefaults returns the Options with zero values replaced by the defaults.
+func (o Options) withDefaults() Options {
+	if o.FrameInterval == 0 {
+		o.FrameInterval = DefaultOptions.FrameInterval
+	}
+	if o.BatteryInterval == 0 {
+		o.BatteryInterval = DefaultOptions.BatteryInterval
+	}
+	if o.ErrorDuration == 0 {
+		o.ErrorDuration = DefaultOptions.ErrorDuration
+	}
+	return o
+}
+
+// receivedPacket is a packet that the radio has received, waiting to be handled by Step.
+type receivedPacket struct {
+	payload []byte
+	rssi    int
+}
+
+// Firmware is a Device running on a Board.
+type Firmware struct {
+	Device  *picodoomsdaymessenger.Device
+	Board   Board
+	Options Options
+
+	received      chan receivedPacket
+	serialLine    []byte
+	syncDecoder   picodoomsdaymessenger.SyncDecoder
+	lastFrame     time.Time
+	lastLEDFrame  time.Time
+	lastBattery   time.Time
+	savedSettings picodoomsdaymessenger.Settings
+	sleep         func(d time.Duration)
+}
+
+// New creates a Device and attaches the hardware of a Board to it.
+func New(board Board, options Options) (f *Firmware, err error) {
+	if board.Display == nil {
+		return nil, ErrNoDisplay
+	}
+	if board.Input == nil {
+		return nil, ErrNoInput
+	}
+	device, err := picodoomsdaymessenger.NewDevice()
+	if err != nil {
+		return nil, err
+	}
+	f = &Firmware{
+		Device:   device,
+		Board:    board,
+		Options:  options.withDefaults(),
+		received: make(chan receivedPacket, 8),
+		sleep:    time.Sleep,
+	}
+	device.SetDisplay(board.Display)
+	device.Version = options.Version
+	device.Storage = board.Storage
+	// If the Device crashed last time, the user is asked whether to start in safe mode before anything else is loaded.
+	err = device.Boot()
+	if err != nil {
+		return f, err
+	}
+	f.savedSettings = device.Settings
+
+	if board.Serial != nil {
+		// When in gateway mode, events are written to the serial port for a base-station computer.
+		device.SendToGateway = func(event []byte) (err error) {
+			_, err = board.Serial.Write(event)
+			return err
+		}
+		// A companion app on a host computer is sent sync frames on the same serial port.
+		device.SendToHost = func(frame []byte) (err error) {
+			_, err = board.Serial.Write(frame)
+			return err
+		}
+		// Exports of the message history are written to the serial port for a computer to save.
+		device.WriteSerial = func(data []byte) (err error) {
+			_, err = board.Serial.Write(data)
+			return err
+		}
+		// Log rejected packets and other details to the serial port.
+		device.Log = func(message string) {
+			board.Serial.Write([]byte(message + "\n"))
+		}
+	}
+
+	if board.Radio != nil {
+		device.SendUsingRadio = board.Radio.Send
+		// Packets are handled in Step rather than in the handler, as the handler may be called from an interrupt.
+		board.Radio.SetReceiveHandler(func(packet []byte, rssi int) {
+			select {
+			case f.received <- receivedPacket{payload: append([]byte{}, packet...), rssi: rssi}:
+			default:
+				// Drop the packet if Step has fallen behind.
+			}
+		})
+		err = device.SetRadio(board.Radio)
+		if err != nil {
+			return f, err
+		}
+	}
+
+	device.GetFreeHeap = freeHeap
+
+	if board.Buzzer != nil {
+		device.PlayTone = board.Buzzer.PlayTone
+	}
+
+	if board.LEDs != nil {
+		err = board.LEDs.ShowLEDs([6]color.RGBA{})
+		if err != nil {
+			return f, err
+		}
+	}
+
+	if f.Options.Setup != nil {
+		err = f.Options.Setup(device)
+		if err != nil {
+			return f, err
+		}
+	}
+	return f, nil
+}
+
+// Step does one pass of the main loop: read the buttons, handle received packets and serial commands, run the periodic work of the Device, and update the display and LEDs.
+func (f *Firmware) Step(now time.Time) (err error) {
+	d := f.Device
+
+	// Read the buttons. The Device presses each button once, and repeats the ones that are held.
+	err = d.ProcessHeldInputs(f.Board.Input.Scan(), now)
+	if err != nil {
+		return err
+	}
+
+	f.receivePackets()
+	f.readSerialCommands()
+
+	err = d.Tick(now)
+	if err != nil {
+		return err
+	}
+
+	if f.Board.Battery != nil && (f.lastBattery.IsZero() || now.Sub(f.lastBattery) >= f.Options.BatteryInterval) {
+		f.lastBattery = now
+		percent, err := f.Board.Battery.Percent()
+		if err != nil {
+			return err
+		}
+		err = d.SetBatteryPercent(percent)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Save the Settings whenever they are changed.
+	if !reflect.DeepEqual(f.savedSettings, d.Settings) {
+		f.savedSettings = d.Settings
+		err = d.SaveSettings()
+		if err != nil {
+			return err
+		}
+	}
+
+	// Update the display if something has changed, and slow screens have had time to finish the last frame.
+	interval := f.Options.FrameInterval
+	if d.Display.MinRefreshInterval > interval {
+		interval = d.Display.MinRefreshInterval
+	}
+	if d.NeedsRedraw() && (f.lastFrame.IsZero() || now.Sub(f.lastFrame) >= interval) {
+		f.lastFrame = now
+		err = d.Render()
+		if err != nil {
+			return err
+		}
+	}
+
+	// Show the next animation frame if it has been long enough since the last frame.
+	animation := d.LEDAnimation
+	if f.Board.LEDs != nil && animation != nil && len(animation.Frames) > 0 && now.Sub(f.lastLEDFrame) >= animation.FrameDuration {
+		if animation.CurrentFrame >= len(animation.Frames) {
+			animation.CurrentFrame = 0
+		}
+		err = f.Board.LEDs.ShowLEDs(d.LEDColors(animation.Frames[animation.CurrentFrame]))
+		if err != nil {
+			return err
+		}
+		animation.CurrentFrame++
+		f.lastLEDFrame = now
+	}
+	return nil
+}
+
+// receivePackets passes the packets that the radio has received to the Device.
+// A bad packet from another device is not the fault of the user, so it is logged rather than shown as an error.
+func (f *Firmware) receivePackets() {
+	for {
+		select {
+		case packet := <-f.received:
+			err := f.Device.ReceiveFromRadioWithRSSI(packet.payload, packet.rssi)
+			if err != nil {
+				f.log("error: " + err.Error())
+			}
+		default:
+			return
+		}
+	}
+}
+
+// readSerialCommands reads any waiting bytes from the serial port, and runs each complete line as a command on the Device.
+// Sync frames from a companion app are picked out by their start byte, and passed to the Device as they are completed.
+func (f *Firmware) readSerialCommands() {
+	if f.Board.Serial == nil {
+		return
+	}
+	for f.Board.Serial.Buffered() > 0 {
+		b, err := f.Board.Serial.ReadByte()
+		if err != nil {
+			break
+		}
+		if len(f.serialLine) == 0 && (b == picodoomsdaymessenger.SyncFrameStart || f.syncDecoder.Active()) {
+			f.readSyncByte(b)
+			continue
+		}
+		if b != '\n' && b != '\r' {
+			f.serialLine = append(f.serialLine, b)
+			continue
+		}
+		if len(f.serialLine) == 0 {
+			continue
+		}
+		response, err := f.Device.ProcessSerialCommand(string(f.serialLine))
+		f.serialLine = f.serialLine[:0]
+		if err != nil {
+			f.log("error: " + err.Error())
+			continue
+		}
+		if response != "" {
+			f.log(response)
+		}
+	}
+}
+
+// readSyncByte adds a byte to the sync frame being read, and runs the frame once it is complete.
+func (f *Firmware) readSyncByte(b byte) {
+	frameType, payload, done, err := f.syncDecoder.Decode(b)
+	if err == nil && done {
+		err = f.Device.ProcessSyncFrame(frameType, payload)
+	}
+	if err != nil {
+		f.log("error: " + err.Error())
+	}
+}
+
+// log writes a line to the serial port, if the Board has one.
+func (f *Firmware) log(message string) {
+	if f.Board.Serial != nil {
+		f.Board.Serial.Write([]byte(message + "\n"))
+	}
+}
+
+// ShowError communicates an error to the user, by flashing the status LED, playing the error tone and showing the error on the display.
+// If the error cannot be shown, the status LED flashes twice.
+func (f *Firmware) ShowError(inputerr error) {
+	f.log("error: " + inputerr.Error())
+	if f.Device != nil {
+		if err := f.Device.Notify(picodoomsdaymessenger.DeviceEventError); err != nil {
+			f.log("error: " + err.Error())
+		}
+	}
+	f.flashStatusLED(1)
+	frame, err := picodoomsdaymessenger.GetErrorFrame(f.Board.Display.Capabilities().Bounds(), f.Device, inputerr.Error())
+	if err != nil {
+		f.flashStatusLED(2)
+		return
+	}
+	err = f.Board.Display.DrawFrame(frame)
+	if err != nil {
+		f.flashStatusLED(2)
+		return
+	}
+	// Give the user time to read the error.
+	f.sleep(f.Options.ErrorDuration)
+}
+
+// flashStatusLED toggles the status LED a number of times.
+func (f *Firmware) flashStatusLED(count int) {
+	if f.Board.StatusLED == nil {
+		return
+	}
+	for i := 0; i < count; i++ {
+		f.Board.StatusLED(true)
+		f.sleep(300 * time.Millisecond)
+		f.Board.StatusLED(false)
+		f.sleep(300 * time.Millisecond)
+	}
+}
+
+// stepSafely runs Step, and turns a panic into an error so that the firmware can carry on. The panic and its stack trace are also saved as the LastCrash of the Device, so that they can be read after a restart.
+func (f *Firmware) stepSafely(now time.Time) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+			if recordErr := f.Device.RecordCrash(err.Error(), debug.Stack()); recordErr != nil {
+				f.log("error: " + recordErr.Error())
+			}
+		}
+	}()
+	return f.Step(now)
+}
+
+// RunFirmware creates a Device on a Board and runs it forever. Errors, including panics, are shown on the display and the firmware carries on.
+func RunFirmware(board Board, options Options) {
+	f, err := New(board, options)
+	if err != nil {
+		if f == nil {
+			// Without a Device there is nothing to run, so keep showing the error.
+			f = &Firmware{Board: board, Options: options.withDefaults(), sleep: time.Sleep}
+			for {
+				if board.Display == nil {
+					f.flashStatusLED(2)
+					continue
+				}
+				f.ShowError(err)
+			}
+		}
+		f.ShowError(err)
+	}
+	for {
+		err = f.stepSafely(time.Now())
+		if err != nil {
+			f.ShowError(err)
+		}
+	}
+}
+
+// freeHeap returns how many bytes of the heap are not in use.
+func freeHeap() (bytes int, ok bool) {
+	stats := runtime.MemStats{}
+	runtime.ReadMemStats(&stats)
+	return int(stats.HeapSys - stats.HeapInuse), true
+}
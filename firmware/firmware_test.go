@@ -0,0 +1,351 @@
+package firmware
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"testing"
+	"time"
+
+	picodoomsdaymessenger "github.com/headblockhead/picoDoomsdayMessenger"
+)
+
+type fakeDisplay struct {
+	frames int
+}
+
+func (f *fakeDisplay) Capabilities() picodoomsdaymessenger.DisplayCapabilities {
+	return picodoomsdaymessenger.DisplaySSD1306
+}
+
+func (f *fakeDisplay) Size() (width, height int) {
+	return 128, 64
+}
+
+func (f *fakeDisplay) DrawFrame(frame image.Image) (err error) {
+	f.frames++
+	return nil
+}
+
+type fakeInput struct {
+	pressed []picodoomsdaymessenger.InputEvent
+}
+
+func (f *fakeInput) Scan() (events []picodoomsdaymessenger.InputEvent) {
+	events = f.pressed
+	f.pressed = nil
+	return events
+}
+
+type fakeRadio struct {
+	sent    [][]byte
+	handler func(packet []byte, rssi int)
+}
+
+func (f *fakeRadio) SetFrequency(frequencyMHz float64) (err error) { return nil }
+func (f *fakeRadio) SetModemConfig(config picodoomsdaymessenger.ModemConfig) (err error) {
+	return nil
+}
+func (f *fakeRadio) SetTxPower(dBm int) (err error)            { return nil }
+func (f *fakeRadio) ChannelActive() (busy bool, err error)     { return false, nil }
+func (f *fakeRadio) ChannelRSSI() (dBm int, err error)         { return 0, nil }
+func (f *fakeRadio) SetPreambleLength(symbols int) (err error) { return nil }
+func (f *fakeRadio) Sleep() (err error)                        { return nil }
+func (f *fakeRadio) Listen() (err error)                       { return nil }
+func (f *fakeRadio) Send(packet []byte) (err error) {
+	f.sent = append(f.sent, packet)
+	return nil
+}
+func (f *fakeRadio) SetReceiveHandler(handler func(packet []byte, rssi int)) { f.handler = handler }
+
+type fakeLEDs struct {
+	shown [][6]color.RGBA
+}
+
+func (f *fakeLEDs) ShowLEDs(colors [6]color.RGBA) (err error) {
+	f.shown = append(f.shown, colors)
+	return nil
+}
+
+type fakeBattery int
+
+func (f fakeBattery) Percent() (percent int, err error) { return int(f), nil }
+
+type fakeSerial struct {
+	in  []byte
+	out []byte
+}
+
+func (f *fakeSerial) Buffered() int { return len(f.in) }
+func (f *fakeSerial) ReadByte() (b byte, err error) {
+	b, f.in = f.in[0], f.in[1:]
+	return b, nil
+}
+func (f *fakeSerial) Write(data []byte) (n int, err error) {
+	f.out = append(f.out, data...)
+	return len(data), nil
+}
+
+func TestNewRequiresDisplayAndInput(t *testing.T) {
+	_, err := New(Board{Input: &fakeInput{}}, Options{})
+	if err != ErrNoDisplay || err != picodoomsdaymessenger.ErrNoDisplay {
+		t.Errorf("expected ErrNoDisplay, got %v", err)
+	}
+	_, err = New(Board{Display: &fakeDisplay{}}, Options{})
+	if err != ErrNoInput {
+		t.Errorf("expected ErrNoInput, got %v", err)
+	}
+}
+
+func TestStep(t *testing.T) {
+	display := &fakeDisplay{}
+	input := &fakeInput{}
+	radio := &fakeRadio{}
+	leds := &fakeLEDs{}
+	storage := picodoomsdaymessenger.MemoryStorage{}
+	setupCalled := false
+	f, err := New(Board{
+		Display: display,
+		Input:   input,
+		LEDs:    leds,
+		Radio:   radio,
+		Battery: fakeBattery(50),
+		Storage: storage,
+	}, Options{Setup: func(d *picodoomsdaymessenger.Device) (err error) {
+		setupCalled = true
+		return nil
+	}})
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if !setupCalled {
+		t.Error("expected Setup to be called")
+	}
+
+	now := time.Now()
+	input.pressed = []picodoomsdaymessenger.InputEvent{picodoomsdaymessenger.InputEventOpenSettings}
+	err = f.Step(now)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if f.Device.State != &f.Device.StateSettingsMenu {
+		t.Errorf("expected the settings menu to be open, got %q", f.Device.State.Title)
+	}
+	if display.frames != 1 {
+		t.Errorf("expected 1 frame, got %d", display.frames)
+	}
+	if len(leds.shown) == 0 {
+		t.Error("expected the LEDs to be updated")
+	}
+	if !f.Device.Battery.Valid || f.Device.Battery.Percent != 50 {
+		t.Errorf("expected the battery to be 50%%, got %+v", f.Device.Battery)
+	}
+
+	// Frames are not sent faster than the FrameInterval.
+	err = f.Step(now.Add(time.Millisecond))
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if display.frames != 1 {
+		t.Errorf("expected 1 frame, got %d", display.frames)
+	}
+
+	// Changed Settings are saved.
+	f.Device.Settings.Gateway = true
+	err = f.Step(now.Add(time.Second))
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if _, err := storage.Load("settings"); err != nil {
+		t.Errorf("expected the settings to be saved, got %v", err)
+	}
+}
+
+func TestStepReceivesPackets(t *testing.T) {
+	radio := &fakeRadio{}
+	serial := &fakeSerial{}
+	f, err := New(Board{Display: &fakeDisplay{}, Input: &fakeInput{}, Radio: radio, Serial: serial}, Options{})
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if radio.handler == nil {
+		t.Fatal("expected a receive handler to be set")
+	}
+	radio.handler([]byte("not a packet"), -80)
+	err = f.Step(time.Now())
+	if err != nil {
+		t.Fatalf("expected bad packets to be logged rather than returned, got %v", err)
+	}
+	if len(serial.out) == 0 {
+		t.Error("expected the bad packet to be logged to serial")
+	}
+}
+
+func TestStepRunsSerialCommands(t *testing.T) {
+	serial := &fakeSerial{in: []byte("not a command\n")}
+	f, err := New(Board{Display: &fakeDisplay{}, Input: &fakeInput{}, Serial: serial}, Options{})
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	err = f.Step(time.Now())
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if len(serial.out) == 0 {
+		t.Error("expected a response on serial")
+	}
+}
+
+func TestStepRunsSyncFrames(t *testing.T) {
+	hello, err := picodoomsdaymessenger.EncodeSyncFrame(picodoomsdaymessenger.SyncFrameHello, nil)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	serial := &fakeSerial{in: append(append([]byte("state\n"), hello...), "state\n"...)}
+	f, err := New(Board{Display: &fakeDisplay{}, Input: &fakeInput{}, Serial: serial}, Options{})
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	f.Device.State = &f.Device.StateMainMenu
+	err = f.Step(time.Now())
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if string(serial.out) != "Main Menu\nMain Menu\n" {
+		t.Errorf("expected the frame to be read between the commands, got %q", serial.out)
+	}
+	err = f.Device.ReceiveFromRadio([]byte("doom7\xccBob\xcchello"))
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if len(serial.out) == 0 || serial.out[len("Main Menu\nMain Menu\n")] != picodoomsdaymessenger.SyncFrameStart {
+		t.Errorf("expected the message to be streamed after the hello, got %q", serial.out)
+	}
+}
+
+func TestStepSafelyRecoversPanics(t *testing.T) {
+	storage := picodoomsdaymessenger.MemoryStorage{}
+	f, err := New(Board{Display: &fakeDisplay{}, Input: panicInput{}, Storage: storage}, Options{})
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	err = f.stepSafely(time.Now())
+	if err == nil {
+		t.Error("expected the panic to be returned as an error")
+	}
+
+	// The panic is still there after a restart.
+	restarted, err := New(Board{Display: &fakeDisplay{}, Input: &fakeInput{}, Storage: storage}, Options{})
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if restarted.Device.LastCrash == nil || restarted.Device.LastCrash.Message != "panic: broken" {
+		t.Fatalf("expected the panic to be kept as the last crash, got %+v", restarted.Device.LastCrash)
+	}
+	if restarted.Device.LastCrash.Stack == "" {
+		t.Errorf("expected the stack trace of the panic to be kept")
+	}
+	if restarted.Device.State != &restarted.Device.StateSafeModeOffer {
+		t.Errorf("expected safe mode to be offered after the crash, got %q", restarted.Device.State.Title)
+	}
+}
+
+type panicInput struct{}
+
+func (panicInput) Scan() (events []picodoomsdaymessenger.InputEvent) {
+	panic(errors.New("broken"))
+}
+
+// fakeBuzzer records the frequencies of the notes it has played.
+type fakeBuzzer struct {
+	played []int
+}
+
+func (b *fakeBuzzer) PlayTone(frequencyHz int, duration time.Duration) (err error) {
+	b.played = append(b.played, frequencyHz)
+	return nil
+}
+
+func TestShowError(t *testing.T) {
+	display := &fakeDisplay{}
+	buzzer := &fakeBuzzer{}
+	statusLED := []bool{}
+	f, err := New(Board{Display: display, Input: &fakeInput{}, Buzzer: buzzer, StatusLED: func(on bool) {
+		statusLED = append(statusLED, on)
+	}}, Options{})
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	f.sleep = func(d time.Duration) {}
+	f.ShowError(errors.New("test"))
+	if display.frames != 1 {
+		t.Errorf("expected the error to be shown, got %d frames", display.frames)
+	}
+	if len(statusLED) != 2 {
+		t.Errorf("expected the status LED to flash once, got %v", statusLED)
+	}
+	if len(buzzer.played) != 1 {
+		t.Errorf("expected the error tone to be played, got %v", buzzer.played)
+	}
+}
+
+func TestLiPoPercent(t *testing.T) {
+	tests := []struct {
+		volts    float64
+		expected int
+	}{
+		{3.0, 0},
+		{3.27, 0},
+		{3.75, 50},
+		{4.2, 100},
+		{4.5, 100},
+	}
+	for _, test := range tests {
+		if actual := LiPoPercent(test.volts); actual != test.expected {
+			t.Errorf("LiPoPercent(%v): expected %d, got %d", test.volts, test.expected, actual)
+		}
+	}
+}
+
+type fakeBlockDevice []byte
+
+func (f fakeBlockDevice) ReadAt(p []byte, off int64) (n int, err error) {
+	return copy(p, f[off:]), nil
+}
+func (f fakeBlockDevice) WriteAt(p []byte, off int64) (n int, err error) {
+	return copy(f[off:], p), nil
+}
+func (f fakeBlockDevice) Size() int64           { return int64(len(f)) }
+func (f fakeBlockDevice) EraseBlockSize() int64 { return 64 }
+func (f fakeBlockDevice) EraseBlocks(start, length int64) error {
+	for i := start * 64; i < (start+length)*64 && i < int64(len(f)); i++ {
+		f[i] = 0xff
+	}
+	return nil
+}
+
+func TestBlockStorage(t *testing.T) {
+	device := make(fakeBlockDevice, 256)
+	for i := range device {
+		device[i] = 0xff
+	}
+	s := NewBlockStorage(device)
+	if _, err := s.Load("settings"); err != picodoomsdaymessenger.ErrStorageNotFound {
+		t.Errorf("expected blank flash to be empty, got %v", err)
+	}
+	err := s.Save("settings", []byte("saved"))
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	data, err := NewBlockStorage(device).Load("settings")
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if string(data) != "saved" {
+		t.Errorf("expected %q, got %q", "saved", data)
+	}
+	err = s.Save("big", make([]byte, 256))
+	if err != ErrStorageFull {
+		t.Errorf("expected ErrStorageFull, got %v", err)
+	}
+}
@@ -0,0 +1,89 @@
+package firmware
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+
+	picodoomsdaymessenger "github.com/headblockhead/picoDoomsdayMessenger"
+)
+
+// ErrStorageFull is returned by BlockStorage when the saved data does not fit on the BlockDevice.
+var ErrStorageFull = errors.New("storage is full")
+
+// BlockDevice is flash memory that is written in erase blocks, such as machine.Flash.
+type BlockDevice interface {
+	io.ReaderAt
+	io.WriterAt
+	Size() int64
+	EraseBlockSize() int64
+	EraseBlocks(start, length int64) error
+}
+
+// BlockStorage is a Storage that keeps every key in a single record at the start of a BlockDevice.
+// The record is a 4 byte length followed by the keys and data as JSON.
+type BlockStorage struct {
+	device BlockDevice
+	data   map[string][]byte
+}
+
+// NewBlockStorage creates a BlockStorage and reads what was saved on the BlockDevice. Blank or corrupt flash is treated as empty.
+func NewBlockStorage(device BlockDevice) (s *BlockStorage) {
+	s = &BlockStorage{device: device, data: map[string][]byte{}}
+	header := make([]byte, 4)
+	_, err := device.ReadAt(header, 0)
+	if err != nil {
+		return s
+	}
+	length := int64(binary.LittleEndian.Uint32(header))
+	if length == 0 || length > device.Size()-4 {
+		return s
+	}
+	record := make([]byte, length)
+	_, err = device.ReadAt(record, 4)
+	if err != nil {
+		return s
+	}
+	data := map[string][]byte{}
+	if json.Unmarshal(record, &data) == nil {
+		s.data = data
+	}
+	return s
+}
+
+// Load returns a copy of the data saved with a key.
+func (s *BlockStorage) Load(key string) (data []byte, err error) {
+	saved, ok := s.data[key]
+	if !ok {
+		return nil, picodoomsdaymessenger.ErrStorageNotFound
+	}
+	return append([]byte{}, saved...), nil
+}
+
+// Save keeps a copy of the data with a key, and rewrites the record on the BlockDevice.
+func (s *BlockStorage) Save(key string, data []byte) (err error) {
+	previous, existed := s.data[key]
+	s.data[key] = append([]byte{}, data...)
+	record, err := json.Marshal(s.data)
+	if err == nil && int64(len(record))+4 > s.device.Size() {
+		err = ErrStorageFull
+	}
+	if err != nil {
+		// Keep the data in memory the same as the data on the BlockDevice.
+		if existed {
+			s.data[key] = previous
+		} else {
+			delete(s.data, key)
+		}
+		return err
+	}
+	record = append(binary.LittleEndian.AppendUint32(nil, uint32(len(record))), record...)
+	blockSize := s.device.EraseBlockSize()
+	err = s.device.EraseBlocks(0, (int64(len(record))+blockSize-1)/blockSize)
+	if err != nil {
+		return err
+	}
+	_, err = s.device.WriteAt(record, 0)
+	return err
+}
@@ -0,0 +1,54 @@
+package picodoomsdaymessenger
+
+import (
+	"image"
+	"testing"
+)
+
+func TestFaceSmall(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 12, 8))
+	drawTextFace(img, FaceSmall, 0, 7, "I", MonochromePalette.Text)
+	// The I is a vertical line down the middle of its cell, from the top to the baseline.
+	for y := 0; y < 7; y++ {
+		if img.RGBAAt(2, y) != MonochromePalette.Text {
+			t.Errorf("The middle of the I should be lit at y=%d", y)
+		}
+	}
+	if img.RGBAAt(2, 7) == MonochromePalette.Text || img.RGBAAt(8, 3) == MonochromePalette.Text {
+		t.Errorf("Only the I should be drawn")
+	}
+	if _, ok := FaceSmall.GlyphAdvance('~'); !ok {
+		t.Errorf("Every printable ASCII character should be in the small face")
+	}
+}
+
+func TestTextSizeLayouts(t *testing.T) {
+	// Create a new Machine
+	device, err := NewDevice()
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	if device.Face() != FaceNormal {
+		t.Errorf("The default face should be the 7x13 font")
+	}
+	for _, size := range TextSizes {
+		device.Settings.TextSize = size
+		layout := device.Layout()
+		if layout.Face != size.Face() {
+			t.Errorf("The %s layout should use the %s face", size, size)
+		}
+		if layout.ComposerBaseline > device.Display.Height || layout.HighlightBaseline-layout.TextHeight < layout.TitleHeight {
+			t.Errorf("The %s layout does not fit on the screen: %+v", size, layout)
+		}
+		if _, err = GetFrame(device.Display.Bounds(), device); err != nil {
+			t.Errorf("The error should be nil but is %v", err)
+		}
+	}
+	if NewLayout(DisplaySSD1306.Bounds()) != NewFaceLayout(DisplaySSD1306.Bounds(), TextSizeNormal.Face()) {
+		t.Errorf("The normal text size should have the same layout as NewLayout")
+	}
+	device.Font = FaceLarge
+	if device.Layout().Face != FaceLarge {
+		t.Errorf("The Font of the Device should be used over the TextSize")
+	}
+}
@@ -0,0 +1,169 @@
+package picodoomsdaymessenger
+
+import "errors"
+
+// Define function key errors
+var (
+	ErrNotFunctionKey = errors.New("input event is not a function key")
+)
+
+// FunctionKeys are the InputEvents of the function keys, in the order of the FunctionKeys in the Settings.
+var FunctionKeys = [4]InputEvent{InputEventFunction1, InputEventFunction2, InputEventFunction3, InputEventFunction4}
+
+// FunctionKeyDefaults describe what each of the FunctionKeys does in the conversation reader while it is not bound to anything. Each of them can also be done from the options of a Conversation, so binding a key to something else does not take it away.
+var FunctionKeyDefaults = [4]string{"Listen only", "Quick replies", "Emergency", "Pin message"}
+
+// FunctionKeyAction is something that a function key can be set to do.
+type FunctionKeyAction string
+
+const (
+	// FunctionKeyActionDefault leaves the function key doing what it normally does, such as pinning a message in the conversation reader.
+	FunctionKeyActionDefault FunctionKeyAction = ""
+	// FunctionKeyActionOpenConversation opens a Conversation from anywhere.
+	FunctionKeyActionOpenConversation FunctionKeyAction = "conversation"
+	// FunctionKeyActionToggleSOS turns SOS mode on or off.
+	FunctionKeyActionToggleSOS FunctionKeyAction = "sos"
+	// FunctionKeyActionQuickReply sends a quick reply to the Conversation that is being read.
+	FunctionKeyActionQuickReply FunctionKeyAction = "quick reply"
+)
+
+// FunctionKeyBinding is what a function key has been set to do.
+type FunctionKeyBinding struct {
+	Action FunctionKeyAction
+	// Conversation is the Name of the Conversation that FunctionKeyActionOpenConversation opens.
+	Conversation string
+	// QuickReply is the text that FunctionKeyActionQuickReply sends. The text is kept, so the key carries on working if the quick reply is edited.
+	QuickReply string
+	// key is the function key that the binding is for, so that String can say what the key does by default. It is not saved, and is only set by functionKeyBinding.
+	key InputEvent
+}
+
+// String describes what the function key does, to show in the Settings. The default of a key is only described if the binding knows which key it is for.
+func (b FunctionKeyBinding) String() string {
+	switch b.Action {
+	case FunctionKeyActionOpenConversation:
+		return "Open " + b.Conversation
+	case FunctionKeyActionToggleSOS:
+		return "Toggle SOS"
+	case FunctionKeyActionQuickReply:
+		return "Reply " + b.QuickReply
+	}
+	if i := functionKeyIndex(b.key); i >= 0 {
+		return "Default: " + FunctionKeyDefaults[i]
+	}
+	return "Default"
+}
+
+// functionKeyBinding returns a binding for the function key at an index of the FunctionKeys, that knows which key it is for.
+func functionKeyBinding(i int, binding FunctionKeyBinding) FunctionKeyBinding {
+	binding.key = FunctionKeys[i]
+	return binding
+}
+
+// functionKeyIndex returns the index of a function key in the FunctionKeys, or -1 if the InputEvent is not a function key.
+func functionKeyIndex(key InputEvent) int {
+	for i, functionKey := range FunctionKeys {
+		if functionKey == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// BindFunctionKey sets what a function key does. Binding FunctionKeyActionDefault puts the key back to what it normally does.
+// The binding is kept in the Settings, so it is saved with them.
+func (d *Device) BindFunctionKey(key InputEvent, binding FunctionKeyBinding) (err error) {
+	i := functionKeyIndex(key)
+	if i < 0 {
+		return ErrNotFunctionKey
+	}
+	d.Settings.FunctionKeys[i] = binding
+	return nil
+}
+
+// processFunctionKey does what a function key has been bound to do. It returns false if the InputEvent is not a function key, or the key has not been bound to anything.
+func (d *Device) processFunctionKey(inputEvent InputEvent) (handled bool, err error) {
+	i := functionKeyIndex(inputEvent)
+	if i < 0 {
+		return false, nil
+	}
+	binding := d.Settings.FunctionKeys[i]
+	switch binding.Action {
+	case FunctionKeyActionOpenConversation:
+		for j, c := range d.Conversations {
+			if c.Name == binding.Conversation {
+				return true, d.OpenConversation(j)
+			}
+		}
+		return true, ErrConversationNotFound
+	case FunctionKeyActionToggleSOS:
+		return true, d.SetSOS(!d.sos.Active)
+	case FunctionKeyActionQuickReply:
+		// Quick replies go to the Conversation that is being read, so there is nowhere to send them from other States.
+		if d.State != &d.StateConversationReader {
+			return true, nil
+		}
+		return true, d.SendMessage(d.Conversations[d.CurrentConversationIndex], binding.QuickReply)
+	}
+	return false, nil
+}
+
+// The function key menus are refreshed every time they are shown, so that they list the Conversations and QuickReplies as they are now.
+func init() {
+	stateSetups = append(stateSetups, func(s *States) {
+		s.StateFunctionKeysMenu.LoadAction = func(d *Device) (err error) {
+			d.UpdateFunctionKeysMenu()
+			return nil
+		}
+		s.StateFunctionKeyMenu.LoadAction = func(d *Device) (err error) {
+			d.UpdateFunctionKeyMenu()
+			return nil
+		}
+	})
+}
+
+// UpdateFunctionKeysMenu lists the function keys, with what each one does under it.
+func (d *Device) UpdateFunctionKeysMenu() {
+	d.StateFunctionKeysMenu.Content = []MenuItem{GlobalMenuItemGoBack}
+	for i := range FunctionKeys {
+		j := i
+		d.StateFunctionKeysMenu.Content = append(d.StateFunctionKeysMenu.Content, MenuItem{
+			Text:   "F" + string(rune('1'+j)),
+			Detail: functionKeyBinding(j, d.Settings.FunctionKeys[j]).String(),
+			Action: func(d *Device) (err error) {
+				d.functionKey = j
+				d.StateFunctionKeyMenu.HighlightedItemIndex = 0
+				return d.ChangeStateWithHistory(&d.StateFunctionKeyMenu)
+			},
+			CursorIcon: CursorIconRightArrow,
+		})
+	}
+}
+
+// UpdateFunctionKeyMenu lists everything that the function key being set can do: its default, toggling SOS, opening each Conversation and sending each quick reply.
+func (d *Device) UpdateFunctionKeyMenu() {
+	bindings := []FunctionKeyBinding{{Action: FunctionKeyActionDefault}, {Action: FunctionKeyActionToggleSOS}}
+	for _, c := range d.Conversations {
+		bindings = append(bindings, FunctionKeyBinding{Action: FunctionKeyActionOpenConversation, Conversation: c.Name})
+	}
+	for _, reply := range d.QuickReplies {
+		bindings = append(bindings, FunctionKeyBinding{Action: FunctionKeyActionQuickReply, QuickReply: reply})
+	}
+	names := make([]string, len(bindings))
+	for i, binding := range bindings {
+		names[i] = functionKeyBinding(d.functionKey, binding).String()
+	}
+	d.StateFunctionKeyMenu.Title = "F" + string(rune('1'+d.functionKey))
+	d.StateFunctionKeyMenu.Content = append([]MenuItem{GlobalMenuItemGoBack}, choiceMenuItems(names, func(d *Device, i int) bool {
+		return d.Settings.FunctionKeys[d.functionKey] == bindings[i]
+	}, func(d *Device, i int) (err error) {
+		err = d.BindFunctionKey(FunctionKeys[d.functionKey], bindings[i])
+		if err != nil {
+			return err
+		}
+		return d.GoBackState()
+	})...)
+	if d.StateFunctionKeyMenu.HighlightedItemIndex >= len(d.StateFunctionKeyMenu.Content) {
+		d.StateFunctionKeyMenu.HighlightedItemIndex = 0
+	}
+}
@@ -0,0 +1,145 @@
+package picodoomsdaymessenger
+
+import (
+	"testing"
+)
+
+func TestFunctionKeys(t *testing.T) {
+	device, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	device.State = &device.StateMainMenu
+	device.SendUsingRadio = func(packet []byte) (err error) { return nil }
+	if err = device.ReceiveFromRadio([]byte("doom7\xccBob\xcchello")); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	c := device.Conversations[0]
+	if err = device.BindFunctionKey(InputEventAccept, FunctionKeyBinding{}); err != ErrNotFunctionKey {
+		t.Errorf("expected ErrNotFunctionKey, got %v", err)
+	}
+
+	if err = device.BindFunctionKey(InputEventFunction1, FunctionKeyBinding{Action: FunctionKeyActionOpenConversation, Conversation: c.Name}); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if err = device.ProcessInputEvent(InputEventFunction1); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if device.State != &device.StateConversationReader || device.Conversations[device.CurrentConversationIndex] != c {
+		t.Fatalf("expected F1 to open the conversation, got %q", device.State.Title)
+	}
+
+	if err = device.BindFunctionKey(InputEventFunction2, FunctionKeyBinding{Action: FunctionKeyActionQuickReply, QuickReply: "On my way"}); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if err = device.ProcessInputEvent(InputEventFunction2); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if last := c.Messages[len(c.Messages)-1]; last.Text != "On my way" {
+		t.Errorf("expected F2 to send the quick reply, got %q", last.Text)
+	}
+
+	if err = device.BindFunctionKey(InputEventFunction4, FunctionKeyBinding{Action: FunctionKeyActionToggleSOS}); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if err = device.ProcessInputEvent(InputEventFunction4); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if !device.sos.Active {
+		t.Errorf("expected F4 to turn SOS on")
+	}
+
+	// F3 has not been bound, so it still marks the message being written as an emergency.
+	if err = device.ProcessInputEvent(InputEventFunction3); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if c.Priority != PriorityEmergency {
+		t.Errorf("expected F3 to change the priority, got %v", c.Priority)
+	}
+}
+
+func TestFunctionKeysMenu(t *testing.T) {
+	storage := MemoryStorage{}
+	device, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	device.Storage = storage
+	device.State = &device.StateSettingsMenu
+	if err = device.ChangeStateWithHistory(&device.StateFunctionKeysMenu); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if detail := device.State.Content[1].Detail; detail != "Default: Listen only" {
+		t.Errorf("expected F1 to say what it does by default, got %q", detail)
+	}
+	// Choose F2, and then Toggle SOS, which comes after Default.
+	device.State.HighlightedItemIndex = 2
+	if err = device.ProcessInputEvent(InputEventAccept); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if device.State != &device.StateFunctionKeyMenu || device.State.Title != "F2" {
+		t.Fatalf("expected the F2 menu, got %q", device.State.Title)
+	}
+	if text := device.State.Content[1].Text; text != "Default: Quick replies" {
+		t.Errorf("expected the default of F2 to be described, got %q", text)
+	}
+	device.State.HighlightedItemIndex = 2
+	if err = device.ProcessInputEvent(InputEventAccept); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if device.State != &device.StateFunctionKeysMenu || device.State.Content[2].Detail != "Toggle SOS" {
+		t.Fatalf("expected to go back to the function keys with F2 set")
+	}
+
+	if err = device.SaveSettings(); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	restarted, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	restarted.Storage = storage
+	if err = restarted.LoadSettings(); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if restarted.Settings.FunctionKeys[1].Action != FunctionKeyActionToggleSOS {
+		t.Errorf("expected the function keys to be saved, got %v", restarted.Settings.FunctionKeys)
+	}
+}
+
+func TestReboundDefaultsInOptions(t *testing.T) {
+	device, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	device.State = &device.StateMainMenu
+	if err = device.ReceiveFromRadio([]byte("doom7\xccBob\xcchello")); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if err = device.BindFunctionKey(InputEventFunction1, FunctionKeyBinding{Action: FunctionKeyActionToggleSOS}); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	c := device.Conversations[0]
+	c.ListenOnly = true
+	if err = device.OpenConversation(0); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if err = device.ChangeStateWithHistory(&device.StateConversationOptionsMenu); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	for i, text := range FunctionKeyDefaults {
+		found := false
+		for _, item := range device.StateConversationOptionsMenu.Content {
+			found = found || item.Text == text
+		}
+		if !found {
+			t.Errorf("expected the default of F%d, %q, to be in the options", i+1, text)
+		}
+	}
+	if err = ConversationOptionsMenuItemListenOnly.Action(device); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if c.ListenOnly {
+		t.Errorf("expected listen only to be turned off from the options while F1 is bound to something else")
+	}
+}
@@ -0,0 +1,75 @@
+package picodoomsdaymessenger
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Define game errors
+var (
+	ErrInvalidGamePacket = errors.New("invalid game packet")
+	ErrUnknownGame       = errors.New("unknown game")
+)
+
+// gamePrefix is the start of every game packet, ASCII for "game".
+var gamePrefix = []byte{0x67, 0x61, 0x6D, 0x65}
+
+// GamePacket is a packet that carries the moves of a game between two devices.
+type GamePacket struct {
+	// Game is the name of the game, for example "pong".
+	Game string
+	// Person is the sender of the packet.
+	Person Person
+	// Data is the state of the game, in a format that belongs to the game.
+	Data []byte
+}
+
+// GamePacketToBytes encodes a GamePacket as "game", the name of the game, the ID of the sender and the data, separated by 0xcc.
+func GamePacketToBytes(input GamePacket) (output []byte) {
+	seperatorByte := byte(0xcc)
+	output = append(output, gamePrefix...)
+	output = append(output, []byte(input.Game)...)
+	output = append(output, seperatorByte)
+	output = append(output, []byte(fmt.Sprint(input.Person.ID))...)
+	output = append(output, seperatorByte)
+	output = append(output, input.Data...)
+	return output
+}
+
+// BytesToGamePacket decodes a game packet.
+func BytesToGamePacket(input []byte) (output GamePacket, err error) {
+	if !bytes.HasPrefix(input, gamePrefix) {
+		return output, ErrInvalidGamePacket
+	}
+	fields := bytes.SplitN(input[len(gamePrefix):], []byte{0xcc}, 3)
+	if len(fields) != 3 || len(fields[0]) == 0 {
+		return output, ErrInvalidGamePacket
+	}
+	id, err := strconv.Atoi(string(fields[1]))
+	if err != nil {
+		return output, ErrInvalidGamePacket
+	}
+	output.Game = string(fields[0])
+	output.Person = Person{ID: id}
+	output.Data = fields[2]
+	return output, nil
+}
+
+// SendGamePacket sends the data of a game to the other player.
+func (d *Device) SendGamePacket(game string, data []byte) (err error) {
+	return d.SendPacket(nil, GamePacketToBytes(GamePacket{Game: game, Person: d.SelfIdentity, Data: data}))
+}
+
+// receiveGamePacket passes a received GamePacket to the game that it belongs to.
+func (d *Device) receiveGamePacket(packet GamePacket, now time.Time) (err error) {
+	switch packet.Game {
+	case pongGameName:
+		return d.receivePong(packet, now)
+	case ticTacToeGameName:
+		return d.receiveTicTacToe(packet)
+	}
+	return ErrUnknownGame
+}
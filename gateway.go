@@ -0,0 +1,136 @@
+package picodoomsdaymessenger
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// GatewayEventType is the kind of a GatewayEvent.
+type GatewayEventType string
+
+const (
+	// GatewayEventMessage is emitted when a message is received.
+	GatewayEventMessage GatewayEventType = "message"
+	// GatewayEventSOS is emitted when SOS mode is turned on, or an SOS is received from another node.
+	GatewayEventSOS GatewayEventType = "sos"
+	// GatewayEventNodeJoined is emitted when a node is heard for the first time, or again after it has left.
+	GatewayEventNodeJoined GatewayEventType = "joined"
+	// GatewayEventNodeLeft is emitted when a node has not been heard from for GatewayNodeTimeout.
+	GatewayEventNodeLeft GatewayEventType = "left"
+)
+
+// GatewayNodeTimeout is how long a node can go unheard before a GatewayEventNodeLeft is emitted for it.
+var GatewayNodeTimeout = 10 * time.Minute
+
+// GatewayEvent is a structured event that is emitted to a base-station computer when the Device is a gateway.
+//
+// Each event is sent to SendToGateway as a single line of JSON, for example:
+//
+//	{"type":"message","time":1670000000,"id":1234,"name":"You","text":"hello"}
+//
+// The type is one of "message", "sos", "joined" or "left" and the time is in Unix seconds.
+// The id and name are of the node that caused the event, and text is only present for messages.
+type GatewayEvent struct {
+	Type       GatewayEventType `json:"type"`
+	Time       int64            `json:"time"`
+	PersonID   int              `json:"id"`
+	PersonName string           `json:"name,omitempty"`
+	Text       string           `json:"text,omitempty"`
+}
+
+// EmitGatewayEvent sends a GatewayEvent to SendToGateway. Nothing is emitted unless the Device is in gateway mode and SendToGateway is defined.
+// If the event has no time, the current time of the Device is used.
+func (d *Device) EmitGatewayEvent(event GatewayEvent) (err error) {
+	if !d.Settings.Gateway || d.SendToGateway == nil {
+		return nil
+	}
+	if event.Time == 0 {
+		event.Time = d.Now().Unix()
+	}
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return d.SendToGateway(append(line, '\n'))
+}
+
+// heardGatewayNode records that a Person has been heard, and emits a GatewayEventNodeJoined if it is new.
+// Nodes are only recorded while the Device is a gateway, so that nodes heard before gateway mode was turned on still join when they are next heard.
+func (d *Device) heardGatewayNode(p Person, now time.Time) (err error) {
+	if !d.Settings.Gateway {
+		d.gatewayNodes = nil
+		return nil
+	}
+	if d.gatewayNodes == nil {
+		d.gatewayNodes = make(map[int]time.Time)
+	}
+	_, known := d.gatewayNodes[p.ID]
+	d.gatewayNodes[p.ID] = now
+	if known {
+		return nil
+	}
+	return d.EmitGatewayEvent(GatewayEvent{Type: GatewayEventNodeJoined, PersonID: p.ID, PersonName: p.Name})
+}
+
+// Tick runs the periodic work of the Device, it should be called regularly from the main loop.
+func (d *Device) Tick(now time.Time) (err error) {
+	err = d.sendBeaconIfDue(now)
+	if err != nil {
+		return err
+	}
+	d.tickMorse(now)
+	d.tickMultiTap(now)
+	d.tickMarquee(now)
+	err = d.tickNotification(now)
+	if err != nil {
+		return err
+	}
+	err = d.tickTones(now)
+	if err != nil {
+		return err
+	}
+	if d.State.OnTick != nil {
+		err = d.State.OnTick(d, now)
+		if err != nil {
+			return err
+		}
+	}
+	err = d.tickScanning(now)
+	if err != nil {
+		return err
+	}
+	err = d.tickSOS(now)
+	if err != nil {
+		return err
+	}
+	err = d.tickSchedule(now)
+	if err != nil {
+		return err
+	}
+	err = d.tickScreensaver(now)
+	if err != nil {
+		return err
+	}
+	if !d.Settings.Gateway {
+		d.gatewayNodes = nil
+	}
+	// Nodes are heard with the corrected clock of the Device.
+	clock := now.Add(d.TimeOffset)
+	for id, lastHeard := range d.gatewayNodes {
+		if clock.Sub(lastHeard) < GatewayNodeTimeout {
+			continue
+		}
+		delete(d.gatewayNodes, id)
+		err = d.EmitGatewayEvent(GatewayEvent{Type: GatewayEventNodeLeft, PersonID: id})
+		if err != nil {
+			return err
+		}
+	}
+	// The radio is woken before packets are sent, if any are waiting.
+	err = d.tickListenSchedule(now)
+	if err != nil {
+		return err
+	}
+	// Packets are sent last, so that those queued by this Tick do not wait for the next one.
+	return d.sendQueuedPackets(now)
+}
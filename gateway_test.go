@@ -0,0 +1,107 @@
+package picodoomsdaymessenger
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestEmitGatewayEvent(t *testing.T) {
+	// Create a new Machine
+	device, err := NewDevice()
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	lines := []string{}
+	device.SendToGateway = func(event []byte) (err error) {
+		lines = append(lines, string(event))
+		return nil
+	}
+
+	// Events should not be emitted unless gateway mode is on.
+	err = device.EmitGatewayEvent(GatewayEvent{Type: GatewayEventSOS})
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	if len(lines) != 0 {
+		t.Errorf("No events should have been emitted but %v were", lines)
+	}
+
+	device.Settings.Gateway = true
+	err = device.EmitGatewayEvent(GatewayEvent{Type: GatewayEventMessage, Time: 10, PersonID: 5, PersonName: "Test", Text: "hi"})
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	want := `{"type":"message","time":10,"id":5,"name":"Test","text":"hi"}` + "\n"
+	if len(lines) != 1 || lines[0] != want {
+		t.Errorf("The emitted event should be %q but is %q", want, lines)
+	}
+}
+
+func TestGatewayNodeJoinedAndLeft(t *testing.T) {
+	// Create a new Machine
+	device, err := NewDevice()
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	events := []GatewayEvent{}
+	device.Settings.Gateway = true
+	device.SendToGateway = func(line []byte) (err error) {
+		var event GatewayEvent
+		err = json.Unmarshal(line, &event)
+		events = append(events, event)
+		return err
+	}
+	now := time.Unix(1000, 0)
+	person := Person{Name: "Test", ID: 42}
+
+	err = device.heardGatewayNode(person, now)
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	err = device.heardGatewayNode(person, now.Add(time.Minute))
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	if len(events) != 1 || events[0].Type != GatewayEventNodeJoined || events[0].PersonID != 42 {
+		t.Errorf("A single joined event should have been emitted but have: %v", events)
+	}
+
+	err = device.Tick(now.Add(time.Minute + GatewayNodeTimeout))
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	if len(events) != 2 || events[1].Type != GatewayEventNodeLeft || events[1].PersonID != 42 {
+		t.Errorf("A left event should have been emitted but have: %v", events)
+	}
+}
+
+func TestGatewayNodeHeardBeforeGatewayMode(t *testing.T) {
+	// Create a new Machine
+	device, err := NewDevice()
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	events := []GatewayEvent{}
+	device.SendToGateway = func(line []byte) (err error) {
+		var event GatewayEvent
+		err = json.Unmarshal(line, &event)
+		events = append(events, event)
+		return err
+	}
+	now := time.Unix(1000, 0)
+	person := Person{Name: "Test", ID: 42}
+
+	err = device.heardGatewayNode(person, now)
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	device.Settings.Gateway = true
+	err = device.heardGatewayNode(person, now.Add(time.Minute))
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	if len(events) != 1 || events[0].Type != GatewayEventNodeJoined || events[0].PersonID != 42 {
+		t.Errorf("A joined event should have been emitted once gateway mode was turned on but have: %v", events)
+	}
+}
@@ -1,6 +1,6 @@
 module github.com/headblockhead/picoDoomsdayMessenger
 
-go 1.19
+go 1.20
 
 replace github.com/headblockhead/tinygorfm9x => ../tinygoRFM9X
 
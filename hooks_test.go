@@ -0,0 +1,83 @@
+package picodoomsdaymessenger
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestHooks(t *testing.T) {
+	device, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	device.State = &device.StateMainMenu
+
+	changes := [][2]*State{}
+	device.OnStateChanged = func(from, to *State) {
+		changes = append(changes, [2]*State{from, to})
+	}
+	err = device.ChangeStateWithHistory(&device.StateSettingsMenu)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	err = device.GoBackState()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if len(changes) != 2 || changes[0] != [2]*State{&device.StateMainMenu, &device.StateSettingsMenu} || changes[1] != [2]*State{&device.StateSettingsMenu, &device.StateMainMenu} {
+		t.Errorf("expected both state changes to be reported, got %v", changes)
+	}
+
+	var received []string
+	var events []DeviceEvent
+	device.OnMessageReceived = func(c *Conversation, m Message) {
+		received = append(received, c.Name+": "+m.Text)
+	}
+	device.OnDeviceEvent = func(event DeviceEvent) {
+		events = append(events, event)
+	}
+	err = device.ReceiveFromRadio([]byte("doom7\xccBob\xcchello"))
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if len(received) != 1 || received[0] != "7: hello" {
+		t.Errorf("expected the message to be reported with its conversation, got %q", received)
+	}
+
+	var results []error
+	device.OnSendResult = func(packet []byte, err error) {
+		results = append(results, err)
+	}
+	broken := errors.New("radio broken")
+	device.SendUsingRadio = func(packet []byte) (err error) {
+		return broken
+	}
+	var statuses []MessageStatus
+	device.OnMessageStatus = func(c *Conversation, m Message) {
+		statuses = append(statuses, m.Status)
+	}
+	if err = device.SendMessage(device.Conversations[0], "hi"); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if err = sendQueued(device); err != broken {
+		t.Fatalf("expected the radio error, got %v", err)
+	}
+	device.SendUsingRadio = func(packet []byte) (err error) {
+		return nil
+	}
+	if err = device.SendMessage(device.Conversations[0], "hi"); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if err = sendQueued(device); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if len(results) != 2 || results[0] != broken || results[1] != nil {
+		t.Errorf("expected both send results to be reported, got %v", results)
+	}
+	if len(statuses) != 2 || statuses[0] != MessageStatusFailed || statuses[1] != MessageStatusSent {
+		t.Errorf("expected the status of both messages to be reported, got %v", statuses)
+	}
+	if len(events) != 2 || events[0] != DeviceEventMessageReceived || events[1] != DeviceEventSendFailed {
+		t.Errorf("expected the device events to be reported, got %v", events)
+	}
+}
@@ -0,0 +1,94 @@
+package picodoomsdaymessenger
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+// ErrPacketExpired is returned when a packet that has no hops left is relayed.
+var ErrPacketExpired = errors.New("packet has no hops left")
+
+// hopPrefix is the start of the hop header that can be put in front of a packet, ASCII for "hops".
+var hopPrefix = []byte{0x68, 0x6F, 0x70, 0x73}
+
+// hopHeaderLength is the length of the hop header: the prefix, then a byte each for the TTL and the number of hops.
+const hopHeaderLength = 4 + 2
+
+// HopLimits are the choices of how many times a message can be relayed, in the Settings. Zero sends messages without a hop header, so that older devices can read them.
+var HopLimits = []int{0, 1, 2, 3, 5, 7}
+
+// HopHeader is how far a packet has travelled, and how much further it can go.
+type HopHeader struct {
+	// TTL is how many more times the packet can be relayed.
+	TTL int
+	// Hops is how many times the packet has been relayed.
+	Hops int
+}
+
+// AddHopHeader puts a hop header in front of a packet.
+func AddHopHeader(packet []byte, h HopHeader) (output []byte) {
+	output = make([]byte, 0, hopHeaderLength+len(packet))
+	output = append(output, hopPrefix...)
+	output = append(output, byte(h.TTL), byte(h.Hops))
+	return append(output, packet...)
+}
+
+// SplitHopHeader separates the hop header from the packet behind it. A packet without a hop header is returned as it is, with ok set to false.
+func SplitHopHeader(packet []byte) (h HopHeader, inner []byte, ok bool, err error) {
+	if !bytes.HasPrefix(packet, hopPrefix) {
+		return h, packet, false, nil
+	}
+	if len(packet) < hopHeaderLength {
+		return h, packet, false, ErrMalformedMessage
+	}
+	h.TTL = int(packet[len(hopPrefix)])
+	h.Hops = int(packet[len(hopPrefix)+1])
+	return h, packet[hopHeaderLength:], true, nil
+}
+
+// RelayPacket returns a packet ready to be sent on by a relay, with one less hop to live and one more hop taken.
+// Packets without a hop header are not relayed, and packets with no hops left are dropped with ErrPacketExpired.
+func RelayPacket(packet []byte) (relayed []byte, err error) {
+	h, inner, ok, err := SplitHopHeader(packet)
+	if err != nil {
+		return nil, err
+	}
+	if !ok || h.TTL <= 0 {
+		return nil, ErrPacketExpired
+	}
+	return AddHopHeader(inner, HopHeader{TTL: h.TTL - 1, Hops: h.Hops + 1}), nil
+}
+
+// addHopHeader puts a hop header with the HopLimit in the Settings in front of a message packet. Meshtastic packets have hop fields of their own, so they are left alone.
+// A packet that is too long to fit the header as well is sent without one, so it can only be heard directly.
+func (d *Device) addHopHeader(packet []byte) (output []byte) {
+	if d.Settings.HopLimit <= 0 || d.Settings.Meshtastic || len(packet)+hopHeaderLength > MaxPacketLength {
+		return packet
+	}
+	return AddHopHeader(packet, HopHeader{TTL: d.Settings.HopLimit})
+}
+
+// hopLimitSelector creates a Selector that chooses one of the HopLimits.
+func hopLimitSelector() Selector {
+	names := make([]string, len(HopLimits))
+	for i, limit := range HopLimits {
+		names[i] = fmt.Sprintf("%d", limit)
+	}
+	names[0] = "Direct"
+	return Selector{
+		Names: names,
+		Get: func(d *Device) int {
+			for i, limit := range HopLimits {
+				if d.Settings.HopLimit == limit {
+					return i
+				}
+			}
+			return -1
+		},
+		Set: func(d *Device, i int) (err error) {
+			d.Settings.HopLimit = HopLimits[i]
+			return nil
+		},
+	}
+}
@@ -0,0 +1,73 @@
+package picodoomsdaymessenger
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestRelayPacket(t *testing.T) {
+	packet := AddHopHeader([]byte("doom7\xccBob\xcchello"), HopHeader{TTL: 1})
+	relayed, err := RelayPacket(packet)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	h, inner, ok, err := SplitHopHeader(relayed)
+	if err != nil || !ok {
+		t.Fatalf("expected a hop header, got %v", err)
+	}
+	if h.TTL != 0 || h.Hops != 1 || !bytes.Equal(inner, []byte("doom7\xccBob\xcchello")) {
+		t.Errorf("expected one hop taken with the packet unchanged, got %+v %q", h, inner)
+	}
+	if _, err = RelayPacket(relayed); err != ErrPacketExpired {
+		t.Errorf("expected ErrPacketExpired, got %v", err)
+	}
+	if _, err = RelayPacket([]byte("doom7\xccBob\xcchello")); err != ErrPacketExpired {
+		t.Errorf("expected a packet without a header not to be relayed, got %v", err)
+	}
+	if _, _, _, err = SplitHopHeader([]byte("hops")); err != ErrMalformedMessage {
+		t.Errorf("expected ErrMalformedMessage for a short header, got %v", err)
+	}
+}
+
+func TestReceiveRelayedMessage(t *testing.T) {
+	sender, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	receiver, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	sender.Settings.HopLimit = 3
+	// Relay the packet twice on its way to the receiver.
+	sender.SendUsingRadio = func(packet []byte) (err error) {
+		if !bytes.HasPrefix(packet, hopPrefix) {
+			t.Errorf("expected the message to be sent with a hop header, got %q", packet)
+		}
+		for i := 0; i < 2; i++ {
+			packet, err = RelayPacket(packet)
+			if err != nil {
+				return err
+			}
+		}
+		return receiver.ReceiveFromRadio(packet)
+	}
+	err = sender.SendMessage(sender.NewConversation(receiver.SelfIdentity), "hello")
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	err = sendQueued(sender)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if len(receiver.Conversations) != 1 {
+		t.Fatalf("expected the message to be received")
+	}
+	message := receiver.Conversations[0].Messages[0]
+	if message.Hops != 2 || message.TTL != 1 {
+		t.Errorf("expected 2 hops with 1 left, got %d hops with %d left", message.Hops, message.TTL)
+	}
+	if text := message.DisplayText(); text != "hello (via 2 hops)" {
+		t.Errorf("expected the hops to be shown, got %q", text)
+	}
+}
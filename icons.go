@@ -0,0 +1,111 @@
+package picodoomsdaymessenger
+
+import "image/color"
+
+// IconSize is the width and height of an Icon in pixels.
+const IconSize = 8
+
+// Icon is an 8x8 bitmap that is drawn to the left of the text of a MenuItem. Each byte is a row, from the top, with the leftmost pixel in the highest bit.
+type Icon [IconSize]byte
+
+// Define Icons
+var (
+	// IconConversations is a speech bubble.
+	IconConversations = Icon{
+		0b11111111,
+		0b10000001,
+		0b10111101,
+		0b10000001,
+		0b10110001,
+		0b11111111,
+		0b01100000,
+		0b01000000,
+	}
+	// IconPeople is the head and shoulders of a person.
+	IconPeople = Icon{
+		0b00011000,
+		0b00111100,
+		0b00111100,
+		0b00011000,
+		0b01111110,
+		0b11111111,
+		0b11111111,
+		0b00000000,
+	}
+	// IconNotes is a page of writing with a folded corner.
+	IconNotes = Icon{
+		0b11111100,
+		0b10000110,
+		0b10111011,
+		0b10000001,
+		0b10111101,
+		0b10000001,
+		0b10111001,
+		0b11111111,
+	}
+	// IconGames is a game controller.
+	IconGames = Icon{
+		0b00000000,
+		0b01111110,
+		0b10100011,
+		0b11101101,
+		0b10100011,
+		0b01111110,
+		0b01000010,
+		0b00000000,
+	}
+	// IconDemos is a play button.
+	IconDemos = Icon{
+		0b10000000,
+		0b11100000,
+		0b11111000,
+		0b11111110,
+		0b11111110,
+		0b11111000,
+		0b11100000,
+		0b10000000,
+	}
+	// IconTools is a spanner.
+	IconTools = Icon{
+		0b00000110,
+		0b00001001,
+		0b00001010,
+		0b00010100,
+		0b00101000,
+		0b01010000,
+		0b10100000,
+		0b01000000,
+	}
+	// IconSettings is a cog.
+	IconSettings = Icon{
+		0b00011000,
+		0b01111110,
+		0b01100110,
+		0b11100111,
+		0b11100111,
+		0b01100110,
+		0b01111110,
+		0b00011000,
+	}
+)
+
+// drawIcon draws the set pixels of an Icon with its top left corner at a location.
+func drawIcon(img Canvas, icon *Icon, x, y int, col color.RGBA) {
+	for row, bits := range icon {
+		for column := 0; column < IconSize; column++ {
+			if bits&(0x80>>column) != 0 {
+				img.SetRGBA(x+column, y+row, col)
+			}
+		}
+	}
+}
+
+// drawMenuItemText draws the text of a MenuItem with its baseline at y, after its Icon if it has one. The first scroll characters of the text are left out, so that the end of a long item can be shown.
+func drawMenuItemText(img Canvas, layout Layout, item MenuItem, y int, scroll int, col color.RGBA) {
+	x := 0
+	if item.Icon != nil {
+		drawIcon(img, item.Icon, 0, y-IconSize, col)
+		x = IconSize + 2
+	}
+	drawTextFace(img, layout.Face, x, y, marqueeText(item.Text, scroll), col)
+}
@@ -0,0 +1,53 @@
+package picodoomsdaymessenger
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestDrawIcon(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 16, 16))
+	white := color.RGBA{255, 255, 255, 255}
+	drawIcon(img, &IconDemos, 2, 3, white)
+	for row, bits := range IconDemos {
+		for column := 0; column < IconSize; column++ {
+			set := bits&(0x80>>column) != 0
+			if got := img.RGBAAt(2+column, 3+row) == white; got != set {
+				t.Errorf("pixel %d,%d of the icon should be set: %v, but is %v", column, row, set, got)
+			}
+		}
+	}
+}
+
+func TestMenuItemIcon(t *testing.T) {
+	// Create a new Machine
+	device, err := NewDevice()
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	device.State = &device.StateMainMenu
+	frame := image.NewRGBA(DisplaySSD1306.Bounds())
+	err = RenderFrame(frame, device)
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	layout := device.Layout()
+	// The top of the head of the People item, which is below the highlighted item.
+	top := layout.HighlightBaseline + layout.LineHeight - IconSize
+	if frame.RGBAAt(3, top).R == 0 || frame.RGBAAt(0, top).R != 0 {
+		t.Errorf("The icon of the People item should be drawn to the left of its text")
+	}
+	// The highlighted item has its icon drawn on the highlight, and its text moved along.
+	highlighted := image.NewRGBA(image.Rect(0, 0, 128, 16))
+	drawMenuItemText(highlighted, layout, MainMenuItemConversations, 12, 0, color.RGBA{255, 255, 255, 255})
+	plain := image.NewRGBA(image.Rect(0, 0, 128, 16))
+	drawTextFace(plain, layout.Face, IconSize+2, 12, MainMenuItemConversations.Text, color.RGBA{255, 255, 255, 255})
+	for x := IconSize; x < 128; x++ {
+		for y := 0; y < 16; y++ {
+			if highlighted.RGBAAt(x, y) != plain.RGBAAt(x, y) {
+				t.Fatalf("The text of an item with an icon should start after the icon")
+			}
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package picodoomsdaymessenger
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// ErrEmptyName is returned when the name of the Device is set to nothing.
+var ErrEmptyName = errors.New("name cannot be empty")
+
+// identityStorageKey is the key that the SelfIdentity is saved with.
+const identityStorageKey = "identity"
+
+// SaveIdentity writes the SelfIdentity to the Storage of the Device. It does nothing if the Device has no Storage.
+func (d *Device) SaveIdentity() (err error) {
+	if d.Storage == nil {
+		return nil
+	}
+	data, err := json.Marshal(d.SelfIdentity)
+	if err != nil {
+		return err
+	}
+	return d.Storage.Save(identityStorageKey, data)
+}
+
+// LoadIdentity reads the SelfIdentity from the Storage of the Device.
+// The first time the Device starts there is nothing to load, so the random ID that it was created with is saved instead. This keeps the ID the same from then on, so that other devices can recognise it.
+func (d *Device) LoadIdentity() (err error) {
+	if d.Storage == nil {
+		return nil
+	}
+	data, err := d.Storage.Load(identityStorageKey)
+	if err == ErrStorageNotFound {
+		return d.SaveIdentity()
+	}
+	if err != nil {
+		return err
+	}
+	identity := Person{}
+	err = json.Unmarshal(data, &identity)
+	if err != nil {
+		return err
+	}
+	d.setSelfIdentity(identity)
+	return nil
+}
+
+// SetName changes the name that the Device sends with its messages, and saves it.
+func (d *Device) SetName(name string) (err error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return ErrEmptyName
+	}
+	name, _ = truncateString(name, MaxNameLength)
+	d.setSelfIdentity(Person{Name: name, ID: d.SelfIdentity.ID})
+	return d.SaveIdentity()
+}
+
+// setSelfIdentity changes the SelfIdentity, and updates the Conversations so that the messages sent by the Device are still shown as its own.
+func (d *Device) setSelfIdentity(identity Person) {
+	old := d.SelfIdentity
+	d.SelfIdentity = identity
+	for _, c := range d.Conversations {
+		for i := range c.People {
+			if c.People[i] == old {
+				c.People[i] = identity
+			}
+		}
+		for i := range c.Messages {
+			if c.Messages[i].Person == old {
+				c.Messages[i].Person = identity
+			}
+		}
+	}
+}
+
+// processNameEditorInputEvent types the new name, and saves it with accept.
+func processNameEditorInputEvent(d *Device, inputEvent InputEvent) (handled bool, err error) {
+	if d.processTextEntryInputEvent(&d.nameDraft, inputEvent) {
+		return true, nil
+	}
+	if inputEvent != InputEventAccept {
+		return false, nil
+	}
+	err = d.SetName(d.finishTextEntry(&d.nameDraft))
+	if err != nil && err != ErrEmptyName {
+		return true, err
+	}
+	// An empty name leaves the name as it was.
+	return true, d.GoBackState()
+}
+
+// drawNameEditor draws the name that is being typed, with the ID that goes with it.
+func drawNameEditor(d *Device, img Canvas, layout Layout) (err error) {
+	text := d.nameDraft + d.pendingCharacter() + "_\nID " + strconv.Itoa(d.SelfIdentity.ID)
+	drawTextPage(img, layout, d.Palette(), "Your Name", text, 0)
+	return nil
+}
@@ -0,0 +1,78 @@
+package picodoomsdaymessenger
+
+import "testing"
+
+func TestIdentityIsKept(t *testing.T) {
+	storage := MemoryStorage{}
+	device, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	device.Storage = storage
+	err = device.LoadIdentity()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	first := device.SelfIdentity
+
+	restarted, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	restarted.SelfIdentity.ID = first.ID + 1
+	restarted.Storage = storage
+	err = restarted.LoadIdentity()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if restarted.SelfIdentity != first {
+		t.Errorf("expected the identity %+v to be kept, got %+v", first, restarted.SelfIdentity)
+	}
+}
+
+func TestEditName(t *testing.T) {
+	device, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	storage := MemoryStorage{}
+	device.Storage = storage
+	c := device.NewConversation(Person{"Friend", 1})
+	c.Messages = append(c.Messages, Message{Person: device.SelfIdentity, Text: "hi"})
+
+	err = device.ChangeStateWithHistory(&device.StateSettingsMenu)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	err = SettingsMenuItemName.Action(device)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	// Delete "You" and type "Al" on the phone keypad.
+	for _, inputEvent := range []InputEvent{InputEventLeft, InputEventLeft, InputEventLeft, InputEventNumber2, InputEventNumber5, InputEventNumber5, InputEventNumber5} {
+		err = device.ProcessInputEvent(inputEvent)
+		if err != nil {
+			t.Fatalf("The error should be nil but is %v", err)
+		}
+	}
+	err = device.ProcessInputEvent(InputEventAccept)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if device.SelfIdentity.Name != "al" {
+		t.Fatalf("expected the name to be %q, got %q", "al", device.SelfIdentity.Name)
+	}
+	if device.State != &device.StateSettingsMenu {
+		t.Errorf("expected to go back to the settings")
+	}
+	if c.Messages[0].Person != device.SelfIdentity || c.People[0] != device.SelfIdentity {
+		t.Errorf("expected the conversation to follow the new name")
+	}
+	if _, err := storage.Load(identityStorageKey); err != nil {
+		t.Errorf("expected the name to be saved, got %v", err)
+	}
+
+	if err := device.SetName("  "); err != ErrEmptyName {
+		t.Errorf("expected ErrEmptyName, got %v", err)
+	}
+}
@@ -0,0 +1,202 @@
+package picodoomsdaymessenger
+
+import "time"
+
+// InputTiming changes how the Device handles buttons that bounce or are held down.
+type InputTiming struct {
+	// Debounce is how soon an InputEvent can follow the same InputEvent. Anything sooner is a bouncing contact, and is ignored. Zero turns it off.
+	Debounce time.Duration
+	// RepeatDelay is how long a button given to ProcessHeldInputs has to be held before it starts repeating. Zero turns repeating off.
+	RepeatDelay time.Duration
+	// RepeatInterval is the time between each repeat of a held button.
+	RepeatInterval time.Duration
+}
+
+// DefaultInputTiming repeats held buttons, but does not debounce, as most front ends debounce their own buttons.
+var DefaultInputTiming = InputTiming{
+	RepeatDelay:    500 * time.Millisecond,
+	RepeatInterval: 150 * time.Millisecond,
+}
+
+// RepeatingInputEvents are the buttons that repeat when they are held, so that holding Down keeps scrolling.
+var RepeatingInputEvents = []InputEvent{InputEventUp, InputEventDown}
+
+// ProcessHeldInputs takes in every button that is held down, and runs ProcessInputEvent for each button that has been pressed since the last call.
+// Buttons in RepeatingInputEvents are processed again every RepeatInterval once they have been held for the RepeatDelay.
+// Front ends that can tell whether a button is held should call it every loop, instead of calling ProcessInputEvent and waiting for the button to be let go.
+func (d *Device) ProcessHeldInputs(held []InputEvent, now time.Time) (err error) {
+	if d.heldInputs == nil {
+		d.heldInputs = make(map[InputEvent]time.Time)
+	}
+	for event := range d.heldInputs {
+		if !containsInputEvent(held, event) {
+			delete(d.heldInputs, event)
+		}
+	}
+	for _, event := range held {
+		nextRepeat, ok := d.heldInputs[event]
+		if ok && (d.InputTiming.RepeatDelay <= 0 || !containsInputEvent(RepeatingInputEvents, event) || now.Before(nextRepeat)) {
+			continue
+		}
+		if ok {
+			d.heldInputs[event] = now.Add(d.InputTiming.RepeatInterval)
+		} else {
+			d.heldInputs[event] = now.Add(d.InputTiming.RepeatDelay)
+		}
+		err = d.ProcessInputEvent(event)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// containsInputEvent returns true if an InputEvent is in a list.
+func containsInputEvent(events []InputEvent, event InputEvent) bool {
+	for _, e := range events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// rotaryTransitions gives the direction of every change between two states of a rotary encoder's A and B pins, indexed by the old state times 4 plus the new state.
+// Invalid changes, where both pins change at once, are 0.
+var rotaryTransitions = [16]int{0, -1, 1, 0, 1, 0, 0, -1, -1, 0, 0, 1, 0, 1, -1, 0}
+
+// RotaryEncoder turns the pins of a rotary encoder with a push button into InputEvents.
+// Turning it clockwise is InputEventDown, turning it anticlockwise is InputEventUp and pressing it is InputEventAccept.
+type RotaryEncoder struct {
+	// StepsPerDetent is the number of pin changes between each click of the encoder.
+	StepsPerDetent int
+	state          int
+	steps          int
+	pressed        bool
+}
+
+// NewRotaryEncoder creates a RotaryEncoder for the common encoders that have 4 pin changes between each click.
+func NewRotaryEncoder() *RotaryEncoder {
+	return &RotaryEncoder{StepsPerDetent: 4}
+}
+
+// Update takes in the current level of the A and B pins and the push button, and returns the InputEvents that have happened since the last Update.
+// It should be called often enough to see every change of the pins.
+func (r *RotaryEncoder) Update(a bool, b bool, pressed bool) (events []InputEvent) {
+	state := 0
+	if a {
+		state |= 2
+	}
+	if b {
+		state |= 1
+	}
+	r.steps += rotaryTransitions[r.state*4+state]
+	r.state = state
+	for r.steps >= r.StepsPerDetent {
+		r.steps -= r.StepsPerDetent
+		events = append(events, InputEventDown)
+	}
+	for r.steps <= -r.StepsPerDetent {
+		r.steps += r.StepsPerDetent
+		events = append(events, InputEventUp)
+	}
+	if pressed && !r.pressed {
+		events = append(events, InputEventAccept)
+	}
+	r.pressed = pressed
+	return events
+}
+
+// DPad turns the buttons of a 5 button D-pad into InputEvents. The center button is InputEventAccept.
+type DPad struct {
+	pressed [5]bool
+}
+
+// dPadEvents is the InputEvent of each D-pad button, in the order that they are given to Update.
+var dPadEvents = [5]InputEvent{InputEventUp, InputEventDown, InputEventLeft, InputEventRight, InputEventAccept}
+
+// Update takes in whether each button is held down, and returns an InputEvent for every button that has been pressed since the last Update.
+func (p *DPad) Update(up bool, down bool, left bool, right bool, center bool) (events []InputEvent) {
+	for i, pressed := range [5]bool{up, down, left, right, center} {
+		if pressed && !p.pressed[i] {
+			events = append(events, dPadEvents[i])
+		}
+		p.pressed[i] = pressed
+	}
+	return events
+}
+
+// KeypadRow is a pin that drives a row of a button matrix, such as a machine.Pin that is configured as an output.
+type KeypadRow interface {
+	High()
+	Low()
+}
+
+// KeypadColumn is a pin that reads a column of a button matrix, such as a machine.Pin that is configured as an input with a pulldown.
+type KeypadColumn interface {
+	Get() bool
+}
+
+// DefaultKeypadDebounce is how long a button of a MatrixKeypad has to stay the same before the change is believed.
+const DefaultKeypadDebounce = 20 * time.Millisecond
+
+// MatrixKeypad turns a button matrix into InputEvents. Each row is pulsed high in turn, and the columns that read high are the buttons that are pressed in that row.
+type MatrixKeypad struct {
+	Rows    []KeypadRow
+	Columns []KeypadColumn
+	// Keymap is the InputEvent of each button, by row then column.
+	Keymap [][]InputEvent
+	// Debounce is how long a button has to read the same before it counts as pressed or released, so that a bouncing contact is only one press.
+	Debounce time.Duration
+	keys     []matrixKey
+}
+
+// matrixKey is the debounce state of one button of a MatrixKeypad.
+type matrixKey struct {
+	// reading is the last level of the button, and changed is when it last changed.
+	reading bool
+	changed time.Time
+	// pressed is the level once it has stopped bouncing.
+	pressed bool
+}
+
+// NewMatrixKeypad creates a MatrixKeypad with the DefaultKeypadDebounce. The pins should already be configured, with every row low.
+func NewMatrixKeypad(rows []KeypadRow, columns []KeypadColumn, keymap [][]InputEvent) *MatrixKeypad {
+	return &MatrixKeypad{Rows: rows, Columns: columns, Keymap: keymap, Debounce: DefaultKeypadDebounce}
+}
+
+// Scan reads the button matrix, and returns the InputEvent of every button that is held down.
+func (k *MatrixKeypad) Scan() (events []InputEvent) {
+	return k.ScanAt(time.Now())
+}
+
+// ScanAt is Scan at a given time. A button is only counted as held, or let go, once it has stopped bouncing.
+func (k *MatrixKeypad) ScanAt(now time.Time) (events []InputEvent) {
+	if len(k.keys) != len(k.Rows)*len(k.Columns) {
+		k.keys = make([]matrixKey, len(k.Rows)*len(k.Columns))
+	}
+	for r, row := range k.Rows {
+		row.High()
+		for c, col := range k.Columns {
+			key := &k.keys[r*len(k.Columns)+c]
+			reading := col.Get()
+			if reading != key.reading {
+				key.reading = reading
+				key.changed = now
+			}
+			if key.reading != key.pressed && now.Sub(key.changed) >= k.Debounce {
+				key.pressed = key.reading
+			}
+			if key.pressed && r < len(k.Keymap) && c < len(k.Keymap[r]) && k.Keymap[r][c] != "" {
+				events = append(events, k.Keymap[r][c])
+			}
+		}
+		row.Low()
+	}
+	return events
+}
+
+// Update scans the button matrix and gives the held buttons to a Device, which presses and repeats them.
+func (k *MatrixKeypad) Update(d *Device, now time.Time) (err error) {
+	return d.ProcessHeldInputs(k.ScanAt(now), now)
+}
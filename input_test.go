@@ -0,0 +1,172 @@
+package picodoomsdaymessenger
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRotaryEncoder(t *testing.T) {
+	encoder := NewRotaryEncoder()
+	var events []InputEvent
+	// One click one way goes through every state of the pins.
+	for _, pins := range [][2]bool{{true, false}, {true, true}, {false, true}, {false, false}} {
+		events = append(events, encoder.Update(pins[0], pins[1], false)...)
+	}
+	if !reflect.DeepEqual(events, []InputEvent{InputEventDown}) {
+		t.Errorf("Turning one way should be down but is %v", events)
+	}
+	events = nil
+	for _, pins := range [][2]bool{{false, true}, {true, true}, {true, false}, {false, false}} {
+		events = append(events, encoder.Update(pins[0], pins[1], false)...)
+	}
+	if !reflect.DeepEqual(events, []InputEvent{InputEventUp}) {
+		t.Errorf("Turning the other way should be up but is %v", events)
+	}
+	// Holding the button should only accept once.
+	events = append(encoder.Update(false, false, true), encoder.Update(false, false, true)...)
+	if !reflect.DeepEqual(events, []InputEvent{InputEventAccept}) {
+		t.Errorf("Pressing the button should accept once but is %v", events)
+	}
+}
+
+func TestDPad(t *testing.T) {
+	dpad := &DPad{}
+	events := dpad.Update(true, false, false, false, true)
+	if !reflect.DeepEqual(events, []InputEvent{InputEventUp, InputEventAccept}) {
+		t.Errorf("The events should be up and accept but are %v", events)
+	}
+	events = dpad.Update(true, false, false, true, false)
+	if !reflect.DeepEqual(events, []InputEvent{InputEventRight}) {
+		t.Errorf("The events should only be right but are %v", events)
+	}
+}
+
+// fakeMatrix is a button matrix, with a pin for each row and column.
+type fakeMatrix struct {
+	pressed [2][2]bool
+	row     int
+}
+
+type fakeMatrixRow struct {
+	matrix *fakeMatrix
+	index  int
+}
+
+func (r fakeMatrixRow) High() { r.matrix.row = r.index }
+func (r fakeMatrixRow) Low()  { r.matrix.row = -1 }
+
+type fakeMatrixColumn struct {
+	matrix *fakeMatrix
+	index  int
+}
+
+func (c fakeMatrixColumn) Get() bool {
+	return c.matrix.row >= 0 && c.matrix.pressed[c.matrix.row][c.index]
+}
+
+func TestMatrixKeypad(t *testing.T) {
+	matrix := &fakeMatrix{row: -1}
+	keypad := NewMatrixKeypad(
+		[]KeypadRow{fakeMatrixRow{matrix, 0}, fakeMatrixRow{matrix, 1}},
+		[]KeypadColumn{fakeMatrixColumn{matrix, 0}, fakeMatrixColumn{matrix, 1}},
+		[][]InputEvent{{InputEventUp, InputEventDown}, {InputEventLeft, InputEventAccept}},
+	)
+	now := time.Now()
+	matrix.pressed[1][0] = true
+	if events := keypad.ScanAt(now); len(events) != 0 {
+		t.Errorf("A button should not be pressed until it has stopped bouncing, but the events are %v", events)
+	}
+	// The contact bounces open again before settling.
+	matrix.pressed[1][0] = false
+	keypad.ScanAt(now.Add(5 * time.Millisecond))
+	matrix.pressed[1][0] = true
+	keypad.ScanAt(now.Add(10 * time.Millisecond))
+	if events := keypad.ScanAt(now.Add(20 * time.Millisecond)); len(events) != 0 {
+		t.Errorf("A bounce should restart the debounce, but the events are %v", events)
+	}
+	events := keypad.ScanAt(now.Add(30 * time.Millisecond))
+	if !reflect.DeepEqual(events, []InputEvent{InputEventLeft}) {
+		t.Errorf("The events should be left but are %v", events)
+	}
+	if events = keypad.ScanAt(now.Add(time.Second)); !reflect.DeepEqual(events, []InputEvent{InputEventLeft}) {
+		t.Errorf("A held button should still be held, but the events are %v", events)
+	}
+	if matrix.row != -1 {
+		t.Error("Every row should be low after a scan")
+	}
+
+	device, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	device.State = &device.StateMainMenu
+	defer func() { device.StateMainMenu.HighlightedItemIndex = 0 }()
+	matrix.pressed[1][0] = false
+	matrix.pressed[0][1] = true
+	keypad.Debounce = 0
+	err = keypad.Update(device, now.Add(2*time.Second))
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if device.StateMainMenu.HighlightedItemIndex != 1 {
+		t.Errorf("Down should have been given to the Device, but the highlighted item is %d", device.StateMainMenu.HighlightedItemIndex)
+	}
+}
+
+func TestProcessHeldInputs(t *testing.T) {
+	device, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	device.State = &device.StateMainMenu
+	defer func() { device.StateMainMenu.HighlightedItemIndex = 0 }()
+	now := time.Now()
+	// Down is pressed once, and then repeats every RepeatInterval after the RepeatDelay.
+	for _, ms := range []int{0, 100, 400, 500, 600, 650} {
+		err = device.ProcessHeldInputs([]InputEvent{InputEventDown}, now.Add(time.Duration(ms)*time.Millisecond))
+		if err != nil {
+			t.Fatalf("The error should be nil but is %v", err)
+		}
+	}
+	if device.StateMainMenu.HighlightedItemIndex != 3 {
+		t.Errorf("Holding down should have moved the highlight to 3 but it is at %d", device.StateMainMenu.HighlightedItemIndex)
+	}
+	// Letting go and pressing again is a new press.
+	device.ProcessHeldInputs(nil, now.Add(700*time.Millisecond))
+	device.ProcessHeldInputs([]InputEvent{InputEventDown}, now.Add(710*time.Millisecond))
+	if device.StateMainMenu.HighlightedItemIndex != 4 {
+		t.Errorf("Pressing down again should have moved the highlight to 4 but it is at %d", device.StateMainMenu.HighlightedItemIndex)
+	}
+	// Accept does not repeat.
+	device.StateMainMenu.HighlightedItemIndex = 0
+	device.ProcessHeldInputs([]InputEvent{InputEventAccept}, now.Add(time.Second))
+	device.ProcessHeldInputs([]InputEvent{InputEventAccept}, now.Add(5*time.Second))
+	if device.State != &device.StateConversationsMenu {
+		t.Errorf("Accept should have been pressed once to open the conversations, but the State is %q", device.State.Title)
+	}
+}
+
+func TestInputDebounce(t *testing.T) {
+	device, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	device.State = &device.StateMainMenu
+	defer func() { device.StateMainMenu.HighlightedItemIndex = 0 }()
+	device.InputTiming.Debounce = time.Hour
+	for i := 0; i < 3; i++ {
+		if err = device.ProcessInputEvent(InputEventDown); err != nil {
+			t.Fatalf("The error should be nil but is %v", err)
+		}
+	}
+	if device.StateMainMenu.HighlightedItemIndex != 1 {
+		t.Errorf("A bouncing button should only be pressed once, but the highlight is at %d", device.StateMainMenu.HighlightedItemIndex)
+	}
+	if err = device.ProcessInputEvent(InputEventUp); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if device.StateMainMenu.HighlightedItemIndex != 0 {
+		t.Errorf("A different button should not be debounced, but the highlight is at %d", device.StateMainMenu.HighlightedItemIndex)
+	}
+}
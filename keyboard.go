@@ -0,0 +1,112 @@
+package picodoomsdaymessenger
+
+import "errors"
+
+// Define keyboard layout errors
+var (
+	// ErrKeyboardLayoutExists is returned when a KeyboardLayout with the same name has already been registered.
+	ErrKeyboardLayoutExists = errors.New("keyboard layout already registered")
+	// ErrKeyboardLayoutEmptyKey is returned when a KeyboardLayout is registered with a key that has no characters to type.
+	ErrKeyboardLayoutEmptyKey = errors.New("keyboard layout has a key without characters")
+)
+
+// KeyboardLayout is a profile that maps each number key to the KeyboardButton that it types with.
+type KeyboardLayout struct {
+	Name    string
+	Buttons map[InputEvent]*KeyboardButton
+}
+
+// NewKeyboardLayout creates a KeyboardLayout from the characters that each number key cycles through. Keys that are left out do not type anything.
+func NewKeyboardLayout(name string, keys map[InputEvent][]string) *KeyboardLayout {
+	layout := &KeyboardLayout{Name: name, Buttons: make(map[InputEvent]*KeyboardButton)}
+	for inputEvent, characters := range keys {
+		layout.Buttons[inputEvent] = &KeyboardButton{Characters: characters}
+	}
+	return layout
+}
+
+// Define the Keyboard Layouts
+var (
+	// KeyboardLayoutPhone is the classic phone keypad layout, it is the default.
+	KeyboardLayoutPhone = &KeyboardLayout{
+		Name: "Phone",
+		Buttons: map[InputEvent]*KeyboardButton{
+			InputEventNumber1: KeyboardButton1,
+			InputEventNumber2: KeyboardButton2,
+			InputEventNumber3: KeyboardButton3,
+			InputEventNumber4: KeyboardButton4,
+			InputEventNumber5: KeyboardButton5,
+			InputEventNumber6: KeyboardButton6,
+			InputEventNumber7: KeyboardButton7,
+			InputEventNumber8: KeyboardButton8,
+			InputEventNumber9: KeyboardButton9,
+			InputEventNumber0: KeyboardButton0,
+		},
+	}
+	// KeyboardLayoutAlphabetical is a layout with the alphabet in order, three letters per key starting at 1.
+	KeyboardLayoutAlphabetical = NewKeyboardLayout("Alphabetical", map[InputEvent][]string{
+		InputEventNumber1: {"a", "b", "c", "1"},
+		InputEventNumber2: {"d", "e", "f", "2"},
+		InputEventNumber3: {"g", "h", "i", "3"},
+		InputEventNumber4: {"j", "k", "l", "4"},
+		InputEventNumber5: {"m", "n", "o", "5"},
+		InputEventNumber6: {"p", "q", "r", "6"},
+		InputEventNumber7: {"s", "t", "u", "7"},
+		InputEventNumber8: {"v", "w", "x", "8"},
+		InputEventNumber9: {"y", "z", "9"},
+		InputEventNumber0: {" ", "0"},
+	})
+)
+
+// KeyboardLayouts is the list of KeyboardLayouts that can be selected in the Settings.
+var KeyboardLayouts = []*KeyboardLayout{KeyboardLayoutPhone, KeyboardLayoutAlphabetical}
+
+// RegisterKeyboardLayout adds a custom KeyboardLayout to the KeyboardLayouts, so that it can be selected in the Settings.
+// Every key in the layout must have at least one character, as pressing it types the first one.
+func RegisterKeyboardLayout(layout *KeyboardLayout) (err error) {
+	for _, button := range layout.Buttons {
+		if button == nil || len(button.Characters) == 0 {
+			return ErrKeyboardLayoutEmptyKey
+		}
+	}
+	for _, existing := range KeyboardLayouts {
+		if existing.Name == layout.Name {
+			return ErrKeyboardLayoutExists
+		}
+	}
+	KeyboardLayouts = append(KeyboardLayouts, layout)
+	return nil
+}
+
+// KeyboardLayout returns the KeyboardLayout chosen in the Settings, or KeyboardLayoutPhone if it cannot be found.
+func (d *Device) KeyboardLayout() *KeyboardLayout {
+	for _, layout := range KeyboardLayouts {
+		if layout.Name == d.Settings.KeyboardLayout {
+			return layout
+		}
+	}
+	return KeyboardLayoutPhone
+}
+
+// SetKeyboardLayout chooses the KeyboardLayout to type with. Any character that is still being typed is dropped, as its KeyboardButton belongs to the old layout.
+func (d *Device) SetKeyboardLayout(name string) {
+	d.clearPendingCharacter()
+	d.Settings.KeyboardLayout = name
+}
+
+// UpdateKeyboardLayoutMenu fills the StateKeyboardLayoutMenu with the KeyboardLayouts, including any that have been registered.
+func (d *Device) UpdateKeyboardLayoutMenu() {
+	names := make([]string, len(KeyboardLayouts))
+	for i, layout := range KeyboardLayouts {
+		names[i] = layout.Name
+	}
+	d.StateKeyboardLayoutMenu.Content = append([]MenuItem{GlobalMenuItemGoBack}, choiceMenuItems(names, func(d *Device, i int) bool {
+		return d.KeyboardLayout().Name == names[i]
+	}, func(d *Device, i int) (err error) {
+		d.SetKeyboardLayout(names[i])
+		return nil
+	})...)
+	if d.StateKeyboardLayoutMenu.HighlightedItemIndex >= len(d.StateKeyboardLayoutMenu.Content) {
+		d.StateKeyboardLayoutMenu.HighlightedItemIndex = 0
+	}
+}
@@ -0,0 +1,207 @@
+package picodoomsdaymessenger
+
+import (
+	"fmt"
+	"image"
+	"strings"
+	"testing"
+)
+
+func TestKeyboardLayouts(t *testing.T) {
+	// Create a new Machine
+	device, err := NewDevice()
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	if device.KeyboardLayout() != KeyboardLayoutPhone {
+		t.Errorf("The default keyboard layout should be KeyboardLayoutPhone but is %v", device.KeyboardLayout().Name)
+	}
+	device.Conversations = []*Conversation{{}}
+	device.CurrentConversationIndex = 0
+	device.State = &device.StateConversationReader
+
+	device.UpdateKeyboardLayoutMenu()
+	err = device.StateKeyboardLayoutMenu.Content[2].Action(device)
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	if device.KeyboardLayout() != KeyboardLayoutAlphabetical {
+		t.Errorf("The keyboard layout should be KeyboardLayoutAlphabetical but is %v", device.KeyboardLayout().Name)
+	}
+
+	err = device.ProcessInputEvent(InputEventNumber1)
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	err = device.ProcessInputEvent(InputEventNumber1)
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	if device.ComposerText() != "b" {
+		t.Errorf("The composer text should be b but is %v", device.ComposerText())
+	}
+	device.SetKeyboardLayout(KeyboardLayoutPhone.Name)
+	KeyboardLayoutAlphabetical.Buttons[InputEventNumber1].CurrentCharacterIndex = 0
+}
+
+func TestRegisterKeyboardLayout(t *testing.T) {
+	// Create a new Machine
+	device, err := NewDevice()
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	layout := NewKeyboardLayout("Test", map[InputEvent][]string{InputEventNumber5: {"x", "y"}})
+	err = RegisterKeyboardLayout(layout)
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	defer func() {
+		KeyboardLayouts = KeyboardLayouts[:len(KeyboardLayouts)-1]
+	}()
+	err = RegisterKeyboardLayout(layout)
+	if err != ErrKeyboardLayoutExists {
+		t.Errorf("The error should be ErrKeyboardLayoutExists but is %v", err)
+	}
+	device.UpdateKeyboardLayoutMenu()
+	if device.StateKeyboardLayoutMenu.Content[len(device.StateKeyboardLayoutMenu.Content)-1].Text != "Test" {
+		t.Errorf("The registered layout should be in the menu but the menu is %v", device.StateKeyboardLayoutMenu.Content)
+	}
+
+	device.SetKeyboardLayout("Test")
+	device.Conversations = []*Conversation{{}}
+	device.CurrentConversationIndex = 0
+	device.State = &device.StateConversationReader
+	// Keys that are not in the layout do nothing.
+	err = device.ProcessInputEvent(InputEventNumber2)
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	err = device.ProcessInputEvent(InputEventNumber5)
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	if device.ComposerText() != "x" {
+		t.Errorf("The composer text should be x but is %v", device.ComposerText())
+	}
+}
+
+func TestRegisterKeyboardLayoutEmptyKey(t *testing.T) {
+	layouts := len(KeyboardLayouts)
+	err := RegisterKeyboardLayout(NewKeyboardLayout("Empty", map[InputEvent][]string{InputEventNumber5: {"x"}, InputEventNumber6: {}}))
+	if err != ErrKeyboardLayoutEmptyKey {
+		t.Errorf("The error should be ErrKeyboardLayoutEmptyKey but is %v", err)
+	}
+	err = RegisterKeyboardLayout(&KeyboardLayout{Name: "Nil", Buttons: map[InputEvent]*KeyboardButton{InputEventNumber5: nil}})
+	if err != ErrKeyboardLayoutEmptyKey {
+		t.Errorf("The error should be ErrKeyboardLayoutEmptyKey but is %v", err)
+	}
+	if len(KeyboardLayouts) != layouts {
+		t.Errorf("The rejected layouts should not have been registered, but there are %d layouts", len(KeyboardLayouts))
+	}
+}
+
+func TestKeyboardHint(t *testing.T) {
+	// Create a new Machine
+	device, err := NewDevice()
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	if device.KeyboardHint() != "" {
+		t.Errorf("There should be no hint for a single character key but the hint is %v", device.KeyboardHint())
+	}
+	device.CurrentKeyboardButton = &KeyboardButton{Characters: []string{"a", "b", "c"}, CurrentCharacterIndex: 1}
+	if device.KeyboardHint() != "a [b] c" {
+		t.Errorf("The hint should be a [b] c but is %v", device.KeyboardHint())
+	}
+	device.CurrentKeyboardButton = &KeyboardButton{Characters: []string{" ", "0"}, CurrentCharacterIndex: 0}
+	if device.KeyboardHint() != "[_] 0" {
+		t.Errorf("The hint should be [_] 0 but is %v", device.KeyboardHint())
+	}
+	device.Settings.InputMethod = InputMethodMorse
+	if device.KeyboardHint() != "" {
+		t.Errorf("There should be no hint while typing Morse but the hint is %v", device.KeyboardHint())
+	}
+}
+
+func TestGetFrameKeyboardHint(t *testing.T) {
+	// Create a new Machine
+	device, err := NewDevice()
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	device.Conversations = []*Conversation{{Name: "Test"}}
+	device.CurrentConversationIndex = 0
+	device.State = &device.StateConversationReader
+	device.CurrentKeyboardButton = &KeyboardButton{Characters: []string{""}}
+	withoutHint, err := GetFrame(image.Rect(0, 0, 128, 64), device)
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	device.CurrentKeyboardButton = &KeyboardButton{Characters: []string{"a", "b", "c"}}
+	withHint, err := GetFrame(image.Rect(0, 0, 128, 64), device)
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	// The line above the hint strip should only be drawn when there is a hint.
+	if withoutHint.At(0, 34) == withHint.At(0, 34) {
+		t.Errorf("The hint strip should have been drawn but was not")
+	}
+}
+
+func TestComposeMode(t *testing.T) {
+	device, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	c := device.NewConversation(Person{Name: "Bob", ID: 7})
+	device.CurrentConversationIndex = 0
+	if err = device.ChangeStateWithHistory(&device.StateConversationReader); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	device.clearPendingCharacter()
+	for _, inputEvent := range []InputEvent{InputEventNumber4, InputEventNumber4, InputEventStar, InputEventNumber4, InputEventNumber4, InputEventStar, InputEventNumber4, InputEventNumber4, InputEventStar, InputEventLeft, InputEventNumber2} {
+		if err = device.ProcessInputEvent(inputEvent); err != nil {
+			t.Fatalf("The error should be nil but is %v", err)
+		}
+	}
+	if text := device.ComposerText(); text != "hH44a" {
+		t.Errorf("The compose bar should be %q but is %q", "hH44a", text)
+	}
+	if device.ComposeMode != ComposeModeLower || c.KeyboardBuffer != "hH44" {
+		t.Errorf("Star should have gone through the symbol picker back to abc, have mode %v and buffer %q", device.ComposeMode, c.KeyboardBuffer)
+	}
+	device.ComposeMode = ComposeModeUpper
+	if hint := device.KeyboardHint(); !strings.Contains(hint, "[A]") {
+		t.Errorf("The keyboard hint should be upper case but is %q", hint)
+	}
+	if _, err := GetFrame(image.Rect(0, 0, 128, 64), device); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+}
+
+func TestComposerStatus(t *testing.T) {
+	device, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	device.NewConversation(Person{Name: "Bob", ID: 7})
+	device.CurrentConversationIndex = 0
+	device.State = &device.StateConversationReader
+	device.clearPendingCharacter()
+	empty := device.ComposerRemaining()
+	device.Conversations[0].KeyboardBuffer = "hello"
+	if remaining := device.ComposerRemaining(); remaining != empty-5 {
+		t.Errorf("expected %d characters left, got %d", empty-5, remaining)
+	}
+	short := device.ComposerAirtime()
+	device.Settings.Modem.SpreadingFactor = 12
+	if long := device.ComposerAirtime(); long <= short {
+		t.Errorf("expected SF12 to take longer than %v, got %v", short, long)
+	}
+	if status := device.ComposerStatus(); !strings.HasPrefix(status, fmt.Sprint(empty-5)+" ") || !strings.HasSuffix(status, "s") {
+		t.Errorf("expected the status to show the characters left and the airtime, got %q", status)
+	}
+	if _, err := GetFrame(image.Rect(0, 0, 128, 64), device); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+}
@@ -0,0 +1,251 @@
+package picodoomsdaymessenger
+
+import (
+	"image"
+	"image/color"
+	"unicode"
+
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// latinMark is a diacritic that is drawn onto a letter to make an accented letter.
+type latinMark int
+
+const (
+	latinMarkDotless latinMark = iota
+	latinMarkGrave
+	latinMarkAcute
+	latinMarkCircumflex
+	latinMarkTilde
+	latinMarkDiaeresis
+	latinMarkRing
+	latinMarkMacron
+	latinMarkBreve
+	latinMarkDot
+	latinMarkCaron
+	latinMarkDoubleAcute
+	latinMarkCedilla
+	latinMarkOgonek
+	latinMarkStroke
+	latinMarkSlash
+)
+
+// above returns true if the mark is drawn above the letter.
+func (m latinMark) above() bool {
+	return m >= latinMarkGrave && m <= latinMarkDoubleAcute
+}
+
+// latinMarkPixels holds the pixels of the marks that are drawn above or below a letter, centered on it.
+var latinMarkPixels = map[latinMark][]string{
+	latinMarkGrave:       {"#.", ".#"},
+	latinMarkAcute:       {".#", "#."},
+	latinMarkCircumflex:  {".#.", "#.#"},
+	latinMarkTilde:       {".##.#", "#..#."},
+	latinMarkDiaeresis:   {"#.#"},
+	latinMarkRing:        {"##", "##"},
+	latinMarkMacron:      {"####"},
+	latinMarkBreve:       {"#..#", ".##."},
+	latinMarkDot:         {"#"},
+	latinMarkCaron:       {"#.#", ".#."},
+	latinMarkDoubleAcute: {".#.#", "#.#."},
+	latinMarkCedilla:     {"#.", "##"},
+	latinMarkOgonek:      {"#.", ".#"},
+}
+
+// latinLetter is an accented letter made from a base letter and a mark.
+type latinLetter struct {
+	Rune rune
+	Base rune
+	Mark latinMark
+}
+
+// latinCapitals lists the accented capital letters from U+00C0 to U+017F that can be made from a base letter.
+// Each one has a small letter that is made from the small base letter with the same mark.
+var latinCapitals = []latinLetter{
+	{'À', 'A', latinMarkGrave}, {'Á', 'A', latinMarkAcute}, {'Â', 'A', latinMarkCircumflex}, {'Ã', 'A', latinMarkTilde}, {'Ä', 'A', latinMarkDiaeresis}, {'Å', 'A', latinMarkRing},
+	{'Ç', 'C', latinMarkCedilla}, {'È', 'E', latinMarkGrave}, {'É', 'E', latinMarkAcute}, {'Ê', 'E', latinMarkCircumflex}, {'Ë', 'E', latinMarkDiaeresis},
+	{'Ì', 'I', latinMarkGrave}, {'Í', 'I', latinMarkAcute}, {'Î', 'I', latinMarkCircumflex}, {'Ï', 'I', latinMarkDiaeresis}, {'Ñ', 'N', latinMarkTilde},
+	{'Ò', 'O', latinMarkGrave}, {'Ó', 'O', latinMarkAcute}, {'Ô', 'O', latinMarkCircumflex}, {'Õ', 'O', latinMarkTilde}, {'Ö', 'O', latinMarkDiaeresis}, {'Ø', 'O', latinMarkSlash},
+	{'Ù', 'U', latinMarkGrave}, {'Ú', 'U', latinMarkAcute}, {'Û', 'U', latinMarkCircumflex}, {'Ü', 'U', latinMarkDiaeresis}, {'Ý', 'Y', latinMarkAcute}, {'Ÿ', 'Y', latinMarkDiaeresis},
+	{'Ā', 'A', latinMarkMacron}, {'Ă', 'A', latinMarkBreve}, {'Ą', 'A', latinMarkOgonek}, {'Ć', 'C', latinMarkAcute}, {'Ĉ', 'C', latinMarkCircumflex}, {'Ċ', 'C', latinMarkDot}, {'Č', 'C', latinMarkCaron},
+	{'Ď', 'D', latinMarkCaron}, {'Đ', 'D', latinMarkStroke}, {'Ē', 'E', latinMarkMacron}, {'Ĕ', 'E', latinMarkBreve}, {'Ė', 'E', latinMarkDot}, {'Ę', 'E', latinMarkOgonek}, {'Ě', 'E', latinMarkCaron},
+	{'Ĝ', 'G', latinMarkCircumflex}, {'Ğ', 'G', latinMarkBreve}, {'Ġ', 'G', latinMarkDot}, {'Ģ', 'G', latinMarkCedilla}, {'Ĥ', 'H', latinMarkCircumflex}, {'Ħ', 'H', latinMarkStroke},
+	{'Ĩ', 'I', latinMarkTilde}, {'Ī', 'I', latinMarkMacron}, {'Ĭ', 'I', latinMarkBreve}, {'Į', 'I', latinMarkOgonek}, {'Ĵ', 'J', latinMarkCircumflex}, {'Ķ', 'K', latinMarkCedilla},
+	{'Ĺ', 'L', latinMarkAcute}, {'Ļ', 'L', latinMarkCedilla}, {'Ľ', 'L', latinMarkCaron}, {'Ł', 'L', latinMarkStroke}, {'Ń', 'N', latinMarkAcute}, {'Ņ', 'N', latinMarkCedilla}, {'Ň', 'N', latinMarkCaron},
+	{'Ō', 'O', latinMarkMacron}, {'Ŏ', 'O', latinMarkBreve}, {'Ő', 'O', latinMarkDoubleAcute}, {'Ŕ', 'R', latinMarkAcute}, {'Ŗ', 'R', latinMarkCedilla}, {'Ř', 'R', latinMarkCaron},
+	{'Ś', 'S', latinMarkAcute}, {'Ŝ', 'S', latinMarkCircumflex}, {'Ş', 'S', latinMarkCedilla}, {'Š', 'S', latinMarkCaron}, {'Ţ', 'T', latinMarkCedilla}, {'Ť', 'T', latinMarkCaron}, {'Ŧ', 'T', latinMarkStroke},
+	{'Ũ', 'U', latinMarkTilde}, {'Ū', 'U', latinMarkMacron}, {'Ŭ', 'U', latinMarkBreve}, {'Ů', 'U', latinMarkRing}, {'Ű', 'U', latinMarkDoubleAcute}, {'Ų', 'U', latinMarkOgonek},
+	{'Ŵ', 'W', latinMarkCircumflex}, {'Ŷ', 'Y', latinMarkCircumflex}, {'Ź', 'Z', latinMarkAcute}, {'Ż', 'Z', latinMarkDot}, {'Ž', 'Z', latinMarkCaron},
+}
+
+// latinSmallOnly lists the accented small letters that do not have a capital made in the same way.
+var latinSmallOnly = []latinLetter{
+	{'ı', 'i', latinMarkDotless}, {'ÿ', 'y', latinMarkDiaeresis},
+}
+
+// LatinFace returns a copy of a font face with the accented Latin letters from U+00C0 to U+017F added, so that names and messages in most European languages can be read.
+// Letters that the face does not already have are made by drawing a mark onto the base letter. If there is no room for the mark, the base letter is drawn on its own.
+func LatinFace(face *basicfont.Face) *basicfont.Face {
+	var letters []latinLetter
+	for _, capital := range latinCapitals {
+		letters = append(letters, capital)
+		if capital.Rune != 'Ÿ' {
+			letters = append(letters, latinLetter{unicode.ToLower(capital.Rune), unicode.ToLower(capital.Base), capital.Mark})
+		}
+	}
+	letters = append(letters, latinSmallOnly...)
+
+	height := face.Ascent + face.Descent
+	glyphs := face.Mask.Bounds().Dy() / height
+	var added []latinLetter
+	for _, letter := range letters {
+		if !faceHas(face, letter.Rune) && faceHas(face, letter.Base) {
+			added = append(added, letter)
+		}
+	}
+	mask := image.NewAlpha(image.Rect(0, 0, face.Width, (glyphs+len(added))*height))
+	for y := 0; y < glyphs*height; y++ {
+		for x := 0; x < face.Width; x++ {
+			mask.SetAlpha(x, y, face.Mask.(*image.Alpha).AlphaAt(face.Mask.Bounds().Min.X+x, face.Mask.Bounds().Min.Y+y))
+		}
+	}
+	latin := *face
+	latin.Mask = mask
+	latin.Ranges = append([]basicfont.Range{}, face.Ranges...)
+	for i, letter := range added {
+		offset := glyphs + i
+		drawLatinLetter(face, mask, offset*height, letter)
+		latin.Ranges = append(latin.Ranges, basicfont.Range{Low: letter.Rune, High: letter.Rune + 1, Offset: offset})
+	}
+	return &latin
+}
+
+// faceHas returns true if a font face has a glyph for a rune, without falling back to the replacement character.
+func faceHas(face *basicfont.Face, r rune) bool {
+	for _, rng := range face.Ranges {
+		if rng.Low <= r && r < rng.High {
+			return true
+		}
+	}
+	return false
+}
+
+// drawLatinLetter draws an accented letter into the mask of a font face, at the top of the glyph that starts at row top.
+func drawLatinLetter(face *basicfont.Face, mask *image.Alpha, top int, letter latinLetter) {
+	height := face.Ascent + face.Descent
+	copyGlyph := func(r rune, clearAbove int) {
+		_, glyph, p, _, _ := face.Glyph(fixed.P(0, face.Ascent), r)
+		alpha := glyph.(*image.Alpha)
+		for y := clearAbove; y < height; y++ {
+			for x := 0; x < face.Width; x++ {
+				mask.SetAlpha(x, top+y, alpha.AlphaAt(p.X+x, p.Y+y))
+			}
+		}
+	}
+	// The dot of an i or j is left out, as the mark goes where it was.
+	clearAbove := 0
+	if letter.Base == 'i' || letter.Base == 'j' {
+		if letter.Mark == latinMarkDotless || letter.Mark.above() {
+			clearAbove, _, _, _ = glyphRows(face, 'x')
+		}
+	}
+	copyGlyph(letter.Base, clearAbove)
+
+	first, last, left, right := glyphRows(face, letter.Base)
+	if clearAbove > 0 {
+		first = clearAbove
+	}
+	set := func(x, y int) {
+		if x >= 0 && x < face.Width && y >= 0 && y < height {
+			mask.SetAlpha(x, top+y, color.Alpha{A: 0xff})
+		}
+	}
+	switch letter.Mark {
+	case latinMarkDotless:
+		return
+	case latinMarkStroke:
+		// The stroke crosses the tallest stem of the letter.
+		stem := glyphStem(face, letter.Base)
+		y := first + (last-first)/3
+		for x := stem - 1; x <= stem+1; x++ {
+			set(x, y)
+		}
+		return
+	case latinMarkSlash:
+		for y := first; y <= last; y++ {
+			set(left+(right-left)*(last-y)/(last-first), y)
+		}
+		return
+	}
+	pixels := latinMarkPixels[letter.Mark]
+	x0 := (left+right+1)/2 - len(pixels[0])/2
+	var y0 int
+	switch letter.Mark {
+	case latinMarkCedilla:
+		y0 = last + 1
+	case latinMarkOgonek:
+		y0 = last + 1
+		x0 = right - 1
+	default:
+		// Leave a gap above the letter if there is room, or else touch it.
+		y0 = first - 1 - len(pixels)
+		if y0 < 0 {
+			y0 = first - len(pixels)
+		}
+		if y0 < 0 {
+			return
+		}
+	}
+	for y, row := range pixels {
+		for x, pixel := range row {
+			if pixel == '#' {
+				set(x0+x, y0+y)
+			}
+		}
+	}
+}
+
+// glyphRows returns the first and last rows, and the leftmost and rightmost columns, that a glyph draws in.
+func glyphRows(face *basicfont.Face, r rune) (first, last, left, right int) {
+	_, glyph, p, _, _ := face.Glyph(fixed.P(0, face.Ascent), r)
+	alpha := glyph.(*image.Alpha)
+	first, left = face.Ascent+face.Descent, face.Width
+	for y := 0; y < face.Ascent+face.Descent; y++ {
+		for x := 0; x < face.Width; x++ {
+			if alpha.AlphaAt(p.X+x, p.Y+y).A == 0 {
+				continue
+			}
+			if y < first {
+				first = y
+			}
+			last = y
+			if x < left {
+				left = x
+			}
+			if x > right {
+				right = x
+			}
+		}
+	}
+	return first, last, left, right
+}
+
+// glyphStem returns the column of a glyph with the most pixels drawn in it, which is its tallest stem.
+func glyphStem(face *basicfont.Face, r rune) (stem int) {
+	_, glyph, p, _, _ := face.Glyph(fixed.P(0, face.Ascent), r)
+	alpha := glyph.(*image.Alpha)
+	most := 0
+	for x := 0; x < face.Width; x++ {
+		count := 0
+		for y := 0; y < face.Ascent+face.Descent; y++ {
+			if alpha.AlphaAt(p.X+x, p.Y+y).A != 0 {
+				count++
+			}
+		}
+		if count > most {
+			stem, most = x, count
+		}
+	}
+	return stem
+}
@@ -0,0 +1,54 @@
+package picodoomsdaymessenger
+
+import (
+	"image"
+	"testing"
+
+	"golang.org/x/image/font/basicfont"
+)
+
+func TestLatinFace(t *testing.T) {
+	for _, r := range "ÀéüçÑøłĐıŐąž" {
+		if !faceHas(FaceNormal, r) {
+			t.Errorf("The normal face should have %q", r)
+		}
+	}
+	if faceHas(FaceNormal, 'Ș') {
+		t.Errorf("Letters that are not made should not be added")
+	}
+	if len(FaceNormal.Ranges) <= len(basicfont.Face7x13.Ranges) || len(basicfont.Face7x13.Ranges) != 2 {
+		t.Errorf("LatinFace should add ranges to a copy of the face, not to the face itself")
+	}
+
+	// The e of é is the same as an e, with the accent above it.
+	e := image.NewRGBA(image.Rect(0, 0, 7, 13))
+	drawTextFace(e, FaceNormal, 0, 11, "e", MonochromePalette.Text)
+	accented := image.NewRGBA(image.Rect(0, 0, 7, 13))
+	drawTextFace(accented, FaceNormal, 0, 11, "é", MonochromePalette.Text)
+	first, _, _, _ := glyphRows(FaceNormal, 'e')
+	above := false
+	for y := 0; y < 13; y++ {
+		for x := 0; x < 7; x++ {
+			if y >= first && accented.RGBAAt(x, y) != e.RGBAAt(x, y) {
+				t.Errorf("The e of é is different at (%d, %d)", x, y)
+			}
+			if y < first && accented.RGBAAt(x, y) == MonochromePalette.Text {
+				above = true
+			}
+		}
+	}
+	if !above {
+		t.Errorf("The accent of é should be drawn above the e")
+	}
+}
+
+func TestDrawMessageRightAlignsAccents(t *testing.T) {
+	layout := NewLayout(DisplaySSD1306.Bounds())
+	palette := ColorPalette
+	img := image.NewRGBA(DisplaySSD1306.Bounds())
+	drawMessage(img, layout, palette, "héllo", true, true, 30)
+	// The bubble is 7 characters wide, so it starts 49 pixels from the right edge.
+	if img.RGBAAt(128-49, 20) != palette.OwnMessage || img.RGBAAt(128-50, 20) == palette.OwnMessage {
+		t.Errorf("The bubble of a message with an accent should be as wide as its characters")
+	}
+}
@@ -0,0 +1,92 @@
+package picodoomsdaymessenger
+
+// LeftRight is what the left and right keys do in a State.
+type LeftRight int
+
+const (
+	// LeftRightHistory goes back and forward through the States that have been shown, like the back and forward buttons of a web browser.
+	LeftRightHistory LeftRight = iota
+	// LeftRightPage moves the highlighted item up and down by a screen full of items, for long lists.
+	LeftRightPage
+)
+
+// ProcessInputEventLeft moves the cursor of the compose bar left while there is text in it, and otherwise pages back through the conversation.
+// In other States it turns down the Widget of the highlighted item if it has one, and otherwise does what the LeftRight of the State says.
+func (d *Device) ProcessInputEventLeft() (err error) {
+	if d.State == &d.StateConversationReader {
+		c := d.Conversations[d.CurrentConversationIndex]
+		if !c.ListenOnly && d.composing(c) {
+			d.moveComposerCursor(c, -1)
+			return nil
+		}
+		d.PageConversation(c, -1)
+		return nil
+	}
+	if widget := d.highlightedWidget(); widget != nil {
+		return widget.Adjust(d, -1)
+	}
+	if d.State.LeftRight == LeftRightPage {
+		d.PageMenu(-1)
+		return nil
+	}
+	err = d.GoBackState()
+	if err == ErrGoBackStateRootState {
+		return nil
+	}
+	return err
+}
+
+// ProcessInputEventRight moves the cursor of the compose bar right while there is text in it, and otherwise pages forward through the conversation.
+// In other States it turns up the Widget of the highlighted item if it has one, and otherwise does what the LeftRight of the State says.
+func (d *Device) ProcessInputEventRight() (err error) {
+	if d.State == &d.StateConversationReader {
+		c := d.Conversations[d.CurrentConversationIndex]
+		if !c.ListenOnly && d.composing(c) {
+			d.moveComposerCursor(c, 1)
+			return nil
+		}
+		d.PageConversation(c, 1)
+		return nil
+	}
+	if widget := d.highlightedWidget(); widget != nil {
+		return widget.Adjust(d, 1)
+	}
+	if d.State.LeftRight == LeftRightPage {
+		d.PageMenu(1)
+		return nil
+	}
+	err = d.GoForwardState()
+	if err == ErrGoForwardStateNoState {
+		return nil
+	}
+	return err
+}
+
+// composing returns true if anything has been typed into the compose bar of a Conversation.
+func (d *Device) composing(c *Conversation) bool {
+	return c.KeyboardBuffer != "" || c.KeyboardBufferAfter != "" || d.pendingBuffer != nil || d.morse.elements != ""
+}
+
+// moveComposerCursor moves the cursor of the compose bar of a Conversation by a number of characters. Negative numbers move it left.
+// Anything still being typed is added to the KeyboardBuffer first, so that it stays where it was typed.
+func (d *Device) moveComposerCursor(c *Conversation, by int) {
+	switch d.Settings.InputMethod {
+	case InputMethodMorse:
+		d.flushMorse()
+	case InputMethodMultiTap:
+		if d.pendingBuffer != nil {
+			c.KeyboardBuffer += d.pendingCharacter()
+		}
+		d.clearPendingCharacter()
+	}
+	before, after := []rune(c.KeyboardBuffer), []rune(c.KeyboardBufferAfter)
+	for ; by < 0 && len(before) > 0; by++ {
+		after = append([]rune{before[len(before)-1]}, after...)
+		before = before[:len(before)-1]
+	}
+	for ; by > 0 && len(after) > 0; by-- {
+		before = append(before, after[0])
+		after = after[1:]
+	}
+	c.KeyboardBuffer, c.KeyboardBufferAfter = string(before), string(after)
+}
@@ -0,0 +1,97 @@
+package picodoomsdaymessenger
+
+import (
+	"testing"
+)
+
+func TestLeftRightHistory(t *testing.T) {
+	device, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if err = device.ChangeStateWithHistory(&device.StateToolsMenu); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if err = device.ProcessInputEvent(InputEventLeft); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if device.State != &device.StateMainMenu {
+		t.Fatalf("expected left to go back to the main menu, got %q", device.State.Title)
+	}
+	// Going back from the root does nothing.
+	if err = device.ProcessInputEvent(InputEventLeft); err != nil || device.State != &device.StateMainMenu {
+		t.Errorf("expected to stay on the main menu, got %v", err)
+	}
+	if err = device.ProcessInputEvent(InputEventRight); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if device.State != &device.StateToolsMenu {
+		t.Fatalf("expected right to go forward to the tools menu, got %q", device.State.Title)
+	}
+	if err = device.GoForwardState(); err != ErrGoForwardStateNoState {
+		t.Errorf("expected ErrGoForwardStateNoState, got %v", err)
+	}
+}
+
+func TestLeftRightPage(t *testing.T) {
+	device, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	state := State{LeftRight: LeftRightPage}
+	for i := 0; i < 20; i++ {
+		state.Content = append(state.Content, MenuItem{Text: "Item", CursorIcon: CursorIconNone})
+	}
+	device.State = &state
+	visible := device.Layout().VisibleItems(false)
+	if err = device.ProcessInputEvent(InputEventRight); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if state.HighlightedItemIndex != visible {
+		t.Errorf("expected right to page down to item %d, got %d", visible, state.HighlightedItemIndex)
+	}
+	device.PageMenu(100)
+	if state.HighlightedItemIndex != 19 {
+		t.Errorf("expected paging to stop at the last item, got %d", state.HighlightedItemIndex)
+	}
+	if err = device.ProcessInputEvent(InputEventLeft); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if state.HighlightedItemIndex != 19-visible {
+		t.Errorf("expected left to page up to item %d, got %d", 19-visible, state.HighlightedItemIndex)
+	}
+}
+
+func TestComposerCursor(t *testing.T) {
+	device, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	device.InputTiming.Debounce = 0
+	c := device.NewConversation(device.SelfIdentity)
+	device.CurrentConversationIndex = len(device.Conversations) - 1
+	device.State = &device.StateConversationReader
+	device.Settings.InputMethod = InputMethodMultiTap
+	c.KeyboardBuffer = "helo"
+	if err = device.ProcessInputEvent(InputEventLeft); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if c.KeyboardBuffer != "hel" || c.KeyboardBufferAfter != "o" {
+		t.Fatalf("expected the cursor before the last letter, got %q and %q", c.KeyboardBuffer, c.KeyboardBufferAfter)
+	}
+	device.typeKey(&c.KeyboardBuffer, device.KeyboardLayout().Buttons[InputEventNumber5])
+	device.typeKey(&c.KeyboardBuffer, device.KeyboardLayout().Buttons[InputEventNumber5])
+	device.typeKey(&c.KeyboardBuffer, device.KeyboardLayout().Buttons[InputEventNumber5])
+	if text := device.ComposerText(); text != "hello" {
+		t.Errorf("expected typing to go in at the cursor, got %q", text)
+	}
+	if err = device.ProcessInputEvent(InputEventRight); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if c.KeyboardBuffer != "hello" || c.KeyboardBufferAfter != "" {
+		t.Errorf("expected the cursor at the end, got %q and %q", c.KeyboardBuffer, c.KeyboardBufferAfter)
+	}
+	if _, err = GetFrame(device.Display.Bounds(), device); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+}
@@ -0,0 +1,32 @@
+package picodoomsdaymessenger
+
+import (
+	"errors"
+	"math/rand"
+	"time"
+)
+
+// ErrChannelBusy is returned when another device is still sending after every ListenAttempts.
+var ErrChannelBusy = errors.New("channel is busy")
+
+// ListenAttempts is how many times the channel is checked for other devices before a packet is given up on.
+const ListenAttempts = 5
+
+// ListenBackoff is the longest time to wait before checking a busy channel again. Each wait is random, so that devices that are waiting for the same packet to end do not all send at once when it does.
+const ListenBackoff = 250 * time.Millisecond
+
+// channelBusy uses the channel activity detection of the Radio to find out if another device is sending, so that packets do not collide.
+// If the channel is busy, nothing is sent until a random backoff has passed. It always returns false if no Radio is attached.
+func (d *Device) channelBusy(now time.Time) (busy bool, err error) {
+	if d.Radio == nil {
+		return false, nil
+	}
+	busy, err = d.Radio.ChannelActive()
+	if err != nil {
+		return false, err
+	}
+	if busy {
+		d.backoffUntil = now.Add(time.Duration(rand.Int63n(int64(ListenBackoff))) + time.Millisecond)
+	}
+	return busy, nil
+}
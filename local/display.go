@@ -0,0 +1,53 @@
+package main
+
+import (
+	"image"
+
+	"github.com/faiface/pixel/pixelgl"
+	picodoomsdaymessenger "github.com/headblockhead/picoDoomsdayMessenger"
+	"github.com/nfnt/resize"
+)
+
+// windowDisplay is a picodoomsdaymessenger.Display that scales frames up to fill a window, and colors them like the two-tone OLED of the Device.
+type windowDisplay struct {
+	win           *pixelgl.Window
+	width, height int
+}
+
+var _ picodoomsdaymessenger.Display = &windowDisplay{}
+
+func (w *windowDisplay) Size() (width, height int) {
+	return w.width, w.height
+}
+
+// DrawFrame takes in an image and writes it to the window.
+func (w *windowDisplay) DrawFrame(frame image.Image) (err error) {
+	pixelArray := []uint8{}
+	img := resize.Resize(uint(w.win.Bounds().Max.X), uint(w.win.Bounds().Max.Y), frame, resize.NearestNeighbor)
+	// Put the image into the buffer.
+	for y := img.Bounds().Dy(); y > 0; y-- {
+		for x := 0; x < img.Bounds().Dx(); x++ {
+			r, g, b, a := img.At(x, y).RGBA()
+			if uint8(r) == 255 && uint8(g) == 255 && uint8(b) == 255 && uint8(a) == 255 {
+				if y < 68 {
+					pixelArray = append(pixelArray, uint8(255))
+					pixelArray = append(pixelArray, uint8(170))
+					pixelArray = append(pixelArray, uint8(0))
+					pixelArray = append(pixelArray, uint8(255))
+				} else {
+					pixelArray = append(pixelArray, uint8(20))
+					pixelArray = append(pixelArray, uint8(240))
+					pixelArray = append(pixelArray, uint8(255))
+					pixelArray = append(pixelArray, uint8(255))
+				}
+			} else {
+				pixelArray = append(pixelArray, uint8(r))
+				pixelArray = append(pixelArray, uint8(g))
+				pixelArray = append(pixelArray, uint8(b))
+				pixelArray = append(pixelArray, uint8(a))
+			}
+		}
+	}
+	w.win.Canvas().SetPixels(pixelArray)
+	return nil
+}
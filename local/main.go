@@ -1,20 +1,30 @@
 package main
 
 import (
+	"bufio"
+	"flag"
 	"fmt"
 	"image"
-	"reflect"
+	"os"
 	"time"
 
 	"github.com/faiface/pixel"
 	"github.com/faiface/pixel/pixelgl"
 	picodoomsdaymessenger "github.com/headblockhead/picoDoomsdayMessenger"
-	"github.com/nfnt/resize"
+	"github.com/headblockhead/picoDoomsdayMessenger/simradio"
 	"golang.org/x/image/colornames"
 )
 
 var currentFrame image.Image
 
+var radioAddress = flag.String("radio", simradio.DefaultAddress, "the UDP multicast group that the simulated radio uses, or an empty string for no radio")
+
+// receivedPacket is a packet heard by the simulated radio, waiting to be passed to the device.
+type receivedPacket struct {
+	payload []byte
+	rssi    int
+}
+
 func run() {
 
 	cfg := pixelgl.WindowConfig{
@@ -35,12 +45,53 @@ func run() {
 		panic(err)
 	}
 
-	// Record the display size
-	displayx, displayy := 128, 64
-	// Set the old machine state and old menu item to something that is not the starting value.
-	oldDeviceState := picodoomsdaymessenger.StateDefault
-	oldDeviceHighlightedItemIndex := 0
+	// When in gateway mode, events are written to stdout for a base-station computer.
+	device.SendToGateway = func(event []byte) (err error) {
+		fmt.Print(string(event))
+		return nil
+	}
+
+	// Log rejected packets and other details to stdout.
+	device.Log = func(message string) {
+		fmt.Println(message)
+	}
+
+	// Sign messages, so that other simulators can verify them.
+	err = device.GenerateKeys()
+	if err != nil {
+		panic(err)
+	}
+
+	// Talk to other copies of the simulator through a simulated radio.
+	received := make(chan receivedPacket, 8)
+	if *radioAddress != "" {
+		radio, err := simradio.New(*radioAddress, uint64(device.SelfIdentity.ID))
+		if err != nil {
+			panic(err)
+		}
+		defer radio.Close()
+		radio.SetReceiveHandler(func(packet []byte, rssi int) {
+			received <- receivedPacket{payload: packet, rssi: rssi}
+		})
+		err = device.SetRadio(radio)
+		if err != nil {
+			panic(err)
+		}
+		device.SendUsingRadio = radio.Send
+	}
 
+	// Read serial commands from stdin.
+	serialLines := make(chan string)
+	go func() {
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() {
+			serialLines <- scanner.Text()
+		}
+	}()
+
+	// Draw the device on the window as if it was a 128x64 OLED.
+	device.SetDisplay(&windowDisplay{win: win, width: 128, height: 64})
+	device.Version = "simulator"
 	// Panic recovery
 	defer func() {
 		if err := recover(); err != nil {
@@ -49,41 +100,48 @@ func run() {
 	}()
 
 	for !win.Closed() {
-		if win.JustPressed(pixelgl.KeySpace) {
-			err := device.ProcessInputEvent(picodoomsdaymessenger.InputEventAccept)
-			if err != nil {
-				handleError(win, device, err)
-				return
+		// Give the held keys to the device, which presses them once and repeats the arrows.
+		held := []picodoomsdaymessenger.InputEvent{}
+		for key, event := range keyboardInputs {
+			if win.Pressed(key) {
+				held = append(held, event)
 			}
-			time.Sleep(time.Millisecond * 100)
 		}
-		if win.JustPressed(pixelgl.KeyUp) {
-			err := device.ProcessInputEvent(picodoomsdaymessenger.InputEventUp)
-			if err != nil {
-				handleError(win, device, err)
-				return
-			}
-			time.Sleep(time.Millisecond * 100)
+		err = device.ProcessHeldInputs(held, time.Now())
+		if err != nil {
+			handleError(win, device, err)
+			return
 		}
-		if win.JustPressed(pixelgl.KeyDown) {
-			err := device.ProcessInputEvent(picodoomsdaymessenger.InputEventDown)
+		time.Sleep(time.Millisecond * 1)
+		// Run any serial commands that have been typed.
+		select {
+		case line := <-serialLines:
+			response, err := device.ProcessSerialCommand(line)
 			if err != nil {
-				handleError(win, device, err)
-				return
+				fmt.Println("error: " + err.Error())
+			} else if response != "" {
+				fmt.Println(response)
 			}
-			time.Sleep(time.Millisecond * 100)
+		default:
 		}
-		time.Sleep(time.Millisecond * 1)
-		// Update the display only if the state changes
-		if !reflect.DeepEqual(oldDeviceState, device.State) || !(oldDeviceHighlightedItemIndex == device.State.HighlightedItemIndex) {
-			oldDeviceState = *device.State
-			oldDeviceHighlightedItemIndex = device.State.HighlightedItemIndex
-			frame, err := picodoomsdaymessenger.GetFrame(image.Rect(0, 0, int(displayx), int(displayy)), device)
+		// Pass on any packets that the radio has heard.
+		select {
+		case packet := <-received:
+			err = device.ReceiveFromRadioWithRSSI(packet.payload, packet.rssi)
 			if err != nil {
-				handleError(win, device, err)
-				return
+				fmt.Println("error: " + err.Error())
 			}
-			err = displayImage(win, frame)
+		default:
+		}
+		// Run the periodic work of the device.
+		err = device.Tick(time.Now())
+		if err != nil {
+			handleError(win, device, err)
+			return
+		}
+		// Update the display only if something on it has changed
+		if device.NeedsRedraw() {
+			err = device.Render()
 			if err != nil {
 				handleError(win, device, err)
 				return
@@ -94,36 +152,11 @@ func run() {
 	}
 }
 
-// displayImage takes in an image and writes it to the screen.
-func displayImage(win *pixelgl.Window, img image.Image) (err error) {
-	pixelArray := []uint8{}
-	img = resize.Resize(uint(win.Bounds().Max.X), uint(win.Bounds().Max.Y), img, resize.NearestNeighbor)
-	// Put the image into the buffer.
-	for y := img.Bounds().Dy(); y > 0; y-- {
-		for x := 0; x < img.Bounds().Dx(); x++ {
-			r, g, b, a := img.At(x, y).RGBA()
-			if uint8(r) == 255 && uint8(g) == 255 && uint8(b) == 255 && uint8(a) == 255 {
-				if y < 68 {
-					pixelArray = append(pixelArray, uint8(255))
-					pixelArray = append(pixelArray, uint8(170))
-					pixelArray = append(pixelArray, uint8(0))
-					pixelArray = append(pixelArray, uint8(255))
-				} else {
-					pixelArray = append(pixelArray, uint8(20))
-					pixelArray = append(pixelArray, uint8(240))
-					pixelArray = append(pixelArray, uint8(255))
-					pixelArray = append(pixelArray, uint8(255))
-				}
-			} else {
-				pixelArray = append(pixelArray, uint8(r))
-				pixelArray = append(pixelArray, uint8(g))
-				pixelArray = append(pixelArray, uint8(b))
-				pixelArray = append(pixelArray, uint8(a))
-			}
-		}
-	}
-	win.Canvas().SetPixels(pixelArray)
-	return nil
+// keyboardInputs is the InputEvent of each key of the computer's keyboard.
+var keyboardInputs = map[pixelgl.Button]picodoomsdaymessenger.InputEvent{
+	pixelgl.KeySpace: picodoomsdaymessenger.InputEventAccept,
+	pixelgl.KeyUp:    picodoomsdaymessenger.InputEventUp,
+	pixelgl.KeyDown:  picodoomsdaymessenger.InputEventDown,
 }
 
 // handleError takes in an error and communicates it to the user.
@@ -132,5 +165,6 @@ func handleError(win *pixelgl.Window, device *picodoomsdaymessenger.Device, inpu
 }
 
 func main() {
+	flag.Parse()
 	pixelgl.Run(run)
 }
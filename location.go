@@ -0,0 +1,126 @@
+package picodoomsdaymessenger
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// Define location errors
+var (
+	// ErrNoLocation is returned when the location of the Device is needed, but it is not known.
+	ErrNoLocation = errors.New("location is not known")
+	// ErrInvalidLocation is returned when a location packet cannot be decoded.
+	ErrInvalidLocation = errors.New("invalid location packet")
+)
+
+// locationPrefix is the start of every location packet, ASCII for "locn".
+var locationPrefix = []byte{0x6C, 0x6F, 0x63, 0x6E}
+
+// SourceGPS is the source tag of a Position read from GetLocation.
+const SourceGPS = "gps"
+
+// earthRadiusKm is the mean radius of the earth.
+const earthRadiusKm = 6371.0
+
+// CurrentLocation returns where the Device is. The GetLocation hook is asked first, then the last Position that was pushed with SetPosition is used.
+func (d *Device) CurrentLocation() (p Position, ok bool) {
+	if d.GetLocation != nil {
+		latitude, longitude, ok := d.GetLocation()
+		if ok {
+			return Position{Latitude: latitude, Longitude: longitude, Source: SourceGPS, UpdatedAt: d.Now()}, true
+		}
+	}
+	return d.Position, d.Position.Valid()
+}
+
+// LocationToBytes creates a location packet with the identity of the sender and a Position.
+func LocationToBytes(person Person, p Position) (output []byte) {
+	seperatorByte := byte(0xcc)
+	output = append(output, locationPrefix...)
+	output = append(output, []byte(fmt.Sprint(person.ID))...)
+	output = append(output, seperatorByte)
+	output = append(output, []byte(person.Name)...)
+	output = append(output, seperatorByte)
+	output = strconv.AppendFloat(output, p.Latitude, 'f', 5, 64)
+	output = append(output, seperatorByte)
+	output = strconv.AppendFloat(output, p.Longitude, 'f', 5, 64)
+	return output
+}
+
+// BytesToLocationMessage decodes a location packet into a Message that has a Location.
+func BytesToLocationMessage(input []byte) (output Message, err error) {
+	if !bytes.HasPrefix(input, locationPrefix) {
+		return output, ErrInvalidLocation
+	}
+	fields := bytes.Split(input[len(locationPrefix):], []byte{0xcc})
+	if len(fields) != 4 {
+		return output, ErrInvalidLocation
+	}
+	output.Person.ID, err = strconv.Atoi(string(fields[0]))
+	if err != nil {
+		return output, ErrInvalidLocation
+	}
+	output.Person.Name, _ = truncateString(string(fields[1]), MaxNameLength)
+	output.Location.Latitude, err = strconv.ParseFloat(string(fields[2]), 64)
+	if err != nil || math.Abs(output.Location.Latitude) > 90 {
+		return output, ErrInvalidLocation
+	}
+	output.Location.Longitude, err = strconv.ParseFloat(string(fields[3]), 64)
+	if err != nil || math.Abs(output.Location.Longitude) > 180 {
+		return output, ErrInvalidLocation
+	}
+	output.Location.Source = "message"
+	output.Text = fmt.Sprintf("at %.5f,%.5f", output.Location.Latitude, output.Location.Longitude)
+	return output, nil
+}
+
+// SendLocation broadcasts where the Device is.
+func (d *Device) SendLocation() (err error) {
+	p, ok := d.CurrentLocation()
+	if !ok {
+		return ErrNoLocation
+	}
+	return d.SendPacket(nil, d.addHopHeader(LocationToBytes(d.SelfIdentity, p)))
+}
+
+// DistanceAndBearing returns the great circle distance in kilometres from one Position to another, and the bearing to it in degrees clockwise from north.
+func DistanceAndBearing(from, to Position) (distanceKm float64, bearing float64) {
+	lat1, lat2 := from.Latitude*math.Pi/180, to.Latitude*math.Pi/180
+	dLat := lat2 - lat1
+	dLon := (to.Longitude - from.Longitude) * math.Pi / 180
+
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	distanceKm = 2 * earthRadiusKm * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+
+	y := math.Sin(dLon) * math.Cos(lat2)
+	x := math.Cos(lat1)*math.Sin(lat2) - math.Sin(lat1)*math.Cos(lat2)*math.Cos(dLon)
+	bearing = math.Mod(math.Atan2(y, x)*180/math.Pi+360, 360)
+	return distanceKm, bearing
+}
+
+// compassPoints are the names of the eight directions, starting from north.
+var compassPoints = []string{"N", "NE", "E", "SE", "S", "SW", "W", "NW"}
+
+// CompassPoint returns the nearest of the eight compass directions to a bearing.
+func CompassPoint(bearing float64) string {
+	return compassPoints[int(math.Mod(bearing+22.5, 360)/45)%8]
+}
+
+// MessageText returns the text to show for a Message. Locations are shown as a distance and direction if the Device knows where it is.
+func (d *Device) MessageText(m Message) string {
+	if !m.Location.Valid() {
+		return m.DisplayText()
+	}
+	here, ok := d.CurrentLocation()
+	if !ok {
+		return m.Text
+	}
+	distance, bearing := DistanceAndBearing(here, m.Location)
+	if distance < 1 {
+		return fmt.Sprintf("%.0fm %s", distance*1000, CompassPoint(bearing))
+	}
+	return fmt.Sprintf("%.1fkm %s", distance, CompassPoint(bearing))
+}
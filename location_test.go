@@ -0,0 +1,93 @@
+package picodoomsdaymessenger
+
+import (
+	"math"
+	"testing"
+)
+
+func TestCurrentLocation(t *testing.T) {
+	device, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if _, ok := device.CurrentLocation(); ok {
+		t.Error("expected the location to be unknown")
+	}
+	device.SetPosition(1, 2, SourceSerial)
+	if p, ok := device.CurrentLocation(); !ok || p.Latitude != 1 {
+		t.Errorf("expected the pushed position, got %+v", p)
+	}
+	device.GetLocation = func() (latitude, longitude float64, ok bool) {
+		return 3, 4, true
+	}
+	if p, ok := device.CurrentLocation(); !ok || p.Latitude != 3 || p.Source != SourceGPS {
+		t.Errorf("expected the GPS position, got %+v", p)
+	}
+}
+
+func TestSendAndReceiveLocation(t *testing.T) {
+	sender, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	receiver, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	sender.SendUsingRadio = receiver.ReceiveFromRadio
+	err = sender.SendLocation()
+	if err != ErrNoLocation {
+		t.Errorf("expected ErrNoLocation, got %v", err)
+	}
+
+	sender.GetLocation = func() (latitude, longitude float64, ok bool) {
+		return 51.5, -0.1, true
+	}
+	err = ToolsMenuItemSendLocation.Action(sender)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	err = sendQueued(sender)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if len(receiver.Conversations) != 1 {
+		t.Fatalf("expected a conversation, got %d", len(receiver.Conversations))
+	}
+	message := receiver.Conversations[0].Messages[0]
+	if !message.Location.Valid() || message.Location.Latitude != 51.5 || message.Location.Longitude != -0.1 {
+		t.Errorf("expected the location to be received, got %+v", message.Location)
+	}
+	if text := receiver.MessageText(message); text != "at 51.50000,-0.10000" {
+		t.Errorf("expected the coordinates without a GPS, got %q", text)
+	}
+
+	// About 11km due north.
+	receiver.SetPosition(51.4, -0.1, SourceSerial)
+	if text := receiver.MessageText(message); text != "11.1km N" {
+		t.Errorf("expected the distance and direction, got %q", text)
+	}
+}
+
+func TestBytesToLocationMessageRejectsBadPackets(t *testing.T) {
+	for _, packet := range []string{"locn", "locn1\xccname\xcc91\xcc0", "locn1\xccname\xcc0\xccwest", "locnx\xccname\xcc0\xcc0"} {
+		if _, err := BytesToLocationMessage([]byte(packet)); err != ErrInvalidLocation {
+			t.Errorf("%q: expected ErrInvalidLocation, got %v", packet, err)
+		}
+	}
+}
+
+func TestDistanceAndBearing(t *testing.T) {
+	distance, bearing := DistanceAndBearing(Position{Latitude: 0, Longitude: 0}, Position{Latitude: 0, Longitude: 1})
+	if math.Abs(distance-111.19) > 0.01 {
+		t.Errorf("expected about 111.19km, got %v", distance)
+	}
+	if math.Abs(bearing-90) > 0.01 {
+		t.Errorf("expected due east, got %v", bearing)
+	}
+	for bearing, expected := range map[float64]string{0: "N", 350: "N", 44: "NE", 180: "S", 270: "W", 300: "NW"} {
+		if actual := CompassPoint(bearing); actual != expected {
+			t.Errorf("CompassPoint(%v): expected %q, got %q", bearing, expected, actual)
+		}
+	}
+}
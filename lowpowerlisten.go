@@ -0,0 +1,117 @@
+package picodoomsdaymessenger
+
+import (
+	"fmt"
+	"time"
+)
+
+// ListenIntervals is the list of times that the radio can sleep for between listening, in low power listening. Zero keeps the radio listening all the time.
+var ListenIntervals = []time.Duration{0, time.Second, 2 * time.Second, 5 * time.Second}
+
+// MinListenWindow is the shortest time that the radio listens for each time it wakes.
+const MinListenWindow = 20 * time.Millisecond
+
+// listenWindowSymbols is how many symbols the radio listens for each time it wakes, which is enough to hear a preamble.
+const listenWindowSymbols = 4
+
+// listenSchedule is when the radio sleeps and wakes in low power listening.
+type listenSchedule struct {
+	asleep bool
+	// next is when the radio next wakes or sleeps.
+	next time.Time
+}
+
+// SymbolDuration returns how long one LoRa symbol takes to send with the ModemConfig.
+func (m ModemConfig) SymbolDuration() time.Duration {
+	if m.BandwidthHz <= 0 {
+		return 0
+	}
+	return time.Duration(int64(1)<<uint(m.SpreadingFactor)) * time.Second / time.Duration(m.BandwidthHz)
+}
+
+// ListenWindow returns how long the radio listens for each time it wakes in low power listening.
+func (d *Device) ListenWindow() time.Duration {
+	window := listenWindowSymbols * d.Settings.Modem.SymbolDuration()
+	if window < MinListenWindow {
+		return MinListenWindow
+	}
+	return window
+}
+
+// preambleLength returns the number of preamble symbols that the radio sends in front of every packet.
+// In low power listening, the preamble lasts for a whole ListenInterval and ListenWindow, so that a device that is asleep when a packet starts wakes while its preamble is still being sent, and stays awake to receive it.
+// Every device in a group needs the same ListenInterval, so that the packets they send each other have long enough preambles.
+func (d *Device) preambleLength() int {
+	if d.Settings.ListenInterval <= 0 {
+		return PreambleLength
+	}
+	symbol := d.Settings.Modem.SymbolDuration()
+	if symbol <= 0 {
+		return PreambleLength
+	}
+	cycle := d.Settings.ListenInterval + d.ListenWindow()
+	return PreambleLength + int((cycle+symbol-1)/symbol)
+}
+
+// packetAirtime estimates how long a packet of a length in bytes takes to send, with the preamble that the radio sends it with.
+func (d *Device) packetAirtime(length int) time.Duration {
+	return d.Settings.Modem.airtimeWithPreamble(length, d.preambleLength())
+}
+
+// SetListenInterval stores how long the radio sleeps between listening in the Settings, and applies the preamble length that it needs to the Radio. Zero turns low power listening off.
+func (d *Device) SetListenInterval(interval time.Duration) (err error) {
+	d.Settings.ListenInterval = interval
+	d.listen.next = time.Time{}
+	if d.Radio == nil {
+		return nil
+	}
+	return d.Radio.SetPreambleLength(d.preambleLength())
+}
+
+// tickListenSchedule puts the Radio to sleep for the ListenInterval, then wakes it to listen for a ListenWindow, over and over.
+// The Radio is kept awake while something is heard, while packets are waiting to be sent, and while the channel scanner is using it.
+func (d *Device) tickListenSchedule(now time.Time) (err error) {
+	if d.Radio == nil {
+		return nil
+	}
+	if d.Settings.ListenInterval <= 0 || len(d.txQueue) > 0 || d.channelScan.active {
+		if !d.listen.asleep {
+			return nil
+		}
+		d.listen = listenSchedule{next: now.Add(d.ListenWindow())}
+		return d.Radio.Listen()
+	}
+	if now.Before(d.listen.next) {
+		return nil
+	}
+	if d.listen.asleep {
+		d.listen = listenSchedule{next: now.Add(d.ListenWindow())}
+		return d.Radio.Listen()
+	}
+	busy, err := d.Radio.ChannelActive()
+	if err != nil {
+		return err
+	}
+	if busy {
+		d.listen.next = now.Add(d.ListenWindow())
+		return nil
+	}
+	d.listen = listenSchedule{asleep: true, next: now.Add(d.Settings.ListenInterval)}
+	return d.Radio.Sleep()
+}
+
+// listenIntervalMenuItems creates a MenuItem for every ListenInterval.
+func listenIntervalMenuItems() (items []MenuItem) {
+	names := make([]string, len(ListenIntervals))
+	for i, interval := range ListenIntervals {
+		names[i] = fmt.Sprintf("Sleep %v", interval)
+		if interval == 0 {
+			names[i] = "Always listen"
+		}
+	}
+	return choiceMenuItems(names, func(d *Device, i int) bool {
+		return d.Settings.ListenInterval == ListenIntervals[i]
+	}, func(d *Device, i int) (err error) {
+		return d.SetListenInterval(ListenIntervals[i])
+	})
+}
@@ -0,0 +1,94 @@
+package picodoomsdaymessenger
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLowPowerListen(t *testing.T) {
+	device, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	radio := &testRadio{}
+	err = device.SetRadio(radio)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if radio.preambleLength != PreambleLength {
+		t.Errorf("expected the normal preamble while always listening, got %d symbols", radio.preambleLength)
+	}
+	normal := device.packetAirtime(20)
+
+	err = device.SetListenInterval(time.Second)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	// At SF7 and 125kHz a symbol is 1.024ms, so the preamble has to be about a thousand symbols to last the whole second.
+	cycle := time.Second + device.ListenWindow()
+	preamble := time.Duration(radio.preambleLength-PreambleLength) * device.Settings.Modem.SymbolDuration()
+	if preamble < cycle || preamble > cycle+device.Settings.Modem.SymbolDuration() {
+		t.Errorf("expected the preamble to last %v, got %v", cycle, preamble)
+	}
+	if device.packetAirtime(20) < normal+time.Second {
+		t.Errorf("expected the airtime to count the long preamble, got %v", device.packetAirtime(20))
+	}
+
+	now := time.Now()
+	err = device.Tick(now)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if !radio.asleep {
+		t.Fatalf("expected the radio to sleep")
+	}
+	err = device.Tick(now.Add(time.Second))
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if radio.asleep {
+		t.Fatalf("expected the radio to wake after the interval")
+	}
+	// The radio stays awake while something is heard.
+	radio.busy = 1
+	err = device.Tick(now.Add(time.Second + device.ListenWindow()))
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if radio.asleep {
+		t.Errorf("expected the radio to stay awake while the channel is busy")
+	}
+	err = device.Tick(now.Add(time.Second + 2*device.ListenWindow()))
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if !radio.asleep {
+		t.Fatalf("expected the radio to sleep again")
+	}
+
+	// Queued packets wake the radio to be sent.
+	sent := 0
+	device.SendUsingRadio = func(packet []byte) (err error) {
+		sent++
+		return nil
+	}
+	err = device.SendPacket(nil, []byte("doom"))
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	err = device.Tick(now.Add(time.Second + 3*device.ListenWindow()))
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if radio.asleep || sent != 1 {
+		t.Errorf("expected the radio to wake and send, sent %d", sent)
+	}
+
+	err = device.SetListenInterval(0)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if radio.preambleLength != PreambleLength {
+		t.Errorf("expected the normal preamble again, got %d symbols", radio.preambleLength)
+	}
+}
@@ -0,0 +1,114 @@
+package picodoomsdaymessenger
+
+import (
+	"time"
+	"unicode/utf8"
+)
+
+// MarqueeStep is how long a long title or highlighted menu item waits before scrolling along by another character.
+const MarqueeStep = 300 * time.Millisecond
+
+// MarqueePause is how long a long title or highlighted menu item is held still at the start and end of its text before scrolling again.
+const MarqueePause = 1500 * time.Millisecond
+
+// marqueeState is how far the title and highlighted menu item are scrolled along, so that text too long for the screen can be read in full.
+type marqueeState struct {
+	// state and item are the State and highlighted item that the scrolling started on. Scrolling starts again from the beginning when either changes.
+	state   *State
+	item    int
+	started time.Time
+	// Title and Item are how many characters the title and highlighted menu item are scrolled along by.
+	Title int
+	Item  int
+}
+
+// marqueeOffset returns how many characters text that is overflow characters too long for the screen is scrolled along by, elapsed after it was first shown.
+// The text is held at its start for MarqueePause, scrolls one character every MarqueeStep until its end is shown, is held there for MarqueePause, and then starts again.
+func marqueeOffset(overflow int, elapsed time.Duration) int {
+	if overflow <= 0 {
+		return 0
+	}
+	scrolling := time.Duration(overflow) * MarqueeStep
+	t := elapsed % (MarqueePause + scrolling + MarqueePause)
+	if t < MarqueePause {
+		return 0
+	}
+	if t < MarqueePause+scrolling {
+		return int((t-MarqueePause)/MarqueeStep) + 1
+	}
+	return overflow
+}
+
+// marqueeText returns text without its first offset characters.
+func marqueeText(text string, offset int) string {
+	for i := range text {
+		if offset <= 0 {
+			return text[i:]
+		}
+		offset--
+	}
+	return ""
+}
+
+// menuItemColumns returns how many characters of a MenuItem fit on a screen width pixels wide, between its Icon and the cursor.
+func menuItemColumns(width int, layout Layout, item MenuItem) int {
+	x := 0
+	if item.Icon != nil {
+		x = IconSize + 2
+	}
+	return (width - x - 8) / layout.Face.Advance
+}
+
+// marqueeTitle returns the title that RenderFrame draws for the State, and false for States that draw their own.
+func (d *Device) marqueeTitle() (title string, ok bool) {
+	switch {
+	case d.State == &d.StateConversationReader:
+		if d.CurrentConversationIndex < 0 || d.CurrentConversationIndex >= len(d.Conversations) {
+			return "", false
+		}
+		return d.Conversations[d.CurrentConversationIndex].Name, true
+	case d.State.Draw != nil || d.State == &d.StateNewConversation:
+		return "", false
+	}
+	return d.State.Title, true
+}
+
+// tickMarquee scrolls the title and highlighted menu item along if they are too long to fit on the screen, and redraws the screen when they move.
+// Nothing scrolls on displays that refresh slowly.
+func (d *Device) tickMarquee(now time.Time) {
+	if d.State != d.marquee.state || d.State.HighlightedItemIndex != d.marquee.item {
+		d.marquee = marqueeState{state: d.State, item: d.State.HighlightedItemIndex, started: now}
+	}
+	if d.ReducedAnimation() {
+		return
+	}
+	title, ok := d.marqueeTitle()
+	if !ok {
+		return
+	}
+	width := d.Display.Bounds().Dx()
+	layout := NewFaceLayout(d.Display.Bounds(), d.Face())
+	elapsed := now.Sub(d.marquee.started)
+	titleOffset := marqueeOffset(utf8.RuneCountInString(title)-layout.Columns, elapsed)
+	itemOffset := 0
+	if d.State != &d.StateConversationReader && d.State.HighlightedItemIndex < len(d.State.Content) {
+		item := d.State.Content[d.State.HighlightedItemIndex]
+		columns := menuItemColumns(width, layout, item)
+		if value := d.menuItemValue(item); value != "" {
+			columns -= utf8.RuneCountInString(value)
+		}
+		itemOffset = marqueeOffset(utf8.RuneCountInString(item.Text)-columns, elapsed)
+	}
+	if titleOffset != d.marquee.Title || itemOffset != d.marquee.Item {
+		d.marquee.Title, d.marquee.Item = titleOffset, itemOffset
+		d.MarkDirty()
+	}
+}
+
+// marqueeOffsets returns how far the title and highlighted menu item are scrolled along, or zero if they belong to a State or item that is no longer shown.
+func (d *Device) marqueeOffsets() (title, item int) {
+	if d.State != d.marquee.state || d.State.HighlightedItemIndex != d.marquee.item {
+		return 0, 0
+	}
+	return d.marquee.Title, d.marquee.Item
+}
@@ -0,0 +1,66 @@
+package picodoomsdaymessenger
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMarqueeOffset(t *testing.T) {
+	for _, check := range []struct {
+		elapsed time.Duration
+		want    int
+	}{
+		{0, 0},
+		{MarqueePause - time.Millisecond, 0},
+		{MarqueePause, 1},
+		{MarqueePause + MarqueeStep, 2},
+		{MarqueePause + 4*MarqueeStep, 3},
+		// After pausing at the end, the text starts again from the beginning.
+		{2*MarqueePause + 3*MarqueeStep, 0},
+	} {
+		if got := marqueeOffset(3, check.elapsed); got != check.want {
+			t.Errorf("expected an offset of %d after %v, got %d", check.want, check.elapsed, got)
+		}
+	}
+	if got := marqueeOffset(-2, time.Hour); got != 0 {
+		t.Errorf("expected text that fits not to scroll, got %d", got)
+	}
+	if got := marqueeText("héllo", 2); got != "llo" {
+		t.Errorf("expected %q, got %q", "llo", got)
+	}
+}
+
+func TestTickMarquee(t *testing.T) {
+	device, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	state := State{
+		Title: "A title that is far too long for the screen",
+		Content: []MenuItem{
+			{Text: "Short", CursorIcon: CursorIconNone},
+			{Text: "An item that is far too long for the screen", CursorIcon: CursorIconNone},
+		},
+	}
+	device.State = &state
+	now := time.Now()
+	device.tickMarquee(now)
+	device.tickMarquee(now.Add(MarqueePause + MarqueeStep))
+	title, item := device.marqueeOffsets()
+	if title != 2 || item != 0 {
+		t.Errorf("expected only the title to scroll, got %d and %d", title, item)
+	}
+
+	state.HighlightedItemIndex = 1
+	if title, _ = device.marqueeOffsets(); title != 0 {
+		t.Errorf("expected scrolling to start again when the highlighted item changes, got %d", title)
+	}
+	device.tickMarquee(now.Add(time.Second))
+	device.tickMarquee(now.Add(time.Second + MarqueePause))
+	if _, item = device.marqueeOffsets(); item != 1 {
+		t.Errorf("expected the highlighted item to scroll, got %d", item)
+	}
+	if _, err = GetFrame(device.Display.Bounds(), device); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+}
@@ -0,0 +1,89 @@
+package picodoomsdaymessenger
+
+// MenuBuilder builds the State of a menu one item at a time, so that menus can be made without changing the States of the package.
+// Every method returns the MenuBuilder, so that calls can be chained:
+//
+//	menu := NewMenu("Lights").AddToggle("Torch", getTorch, setTorch).AddSubmenu("Colours", &coloursMenu).State()
+type MenuBuilder struct {
+	state State
+}
+
+// NewMenu starts building a menu with a title. Like every other menu, its first item goes back.
+func NewMenu(title string) *MenuBuilder {
+	return &MenuBuilder{state: State{
+		Title:   title,
+		Content: []MenuItem{GlobalMenuItemGoBack},
+	}}
+}
+
+// AddItem adds MenuItems to the end of the menu.
+func (m *MenuBuilder) AddItem(items ...MenuItem) *MenuBuilder {
+	m.state.Content = append(m.state.Content, items...)
+	return m
+}
+
+// AddAction adds an item that runs an action when it is accepted.
+func (m *MenuBuilder) AddAction(text string, action func(d *Device) (err error)) *MenuBuilder {
+	return m.AddItem(MenuItem{Text: text, Action: action, CursorIcon: CursorIconNone})
+}
+
+// AddSubmenu adds an item that opens another State.
+func (m *MenuBuilder) AddSubmenu(text string, submenu *State) *MenuBuilder {
+	return m.AddItem(MenuItem{
+		Text: text,
+		Action: func(d *Device) (err error) {
+			return d.ChangeStateWithHistory(submenu)
+		},
+		CursorIcon: CursorIconRightArrow,
+	})
+}
+
+// AddToggle adds an item with a Toggle that turns a setting on and off.
+func (m *MenuBuilder) AddToggle(text string, get func(d *Device) bool, set func(d *Device, on bool) (err error)) *MenuBuilder {
+	return m.AddWidget(text, Toggle{Get: get, Set: set})
+}
+
+// AddSelector adds an item with a Selector that chooses one of a list of names.
+func (m *MenuBuilder) AddSelector(text string, names []string, get func(d *Device) int, set func(d *Device, i int) (err error)) *MenuBuilder {
+	return m.AddWidget(text, Selector{Names: names, Get: get, Set: set})
+}
+
+// AddSlider adds an item with a Slider that changes a number.
+func (m *MenuBuilder) AddSlider(text string, slider Slider) *MenuBuilder {
+	return m.AddWidget(text, slider)
+}
+
+// AddWidget adds an item that shows and changes a setting with a MenuWidget.
+func (m *MenuBuilder) AddWidget(text string, widget MenuWidget) *MenuBuilder {
+	return m.AddItem(MenuItem{Text: text, Widget: widget})
+}
+
+// AddChoices adds an item for each of a list of names, with a box that is checked on the one that is chosen.
+func (m *MenuBuilder) AddChoices(names []string, isChosen func(d *Device, i int) bool, choose func(d *Device, i int) (err error)) *MenuBuilder {
+	return m.AddItem(choiceMenuItems(names, isChosen, choose)...)
+}
+
+// OnLoad sets an action that is run every time the menu is shown, such as filling in items that change.
+func (m *MenuBuilder) OnLoad(action func(d *Device) (err error)) *MenuBuilder {
+	m.state.LoadAction = action
+	return m
+}
+
+// TwoLineItems draws the Detail of each item on a second line under its Text.
+func (m *MenuBuilder) TwoLineItems() *MenuBuilder {
+	m.state.TwoLineItems = true
+	return m
+}
+
+// LeftRight sets what left and right do in the menu.
+func (m *MenuBuilder) LeftRight(leftRight LeftRight) *MenuBuilder {
+	m.state.LeftRight = leftRight
+	return m
+}
+
+// State returns the State of the menu that has been built. Each call returns a new copy, so the menu can be built on further without changing States that have already been returned.
+func (m *MenuBuilder) State() State {
+	state := m.state
+	state.Content = append([]MenuItem{}, m.state.Content...)
+	return state
+}
@@ -0,0 +1,58 @@
+package picodoomsdaymessenger
+
+import (
+	"testing"
+)
+
+func TestMenuBuilder(t *testing.T) {
+	device, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	torch, colour, pressed := false, 0, 0
+	builder := NewMenu("Lights").
+		AddAction("Flash", func(d *Device) (err error) {
+			pressed++
+			return nil
+		}).
+		AddToggle("Torch", func(d *Device) bool {
+			return torch
+		}, func(d *Device, on bool) (err error) {
+			torch = on
+			return nil
+		}).
+		AddSelector("Colour", []string{"Red", "Green"}, func(d *Device) int {
+			return colour
+		}, func(d *Device, i int) (err error) {
+			colour = i
+			return nil
+		}).
+		AddSubmenu("Tools", &device.StateToolsMenu)
+	menu := builder.State()
+	if menu.Title != "Lights" || len(menu.Content) != 5 {
+		t.Fatalf("expected a menu with a back item and 4 more, got %d items", len(menu.Content))
+	}
+	if builder.AddAction("Extra", nil); len(menu.Content) != 5 {
+		t.Errorf("expected building on to leave the menu that was returned alone")
+	}
+
+	device.State = &device.StateMainMenu
+	if err = device.ChangeStateWithHistory(&menu); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	for i := 1; i <= 4; i++ {
+		menu.HighlightedItemIndex = i
+		if err = device.ProcessInputEvent(InputEventAccept); err != nil {
+			t.Fatalf("The error should be nil but is %v", err)
+		}
+	}
+	if pressed != 1 || !torch || colour != 1 {
+		t.Errorf("expected each item to be accepted, got %d, %v and %d", pressed, torch, colour)
+	}
+	if device.State != &device.StateToolsMenu {
+		t.Errorf("expected the submenu to be opened, got %q", device.State.Title)
+	}
+	if err = device.GoBackState(); err != nil || device.State != &menu {
+		t.Errorf("expected to go back to the built menu, got %v", err)
+	}
+}
@@ -0,0 +1,210 @@
+package picodoomsdaymessenger
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/rand"
+)
+
+// Define Meshtastic errors
+var (
+	ErrMeshtasticPacketTooShort = errors.New("meshtastic packet too short")
+	ErrMeshtasticWrongChannel   = errors.New("meshtastic packet is for another channel")
+	ErrMeshtasticNotText        = errors.New("meshtastic packet is not a text message")
+	ErrMeshtasticBadPayload     = errors.New("meshtastic payload could not be decoded")
+)
+
+// meshtasticHeaderLength is the length of the unencrypted header at the start of every Meshtastic packet.
+const meshtasticHeaderLength = 16
+
+// meshtasticBroadcast is the Meshtastic node number that every node listens to.
+const meshtasticBroadcast = 0xffffffff
+
+// meshtasticPortTextMessage is the Meshtastic port number of text messages.
+const meshtasticPortTextMessage = 1
+
+// MeshtasticCodec encodes and decodes Meshtastic text message packets, so that the Device can talk to Meshtastic nodes.
+// The radio has to use the same frequency and modem settings as the Meshtastic nodes, for example SF11 at 250kHz with a 4/5 coding rate for the default "LongFast" channel.
+type MeshtasticCodec struct {
+	// ChannelName is the name of the Meshtastic channel.
+	ChannelName string
+	// Key is the AES-128 or AES-256 pre-shared key of the channel.
+	Key []byte
+	// HopLimit is the number of times that a packet sent by the Device may be relayed.
+	HopLimit uint8
+}
+
+// DefaultMeshtasticCodec is a MeshtasticCodec for the public "LongFast" channel that Meshtastic nodes use by default.
+var DefaultMeshtasticCodec = MeshtasticCodec{
+	ChannelName: "LongFast",
+	Key:         []byte{0xd4, 0xf1, 0xbb, 0x3a, 0x20, 0x29, 0x07, 0x59, 0xf0, 0xbc, 0xff, 0xab, 0xcf, 0x4e, 0x69, 0x01},
+	HopLimit:    3,
+}
+
+// ChannelHash returns the hash byte that Meshtastic puts in the header to identify the channel of a packet.
+func (c MeshtasticCodec) ChannelHash() byte {
+	var hash byte
+	for _, b := range []byte(c.ChannelName) {
+		hash ^= b
+	}
+	for _, b := range c.Key {
+		hash ^= b
+	}
+	return hash
+}
+
+// Encode converts a Message to a Meshtastic text message packet, which is a broadcast unless the Message is addressed to one node. The ID of the Person is used as the Meshtastic node number.
+func (c MeshtasticCodec) Encode(input Message) (output []byte, err error) {
+	packetID := rand.Uint32()
+	from := meshtasticNodeNumber(input.Person.ID)
+	to := uint32(meshtasticBroadcast)
+	if input.To != BroadcastID {
+		to = meshtasticNodeNumber(input.To)
+	}
+
+	output = make([]byte, meshtasticHeaderLength)
+	binary.LittleEndian.PutUint32(output[0:4], to)
+	binary.LittleEndian.PutUint32(output[4:8], from)
+	binary.LittleEndian.PutUint32(output[8:12], packetID)
+	output[12] = (c.HopLimit & 0x07) | (c.HopLimit&0x07)<<5
+	output[13] = c.ChannelHash()
+
+	// The payload is a protobuf Data message with the port number in field 1 and the text in field 2.
+	payload := []byte{0x08, meshtasticPortTextMessage, 0x12}
+	payload = appendVarint(payload, uint64(len(input.Text)))
+	payload = append(payload, []byte(input.Text)...)
+
+	err = c.crypt(payload, packetID, from)
+	if err != nil {
+		return nil, err
+	}
+	return append(output, payload...), nil
+}
+
+// Decode converts a Meshtastic text message packet to a Message. The Person has the node number as its ID, and a name in the Meshtastic "!1234abcd" style.
+func (c MeshtasticCodec) Decode(input []byte) (output Message, err error) {
+	if len(input) < meshtasticHeaderLength {
+		return output, ErrMeshtasticPacketTooShort
+	}
+	from := binary.LittleEndian.Uint32(input[4:8])
+	packetID := binary.LittleEndian.Uint32(input[8:12])
+	if input[13] != c.ChannelHash() {
+		return output, ErrMeshtasticWrongChannel
+	}
+
+	payload := make([]byte, len(input)-meshtasticHeaderLength)
+	copy(payload, input[meshtasticHeaderLength:])
+	err = c.crypt(payload, packetID, from)
+	if err != nil {
+		return output, err
+	}
+
+	port, text, err := decodeMeshtasticData(payload)
+	if err != nil {
+		return output, err
+	}
+	if port != meshtasticPortTextMessage {
+		return output, ErrMeshtasticNotText
+	}
+	output.Text, output.Truncated = truncateString(string(text), MaxMessageTextLength)
+	output.Person = Person{Name: fmt.Sprintf("!%08x", from), ID: meshtasticNodeID(from)}
+	if to := binary.LittleEndian.Uint32(input[0:4]); to != meshtasticBroadcast {
+		output.To = meshtasticNodeID(to)
+	}
+	return output, nil
+}
+
+// meshtasticNodeID converts a Meshtastic node number to the ID of a Person. The number is kept as 32 signed bits, so that node numbers from 2^31 up have the same ID on the 32-bit firmware as everywhere else.
+func meshtasticNodeID(node uint32) int {
+	return int(int32(node))
+}
+
+// meshtasticNodeNumber converts the ID of a Person back to the Meshtastic node number that meshtasticNodeID made it from.
+func meshtasticNodeNumber(id int) uint32 {
+	return uint32(int32(id))
+}
+
+// crypt encrypts or decrypts a Meshtastic payload in place with AES-CTR. The nonce is made from the packet ID and the sender.
+func (c MeshtasticCodec) crypt(payload []byte, packetID uint32, from uint32) (err error) {
+	block, err := aes.NewCipher(c.Key)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, aes.BlockSize)
+	binary.LittleEndian.PutUint64(nonce[0:8], uint64(packetID))
+	binary.LittleEndian.PutUint32(nonce[8:12], from)
+	cipher.NewCTR(block, nonce).XORKeyStream(payload, payload)
+	return nil
+}
+
+// decodeMeshtasticData reads the port number and payload out of a protobuf Data message. Unknown fields are skipped.
+func decodeMeshtasticData(data []byte) (port uint64, payload []byte, err error) {
+	for len(data) > 0 {
+		key, n := readVarint(data)
+		if n == 0 {
+			return 0, nil, ErrMeshtasticBadPayload
+		}
+		data = data[n:]
+		switch key & 0x07 {
+		case 0:
+			// A varint field.
+			value, n := readVarint(data)
+			if n == 0 {
+				return 0, nil, ErrMeshtasticBadPayload
+			}
+			data = data[n:]
+			if key>>3 == 1 {
+				port = value
+			}
+		case 2:
+			// A length delimited field.
+			length, n := readVarint(data)
+			if n == 0 || uint64(len(data)-n) < length {
+				return 0, nil, ErrMeshtasticBadPayload
+			}
+			data = data[n:]
+			if key>>3 == 2 {
+				payload = data[:length]
+			}
+			data = data[length:]
+		case 5:
+			// A 32 bit field.
+			if len(data) < 4 {
+				return 0, nil, ErrMeshtasticBadPayload
+			}
+			data = data[4:]
+		case 1:
+			// A 64 bit field.
+			if len(data) < 8 {
+				return 0, nil, ErrMeshtasticBadPayload
+			}
+			data = data[8:]
+		default:
+			return 0, nil, ErrMeshtasticBadPayload
+		}
+	}
+	return port, payload, nil
+}
+
+// appendVarint appends a protobuf varint to a byte slice.
+func appendVarint(b []byte, value uint64) []byte {
+	for value >= 0x80 {
+		b = append(b, byte(value)|0x80)
+		value >>= 7
+	}
+	return append(b, byte(value))
+}
+
+// readVarint reads a protobuf varint from the start of a byte slice. It returns the value and the number of bytes read, which is 0 if the varint is invalid.
+func readVarint(b []byte) (value uint64, n int) {
+	for i := 0; i < len(b) && i < 10; i++ {
+		value |= uint64(b[i]&0x7f) << (7 * i)
+		if b[i] < 0x80 {
+			return value, i + 1
+		}
+	}
+	return 0, 0
+}
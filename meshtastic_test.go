@@ -0,0 +1,95 @@
+package picodoomsdaymessenger
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+func TestMeshtasticChannelHash(t *testing.T) {
+	// The default LongFast channel is known to have a hash of 8.
+	if DefaultMeshtasticCodec.ChannelHash() != 8 {
+		t.Errorf("The channel hash should be 8 but is %v", DefaultMeshtasticCodec.ChannelHash())
+	}
+}
+
+func TestMeshtasticEncodeDecode(t *testing.T) {
+	packet, err := DefaultMeshtasticCodec.Encode(Message{Text: "hello mesh", Person: Person{Name: "Test", ID: 0x1234abcd}})
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	if bytes.Contains(packet, []byte("hello mesh")) {
+		t.Errorf("The packet should be encrypted but contains the text")
+	}
+	message, err := DefaultMeshtasticCodec.Decode(packet)
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	if message.Text != "hello mesh" {
+		t.Errorf("The message text is not correct, have: %v want: %v", message.Text, "hello mesh")
+	}
+	if message.Person.ID != 0x1234abcd || message.Person.Name != "!1234abcd" {
+		t.Errorf("The message person is not correct, have: %v want: %v", message.Person, Person{Name: "!1234abcd", ID: 0x1234abcd})
+	}
+
+	otherChannel := DefaultMeshtasticCodec
+	otherChannel.ChannelName = "Other"
+	_, err = otherChannel.Decode(packet)
+	if err != ErrMeshtasticWrongChannel {
+		t.Errorf("The error should be ErrMeshtasticWrongChannel but is %v", err)
+	}
+	_, err = DefaultMeshtasticCodec.Decode(packet[:10])
+	if err != ErrMeshtasticPacketTooShort {
+		t.Errorf("The error should be ErrMeshtasticPacketTooShort but is %v", err)
+	}
+}
+
+func TestMeshtasticHighNodeNumber(t *testing.T) {
+	from := uint32(0xFFFFFFF0)
+	packet, err := DefaultMeshtasticCodec.Encode(Message{Text: "hi", Person: Person{ID: meshtasticNodeID(from)}, To: meshtasticNodeID(0xFFFFFFF1)})
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	if binary.LittleEndian.Uint32(packet[4:8]) != from || binary.LittleEndian.Uint32(packet[0:4]) != 0xFFFFFFF1 {
+		t.Errorf("The packet should be from fffffff0 to fffffff1 but is %x", packet[0:8])
+	}
+	message, err := DefaultMeshtasticCodec.Decode(packet)
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	// The ID is the same on 32-bit and 64-bit targets.
+	if message.Person.ID != -16 || message.Person.Name != "!fffffff0" {
+		t.Errorf("The message person is not correct, have: %v want: %v", message.Person, Person{Name: "!fffffff0", ID: -16})
+	}
+	if message.To != -15 {
+		t.Errorf("The message should be to -15 but is to %v", message.To)
+	}
+}
+
+func TestReceiveMeshtasticFromRadio(t *testing.T) {
+	// Create a new Machine
+	device, err := NewDevice()
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	packet, err := DefaultMeshtasticCodec.Encode(Message{Text: "hello", Person: Person{ID: 7}})
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+
+	// Meshtastic packets are not understood unless Meshtastic is turned on.
+	err = device.ReceiveFromRadio(packet)
+	if err != ErrInvalidMessage {
+		t.Errorf("The error should be ErrInvalidMessage but is %v", err)
+	}
+
+	device.Settings.Meshtastic = true
+	err = device.ReceiveFromRadio(packet)
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	if len(device.Conversations) != 1 || device.Conversations[0].Messages[0].Text != "hello" {
+		t.Errorf("A conversation with the message should have been created but have: %v", device.Conversations)
+	}
+	device.StateConversationsMenu = device.StateConversationsMenuOld
+}
@@ -0,0 +1,77 @@
+package picodoomsdaymessenger
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// MonitorSize is the number of packets that the packet monitor remembers.
+var MonitorSize = 50
+
+// MonitoredPacket is a packet that was heard by the radio, whether or not the Device could decode it.
+type MonitoredPacket struct {
+	Time    time.Time
+	Length  int
+	RSSI    int
+	Kind    string
+	Decoded bool
+}
+
+// packetKind returns the prefix of a packet if it is printable, or "raw" for packets from other protocols.
+func packetKind(packetPayload []byte) string {
+	if len(packetPayload) < 4 {
+		return "raw"
+	}
+	for _, b := range packetPayload[:4] {
+		if b < 0x21 || b > 0x7e {
+			return "raw"
+		}
+	}
+	return string(packetPayload[:4])
+}
+
+// monitorPacket records a packet in the packet monitor, forgetting the oldest packet once there are MonitorSize of them.
+func (d *Device) monitorPacket(packetPayload []byte) {
+	d.MonitoredPackets = append(d.MonitoredPackets, MonitoredPacket{
+		Time:    d.Now(),
+		Length:  len(packetPayload),
+		RSSI:    d.lastRSSI,
+		Kind:    packetKind(packetPayload),
+		Decoded: true,
+	})
+	if len(d.MonitoredPackets) > MonitorSize {
+		d.MonitoredPackets = d.MonitoredPackets[len(d.MonitoredPackets)-MonitorSize:]
+	}
+}
+
+// monitorText lists the monitored packets, newest first, one per line short enough to fit on the screen.
+func (d *Device) monitorText() string {
+	if len(d.MonitoredPackets) == 0 {
+		return "Nothing heard yet"
+	}
+	lines := make([]string, 0, len(d.MonitoredPackets))
+	for i := len(d.MonitoredPackets) - 1; i >= 0; i-- {
+		p := d.MonitoredPackets[i]
+		status := "ok"
+		if !p.Decoded {
+			status = "bad"
+		}
+		lines = append(lines, fmt.Sprintf("%3db %4s %s %s", p.Length, formatRSSI(p.RSSI), p.Kind, status))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// processMonitorInputEvent scrolls the packet monitor, and leaves it with accept.
+func processMonitorInputEvent(d *Device, inputEvent InputEvent) (handled bool, err error) {
+	if inputEvent == InputEventAccept {
+		return true, d.GoBackState()
+	}
+	return scrollTextPage(&d.monitorScroll, inputEvent, d.monitorText(), d.Layout()), nil
+}
+
+// drawMonitor draws the list of monitored packets.
+func drawMonitor(d *Device, img Canvas, layout Layout) (err error) {
+	drawTextPage(img, layout, d.Palette(), fmt.Sprintf("Monitor (%d)", len(d.MonitoredPackets)), d.monitorText(), d.monitorScroll)
+	return nil
+}
@@ -0,0 +1,51 @@
+package picodoomsdaymessenger
+
+import (
+	"image"
+	"strings"
+	"testing"
+)
+
+func TestPacketMonitor(t *testing.T) {
+	device, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	device.ReceiveFromRadioWithRSSI(LocationToBytes(Person{"Test", 1}, Position{Latitude: 1, Longitude: 2}), -70)
+	device.ReceiveFromRadioWithRSSI([]byte{0x94, 0xc3, 0x00}, -110)
+	if len(device.MonitoredPackets) != 2 {
+		t.Fatalf("expected 2 packets, got %d", len(device.MonitoredPackets))
+	}
+	first, second := device.MonitoredPackets[0], device.MonitoredPackets[1]
+	if first.Kind != "locn" || !first.Decoded || first.RSSI != -70 {
+		t.Errorf("unexpected first packet %+v", first)
+	}
+	if second.Kind != "raw" || second.Decoded || second.RSSI != -110 || second.Length != 3 {
+		t.Errorf("unexpected second packet %+v", second)
+	}
+	if lines := strings.Split(device.monitorText(), "\n"); lines[0] != "  3b -110 raw bad" {
+		t.Errorf("expected the newest packet first, got %q", lines[0])
+	}
+
+	for i := 0; i < MonitorSize+5; i++ {
+		device.ReceiveFromRadio([]byte("bad"))
+	}
+	if len(device.MonitoredPackets) != MonitorSize {
+		t.Errorf("expected only %d packets to be kept, got %d", MonitorSize, len(device.MonitoredPackets))
+	}
+
+	err = ToolsMenuItemMonitor.Action(device)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	err = device.ProcessInputEvent(InputEventDown)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if device.monitorScroll != 1 {
+		t.Errorf("expected to scroll down, got %d", device.monitorScroll)
+	}
+	if _, err := GetFrame(image.Rect(0, 0, 128, 64), device); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+}
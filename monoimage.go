@@ -0,0 +1,101 @@
+package picodoomsdaymessenger
+
+import (
+	"image"
+	"image/color"
+)
+
+// Canvas is an image that frames can be drawn on. Both *image.RGBA and *MonoImage are Canvases.
+type Canvas interface {
+	image.Image
+	// SetRGBA changes the color of a pixel. Pixels outside of the Canvas are ignored.
+	SetRGBA(x, y int, c color.RGBA)
+	// RGBAAt returns the color of a pixel.
+	RGBAAt(x, y int) color.RGBA
+}
+
+// MonoImage is a Canvas with one bit per pixel, laid out in pages like the memory of an SSD1306 display and a PageBuffer.
+// A 128x64 frame fits in 1KB, and each page can be sent to the screen as it is.
+// Any color that is not black is drawn as white.
+type MonoImage struct {
+	// Pix holds the pixels of every page, one after another. Each byte is one column of a page, with the top pixel in the lowest bit.
+	Pix  []byte
+	Rect image.Rectangle
+}
+
+// NewMonoImage creates a black MonoImage. Its height is rounded up to a whole number of pages.
+func NewMonoImage(r image.Rectangle) *MonoImage {
+	pages := (r.Dy() + PageHeight - 1) / PageHeight
+	return &MonoImage{Pix: make([]byte, r.Dx()*pages), Rect: r}
+}
+
+// ColorModel returns color.GrayModel, as a MonoImage only holds black and white.
+func (m *MonoImage) ColorModel() color.Model {
+	return color.GrayModel
+}
+
+// Bounds returns the rectangle that the MonoImage covers.
+func (m *MonoImage) Bounds() image.Rectangle {
+	return m.Rect
+}
+
+// At returns the color of a pixel, either black or white.
+func (m *MonoImage) At(x, y int) color.Color {
+	if m.on(x, y) {
+		return color.Gray{Y: 255}
+	}
+	return color.Gray{}
+}
+
+// RGBAAt returns the color of a pixel, either black or white.
+func (m *MonoImage) RGBAAt(x, y int) color.RGBA {
+	if m.on(x, y) {
+		return color.RGBA{255, 255, 255, 255}
+	}
+	return color.RGBA{0, 0, 0, 255}
+}
+
+// Set changes a pixel to white if the color is not black, or to black if it is.
+func (m *MonoImage) Set(x, y int, c color.Color) {
+	m.SetRGBA(x, y, color.RGBAModel.Convert(c).(color.RGBA))
+}
+
+// SetRGBA changes a pixel to white if the color is not black, or to black if it is. It matches how PageBuffer.DrawImage converts colors.
+func (m *MonoImage) SetRGBA(x, y int, c color.RGBA) {
+	if !(image.Point{x, y}.In(m.Rect)) {
+		return
+	}
+	i, bit := m.offset(x, y)
+	// The same luminance as color.GrayModel, without converting through an interface.
+	r, g, b := uint32(c.R)*0x101, uint32(c.G)*0x101, uint32(c.B)*0x101
+	if (19595*r+38470*g+7471*b+1<<15)>>24 != 0 {
+		m.Pix[i] |= bit
+	} else {
+		m.Pix[i] &^= bit
+	}
+}
+
+// Page returns the bytes of a page, ready to be sent to the screen.
+func (m *MonoImage) Page(page int) []byte {
+	return m.Pix[page*m.Rect.Dx() : (page+1)*m.Rect.Dx()]
+}
+
+// Pages returns the number of pages in the MonoImage.
+func (m *MonoImage) Pages() int {
+	return len(m.Pix) / m.Rect.Dx()
+}
+
+// on returns true if a pixel is white.
+func (m *MonoImage) on(x, y int) bool {
+	if !(image.Point{x, y}.In(m.Rect)) {
+		return false
+	}
+	i, bit := m.offset(x, y)
+	return m.Pix[i]&bit != 0
+}
+
+// offset returns the index in Pix of the byte that holds a pixel, and the bit of the pixel in it.
+func (m *MonoImage) offset(x, y int) (i int, bit byte) {
+	x, y = x-m.Rect.Min.X, y-m.Rect.Min.Y
+	return x + (y/PageHeight)*m.Rect.Dx(), 1 << uint(y%PageHeight)
+}
@@ -0,0 +1,64 @@
+package picodoomsdaymessenger
+
+import (
+	"image"
+	"image/color"
+	"reflect"
+	"testing"
+)
+
+func TestMonoImage(t *testing.T) {
+	m := NewMonoImage(image.Rect(0, 0, 128, 64))
+	if len(m.Pix) != 1024 || m.Pages() != 8 {
+		t.Fatalf("expected a 128x64 frame to fit in 1KB, got %d bytes", len(m.Pix))
+	}
+	m.SetRGBA(3, 10, color.RGBA{255, 170, 0, 255})
+	m.SetRGBA(200, 10, color.RGBA{255, 255, 255, 255})
+	if m.RGBAAt(3, 10) != (color.RGBA{255, 255, 255, 255}) || m.Pix[128+3] != 1<<2 {
+		t.Errorf("expected the pixel to be white in page 1, got %v", m.Pix[128+3])
+	}
+	m.Set(3, 10, color.Black)
+	if m.RGBAAt(3, 10) != (color.RGBA{0, 0, 0, 255}) {
+		t.Errorf("expected the pixel to be black")
+	}
+}
+
+func TestRenderFrameMono(t *testing.T) {
+	device, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	rgba := image.NewRGBA(image.Rect(0, 0, 128, 64))
+	err = RenderFrame(rgba, device)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	mono := NewMonoImage(image.Rect(0, 0, 128, 64))
+	err = RenderFrame(mono, device)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	want := NewPageBuffer(128, 64)
+	want.DrawImage(rgba)
+	if !reflect.DeepEqual(mono.Pix, want.Buffer) {
+		t.Errorf("expected the MonoImage to match the RGBA frame")
+	}
+
+	screen := NewPageBuffer(128, 64)
+	if rects := screen.DrawMonoImage(mono); len(rects) != 8 {
+		t.Errorf("expected every page to be sent the first time, got %v", rects)
+	}
+	for page := 0; page < screen.Pages(); page++ {
+		screen.MarkSent(page)
+	}
+	if !reflect.DeepEqual(screen.Buffer, mono.Pix) {
+		t.Errorf("expected the MonoImage to be copied into the PageBuffer")
+	}
+	// Flip a pixel in two columns of page 2.
+	for _, x := range []int{10, 30} {
+		mono.SetRGBA(x, 20, color.RGBA{^mono.RGBAAt(x, 20).R, 0, 0, 255})
+	}
+	if rects := screen.DrawMonoImage(mono); !reflect.DeepEqual(rects, []image.Rectangle{image.Rect(10, 16, 31, 24)}) {
+		t.Errorf("expected only the changed columns to be sent, got %v", rects)
+	}
+}
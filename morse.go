@@ -0,0 +1,192 @@
+package picodoomsdaymessenger
+
+import (
+	"image/color"
+	"strings"
+	"time"
+)
+
+// InputMethod is a way of typing text into the KeyboardBuffer.
+type InputMethod string
+
+const (
+	// InputMethodMultiTap types by pressing the number keys one or more times, like an old phone.
+	InputMethodMultiTap InputMethod = "Multi-tap"
+	// InputMethodMorse types by tapping out Morse code on the MorseKey.
+	InputMethodMorse InputMethod = "Morse"
+)
+
+// InputMethods is the list of input methods that can be selected.
+var InputMethods = []InputMethod{InputMethodMultiTap, InputMethodMorse, InputMethodPicker, InputMethodPredictive}
+
+// Define the timings of Morse input.
+var (
+	// MorseHoldGap is the longest time between repeated key events that still counts as the key being held down, which turns a dot into a dash.
+	MorseHoldGap = 300 * time.Millisecond
+	// MorseLetterGap is how long the key has to be left alone for the letter to be finished.
+	MorseLetterGap = 1 * time.Second
+	// MorseWordGap is how long the key has to be left alone for a space to be added.
+	MorseWordGap = 3 * time.Second
+)
+
+// MorseCode maps each character to its Morse code, made of dots and dashes.
+var MorseCode = map[rune]string{
+	'a': ".-", 'b': "-...", 'c': "-.-.", 'd': "-..", 'e': ".", 'f': "..-.", 'g': "--.", 'h': "....", 'i': "..",
+	'j': ".---", 'k': "-.-", 'l': ".-..", 'm': "--", 'n': "-.", 'o': "---", 'p': ".--.", 'q': "--.-", 'r': ".-.",
+	's': "...", 't': "-", 'u': "..-", 'v': "...-", 'w': ".--", 'x': "-..-", 'y': "-.--", 'z': "--..",
+	'1': ".----", '2': "..---", '3': "...--", '4': "....-", '5': ".....", '6': "-....", '7': "--...", '8': "---..", '9': "----.", '0': "-----",
+	'.': ".-.-.-", ',': "--..--", '?': "..--..", '!': "-.-.--", '/': "-..-.", '@': ".--.-.", '-': "-....-",
+}
+
+// DecodeMorse returns the character for a Morse code of dots and dashes. It returns false if the code is not known.
+func DecodeMorse(code string) (character string, ok bool) {
+	for r, c := range MorseCode {
+		if c == code {
+			return string(r), true
+		}
+	}
+	return "", false
+}
+
+// morseKeyer holds the Morse code that is currently being tapped out.
+type morseKeyer struct {
+	elements  string
+	lastPress time.Time
+	// wordPending is true when a letter has been typed since the last space.
+	wordPending bool
+}
+
+// ProcessMorseKey handles the MorseKey being pressed at a time. A single press is a dot, and holding the key so that it repeats within MorseHoldGap makes a dash.
+func (d *Device) ProcessMorseKey(now time.Time) {
+	if d.morse.elements != "" && now.Sub(d.morse.lastPress) <= MorseHoldGap {
+		d.morse.elements = d.morse.elements[:len(d.morse.elements)-1] + "-"
+	} else {
+		d.morse.elements += "."
+	}
+	d.morse.lastPress = now
+}
+
+// tickMorse finishes the current letter or word if the MorseKey has been left alone for long enough.
+func (d *Device) tickMorse(now time.Time) {
+	if d.Settings.InputMethod != InputMethodMorse || d.State != &d.StateConversationReader || d.CurrentConversationIndex >= len(d.Conversations) {
+		return
+	}
+	if d.morse.elements != "" && now.Sub(d.morse.lastPress) > MorseLetterGap {
+		d.flushMorse()
+		d.MarkDirty()
+	}
+	if d.morse.wordPending && now.Sub(d.morse.lastPress) > MorseWordGap {
+		d.Conversations[d.CurrentConversationIndex].KeyboardBuffer += " "
+		d.morse.wordPending = false
+		d.MarkDirty()
+	}
+}
+
+// flushMorse decodes the Morse code that has been tapped out so far into the KeyboardBuffer. Unknown codes are dropped.
+func (d *Device) flushMorse() {
+	if d.morse.elements == "" {
+		return
+	}
+	character, ok := DecodeMorse(d.morse.elements)
+	d.morse.elements = ""
+	if !ok {
+		return
+	}
+	d.Conversations[d.CurrentConversationIndex].KeyboardBuffer += character
+	d.morse.wordPending = true
+}
+
+// inputMethodMenuItems creates a MenuItem for every InputMethod.
+func inputMethodMenuItems() (items []MenuItem) {
+	names := make([]string, len(InputMethods))
+	for i, inputMethod := range InputMethods {
+		names[i] = string(inputMethod)
+	}
+	return choiceMenuItems(names, func(d *Device, i int) bool {
+		return d.Settings.InputMethod == InputMethods[i]
+	}, func(d *Device, i int) (err error) {
+		d.Settings.InputMethod = InputMethods[i]
+		d.morse = morseKeyer{}
+		d.predictive = predictiveState{}
+		return nil
+	})
+}
+
+// MorseFrameDuration is the length of one Morse unit (the length of a dot) in a Morse LED animation.
+var MorseFrameDuration = 200 * time.Millisecond
+
+// MorseLightMessages is the list of messages that can be flashed in Morse code from the Tools menu.
+var MorseLightMessages = []string{"HELP", "OK", "NEED WATER", "NEED MEDIC", "FOLLOW ME"}
+
+// NewMorseLEDAnimation converts text to an LED animation that flashes it in Morse code on all the LEDs.
+// Each frame is one unit long: a dot is 1 unit on, a dash is 3 units on, the gaps are 1 unit between parts of a letter, 3 between letters and 7 between words.
+// The animation ends with a word gap, so that it can be repeated. Characters without a Morse code are skipped.
+func NewMorseLEDAnimation(text string) *LEDAnimation {
+	on := [6]color.RGBA{{255, 255, 255, 255}, {255, 255, 255, 255}, {255, 255, 255, 255}, {255, 255, 255, 255}, {255, 255, 255, 255}, {255, 255, 255, 255}}
+	off := [6]color.RGBA{{0, 0, 0, 0}, {0, 0, 0, 0}, {0, 0, 0, 0}, {0, 0, 0, 0}, {0, 0, 0, 0}, {0, 0, 0, 0}}
+	frames := [][6]color.RGBA{}
+	addFrames := func(frame [6]color.RGBA, count int) {
+		for i := 0; i < count; i++ {
+			frames = append(frames, frame)
+		}
+	}
+	for _, word := range strings.Fields(strings.ToLower(text)) {
+		for _, character := range word {
+			code, ok := MorseCode[character]
+			if !ok {
+				continue
+			}
+			for i, element := range code {
+				if i > 0 {
+					addFrames(off, 1)
+				}
+				if element == '.' {
+					addFrames(on, 1)
+				} else {
+					addFrames(on, 3)
+				}
+			}
+			addFrames(off, 3)
+		}
+		// A word gap is 7 units, 3 of which have already been added after the last letter.
+		addFrames(off, 4)
+	}
+	if len(frames) == 0 {
+		addFrames(off, 1)
+	}
+	return &LEDAnimation{
+		FrameDuration: MorseFrameDuration,
+		CurrentFrame:  0,
+		Frames:        frames,
+	}
+}
+
+// morseLightMenuItems creates a MenuItem for every message in MorseLightMessages, that toggles flashing it on the RGB LEDs.
+// With MorseTones on, the message is also played once on the buzzer.
+func morseLightMenuItems() (items []MenuItem) {
+	for i := 0; i < len(MorseLightMessages); i++ {
+		animation := NewMorseLEDAnimation(MorseLightMessages[i])
+		tones := NewMorseTones(MorseLightMessages[i])
+		items = append(items, MenuItem{
+			Text: MorseLightMessages[i],
+			Action: func(d *Device) (err error) {
+				if d.LEDAnimation != animation {
+					if d.Settings.MorseTones {
+						err = d.PlayTones(tones)
+						if err != nil {
+							return err
+						}
+					}
+					return d.ChangeLEDAnimationWithoutContinue(animation)
+				}
+				d.stopTones()
+				return d.ChangeLEDAnimationWithoutContinue(&LEDAnimationDefault)
+			},
+			GetCursorData: func(d *Device) (data any, err error) {
+				return d.LEDAnimation == animation, nil
+			},
+			CursorIcon: CursorIconBox,
+		})
+	}
+	return items
+}
@@ -0,0 +1,176 @@
+package picodoomsdaymessenger
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDecodeMorse(t *testing.T) {
+	for r, code := range MorseCode {
+		character, ok := DecodeMorse(code)
+		if !ok || character != string(r) {
+			t.Errorf("The Morse code %v should decode to %v but decodes to %v", code, string(r), character)
+		}
+	}
+	_, ok := DecodeMorse("........")
+	if ok {
+		t.Errorf("An unknown Morse code should not decode")
+	}
+}
+
+func TestMorseInput(t *testing.T) {
+	// Create a new Machine
+	device, err := NewDevice()
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	device.Settings.InputMethod = InputMethodMorse
+	device.Conversations = []*Conversation{{}}
+	device.CurrentConversationIndex = 0
+	device.State = &device.StateConversationReader
+	now := time.Unix(1000, 0)
+
+	// Tap "s" (...), then hold for "o" (---).
+	for i := 0; i < 3; i++ {
+		device.ProcessMorseKey(now)
+		now = now.Add(MorseHoldGap * 2)
+	}
+	if device.ComposerText() != "..." {
+		t.Errorf("The composer text should be ... but is %v", device.ComposerText())
+	}
+	now = now.Add(MorseLetterGap)
+	device.tickMorse(now)
+	for i := 0; i < 3; i++ {
+		device.ProcessMorseKey(now)
+		now = now.Add(MorseHoldGap / 2)
+		device.ProcessMorseKey(now)
+		now = now.Add(MorseHoldGap * 2)
+	}
+	now = now.Add(MorseLetterGap)
+	device.tickMorse(now)
+	if device.Conversations[0].KeyboardBuffer != "so" {
+		t.Errorf("The keyboard buffer should be so but is %v", device.Conversations[0].KeyboardBuffer)
+	}
+	device.tickMorse(now.Add(MorseWordGap))
+	if device.Conversations[0].KeyboardBuffer != "so " {
+		t.Errorf("The keyboard buffer should end with a space but is %q", device.Conversations[0].KeyboardBuffer)
+	}
+}
+
+func TestMorseInputEvents(t *testing.T) {
+	// Create a new Machine
+	device, err := NewDevice()
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	var sent []byte
+	device.SendUsingRadio = func(packet []byte) (err error) {
+		sent = packet
+		return nil
+	}
+	err = device.StateInputMethodMenu.Content[2].Action(device)
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	if device.Settings.InputMethod != InputMethodMorse {
+		t.Errorf("The input method should be Morse but is %v", device.Settings.InputMethod)
+	}
+	device.Conversations = []*Conversation{{}}
+	device.CurrentConversationIndex = 0
+	device.State = &device.StateConversationReader
+
+	// Other number keys do nothing in Morse mode.
+	err = device.ProcessInputEvent(InputEventNumber2)
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	err = device.ProcessInputEvent(device.Settings.MorseKey)
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	err = device.ProcessInputEvent(InputEventAccept)
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	err = sendQueued(device)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	message, err := device.BytesToMessage(sent)
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	if message.Text != "e" {
+		t.Errorf("The sent message should be e but is %v", message.Text)
+	}
+}
+
+func TestMorseWordGapAfterSending(t *testing.T) {
+	// Create a new Machine
+	device, err := NewDevice()
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	device.SendUsingRadio = func(packet []byte) (err error) {
+		return nil
+	}
+	device.Settings.InputMethod = InputMethodMorse
+	device.Conversations = []*Conversation{{}}
+	device.CurrentConversationIndex = 0
+	device.State = &device.StateConversationReader
+	now := time.Now()
+
+	// Tap "e" and send it before the letter is finished.
+	device.ProcessMorseKey(now)
+	err = device.ProcessInputEvent(InputEventAccept)
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	device.tickMorse(now.Add(MorseWordGap * 2))
+	if device.Conversations[0].KeyboardBuffer != "" {
+		t.Errorf("The keyboard buffer should be empty after sending but is %q", device.Conversations[0].KeyboardBuffer)
+	}
+}
+
+func TestNewMorseLEDAnimation(t *testing.T) {
+	animation := NewMorseLEDAnimation("Et e")
+	// e is ".", t is "-": dot, letter gap, dash, word gap, dot, word gap.
+	want := []bool{true, false, false, false, true, true, true, false, false, false, false, false, false, false, true, false, false, false, false, false, false, false}
+	if len(animation.Frames) != len(want) {
+		t.Fatalf("The animation should have %d frames but has %d", len(want), len(animation.Frames))
+	}
+	for i, on := range want {
+		if (animation.Frames[i][0].R == 255) != on {
+			t.Errorf("Frame %d should be on: %v but is %v", i, on, animation.Frames[i][0])
+		}
+	}
+	if animation.FrameDuration != MorseFrameDuration {
+		t.Errorf("The frame duration should be %v but is %v", MorseFrameDuration, animation.FrameDuration)
+	}
+	if len(LEDAnimationSOS.Frames) != 34 {
+		t.Errorf("The SOS animation should have 34 frames but has %d", len(LEDAnimationSOS.Frames))
+	}
+}
+
+func TestMorseLightMenu(t *testing.T) {
+	// Create a new Machine
+	device, err := NewDevice()
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	err = device.StateMorseLightMenu.Content[1].Action(device)
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	checked, _ := device.StateMorseLightMenu.Content[1].GetCursorData(device)
+	if checked != true || device.LEDAnimation == &LEDAnimationDefault {
+		t.Errorf("The Morse light should be on but is not")
+	}
+	err = device.StateMorseLightMenu.Content[1].Action(device)
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	if device.LEDAnimation != &LEDAnimationDefault {
+		t.Errorf("The LED animation should be LEDAnimationDefault but is %v", device.LEDAnimation)
+	}
+}
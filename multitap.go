@@ -0,0 +1,43 @@
+package picodoomsdaymessenger
+
+import "time"
+
+// MultiTapTimeouts is the list of times that a multi-tap character can be left alone for before it is added to the text, so that the same key can type the next character. Zero only adds it when another key is pressed.
+var MultiTapTimeouts = []time.Duration{0, 500 * time.Millisecond, time.Second, 1500 * time.Millisecond, 2 * time.Second}
+
+// tickMultiTap adds the character that is being typed to its buffer once its KeyboardButton has not been pressed for the MultiTapTimeout in the Settings.
+func (d *Device) tickMultiTap(now time.Time) {
+	if d.Settings.MultiTapTimeout <= 0 || d.pendingBuffer == nil || now.Sub(d.CurrentKeyboardButton.LastPress) < d.Settings.MultiTapTimeout {
+		return
+	}
+	*d.pendingBuffer += d.pendingCharacter()
+	d.pendingBuffer = nil
+	d.clearPendingCharacter()
+	d.MarkDirty()
+}
+
+// multiTapTimeoutSelector creates a Selector that chooses one of the MultiTapTimeouts.
+func multiTapTimeoutSelector() Selector {
+	names := make([]string, len(MultiTapTimeouts))
+	for i, timeout := range MultiTapTimeouts {
+		names[i] = timeout.String()
+		if timeout == 0 {
+			names[i] = "Off"
+		}
+	}
+	return Selector{
+		Names: names,
+		Get: func(d *Device) int {
+			for i, timeout := range MultiTapTimeouts {
+				if d.Settings.MultiTapTimeout == timeout {
+					return i
+				}
+			}
+			return -1
+		},
+		Set: func(d *Device, i int) (err error) {
+			d.Settings.MultiTapTimeout = MultiTapTimeouts[i]
+			return nil
+		},
+	}
+}
@@ -0,0 +1,57 @@
+package picodoomsdaymessenger
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMultiTapTimeout(t *testing.T) {
+	device, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	device.NewConversation(Person{ID: 7, Name: "Bob"})
+	device.CurrentConversationIndex = 0
+	device.State = &device.StateConversationReader
+	device.CurrentKeyboardButton = &KeyboardButton{Characters: []string{""}}
+	device.InputTiming.Debounce = 0
+
+	// Pressing 2 twice types "b", which is added to the text once the key is left alone.
+	for i := 0; i < 2; i++ {
+		err = device.ProcessInputEvent(InputEventNumber2)
+		if err != nil {
+			t.Fatalf("The error should be nil but is %v", err)
+		}
+	}
+	now := time.Now()
+	device.tickMultiTap(now)
+	if device.Conversations[0].KeyboardBuffer != "" || device.ComposerText() != "b" {
+		t.Fatalf("expected b to still be pending, got %q", device.Conversations[0].KeyboardBuffer)
+	}
+	err = device.Tick(now.Add(device.Settings.MultiTapTimeout))
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if device.Conversations[0].KeyboardBuffer != "b" {
+		t.Fatalf("expected b to be added after the timeout, got %q", device.Conversations[0].KeyboardBuffer)
+	}
+
+	// The same key then starts a new character.
+	err = device.ProcessInputEvent(InputEventNumber2)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if device.ComposerText() != "ba" {
+		t.Errorf("expected the same key to type a new character, got %q", device.ComposerText())
+	}
+
+	// With the timeout off, the character waits for another key.
+	device.Settings.MultiTapTimeout = 0
+	err = device.Tick(now.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if device.Conversations[0].KeyboardBuffer != "b" {
+		t.Errorf("expected a to still be pending, got %q", device.Conversations[0].KeyboardBuffer)
+	}
+}
@@ -0,0 +1,43 @@
+package picodoomsdaymessenger
+
+// IconMuted is a speaker with a cross, shown next to Conversations that are Muted.
+var IconMuted = Icon{
+	0b00010000,
+	0b00110000,
+	0b11110101,
+	0b11110010,
+	0b11110101,
+	0b00110000,
+	0b00010000,
+	0b00000000,
+}
+
+// Right on a Conversation opens its options. The copy that the Conversations menu is reset from needs the InputHandler too.
+func init() {
+	stateSetups = append(stateSetups, func(s *States) {
+		s.StateConversationsMenu.InputHandler = processConversationsMenuInputEvent
+		s.StateConversationsMenuOld.InputHandler = processConversationsMenuInputEvent
+	})
+}
+
+// ToggleConversationMuted mutes or unmutes a Conversation. Messages that are already Unread stay Unread.
+// Emergency messages are always shown, even in a Muted Conversation.
+func (d *Device) ToggleConversationMuted(c *Conversation) {
+	c.Muted = !c.Muted
+	d.UpdateConversationsMenu()
+}
+
+// processConversationsMenuInputEvent opens the options of the highlighted Conversation with right.
+func processConversationsMenuInputEvent(d *Device, inputEvent InputEvent) (handled bool, err error) {
+	if inputEvent != InputEventRight {
+		return false, nil
+	}
+	// The Conversations are listed after the other items of the menu.
+	index := d.State.HighlightedItemIndex - (len(d.State.Content) - len(d.Conversations))
+	if index < 0 || index >= len(d.Conversations) {
+		return true, nil
+	}
+	d.CurrentConversationIndex = index
+	d.StateConversationOptionsMenu.HighlightedItemIndex = 0
+	return true, d.ChangeStateWithHistory(&d.StateConversationOptionsMenu)
+}
@@ -0,0 +1,61 @@
+package picodoomsdaymessenger
+
+import "testing"
+
+func TestMuteConversation(t *testing.T) {
+	device, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	device.State = &device.StateMainMenu
+	events := []DeviceEvent{}
+	device.OnDeviceEvent = func(event DeviceEvent) {
+		events = append(events, event)
+	}
+	if err = device.ReceiveFromRadio([]byte("doom7\xccBob\xcchello")); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if err = device.ChangeStateWithHistory(&device.StateConversationsMenu); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	// The new Conversation is highlighted, so right opens its options.
+	if err = device.ProcessInputEvent(InputEventRight); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if device.State != &device.StateConversationOptionsMenu {
+		t.Fatalf("Right should open the options of the Conversation, have %q", device.State.Title)
+	}
+	device.StateConversationOptionsMenu.HighlightedItemIndex = 1
+	if err = device.ProcessInputEvent(InputEventAccept); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	c := device.Conversations[0]
+	if !c.Muted {
+		t.Fatalf("The Conversation should be muted")
+	}
+	if err = device.GoBackState(); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if item := device.StateConversationsMenu.Content[len(device.StateConversationsMenu.Content)-1]; item.Icon != &IconMuted {
+		t.Errorf("A muted Conversation should be shown with IconMuted")
+	}
+
+	events = events[:0]
+	if err = device.ReceiveFromRadio([]byte("doom7\xccBob\xccstill there?")); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if len(events) != 0 {
+		t.Errorf("A muted Conversation should not notify, have %v", events)
+	}
+	if c.Unread != 1 || len(c.Messages) != 2 {
+		t.Errorf("A muted Conversation should keep its messages without counting them as unread, have %d unread of %d", c.Unread, len(c.Messages))
+	}
+
+	device.ToggleConversationMuted(c)
+	if err = device.ReceiveFromRadio([]byte("doom7\xccBob\xcchello?")); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if len(events) != 1 || events[0] != DeviceEventMessageReceived || c.Unread != 2 {
+		t.Errorf("An unmuted Conversation should notify again, have %v and %d unread", events, c.Unread)
+	}
+}
@@ -0,0 +1,71 @@
+package picodoomsdaymessenger
+
+import "errors"
+
+// Define navigation errors
+var (
+	ErrConversationNotFound = errors.New("conversation does not exist")
+	ErrStateNotFound        = errors.New("no state has that name")
+)
+
+// NamedStates returns the States of the Device that can be opened by name with OpenState. States that need more context, such as the Conversation being read, are opened by their own functions instead.
+func (d *Device) NamedStates() map[string]*State {
+	return map[string]*State{
+		"main":          &d.StateMainMenu,
+		"conversations": &d.StateConversationsMenu,
+		"people":        &d.StatePeopleMenu,
+		"nearby":        &d.StateNearbyMenu,
+		"notes":         &d.StateNotesMenu,
+		"games":         &d.StateGamesMenu,
+		"tools":         &d.StateToolsMenu,
+		"compass":       &d.StateCompass,
+		"monitor":       &d.StateMonitor,
+		"settings":      &d.StateSettingsMenu,
+		"radio":         &d.StateRadioMenu,
+		"about":         &d.StateAbout,
+		"crash log":     &d.StateCrashLog,
+		"screensaver":   &d.StateScreensaver,
+	}
+}
+
+// OpenConversation shows the Conversation with an index in the conversation reader, and marks it as read. Going back returns to whatever was shown before.
+func (d *Device) OpenConversation(index int) (err error) {
+	if index < 0 || index >= len(d.Conversations) {
+		return ErrConversationNotFound
+	}
+	d.CurrentConversationIndex = index
+	d.Conversations[index].Unread = 0
+	if d.notifiedConversation == d.Conversations[index] {
+		d.notifiedConversation = nil
+	}
+	return d.ChangeStateWithHistory(&d.StateConversationReader)
+}
+
+// GoHome shows the main menu and forgets the StateHistory, so there is nothing to go back to.
+func (d *Device) GoHome() (err error) {
+	d.StateHistory = nil
+	return d.ChangeStateWithHistory(&d.StateMainMenu)
+}
+
+// OpenState shows one of the NamedStates.
+func (d *Device) OpenState(name string) (err error) {
+	state, ok := d.NamedStates()[name]
+	if !ok {
+		return ErrStateNotFound
+	}
+	return d.ChangeStateWithHistory(state)
+}
+
+// openNotifiedConversation opens the Conversation that the last unread message was received in, if it has not been read since.
+func (d *Device) openNotifiedConversation() (err error) {
+	if d.notifiedConversation == nil {
+		return nil
+	}
+	for i, c := range d.Conversations {
+		if c == d.notifiedConversation {
+			return d.OpenConversation(i)
+		}
+	}
+	d.notifiedConversation = nil
+	return nil
+}
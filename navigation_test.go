@@ -0,0 +1,89 @@
+package picodoomsdaymessenger
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNavigation(t *testing.T) {
+	device, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	device.State = &device.StateMainMenu
+	if err = device.ReceiveFromRadio([]byte("doom7\xccBob\xcchello")); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+
+	if err = device.OpenState("tools"); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if device.State != &device.StateToolsMenu {
+		t.Errorf("expected the tools menu, got %q", device.State.Title)
+	}
+	if err = device.OpenState("nowhere"); err != ErrStateNotFound {
+		t.Errorf("expected ErrStateNotFound, got %v", err)
+	}
+
+	if err = device.OpenConversation(0); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if device.State != &device.StateConversationReader || device.CurrentConversationIndex != 0 || device.Conversations[0].Unread != 0 {
+		t.Errorf("expected the conversation to be open and read")
+	}
+	if err = device.OpenConversation(1); err != ErrConversationNotFound {
+		t.Errorf("expected ErrConversationNotFound, got %v", err)
+	}
+	if err = device.GoBackState(); err != nil || device.State != &device.StateToolsMenu {
+		t.Errorf("expected to go back to the tools menu, got %v", err)
+	}
+
+	if err = device.GoHome(); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if device.State != &device.StateMainMenu || device.GoBackState() != ErrGoBackStateRootState {
+		t.Errorf("expected the main menu with nothing to go back to")
+	}
+}
+
+func TestScreensaverOpensNewMessage(t *testing.T) {
+	device, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	device.State = &device.StateMainMenu
+	device.Settings.ScreensaverTimeout = time.Minute
+	now := time.Now()
+	if err = device.Tick(now); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if err = device.Tick(now.Add(2 * time.Minute)); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if device.State != &device.StateScreensaver {
+		t.Fatalf("expected the screensaver, got %q", device.State.Title)
+	}
+	if err = device.ReceiveFromRadio([]byte("doom7\xccBob\xcchello")); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+
+	if err = device.ProcessInputEvent(InputEventAccept); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if device.State != &device.StateConversationReader || device.Conversations[device.CurrentConversationIndex].Unread != 0 {
+		t.Fatalf("expected accept to open the new message, got %q", device.State.Title)
+	}
+	// Once read, the message is not opened again.
+	if err = device.GoHome(); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if err = device.ChangeStateWithHistory(&device.StateScreensaver); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if err = device.ProcessInputEvent(InputEventAccept); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if device.State != &device.StateMainMenu {
+		t.Errorf("expected to go back to the main menu, got %q", device.State.Title)
+	}
+}
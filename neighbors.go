@@ -0,0 +1,151 @@
+package picodoomsdaymessenger
+
+import (
+	"fmt"
+	"image"
+	"sort"
+	"time"
+)
+
+// RSSIHistoryLength is how many RSSI samples are kept for each Neighbor.
+const RSSIHistoryLength = 32
+
+// Neighbor is a device that a packet has been heard from directly, without being relayed.
+type Neighbor struct {
+	Person Person
+	// RSSI is how strongly the last packet from the Neighbor was heard, or RSSIUnknown.
+	RSSI      int
+	LastHeard time.Time
+	// RSSIHistory is the RSSI of the last RSSIHistoryLength packets from the Neighbor that the radio reported it for, oldest first.
+	RSSIHistory []int
+}
+
+// heardNeighbor updates the Neighbor that sent a packet, or adds them to the Neighbors. Relayed packets are ignored, as the device that sent them may be out of range.
+// Some packets only carry an ID, so a name that is already known is kept. A name that the user has given them is always used.
+func (d *Device) heardNeighbor(p Person, hops int, now time.Time) {
+	if hops > 0 || p.ID == d.SelfIdentity.ID {
+		return
+	}
+	if name, ok := d.localName(p.ID); ok {
+		p.Name = name
+	}
+	for i := range d.Neighbors {
+		if d.Neighbors[i].Person.ID == p.ID {
+			if p.Name != "" {
+				d.Neighbors[i].Person.Name = p.Name
+			}
+			d.Neighbors[i].RSSI = d.lastRSSI
+			d.Neighbors[i].LastHeard = now
+			d.Neighbors[i].addRSSI(d.lastRSSI)
+			return
+		}
+	}
+	if p.Name == "" {
+		p.Name = d.PersonName(p.ID)
+	}
+	neighbor := Neighbor{Person: p, RSSI: d.lastRSSI, LastHeard: now}
+	neighbor.addRSSI(d.lastRSSI)
+	d.Neighbors = append(d.Neighbors, neighbor)
+}
+
+// addRSSI adds an RSSI sample to the RSSIHistory, and forgets the oldest once there are more than RSSIHistoryLength. Unknown RSSIs are not kept.
+func (n *Neighbor) addRSSI(rssi int) {
+	if rssi == RSSIUnknown {
+		return
+	}
+	n.RSSIHistory = append(n.RSSIHistory, rssi)
+	if len(n.RSSIHistory) > RSSIHistoryLength {
+		n.RSSIHistory = n.RSSIHistory[len(n.RSSIHistory)-RSSIHistoryLength:]
+	}
+}
+
+// neighbor returns the Neighbor with an ID.
+func (d *Device) neighbor(id int) (n Neighbor, ok bool) {
+	for _, n := range d.Neighbors {
+		if n.Person.ID == id {
+			return n, true
+		}
+	}
+	return Neighbor{}, false
+}
+
+// The Nearby menu shows how long ago each Neighbor was heard, so it is filled when it is shown.
+func init() {
+	stateSetups = append(stateSetups, func(s *States) {
+		s.StateNearbyMenu.LoadAction = func(d *Device) (err error) {
+			d.UpdateNearbyMenu(d.Now())
+			return nil
+		}
+	})
+}
+
+// UpdateNearbyMenu fills the StateNearbyMenu with the Neighbors, most recently heard first. Choosing a Neighbor shows how well they have been heard.
+func (d *Device) UpdateNearbyMenu(now time.Time) {
+	sort.SliceStable(d.Neighbors, func(i, j int) bool {
+		return d.Neighbors[i].LastHeard.After(d.Neighbors[j].LastHeard)
+	})
+	d.StateNearbyMenu.Content = []MenuItem{GlobalMenuItemGoBack}
+	for _, neighbor := range d.Neighbors {
+		person := neighbor.Person
+		d.StateNearbyMenu.Content = append(d.StateNearbyMenu.Content, MenuItem{
+			Text: fmt.Sprintf("%s %s %s", person.Name, formatRSSI(neighbor.RSSI), formatAge(now.Sub(neighbor.LastHeard))),
+			Action: func(d *Device) (err error) {
+				d.currentNeighbor = person.ID
+				return d.ChangeStateWithHistory(&d.StateNeighbor)
+			},
+			CursorIcon: CursorIconRightArrow,
+		})
+	}
+	if d.StateNearbyMenu.HighlightedItemIndex >= len(d.StateNearbyMenu.Content) {
+		d.StateNearbyMenu.HighlightedItemIndex = 0
+	}
+}
+
+// formatAge shows how long ago something happened, in the largest whole unit.
+func formatAge(age time.Duration) string {
+	switch {
+	case age < time.Minute:
+		return fmt.Sprintf("%ds", int(age.Seconds()))
+	case age < time.Hour:
+		return fmt.Sprintf("%dm", int(age.Minutes()))
+	}
+	return fmt.Sprintf("%dh", int(age.Hours()))
+}
+
+// processNeighborInputEvent starts a Conversation with the Neighbor with accept, renames them with right, and goes back with left.
+func processNeighborInputEvent(d *Device, inputEvent InputEvent) (handled bool, err error) {
+	switch inputEvent {
+	case InputEventAccept:
+		n, ok := d.neighbor(d.currentNeighbor)
+		if !ok {
+			return true, d.GoBackState()
+		}
+		return true, d.openConversationWith(n.Person)
+	case InputEventRight:
+		n, ok := d.neighbor(d.currentNeighbor)
+		if !ok {
+			return true, nil
+		}
+		return true, d.startRename(n.Person.ID, n.Person.Name)
+	case InputEventLeft:
+		return true, d.GoBackState()
+	case InputEventUp, InputEventDown:
+		return true, nil
+	}
+	return false, nil
+}
+
+// drawNeighbor draws when a Neighbor was last heard and how strongly, with a sparkline of their RSSIHistory below.
+func drawNeighbor(d *Device, img Canvas, layout Layout) (err error) {
+	n, ok := d.neighbor(d.currentNeighbor)
+	if !ok {
+		drawTextPage(img, layout, d.Palette(), "Neighbor", "Not heard", 0)
+		return nil
+	}
+	text := fmt.Sprintf("ID %d\nRSSI %s %s ago", n.Person.ID, formatRSSI(n.RSSI), formatAge(d.Now().Sub(n.LastHeard)))
+	drawTextPage(img, layout, d.Palette(), n.Person.Name, text, 0)
+	bounds := img.Bounds()
+	top := layout.TitleHeight + 2*layout.LineHeight + 4
+	drawSparkline(img, image.Rect(bounds.Min.X+2, top, bounds.Max.X-2, bounds.Max.Y-2), n.RSSIHistory, d.Palette().Text)
+	return nil
+}
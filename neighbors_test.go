@@ -0,0 +1,148 @@
+package picodoomsdaymessenger
+
+import (
+	"image"
+	"image/color"
+	"testing"
+	"time"
+)
+
+func TestNeighbors(t *testing.T) {
+	receiver, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	sender, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	sender.SelfIdentity = Person{Name: "Sender", ID: receiver.SelfIdentity.ID + 1}
+	relayed := false
+	sender.SendUsingRadio = func(packet []byte) (err error) {
+		if relayed {
+			packet, err = RelayPacket(packet)
+			if err != nil {
+				return err
+			}
+		}
+		return receiver.ReceiveFromRadioWithRSSI(packet, -90)
+	}
+
+	err = sender.SendPacket(nil, sender.BeaconToBytes())
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	err = sendQueued(sender)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if len(receiver.Neighbors) != 1 || receiver.Neighbors[0].Person != sender.SelfIdentity || receiver.Neighbors[0].RSSI != -90 {
+		t.Fatalf("expected the sender to be a neighbor, got %+v", receiver.Neighbors)
+	}
+	heard := receiver.Neighbors[0].LastHeard
+
+	if history := receiver.Neighbors[0].RSSIHistory; len(history) != 1 || history[0] != -90 {
+		t.Errorf("expected the RSSI to be kept in the history, got %v", history)
+	}
+
+	// A relayed message does not show that the sender is in range.
+	relayed = true
+	sender.Settings.HopLimit = 3
+	err = sender.SendMessage(sender.NewConversation(receiver.SelfIdentity), "hello")
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	err = sendQueued(sender)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if len(receiver.Conversations) != 1 {
+		t.Fatalf("expected the relayed message to be received")
+	}
+	if len(receiver.Neighbors) != 1 || !receiver.Neighbors[0].LastHeard.Equal(heard) {
+		t.Errorf("expected a relayed message not to update the neighbor, got %+v", receiver.Neighbors)
+	}
+
+	receiver.UpdateNearbyMenu(heard.Add(2 * time.Minute))
+	if len(receiver.StateNearbyMenu.Content) != 2 || receiver.StateNearbyMenu.Content[1].Text != "Sender -90 2m" {
+		t.Fatalf("expected the sender in the nearby menu, got %+v", receiver.StateNearbyMenu.Content)
+	}
+	err = receiver.StateNearbyMenu.Content[1].Action(receiver)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if receiver.State != &receiver.StateNeighbor {
+		t.Fatalf("expected choosing a neighbor to show them, got %q", receiver.State.Title)
+	}
+	if _, err := GetFrame(image.Rect(0, 0, 128, 64), receiver); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	err = receiver.ProcessInputEvent(InputEventAccept)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if receiver.State != &receiver.StateConversationReader || receiver.Conversations[receiver.CurrentConversationIndex].People[1].ID != sender.SelfIdentity.ID {
+		t.Errorf("expected choosing a neighbor to open a conversation with them")
+	}
+}
+
+func TestRSSIHistory(t *testing.T) {
+	n := Neighbor{}
+	n.addRSSI(RSSIUnknown)
+	for i := 0; i < RSSIHistoryLength+5; i++ {
+		n.addRSSI(-100 + i)
+	}
+	if len(n.RSSIHistory) != RSSIHistoryLength || n.RSSIHistory[0] != -95 || n.RSSIHistory[RSSIHistoryLength-1] != -100+RSSIHistoryLength+4 {
+		t.Errorf("expected the newest %d samples to be kept, got %v", RSSIHistoryLength, n.RSSIHistory)
+	}
+}
+
+func TestDrawSparkline(t *testing.T) {
+	img := NewMonoImage(image.Rect(0, 0, 16, 16))
+	white := color.RGBA{255, 255, 255, 255}
+	drawSparkline(img, image.Rect(2, 2, 12, 12), []int{-100, -50, -75}, white)
+	for _, p := range []image.Point{{2, 11}, {6, 2}, {11, 7}} {
+		if !img.on(p.X, p.Y) {
+			t.Errorf("expected the sparkline to pass through %v", p)
+		}
+	}
+	if img.on(0, 0) || img.on(13, 13) {
+		t.Errorf("expected the sparkline to stay inside its rectangle")
+	}
+}
+
+func TestNeighborsUseDeviceClock(t *testing.T) {
+	receiver, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	uncorrected := time.Unix(1000, 0)
+	receiver.Clock = func() time.Time { return uncorrected }
+	receiver.TimeOffset = time.Hour
+	receiver.State = &receiver.StateMainMenu
+	receiver.Settings.Gateway = true
+	sos := SOSToBytes(Person{Name: "Sender", ID: receiver.SelfIdentity.ID + 1}, Position{})
+	if err = receiver.ReceiveFromRadio(sos); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	want := uncorrected.Add(time.Hour)
+	if len(receiver.Neighbors) != 1 || !receiver.Neighbors[0].LastHeard.Equal(want) {
+		t.Errorf("expected the neighbor to be heard at %v, got %+v", want, receiver.Neighbors)
+	}
+	if !receiver.sos.Alert.Received.Equal(want) {
+		t.Errorf("expected the SOS to be received at %v, got %v", want, receiver.sos.Alert.Received)
+	}
+	// Tick is passed the uncorrected time, so the node has only just been heard.
+	if err = receiver.Tick(uncorrected); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if len(receiver.gatewayNodes) != 1 {
+		t.Errorf("expected the gateway node not to have left yet, got %v", receiver.gatewayNodes)
+	}
+	if err = receiver.Tick(uncorrected.Add(GatewayNodeTimeout)); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if len(receiver.gatewayNodes) != 0 {
+		t.Errorf("expected the gateway node to have left after the GatewayNodeTimeout, got %v", receiver.gatewayNodes)
+	}
+}
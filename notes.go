@@ -0,0 +1,168 @@
+package picodoomsdaymessenger
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// notesStorageKey is the key that the Notes are saved with.
+const notesStorageKey = "notes"
+
+// Note is a short piece of text that is kept on the Device and never sent.
+type Note struct {
+	Text    string
+	Created time.Time
+}
+
+// notesState is what the notes screens are showing.
+type notesState struct {
+	// Draft is the text of the Note that is being written.
+	Draft string
+	// Current is the index of the Note that is open.
+	Current int
+	// Scroll is the first line of the open Note that is shown.
+	Scroll int
+}
+
+// AddNote saves a new Note. Empty notes are not kept.
+func (d *Device) AddNote(text string) (err error) {
+	if strings.TrimSpace(text) == "" {
+		return nil
+	}
+	d.Notes = append(d.Notes, Note{Text: text, Created: d.Now()})
+	return d.SaveNotes()
+}
+
+// DeleteNote removes the Note at an index.
+func (d *Device) DeleteNote(index int) (err error) {
+	if index < 0 || index >= len(d.Notes) {
+		return nil
+	}
+	d.Notes = append(d.Notes[:index], d.Notes[index+1:]...)
+	return d.SaveNotes()
+}
+
+// SaveNotes writes the Notes to the Storage of the Device. It does nothing if the Device has no Storage.
+func (d *Device) SaveNotes() (err error) {
+	if d.Storage == nil {
+		return nil
+	}
+	data, err := json.Marshal(d.Notes)
+	if err != nil {
+		return err
+	}
+	return d.Storage.Save(notesStorageKey, data)
+}
+
+// LoadNotes reads the Notes from the Storage of the Device.
+func (d *Device) LoadNotes() (err error) {
+	if d.Storage == nil {
+		return nil
+	}
+	data, err := d.Storage.Load(notesStorageKey)
+	if err == ErrStorageNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	notes := []Note{}
+	err = json.Unmarshal(data, &notes)
+	if err != nil {
+		return err
+	}
+	d.Notes = notes
+	return nil
+}
+
+// noteTitle returns the first line of a Note, short enough to fit in a menu.
+func noteTitle(n Note) string {
+	title := strings.SplitN(n.Text, "\n", 2)[0]
+	title, _ = truncateString(title, 15)
+	return title
+}
+
+// The notes menu is refreshed every time it is shown, so that it is up to date after a Note is added or deleted.
+// This is set here rather than in StateNotesMenu, as the menu refers back to itself through the Notes that it opens.
+func init() {
+	stateSetups = append(stateSetups, func(s *States) {
+		s.StateNotesMenu.LoadAction = func(d *Device) (err error) {
+			d.UpdateNotesMenu()
+			return nil
+		}
+	})
+}
+
+// UpdateNotesMenu lists the Notes, newest first, after an item to write a new one.
+func (d *Device) UpdateNotesMenu() {
+	d.StateNotesMenu.Content = []MenuItem{GlobalMenuItemGoBack, NotesMenuItemNew}
+	d.StateNotesMenu.HighlightedItemIndex = 0
+	for i := len(d.Notes) - 1; i >= 0; i-- {
+		j := i
+		d.StateNotesMenu.Content = append(d.StateNotesMenu.Content, MenuItem{
+			Text: noteTitle(d.Notes[j]),
+			Action: func(d *Device) (err error) {
+				d.notes.Current = j
+				d.notes.Scroll = 0
+				d.StateNoteMenu.Title = noteTitle(d.Notes[j])
+				d.StateNoteMenu.HighlightedItemIndex = 0
+				return d.ChangeStateWithHistory(&d.StateNoteMenu)
+			},
+			CursorIcon: CursorIconRightArrow,
+		})
+	}
+}
+
+// processNoteEditorInputEvent types into the draft with the number keys, deletes with left and saves the Note with accept.
+// Notes are always typed with the multi-tap keyboard, whatever the input method of the conversations is.
+func processNoteEditorInputEvent(d *Device, inputEvent InputEvent) (handled bool, err error) {
+	if d.processTextEntryInputEvent(&d.notes.Draft, inputEvent) {
+		return true, nil
+	}
+	if inputEvent != InputEventAccept {
+		return false, nil
+	}
+	err = d.AddNote(d.finishTextEntry(&d.notes.Draft))
+	if err != nil {
+		return true, err
+	}
+	return true, d.GoBackState()
+}
+
+// drawNoteEditor draws the draft Note with the character that is being typed.
+func drawNoteEditor(d *Device, img Canvas, layout Layout) (err error) {
+	palette := d.Palette()
+	text := d.notes.Draft + d.pendingCharacter() + "_"
+	lines := wrapText(text, layout.Columns)
+	// Keep the end of the draft on the screen.
+	visible := (img.Bounds().Dy() - layout.TitleHeight) / layout.LineHeight
+	scroll := 0
+	if len(lines) > visible {
+		scroll = len(lines) - visible
+	}
+	drawTextPage(img, layout, palette, "New Note", text, scroll)
+	return nil
+}
+
+// processNoteViewerInputEvent scrolls the open Note. Accept goes back to the notes.
+func processNoteViewerInputEvent(d *Device, inputEvent InputEvent) (handled bool, err error) {
+	if inputEvent == InputEventAccept {
+		return true, d.GoBackState()
+	}
+	if d.notes.Current >= len(d.Notes) {
+		return false, nil
+	}
+	return scrollTextPage(&d.notes.Scroll, inputEvent, d.Notes[d.notes.Current].Text, d.Layout()), nil
+}
+
+// drawNoteViewer draws the open Note.
+func drawNoteViewer(d *Device, img Canvas, layout Layout) (err error) {
+	if d.notes.Current >= len(d.Notes) {
+		drawTitleBar(img, layout, d.Palette(), "Note")
+		return nil
+	}
+	note := d.Notes[d.notes.Current]
+	drawTextPage(img, layout, d.Palette(), note.Created.Format("02 Jan 15:04"), note.Text, d.notes.Scroll)
+	return nil
+}
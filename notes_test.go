@@ -0,0 +1,107 @@
+package picodoomsdaymessenger
+
+import (
+	"image"
+	"testing"
+)
+
+func TestWriteNote(t *testing.T) {
+	device, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	storage := MemoryStorage{}
+	device.Storage = storage
+	err = device.ProcessInputEvent(InputEventOpenMainMenu)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	err = MainMenuItemNotes.Action(device)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	err = NotesMenuItemNew.Action(device)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	// Type "h" with 4 twice, start a space with 0, then delete the space with left.
+	for _, inputEvent := range []InputEvent{InputEventNumber4, InputEventNumber4, InputEventNumber0, InputEventLeft} {
+		err = device.ProcessInputEvent(inputEvent)
+		if err != nil {
+			t.Fatalf("The error should be nil but is %v", err)
+		}
+	}
+	if device.State != &device.StateNoteEditor {
+		t.Fatalf("expected to still be writing the note")
+	}
+	if _, err := GetFrame(image.Rect(0, 0, 128, 64), device); err != nil {
+		t.Fatalf("The error drawing the editor should be nil but is %v", err)
+	}
+	err = device.ProcessInputEvent(InputEventAccept)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if device.State != &device.StateNotesMenu {
+		t.Errorf("expected to go back to the notes menu")
+	}
+	if len(device.Notes) != 1 || device.Notes[0].Text != "h" {
+		t.Fatalf("expected a note saying %q, got %+v", "h", device.Notes)
+	}
+	if len(device.StateNotesMenu.Content) != 3 || device.StateNotesMenu.Content[2].Text != "h" {
+		t.Errorf("expected the note to be listed in the menu, got %d items", len(device.StateNotesMenu.Content))
+	}
+
+	restarted, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	restarted.Storage = storage
+	err = restarted.LoadNotes()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if len(restarted.Notes) != 1 || restarted.Notes[0].Text != "h" {
+		t.Errorf("expected the note to be loaded from storage, got %+v", restarted.Notes)
+	}
+}
+
+func TestDeleteNote(t *testing.T) {
+	device, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	device.Notes = []Note{{Text: "first"}, {Text: "second"}}
+	err = device.ChangeStateWithHistory(&device.StateNotesMenu)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	// The newest note is listed first.
+	err = device.StateNotesMenu.Content[2].Action(device)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if device.StateNoteMenu.Title != "second" {
+		t.Errorf("expected the newest note to be opened, got %q", device.StateNoteMenu.Title)
+	}
+	err = NoteMenuItemView.Action(device)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if _, err := GetFrame(image.Rect(0, 0, 128, 64), device); err != nil {
+		t.Fatalf("The error drawing the note should be nil but is %v", err)
+	}
+	err = device.ProcessInputEvent(InputEventAccept)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	err = NoteMenuItemDelete.Action(device)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if len(device.Notes) != 1 || device.Notes[0].Text != "first" {
+		t.Errorf("expected only the first note to be left, got %+v", device.Notes)
+	}
+	if device.State != &device.StateNotesMenu || len(device.StateNotesMenu.Content) != 3 {
+		t.Errorf("expected to go back to the refreshed notes menu")
+	}
+}
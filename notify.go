@@ -0,0 +1,96 @@
+package picodoomsdaymessenger
+
+import (
+	"image/color"
+	"time"
+)
+
+// DeviceEvent is something that happened on the Device that the user should be told about.
+type DeviceEvent string
+
+const (
+	// DeviceEventMessageReceived happens when a message is received from the radio.
+	DeviceEventMessageReceived DeviceEvent = "message received"
+	// DeviceEventSendFailed happens when a packet could not be sent by the radio.
+	DeviceEventSendFailed DeviceEvent = "send failed"
+	// DeviceEventLowBattery happens when the battery is running low. It is raised by SetBatteryPercent.
+	DeviceEventLowBattery DeviceEvent = "low battery"
+	// DeviceEventSOSReceived happens when a SOS is received from another device.
+	DeviceEventSOSReceived DeviceEvent = "sos received"
+)
+
+// newFlashLEDAnimation creates an LED animation that flashes all the LEDs in a color a number of times.
+func newFlashLEDAnimation(c color.RGBA, flashes int, frameDuration time.Duration) *LEDAnimation {
+	on := [6]color.RGBA{c, c, c, c, c, c}
+	off := [6]color.RGBA{}
+	frames := [][6]color.RGBA{}
+	for i := 0; i < flashes; i++ {
+		frames = append(frames, on, off)
+	}
+	return &LEDAnimation{
+		FrameDuration: frameDuration,
+		CurrentFrame:  0,
+		Frames:        frames,
+	}
+}
+
+// LEDNotifications maps each DeviceEvent to the short LED animation that is played once when it happens.
+// Events that are not in the map do not show anything on the LEDs.
+var LEDNotifications = map[DeviceEvent]*LEDAnimation{
+	DeviceEventMessageReceived: newFlashLEDAnimation(color.RGBA{0, 0, 255, 0}, 2, 150*time.Millisecond),
+	DeviceEventSendFailed:      newFlashLEDAnimation(color.RGBA{255, 0, 0, 0}, 3, 100*time.Millisecond),
+	DeviceEventLowBattery:      newFlashLEDAnimation(color.RGBA{255, 100, 0, 0}, 1, 500*time.Millisecond),
+	DeviceEventSOSReceived:     newFlashLEDAnimation(color.RGBA{255, 0, 0, 0}, 10, 100*time.Millisecond),
+}
+
+// ledNotification holds the one-shot LED animation that is playing, and the animation to go back to when it has finished.
+type ledNotification struct {
+	animation *LEDAnimation
+	previous  *LEDAnimation
+	ends      time.Time
+}
+
+// Notify plays the LED animation of a DeviceEvent once, then goes back to the LEDAnimation that was playing before.
+// If another notification is already playing, it is replaced, but the Device still goes back to the animation from before both of them.
+// The OnDeviceEvent hook is called first, so that the firmware can react in other ways too. Its ToneNotifications are played if AlertTones are on.
+// The notification is timed with the Clock of the Device, the same clock that the main loop passes to Tick.
+func (d *Device) Notify(event DeviceEvent) (err error) {
+	d.MarkDirty()
+	if d.OnDeviceEvent != nil {
+		d.OnDeviceEvent(event)
+	}
+	if tones, ok := ToneNotifications[event]; ok && d.Settings.AlertTones {
+		err = d.PlayTones(tones)
+		if err != nil {
+			return err
+		}
+	}
+	animation, ok := LEDNotifications[event]
+	if !ok {
+		return nil
+	}
+	previous := d.LEDAnimation
+	if d.notification.animation != nil && d.LEDAnimation == d.notification.animation {
+		previous = d.notification.previous
+	}
+	d.notification = ledNotification{
+		animation: animation,
+		previous:  previous,
+		ends:      d.clock().Add(animation.FrameDuration * time.Duration(len(animation.Frames))),
+	}
+	return d.ChangeLEDAnimationWithoutContinue(animation)
+}
+
+// tickNotification goes back to the previous LEDAnimation once a notification has finished playing.
+// If the LEDAnimation has been changed while the notification was playing, it is left alone.
+func (d *Device) tickNotification(now time.Time) (err error) {
+	if d.notification.animation == nil || now.Before(d.notification.ends) {
+		return nil
+	}
+	notification := d.notification
+	d.notification = ledNotification{}
+	if d.LEDAnimation != notification.animation {
+		return nil
+	}
+	return d.ChangeLEDAnimationWithContinue(notification.previous)
+}
@@ -0,0 +1,159 @@
+package picodoomsdaymessenger
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestNotifyRestoresAnimation(t *testing.T) {
+	// Create a new Machine
+	device, err := NewDevice()
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	device.ChangeLEDAnimationWithoutContinue(&LEDAnimationSOS)
+
+	err = device.Notify(DeviceEventMessageReceived)
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	if device.LEDAnimation != LEDNotifications[DeviceEventMessageReceived] {
+		t.Errorf("The message received notification should be playing")
+	}
+	// A second notification should still go back to the first animation.
+	err = device.Notify(DeviceEventSendFailed)
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	if device.LEDAnimation != LEDNotifications[DeviceEventSendFailed] {
+		t.Errorf("The send failed notification should be playing")
+	}
+
+	err = device.Tick(time.Now())
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	if device.LEDAnimation != LEDNotifications[DeviceEventSendFailed] {
+		t.Errorf("The send failed notification should still be playing")
+	}
+	err = device.Tick(time.Now().Add(time.Minute))
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	if device.LEDAnimation != &LEDAnimationSOS {
+		t.Errorf("The SOS animation should be playing again")
+	}
+}
+
+func TestNotifyUsesDeviceClock(t *testing.T) {
+	// Create a new Machine
+	device, err := NewDevice()
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	clock := time.Unix(1000, 0)
+	device.Clock = func() time.Time {
+		return clock
+	}
+	// Correcting the time shown to the user does not change when the notification ends.
+	device.SetTime(time.Unix(2000000000, 0), SourceSerial)
+	device.ChangeLEDAnimationWithoutContinue(&LEDAnimationSOS)
+	err = device.Notify(DeviceEventMessageReceived)
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	animation := LEDNotifications[DeviceEventMessageReceived]
+	length := animation.FrameDuration * time.Duration(len(animation.Frames))
+
+	err = device.Tick(clock.Add(length - time.Millisecond))
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	if device.LEDAnimation != animation {
+		t.Errorf("The message received notification should still be playing")
+	}
+	err = device.Tick(clock.Add(length))
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	if device.LEDAnimation != &LEDAnimationSOS {
+		t.Errorf("The SOS animation should be playing again")
+	}
+}
+
+func TestNotifyErrorIsReturned(t *testing.T) {
+	// Create a new Machine
+	device, err := NewDevice()
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	sendErr := errors.New("radio is broken")
+	toneErr := errors.New("buzzer is broken")
+	device.SendUsingRadio = func(packet []byte) (err error) {
+		return sendErr
+	}
+	device.PlayTone = func(frequencyHz int, duration time.Duration) (err error) {
+		return toneErr
+	}
+	device.Settings.AlertTones = true
+	err = device.SendPacket(nil, []byte("test"))
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	err = sendQueued(device)
+	if !errors.Is(err, sendErr) || !errors.Is(err, toneErr) {
+		t.Errorf("The error should be both %v and %v but is %v", sendErr, toneErr, err)
+	}
+}
+
+func TestNotifyKeepsNewAnimation(t *testing.T) {
+	// Create a new Machine
+	device, err := NewDevice()
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	device.Notify(DeviceEventLowBattery)
+	// Changing the animation during a notification should not be undone when it finishes.
+	device.ChangeLEDAnimationWithoutContinue(&LEDAnimationSOS)
+	device.Tick(time.Now().Add(time.Minute))
+	if device.LEDAnimation != &LEDAnimationSOS {
+		t.Errorf("The SOS animation should still be playing")
+	}
+}
+
+func TestNotifyOnEvents(t *testing.T) {
+	// Create a new Machine
+	device, err := NewDevice()
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	sendErr := errors.New("radio is broken")
+	device.SendUsingRadio = func(packet []byte) (err error) {
+		return sendErr
+	}
+	err = device.SendPacket(nil, []byte("test"))
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	err = sendQueued(device)
+	if err != sendErr {
+		t.Errorf("The error should be %v but is %v", sendErr, err)
+	}
+	if device.LEDAnimation != LEDNotifications[DeviceEventSendFailed] {
+		t.Errorf("The send failed notification should be playing")
+	}
+
+	other := Person{Name: "Other", ID: 5678}
+	packet, err := device.EncodeMessage(Message{Text: "hello", Person: other})
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	err = device.ReceiveFromRadio(packet)
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	if device.LEDAnimation != LEDNotifications[DeviceEventMessageReceived] {
+		t.Errorf("The message received notification should be playing")
+	}
+}
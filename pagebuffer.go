@@ -0,0 +1,166 @@
+package picodoomsdaymessenger
+
+import (
+	"image"
+	"image/color"
+)
+
+// PageHeight is the number of pixel rows in one page of a PageBuffer.
+const PageHeight = 8
+
+// Pixel is a single pixel to be written to a PageBuffer.
+type Pixel struct {
+	X  int
+	Y  int
+	On bool
+}
+
+// PageBuffer is a monochrome frame buffer laid out like the memory of an SSD1306 display.
+// The screen is split into pages of 8 pixel rows, and each byte holds one column of a page with the top pixel in the lowest bit.
+// The PageBuffer keeps track of which pages have changed since they were last sent to the screen, so that only those need to be sent again.
+type PageBuffer struct {
+	// Width is the width of the screen in pixels.
+	Width int
+	// Height is the height of the screen in pixels.
+	Height int
+	// Buffer holds the pixels of every page, one after another.
+	Buffer []byte
+	// PartialRefresh makes ChangedPages return only the pages that have changed. If it is false, every page is always sent.
+	PartialRefresh bool
+	// dirty is true for every page that has changed since it was last sent to the screen.
+	dirty []bool
+}
+
+// NewPageBuffer creates a PageBuffer for a screen of a size. Every page starts dirty, so that the whole screen is sent the first time.
+func NewPageBuffer(width int, height int) *PageBuffer {
+	pages := (height + PageHeight - 1) / PageHeight
+	p := &PageBuffer{
+		Width:          width,
+		Height:         height,
+		Buffer:         make([]byte, width*pages),
+		PartialRefresh: true,
+		dirty:          make([]bool, pages),
+	}
+	for i := range p.dirty {
+		p.dirty[i] = true
+	}
+	return p
+}
+
+// Pages returns the number of pages in the PageBuffer.
+func (p *PageBuffer) Pages() int {
+	return len(p.dirty)
+}
+
+// SetPixel turns a pixel on or off. Pixels outside of the screen are ignored.
+func (p *PageBuffer) SetPixel(x int, y int, on bool) {
+	if x < 0 || x >= p.Width || y < 0 || y >= p.Height {
+		return
+	}
+	page := y / PageHeight
+	index := x + page*p.Width
+	old := p.Buffer[index]
+	if on {
+		p.Buffer[index] |= 1 << uint(y%PageHeight)
+	} else {
+		p.Buffer[index] &^= 1 << uint(y%PageHeight)
+	}
+	if p.Buffer[index] != old {
+		p.dirty[page] = true
+	}
+}
+
+// GetPixel returns true if a pixel is on. Pixels outside of the screen are off.
+func (p *PageBuffer) GetPixel(x int, y int) bool {
+	if x < 0 || x >= p.Width || y < 0 || y >= p.Height {
+		return false
+	}
+	return p.Buffer[x+(y/PageHeight)*p.Width]>>uint(y%PageHeight)&1 == 1
+}
+
+// SetPixels writes a batch of pixels at once.
+func (p *PageBuffer) SetPixels(pixels []Pixel) {
+	for _, pixel := range pixels {
+		p.SetPixel(pixel.X, pixel.Y, pixel.On)
+	}
+}
+
+// DrawImage writes a whole image to the PageBuffer. Any pixel that is not black is on.
+func (p *PageBuffer) DrawImage(img image.Image) {
+	p.DrawImageRect(img, img.Bounds())
+}
+
+// DrawImageRect writes part of an image to the PageBuffer, such as a rectangle reported by a DirtyTracker. Any pixel that is not black is on.
+func (p *PageBuffer) DrawImageRect(img image.Image, r image.Rectangle) {
+	bounds := img.Bounds()
+	r = r.Intersect(bounds)
+	for y := r.Min.Y; y < r.Max.Y && y-bounds.Min.Y < p.Height; y++ {
+		for x := r.Min.X; x < r.Max.X && x-bounds.Min.X < p.Width; x++ {
+			c := color.GrayModel.Convert(img.At(x, y)).(color.Gray)
+			p.SetPixel(x-bounds.Min.X, y-bounds.Min.Y, c.Y != 0)
+		}
+	}
+}
+
+// ChangedPages returns the index of every page that needs to be sent to the screen.
+func (p *PageBuffer) ChangedPages() (pages []int) {
+	for i, dirty := range p.dirty {
+		if dirty || !p.PartialRefresh {
+			pages = append(pages, i)
+		}
+	}
+	return pages
+}
+
+// Page returns the bytes of a page, ready to be sent to the screen.
+func (p *PageBuffer) Page(page int) []byte {
+	return p.Buffer[page*p.Width : (page+1)*p.Width]
+}
+
+// MarkSent records that a page has been sent to the screen.
+func (p *PageBuffer) MarkSent(page int) {
+	p.dirty[page] = false
+}
+
+// MarkAllDirty makes every page be sent to the screen next time, for example after the screen has been cleared by something else.
+func (p *PageBuffer) MarkAllDirty() {
+	for i := range p.dirty {
+		p.dirty[i] = true
+	}
+}
+
+// DrawMonoImage copies a MonoImage into the PageBuffer a page at a time, without converting each pixel.
+// It returns the columns of each page that changed, in the same way as a DirtyTracker with a BandHeight of PageHeight.
+func (p *PageBuffer) DrawMonoImage(m *MonoImage) (rects []image.Rectangle) {
+	if m.Rect.Dx() != p.Width || m.Pages() != p.Pages() {
+		// The pages do not line up, so the pixels are copied one at a time.
+		p.DrawImage(m)
+		for _, page := range p.ChangedPages() {
+			rects = append(rects, image.Rect(0, page*PageHeight, p.Width, (page+1)*PageHeight))
+		}
+		return rects
+	}
+	for page := 0; page < p.Pages(); page++ {
+		src, dst := m.Page(page), p.Page(page)
+		first, last := -1, -1
+		for x := range src {
+			if src[x] != dst[x] {
+				if first < 0 {
+					first = x
+				}
+				last = x
+			}
+		}
+		if first < 0 && !p.dirty[page] {
+			continue
+		}
+		if first < 0 || p.dirty[page] {
+			// A page that has not been sent yet is sent whole.
+			first, last = 0, p.Width-1
+		}
+		copy(dst, src)
+		p.dirty[page] = true
+		rects = append(rects, image.Rect(first, page*PageHeight, last+1, (page+1)*PageHeight))
+	}
+	return rects
+}
@@ -0,0 +1,56 @@
+package picodoomsdaymessenger
+
+import (
+	"image"
+	"image/color"
+	"reflect"
+	"testing"
+)
+
+func TestPageBufferChangedPages(t *testing.T) {
+	screen := NewPageBuffer(128, 64)
+	if len(screen.ChangedPages()) != 8 {
+		t.Errorf("Every page should be sent the first time but only %v are", screen.ChangedPages())
+	}
+	for _, page := range screen.ChangedPages() {
+		screen.MarkSent(page)
+	}
+	if len(screen.ChangedPages()) != 0 {
+		t.Errorf("No pages should have changed but %v have", screen.ChangedPages())
+	}
+
+	// Turning off a pixel that is already off should not change anything.
+	screen.SetPixel(5, 5, false)
+	if len(screen.ChangedPages()) != 0 {
+		t.Errorf("No pages should have changed but %v have", screen.ChangedPages())
+	}
+
+	screen.SetPixels([]Pixel{{X: 5, Y: 5, On: true}, {X: 127, Y: 63, On: true}, {X: 200, Y: 200, On: true}})
+	if !reflect.DeepEqual(screen.ChangedPages(), []int{0, 7}) {
+		t.Errorf("Pages 0 and 7 should have changed but %v have", screen.ChangedPages())
+	}
+	if !screen.GetPixel(5, 5) || screen.Page(0)[5] != 1<<5 {
+		t.Errorf("The pixel at 5, 5 should be on")
+	}
+
+	screen.PartialRefresh = false
+	if len(screen.ChangedPages()) != 8 {
+		t.Errorf("Every page should be sent without partial refresh but only %v are", screen.ChangedPages())
+	}
+}
+
+func TestPageBufferDrawImage(t *testing.T) {
+	screen := NewPageBuffer(128, 64)
+	for _, page := range screen.ChangedPages() {
+		screen.MarkSent(page)
+	}
+	img := image.NewRGBA(image.Rect(0, 0, 128, 64))
+	img.Set(10, 20, color.RGBA{255, 255, 255, 255})
+	screen.DrawImage(img)
+	if !reflect.DeepEqual(screen.ChangedPages(), []int{2}) {
+		t.Errorf("Only page 2 should have changed but %v have", screen.ChangedPages())
+	}
+	if !screen.GetPixel(10, 20) || screen.GetPixel(10, 21) {
+		t.Errorf("Only the pixel at 10, 20 should be on")
+	}
+}
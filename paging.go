@@ -0,0 +1,67 @@
+package picodoomsdaymessenger
+
+// VisibleMessages returns how many whole messages of a conversation fit on the screen at once, between the title, or the top of a Compact screen, and the MessageBaseline.
+func (l Layout) VisibleMessages() int {
+	top := l.TitleHeight
+	if l.Compact {
+		top = 0
+	}
+	visible := (l.MessageBaseline-top-l.TextHeight)/l.LineHeight + 1
+	if visible < 1 {
+		visible = 1
+	}
+	return visible
+}
+
+// messageWindow returns the first and last index of the messages of a Conversation that can be seen around its highlighted message, so that no other message has to be laid out.
+// The messages either side of the whole messages are included, as their edges can show past the title and compose bar.
+func messageWindow(c *Conversation, layout Layout) (first, last int) {
+	first = c.HighlightedMessageIndex - layout.VisibleMessages()
+	if first < 0 {
+		first = 0
+	}
+	last = c.HighlightedMessageIndex + 1
+	if last > len(c.Messages)-1 {
+		last = len(c.Messages) - 1
+	}
+	return first, last
+}
+
+// PageConversation moves the highlighted message of a Conversation by a number of screens full of messages. Negative pages move back to older messages.
+// Unlike moving one message at a time, it stops at the first and last message instead of wrapping around.
+func (d *Device) PageConversation(c *Conversation, pages int) {
+	c.HighlightedMessageIndex += pages * d.Layout().VisibleMessages()
+	if c.HighlightedMessageIndex > len(c.Messages)-1 {
+		c.HighlightedMessageIndex = len(c.Messages) - 1
+	}
+	if c.HighlightedMessageIndex < 0 {
+		c.HighlightedMessageIndex = 0
+	}
+}
+
+// VisibleItems returns how many items of a menu fit on the screen at once below the title. Items are taller when the menu has twoLine items.
+func (l Layout) VisibleItems(twoLine bool) int {
+	itemHeight := l.LineHeight
+	if twoLine {
+		itemHeight += FaceSmall.Height + 1
+	}
+	// The highlighted item is in the middle of the space below the title, so the space is twice the distance to it.
+	visible := 2 * (l.HighlightBaseline - l.TitleHeight - l.Face.Ascent/2 + 2) / itemHeight
+	if visible < 1 {
+		visible = 1
+	}
+	return visible
+}
+
+// PageMenu moves the highlighted item of the current State by a number of screens full of items. Negative pages move up.
+// Like PageConversation, it stops at the first and last item instead of wrapping around.
+func (d *Device) PageMenu(pages int) {
+	s := d.State
+	s.HighlightedItemIndex += pages * d.Layout().VisibleItems(s.TwoLineItems)
+	if s.HighlightedItemIndex > len(s.Content)-1 {
+		s.HighlightedItemIndex = len(s.Content) - 1
+	}
+	if s.HighlightedItemIndex < 0 {
+		s.HighlightedItemIndex = 0
+	}
+}
@@ -0,0 +1,65 @@
+package picodoomsdaymessenger
+
+import (
+	"image"
+	"strconv"
+	"testing"
+)
+
+func TestVisibleMessages(t *testing.T) {
+	if visible := NewLayout(image.Rect(0, 0, 128, 64)).VisibleMessages(); visible != 2 {
+		t.Errorf("2 messages should fit on a 128x64 screen, but %d do", visible)
+	}
+	if visible := NewLayout(image.Rect(0, 0, 256, 128)).VisibleMessages(); visible <= 2 {
+		t.Errorf("More messages should fit on a taller screen, but %d do", visible)
+	}
+}
+
+func TestPageConversation(t *testing.T) {
+	// Create a new Machine
+	device, err := NewDevice()
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	c := device.NewConversation(device.SelfIdentity)
+	for i := 0; i < 500; i++ {
+		c.Messages = append(c.Messages, Message{Person: device.SelfIdentity, Text: strconv.Itoa(i)})
+	}
+	c.HighlightedMessageIndex = len(c.Messages) - 1
+	device.CurrentConversationIndex = len(device.Conversations) - 1
+	device.State = &device.StateConversationReader
+	visible := device.Layout().VisibleMessages()
+
+	err = device.ProcessInputEvent(InputEventLeft)
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	if c.HighlightedMessageIndex != 499-visible {
+		t.Errorf("Left should go back a page to message %d, but is at %d", 499-visible, c.HighlightedMessageIndex)
+	}
+	err = device.ProcessInputEvent(InputEventRight)
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	err = device.ProcessInputEvent(InputEventRight)
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	if c.HighlightedMessageIndex != 499 {
+		t.Errorf("Paging should stop at the newest message, but is at %d", c.HighlightedMessageIndex)
+	}
+	device.PageConversation(c, -1000)
+	if c.HighlightedMessageIndex != 0 {
+		t.Errorf("Paging should stop at the oldest message, but is at %d", c.HighlightedMessageIndex)
+	}
+
+	c.HighlightedMessageIndex = 250
+	first, last := messageWindow(c, device.Layout())
+	if first != 250-visible || last != 251 {
+		t.Errorf("Only the messages around the highlighted message should be laid out, but %d to %d are", first, last)
+	}
+	_, err = GetFrame(DisplaySSD1306.Bounds(), device)
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+}
@@ -0,0 +1,358 @@
+package picodoomsdaymessenger
+
+import (
+	"bytes"
+	"crypto/ecdh"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// ErrInvalidPairing is returned when a pairing packet cannot be decoded.
+var ErrInvalidPairing = errors.New("invalid pairing packet")
+
+// ErrNoKeys is returned when pairing is started on a Device that has no signing keys.
+var ErrNoKeys = errors.New("device has no signing keys")
+
+// pairPrefix is the start of every pairing packet, ASCII for "pair".
+var pairPrefix = []byte{0x70, 0x61, 0x69, 0x72}
+
+// pairingNonceSize is the length of the random challenge in a pairing packet.
+const pairingNonceSize = 8
+
+// pairingCommitmentSize is the length of the hash of the challenge in a pairing packet.
+const pairingCommitmentSize = sha256.Size
+
+// contactsStorageKey is the key that the Contacts are saved with.
+const contactsStorageKey = "contacts"
+
+// PairingInterval is the time between pairing packets while no other device has been heard.
+var PairingInterval = 2 * time.Second
+
+// Contact is a Person whose public key was confirmed in person by pairing.
+type Contact struct {
+	Person    Person
+	PublicKey []byte
+	// SessionKey is the key that was agreed with the Contact while pairing, which messages to and from them are encrypted with. Contacts paired before session keys were added do not have one.
+	SessionKey []byte `json:",omitempty"`
+}
+
+// PairingRequest is a pairing packet. Every pairing has a new random Nonce, so an old pairing packet cannot be replayed.
+// The Nonce is kept secret until the other device has been heard, and only its Commitment is sent before then. Neither device can choose its Nonce after hearing the other's, so a device in the middle cannot keep trying until the PairingCodes match.
+// ExchangeKey is a new X25519 public key for every pairing, that the SessionKey of the Contact is agreed with.
+type PairingRequest struct {
+	Person      Person
+	PublicKey   ed25519.PublicKey
+	ExchangeKey []byte
+	Commitment  []byte
+	// Nonce is empty until it is revealed.
+	Nonce []byte
+}
+
+// pairingState is the state of the pairing screen.
+type pairingState struct {
+	own PairingRequest
+	// nonce is the Nonce of own, which is only put in own once the Peer has been heard.
+	nonce    []byte
+	exchange *ecdh.PrivateKey
+	// Peer is the first device that was heard. Packets from any other device are ignored until pairing is started again.
+	Peer     *PairingRequest
+	lastSent time.Time
+}
+
+// PairingCommitment is the hash of a Nonce that is sent before the Nonce itself. It also covers the keys of the PairingRequest, so it cannot be copied into another device's packet.
+func PairingCommitment(r PairingRequest, nonce []byte) []byte {
+	hash := sha256.New()
+	hash.Write(r.PublicKey)
+	hash.Write(r.ExchangeKey)
+	hash.Write(nonce)
+	return hash.Sum(nil)
+}
+
+// samePairing returns true if two pairing packets were sent by the same device during the same pairing, whether or not they reveal its Nonce.
+func samePairing(a, b PairingRequest) bool {
+	return a.Person == b.Person && bytes.Equal(a.PublicKey, b.PublicKey) && bytes.Equal(a.ExchangeKey, b.ExchangeKey) && bytes.Equal(a.Commitment, b.Commitment)
+}
+
+// PairingRequestToBytes creates a pairing packet.
+func PairingRequestToBytes(r PairingRequest) (output []byte) {
+	output = append(output, pairPrefix...)
+	output = append(output, r.PublicKey...)
+	output = append(output, r.ExchangeKey...)
+	output = append(output, r.Commitment...)
+	output = append(output, byte(len(r.Nonce)))
+	output = append(output, r.Nonce...)
+	output = append(output, []byte(fmt.Sprint(r.Person.ID))...)
+	output = append(output, 0xcc)
+	output = append(output, []byte(r.Person.Name)...)
+	return output
+}
+
+// BytesToPairingRequest decodes a pairing packet. A packet that reveals a Nonce which does not match its Commitment is invalid.
+func BytesToPairingRequest(input []byte) (output PairingRequest, err error) {
+	if !bytes.HasPrefix(input, pairPrefix) {
+		return output, ErrInvalidPairing
+	}
+	input = input[len(pairPrefix):]
+	if len(input) < ed25519.PublicKeySize+exchangeKeySize+pairingCommitmentSize+1 {
+		return output, ErrInvalidPairing
+	}
+	output.PublicKey = append(ed25519.PublicKey{}, input[:ed25519.PublicKeySize]...)
+	input = input[ed25519.PublicKeySize:]
+	output.ExchangeKey = append([]byte{}, input[:exchangeKeySize]...)
+	input = input[exchangeKeySize:]
+	output.Commitment = append([]byte{}, input[:pairingCommitmentSize]...)
+	input = input[pairingCommitmentSize:]
+	nonceLength := int(input[0])
+	input = input[1:]
+	if (nonceLength != 0 && nonceLength != pairingNonceSize) || len(input) < nonceLength {
+		return output, ErrInvalidPairing
+	}
+	if nonceLength > 0 {
+		output.Nonce = append([]byte{}, input[:nonceLength]...)
+		if !bytes.Equal(PairingCommitment(output, output.Nonce), output.Commitment) {
+			return output, ErrInvalidPairing
+		}
+	}
+	fields := bytes.SplitN(input[nonceLength:], []byte{0xcc}, 2)
+	if len(fields) != 2 {
+		return output, ErrInvalidPairing
+	}
+	output.Person.ID, err = strconv.Atoi(string(fields[0]))
+	if err != nil {
+		return output, ErrInvalidPairing
+	}
+	output.Person.Name, _ = truncateString(string(fields[1]), MaxNameLength)
+	return output, nil
+}
+
+// PairingCode is the confirmation code that is shown on both devices while pairing.
+// It is made from both pairing packets once their Nonces have been revealed, so the codes only match if neither packet was changed or replaced on the way.
+func PairingCode(a, b PairingRequest) string {
+	first, second := PairingRequestToBytes(a), PairingRequestToBytes(b)
+	if bytes.Compare(first, second) > 0 {
+		first, second = second, first
+	}
+	sum := sha256.Sum256(append(first, second...))
+	return fmt.Sprintf("%06d", binary.BigEndian.Uint32(sum[:4])%1000000)
+}
+
+// StartPairing forgets any other device that was heard, and broadcasts a new pairing packet with the Commitment to a new Nonce.
+func (d *Device) StartPairing(now time.Time) (err error) {
+	if d.keys.private == nil {
+		return ErrNoKeys
+	}
+	nonce := make([]byte, pairingNonceSize)
+	_, err = rand.Read(nonce)
+	if err != nil {
+		return err
+	}
+	exchange, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return err
+	}
+	own := PairingRequest{Person: d.SelfIdentity, PublicKey: d.PublicKey(), ExchangeKey: exchange.PublicKey().Bytes()}
+	own.Commitment = PairingCommitment(own, nonce)
+	d.pairing = pairingState{
+		own:      own,
+		nonce:    nonce,
+		exchange: exchange,
+	}
+	return d.sendPairingRequest(now)
+}
+
+// sendPairingRequest broadcasts the pairing packet of the Device.
+func (d *Device) sendPairingRequest(now time.Time) (err error) {
+	d.pairing.lastSent = now
+	return d.SendPacket(nil, PairingRequestToBytes(d.pairing.own))
+}
+
+// tickPairing repeats the pairing packet every PairingInterval until another device is heard and has revealed its Nonce.
+// The time of the last packet is also set when one is received, with the corrected clock of the Device, so the TimeOffset is added to the time from Tick.
+func (d *Device) tickPairing(now time.Time) (err error) {
+	clock := now.Add(d.TimeOffset)
+	if d.pairingReady() || clock.Sub(d.pairing.lastSent) < PairingInterval {
+		return nil
+	}
+	return d.sendPairingRequest(clock)
+}
+
+// pairingReady returns true once the other device that is pairing has been heard and has revealed its Nonce, so that the PairingCode can be shown.
+func (d *Device) pairingReady() bool {
+	return d.pairing.Peer != nil && len(d.pairing.Peer.Nonce) > 0
+}
+
+// receivePairingRequest remembers the first other device that is pairing, and answers it with the Nonce of this Device so that it hears this Device too.
+// Pairing packets are ignored unless the pairing screen is open, and once a device has been heard, packets from any other device are ignored so that it cannot be swapped for the one whose code is shown.
+func (d *Device) receivePairingRequest(r PairingRequest, now time.Time) (err error) {
+	if d.State != &d.StatePairing || r.Person.ID == d.SelfIdentity.ID {
+		return nil
+	}
+	peer := d.pairing.Peer
+	if peer == nil {
+		// The Nonce of this Device is only revealed once the Commitment of the other device has been heard.
+		d.pairing.Peer = &r
+		d.pairing.own.Nonce = d.pairing.nonce
+		return d.sendPairingRequest(now)
+	}
+	if !samePairing(*peer, r) {
+		return nil
+	}
+	if len(peer.Nonce) == 0 {
+		peer.Nonce = r.Nonce
+	}
+	// The other device only repeats its packet if it has not heard this Device, so the answer is repeated too, but no faster than the PairingInterval.
+	if now.Sub(d.pairing.lastSent) < PairingInterval {
+		return nil
+	}
+	return d.sendPairingRequest(now)
+}
+
+// ConfirmPairing adds the device that is pairing to the Contacts, and trusts its key from then on. The SessionKey of the Contact is agreed at the same time.
+// It does nothing until the PairingCode can be shown.
+func (d *Device) ConfirmPairing() (err error) {
+	if !d.pairingReady() {
+		return nil
+	}
+	peer := d.pairing.Peer
+	sessionKey, err := d.pairing.sessionKey()
+	if err != nil {
+		return err
+	}
+	contact := Contact{Person: peer.Person, PublicKey: peer.PublicKey, SessionKey: sessionKey}
+	replaced := false
+	for i := range d.Contacts {
+		if d.Contacts[i].Person.ID == contact.Person.ID {
+			d.Contacts[i] = contact
+			replaced = true
+		}
+	}
+	if !replaced {
+		d.Contacts = append(d.Contacts, contact)
+	}
+	if d.keys.pinned == nil {
+		d.keys.pinned = make(map[int]ed25519.PublicKey)
+	}
+	d.keys.pinned[contact.Person.ID] = peer.PublicKey
+	d.pairing = pairingState{}
+	err = d.SaveKeys()
+	if err != nil {
+		return err
+	}
+	return d.SaveContacts()
+}
+
+// SaveContacts writes the Contacts to the Storage of the Device. It does nothing if the Device has no Storage.
+func (d *Device) SaveContacts() (err error) {
+	if d.Storage == nil {
+		return nil
+	}
+	data, err := json.Marshal(d.Contacts)
+	if err != nil {
+		return err
+	}
+	return d.Storage.Save(contactsStorageKey, data)
+}
+
+// LoadContacts reads the Contacts from the Storage of the Device.
+func (d *Device) LoadContacts() (err error) {
+	if d.Storage == nil {
+		return nil
+	}
+	data, err := d.Storage.Load(contactsStorageKey)
+	if err == ErrStorageNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	contacts := []Contact{}
+	err = json.Unmarshal(data, &contacts)
+	if err != nil {
+		return err
+	}
+	d.Contacts = contacts
+	return nil
+}
+
+// The People menu refers back to itself through the pairing screen, so it is filled when it is shown.
+func init() {
+	stateSetups = append(stateSetups, func(s *States) {
+		s.StatePeopleMenu.LoadAction = func(d *Device) (err error) {
+			d.UpdatePeopleMenu()
+			return nil
+		}
+	})
+}
+
+// UpdatePeopleMenu fills the StatePeopleMenu with the Contacts, and the Contacts shared by other devices if there are any. Choosing a Contact starts a Conversation with them.
+func (d *Device) UpdatePeopleMenu() {
+	d.StatePeopleMenu.Content = []MenuItem{GlobalMenuItemGoBack, PeopleMenuItemPair, PeopleMenuItemNearby}
+	if len(d.contactOffers.queue) > 0 {
+		d.StatePeopleMenu.Content = append(d.StatePeopleMenu.Content, PeopleMenuItemShared)
+	}
+	for _, contact := range d.Contacts {
+		person := contact.Person
+		d.StatePeopleMenu.Content = append(d.StatePeopleMenu.Content, MenuItem{
+			Text: person.Name,
+			Action: func(d *Device) (err error) {
+				return d.openConversationWith(person)
+			},
+			CursorIcon: CursorIconRightArrow,
+		})
+	}
+	// Contacts can only be shared once there are some.
+	if len(d.Contacts) > 0 {
+		d.StatePeopleMenu.Content = append(d.StatePeopleMenu.Content, PeopleMenuItemShare)
+	}
+	if d.StatePeopleMenu.HighlightedItemIndex >= len(d.StatePeopleMenu.Content) {
+		d.StatePeopleMenu.HighlightedItemIndex = 0
+	}
+}
+
+// openConversationWith reads the Conversation with a Person, and starts one if there is none.
+func (d *Device) openConversationWith(person Person) (err error) {
+	index := d.ConversationWith(person.ID)
+	if index < 0 {
+		d.NewConversation(person).Name = person.Name
+		index = len(d.Conversations) - 1
+		d.UpdateConversationsMenu()
+	}
+	return d.OpenConversation(index)
+}
+
+// processPairingInputEvent confirms the pairing with accept once the other device has been heard, or cancels it if nothing has been heard.
+func processPairingInputEvent(d *Device, inputEvent InputEvent) (handled bool, err error) {
+	switch inputEvent {
+	case InputEventAccept:
+		err = d.ConfirmPairing()
+		if err != nil {
+			return true, err
+		}
+		return true, d.GoBackState()
+	case InputEventLeft, InputEventRight:
+		return true, d.StartPairing(d.Now())
+	case InputEventUp, InputEventDown:
+		return true, nil
+	}
+	return false, nil
+}
+
+// drawPairing draws the confirmation code, which should be checked against the code on the other device before accepting.
+func drawPairing(d *Device, img Canvas, layout Layout) (err error) {
+	peer := d.pairing.Peer
+	text := "Hold devices close\nWaiting..."
+	if d.pairingReady() {
+		text = fmt.Sprintf("%s\nCode %s\nOK if codes match", peer.Person.Name, PairingCode(d.pairing.own, *peer))
+	} else if peer != nil {
+		text = fmt.Sprintf("%s\nWaiting...", peer.Person.Name)
+	}
+	drawTextPage(img, layout, d.Palette(), "Pairing", text, 0)
+	return nil
+}
@@ -0,0 +1,204 @@
+package picodoomsdaymessenger
+
+import (
+	"image"
+	"testing"
+	"time"
+)
+
+func TestPairing(t *testing.T) {
+	alice := newSigningDevice(t, 10)
+	alice.SelfIdentity.Name = "Alice"
+	bob := newSigningDevice(t, 20)
+	bob.SelfIdentity.Name = "Bob"
+	alice.SendUsingRadio = func(packet []byte) (err error) {
+		return bob.ReceiveFromRadio(packet)
+	}
+	bob.SendUsingRadio = func(packet []byte) (err error) {
+		return alice.ReceiveFromRadio(packet)
+	}
+
+	// Pairing packets are ignored unless the pairing screen is open.
+	for _, device := range []*Device{alice, bob} {
+		err := MainMenuItemPeople.Action(device)
+		if err != nil {
+			t.Fatalf("The error should be nil but is %v", err)
+		}
+	}
+	err := PeopleMenuItemPair.Action(alice)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	err = sendQueued(alice)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if bob.pairing.Peer != nil {
+		t.Fatalf("expected bob to ignore the pairing packet")
+	}
+
+	err = PeopleMenuItemPair.Action(bob)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	// Bob sends his Commitment, alice answers with her Nonce, and bob answers with his.
+	err = sendQueued(bob, alice, bob)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if !alice.pairingReady() || !bob.pairingReady() {
+		t.Fatalf("expected both devices to hear each other")
+	}
+	if alice.pairing.Peer.Person != bob.SelfIdentity {
+		t.Errorf("expected alice to hear bob, got %+v", alice.pairing.Peer.Person)
+	}
+	aliceCode := PairingCode(alice.pairing.own, *alice.pairing.Peer)
+	bobCode := PairingCode(bob.pairing.own, *bob.pairing.Peer)
+	if aliceCode != bobCode || len(aliceCode) != 6 {
+		t.Errorf("expected both devices to show the same code, got %q and %q", aliceCode, bobCode)
+	}
+	if _, err := GetFrame(image.Rect(0, 0, 128, 64), alice); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+
+	err = alice.ProcessInputEvent(InputEventAccept)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if alice.State != &alice.StatePeopleMenu {
+		t.Errorf("expected to go back to the people menu")
+	}
+	if len(alice.Contacts) != 1 || alice.Contacts[0].Person != bob.SelfIdentity {
+		t.Fatalf("expected bob to be a contact, got %+v", alice.Contacts)
+	}
+	if alice.StatePeopleMenu.Content[3].Text != "Bob" {
+		t.Errorf("expected bob in the people menu, got %+v", alice.StatePeopleMenu.Content)
+	}
+
+	// A device that claims to be bob with another key is not verified.
+	spoofer := newSigningDevice(t, 30)
+	packet, err := spoofer.MesageToBytes(Message{Text: "hi", Person: bob.SelfIdentity})
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	message, err := alice.BytesToMessage(packet)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if message.Verified {
+		t.Errorf("expected a spoofed message to be unverified")
+	}
+	packet, err = bob.MesageToBytes(Message{Text: "hi", Person: bob.SelfIdentity})
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	message, err = alice.BytesToMessage(packet)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if !message.Verified {
+		t.Errorf("expected a message from a contact to be verified")
+	}
+
+	restarted, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	restarted.Storage = alice.Storage
+	err = restarted.LoadContacts()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if len(restarted.Contacts) != 1 {
+		t.Errorf("expected the contacts to be kept, got %+v", restarted.Contacts)
+	}
+}
+
+func TestPairingRepeats(t *testing.T) {
+	device := newSigningDevice(t, 10)
+	sent := 0
+	device.SendUsingRadio = func(packet []byte) (err error) {
+		sent++
+		return nil
+	}
+	err := PeopleMenuItemPair.Action(device)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	err = device.Tick(time.Now().Add(PairingInterval))
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if sent != 2 {
+		t.Errorf("expected the pairing packet to be repeated, sent %d", sent)
+	}
+	packet := PairingRequestToBytes(device.pairing.own)
+	request, err := BytesToPairingRequest(packet)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if request.Person != device.SelfIdentity {
+		t.Errorf("expected %+v, got %+v", device.SelfIdentity, request.Person)
+	}
+	_, err = BytesToPairingRequest(packet[:20])
+	if err != ErrInvalidPairing {
+		t.Errorf("expected ErrInvalidPairing, got %v", err)
+	}
+}
+
+func TestPairingIgnoresOtherDevices(t *testing.T) {
+	alice := newSigningDevice(t, 10)
+	bob := newSigningDevice(t, 20)
+	mallory := newSigningDevice(t, 30)
+	alice.SendUsingRadio = func(packet []byte) (err error) {
+		return bob.ReceiveFromRadio(packet)
+	}
+	bob.SendUsingRadio = func(packet []byte) (err error) {
+		return alice.ReceiveFromRadio(packet)
+	}
+	mallory.SendUsingRadio = func(packet []byte) (err error) {
+		return alice.ReceiveFromRadio(packet)
+	}
+	for _, device := range []*Device{alice, bob} {
+		err := PeopleMenuItemPair.Action(device)
+		if err != nil {
+			t.Fatalf("The error should be nil but is %v", err)
+		}
+	}
+	if len(alice.pairing.own.Nonce) != 0 {
+		t.Errorf("expected the nonce to be kept secret until another device is heard")
+	}
+	err := sendQueued(bob, alice, bob)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if !alice.pairingReady() {
+		t.Fatalf("expected alice to show the code")
+	}
+	code := PairingCode(alice.pairing.own, *alice.pairing.Peer)
+
+	// Another device that starts pairing once the code is shown is ignored.
+	err = mallory.StartPairing(time.Now())
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	received := alice.Packets.Received
+	err = sendQueued(mallory)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if alice.Packets.Received != received+1 {
+		t.Fatalf("expected alice to hear mallory")
+	}
+	if alice.pairing.Peer.Person != bob.SelfIdentity || PairingCode(alice.pairing.own, *alice.pairing.Peer) != code {
+		t.Errorf("expected alice to keep pairing with bob, got %+v", alice.pairing.Peer.Person)
+	}
+
+	// A nonce that does not match the commitment is rejected.
+	forged := *bob.pairing.Peer
+	forged.Nonce = []byte("12345678")
+	_, err = BytesToPairingRequest(PairingRequestToBytes(forged))
+	if err != ErrInvalidPairing {
+		t.Errorf("expected ErrInvalidPairing, got %v", err)
+	}
+}
@@ -0,0 +1,96 @@
+package picodoomsdaymessenger
+
+import (
+	"image"
+	"image/color"
+)
+
+// Palette holds the colors that frames are drawn with.
+type Palette struct {
+	// Background is the color behind everything else.
+	Background color.RGBA
+	// Text is the color of menu items and messages.
+	Text color.RGBA
+	// TitleBar is the color behind the title at the top of the screen.
+	TitleBar color.RGBA
+	// TitleText is the color of the title and the lines around the title and compose bar.
+	TitleText color.RGBA
+	// Highlight is the color behind the highlighted menu item.
+	Highlight color.RGBA
+	// Cursor is the color of the cursor icon of the highlighted menu item.
+	Cursor color.RGBA
+	// OwnMessage is the color of the bubble behind messages that the Device has sent.
+	OwnMessage color.RGBA
+	// OtherMessage is the color of the bubble behind messages from other people.
+	OtherMessage color.RGBA
+	// StatusWarning is the color of status text that the user should notice, such as a Conversation being listen only.
+	StatusWarning color.RGBA
+	// StatusAlert is the color of errors.
+	StatusAlert color.RGBA
+}
+
+// MonochromePalette is the Palette that is used on screens that can only show lit and unlit pixels.
+// Everything that is not white is black, so the bubbles and highlights disappear and the layout looks the same as it always has.
+var MonochromePalette = Palette{
+	Background:    color.RGBA{0, 0, 0, 255},
+	Text:          color.RGBA{255, 255, 255, 255},
+	TitleBar:      color.RGBA{0, 0, 0, 255},
+	TitleText:     color.RGBA{255, 255, 255, 255},
+	Highlight:     color.RGBA{0, 0, 0, 255},
+	Cursor:        color.RGBA{255, 255, 255, 255},
+	OwnMessage:    color.RGBA{0, 0, 0, 255},
+	OtherMessage:  color.RGBA{0, 0, 0, 255},
+	StatusWarning: color.RGBA{255, 255, 255, 255},
+	StatusAlert:   color.RGBA{255, 255, 255, 255},
+}
+
+// ColorPalette is the default Palette for color screens.
+var ColorPalette = Palette{
+	Background:    color.RGBA{0, 0, 0, 255},
+	Text:          color.RGBA{255, 255, 255, 255},
+	TitleBar:      color.RGBA{0, 60, 120, 255},
+	TitleText:     color.RGBA{255, 255, 255, 255},
+	Highlight:     color.RGBA{40, 40, 40, 255},
+	Cursor:        color.RGBA{255, 200, 0, 255},
+	OwnMessage:    color.RGBA{0, 90, 40, 255},
+	OtherMessage:  color.RGBA{40, 40, 110, 255},
+	StatusWarning: color.RGBA{255, 150, 0, 255},
+	StatusAlert:   color.RGBA{255, 40, 40, 255},
+}
+
+// Palette returns the Palette to draw frames with. Screens with a ColorDepth of 1 always use the MonochromePalette, whatever the Theme is.
+func (d *Device) Palette() Palette {
+	if d.Display.ColorDepth > 1 {
+		return d.Theme
+	}
+	return MonochromePalette
+}
+
+// recolor changes every white pixel inside a rectangle to another color. It is used to color icons that are drawn in white.
+func recolor(img Canvas, area image.Rectangle, col color.RGBA) {
+	white := color.RGBA{255, 255, 255, 255}
+	for y := area.Min.Y; y < area.Max.Y; y++ {
+		for x := area.Min.X; x < area.Max.X; x++ {
+			if img.RGBAAt(x, y) == white {
+				img.SetRGBA(x, y, col)
+			}
+		}
+	}
+}
+
+// invert swaps light and dark across the whole of a frame, keeping its alpha.
+func invert(img Canvas) {
+	if mono, ok := img.(*MonoImage); ok {
+		for i := range mono.Pix {
+			mono.Pix[i] = ^mono.Pix[i]
+		}
+		return
+	}
+	bounds := img.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c := img.RGBAAt(x, y)
+			img.SetRGBA(x, y, color.RGBA{255 - c.R, 255 - c.G, 255 - c.B, c.A})
+		}
+	}
+}
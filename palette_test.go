@@ -0,0 +1,83 @@
+package picodoomsdaymessenger
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestPaletteFollowsColorDepth(t *testing.T) {
+	// Create a new Machine
+	device, err := NewDevice()
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	if device.Palette() != MonochromePalette {
+		t.Errorf("A monochrome display should use the MonochromePalette but uses %+v", device.Palette())
+	}
+	device.Display = DisplayST7789
+	if device.Palette() != ColorPalette {
+		t.Errorf("A color display should use the ColorPalette but uses %+v", device.Palette())
+	}
+}
+
+func TestGetFrameMessageBubbles(t *testing.T) {
+	// Create a new Machine
+	device, err := NewDevice()
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	other := Person{Name: "Other", ID: 5678}
+	device.Conversations = []*Conversation{{Name: "Test", Messages: []Message{{Text: "hi", Person: other}}}}
+	device.State = &device.StateConversationReader
+
+	// On a monochrome display, the bubble should not be drawn so that only white and black are used.
+	frame, err := GetFrame(DisplaySSD1306.Bounds(), device)
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	layout := NewLayout(DisplaySSD1306.Bounds())
+	if frame.At(20, layout.HighlightBaseline-10) != (color.RGBA{0, 0, 0, 255}) {
+		t.Errorf("The bubble should not be drawn on a monochrome display but is %v", frame.At(20, layout.HighlightBaseline-10))
+	}
+
+	device.Display = DisplayST7789
+	frame, err = GetFrame(DisplayST7789.Bounds(), device)
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	layout = NewLayout(DisplayST7789.Bounds())
+	if frame.At(20, layout.HighlightBaseline-10) != ColorPalette.OtherMessage {
+		t.Errorf("The bubble should be %v but is %v", ColorPalette.OtherMessage, frame.At(20, layout.HighlightBaseline-10))
+	}
+}
+
+func TestRenderFrameInverted(t *testing.T) {
+	// Create a new Machine
+	device, err := NewDevice()
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	for _, frame := range []func() Canvas{
+		func() Canvas { return image.NewRGBA(DisplaySSD1306.Bounds()) },
+		func() Canvas { return NewMonoImage(DisplaySSD1306.Bounds()) },
+	} {
+		normal, inverted := frame(), frame()
+		device.Settings.Inverted = false
+		if err = RenderFrame(normal, device); err != nil {
+			t.Errorf("The error should be nil but is %v", err)
+		}
+		device.Settings.Inverted = true
+		if err = RenderFrame(inverted, device); err != nil {
+			t.Errorf("The error should be nil but is %v", err)
+		}
+		for y := 0; y < DisplaySSD1306.Height; y++ {
+			for x := 0; x < DisplaySSD1306.Width; x++ {
+				n, i := normal.RGBAAt(x, y), inverted.RGBAAt(x, y)
+				if n.R != 255-i.R || n.G != 255-i.G || n.B != 255-i.B {
+					t.Fatalf("The inverted frame %T should be the opposite of the normal frame at (%d, %d), have %v and %v", normal, x, y, n, i)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package picodoomsdaymessenger
+
+import "time"
+
+// PanicPresses is how many times the PanicKey in the Settings has to be pressed to start SOS mode.
+const PanicPresses = 3
+
+// PanicWindow is how quickly all of the PanicPresses have to happen, so that the key can still be used normally.
+const PanicWindow = 1500 * time.Millisecond
+
+// PanicKeys are the keys that can be chosen as the PanicKey in the Settings, with the name of each. The empty InputEvent turns the panic gesture off.
+var PanicKeys = []struct {
+	Name string
+	Key  InputEvent
+}{
+	{"Off", ""},
+	{"F1", InputEventFunction1},
+	{"F2", InputEventFunction2},
+	{"F3", InputEventFunction3},
+	{"F4", InputEventFunction4},
+	{"*", InputEventStar},
+	{"#", InputEventPound},
+}
+
+// processPanicGesture watches for the PanicKey being pressed PanicPresses times within the PanicWindow, and starts SOS mode when it is, whatever is on the screen.
+// It returns true if the InputEvent finished the gesture, so that the last press does nothing else.
+func (d *Device) processPanicGesture(inputEvent InputEvent, now time.Time) (handled bool, err error) {
+	if d.Settings.PanicKey == "" || inputEvent != d.Settings.PanicKey {
+		d.panicPresses = nil
+		return false, nil
+	}
+	d.panicPresses = append(d.panicPresses, now)
+	if len(d.panicPresses) > PanicPresses {
+		d.panicPresses = d.panicPresses[1:]
+	}
+	if len(d.panicPresses) < PanicPresses || now.Sub(d.panicPresses[0]) > PanicWindow {
+		return false, nil
+	}
+	d.panicPresses = nil
+	return true, d.Panic()
+}
+
+// Panic starts SOS mode and broadcasts it over the radio, even if SOSBroadcast is turned off in the Settings, as it is only started on purpose.
+func (d *Device) Panic() (err error) {
+	d.sos.Broadcast = true
+	return d.SetSOS(true)
+}
+
+// panicKeyMenuItems creates a MenuItem for every one of the PanicKeys.
+func panicKeyMenuItems() (items []MenuItem) {
+	names := make([]string, len(PanicKeys))
+	for i, key := range PanicKeys {
+		names[i] = key.Name
+	}
+	return choiceMenuItems(names, func(d *Device, i int) bool {
+		return d.Settings.PanicKey == PanicKeys[i].Key
+	}, func(d *Device, i int) (err error) {
+		d.Settings.PanicKey = PanicKeys[i].Key
+		return nil
+	})
+}
@@ -0,0 +1,76 @@
+package picodoomsdaymessenger
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPanicGesture(t *testing.T) {
+	device, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	sent := 0
+	device.SendUsingRadio = func(packet []byte) (err error) {
+		sent++
+		return nil
+	}
+	device.State = &device.StateGamesMenu
+	device.Settings.PanicKey = InputEventPound
+	for i := 0; i < PanicPresses; i++ {
+		if err = device.ProcessInputEvent(InputEventPound); err != nil {
+			t.Fatalf("The error should be nil but is %v", err)
+		}
+	}
+	if !device.sos.Active || device.LEDAnimation != &LEDAnimationSOS {
+		t.Fatalf("expected pressing the panic key %d times to start SOS mode", PanicPresses)
+	}
+	if err = sendQueued(device); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if sent != 1 {
+		t.Errorf("expected an SOS to be broadcast without SOSBroadcast, sent %d packets", sent)
+	}
+	if err = device.SetSOS(false); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if device.sos.Broadcast {
+		t.Errorf("expected turning SOS mode off to stop broadcasting")
+	}
+}
+
+func TestPanicGestureTiming(t *testing.T) {
+	device, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	device.Settings.PanicKey = InputEventFunction4
+	now := time.Now()
+	for _, press := range []struct {
+		event InputEvent
+		at    time.Duration
+	}{
+		// Too slow.
+		{InputEventFunction4, 0},
+		{InputEventFunction4, PanicWindow / 2},
+		{InputEventFunction4, 2 * PanicWindow},
+		// Another key in between starts the count again.
+		{InputEventFunction4, 2*PanicWindow + 100*time.Millisecond},
+		{InputEventUp, 2*PanicWindow + 200*time.Millisecond},
+		{InputEventFunction4, 2*PanicWindow + 300*time.Millisecond},
+	} {
+		handled, err := device.processPanicGesture(press.event, now.Add(press.at))
+		if err != nil {
+			t.Fatalf("The error should be nil but is %v", err)
+		}
+		if handled {
+			t.Fatalf("expected the press at %v not to finish the gesture", press.at)
+		}
+	}
+	device.Settings.PanicKey = ""
+	for i := 0; i < PanicPresses; i++ {
+		if handled, _ := device.processPanicGesture("", now); handled {
+			t.Fatalf("expected the panic gesture to be off")
+		}
+	}
+}
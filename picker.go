@@ -0,0 +1,65 @@
+package picodoomsdaymessenger
+
+// InputMethodPicker types by scrolling through the characters with up and down (or left and right), and picking one with accept.
+// It only needs 3 buttons, so it works with a rotary encoder or a D-pad. Messages cannot be scrolled while it is in use.
+const InputMethodPicker InputMethod = "Picker"
+
+// Define the special entries of the character picker.
+const (
+	// PickerSend sends the message that has been typed.
+	PickerSend = "SEND"
+	// PickerDelete removes the last character that has been typed.
+	PickerDelete = "DEL"
+	// PickerBack leaves the Conversation, so that it can be left with only the accept button while scanning.
+	PickerBack = "BACK"
+)
+
+// PickerCharacters is the list of entries that the character picker scrolls through.
+var PickerCharacters = []string{
+	PickerSend, PickerBack, " ", "a", "b", "c", "d", "e", "f", "g", "h", "i", "j", "k", "l", "m", "n", "o", "p", "q", "r", "s", "t", "u", "v", "w", "x", "y", "z",
+	"0", "1", "2", "3", "4", "5", "6", "7", "8", "9", ".", ",", "?", "!", "'", "-", "@", PickerDelete,
+}
+
+// processPickerInputEvent handles an InputEvent while typing with the character picker. It returns false if the InputEvent is not used by the picker.
+func (d *Device) processPickerInputEvent(inputEvent InputEvent) (handled bool, err error) {
+	switch inputEvent {
+	case InputEventUp, InputEventLeft:
+		d.picker = (d.picker + len(PickerCharacters) - 1) % len(PickerCharacters)
+		return true, nil
+	case InputEventDown, InputEventRight:
+		d.picker = (d.picker + 1) % len(PickerCharacters)
+		return true, nil
+	case InputEventAccept:
+		conversation := d.Conversations[d.CurrentConversationIndex]
+		switch PickerCharacters[d.picker] {
+		case PickerSend:
+			return true, d.sendComposerText()
+		case PickerBack:
+			return true, d.GoBackState()
+		case PickerDelete:
+			runes := []rune(conversation.KeyboardBuffer)
+			if len(runes) > 0 {
+				conversation.KeyboardBuffer = string(runes[:len(runes)-1])
+			}
+		default:
+			conversation.KeyboardBuffer += PickerCharacters[d.picker]
+		}
+		return true, nil
+	}
+	return false, nil
+}
+
+// pickerHint returns the picker entry that is selected in brackets, with the entries either side of it.
+func (d *Device) pickerHint() string {
+	previous := PickerCharacters[(d.picker+len(PickerCharacters)-1)%len(PickerCharacters)]
+	next := PickerCharacters[(d.picker+1)%len(PickerCharacters)]
+	return pickerEntryText(previous) + " [" + pickerEntryText(PickerCharacters[d.picker]) + "] " + pickerEntryText(next)
+}
+
+// pickerEntryText returns how a picker entry is shown, so that a space can be seen.
+func pickerEntryText(entry string) string {
+	if entry == " " {
+		return "_"
+	}
+	return entry
+}
@@ -0,0 +1,52 @@
+package picodoomsdaymessenger
+
+import (
+	"testing"
+)
+
+func TestCharacterPicker(t *testing.T) {
+	// Create a new Machine
+	device, err := NewDevice()
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	var sent []byte
+	device.SendUsingRadio = func(packet []byte) (err error) {
+		sent = packet
+		return nil
+	}
+	device.Conversations = []*Conversation{{Name: "Test"}}
+	device.State = &device.StateConversationReader
+	device.Settings.InputMethod = InputMethodPicker
+
+	// Scroll to "b", pick it twice, delete one, then go back round to send.
+	for _, inputEvent := range []InputEvent{InputEventDown, InputEventDown, InputEventDown, InputEventRight, InputEventAccept, InputEventAccept} {
+		device.ProcessInputEvent(inputEvent)
+	}
+	if device.KeyboardHint() != "a [b] c" {
+		t.Errorf("The hint should be a [b] c but is %v", device.KeyboardHint())
+	}
+	for i := 0; i < 5; i++ {
+		device.ProcessInputEvent(InputEventUp)
+	}
+	device.ProcessInputEvent(InputEventAccept)
+	if device.ComposerText() != "b" {
+		t.Errorf("The composer text should be b but is %v", device.ComposerText())
+	}
+	device.ProcessInputEvent(InputEventDown)
+	device.ProcessInputEvent(InputEventAccept)
+	err = sendQueued(device)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if sent == nil {
+		t.Errorf("The message should have been sent")
+	}
+	message, err := device.BytesToMessage(sent)
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	if message.Text != "b" {
+		t.Errorf("The message text should be b but is %v", message.Text)
+	}
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"machine"
+
+	"github.com/headblockhead/picoDoomsdayMessenger/firmware"
+)
+
+// adcBattery measures the battery through a voltage divider that halves it into an ADC pin.
+// Boards with a different divider need the calculation in Percent changing.
+type adcBattery struct {
+	adc machine.ADC
+}
+
+// newADCBattery configures the ADC pin that the battery is measured on.
+func newADCBattery(pin machine.Pin) *adcBattery {
+	machine.InitADC()
+	adc := machine.ADC{Pin: pin}
+	adc.Configure(machine.ADCConfig{})
+	return &adcBattery{adc: adc}
+}
+
+// Percent estimates the charge of the battery from its voltage.
+func (b *adcBattery) Percent() (percent int, err error) {
+	// The ADC reads 0 to 3.3V as 0 to 65535, and the divider halves the battery voltage.
+	volts := float64(b.adc.Get()) / 65535 * 3.3 * 2
+	return firmware.LiPoPercent(volts), nil
+}
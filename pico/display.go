@@ -0,0 +1,207 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"machine"
+
+	picodoomsdaymessenger "github.com/headblockhead/picoDoomsdayMessenger"
+	"tinygo.org/x/drivers/ssd1306"
+	"tinygo.org/x/drivers/st7789"
+	"tinygo.org/x/drivers/waveshare-epd/epd2in13"
+)
+
+// ssd1306Display shows frames on a 128x64 SSD1306 OLED over I2C, sending only the columns of each page that have changed.
+type ssd1306Display struct {
+	display *ssd1306.Device
+	screen  *picodoomsdaymessenger.PageBuffer
+	tracker *picodoomsdaymessenger.DirtyTracker
+}
+
+func newSSD1306Display(bus *machine.I2C) *ssd1306Display {
+	display := ssd1306.NewI2C(bus)
+	display.Configure(ssd1306.Config{
+		Address: 0x3C,
+		Width:   128,
+		Height:  64,
+	})
+	display.ClearDisplay()
+	return &ssd1306Display{
+		display: &display,
+		screen:  picodoomsdaymessenger.NewPageBuffer(128, 64),
+		tracker: picodoomsdaymessenger.NewDirtyTracker(picodoomsdaymessenger.PageHeight),
+	}
+}
+
+func (s *ssd1306Display) Capabilities() picodoomsdaymessenger.DisplayCapabilities {
+	return picodoomsdaymessenger.DisplaySSD1306
+}
+
+func (s *ssd1306Display) Size() (width, height int) {
+	return picodoomsdaymessenger.DisplaySSD1306.Width, picodoomsdaymessenger.DisplaySSD1306.Height
+}
+
+func (s *ssd1306Display) DrawFrame(frame image.Image) (err error) {
+	// Each changed rectangle is the changed columns of one page.
+	var changed []image.Rectangle
+	if mono, ok := frame.(*picodoomsdaymessenger.MonoImage); ok {
+		changed = s.screen.DrawMonoImage(mono)
+	} else {
+		changed = s.tracker.Changed(frame)
+		for _, r := range changed {
+			s.screen.DrawImageRect(frame, r)
+		}
+	}
+	for _, r := range changed {
+		page := r.Min.Y / picodoomsdaymessenger.PageHeight
+		s.display.Command(ssd1306.COLUMNADDR)
+		s.display.Command(uint8(r.Min.X))
+		s.display.Command(uint8(r.Max.X - 1))
+		s.display.Command(ssd1306.PAGEADDR)
+		s.display.Command(uint8(page))
+		s.display.Command(uint8(page))
+		s.display.Tx(s.screen.Page(page)[r.Min.X:r.Max.X], false)
+		s.screen.MarkSent(page)
+	}
+	return nil
+}
+
+// sh1106ColumnOffset is the first column of the SH1106 memory that is visible, as it has 132 columns of memory for a 128 pixel wide screen.
+const sh1106ColumnOffset = 2
+
+// sh1106Display shows frames on a 1.3" 128x64 SH1106 OLED over I2C.
+// The SH1106 accepts the same setup commands as the SSD1306, but it can only be written to one page at a time.
+type sh1106Display struct {
+	display *ssd1306.Device
+	screen  *picodoomsdaymessenger.PageBuffer
+}
+
+func newSH1106Display(bus *machine.I2C) *sh1106Display {
+	display := ssd1306.NewI2C(bus)
+	display.Configure(ssd1306.Config{
+		Address: 0x3C,
+		Width:   128,
+		Height:  64,
+	})
+	return &sh1106Display{
+		display: &display,
+		screen:  picodoomsdaymessenger.NewPageBuffer(128, 64),
+	}
+}
+
+func (s *sh1106Display) Capabilities() picodoomsdaymessenger.DisplayCapabilities {
+	return picodoomsdaymessenger.DisplaySH1106
+}
+
+func (s *sh1106Display) Size() (width, height int) {
+	return picodoomsdaymessenger.DisplaySH1106.Width, picodoomsdaymessenger.DisplaySH1106.Height
+}
+
+func (s *sh1106Display) DrawFrame(frame image.Image) (err error) {
+	if mono, ok := frame.(*picodoomsdaymessenger.MonoImage); ok {
+		s.screen.DrawMonoImage(mono)
+	} else {
+		s.screen.DrawImage(frame)
+	}
+	for _, page := range s.screen.ChangedPages() {
+		// Set the page, then the low and high nibbles of the column.
+		s.display.Command(0xB0 | uint8(page))
+		s.display.Command(0x00 | sh1106ColumnOffset&0x0F)
+		s.display.Command(0x10 | sh1106ColumnOffset>>4)
+		s.display.Tx(s.screen.Page(page), false)
+		s.screen.MarkSent(page)
+	}
+	return nil
+}
+
+// epaperDisplay shows frames on a 2.13" e-paper screen over SPI, turned on its side to be 250x122.
+// Lit pixels of the frame are drawn in black ink on the white paper.
+type epaperDisplay struct {
+	display *epd2in13.Device
+}
+
+func newEPaperDisplay(bus *machine.SPI, csPin, dcPin, rstPin, busyPin machine.Pin) *epaperDisplay {
+	display := epd2in13.New(bus, csPin, dcPin, rstPin, busyPin)
+	display.Configure(epd2in13.Config{Rotation: epd2in13.ROTATION_90})
+	display.ClearBuffer()
+	display.ClearDisplay()
+	return &epaperDisplay{display: &display}
+}
+
+func (e *epaperDisplay) Capabilities() picodoomsdaymessenger.DisplayCapabilities {
+	return picodoomsdaymessenger.DisplayEPaper
+}
+
+func (e *epaperDisplay) Size() (width, height int) {
+	return picodoomsdaymessenger.DisplayEPaper.Width, picodoomsdaymessenger.DisplayEPaper.Height
+}
+
+func (e *epaperDisplay) DrawFrame(frame image.Image) (err error) {
+	for y := 0; y < frame.Bounds().Dy(); y++ {
+		for x := 0; x < frame.Bounds().Dx(); x++ {
+			r, g, b, a := frame.At(x, y).RGBA()
+			e.display.SetPixel(int16(x), int16(y), color.RGBA{uint8(r), uint8(g), uint8(b), uint8(a)})
+		}
+	}
+	err = e.display.Display()
+	if err != nil {
+		return err
+	}
+	e.display.WaitUntilIdle()
+	return nil
+}
+
+// st7789Display shows frames on a 1.3" 240x240 ST7789 color LCD over SPI.
+// Each row is only sent if it is different to the last frame, as sending the whole screen over SPI is slow.
+type st7789Display struct {
+	display *st7789.Device
+	row     []color.RGBA
+	last    [][]color.RGBA
+}
+
+func newST7789Display(bus *machine.SPI, resetPin, dcPin, csPin, blPin machine.Pin) *st7789Display {
+	display := st7789.New(bus, resetPin, dcPin, csPin, blPin)
+	display.Configure(st7789.Config{Width: 240, Height: 240})
+	display.FillScreen(color.RGBA{0, 0, 0, 255})
+	s := &st7789Display{
+		display: &display,
+		row:     make([]color.RGBA, 240),
+		last:    make([][]color.RGBA, 240),
+	}
+	for y := range s.last {
+		s.last[y] = make([]color.RGBA, 240)
+		for x := range s.last[y] {
+			s.last[y][x] = color.RGBA{0, 0, 0, 255}
+		}
+	}
+	return s
+}
+
+func (s *st7789Display) Capabilities() picodoomsdaymessenger.DisplayCapabilities {
+	return picodoomsdaymessenger.DisplayST7789
+}
+
+func (s *st7789Display) Size() (width, height int) {
+	return picodoomsdaymessenger.DisplayST7789.Width, picodoomsdaymessenger.DisplayST7789.Height
+}
+
+func (s *st7789Display) DrawFrame(frame image.Image) (err error) {
+	for y := 0; y < len(s.last) && y < frame.Bounds().Dy(); y++ {
+		changed := false
+		for x := 0; x < len(s.row) && x < frame.Bounds().Dx(); x++ {
+			s.row[x] = color.RGBAModel.Convert(frame.At(x, y)).(color.RGBA)
+			if s.row[x] != s.last[y][x] {
+				changed = true
+			}
+		}
+		if !changed {
+			continue
+		}
+		err = s.display.FillRectangleWithBuffer(0, int16(y), int16(len(s.row)), 1, s.row)
+		if err != nil {
+			return err
+		}
+		copy(s.last[y], s.row)
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"machine"
+
+	picodoomsdaymessenger "github.com/headblockhead/picoDoomsdayMessenger"
+)
+
+// keypadLayout is the location of each button in the button matrix, by row then column.
+var keypadLayout = [][]picodoomsdaymessenger.InputEvent{
+	{picodoomsdaymessenger.InputEventNumber1, picodoomsdaymessenger.InputEventNumber2, picodoomsdaymessenger.InputEventNumber3, picodoomsdaymessenger.InputEventFunction1, picodoomsdaymessenger.InputEventUp},
+	{picodoomsdaymessenger.InputEventNumber4, picodoomsdaymessenger.InputEventNumber5, picodoomsdaymessenger.InputEventNumber6, picodoomsdaymessenger.InputEventFunction2, picodoomsdaymessenger.InputEventDown},
+	{picodoomsdaymessenger.InputEventNumber7, picodoomsdaymessenger.InputEventNumber8, picodoomsdaymessenger.InputEventNumber9, picodoomsdaymessenger.InputEventFunction3, picodoomsdaymessenger.InputEventLeft},
+	{picodoomsdaymessenger.InputEventStar, picodoomsdaymessenger.InputEventNumber0, picodoomsdaymessenger.InputEventPound, picodoomsdaymessenger.InputEventFunction4, picodoomsdaymessenger.InputEventRight},
+	{picodoomsdaymessenger.InputEventOpenMainMenu, picodoomsdaymessenger.InputEventOpenConversations, picodoomsdaymessenger.InputEventOpenPeople, picodoomsdaymessenger.InputEventOpenSettings, picodoomsdaymessenger.InputEventAccept},
+}
+
+// newKeypad configures the pins of a 5x5 button matrix. The columns are read and the rows are pulsed.
+func newKeypad(rows [5]machine.Pin, cols [5]machine.Pin) *picodoomsdaymessenger.MatrixKeypad {
+	rowPins := make([]picodoomsdaymessenger.KeypadRow, len(rows))
+	for i, row := range rows {
+		row.Configure(machine.PinConfig{Mode: machine.PinOutput})
+		row.Low()
+		rowPins[i] = row
+	}
+	colPins := make([]picodoomsdaymessenger.KeypadColumn, len(cols))
+	for i, col := range cols {
+		col.Configure(machine.PinConfig{Mode: machine.PinInputPulldown})
+		colPins[i] = col
+	}
+	return picodoomsdaymessenger.NewMatrixKeypad(rowPins, colPins, keypadLayout)
+}
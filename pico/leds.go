@@ -0,0 +1,24 @@
+package main
+
+import (
+	"image/color"
+	"machine"
+
+	"tinygo.org/x/drivers/ws2812"
+)
+
+// ws2812LEDs shows colors on the RGB LED array.
+type ws2812LEDs struct {
+	leds ws2812.Device
+}
+
+// newWS2812LEDs configures the data pin of the RGB LED array.
+func newWS2812LEDs(pin machine.Pin) *ws2812LEDs {
+	pin.Configure(machine.PinConfig{Mode: machine.PinOutput})
+	return &ws2812LEDs{leds: ws2812.New(pin)}
+}
+
+// ShowLEDs displays the given RGBA color array on the LEDs.
+func (l *ws2812LEDs) ShowLEDs(colors [6]color.RGBA) (err error) {
+	return l.leds.WriteColors(colors[:])
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	picodoomsdaymessenger "github.com/headblockhead/picoDoomsdayMessenger"
+	"github.com/headblockhead/tinygorfm9x"
+)
+
+// rfm9xRadio adapts the RFM9x driver to the firmware.Radio interface.
+type rfm9xRadio struct {
+	rfm     *tinygorfm9x.RFM9x
+	options tinygorfm9x.Options
+}
+
+// SetFrequency re-initializes the RFM9x on a new frequency and starts receiving again.
+func (r *rfm9xRadio) SetFrequency(frequencyMHz float64) (err error) {
+	r.options.FrequencyMHz = frequencyMHz
+	err = r.rfm.Init(r.options)
+	if err != nil {
+		return err
+	}
+	return r.rfm.StartReceive()
+}
+
+// SetModemConfig re-initializes the RFM9x with new LoRa modem parameters and starts receiving again.
+func (r *rfm9xRadio) SetModemConfig(config picodoomsdaymessenger.ModemConfig) (err error) {
+	r.options.SpreadingFactor = config.SpreadingFactor
+	r.options.SignalBandwidth = config.BandwidthHz
+	r.options.CodingRate = config.CodingRate
+	err = r.rfm.Init(r.options)
+	if err != nil {
+		return err
+	}
+	return r.rfm.StartReceive()
+}
+
+// SetTxPower re-initializes the RFM9x with a new transmit power in dBm and starts receiving again.
+func (r *rfm9xRadio) SetTxPower(dBm int) (err error) {
+	r.options.TxPower = dBm
+	err = r.rfm.Init(r.options)
+	if err != nil {
+		return err
+	}
+	return r.rfm.StartReceive()
+}
+
+// ChannelActive always returns false, as the driver does not do channel activity detection.
+func (r *rfm9xRadio) ChannelActive() (busy bool, err error) {
+	return false, nil
+}
+
+// ChannelRSSI always returns RSSIUnknown, as the driver does not report the strength of the channel.
+func (r *rfm9xRadio) ChannelRSSI() (dBm int, err error) {
+	return picodoomsdaymessenger.RSSIUnknown, nil
+}
+
+// SetPreambleLength does nothing, as the driver always sends the default preamble. Devices in low power listening may miss packets from this one.
+func (r *rfm9xRadio) SetPreambleLength(symbols int) (err error) {
+	return nil
+}
+
+// Sleep does nothing, as the driver cannot put the RFM9x to sleep. It keeps receiving.
+func (r *rfm9xRadio) Sleep() (err error) {
+	return nil
+}
+
+// Listen starts receiving again.
+func (r *rfm9xRadio) Listen() (err error) {
+	return r.rfm.StartReceive()
+}
+
+// Send transmits a packet.
+func (r *rfm9xRadio) Send(packet []byte) (err error) {
+	return r.rfm.Send(packet)
+}
+
+// SetReceiveHandler sets the function that is called with the payload of every packet that is received.
+// The driver does not report the RSSI of each packet, so it is always unknown.
+func (r *rfm9xRadio) SetReceiveHandler(handler func(packet []byte, rssi int)) {
+	r.rfm.OnReceivedPacket = func(packet tinygorfm9x.Packet) {
+		handler(packet.Payload, picodoomsdaymessenger.RSSIUnknown)
+	}
+}
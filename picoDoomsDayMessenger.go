@@ -7,23 +7,197 @@ import (
 	"image"
 	"image/color"
 	"math/rand"
+	"strconv"
+	"strings"
 	"time"
+	"unicode/utf8"
 
-	"golang.org/x/image/font"
 	"golang.org/x/image/font/basicfont"
 	"golang.org/x/image/math/fixed"
 )
 
 // Device is the main structure that holds all the information about the device. It has a State, a StateHistory, and an LEDAnimation.
 type Device struct {
+	States
 	State                    *State
 	StateHistory             []*State
+	forwardHistory           []*State
 	LEDAnimation             *LEDAnimation
 	Conversations            []*Conversation
 	CurrentConversationIndex int
 	SelfIdentity             Person
 	CurrentKeyboardButton    *KeyboardButton
 	SendUsingRadio           func(packet []byte) (err error)
+	Radio                    Radio
+	Settings                 Settings
+	SendToGateway            func(event []byte) (err error)
+	gatewayNodes             map[int]time.Time
+	TimeOffset               time.Duration
+	TimeSource               string
+	Position                 Position
+	MeshtasticCodec          MeshtasticCodec
+	HeardStations            []HeardStation
+	Neighbors                []Neighbor
+	lastBeacon               time.Time
+	Log                      func(message string)
+	morse                    morseKeyer
+	Display                  DisplayCapabilities
+	Theme                    Palette
+	notification             ledNotification
+	tones                    tonePlayer
+	listen                   listenSchedule
+	marquee                  marqueeState
+	picker                   int
+	pong                     pongGame
+	lastScan                 time.Time
+	ticTacToe                ticTacToeGame
+	Storage                  Storage
+	Battery                  BatteryLevel
+	Notes                    []Note
+	notes                    notesState
+	document                 documentState
+	Sensors                  []Sensor
+	currentSensor            int
+	sos                      sosState
+	replay                   replayState
+	lastRSSI                 int
+	rangeTest                rangeTest
+	channelScan              channelScanState
+	MonitoredPackets         []MonitoredPacket
+	monitorScroll            int
+	nameDraft                string
+	renameDraft              string
+	renaming                 int
+	currentNeighbor          int
+	// pendingBuffer is the text that the character being typed with multi-tap will be added to.
+	pendingBuffer *string
+	// pressedButton is the KeyboardButton of the KeyboardLayout that the CurrentKeyboardButton was copied from, so that pressing it again moves on to its next character.
+	pressedButton *KeyboardButton
+	// notifiedConversation is the Conversation that the last unread message was received in, until it is opened.
+	notifiedConversation *Conversation
+	keys                 signingKeys
+	Contacts             []Contact
+	pairing              pairingState
+	sync                 syncState
+	lastInput            time.Time
+	emergency            Message
+	QuickReplies         []string
+	Scheduled            []ScheduledMessage
+	autoReplied          map[int]time.Time
+	autoReplyDraft       string
+	quickReplies         quickRepliesState
+	functionKey          int
+	panicPresses         []time.Time
+	Channels             []Channel
+	channels             channelsState
+	contactOffers        contactOffersState
+	started              time.Time
+	aboutScroll          int
+	crashScroll          int
+	splashStart          time.Time
+	screen               Display
+	frame                Canvas
+	dirty                bool
+	heldInputs           map[InputEvent]time.Time
+	lastInputEvent       InputEvent
+	predictive           predictiveState
+	symbol               int
+	airtime              []airtimeRecord
+	txQueue              []queuedPacket
+	backoffUntil         time.Time
+	// ComposeMode is what the number keys type with multi-tap, cycled with the Star key.
+	ComposeMode ComposeMode
+	// InputTiming is how buttons are debounced and repeated.
+	InputTiming InputTiming
+	// GetLocation reads the location of the Device from a GPS, if it has one. It returns false if the location is not known yet.
+	GetLocation func() (latitude, longitude float64, ok bool)
+	// GetHeading reads the direction the Device is facing from a magnetometer, in degrees clockwise from north. It returns false if there is no reading.
+	GetHeading func() (degrees float64, ok bool)
+	// Clock reads the time from the clock of the Device, which Now corrects by the TimeOffset. If it is nil, time.Now is used.
+	Clock func() time.Time
+	// SendToHost writes a sync frame to a host computer, such as a laptop running a companion app.
+	SendToHost func(frame []byte) (err error)
+	// OnMessageReceived is called with every Message that is received from another device, once it has been added to its Conversation.
+	OnMessageReceived func(c *Conversation, m Message)
+	// OnStateChanged is called every time the Device changes State, including going back.
+	OnStateChanged func(from, to *State)
+	// OnSendResult is called every time a packet is given to the radio, with the error that the radio returned.
+	OnSendResult func(packet []byte, err error)
+	// OnMessageStatus is called every time the Status of a Message that the Device sent changes.
+	OnMessageStatus func(c *Conversation, m Message)
+	// PlayTone starts playing a note on the buzzer for a duration, without waiting for it to finish. It is nil if the Device has no buzzer.
+	PlayTone func(frequencyHz int, duration time.Duration) (err error)
+	// OnDeviceEvent is called with every DeviceEvent that the user is told about, even those without an LED animation.
+	OnDeviceEvent func(event DeviceEvent)
+	// LastCrash is the last panic of the firmware, or nil if it has not crashed since the CrashLog was cleared.
+	LastCrash *CrashLog
+	// SafeMode is true if the Device was started without loading anything from its Storage, after a crash.
+	SafeMode bool
+	// Packets counts the packets that have been sent and received, which are shown on the About screen.
+	Packets PacketCounters
+	// GetFreeHeap reads how many bytes of memory are free. It returns false if it cannot be measured.
+	GetFreeHeap func() (bytes int, ok bool)
+	// WriteSerial writes bytes to the serial port as they are, such as an export of the message history.
+	WriteSerial func(data []byte) (err error)
+	// Version is the version of the firmware that the Device is running, which is shown on the splash screen.
+	Version string
+	// Font is the font face that text is drawn in. If it is nil, the face of the TextSize in the Settings is used.
+	Font *basicfont.Face
+	// Blocked is the IDs of the devices whose packets are dropped by ReceiveFromRadio.
+	Blocked []int
+	// Names are the names that the user has given to devices that are not Contacts, by their ID.
+	Names map[int]string
+}
+
+// Settings holds the options of a Device that can be changed by the user.
+type Settings struct {
+	FrequencyMHz   float64
+	Modem          ModemConfig
+	Gateway        bool
+	Meshtastic     bool
+	Beacon         bool
+	BeaconInterval time.Duration
+	InputMethod    InputMethod
+	MorseKey       InputEvent
+	KeyboardLayout string
+	Scanning       bool
+	ScanInterval   time.Duration
+	SOSBroadcast   bool
+	TextSize       TextSize
+	Inverted       bool
+	// ScreensaverTimeout is how long the Device waits without any input before it starts the screensaver. Zero turns it off.
+	ScreensaverTimeout time.Duration
+	// EventTime is when the day count shown by the screensaver started. It is zero if there is no day count.
+	EventTime time.Time
+	// AutoReply turns on away mode, where every Person who sends a message is answered with the AutoReplyText, at most once every AutoReplyInterval.
+	AutoReply     bool
+	AutoReplyText string
+	// HopLimit is how many times the messages sent by the Device can be relayed. Zero sends them without a hop header.
+	HopLimit int
+	// Overhear shows messages that were addressed to other devices, instead of dropping them.
+	Overhear bool
+	// Channel is the name of the Channel that new Conversations are started on, or empty for the public channel.
+	Channel string
+	// SendKey is the key that sends the message in the compose bar, one of the SendKeys. When it is not Accept, Accept adds the character that is being typed instead.
+	SendKey InputEvent
+	// DutyCycle is the fraction of every hour that the radio can send for, such as 0.01 for the 1% allowed on EU868. Zero has no limit.
+	DutyCycle float64
+	// Region is the name of the Region whose radio rules are followed, or empty to follow none.
+	Region string
+	// TxPower is the power in dBm that the radio sends with, from MinTxPower to the most that the Region allows.
+	TxPower int
+	// MultiTapTimeout is how long a multi-tap character is left alone for before it is added to the text. Zero waits for another key.
+	MultiTapTimeout time.Duration
+	// ListenInterval is how long the radio sleeps for between listening, to save power. Zero keeps it listening all the time.
+	ListenInterval time.Duration
+	// FunctionKeys are what each of the FunctionKeys has been set to do.
+	FunctionKeys [4]FunctionKeyBinding
+	// PanicKey is the key that starts SOS mode when it is pressed PanicPresses times in a row, or empty to turn the panic gesture off.
+	PanicKey InputEvent
+	// AlertTones plays the ToneNotifications on the buzzer.
+	AlertTones bool
+	// MorseTones plays the Morse light messages on the buzzer as well as the LEDs.
+	MorseTones bool
 }
 
 type KeyboardButton struct {
@@ -33,12 +207,28 @@ type KeyboardButton struct {
 }
 
 // Conversation is a conversation with a person. It contains a list of Messages and a Person that the conversation is with.
+// A ListenOnly Conversation is used to monitor a channel without revealing your presence, nothing is ever sent for it.
 type Conversation struct {
 	Messages                []Message
 	HighlightedMessageIndex int
 	KeyboardBuffer          string
-	Name                    string
-	People                  []Person
+	// KeyboardBufferAfter is the text of the compose bar after the cursor. The KeyboardBuffer is the text before it, so typing always adds to the end of the KeyboardBuffer.
+	KeyboardBufferAfter string
+	Name                string
+	People              []Person
+	ListenOnly          bool
+	// Priority is the Priority that the message being composed will be sent with.
+	Priority Priority
+	// Pinned is a Message that is kept at the top of the conversation reader, if it is set.
+	Pinned *Message
+	// Broadcast is true for the Conversation that sends to everyone in range, and that every broadcast is kept in.
+	Broadcast bool
+	// Channel is the name of the Channel that the Conversation is on, or empty for the public channel.
+	Channel string
+	// Unread is the number of Messages that have been received since the Conversation was last opened.
+	Unread int
+	// Muted Conversations do not flash the LEDs or count Unread messages when a message is received.
+	Muted bool
 }
 
 // Person is a representation of another device. A Person has a name and a unique identifier
@@ -48,10 +238,59 @@ type Person struct {
 }
 
 // Message is a message sent inside a Conversation. It contains the time it was sent, the time it was recieved and the content of the message.
+// A Message that was too long to be accepted is Truncated.
 type Message struct {
-	Text     string
-	Person   Person
-	TimeSent time.Time
+	Text      string
+	Person    Person
+	TimeSent  time.Time
+	Truncated bool
+	// Location is where the Person was, if the Message was sent with SendLocation.
+	Location Position
+	// Verified is true if the Message was signed by the key that was first heard from the Person.
+	Verified bool
+	// Priority is how urgent the Message is.
+	Priority Priority
+	// Hops is how many times the Message was relayed before it was received, and TTL is how many more times it could have been.
+	Hops int
+	TTL  int
+	// To is the ID of the Person that the Message is for, or BroadcastID if it is for everyone.
+	To int
+	// Overheard is true if the Message was received, but was addressed to someone else.
+	Overheard bool
+	// Broadcast is true if the Message was sent to the broadcast Conversation, rather than to one Person.
+	Broadcast bool
+	// Channel is the name of the Channel that the Message was encrypted for, or empty if it was not encrypted.
+	Channel string
+	// Private is true if the Message was encrypted with the SessionKey of the Contact that sent it.
+	Private bool
+	// Status is how far a Message that the Device sent has got. It is empty for Messages from other devices.
+	Status MessageStatus
+}
+
+// DisplayText returns the Text of the Message, with an indicator if it was Truncated, others in front if it is an emergency or was Overheard, and how many hops it took if it was relayed.
+func (m Message) DisplayText() string {
+	text := m.Text
+	if m.Truncated {
+		text += "..."
+	}
+	if m.Priority == PriorityEmergency {
+		text = "!! " + text
+	}
+	if m.Overheard {
+		text = "(to " + strconv.Itoa(m.To) + ") " + text
+	}
+	if m.Hops == 1 {
+		text += " (via 1 hop)"
+	} else if m.Hops > 1 {
+		text += " (via " + strconv.Itoa(m.Hops) + " hops)"
+	}
+	switch m.Status {
+	case MessageStatusQueued:
+		text += " (sending)"
+	case MessageStatusFailed:
+		text += " (not sent)"
+	}
+	return text
 }
 
 // State is the current state of the device. It contains all the information about what is currently being displayed.
@@ -59,7 +298,20 @@ type State struct {
 	Title                string
 	Content              []MenuItem
 	HighlightedItemIndex int
-	LoadAction           func(d *Device) (err error)
+	// LoadAction is run every time the Device changes to the State.
+	LoadAction func(d *Device) (err error)
+	// OnExit is run when the Device changes from the State to a different one.
+	OnExit func(d *Device) (err error)
+	// OnTick is run by every Tick of the Device while the State is shown, so that it can update itself.
+	OnTick func(d *Device, now time.Time) (err error)
+	// Draw draws the whole screen of a State that is not a menu, such as a game.
+	Draw func(d *Device, img Canvas, layout Layout) (err error)
+	// InputHandler sees every InputEvent before the Device does. It returns true if it has used the InputEvent.
+	InputHandler func(d *Device, inputEvent InputEvent) (handled bool, err error)
+	// TwoLineItems draws the Detail of each MenuItem on a second line under its Text.
+	TwoLineItems bool
+	// LeftRight is what left and right do in the State when its InputHandler does not use them.
+	LeftRight LeftRight
 }
 
 // MenuItem is a structure that holds data that can be displayed on the screen. It contains a title and an action that is run when the item is selected.
@@ -68,10 +320,16 @@ type MenuItem struct {
 	Action        func(d *Device) (err error)
 	GetCursorData func(d *Device) (data any, err error)
 	CursorIcon    CursorIcon
+	// Icon is drawn to the left of the text, if it is set.
+	Icon *Icon
+	// Detail is a line of small text under the Text, only drawn if the State has TwoLineItems.
+	Detail string
+	// Widget shows a setting at the end of the line, and changes it with left and right. Accept changes it too, if there is no Action.
+	Widget MenuWidget
 }
 
 // CursorIcon is a function that draws a cursor icon based on the data at a location.
-type CursorIcon func(img *image.RGBA, x int, y int, data any) (err error)
+type CursorIcon func(img Canvas, x int, y int, data any) (err error)
 
 // LEDAnimation is a structure that holds information about an LED animation.
 type LEDAnimation struct {
@@ -87,7 +345,21 @@ var (
 	ErrRadioSendNotDefined                = errors.New("radio send function not defined by user")
 	ErrConversationReaderAcceptDisallowed = errors.New("cannot accept in conversation reader")
 	ErrGoBackStateRootState               = errors.New("already at root state")
+	ErrGoForwardStateNoState              = errors.New("no state to go forward to")
 	ErrInvalidMessage                     = errors.New("invalid message, prefix incorrect")
+	ErrConversationListenOnly             = errors.New("cannot send in a listen only conversation")
+	ErrMalformedMessage                   = errors.New("malformed message")
+	ErrPacketTooLong                      = errors.New("packet is too long")
+)
+
+// Define the limits of what is accepted from the radio. Anything longer is truncated.
+const (
+	// MaxPacketLength is the longest packet that a LoRa radio can receive, longer packets are rejected.
+	MaxPacketLength = 255
+	// MaxNameLength is the longest name of a Person that is kept.
+	MaxNameLength = 16
+	// MaxMessageTextLength is the longest message text that is kept.
+	MaxMessageTextLength = 200
 )
 
 // Define the Keyboard Buttons
@@ -112,44 +384,48 @@ var PersonYou = Person{"You", 0}
 // Define Cursors
 var (
 	// CursorIconRightArrow is a cursor that is a right arrow. It does not need any data.
-	CursorIconRightArrow = func(img *image.RGBA, x int, y int, data any) (err error) {
+	CursorIconRightArrow = func(img Canvas, x int, y int, data any) (err error) {
 		col := color.RGBA{255, 255, 255, 255}
-		img.Set(x+0, y+0, col)
-		img.Set(x+1, y+1, col)
-		img.Set(x+2, y+2, col)
-		img.Set(x+3, y+3, col)
-		img.Set(x+2, y+4, col)
-		img.Set(x+1, y+5, col)
-		img.Set(x+0, y+6, col)
+		img.SetRGBA(x+0, y+0, col)
+		img.SetRGBA(x+1, y+1, col)
+		img.SetRGBA(x+2, y+2, col)
+		img.SetRGBA(x+3, y+3, col)
+		img.SetRGBA(x+2, y+4, col)
+		img.SetRGBA(x+1, y+5, col)
+		img.SetRGBA(x+0, y+6, col)
 		return nil
 	}
 	// CursorIconLeftArrow is a cursor that is a left arrow. It does not need any data.
-	CursorIconLeftArrow = func(img *image.RGBA, x int, y int, data any) (err error) {
+	CursorIconLeftArrow = func(img Canvas, x int, y int, data any) (err error) {
 		col := color.RGBA{255, 255, 255, 255}
-		img.Set(x+6, y+0, col)
-		img.Set(x+5, y+1, col)
-		img.Set(x+4, y+2, col)
-		img.Set(x+3, y+3, col)
-		img.Set(x+4, y+4, col)
-		img.Set(x+5, y+5, col)
-		img.Set(x+6, y+6, col)
+		img.SetRGBA(x+6, y+0, col)
+		img.SetRGBA(x+5, y+1, col)
+		img.SetRGBA(x+4, y+2, col)
+		img.SetRGBA(x+3, y+3, col)
+		img.SetRGBA(x+4, y+4, col)
+		img.SetRGBA(x+5, y+5, col)
+		img.SetRGBA(x+6, y+6, col)
+		return nil
+	}
+	// CursorIconNone is a cursor that draws nothing. It is used for items that only show information. It does not need any data.
+	CursorIconNone = func(img Canvas, x int, y int, data any) (err error) {
 		return nil
 	}
 	// CursorIconBox is a cursor that is a box. It takes in a bool as data. If the bool is true, the box will be filled in. If the bool is false, the box will be empty.
-	CursorIconBox = func(img *image.RGBA, x int, y int, data any) (err error) {
+	CursorIconBox = func(img Canvas, x int, y int, data any) (err error) {
 		isChecked, ok := data.(bool)
 		if !ok {
 			return ErrCursorIconBoxBoolTypeError
 		}
 		for i := 0; i < 7; i++ {
 			for j := 0; j < 7; j++ {
-				img.Set(x+i, y+j, color.RGBA{255, 255, 255, 255})
+				img.SetRGBA(x+i, y+j, color.RGBA{255, 255, 255, 255})
 			}
 		}
 		if !isChecked {
 			for i := 1; i < 6; i++ {
 				for j := 1; j < 6; j++ {
-					img.Set(x+i, y+j, color.RGBA{0, 0, 0, 255})
+					img.SetRGBA(x+i, y+j, color.RGBA{0, 0, 0, 255})
 				}
 			}
 		}
@@ -192,7 +468,7 @@ var (
 	MainMenuItemConversations MenuItem = MenuItem{
 		Text: "Conversations",
 		Action: func(d *Device) (err error) {
-			err = d.ChangeStateWithHistory(&StateConversationsMenu)
+			err = d.ChangeStateWithHistory(&d.StateConversationsMenu)
 			if err != nil {
 				return err
 			}
@@ -200,13 +476,29 @@ var (
 		},
 
 		CursorIcon: CursorIconRightArrow,
+		Icon:       &IconConversations,
 	}
 
 	// MainMenuItemPeople is a MenuItem that goes to the People menu.
 	MainMenuItemPeople MenuItem = MenuItem{
 		Text: "People",
 		Action: func(d *Device) (err error) {
-			err = d.ChangeStateWithHistory(&StatePeopleMenu)
+			err = d.ChangeStateWithHistory(&d.StatePeopleMenu)
+			if err != nil {
+				return err
+			}
+			return nil
+		},
+
+		CursorIcon: CursorIconRightArrow,
+		Icon:       &IconPeople,
+	}
+
+	// MainMenuItemNotes is a MenuItem that goes to the Notes menu.
+	MainMenuItemNotes MenuItem = MenuItem{
+		Text: "Notes",
+		Action: func(d *Device) (err error) {
+			err = d.ChangeStateWithHistory(&d.StateNotesMenu)
 			if err != nil {
 				return err
 			}
@@ -214,13 +506,14 @@ var (
 		},
 
 		CursorIcon: CursorIconRightArrow,
+		Icon:       &IconNotes,
 	}
 
 	// MainMenuItemGames is a MenuItem that goes to the Games menu.
 	MainMenuItemGames MenuItem = MenuItem{
 		Text: "Games",
 		Action: func(d *Device) (err error) {
-			err = d.ChangeStateWithHistory(&StateGamesMenu)
+			err = d.ChangeStateWithHistory(&d.StateGamesMenu)
 			if err != nil {
 				return err
 			}
@@ -228,13 +521,14 @@ var (
 		},
 
 		CursorIcon: CursorIconRightArrow,
+		Icon:       &IconGames,
 	}
 
 	// MainMenuItemDemos is a MenuItem that goes to the Demos menu.
 	MainMenuItemDemos MenuItem = MenuItem{
 		Text: "Demo",
 		Action: func(d *Device) (err error) {
-			err = d.ChangeStateWithHistory(&StateDemosMenu)
+			err = d.ChangeStateWithHistory(&d.StateDemosMenu)
 			if err != nil {
 				return err
 			}
@@ -242,13 +536,15 @@ var (
 		},
 
 		CursorIcon: CursorIconRightArrow,
+		Icon:       &IconDemos,
 	}
 
 	// MainMenuItemTools is a MenuItem that goes to the Tools menu.
 	MainMenuItemTools MenuItem = MenuItem{
 		Text: "Tools",
 		Action: func(d *Device) (err error) {
-			err = d.ChangeStateWithHistory(&StateToolsMenu)
+			d.UpdateToolsMenu()
+			err = d.ChangeStateWithHistory(&d.StateToolsMenu)
 			if err != nil {
 				return err
 			}
@@ -256,13 +552,14 @@ var (
 		},
 
 		CursorIcon: CursorIconRightArrow,
+		Icon:       &IconTools,
 	}
 
 	// MainMenuItemSettings is a MenuItem that goes to the Settings menu.
 	MainMenuItemSettings MenuItem = MenuItem{
 		Text: "Settings",
 		Action: func(d *Device) (err error) {
-			err = d.ChangeStateWithHistory(&StateSettingsMenu)
+			err = d.ChangeStateWithHistory(&d.StateSettingsMenu)
 			if err != nil {
 				return err
 			}
@@ -270,10 +567,95 @@ var (
 		},
 
 		CursorIcon: CursorIconRightArrow,
+		Icon:       &IconSettings,
+	}
+
+	// Notes Menu Items
+
+	// NotesMenuItemNew is a MenuItem that starts writing a new Note.
+	NotesMenuItemNew MenuItem = MenuItem{
+		Text: "New Note",
+		Action: func(d *Device) (err error) {
+			d.clearPendingCharacter()
+			return d.ChangeStateWithHistory(&d.StateNoteEditor)
+		},
+		CursorIcon: CursorIconRightArrow,
+	}
+
+	// Quick Reply Menu Items
+
+	// QuickRepliesMenuItemEdit is a MenuItem that goes to the list of quick replies to change.
+	QuickRepliesMenuItemEdit MenuItem = MenuItem{
+		Text: "Edit Replies",
+		Action: func(d *Device) (err error) {
+			return d.ChangeStateWithHistory(&d.StateEditQuickRepliesMenu)
+		},
+		CursorIcon: CursorIconRightArrow,
+	}
+	// EditQuickRepliesMenuItemNew is a MenuItem that starts writing a new quick reply.
+	EditQuickRepliesMenuItemNew MenuItem = MenuItem{
+		Text: "New Reply",
+		Action: func(d *Device) (err error) {
+			d.quickReplies = quickRepliesState{Editing: -1}
+			return d.ChangeStateWithHistory(&d.StateQuickReplyEditor)
+		},
+		CursorIcon: CursorIconRightArrow,
+	}
+
+	// NoteMenuItemView is a MenuItem that shows the whole of the open Note.
+	NoteMenuItemView MenuItem = MenuItem{
+		Text: "View",
+		Action: func(d *Device) (err error) {
+			return d.ChangeStateWithHistory(&d.StateNoteViewer)
+		},
+		CursorIcon: CursorIconRightArrow,
+	}
+
+	// NoteMenuItemDelete is a MenuItem that deletes the open Note.
+	NoteMenuItemDelete MenuItem = MenuItem{
+		Text: "Delete",
+		Action: func(d *Device) (err error) {
+			err = d.DeleteNote(d.notes.Current)
+			if err != nil {
+				return err
+			}
+			return d.GoBackState()
+		},
+		CursorIcon: CursorIconRightArrow,
 	}
 
 	// Games Menu Items
 
+	// GamesMenuItemPong is a MenuItem that starts a game of Pong against another device over the radio.
+	GamesMenuItemPong MenuItem = MenuItem{
+		Text: "Pong",
+		Action: func(d *Device) (err error) {
+			d.StartPong(d.Now())
+			err = d.ChangeStateWithHistory(&d.StatePong)
+			if err != nil {
+				return err
+			}
+			return nil
+		},
+
+		CursorIcon: CursorIconRightArrow,
+	}
+
+	// GamesMenuItemTicTacToe is a MenuItem that updates and goes to the Tic-tac-toe menu, to invite someone to play or accept an invite.
+	GamesMenuItemTicTacToe MenuItem = MenuItem{
+		Text: "Tic-tac-toe",
+		Action: func(d *Device) (err error) {
+			d.UpdateTicTacToeMenu()
+			err = d.ChangeStateWithHistory(&d.StateTicTacToeMenu)
+			if err != nil {
+				return err
+			}
+			return nil
+		},
+
+		CursorIcon: CursorIconRightArrow,
+	}
+
 	// Demos Menu Items
 
 	// DemoMenuItemRGB is a MenuItem that toggles a demo of the RGB LEDs.
@@ -306,187 +688,1280 @@ var (
 	ToolsMenuItemSOS MenuItem = MenuItem{
 		Text: "SOS Mode",
 		Action: func(d *Device) (err error) {
-			if d.LEDAnimation != &LEDAnimationSOS {
-				err = d.ChangeLEDAnimationWithoutContinue(&LEDAnimationSOS)
-				if err != nil {
-					return err
-				}
-			} else {
-				err = d.ChangeLEDAnimationWithoutContinue(&LEDAnimationDefault)
-				if err != nil {
-					return err
-				}
+			return d.SetSOS(!d.sos.Active)
+		},
+
+		GetCursorData: func(d *Device) (data any, err error) {
+			return d.sos.Active, nil
+		},
+		CursorIcon: CursorIconBox,
+	}
+
+	// ToolsMenuItemSOSBroadcast is a MenuItem that toggles whether SOS mode also broadcasts SOS packets over the radio.
+	ToolsMenuItemSOSBroadcast MenuItem = MenuItem{
+		Text: "SOS Radio",
+		Action: func(d *Device) (err error) {
+			d.Settings.SOSBroadcast = !d.Settings.SOSBroadcast
+			return d.tickSOS(time.Now())
+		},
+
+		GetCursorData: func(d *Device) (data any, err error) {
+			return d.Settings.SOSBroadcast, nil
+		},
+		CursorIcon: CursorIconBox,
+	}
+
+	// ToolsMenuItemStrobe is a MenuItem that goes to the Strobe menu.
+	ToolsMenuItemStrobe MenuItem = MenuItem{
+		Text: "Strobe",
+		Action: func(d *Device) (err error) {
+			return d.ChangeStateWithHistory(&d.StateStrobeMenu)
+		},
+		CursorIcon: CursorIconRightArrow,
+	}
+
+	// ToolsMenuItemMorseLight is a MenuItem that goes to the Morse Light menu.
+	ToolsMenuItemMorseLight MenuItem = MenuItem{
+		Text: "Morse Light",
+		Action: func(d *Device) (err error) {
+			err = d.ChangeStateWithHistory(&d.StateMorseLightMenu)
+			if err != nil {
+				return err
 			}
 			return nil
 		},
 
+		CursorIcon: CursorIconRightArrow,
+	}
+
+	// ToolsMenuItemBeacon is a MenuItem that toggles periodically broadcasting a status and position beacon.
+	ToolsMenuItemBeacon MenuItem = MenuItem{
+		Text: "Beacon",
+		Action: func(d *Device) (err error) {
+			d.Settings.Beacon = !d.Settings.Beacon
+			return nil
+		},
+
 		GetCursorData: func(d *Device) (data any, err error) {
-			return d.LEDAnimation == &LEDAnimationSOS, nil
+			return d.Settings.Beacon, nil
 		},
 		CursorIcon: CursorIconBox,
 	}
-	// Conversation Menu Items
-	ConversationsMenuItemNew MenuItem = MenuItem{
-		Text: "New Conversation",
+
+	// ToolsMenuItemBeaconInterval is a MenuItem that goes to the Beacon Interval menu.
+	ToolsMenuItemBeaconInterval MenuItem = MenuItem{
+		Text: "Beacon Interval",
 		Action: func(d *Device) (err error) {
-			d.ChangeStateWithHistory(&StateNewConversation)
+			err = d.ChangeStateWithHistory(&d.StateBeaconIntervalMenu)
+			if err != nil {
+				return err
+			}
 			return nil
 		},
+
 		CursorIcon: CursorIconRightArrow,
 	}
-)
 
-// Define States
-var (
-	// StateDefault is a State that does nothing. It is used as a placeholder for the default State of the Device.
-	StateDefault = State{
-		Title:                "DefaultState",
-		Content:              []MenuItem{MenuItemDefault},
-		HighlightedItemIndex: 0,
+	// ToolsMenuItemHeardStations is a MenuItem that updates and goes to the Heard Stations menu.
+	ToolsMenuItemHeardStations MenuItem = MenuItem{
+		Text: "Heard Stations",
+		Action: func(d *Device) (err error) {
+			d.UpdateHeardStationsMenu()
+			err = d.ChangeStateWithHistory(&d.StateHeardStationsMenu)
+			if err != nil {
+				return err
+			}
+			return nil
+		},
+
+		CursorIcon: CursorIconRightArrow,
 	}
-	// StateConversationReader is a special State that is used when reading a Conversation.
-	StateConversationReader = State{
-		Title:   "",
-		Content: []MenuItem{},
+
+	// PeopleMenuItemShare is a MenuItem that goes to the list of Contacts that can be sent to other devices.
+	PeopleMenuItemShare MenuItem = MenuItem{
+		Text: "Share contact",
+		Action: func(d *Device) (err error) {
+			return d.ChangeStateWithHistory(&d.StateShareContactMenu)
+		},
+		CursorIcon: CursorIconRightArrow,
 	}
-	// StateMainMenu is a State that shows the main menu.
-	StateMainMenu = State{
-		Title:                "Main Menu",
-		Content:              []MenuItem{MainMenuItemConversations, MainMenuItemPeople, MainMenuItemGames, MainMenuItemDemos, MainMenuItemTools, MainMenuItemSettings},
-		HighlightedItemIndex: 0,
+
+	// PeopleMenuItemShared is a MenuItem that shows the Contacts that were shared by other devices. It is only in the people menu while there are some.
+	PeopleMenuItemShared MenuItem = MenuItem{
+		Text: "Shared with me",
+		Action: func(d *Device) (err error) {
+			return d.ChangeStateWithHistory(&d.StateContactOffer)
+		},
+		CursorIcon: CursorIconRightArrow,
 	}
-	// StateConversationsMenu is a State that shows the conversations menu.
-	StateConversationsMenu = State{
-		Title:                "Conversations",
-		Content:              []MenuItem{GlobalMenuItemGoBack, ConversationsMenuItemNew},
-		HighlightedItemIndex: 0,
+
+	// PeopleMenuItemNearby is a MenuItem that goes to the list of devices that have been heard directly.
+	PeopleMenuItemNearby MenuItem = MenuItem{
+		Text: "Nearby",
+		Action: func(d *Device) (err error) {
+			return d.ChangeStateWithHistory(&d.StateNearbyMenu)
+		},
+		CursorIcon: CursorIconRightArrow,
 	}
-	// StateConversationsMenuOld is a copy of StateConversationsMenu that can be used as a starting point to reset StateConversationsMenu.
-	StateConversationsMenuOld = StateConversationsMenu
-	// StateNewConversation is a special State that is used when creating a new Conversation.
-	StateNewConversation = State{
-		Title:                "New Conversation",
-		Content:              []MenuItem{GlobalMenuItemGoBack},
-		HighlightedItemIndex: 0,
+
+	// PeopleMenuItemPair is a MenuItem that starts pairing with a device nearby.
+	PeopleMenuItemPair MenuItem = MenuItem{
+		Text: "Pair device",
+		Action: func(d *Device) (err error) {
+			err = d.ChangeStateWithHistory(&d.StatePairing)
+			if err != nil {
+				return err
+			}
+			return d.StartPairing(d.Now())
+		},
+		CursorIcon: CursorIconRightArrow,
 	}
-	// StatePeopleMenu is a State that shows the people menu.
-	StatePeopleMenu = State{
-		Title:                "People",
-		Content:              []MenuItem{GlobalMenuItemGoBack},
-		HighlightedItemIndex: 0,
+
+	// ToolsMenuItemRangeTest is a MenuItem that starts the range test.
+	ToolsMenuItemRangeTest MenuItem = MenuItem{
+		Text: "Range Test",
+		Action: func(d *Device) (err error) {
+			d.StartRangeTest()
+			return d.ChangeStateWithHistory(&d.StateRangeTest)
+		},
+		CursorIcon: CursorIconRightArrow,
 	}
-	// StateGamesMenu is a State that shows the games menu.
-	StateGamesMenu = State{
-		Title:                "Games",
-		Content:              []MenuItem{GlobalMenuItemGoBack},
-		HighlightedItemIndex: 0,
+
+	// ToolsMenuItemChannelScanner is a MenuItem that starts sweeping the channels for background noise.
+	ToolsMenuItemChannelScanner MenuItem = MenuItem{
+		Text: "Channel Scan",
+		Action: func(d *Device) (err error) {
+			err = d.ChangeStateWithHistory(&d.StateChannelScanner)
+			if err != nil {
+				return err
+			}
+			return d.StartChannelScan(time.Now())
+		},
+		CursorIcon: CursorIconRightArrow,
 	}
-	// StateDemosMenu is a State that shows the demos menu.
-	StateDemosMenu = State{
-		Title:                "Demos",
-		Content:              []MenuItem{GlobalMenuItemGoBack, DemoMenuItemRGB},
-		HighlightedItemIndex: 0,
+
+	// ToolsMenuItemMonitor is a MenuItem that shows every packet that has been heard.
+	ToolsMenuItemMonitor MenuItem = MenuItem{
+		Text: "Packet Monitor",
+		Action: func(d *Device) (err error) {
+			d.monitorScroll = 0
+			return d.ChangeStateWithHistory(&d.StateMonitor)
+		},
+		CursorIcon: CursorIconRightArrow,
 	}
-	// StateToolsMenu is a State that shows the tools menu.
-	StateToolsMenu = State{
-		Title:                "Tools",
-		Content:              []MenuItem{GlobalMenuItemGoBack, ToolsMenuItemSOS},
-		HighlightedItemIndex: 0,
+
+	// ToolsMenuItemSendLocation is a MenuItem that broadcasts where the Device is.
+	ToolsMenuItemSendLocation MenuItem = MenuItem{
+		Text: "Send my location",
+		Action: func(d *Device) (err error) {
+			return d.SendLocation()
+		},
+		CursorIcon: CursorIconRightArrow,
 	}
-	// StateSettingsMenu is a State that shows the settings menu.
-	StateSettingsMenu = State{
-		Title:                "Settings",
-		Content:              []MenuItem{GlobalMenuItemGoBack},
-		HighlightedItemIndex: 0,
+
+	// ToolsMenuItemCompass is a MenuItem that shows a compass.
+	ToolsMenuItemCompass MenuItem = MenuItem{
+		Text: "Compass",
+		Action: func(d *Device) (err error) {
+			return d.ChangeStateWithHistory(&d.StateCompass)
+		},
+		CursorIcon: CursorIconRightArrow,
 	}
-)
 
-// Define LED animations. They are made of multiple frames of 6 colors.
-var (
-	// LEDAnimationDefault is the default LED animation. It is used when no other animation is active and is simply black.
-	LEDAnimationDefault = LEDAnimation{
-		FrameDuration: 100 * time.Millisecond,
-		CurrentFrame:  0,
-		Frames: [][6]color.RGBA{
-			{color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}},
+	// ToolsMenuItemScheduled is a MenuItem that goes to the list of messages waiting to be sent.
+	ToolsMenuItemScheduled MenuItem = MenuItem{
+		Text: "Scheduled",
+		Action: func(d *Device) (err error) {
+			return d.ChangeStateWithHistory(&d.StateScheduledMenu)
 		},
+		CursorIcon: CursorIconRightArrow,
 	}
-	// LEDAnimationSOS is an LED animation that shows the SOS message in morse code.
-	LEDAnimationSOS = LEDAnimation{
-		FrameDuration: 200 * time.Millisecond,
-		CurrentFrame:  0,
-		Frames: [][6]color.RGBA{
-			{color.RGBA{255, 255, 255, 255}, color.RGBA{255, 255, 255, 255}, color.RGBA{255, 255, 255, 255}, color.RGBA{255, 255, 255, 255}, color.RGBA{255, 255, 255, 255}, color.RGBA{255, 255, 255, 255}},
-			{color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}},
-			{color.RGBA{255, 255, 255, 255}, color.RGBA{255, 255, 255, 255}, color.RGBA{255, 255, 255, 255}, color.RGBA{255, 255, 255, 255}, color.RGBA{255, 255, 255, 255}, color.RGBA{255, 255, 255, 255}},
-			{color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}},
-			{color.RGBA{255, 255, 255, 255}, color.RGBA{255, 255, 255, 255}, color.RGBA{255, 255, 255, 255}, color.RGBA{255, 255, 255, 255}, color.RGBA{255, 255, 255, 255}, color.RGBA{255, 255, 255, 255}},
-			{color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}},
-			{color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}},
-			{color.RGBA{255, 255, 255, 255}, color.RGBA{255, 255, 255, 255}, color.RGBA{255, 255, 255, 255}, color.RGBA{255, 255, 255, 255}, color.RGBA{255, 255, 255, 255}, color.RGBA{255, 255, 255, 255}},
-			{color.RGBA{255, 255, 255, 255}, color.RGBA{255, 255, 255, 255}, color.RGBA{255, 255, 255, 255}, color.RGBA{255, 255, 255, 255}, color.RGBA{255, 255, 255, 255}, color.RGBA{255, 255, 255, 255}},
-			{color.RGBA{255, 255, 255, 255}, color.RGBA{255, 255, 255, 255}, color.RGBA{255, 255, 255, 255}, color.RGBA{255, 255, 255, 255}, color.RGBA{255, 255, 255, 255}, color.RGBA{255, 255, 255, 255}},
-			{color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}},
-			{color.RGBA{255, 255, 255, 255}, color.RGBA{255, 255, 255, 255}, color.RGBA{255, 255, 255, 255}, color.RGBA{255, 255, 255, 255}, color.RGBA{255, 255, 255, 255}, color.RGBA{255, 255, 255, 255}},
-			{color.RGBA{255, 255, 255, 255}, color.RGBA{255, 255, 255, 255}, color.RGBA{255, 255, 255, 255}, color.RGBA{255, 255, 255, 255}, color.RGBA{255, 255, 255, 255}, color.RGBA{255, 255, 255, 255}},
-			{color.RGBA{255, 255, 255, 255}, color.RGBA{255, 255, 255, 255}, color.RGBA{255, 255, 255, 255}, color.RGBA{255, 255, 255, 255}, color.RGBA{255, 255, 255, 255}, color.RGBA{255, 255, 255, 255}},
-			{color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}},
-			{color.RGBA{255, 255, 255, 255}, color.RGBA{255, 255, 255, 255}, color.RGBA{255, 255, 255, 255}, color.RGBA{255, 255, 255, 255}, color.RGBA{255, 255, 255, 255}, color.RGBA{255, 255, 255, 255}},
-			{color.RGBA{255, 255, 255, 255}, color.RGBA{255, 255, 255, 255}, color.RGBA{255, 255, 255, 255}, color.RGBA{255, 255, 255, 255}, color.RGBA{255, 255, 255, 255}, color.RGBA{255, 255, 255, 255}},
-			{color.RGBA{255, 255, 255, 255}, color.RGBA{255, 255, 255, 255}, color.RGBA{255, 255, 255, 255}, color.RGBA{255, 255, 255, 255}, color.RGBA{255, 255, 255, 255}, color.RGBA{255, 255, 255, 255}},
-			{color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}},
-			{color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}},
-			{color.RGBA{255, 255, 255, 255}, color.RGBA{255, 255, 255, 255}, color.RGBA{255, 255, 255, 255}, color.RGBA{255, 255, 255, 255}, color.RGBA{255, 255, 255, 255}, color.RGBA{255, 255, 255, 255}},
-			{color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}},
-			{color.RGBA{255, 255, 255, 255}, color.RGBA{255, 255, 255, 255}, color.RGBA{255, 255, 255, 255}, color.RGBA{255, 255, 255, 255}, color.RGBA{255, 255, 255, 255}, color.RGBA{255, 255, 255, 255}},
-			{color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}},
-			{color.RGBA{255, 255, 255, 255}, color.RGBA{255, 255, 255, 255}, color.RGBA{255, 255, 255, 255}, color.RGBA{255, 255, 255, 255}, color.RGBA{255, 255, 255, 255}, color.RGBA{255, 255, 255, 255}},
-			{color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}},
-			{color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}},
-			{color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}},
-			{color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}},
-			{color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}},
-			{color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}},
-			{color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}},
+
+	// ToolsMenuItemExport is a MenuItem that goes to the choice of formats to export the message history in.
+	ToolsMenuItemExport MenuItem = MenuItem{
+		Text: "Export History",
+		Action: func(d *Device) (err error) {
+			return d.ChangeStateWithHistory(&d.StateExportMenu)
 		},
+		CursorIcon: CursorIconRightArrow,
 	}
-	// LEDAnimationDemo is an LED animation that shows off the capabilities of the LED animation system.
-	LEDAnimationDemo = LEDAnimation{
-		FrameDuration: 1 * time.Millisecond,
-		CurrentFrame:  0,
-		Frames: [][6]color.RGBA{
-			{color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}},
-			{color.RGBA{0, 255, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}},
-			{color.RGBA{0, 0, 255, 0}, color.RGBA{0, 255, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}},
-			{color.RGBA{255, 0, 0, 0}, color.RGBA{0, 0, 255, 0}, color.RGBA{0, 255, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}},
-			{color.RGBA{0, 0, 255, 0}, color.RGBA{255, 0, 0, 0}, color.RGBA{0, 0, 255, 0}, color.RGBA{0, 255, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}},
-			{color.RGBA{255, 0, 0, 0}, color.RGBA{0, 0, 255, 0}, color.RGBA{255, 0, 0, 0}, color.RGBA{0, 0, 255, 0}, color.RGBA{0, 255, 0, 0}, color.RGBA{0, 0, 0, 0}},
-			{color.RGBA{0, 0, 255, 0}, color.RGBA{255, 0, 0, 0}, color.RGBA{0, 0, 255, 0}, color.RGBA{255, 0, 0, 0}, color.RGBA{0, 0, 255, 0}, color.RGBA{0, 255, 0, 0}},
-			{color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 255, 0}, color.RGBA{255, 0, 0, 0}, color.RGBA{0, 0, 255, 0}, color.RGBA{255, 0, 0, 0}, color.RGBA{0, 0, 255, 0}},
-			{color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 255, 0}, color.RGBA{255, 0, 0, 0}, color.RGBA{0, 0, 255, 0}, color.RGBA{255, 0, 0, 0}},
-			{color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 255, 0}, color.RGBA{255, 0, 0, 0}, color.RGBA{0, 0, 255, 0}},
-			{color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 255, 0}, color.RGBA{255, 0, 0, 0}},
-			{color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 255, 0}},
 
-			{color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}},
-			{color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}},
-			{color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}},
-			{color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}},
+	// ToolsMenuItemLastCrash is a MenuItem that shows the LastCrash. It is only in the Tools menu if there has been a crash.
+	ToolsMenuItemLastCrash MenuItem = MenuItem{
+		Text: "Last Crash",
+		Action: func(d *Device) (err error) {
+			return d.ChangeStateWithHistory(&d.StateCrashLog)
+		},
+		CursorIcon: CursorIconRightArrow,
+	}
 
-			{color.RGBA{50, 50, 50, 0}, color.RGBA{50, 50, 50, 0}, color.RGBA{50, 50, 50, 0}, color.RGBA{50, 50, 50, 0}, color.RGBA{50, 50, 50, 0}, color.RGBA{50, 50, 50, 0}},
-			{color.RGBA{100, 100, 100, 0}, color.RGBA{100, 100, 100, 0}, color.RGBA{100, 100, 100, 0}, color.RGBA{100, 100, 100, 0}, color.RGBA{100, 100, 100, 0}, color.RGBA{100, 100, 100, 0}},
-			{color.RGBA{150, 150, 150, 0}, color.RGBA{150, 150, 150, 0}, color.RGBA{150, 150, 150, 0}, color.RGBA{150, 150, 150, 0}, color.RGBA{150, 150, 150, 0}, color.RGBA{150, 150, 150, 0}},
-			{color.RGBA{200, 200, 200, 0}, color.RGBA{200, 200, 200, 0}, color.RGBA{200, 200, 200, 0}, color.RGBA{200, 200, 200, 0}, color.RGBA{200, 200, 200, 0}, color.RGBA{200, 200, 200, 0}},
-			{color.RGBA{255, 255, 255, 0}, color.RGBA{255, 255, 255, 0}, color.RGBA{255, 255, 255, 0}, color.RGBA{255, 255, 255, 0}, color.RGBA{255, 255, 255, 0}, color.RGBA{255, 255, 255, 0}},
-			{color.RGBA{200, 200, 200, 0}, color.RGBA{200, 200, 200, 0}, color.RGBA{200, 200, 200, 0}, color.RGBA{200, 200, 200, 0}, color.RGBA{200, 200, 200, 0}, color.RGBA{200, 200, 200, 0}},
-			{color.RGBA{150, 150, 150, 0}, color.RGBA{150, 150, 150, 0}, color.RGBA{150, 150, 150, 0}, color.RGBA{150, 150, 150, 0}, color.RGBA{150, 150, 150, 0}, color.RGBA{150, 150, 150, 0}},
-			{color.RGBA{100, 100, 100, 0}, color.RGBA{100, 100, 100, 0}, color.RGBA{100, 100, 100, 0}, color.RGBA{100, 100, 100, 0}, color.RGBA{100, 100, 100, 0}, color.RGBA{100, 100, 100, 0}},
-			{color.RGBA{50, 50, 50, 0}, color.RGBA{50, 50, 50, 0}, color.RGBA{50, 50, 50, 0}, color.RGBA{50, 50, 50, 0}, color.RGBA{50, 50, 50, 0}, color.RGBA{50, 50, 50, 0}},
+	// ToolsMenuItemSurvivalGuide is a MenuItem that goes to the list of Documents.
+	ToolsMenuItemSurvivalGuide MenuItem = MenuItem{
+		Text: "Survival Guide",
+		Action: func(d *Device) (err error) {
+			// The list is made here so that it includes any Documents added by the firmware.
+			d.StateSurvivalGuideMenu.Content = append([]MenuItem{GlobalMenuItemGoBack}, documentMenuItems()...)
+			d.StateSurvivalGuideMenu.HighlightedItemIndex = 0
+			return d.ChangeStateWithHistory(&d.StateSurvivalGuideMenu)
+		},
+		CursorIcon: CursorIconRightArrow,
+	}
 
+	// Settings Menu Items
+
+	// SettingsMenuItemRadio is a MenuItem that goes to the Radio menu.
+	SettingsMenuItemRadio MenuItem = MenuItem{
+		Text: "Radio",
+		Action: func(d *Device) (err error) {
+			err = d.ChangeStateWithHistory(&d.StateRadioMenu)
+			if err != nil {
+				return err
+			}
+			return nil
+		},
+
+		CursorIcon: CursorIconRightArrow,
+	}
+
+	// SettingsMenuItemName is a MenuItem that changes the name of the Device.
+	SettingsMenuItemName MenuItem = MenuItem{
+		Text: "Your Name",
+		Action: func(d *Device) (err error) {
+			d.nameDraft = d.SelfIdentity.Name
+			d.clearPendingCharacter()
+			return d.ChangeStateWithHistory(&d.StateNameEditor)
+		},
+		CursorIcon: CursorIconRightArrow,
+	}
+
+	// SettingsMenuItemGateway is a MenuItem that toggles gateway mode, where events are emitted to a connected base-station computer.
+	SettingsMenuItemGateway MenuItem = MenuItem{
+		Text: "Gateway Mode",
+		Widget: SettingToggle(func(d *Device) *bool {
+			return &d.Settings.Gateway
+		}),
+	}
+
+	// SettingsMenuItemInputMethod is a MenuItem that goes to the Input Method menu.
+	SettingsMenuItemInputMethod MenuItem = MenuItem{
+		Text: "Input Method",
+		Action: func(d *Device) (err error) {
+			err = d.ChangeStateWithHistory(&d.StateInputMethodMenu)
+			if err != nil {
+				return err
+			}
+			return nil
+		},
+
+		CursorIcon: CursorIconRightArrow,
+	}
+
+	// SettingsMenuItemKeyboardLayout is a MenuItem that updates and goes to the Keyboard Layout menu.
+	SettingsMenuItemKeyboardLayout MenuItem = MenuItem{
+		Text: "Keyboard Layout",
+		Action: func(d *Device) (err error) {
+			d.UpdateKeyboardLayoutMenu()
+			err = d.ChangeStateWithHistory(&d.StateKeyboardLayoutMenu)
+			if err != nil {
+				return err
+			}
+			return nil
+		},
+
+		CursorIcon: CursorIconRightArrow,
+	}
+
+	// SettingsMenuItemMultiTapTimeout is a MenuItem that chooses how long a multi-tap character is left before it is added to the text.
+	SettingsMenuItemMultiTapTimeout MenuItem = MenuItem{
+		Text:   "Multi-tap Timeout",
+		Widget: multiTapTimeoutSelector(),
+	}
+
+	// SettingsMenuItemSendKey is a MenuItem that goes to the Send Key menu.
+	SettingsMenuItemSendKey MenuItem = MenuItem{
+		Text: "Send Key",
+		Action: func(d *Device) (err error) {
+			return d.ChangeStateWithHistory(&d.StateSendKeyMenu)
+		},
+		CursorIcon: CursorIconRightArrow,
+	}
+
+	// SettingsMenuItemInverted is a MenuItem that toggles drawing dark text on a light background, which is easier to read in bright daylight.
+	SettingsMenuItemInverted MenuItem = MenuItem{
+		Text: "Invert Screen",
+		Widget: SettingToggle(func(d *Device) *bool {
+			return &d.Settings.Inverted
+		}),
+	}
+
+	// SettingsMenuItemScreensaver is a MenuItem that goes to the Screensaver menu.
+	SettingsMenuItemScreensaver MenuItem = MenuItem{
+		Text: "Screensaver",
+		Action: func(d *Device) (err error) {
+			return d.ChangeStateWithHistory(&d.StateScreensaverMenu)
+		},
+		CursorIcon: CursorIconRightArrow,
+	}
+
+	// SettingsMenuItemFunctionKeys is a MenuItem that goes to the Function Keys menu.
+	SettingsMenuItemFunctionKeys MenuItem = MenuItem{
+		Text: "Function Keys",
+		Action: func(d *Device) (err error) {
+			return d.ChangeStateWithHistory(&d.StateFunctionKeysMenu)
+		},
+		CursorIcon: CursorIconRightArrow,
+	}
+
+	// SettingsMenuItemPanicKey is a MenuItem that goes to the Panic Key menu.
+	SettingsMenuItemPanicKey MenuItem = MenuItem{
+		Text: "Panic Key",
+		Action: func(d *Device) (err error) {
+			return d.ChangeStateWithHistory(&d.StatePanicKeyMenu)
+		},
+		CursorIcon: CursorIconRightArrow,
+	}
+
+	// SettingsMenuItemSound is a MenuItem that goes to the Sound menu.
+	SettingsMenuItemSound MenuItem = MenuItem{
+		Text: "Sound",
+		Action: func(d *Device) (err error) {
+			return d.ChangeStateWithHistory(&d.StateSoundMenu)
+		},
+		CursorIcon: CursorIconRightArrow,
+	}
+
+	// SettingsMenuItemChannel is a MenuItem that goes to the Channel menu.
+	SettingsMenuItemChannel MenuItem = MenuItem{
+		Text: "Channel",
+		Action: func(d *Device) (err error) {
+			return d.ChangeStateWithHistory(&d.StateChannelMenu)
+		},
+		CursorIcon: CursorIconRightArrow,
+	}
+
+	// ChannelMenuItemNew is a MenuItem that starts adding a Channel.
+	ChannelMenuItemNew MenuItem = MenuItem{
+		Text: "New Channel",
+		Action: func(d *Device) (err error) {
+			d.channels = channelsState{}
+			return d.ChangeStateWithHistory(&d.StateChannelEditor)
+		},
+		CursorIcon: CursorIconRightArrow,
+	}
+
+	// SettingsMenuItemAway is a MenuItem that goes to the Away menu.
+	SettingsMenuItemAway MenuItem = MenuItem{
+		Text: "Away Mode",
+		Action: func(d *Device) (err error) {
+			return d.ChangeStateWithHistory(&d.StateAwayMenu)
+		},
+		CursorIcon: CursorIconRightArrow,
+	}
+
+	// AwayMenuItemAutoReply is a MenuItem that toggles answering every message automatically.
+	AwayMenuItemAutoReply MenuItem = MenuItem{
+		Text: "Auto Reply",
+		Action: func(d *Device) (err error) {
+			d.Settings.AutoReply = !d.Settings.AutoReply
+			return nil
+		},
+		GetCursorData: func(d *Device) (data any, err error) {
+			return d.Settings.AutoReply, nil
+		},
+		CursorIcon: CursorIconBox,
+	}
+
+	// AwayMenuItemEditReply is a MenuItem that changes the text of the automatic reply.
+	AwayMenuItemEditReply MenuItem = MenuItem{
+		Text: "Edit Reply",
+		Action: func(d *Device) (err error) {
+			d.autoReplyDraft = d.Settings.AutoReplyText
+			d.clearPendingCharacter()
+			return d.ChangeStateWithHistory(&d.StateAutoReplyEditor)
+		},
+		CursorIcon: CursorIconRightArrow,
+	}
+
+	// ScreensaverMenuItemDayCount is a MenuItem that starts counting days from now on the screensaver, or stops counting them.
+	ScreensaverMenuItemDayCount MenuItem = MenuItem{
+		Text: "Day Count",
+		Action: func(d *Device) (err error) {
+			if d.Settings.EventTime.IsZero() {
+				d.Settings.EventTime = d.Now()
+			} else {
+				d.Settings.EventTime = time.Time{}
+			}
+			return nil
+		},
+
+		GetCursorData: func(d *Device) (data any, err error) {
+			return !d.Settings.EventTime.IsZero(), nil
+		},
+		CursorIcon: CursorIconBox,
+	}
+
+	// SettingsMenuItemTextSize is a MenuItem that goes to the Text Size menu.
+	SettingsMenuItemTextSize MenuItem = MenuItem{
+		Text: "Text Size",
+		Action: func(d *Device) (err error) {
+			err = d.ChangeStateWithHistory(&d.StateTextSizeMenu)
+			if err != nil {
+				return err
+			}
+			return nil
+		},
+
+		CursorIcon: CursorIconRightArrow,
+	}
+
+	// SettingsMenuItemScanning is a MenuItem that toggles the scanning input mode, for using the Device with a single button.
+	SettingsMenuItemScanning MenuItem = MenuItem{
+		Text: "Scanning",
+		Widget: Toggle{
+			Get: func(d *Device) bool {
+				return d.Settings.Scanning
+			},
+			Set: func(d *Device, on bool) (err error) {
+				d.SetScanning(on)
+				return nil
+			},
+		},
+	}
+
+	// SettingsMenuItemAbout is a MenuItem that shows the version and diagnostics of the Device.
+	SettingsMenuItemAbout MenuItem = MenuItem{
+		Text: "About",
+		Action: func(d *Device) (err error) {
+			return d.ChangeStateWithHistory(&d.StateAbout)
+		},
+		CursorIcon: CursorIconRightArrow,
+	}
+
+	// SettingsMenuItemScanSpeed is a MenuItem that goes to the Scan Speed menu.
+	SettingsMenuItemScanSpeed MenuItem = MenuItem{
+		Text: "Scan Speed",
+		Action: func(d *Device) (err error) {
+			err = d.ChangeStateWithHistory(&d.StateScanSpeedMenu)
+			if err != nil {
+				return err
+			}
+			return nil
+		},
+
+		CursorIcon: CursorIconRightArrow,
+	}
+
+	// Radio Menu Items
+
+	// RadioMenuItemRegion is a MenuItem that goes to the Region menu.
+	RadioMenuItemRegion MenuItem = MenuItem{
+		Text: "Region",
+		Action: func(d *Device) (err error) {
+			return d.ChangeStateWithHistory(&d.StateRegionMenu)
+		},
+		CursorIcon: CursorIconRightArrow,
+	}
+
+	// RadioMenuItemTxPower is a MenuItem that changes the transmit power with left and right, and goes to the transmit power slider with the limits of the Region.
+	RadioMenuItemTxPower MenuItem = MenuItem{
+		Text: "TX Power",
+		Action: func(d *Device) (err error) {
+			return d.ChangeStateWithHistory(&d.StateTxPower)
+		},
+		Widget: Slider{
+			Min:  MinTxPower,
+			Max:  DefaultMaxTxPower,
+			Step: 1,
+			Unit: "dBm",
+			Get: func(d *Device) int {
+				return d.TxPower()
+			},
+			Set: func(d *Device, value int) (err error) {
+				return d.SetTxPower(value)
+			},
+		},
+	}
+
+	// RadioMenuItemFrequency is a MenuItem that goes to the Frequency menu.
+	RadioMenuItemFrequency MenuItem = MenuItem{
+		Text: "Frequency",
+		Action: func(d *Device) (err error) {
+			err = d.ChangeStateWithHistory(&d.StateFrequencyMenu)
+			if err != nil {
+				return err
+			}
+			return nil
+		},
+
+		CursorIcon: CursorIconRightArrow,
+	}
+
+	// RadioMenuItemSpreadingFactor is a MenuItem that goes to the Spreading Factor menu.
+	RadioMenuItemSpreadingFactor MenuItem = MenuItem{
+		Text: "Spreading Factor",
+		Action: func(d *Device) (err error) {
+			err = d.ChangeStateWithHistory(&d.StateSpreadingFactorMenu)
+			if err != nil {
+				return err
+			}
+			return nil
+		},
+
+		CursorIcon: CursorIconRightArrow,
+	}
+
+	// RadioMenuItemBandwidth is a MenuItem that goes to the Bandwidth menu.
+	RadioMenuItemBandwidth MenuItem = MenuItem{
+		Text: "Bandwidth",
+		Action: func(d *Device) (err error) {
+			err = d.ChangeStateWithHistory(&d.StateBandwidthMenu)
+			if err != nil {
+				return err
+			}
+			return nil
+		},
+
+		CursorIcon: CursorIconRightArrow,
+	}
+
+	// RadioMenuItemCodingRate is a MenuItem that goes to the Coding Rate menu.
+	RadioMenuItemCodingRate MenuItem = MenuItem{
+		Text: "Coding Rate",
+		Action: func(d *Device) (err error) {
+			err = d.ChangeStateWithHistory(&d.StateCodingRateMenu)
+			if err != nil {
+				return err
+			}
+			return nil
+		},
+
+		CursorIcon: CursorIconRightArrow,
+	}
+
+	// RadioMenuItemHopLimit is a MenuItem that chooses how many times messages can be relayed.
+	RadioMenuItemHopLimit MenuItem = MenuItem{
+		Text:   "Hop Limit",
+		Widget: hopLimitSelector(),
+	}
+
+	// RadioMenuItemDutyCycle is a MenuItem that goes to the duty cycle gauge.
+	RadioMenuItemDutyCycle MenuItem = MenuItem{
+		Text: "Duty Cycle",
+		Action: func(d *Device) (err error) {
+			return d.ChangeStateWithHistory(&d.StateDutyCycle)
+		},
+		CursorIcon: CursorIconRightArrow,
+	}
+
+	// RadioMenuItemLowPowerListen is a MenuItem that goes to the Low Power Listen menu.
+	RadioMenuItemLowPowerListen MenuItem = MenuItem{
+		Text: "Low Power Listen",
+		Action: func(d *Device) (err error) {
+			return d.ChangeStateWithHistory(&d.StateListenIntervalMenu)
+		},
+		CursorIcon: CursorIconRightArrow,
+	}
+
+	// RadioMenuItemOverhear is a MenuItem that toggles showing messages that were addressed to other devices.
+	RadioMenuItemOverhear MenuItem = MenuItem{
+		Text: "Show Overheard",
+		Widget: SettingToggle(func(d *Device) *bool {
+			return &d.Settings.Overhear
+		}),
+	}
+
+	// RadioMenuItemMeshtastic is a MenuItem that toggles sending and receiving messages in the Meshtastic format.
+	RadioMenuItemMeshtastic MenuItem = MenuItem{
+		Text: "Meshtastic",
+		Widget: SettingToggle(func(d *Device) *bool {
+			return &d.Settings.Meshtastic
+		}),
+	}
+
+	// Conversation Menu Items
+	ConversationsMenuItemNew MenuItem = MenuItem{
+		Text: "New Conversation",
+		Action: func(d *Device) (err error) {
+			d.ChangeStateWithHistory(&d.StateNewConversation)
+			return nil
+		},
+		CursorIcon: CursorIconRightArrow,
+	}
+
+	// Conversation Options Menu Items
+	ConversationOptionsMenuItemMute MenuItem = MenuItem{
+		Text: "Mute",
+		Action: func(d *Device) (err error) {
+			d.ToggleConversationMuted(d.Conversations[d.CurrentConversationIndex])
+			return nil
+		},
+		GetCursorData: func(d *Device) (data any, err error) {
+			return d.Conversations[d.CurrentConversationIndex].Muted, nil
+		},
+		CursorIcon: CursorIconBox,
+	}
+	// ConversationOptionsMenuItemRename is a MenuItem that renames the Person that a Conversation is with. Broadcast Conversations are not with anyone, so they cannot be renamed.
+	ConversationOptionsMenuItemRename MenuItem = MenuItem{
+		Text: "Rename",
+		Action: func(d *Device) (err error) {
+			c := d.Conversations[d.CurrentConversationIndex]
+			if c.Broadcast {
+				return nil
+			}
+			return d.startRename(c.Destination(), c.Name)
+		},
+		CursorIcon: CursorIconRightArrow,
+	}
+	ConversationOptionsMenuItemBlock MenuItem = MenuItem{
+		Text: "Block",
+		Action: func(d *Device) (err error) {
+			return d.ToggleConversationBlocked(d.Conversations[d.CurrentConversationIndex])
+		},
+		GetCursorData: func(d *Device) (data any, err error) {
+			c := d.Conversations[d.CurrentConversationIndex]
+			return !c.Broadcast && d.IsBlocked(c.Destination()), nil
+		},
+		CursorIcon: CursorIconBox,
+	}
+	// ConversationOptionsMenuItemListenOnly is a MenuItem that does what F1 does in the conversation reader by default, so that it can still be done when F1 is bound to something else.
+	ConversationOptionsMenuItemListenOnly MenuItem = MenuItem{
+		Text: FunctionKeyDefaults[0],
+		Action: func(d *Device) (err error) {
+			d.ToggleConversationListenOnly(d.Conversations[d.CurrentConversationIndex])
+			return nil
+		},
+		GetCursorData: func(d *Device) (data any, err error) {
+			return d.Conversations[d.CurrentConversationIndex].ListenOnly, nil
+		},
+		CursorIcon: CursorIconBox,
+	}
+	// ConversationOptionsMenuItemQuickReplies is a MenuItem that does what F2 does in the conversation reader by default. Nothing can be sent while listening only.
+	ConversationOptionsMenuItemQuickReplies MenuItem = MenuItem{
+		Text: FunctionKeyDefaults[1],
+		Action: func(d *Device) (err error) {
+			if d.Conversations[d.CurrentConversationIndex].ListenOnly {
+				return nil
+			}
+			d.StateQuickRepliesMenu.HighlightedItemIndex = 0
+			return d.ChangeStateWithHistory(&d.StateQuickRepliesMenu)
+		},
+		CursorIcon: CursorIconRightArrow,
+	}
+	// ConversationOptionsMenuItemEmergency is a MenuItem that does what F3 does in the conversation reader by default.
+	ConversationOptionsMenuItemEmergency MenuItem = MenuItem{
+		Text: FunctionKeyDefaults[2],
+		Action: func(d *Device) (err error) {
+			if d.Conversations[d.CurrentConversationIndex].ListenOnly {
+				return nil
+			}
+			d.ToggleComposerPriority(d.Conversations[d.CurrentConversationIndex])
+			return nil
+		},
+		GetCursorData: func(d *Device) (data any, err error) {
+			return d.Conversations[d.CurrentConversationIndex].Priority == PriorityEmergency, nil
+		},
+		CursorIcon: CursorIconBox,
+	}
+	// ConversationOptionsMenuItemPin is a MenuItem that does what F4 does in the conversation reader by default, to the Message that was last highlighted.
+	ConversationOptionsMenuItemPin MenuItem = MenuItem{
+		Text: FunctionKeyDefaults[3],
+		Action: func(d *Device) (err error) {
+			d.TogglePinnedMessage(d.Conversations[d.CurrentConversationIndex])
+			return nil
+		},
+		GetCursorData: func(d *Device) (data any, err error) {
+			return d.Conversations[d.CurrentConversationIndex].Pinned != nil, nil
+		},
+		CursorIcon: CursorIconBox,
+	}
+)
+
+// States are the screens of a Device. Every Device has its own States, made by newStates, so that Devices in the same program do not change each other's menus.
+type States struct {
+	// StateDefault is a State that does nothing. It is used as a placeholder for the default State of the Device.
+	StateDefault State
+	// StateConversationReader is a special State that is used when reading a Conversation.
+	StateConversationReader State
+	// StateMainMenu is a State that shows the main menu.
+	StateMainMenu State
+	// StateConversationsMenu is a State that shows the conversations menu.
+	StateConversationsMenu State
+	// StateConversationsMenuOld is a copy of StateConversationsMenu that can be used as a starting point to reset StateConversationsMenu.
+	StateConversationsMenuOld State
+	// StateNewConversation is a special State that is used when creating a new Conversation.
+	StateNewConversation State
+	// StatePeopleMenu is a State that shows the people menu.
+	StatePeopleMenu State
+	// StateNearbyMenu is a State that lists the devices that have been heard directly, with how strongly and how long ago.
+	StateNearbyMenu State
+	// StateNeighbor is a State that shows how well a Neighbor has been heard.
+	StateNeighbor State
+	// StateChannelScanner is a State that shows how busy each channel is, so that a quiet one can be chosen.
+	StateChannelScanner State
+	// StateNotesMenu is a State that lists the Notes.
+	StateNotesMenu State
+	// StateNoteMenu is a State that shows what can be done with a Note.
+	StateNoteMenu State
+	// StateNoteEditor is a State that shows a Note being written.
+	StateNoteEditor State
+	// StateQuickRepliesMenu is a State that lists the quick replies that can be sent to the current Conversation.
+	StateQuickRepliesMenu State
+	// StateEditQuickRepliesMenu is a State that lists the quick replies to change.
+	StateEditQuickRepliesMenu State
+	// StateQuickReplyEditor is a State that shows a quick reply being written.
+	StateQuickReplyEditor State
+	// StateScheduleMenu is a State that shows when the message being composed can be sent.
+	StateScheduleMenu State
+	// StateScheduledMenu is a State that lists the messages waiting to be sent. Choosing one cancels it.
+	StateScheduledMenu State
+	// StateNoteViewer is a State that shows the whole of a Note.
+	StateNoteViewer State
+	// StateGamesMenu is a State that shows the games menu.
+	StateGamesMenu State
+	// StateDemosMenu is a State that shows the demos menu.
+	StateDemosMenu State
+	// StateExportMenu is a State that shows the formats that the message history can be exported in.
+	StateExportMenu State
+	// StateToolsMenu is a State that shows the tools menu.
+	StateToolsMenu State
+	// StateToolsMenuOld is a copy of StateToolsMenu that can be used as a starting point to reset StateToolsMenu.
+	StateToolsMenuOld State
+	// StateSensor is a State that shows the value of a Sensor.
+	StateSensor State
+	// StateSurvivalGuideMenu is a State that lists the Documents that can be read.
+	StateSurvivalGuideMenu State
+	// StateCompass is a State that shows a compass rose.
+	StateCompass State
+	// StateEmergencyAlert is a State that fills the screen with an emergency Message received from another device.
+	StateEmergencyAlert State
+	// StateSOSAlert is a State that fills the screen with an SOS received from another device.
+	StateSOSAlert State
+	// StateRangeTest is a State that pings other devices and shows how many reply.
+	StateRangeTest State
+	// StatePairing is a State that shows the confirmation code while pairing with another device.
+	StatePairing State
+	// StateShareContactMenu is a State that lists the Contacts that can be sent to other devices.
+	StateShareContactMenu State
+	// StateSymbolPicker is a State that shows a grid of symbols to add to the message being composed.
+	StateSymbolPicker State
+	// StateContactOffer is a State that shows a Contact that was shared by another device, so that it can be added.
+	StateContactOffer State
+	// StateMonitor is a State that lists every packet that has been heard.
+	StateMonitor State
+	// StateDocumentViewer is a State that shows a Document.
+	StateDocumentViewer State
+	// StateMorseLightMenu is a State that shows the messages that can be flashed in Morse code on the RGB LEDs.
+	StateMorseLightMenu State
+	// StateStrobeMenu is a State that shows how often the strobe can flash, and which is flashing.
+	StateStrobeMenu State
+	// StateBeaconIntervalMenu is a State that shows how often a beacon can be broadcast.
+	StateBeaconIntervalMenu State
+	// StateHeardStationsMenu is a State that lists the stations that beacons have been heard from.
+	StateHeardStationsMenu State
+	// StatePong is a State that shows a game of Pong.
+	StatePong State
+	// StateTicTacToeMenu is a State that lists the people that tic-tac-toe can be played with.
+	StateTicTacToeMenu State
+	// StateTicTacToe is a State that shows a game of tic-tac-toe.
+	StateTicTacToe State
+	// StateSettingsMenu is a State that shows the settings menu.
+	StateSettingsMenu State
+	// StateSafeModeOffer is a State that is shown when the Device starts after a crash, to ask whether to start in safe mode.
+	StateSafeModeOffer State
+	// StateCrashLog is a State that shows the LastCrash.
+	StateCrashLog State
+	// StateAbout is a State that shows the version and diagnostics of the Device.
+	StateAbout State
+	// StateDutyCycle is a State that shows how much of the DutyCycle has been used in the last hour.
+	StateDutyCycle State
+	// StateTxPower is a State that shows the transmit power as a slider that can be moved.
+	StateTxPower State
+	// StateChannelMenu is a State that lists the Channels to start new Conversations on.
+	StateChannelMenu State
+	// StateChannelEditor is a State that shows the name and passphrase of a new Channel being written.
+	StateChannelEditor State
+	// StateAwayMenu is a State that shows the options of away mode.
+	StateAwayMenu State
+	// StateAutoReplyEditor is a State that shows the automatic reply being typed.
+	StateAutoReplyEditor State
+	// StateRenameEditor is a State that shows the new name of another Person being typed.
+	StateRenameEditor State
+	// StateNameEditor is a State that shows the name of the Device being typed.
+	StateNameEditor State
+	// StateScanSpeedMenu is a State that shows how long each item can stay highlighted while scanning.
+	StateScanSpeedMenu State
+	// StateInputMethodMenu is a State that shows the ways that text can be typed.
+	StateInputMethodMenu State
+	// StateFunctionKeysMenu is a State that shows what each function key does. Its Content is filled in by UpdateFunctionKeysMenu.
+	StateFunctionKeysMenu State
+	// StateFunctionKeyMenu is a State that chooses what a function key does. Its Content is filled in by UpdateFunctionKeyMenu.
+	StateFunctionKeyMenu State
+	// StatePanicKeyMenu is a State that shows which key starts SOS mode when it is pressed quickly PanicPresses times.
+	StatePanicKeyMenu State
+	// StateSoundMenu is a State that shows which Tones are played on the buzzer.
+	StateSoundMenu State
+	// StateScreensaverMenu is a State that shows when the screensaver starts, and whether it counts days.
+	StateScreensaverMenu State
+	// StateSplash is a State that shows the logo and version of the Device while it starts, before going on to the main menu.
+	StateSplash State
+	// StateScreensaver is a State that shows a large clock while the Device is not being used.
+	StateScreensaver State
+	// StateTextSizeMenu is a State that shows the sizes that text can be drawn in.
+	StateTextSizeMenu State
+	// StateConversationOptionsMenu is a State that shows the options of the current Conversation.
+	StateConversationOptionsMenu State
+	// StateSendKeyMenu is a State that shows the keys that can send a message.
+	StateSendKeyMenu State
+	// StateKeyboardLayoutMenu is a State that shows the KeyboardLayouts that can be used.
+	StateKeyboardLayoutMenu State
+	// StateRadioMenu is a State that shows the settings of the radio.
+	StateRadioMenu State
+	// StateListenIntervalMenu is a State that shows how long the radio can sleep for between listening.
+	StateListenIntervalMenu State
+	// StateRegionMenu is a State that shows the Regions whose radio rules can be followed.
+	StateRegionMenu State
+	// StateFrequencyMenu is a State that shows the frequency presets that the radio can use.
+	StateFrequencyMenu State
+	// StateSpreadingFactorMenu is a State that shows the spreading factors that the radio can use.
+	StateSpreadingFactorMenu State
+	// StateBandwidthMenu is a State that shows the bandwidths that the radio can use.
+	StateBandwidthMenu State
+	// StateCodingRateMenu is a State that shows the coding rates that the radio can use.
+	StateCodingRateMenu State
+}
+
+// stateSetups finish setting up the States of a new Device, after newStates has made them. Each feature that needs to change its States adds one with init.
+var stateSetups []func(s *States)
+
+// newStates makes the States of a new Device.
+func newStates() (s States) {
+	s = States{
+		StateDefault: State{
+			Title:                "DefaultState",
+			Content:              []MenuItem{MenuItemDefault},
+			HighlightedItemIndex: 0,
+		},
+		StateConversationReader: State{
+			Title:   "",
+			Content: []MenuItem{},
+		},
+		StateMainMenu: State{
+			Title:                "Main Menu",
+			Content:              []MenuItem{MainMenuItemConversations, MainMenuItemPeople, MainMenuItemNotes, MainMenuItemGames, MainMenuItemDemos, MainMenuItemTools, MainMenuItemSettings},
+			HighlightedItemIndex: 0,
+		},
+		StateConversationsMenu: State{
+			Title:                "Conversations",
+			Content:              []MenuItem{GlobalMenuItemGoBack, ConversationsMenuItemNew},
+			HighlightedItemIndex: 0,
+			TwoLineItems:         true,
+			LeftRight:            LeftRightPage,
+		},
+		StateNewConversation: State{
+			Title:                "New Conversation",
+			Content:              []MenuItem{GlobalMenuItemGoBack},
+			HighlightedItemIndex: 0,
+		},
+		StatePeopleMenu: State{
+			Title:                "People",
+			Content:              []MenuItem{GlobalMenuItemGoBack},
+			HighlightedItemIndex: 0,
+			LeftRight:            LeftRightPage,
+		},
+		StateNearbyMenu: State{
+			Title:                "Nearby",
+			Content:              []MenuItem{GlobalMenuItemGoBack},
+			HighlightedItemIndex: 0,
+			LeftRight:            LeftRightPage,
+		},
+		StateNeighbor: State{
+			Title:        "Neighbor",
+			Draw:         drawNeighbor,
+			InputHandler: processNeighborInputEvent,
+		},
+		StateChannelScanner: State{
+			Title:        "Channel Scan",
+			Draw:         drawChannelScanner,
+			InputHandler: processChannelScannerInputEvent,
+			OnTick:       (*Device).tickChannelScan,
+			OnExit:       (*Device).stopChannelScan,
+		},
+		StateNotesMenu: State{
+			Title:                "Notes",
+			Content:              []MenuItem{GlobalMenuItemGoBack},
+			HighlightedItemIndex: 0,
+			LeftRight:            LeftRightPage,
+		},
+		StateNoteMenu: State{
+			Title:                "Note",
+			Content:              []MenuItem{GlobalMenuItemGoBack, NoteMenuItemView, NoteMenuItemDelete},
+			HighlightedItemIndex: 0,
+		},
+		StateNoteEditor: State{
+			Title:        "New Note",
+			Draw:         drawNoteEditor,
+			InputHandler: processNoteEditorInputEvent,
+		},
+		StateQuickRepliesMenu: State{
+			Title:                "Quick Reply",
+			Content:              []MenuItem{GlobalMenuItemGoBack},
+			HighlightedItemIndex: 0,
+		},
+		StateEditQuickRepliesMenu: State{
+			Title:                "Edit Replies",
+			Content:              []MenuItem{GlobalMenuItemGoBack},
+			HighlightedItemIndex: 0,
+		},
+		StateQuickReplyEditor: State{
+			Title:        "Edit Reply",
+			Draw:         drawQuickReplyEditor,
+			InputHandler: processQuickReplyEditorInputEvent,
+		},
+		StateScheduleMenu: State{
+			Title:                "Send Later",
+			Content:              append([]MenuItem{GlobalMenuItemGoBack}, scheduleMenuItems()...),
+			HighlightedItemIndex: 0,
+		},
+		StateScheduledMenu: State{
+			Title:                "Scheduled",
+			Content:              []MenuItem{GlobalMenuItemGoBack},
+			HighlightedItemIndex: 0,
+		},
+		StateNoteViewer: State{
+			Title:        "Note",
+			Draw:         drawNoteViewer,
+			InputHandler: processNoteViewerInputEvent,
+		},
+		StateGamesMenu: State{
+			Title:                "Games",
+			Content:              []MenuItem{GlobalMenuItemGoBack, GamesMenuItemPong, GamesMenuItemTicTacToe},
+			HighlightedItemIndex: 0,
+		},
+		StateDemosMenu: State{
+			Title:                "Demos",
+			Content:              []MenuItem{GlobalMenuItemGoBack, DemoMenuItemRGB},
+			HighlightedItemIndex: 0,
+		},
+		StateExportMenu: State{
+			Title:                "Export History",
+			Content:              append([]MenuItem{GlobalMenuItemGoBack}, exportMenuItems()...),
+			HighlightedItemIndex: 0,
+		},
+		StateToolsMenu: State{
+			Title:                "Tools",
+			Content:              []MenuItem{GlobalMenuItemGoBack, ToolsMenuItemSOS, ToolsMenuItemSOSBroadcast, ToolsMenuItemMorseLight, ToolsMenuItemStrobe, ToolsMenuItemBeacon, ToolsMenuItemBeaconInterval, ToolsMenuItemHeardStations, ToolsMenuItemRangeTest, ToolsMenuItemChannelScanner, ToolsMenuItemMonitor, ToolsMenuItemSendLocation, ToolsMenuItemScheduled, ToolsMenuItemCompass, ToolsMenuItemExport, ToolsMenuItemSurvivalGuide},
+			HighlightedItemIndex: 0,
+		},
+		StateSensor: State{
+			Title:        "Sensor",
+			Draw:         drawSensor,
+			InputHandler: processSensorInputEvent,
+		},
+		StateSurvivalGuideMenu: State{
+			Title:                "Survival Guide",
+			Content:              []MenuItem{GlobalMenuItemGoBack},
+			HighlightedItemIndex: 0,
+		},
+		StateCompass: State{
+			Title:        "Compass",
+			Draw:         drawCompass,
+			InputHandler: processCompassInputEvent,
+		},
+		StateEmergencyAlert: State{
+			Title:        "Emergency",
+			Draw:         drawEmergencyAlert,
+			InputHandler: processEmergencyAlertInputEvent,
+		},
+		StateSOSAlert: State{
+			Title:        "SOS",
+			Draw:         drawSOSAlert,
+			InputHandler: processSOSAlertInputEvent,
+		},
+		StateRangeTest: State{
+			Title:        "Range Test",
+			Draw:         drawRangeTest,
+			InputHandler: processRangeTestInputEvent,
+			OnTick:       (*Device).tickRangeTest,
+		},
+		StatePairing: State{
+			Title:        "Pairing",
+			Draw:         drawPairing,
+			InputHandler: processPairingInputEvent,
+			OnTick:       (*Device).tickPairing,
+		},
+		StateShareContactMenu: State{
+			Title:                "Share Contact",
+			Content:              []MenuItem{GlobalMenuItemGoBack},
+			HighlightedItemIndex: 0,
+		},
+		StateSymbolPicker: State{
+			Title:        "Symbols",
+			Draw:         drawSymbolPicker,
+			InputHandler: processSymbolPickerInputEvent,
+		},
+		StateContactOffer: State{
+			Title:        "Shared Contact",
+			Draw:         drawContactOffer,
+			InputHandler: processContactOfferInputEvent,
+		},
+		StateMonitor: State{
+			Title:        "Packet Monitor",
+			Draw:         drawMonitor,
+			InputHandler: processMonitorInputEvent,
+		},
+		StateDocumentViewer: State{
+			Title:        "Document",
+			Draw:         drawDocumentViewer,
+			InputHandler: processDocumentViewerInputEvent,
+		},
+		StateMorseLightMenu: State{
+			Title:                "Morse Light",
+			Content:              append([]MenuItem{GlobalMenuItemGoBack}, morseLightMenuItems()...),
+			HighlightedItemIndex: 0,
+		},
+		StateStrobeMenu: State{
+			Title:                "Strobe",
+			Content:              append([]MenuItem{GlobalMenuItemGoBack}, strobeMenuItems()...),
+			HighlightedItemIndex: 0,
+		},
+		StateBeaconIntervalMenu: State{
+			Title:                "Beacon Interval",
+			Content:              append([]MenuItem{GlobalMenuItemGoBack}, beaconIntervalMenuItems()...),
+			HighlightedItemIndex: 0,
+		},
+		StateHeardStationsMenu: State{
+			Title:                "Heard Stations",
+			Content:              []MenuItem{GlobalMenuItemGoBack},
+			HighlightedItemIndex: 0,
+		},
+		StatePong: State{
+			Title:        "Pong",
+			Draw:         drawPong,
+			InputHandler: processPongInputEvent,
+			OnTick:       (*Device).tickPong,
+			OnExit:       (*Device).stopPong,
+		},
+		StateTicTacToeMenu: State{
+			Title:                "Tic-tac-toe",
+			Content:              []MenuItem{GlobalMenuItemGoBack},
+			HighlightedItemIndex: 0,
+		},
+		StateTicTacToe: State{
+			Title:        "Tic-tac-toe",
+			Draw:         drawTicTacToe,
+			InputHandler: processTicTacToeInputEvent,
+		},
+		StateSettingsMenu: State{
+			Title:                "Settings",
+			Content:              []MenuItem{GlobalMenuItemGoBack, SettingsMenuItemName, SettingsMenuItemRadio, SettingsMenuItemChannel, SettingsMenuItemGateway, SettingsMenuItemInputMethod, SettingsMenuItemKeyboardLayout, SettingsMenuItemMultiTapTimeout, SettingsMenuItemSendKey, SettingsMenuItemFunctionKeys, SettingsMenuItemPanicKey, SettingsMenuItemTextSize, SettingsMenuItemInverted, SettingsMenuItemScreensaver, SettingsMenuItemSound, SettingsMenuItemAway, SettingsMenuItemScanning, SettingsMenuItemScanSpeed, SettingsMenuItemAbout},
+			HighlightedItemIndex: 0,
+		},
+		StateSafeModeOffer: State{
+			Title:        "Crashed",
+			Draw:         drawSafeModeOffer,
+			InputHandler: processSafeModeOfferInputEvent,
+		},
+		StateCrashLog: State{
+			Title: "Last Crash",
+			Draw:  drawCrashLog,
+		},
+		StateAbout: State{
+			Title:        "About",
+			Draw:         drawAbout,
+			InputHandler: processAboutInputEvent,
+		},
+		StateDutyCycle: State{
+			Title:        "Duty Cycle",
+			Draw:         drawDutyCycle,
+			InputHandler: processDutyCycleInputEvent,
+		},
+		StateTxPower: State{
+			Title:        "TX Power",
+			Draw:         drawTxPower,
+			InputHandler: processTxPowerInputEvent,
+		},
+		StateChannelMenu: State{
+			Title:                "Channel",
+			Content:              []MenuItem{GlobalMenuItemGoBack},
+			HighlightedItemIndex: 0,
+		},
+		StateChannelEditor: State{
+			Title:        "New Channel",
+			Draw:         drawChannelEditor,
+			InputHandler: processChannelEditorInputEvent,
+		},
+		StateAwayMenu: State{
+			Title:                "Away Mode",
+			Content:              []MenuItem{GlobalMenuItemGoBack, AwayMenuItemAutoReply, AwayMenuItemEditReply},
+			HighlightedItemIndex: 0,
+		},
+		StateAutoReplyEditor: State{
+			Title:        "Away Reply",
+			Draw:         drawAutoReplyEditor,
+			InputHandler: processAutoReplyEditorInputEvent,
+		},
+		StateRenameEditor: State{
+			Title:        "Rename",
+			Draw:         drawRenameEditor,
+			InputHandler: processRenameEditorInputEvent,
+		},
+		StateNameEditor: State{
+			Title:        "Your Name",
+			Draw:         drawNameEditor,
+			InputHandler: processNameEditorInputEvent,
+		},
+		StateScanSpeedMenu: State{
+			Title:                "Scan Speed",
+			Content:              append([]MenuItem{GlobalMenuItemGoBack}, scanIntervalMenuItems()...),
+			HighlightedItemIndex: 0,
+		},
+		StateInputMethodMenu: State{
+			Title:                "Input Method",
+			Content:              append([]MenuItem{GlobalMenuItemGoBack}, inputMethodMenuItems()...),
+			HighlightedItemIndex: 0,
+		},
+		StateFunctionKeysMenu: State{
+			Title:                "Function Keys",
+			Content:              []MenuItem{GlobalMenuItemGoBack},
+			HighlightedItemIndex: 0,
+			TwoLineItems:         true,
+		},
+		StateFunctionKeyMenu: State{
+			Title:                "Function Key",
+			Content:              []MenuItem{GlobalMenuItemGoBack},
+			HighlightedItemIndex: 0,
+		},
+		StatePanicKeyMenu: NewMenu("Panic Key").AddItem(panicKeyMenuItems()...).State(),
+		StateSoundMenu:    NewMenu("Sound").AddItem(SoundMenuItemAlerts, SoundMenuItemMorse).State(),
+		StateScreensaverMenu: State{
+			Title:                "Screensaver",
+			Content:              append([]MenuItem{GlobalMenuItemGoBack, ScreensaverMenuItemDayCount}, screensaverTimeoutMenuItems()...),
+			HighlightedItemIndex: 0,
+		},
+		StateSplash: State{
+			Title:        "Doomsday Messenger",
+			Draw:         drawSplash,
+			InputHandler: processSplashInputEvent,
+			OnTick:       (*Device).tickSplash,
+		},
+		StateScreensaver: State{
+			Title:        "Screensaver",
+			Draw:         drawScreensaver,
+			InputHandler: processScreensaverInputEvent,
+		},
+		StateTextSizeMenu: State{
+			Title:                "Text Size",
+			Content:              append([]MenuItem{GlobalMenuItemGoBack}, textSizeMenuItems()...),
+			HighlightedItemIndex: 0,
+		},
+		StateConversationOptionsMenu: State{
+			Title:                "Options",
+			Content:              []MenuItem{GlobalMenuItemGoBack, ConversationOptionsMenuItemMute, ConversationOptionsMenuItemBlock, ConversationOptionsMenuItemRename, ConversationOptionsMenuItemListenOnly, ConversationOptionsMenuItemQuickReplies, ConversationOptionsMenuItemEmergency, ConversationOptionsMenuItemPin},
+			HighlightedItemIndex: 0,
+		},
+		StateSendKeyMenu: State{
+			Title:                "Send Key",
+			Content:              append([]MenuItem{GlobalMenuItemGoBack}, sendKeyMenuItems()...),
+			HighlightedItemIndex: 0,
+		},
+		StateKeyboardLayoutMenu: State{
+			Title:                "Keyboard Layout",
+			Content:              []MenuItem{GlobalMenuItemGoBack},
+			HighlightedItemIndex: 0,
+		},
+		StateRadioMenu: State{
+			Title:                "Radio",
+			Content:              []MenuItem{GlobalMenuItemGoBack, RadioMenuItemRegion, RadioMenuItemFrequency, RadioMenuItemSpreadingFactor, RadioMenuItemBandwidth, RadioMenuItemCodingRate, RadioMenuItemTxPower, RadioMenuItemHopLimit, RadioMenuItemDutyCycle, RadioMenuItemLowPowerListen, RadioMenuItemOverhear, RadioMenuItemMeshtastic},
+			HighlightedItemIndex: 0,
+		},
+		StateListenIntervalMenu: State{
+			Title:                "Low Power Listen",
+			Content:              append([]MenuItem{GlobalMenuItemGoBack}, listenIntervalMenuItems()...),
+			HighlightedItemIndex: 0,
+		},
+		StateRegionMenu: State{
+			Title:                "Region",
+			Content:              append([]MenuItem{GlobalMenuItemGoBack}, regionMenuItems()...),
+			HighlightedItemIndex: 0,
+		},
+		StateFrequencyMenu: State{
+			Title:                "Frequency",
+			Content:              append([]MenuItem{GlobalMenuItemGoBack}, frequencyMenuItems()...),
+			HighlightedItemIndex: 0,
+		},
+		StateSpreadingFactorMenu: State{
+			Title:                "Spreading Factor",
+			Content:              append([]MenuItem{GlobalMenuItemGoBack}, spreadingFactorMenuItems()...),
+			HighlightedItemIndex: 0,
+		},
+		StateBandwidthMenu: State{
+			Title:                "Bandwidth",
+			Content:              append([]MenuItem{GlobalMenuItemGoBack}, bandwidthMenuItems()...),
+			HighlightedItemIndex: 0,
+		},
+		StateCodingRateMenu: State{
+			Title:                "Coding Rate",
+			Content:              append([]MenuItem{GlobalMenuItemGoBack}, codingRateMenuItems()...),
+			HighlightedItemIndex: 0,
+		},
+	}
+	s.StateConversationsMenuOld = s.StateConversationsMenu
+	s.StateToolsMenuOld = s.StateToolsMenu
+	for _, setup := range stateSetups {
+		setup(&s)
+	}
+	return s
+}
+
+// Define LED animations. They are made of multiple frames of 6 colors.
+var (
+	// LEDAnimationDefault is the default LED animation. It is used when no other animation is active and is simply black.
+	LEDAnimationDefault = LEDAnimation{
+		FrameDuration: 100 * time.Millisecond,
+		CurrentFrame:  0,
+		Frames: [][6]color.RGBA{
+			{color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}},
+		},
+	}
+	// LEDAnimationSOS is an LED animation that shows the SOS message in morse code.
+	LEDAnimationSOS = *NewMorseLEDAnimation("SOS")
+	// LEDAnimationDemo is an LED animation that shows off the capabilities of the LED animation system.
+	LEDAnimationDemo = LEDAnimation{
+		FrameDuration: 1 * time.Millisecond,
+		CurrentFrame:  0,
+		Frames: [][6]color.RGBA{
+			{color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}},
+			{color.RGBA{0, 255, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}},
+			{color.RGBA{0, 0, 255, 0}, color.RGBA{0, 255, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}},
+			{color.RGBA{255, 0, 0, 0}, color.RGBA{0, 0, 255, 0}, color.RGBA{0, 255, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}},
+			{color.RGBA{0, 0, 255, 0}, color.RGBA{255, 0, 0, 0}, color.RGBA{0, 0, 255, 0}, color.RGBA{0, 255, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}},
+			{color.RGBA{255, 0, 0, 0}, color.RGBA{0, 0, 255, 0}, color.RGBA{255, 0, 0, 0}, color.RGBA{0, 0, 255, 0}, color.RGBA{0, 255, 0, 0}, color.RGBA{0, 0, 0, 0}},
+			{color.RGBA{0, 0, 255, 0}, color.RGBA{255, 0, 0, 0}, color.RGBA{0, 0, 255, 0}, color.RGBA{255, 0, 0, 0}, color.RGBA{0, 0, 255, 0}, color.RGBA{0, 255, 0, 0}},
+			{color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 255, 0}, color.RGBA{255, 0, 0, 0}, color.RGBA{0, 0, 255, 0}, color.RGBA{255, 0, 0, 0}, color.RGBA{0, 0, 255, 0}},
+			{color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 255, 0}, color.RGBA{255, 0, 0, 0}, color.RGBA{0, 0, 255, 0}, color.RGBA{255, 0, 0, 0}},
+			{color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 255, 0}, color.RGBA{255, 0, 0, 0}, color.RGBA{0, 0, 255, 0}},
+			{color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 255, 0}, color.RGBA{255, 0, 0, 0}},
+			{color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 255, 0}},
+
+			{color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}},
+			{color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}},
+			{color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}},
+			{color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}},
+
+			{color.RGBA{50, 50, 50, 0}, color.RGBA{50, 50, 50, 0}, color.RGBA{50, 50, 50, 0}, color.RGBA{50, 50, 50, 0}, color.RGBA{50, 50, 50, 0}, color.RGBA{50, 50, 50, 0}},
+			{color.RGBA{100, 100, 100, 0}, color.RGBA{100, 100, 100, 0}, color.RGBA{100, 100, 100, 0}, color.RGBA{100, 100, 100, 0}, color.RGBA{100, 100, 100, 0}, color.RGBA{100, 100, 100, 0}},
+			{color.RGBA{150, 150, 150, 0}, color.RGBA{150, 150, 150, 0}, color.RGBA{150, 150, 150, 0}, color.RGBA{150, 150, 150, 0}, color.RGBA{150, 150, 150, 0}, color.RGBA{150, 150, 150, 0}},
+			{color.RGBA{200, 200, 200, 0}, color.RGBA{200, 200, 200, 0}, color.RGBA{200, 200, 200, 0}, color.RGBA{200, 200, 200, 0}, color.RGBA{200, 200, 200, 0}, color.RGBA{200, 200, 200, 0}},
+			{color.RGBA{255, 255, 255, 0}, color.RGBA{255, 255, 255, 0}, color.RGBA{255, 255, 255, 0}, color.RGBA{255, 255, 255, 0}, color.RGBA{255, 255, 255, 0}, color.RGBA{255, 255, 255, 0}},
+			{color.RGBA{200, 200, 200, 0}, color.RGBA{200, 200, 200, 0}, color.RGBA{200, 200, 200, 0}, color.RGBA{200, 200, 200, 0}, color.RGBA{200, 200, 200, 0}, color.RGBA{200, 200, 200, 0}},
+			{color.RGBA{150, 150, 150, 0}, color.RGBA{150, 150, 150, 0}, color.RGBA{150, 150, 150, 0}, color.RGBA{150, 150, 150, 0}, color.RGBA{150, 150, 150, 0}, color.RGBA{150, 150, 150, 0}},
+			{color.RGBA{100, 100, 100, 0}, color.RGBA{100, 100, 100, 0}, color.RGBA{100, 100, 100, 0}, color.RGBA{100, 100, 100, 0}, color.RGBA{100, 100, 100, 0}, color.RGBA{100, 100, 100, 0}},
+			{color.RGBA{50, 50, 50, 0}, color.RGBA{50, 50, 50, 0}, color.RGBA{50, 50, 50, 0}, color.RGBA{50, 50, 50, 0}, color.RGBA{50, 50, 50, 0}, color.RGBA{50, 50, 50, 0}},
+
+			{color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}},
+			{color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}},
 			{color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}},
 			{color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}},
-			{color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}},
-			{color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}, color.RGBA{0, 0, 0, 0}},
 
 			{color.RGBA{50, 0, 0, 0}, color.RGBA{50, 0, 0, 0}, color.RGBA{50, 0, 0, 0}, color.RGBA{50, 0, 0, 0}, color.RGBA{50, 0, 0, 0}, color.RGBA{50, 0, 0, 0}},
 			{color.RGBA{100, 0, 0, 0}, color.RGBA{100, 0, 0, 0}, color.RGBA{100, 0, 0, 0}, color.RGBA{100, 0, 0, 0}, color.RGBA{100, 0, 0, 0}, color.RGBA{100, 0, 0, 0}},
@@ -538,10 +2013,9 @@ var (
 // NewDevice returns a new Device with default parameters.
 func NewDevice() (d *Device, err error) {
 	rand.Seed(time.Now().UnixNano())
-	PersonYou.ID = rand.Intn(2147483647) // Max value of an int32
-	return &Device{
-		State:                    &StateMainMenu,
-		StateHistory:             []*State{&StateMainMenu},
+	PersonYou.ID = rand.Intn(2147483646) + 1 // Max value of an int32, and never the BroadcastID
+	d = &Device{
+		States:                   newStates(),
 		LEDAnimation:             &LEDAnimationDefault,
 		Conversations:            []*Conversation{},
 		SelfIdentity:             PersonYou,
@@ -550,48 +2024,304 @@ func NewDevice() (d *Device, err error) {
 		SendUsingRadio: func(packet []byte) (err error) {
 			return ErrRadioSendNotDefined
 		},
-	}, nil
+		Settings: Settings{
+			FrequencyMHz:       FrequencyPresets[0].FrequencyMHz,
+			Modem:              DefaultModemConfig,
+			BeaconInterval:     BeaconIntervals[2],
+			InputMethod:        InputMethodMultiTap,
+			MorseKey:           InputEventNumber0,
+			SendKey:            InputEventAccept,
+			DutyCycle:          Regions[0].DutyCycle,
+			Region:             Regions[0].Name,
+			TxPower:            Regions[0].MaxTxPower,
+			KeyboardLayout:     KeyboardLayoutPhone.Name,
+			ScanInterval:       ScanIntervals[2],
+			ScreensaverTimeout: ScreensaverTimeouts[3],
+			MultiTapTimeout:    MultiTapTimeouts[2],
+			AlertTones:         true,
+			AutoReplyText:      DefaultAutoReplyText,
+		},
+		MeshtasticCodec: DefaultMeshtasticCodec,
+		Display:         DisplaySSD1306,
+		Theme:           ColorPalette,
+		QuickReplies:    append([]string{}, DefaultQuickReplies...),
+		started:         time.Now(),
+		dirty:           true,
+		InputTiming:     DefaultInputTiming,
+	}
+	// The States are made with the Device, so they can only be pointed to once it has been.
+	d.State = &d.StateSplash
+	d.StateHistory = []*State{&d.StateMainMenu}
+	return d, nil
 }
 
 // RecieveFromRadio takes in the payload of a radio packet, usually recieved from the RFM9x radio.
+// Packets that are too long or cannot be decoded are rejected and logged, and never change the Conversations.
 func (d *Device) ReceiveFromRadio(packetPayload []byte) (err error) {
-	payloadMessage, err := d.BytesToMessage(packetPayload)
+	d.Packets.Received++
+	d.MarkDirty()
+	// Everything that is heard is timed with the clock of the Device, the same as the rest of what it records.
+	now := d.Now()
+	d.monitorPacket(packetPayload)
+	if len(packetPayload) > MaxPacketLength {
+		return d.rejectPacket(packetPayload, ErrPacketTooLong)
+	}
+	// The hop header can be in front of any packet, and is changed by relays without touching what is behind it.
+	header, packetPayload, _, err := SplitHopHeader(packetPayload)
+	if err != nil {
+		return d.rejectPacket(packetPayload, err)
+	}
+
+	if bytes.HasPrefix(packetPayload, beaconPrefix) {
+		station, err := BytesToHeardStation(packetPayload)
+		if err != nil {
+			return d.rejectPacket(packetPayload, err)
+		}
+		if d.IsBlocked(station.Person.ID) {
+			return nil
+		}
+		d.heardNeighbor(station.Person, header.Hops, now)
+		return d.receiveBeacon(station, now)
+	}
+
+	if bytes.HasPrefix(packetPayload, gamePrefix) {
+		packet, err := BytesToGamePacket(packetPayload)
+		if err != nil {
+			return d.rejectPacket(packetPayload, err)
+		}
+		if d.IsBlocked(packet.Person.ID) {
+			return nil
+		}
+		d.heardNeighbor(packet.Person, header.Hops, now)
+		return d.receiveGamePacket(packet, now)
+	}
+
+	if bytes.HasPrefix(packetPayload, pingPrefix) || bytes.HasPrefix(packetPayload, pingReplyPrefix) {
+		ping, err := BytesToPing(packetPayload)
+		if err != nil {
+			return d.rejectPacket(packetPayload, err)
+		}
+		if d.IsBlocked(ping.From) {
+			return nil
+		}
+		d.heardNeighbor(Person{ID: ping.From}, header.Hops, now)
+		if bytes.HasPrefix(packetPayload, pingReplyPrefix) {
+			return d.receivePingReply(ping, now)
+		}
+		return d.receivePing(ping)
+	}
+
+	if bytes.HasPrefix(packetPayload, pairPrefix) {
+		request, err := BytesToPairingRequest(packetPayload)
+		if err != nil {
+			return d.rejectPacket(packetPayload, err)
+		}
+		if d.IsBlocked(request.Person.ID) {
+			return nil
+		}
+		d.heardNeighbor(request.Person, header.Hops, now)
+		return d.receivePairingRequest(request, now)
+	}
+
+	if bytes.HasPrefix(packetPayload, cardPrefix) {
+		contact, err := BytesToContact(packetPayload)
+		if err != nil {
+			return d.rejectPacket(packetPayload, err)
+		}
+		if d.IsBlocked(contact.Person.ID) {
+			return nil
+		}
+		return d.receiveContactCard(contact, d.Now())
+	}
+
+	if bytes.HasPrefix(packetPayload, sosPrefix) {
+		alert, err := BytesToSOSAlert(packetPayload)
+		if err != nil {
+			return d.rejectPacket(packetPayload, err)
+		}
+		if d.IsBlocked(alert.Person.ID) {
+			return nil
+		}
+		d.heardNeighbor(alert.Person, header.Hops, now)
+		return d.receiveSOS(alert, now)
+	}
+
+	if bytes.HasPrefix(packetPayload, locationPrefix) {
+		locationMessage, err := BytesToLocationMessage(packetPayload)
+		if err != nil {
+			return d.rejectPacket(packetPayload, err)
+		}
+		if d.IsBlocked(locationMessage.Person.ID) {
+			return nil
+		}
+		d.heardNeighbor(locationMessage.Person, header.Hops, now)
+		locationMessage.Hops, locationMessage.TTL = header.Hops, header.TTL
+		return d.receiveMessage(locationMessage)
+	}
+
+	payloadMessage, err := d.DecodeMessage(packetPayload)
+	if err != nil {
+		return d.rejectPacket(packetPayload, err)
+	}
+	if d.IsBlocked(payloadMessage.Person.ID) {
+		return nil
+	}
+	d.heardNeighbor(payloadMessage.Person, header.Hops, now)
+	payloadMessage.Hops, payloadMessage.TTL = header.Hops, header.TTL
+	return d.receiveMessage(payloadMessage)
+}
+
+// receiveMessage puts a Message from another device into a Conversation.
+func (d *Device) receiveMessage(payloadMessage Message) (err error) {
+	// Messages for other devices are dropped, unless the Settings ask for them to be shown.
+	if !d.AddressedToDevice(payloadMessage) {
+		if !d.Settings.Overhear {
+			return nil
+		}
+		payloadMessage.Overheard = true
+	}
+	err = d.heardGatewayNode(payloadMessage.Person, time.Now())
+	if err != nil {
+		return err
+	}
+	err = d.EmitGatewayEvent(GatewayEvent{Type: GatewayEventMessage, PersonID: payloadMessage.Person.ID, PersonName: payloadMessage.Person.Name, Text: payloadMessage.Text})
+	if err != nil {
+		return err
+	}
+
+	// Broadcasts are kept together, and messages from the same Person are kept together, with only the first starting a new Conversation. Each Channel has its own Conversations.
+	index := d.conversationWith(payloadMessage.Person.ID, payloadMessage.Channel)
+	if payloadMessage.Broadcast {
+		index = d.broadcastConversation(payloadMessage.Channel)
+	} else if index < 0 {
+		c := d.NewConversation(payloadMessage.Person)
+		c.Name = d.PersonName(payloadMessage.Person.ID)
+		c.Channel = payloadMessage.Channel
+		index = len(d.Conversations) - 1
+	}
+	conversation := d.Conversations[index]
+	following := conversation.HighlightedMessageIndex >= len(conversation.Messages)-1
+	conversation.Messages = append(conversation.Messages, payloadMessage)
+	if !conversation.Muted && (d.State != &d.StateConversationReader || d.CurrentConversationIndex != index) {
+		conversation.Unread++
+		d.notifiedConversation = conversation
+	}
+	// The newest message is only scrolled to if the older messages are not being read.
+	if following {
+		conversation.HighlightedMessageIndex = len(conversation.Messages) - 1
+	}
+
+	d.UpdateConversationsMenu()
+	err = d.syncMessage(index, payloadMessage)
+	if err != nil {
+		return err
+	}
+	if d.OnMessageReceived != nil {
+		d.OnMessageReceived(conversation, payloadMessage)
+	}
+	err = d.autoReply(conversation, payloadMessage.Person, time.Now())
 	if err != nil {
 		return err
 	}
+	if payloadMessage.Priority == PriorityEmergency {
+		return d.receiveEmergency(payloadMessage)
+	}
+	if conversation.Muted {
+		return nil
+	}
+	return d.Notify(DeviceEventMessageReceived)
+}
 
-	newConversation := d.NewConversation(payloadMessage.Person)
-	newConversation.Messages = append(newConversation.Messages, payloadMessage)
-	newConversation.HighlightedMessageIndex = len(newConversation.Messages) - 1
-	newConversation.Name = fmt.Sprint(payloadMessage.Person.ID)
-
-	d.UpdateConversationsMenu()
-	return nil
+// rejectPacket logs that a packet was not accepted, and returns the reason.
+func (d *Device) rejectPacket(packetPayload []byte, reason error) (err error) {
+	d.Packets.Rejected++
+	if len(d.MonitoredPackets) > 0 {
+		d.MonitoredPackets[len(d.MonitoredPackets)-1].Decoded = false
+	}
+	if d.Log != nil {
+		d.Log(fmt.Sprintf("rejected %d byte packet: %v", len(packetPayload), reason))
+	}
+	return reason
 }
 
-// NewConversation creates a blank new Conversation with a person and adds it to the Device. It also returns a pointer to that Conversation.
+// NewConversation creates a blank new Conversation with a person on the Channel in the Settings, and adds it to the Device. It also returns a pointer to that Conversation.
 func (d *Device) NewConversation(p Person) (c *Conversation) {
-	newConversation := &Conversation{People: []Person{d.SelfIdentity, p}}
+	newConversation := &Conversation{People: []Person{d.SelfIdentity, p}, Channel: d.Settings.Channel}
 	d.Conversations = append(d.Conversations, newConversation)
 	return newConversation
 }
 
+// ConversationWith returns the index of the Conversation between the Device and the Person with an ID on the Channel in the Settings, or -1 if there is not one.
+func (d *Device) ConversationWith(id int) (index int) {
+	return d.conversationWith(id, d.Settings.Channel)
+}
+
+// conversationWith returns the index of the Conversation between the Device and the Person with an ID on a Channel, or -1 if there is not one.
+func (d *Device) conversationWith(id int, channel string) (index int) {
+	for i, c := range d.Conversations {
+		if len(c.People) == 2 && c.People[1].ID == id && c.Channel == channel {
+			return i
+		}
+	}
+	return -1
+}
+
+// PersonName returns the name that a Person with an ID is saved with in the Contacts or the Names. Anyone else is named by their ID, as the name in their messages could be anyone's.
+func (d *Device) PersonName(id int) (name string) {
+	if name, ok := d.localName(id); ok {
+		return name
+	}
+	return fmt.Sprint(id)
+}
+
 func (d *Device) UpdateConversationsMenu() {
-	StateConversationsMenu = StateConversationsMenuOld
+	d.StateConversationsMenu = d.StateConversationsMenuOld
 	for i := 0; i < len(d.Conversations); i++ {
 		// Define a seperate variable to seperate the increasing i from the functions defined here.
 		j := i
-		StateConversationsMenu.Content = append(StateConversationsMenu.Content, MenuItem{
-			Text: d.Conversations[j].Name,
+		name := d.Conversations[j].Name
+		// Conversations on a Channel are marked, as the same Person can be talked to on more than one.
+		if d.Conversations[j].Channel != "" && !d.Conversations[j].Broadcast {
+			name += " #" + d.Conversations[j].Channel
+		}
+		if d.Conversations[j].Unread > 0 {
+			name += fmt.Sprintf(" (%d)", d.Conversations[j].Unread)
+		}
+		var icon *Icon
+		if d.ConversationPrivate(d.Conversations[j]) {
+			icon = &IconLocked
+		}
+		if d.Conversations[j].Muted {
+			icon = &IconMuted
+		}
+		d.StateConversationsMenu.Content = append(d.StateConversationsMenu.Content, MenuItem{
+			Text:   name,
+			Icon:   icon,
+			Detail: d.ConversationPreview(d.Conversations[j]),
 			Action: func(d *Device) (err error) {
-				d.CurrentConversationIndex = j
-				err = d.ChangeStateWithHistory(&StateConversationReader)
-				return err
+				return d.OpenConversation(j)
 			},
 			CursorIcon: CursorIconRightArrow,
 		})
 	}
-	StateConversationsMenu.HighlightedItemIndex = len(StateConversationsMenu.Content) - 1
+	d.StateConversationsMenu.HighlightedItemIndex = len(d.StateConversationsMenu.Content) - 1
+}
+
+// maxPreviewLength is the longest preview of a Conversation in the Conversations menu, which is more than fits on any screen.
+const maxPreviewLength = 64
+
+// ConversationPreview returns the newest Message of a Conversation with who sent it, such as "Bob: on my way", or an empty string if it has no Messages.
+func (d *Device) ConversationPreview(c *Conversation) string {
+	if len(c.Messages) == 0 {
+		return ""
+	}
+	m := c.Messages[len(c.Messages)-1]
+	name := m.Person.Name
+	if m.Person == d.SelfIdentity {
+		name = "You"
+	}
+	preview, _ := truncateString(name+": "+strings.ReplaceAll(d.MessageText(m), "\n", " "), maxPreviewLength)
+	return preview
 }
 
 // ChangeLEDAnimationWithoutContinue changes the current LED animation of the device without continuing from the last time it was played.
@@ -607,16 +2337,47 @@ func (d *Device) ChangeLEDAnimationWithContinue(newAnimation *LEDAnimation) (err
 	return nil
 }
 
+// MaxStateHistory is the most States that are kept in the StateHistory. Once it is full, the oldest States after the root are forgotten.
+const MaxStateHistory = 16
+
 // ChangeStateWithHistory will take in a State and update the Device while adding the State to the StateHistory.
+// If the State is already in the StateHistory, everything after it is forgotten instead, so going round in a loop does not fill it up.
 func (d *Device) ChangeStateWithHistory(newState *State) (err error) {
+	d.forwardHistory = nil
+	for i, state := range d.StateHistory {
+		if state == newState {
+			d.StateHistory = d.StateHistory[:i]
+			break
+		}
+	}
 	d.StateHistory = append(d.StateHistory, newState)
+	if len(d.StateHistory) > MaxStateHistory {
+		d.StateHistory = append(d.StateHistory[:1], d.StateHistory[len(d.StateHistory)-MaxStateHistory+1:]...)
+	}
 	err = d.ChangeStateWithoutHistory(newState)
 	return err
 }
 
+// openShortcut changes to a State from one of the shortcut keys. The StateHistory is started again from the main menu, so going back from the State always goes there.
+func (d *Device) openShortcut(newState *State) (err error) {
+	d.StateHistory = []*State{&d.StateMainMenu}
+	return d.ChangeStateWithHistory(newState)
+}
+
 // ChangeStateWithoutHistory will take in a State and update the Device.
 func (d *Device) ChangeStateWithoutHistory(newState *State) (err error) {
+	previous := d.State
+	if previous != nil && previous != newState && previous.OnExit != nil {
+		err = previous.OnExit(d)
+		if err != nil {
+			return err
+		}
+	}
 	d.State = newState
+	d.MarkDirty()
+	if d.OnStateChanged != nil {
+		d.OnStateChanged(previous, newState)
+	}
 	if d.State.LoadAction != nil {
 		err = d.State.LoadAction(d)
 	}
@@ -628,11 +2389,24 @@ func (d *Device) GoBackState() (err error) {
 	if len(d.StateHistory) <= 1 {
 		return ErrGoBackStateRootState
 	}
+	d.forwardHistory = append(d.forwardHistory, d.State)
 	err = d.ChangeStateWithoutHistory(d.StateHistory[len(d.StateHistory)-2])
 	d.StateHistory = d.StateHistory[0 : len(d.StateHistory)-1]
 	return err
 }
 
+// GoForwardState returns to the State that GoBackState last left, as long as no other State has been opened since.
+func (d *Device) GoForwardState() (err error) {
+	if len(d.forwardHistory) == 0 {
+		return ErrGoForwardStateNoState
+	}
+	next := d.forwardHistory[len(d.forwardHistory)-1]
+	forward := d.forwardHistory[:len(d.forwardHistory)-1]
+	err = d.ChangeStateWithHistory(next)
+	d.forwardHistory = forward
+	return err
+}
+
 // InputEvent is a string that represents a button press.
 type InputEvent string
 
@@ -666,6 +2440,46 @@ const (
 
 // ProcessInputEvent will take in an InputEvent and run appropriate actions based on the event.
 func (d *Device) ProcessInputEvent(inputEvent InputEvent) (err error) {
+	now := time.Now()
+	// The same button again within the Debounce is its contact bouncing, not a second press.
+	if inputEvent == d.lastInputEvent && now.Sub(d.lastInput) < d.InputTiming.Debounce {
+		return nil
+	}
+	d.lastInput = now
+	d.lastInputEvent = inputEvent
+	d.MarkDirty()
+	// The panic gesture works from every State, so it is checked before the State sees the InputEvent.
+	if handled, err := d.processPanicGesture(inputEvent, now); handled {
+		return err
+	}
+	// Give the user a whole ScanInterval to look at whatever they have just selected.
+	if d.Settings.Scanning && inputEvent == InputEventAccept {
+		d.lastScan = time.Now()
+	}
+	if d.State.InputHandler != nil {
+		handled, err := d.State.InputHandler(d, inputEvent)
+		if handled {
+			return err
+		}
+	}
+	// The character picker uses the navigation keys to type, so it has to see them first.
+	if d.State == &d.StateConversationReader && d.Settings.InputMethod == InputMethodPicker && !d.Conversations[d.CurrentConversationIndex].ListenOnly {
+		handled, err := d.processPickerInputEvent(inputEvent)
+		if handled {
+			return err
+		}
+	}
+	// Predictive text uses up and down to choose a word, so it has to see them first too.
+	if d.State == &d.StateConversationReader && d.Settings.InputMethod == InputMethodPredictive && !d.Conversations[d.CurrentConversationIndex].ListenOnly {
+		handled, err := d.processPredictiveInputEvent(inputEvent)
+		if handled {
+			return err
+		}
+	}
+	// Function keys that have been set to do something in the Settings do it everywhere, instead of what they normally do.
+	if handled, err := d.processFunctionKey(inputEvent); handled {
+		return err
+	}
 	// Process the keys that are always available.
 	switch inputEvent {
 	case InputEventUp:
@@ -678,6 +2492,16 @@ func (d *Device) ProcessInputEvent(inputEvent InputEvent) (err error) {
 			err = d.ProcessInputEventDown()
 			return err
 		}
+	case InputEventLeft:
+		{
+			err = d.ProcessInputEventLeft()
+			return err
+		}
+	case InputEventRight:
+		{
+			err = d.ProcessInputEventRight()
+			return err
+		}
 	case InputEventAccept:
 		{
 			err = d.ProcessInputEventAccept()
@@ -685,27 +2509,73 @@ func (d *Device) ProcessInputEvent(inputEvent InputEvent) (err error) {
 		}
 	case InputEventOpenSettings:
 		{
-			err = d.ChangeStateWithHistory(&StateSettingsMenu)
+			err = d.openShortcut(&d.StateSettingsMenu)
 			return err
 		}
 	case InputEventOpenPeople:
 		{
-			err = d.ChangeStateWithHistory(&StatePeopleMenu)
+			err = d.openShortcut(&d.StatePeopleMenu)
 			return err
 		}
 	case InputEventOpenConversations:
 		{
-			err = d.ChangeStateWithHistory(&StateConversationsMenu)
+			err = d.openShortcut(&d.StateConversationsMenu)
 			return err
 		}
 	case InputEventOpenMainMenu:
 		{
-			err = d.ChangeStateWithHistory(&StateMainMenu)
+			err = d.openShortcut(&d.StateMainMenu)
 			return err
 		}
 	}
 	// Process the keys that are available in the conversationreader state.
-	if d.State == &StateConversationReader {
+	if d.State == &d.StateConversationReader {
+		if inputEvent == InputEventFunction1 {
+			d.ToggleConversationListenOnly(d.Conversations[d.CurrentConversationIndex])
+			return nil
+		}
+		if inputEvent == InputEventFunction4 {
+			d.TogglePinnedMessage(d.Conversations[d.CurrentConversationIndex])
+			return nil
+		}
+		// The composer is disabled while listening only.
+		if d.Conversations[d.CurrentConversationIndex].ListenOnly {
+			return nil
+		}
+		if inputEvent == InputEventFunction3 {
+			d.ToggleComposerPriority(d.Conversations[d.CurrentConversationIndex])
+			return nil
+		}
+		if inputEvent == InputEventFunction2 {
+			d.StateQuickRepliesMenu.HighlightedItemIndex = 0
+			return d.ChangeStateWithHistory(&d.StateQuickRepliesMenu)
+		}
+		if inputEvent == d.sendKey() {
+			return d.sendComposerText()
+		}
+		if inputEvent == InputEventPound {
+			d.StateScheduleMenu.HighlightedItemIndex = 0
+			return d.ChangeStateWithHistory(&d.StateScheduleMenu)
+		}
+		if d.Settings.InputMethod == InputMethodMorse {
+			if inputEvent == d.Settings.MorseKey {
+				d.ProcessMorseKey(time.Now())
+			}
+			return nil
+		}
+		if inputEvent == InputEventStar {
+			wasNumbers := d.ComposeMode == ComposeModeNumbers
+			d.CycleComposeMode(&d.Conversations[d.CurrentConversationIndex].KeyboardBuffer)
+			// After 123 comes the symbol picker, and then back to abc.
+			if wasNumbers {
+				return d.OpenSymbolPicker()
+			}
+			return nil
+		}
+		if _, ok := numberKeyDigits[inputEvent]; ok && d.ComposeMode == ComposeModeNumbers {
+			d.typeNumberKey(&d.Conversations[d.CurrentConversationIndex].KeyboardBuffer, inputEvent)
+			return nil
+		}
 		switch inputEvent {
 		case InputEventNumber1:
 			{
@@ -763,7 +2633,7 @@ func (d *Device) ProcessInputEvent(inputEvent InputEvent) (err error) {
 }
 
 func (d *Device) ProcessInputEventUp() (err error) {
-	if d.State != &StateConversationReader {
+	if d.State != &d.StateConversationReader {
 		if d.State.HighlightedItemIndex <= 0 {
 			d.State.HighlightedItemIndex = len(d.State.Content) - 1
 		} else {
@@ -780,7 +2650,7 @@ func (d *Device) ProcessInputEventUp() (err error) {
 }
 
 func (d *Device) ProcessInputEventDown() (err error) {
-	if d.State != &StateConversationReader {
+	if d.State != &d.StateConversationReader {
 		if d.State.HighlightedItemIndex >= len(d.State.Content)-1 {
 			d.State.HighlightedItemIndex = 0
 		} else {
@@ -797,67 +2667,186 @@ func (d *Device) ProcessInputEventDown() (err error) {
 }
 
 func (d *Device) ProcessInputEventAccept() (err error) {
-	if d.State != &StateConversationReader {
-		err = d.State.Content[d.State.HighlightedItemIndex].Action(d)
+	if d.State != &d.StateConversationReader {
+		item := d.State.Content[d.State.HighlightedItemIndex]
+		if item.Action == nil && item.Widget != nil {
+			return item.Widget.Accept(d)
+		}
+		err = item.Action(d)
 		return err
 	}
-	packetToSend, err := d.MesageToBytes(Message{
-		Text:   d.Conversations[d.CurrentConversationIndex].KeyboardBuffer + d.CurrentKeyboardButton.Characters[d.CurrentKeyboardButton.CurrentCharacterIndex],
-		Person: d.SelfIdentity,
-	})
-	if err != nil {
-		return err
+	if d.Conversations[d.CurrentConversationIndex].ListenOnly {
+		// There is nothing to send, so while scanning, accept leaves the Conversation instead of doing nothing.
+		if d.Settings.Scanning {
+			return d.GoBackState()
+		}
+		return nil
 	}
+	if d.sendKey() != InputEventAccept {
+		return d.acceptComposerCharacter()
+	}
+	return d.sendComposerText()
+}
+
+// takeComposerText empties the compose bar of the current Conversation, and returns what was in it with the Priority it was marked with.
+func (d *Device) takeComposerText() (text string, priority Priority) {
+	d.flushMorse()
+	text = d.ComposerText()
+	priority = d.Conversations[d.CurrentConversationIndex].Priority
 	d.Conversations[d.CurrentConversationIndex].KeyboardBuffer = ""
-	d.CurrentKeyboardButton = &KeyboardButton{Characters: []string{""}, CurrentCharacterIndex: 0}
-	return d.SendUsingRadio(packetToSend)
+	d.Conversations[d.CurrentConversationIndex].KeyboardBufferAfter = ""
+	d.Conversations[d.CurrentConversationIndex].Priority = PriorityNormal
+	d.clearPendingCharacter()
+	d.morse = morseKeyer{}
+	d.predictive = predictiveState{}
+	return text, priority
+}
+
+// ComposerText returns the text in the compose bar of the current Conversation, including anything that is still being typed.
+func (d *Device) ComposerText() string {
+	return d.composerTextBeforeCursor() + d.Conversations[d.CurrentConversationIndex].KeyboardBufferAfter
+}
+
+// composerTextBeforeCursor returns the text in the compose bar of the current Conversation up to the cursor, which is where anything that is still being typed goes.
+func (d *Device) composerTextBeforeCursor() string {
+	if d.Settings.InputMethod == InputMethodMorse {
+		return d.Conversations[d.CurrentConversationIndex].KeyboardBuffer + d.morse.elements
+	}
+	if d.Settings.InputMethod == InputMethodPicker {
+		return d.Conversations[d.CurrentConversationIndex].KeyboardBuffer
+	}
+	if d.Settings.InputMethod == InputMethodPredictive {
+		return d.Conversations[d.CurrentConversationIndex].KeyboardBuffer + d.predictiveWord()
+	}
+	return d.Conversations[d.CurrentConversationIndex].KeyboardBuffer + d.pendingCharacter()
+}
+
+// pendingCharacter returns the character of the key that is being pressed, which has not been added to the buffer yet.
+func (d *Device) pendingCharacter() string {
+	return d.composeModeCase(d.CurrentKeyboardButton.Characters[d.CurrentKeyboardButton.CurrentCharacterIndex])
+}
+
+// KeyboardHint returns the characters of the key that is being pressed, with the selected one in brackets, for example "a [b] c".
+// It is empty if no key with more than one character is being pressed. With the character picker, it shows the selected entry and the entries either side of it, and with predictive text it shows the words that match.
+func (d *Device) KeyboardHint() string {
+	if d.Settings.InputMethod == InputMethodPicker {
+		return d.pickerHint()
+	}
+	if d.Settings.InputMethod == InputMethodPredictive {
+		return d.predictiveHint()
+	}
+	if d.Settings.InputMethod != InputMethodMultiTap || len(d.CurrentKeyboardButton.Characters) <= 1 {
+		return ""
+	}
+	hint := make([]string, len(d.CurrentKeyboardButton.Characters))
+	for i, character := range d.CurrentKeyboardButton.Characters {
+		if character == " " {
+			character = "_"
+		}
+		character = d.composeModeCase(character)
+		if i == d.CurrentKeyboardButton.CurrentCharacterIndex {
+			character = "[" + character + "]"
+		}
+		hint[i] = character
+	}
+	return strings.Join(hint, " ")
+}
+
+// SendPacket is the outbox of the Device, every packet that belongs to a Conversation is sent through here.
+// Packets for a ListenOnly Conversation are never sent, so that the Device does not reveal its presence.
+// The packet is sent straight away if it can be. Otherwise it waits in the queue for the DutyCycle or a busy channel, and is sent by Tick.
+func (d *Device) SendPacket(c *Conversation, packet []byte) (err error) {
+	return d.sendPacketWithPriority(c, packet, txPriorityMessage, nil)
+}
+
+// sendPacketWithPriority is SendPacket for packets that jump ahead of, or wait behind, the messages in the queue. The done function is called with the result of sending the packet, and can be nil.
+func (d *Device) sendPacketWithPriority(c *Conversation, packet []byte, priority txPriority, done func(err error)) (err error) {
+	if c != nil && c.ListenOnly {
+		return ErrConversationListenOnly
+	}
+	return d.queuePacket(packet, priority, done)
+}
+
+// ListeningOnly returns true if any Conversation is ListenOnly. Packets that the Device would send by itself, such as ping replies, beacons and automatic replies, are not sent while it is, as they would reveal the Device to whoever it is listening to.
+func (d *Device) ListeningOnly() bool {
+	for _, c := range d.Conversations {
+		if c.ListenOnly {
+			return true
+		}
+	}
+	return false
+}
+
+// ToggleConversationListenOnly switches a Conversation between listen only (monitor) mode and normal mode.
+// Anything half typed is thrown away when entering listen only mode.
+func (d *Device) ToggleConversationListenOnly(c *Conversation) {
+	c.ListenOnly = !c.ListenOnly
+	if c.ListenOnly {
+		c.KeyboardBuffer = ""
+		c.KeyboardBufferAfter = ""
+		d.clearPendingCharacter()
+	}
 }
 
 func (d *Device) ProcessConversationInputEventNumber1() (err error) {
-	return d.ProcessConversationInputEventNumber(KeyboardButton1)
+	return d.ProcessConversationInputEventNumber(d.KeyboardLayout().Buttons[InputEventNumber1])
 }
 
 func (d *Device) ProcessConversationInputEventNumber2() (err error) {
-	return d.ProcessConversationInputEventNumber(KeyboardButton2)
+	return d.ProcessConversationInputEventNumber(d.KeyboardLayout().Buttons[InputEventNumber2])
 }
 
 func (d *Device) ProcessConversationInputEventNumber3() (err error) {
-	return d.ProcessConversationInputEventNumber(KeyboardButton3)
+	return d.ProcessConversationInputEventNumber(d.KeyboardLayout().Buttons[InputEventNumber3])
 }
 
 func (d *Device) ProcessConversationInputEventNumber4() (err error) {
-	return d.ProcessConversationInputEventNumber(KeyboardButton4)
+	return d.ProcessConversationInputEventNumber(d.KeyboardLayout().Buttons[InputEventNumber4])
 }
 
 func (d *Device) ProcessConversationInputEventNumber5() (err error) {
-	return d.ProcessConversationInputEventNumber(KeyboardButton5)
+	return d.ProcessConversationInputEventNumber(d.KeyboardLayout().Buttons[InputEventNumber5])
 }
 
 func (d *Device) ProcessConversationInputEventNumber6() (err error) {
-	return d.ProcessConversationInputEventNumber(KeyboardButton6)
+	return d.ProcessConversationInputEventNumber(d.KeyboardLayout().Buttons[InputEventNumber6])
 }
 
 func (d *Device) ProcessConversationInputEventNumber7() (err error) {
-	return d.ProcessConversationInputEventNumber(KeyboardButton7)
+	return d.ProcessConversationInputEventNumber(d.KeyboardLayout().Buttons[InputEventNumber7])
 }
 
 func (d *Device) ProcessConversationInputEventNumber8() (err error) {
-	return d.ProcessConversationInputEventNumber(KeyboardButton8)
+	return d.ProcessConversationInputEventNumber(d.KeyboardLayout().Buttons[InputEventNumber8])
 }
 
 func (d *Device) ProcessConversationInputEventNumber9() (err error) {
-	return d.ProcessConversationInputEventNumber(KeyboardButton9)
+	return d.ProcessConversationInputEventNumber(d.KeyboardLayout().Buttons[InputEventNumber9])
 }
 
 func (d *Device) ProcessConversationInputEventNumber0() (err error) {
-	return d.ProcessConversationInputEventNumber(KeyboardButton0)
+	return d.ProcessConversationInputEventNumber(d.KeyboardLayout().Buttons[InputEventNumber0])
 }
 
 func (d *Device) ProcessConversationInputEventNumber(button *KeyboardButton) (err error) {
-	if d.CurrentKeyboardButton != button {
-		d.Conversations[d.CurrentConversationIndex].KeyboardBuffer += d.CurrentKeyboardButton.Characters[d.CurrentKeyboardButton.CurrentCharacterIndex]
-		d.CurrentKeyboardButton = button
-		d.CurrentKeyboardButton.CurrentCharacterIndex = 0
+	d.typeKey(&d.Conversations[d.CurrentConversationIndex].KeyboardBuffer, button)
+	return nil
+}
+
+// typeKey types with a multi-tap key. Pressing a different key adds the pending character to the buffer, and pressing the same key again moves on to its next character.
+// The pending character is also added once the key has been left alone for the MultiTapTimeout, by tickMultiTap.
+// The KeyboardButtons of a KeyboardLayout are shared by every Device, so the Device types with its own copy of the key.
+func (d *Device) typeKey(buffer *string, button *KeyboardButton) {
+	if button == nil {
+		// The key does not type anything in this KeyboardLayout.
+		return
+	}
+	if d.pressedButton != button {
+		*buffer += d.pendingCharacter()
+		pressed := *button
+		pressed.CurrentCharacterIndex = 0
+		d.CurrentKeyboardButton = &pressed
+		d.pressedButton = button
 	} else {
 		if d.CurrentKeyboardButton.CurrentCharacterIndex >= len(d.CurrentKeyboardButton.Characters)-1 {
 			d.CurrentKeyboardButton.CurrentCharacterIndex = 0
@@ -865,171 +2854,527 @@ func (d *Device) ProcessConversationInputEventNumber(button *KeyboardButton) (er
 			d.CurrentKeyboardButton.CurrentCharacterIndex++
 		}
 	}
-	return nil
+	d.CurrentKeyboardButton.LastPress = time.Now()
+	d.pendingBuffer = buffer
 }
 
-// MesageToBytes converts a Message to a compressed byte array.
+// MesageToBytes converts a Message to a compressed byte array. Text longer than the MessageTextLimit is cut off.
 func (d *Device) MesageToBytes(input Message) (output []byte, err error) {
+	bytesToSend := messageHeader(input)
+	text, _ := truncateString(input.Text, d.MessageTextLimit(input))
+	bytesToSend = append(bytesToSend, []byte(text)...)
+	if d.keys.private != nil {
+		return d.signMessageBytes(bytesToSend), nil
+	}
+	return bytesToSend, nil
+}
+
+// messageHeader returns the start of the packet for a Message, which comes before its text.
+func messageHeader(input Message) (header []byte) {
 	staringBytes := []byte{0x64, 0x6F, 0x6F, 0x6D} // ASCII for "doom"
 	seperatorByte := byte(0xcc)
-	bytesToSend := make([]byte, 0)
-	bytesToSend = append(bytesToSend, staringBytes...)
-	bytesToSend = append(bytesToSend, []byte(fmt.Sprint(input.Person.ID))...)
-	bytesToSend = append(bytesToSend, seperatorByte)
-	bytesToSend = append(bytesToSend, []byte(input.Person.Name)...)
-	bytesToSend = append(bytesToSend, seperatorByte)
-	bytesToSend = append(bytesToSend, []byte(input.Text)...)
-	return bytesToSend, nil
+	header = append(header, staringBytes...)
+	if input.Priority == PriorityEmergency {
+		header = append(header, emergencyMarker)
+	}
+	header = appendDestination(header, input)
+	header = append(header, []byte(fmt.Sprint(input.Person.ID))...)
+	header = append(header, seperatorByte)
+	header = append(header, []byte(input.Person.Name)...)
+	header = append(header, seperatorByte)
+	return header
+}
+
+// MessageTextLimit returns how many bytes of text fit in the packet that a Message is sent in, once its address, signature and encryption are counted.
+func (d *Device) MessageTextLimit(input Message) (limit int) {
+	if d.Settings.Meshtastic {
+		input.Text = ""
+		empty, err := d.MeshtasticCodec.Encode(input)
+		if err != nil {
+			return 0
+		}
+		limit = MaxPacketLength - len(empty)
+	} else {
+		limit = MaxPacketLength - len(messageHeader(input))
+		// Encrypting and signing the message take up part of the packet.
+		if d.encrypted(input) {
+			limit -= channelOverhead + replayCounterSize
+		}
+		if d.keys.private != nil {
+			limit -= signatureOverhead
+		}
+	}
+	if limit < 0 {
+		return 0
+	}
+	return limit
 }
 
-// BytesToMessage converts a compressed byte array to a Message.
+// BytesToMessage converts a compressed byte array to a Message. Names and text that are too long are truncated.
+// Signed messages are checked, and only marked as Verified if they were signed by the key first heard from the sender.
 func (d *Device) BytesToMessage(input []byte) (output Message, err error) {
+	if bytes.HasPrefix(input, signedPrefix) {
+		return d.verifySignedMessage(input)
+	}
 	startingBytes := []byte{0x64, 0x6F, 0x6F, 0x6D} // ASCII for "doom"
 	if !bytes.HasPrefix(input, startingBytes) {
 		return output, ErrInvalidMessage
 	}
 	seperatorByte := byte(0xcc)
-	receivedBytesSplit := bytes.Split(input, []byte{seperatorByte})
-	personID := receivedBytesSplit[0]
-	output.Person.ID = int(personID[0])
-	output.Person.Name = string(receivedBytesSplit[1])
-	output.Text = string(receivedBytesSplit[2])
+	input = input[len(startingBytes):]
+	if len(input) > 0 && input[0] == emergencyMarker {
+		output.Priority = PriorityEmergency
+		input = input[1:]
+	}
+	output.To, output.Broadcast, input, err = splitDestination(input)
+	if err != nil {
+		return output, err
+	}
+	// The text is last, so it may contain the seperator.
+	receivedBytesSplit := bytes.SplitN(input, []byte{seperatorByte}, 3)
+	if len(receivedBytesSplit) != 3 {
+		return output, ErrMalformedMessage
+	}
+	output.Person.ID, err = strconv.Atoi(string(receivedBytesSplit[0]))
+	if err != nil {
+		return output, ErrMalformedMessage
+	}
+	output.Person.Name, _ = truncateString(string(receivedBytesSplit[1]), MaxNameLength)
+	output.Text, output.Truncated = truncateString(string(receivedBytesSplit[2]), MaxMessageTextLength)
 	return output, nil
 }
 
+// truncateString shortens a string to at most length bytes without splitting a character. It also returns whether the string was shortened.
+func truncateString(input string, length int) (output string, truncated bool) {
+	if len(input) <= length {
+		return input, false
+	}
+	for length > 0 && !utf8.RuneStart(input[length]) {
+		length--
+	}
+	return input[:length], true
+}
+
+// EncodeMessage converts a Message to a radio packet, using the Meshtastic format if it is turned on in the Settings. Messages on a Channel are encrypted for it.
+func (d *Device) EncodeMessage(input Message) (output []byte, err error) {
+	if d.Settings.Meshtastic {
+		return d.MeshtasticCodec.Encode(input)
+	}
+	output, err = d.MesageToBytes(input)
+	if err != nil {
+		return nil, err
+	}
+	return d.sealForChannel(output, input)
+}
+
+// DecodeMessage converts a radio packet to a Message. Packets for a Channel are decrypted with it, and packets without the "doom", "dsig" or "dchn" prefix are decoded as Meshtastic packets if Meshtastic is turned on in the Settings.
+func (d *Device) DecodeMessage(input []byte) (output Message, err error) {
+	if bytes.HasPrefix(input, channelPrefix) {
+		return d.openChannelMessage(input)
+	}
+	if d.Settings.Meshtastic && !bytes.HasPrefix(input, []byte{0x64, 0x6F, 0x6F, 0x6D}) && !bytes.HasPrefix(input, signedPrefix) {
+		return d.MeshtasticCodec.Decode(input)
+	}
+	return d.BytesToMessage(input)
+}
+
 // GetFrame will take in a Device and return an image based on the state.
+// It allocates a new image every time, so RenderFrame should be used instead where memory is tight.
 func GetFrame(dimensions image.Rectangle, d *Device) (frame image.Image, err error) {
 	img := image.NewRGBA(dimensions)
+	err = RenderFrame(img, d)
+	if err != nil {
+		return nil, err
+	}
+	return img, nil
+}
 
-	if d.State != &StateConversationReader && d.State != &StateNewConversation {
+// RenderFrame draws the state of a Device into an image that is reused from frame to frame, so that no new frame has to be allocated.
+// Everything in the image is drawn over, and the Device no longer NeedsRedraw.
+func RenderFrame(img Canvas, d *Device) (err error) {
+	d.dirty = false
+	dimensions := img.Bounds()
+	layout := NewFaceLayout(dimensions, d.Face())
+	palette := d.Palette()
+	titleScroll, itemScroll := d.marqueeOffsets()
+	drawFilledBox(img, 0, 0, dimensions.Dx(), dimensions.Dy(), palette.Background)
+
+	if d.State.Draw != nil {
+		err = d.State.Draw(d, img, layout)
+		if err != nil {
+			return err
+		}
+	} else if d.State != &d.StateConversationReader && d.State != &d.StateNewConversation {
 		// Draw the content with the currently highlighted item in the middle of the screen and the other items above and below it.
+		// Items with two lines take up the height of their Detail as well.
+		itemHeight, detailHeight := layout.LineHeight, 0
+		if d.State.TwoLineItems {
+			detailHeight = FaceSmall.Height + 1
+			itemHeight += detailHeight
+		}
 		for i := 0; i < len(d.State.Content); i++ {
+			y := layout.HighlightBaseline + (i-d.State.HighlightedItemIndex)*itemHeight
+			scroll := 0
 			if i == d.State.HighlightedItemIndex {
-				drawText(img, 0, 43, d.State.Content[i].Text)
-			} else if i < d.State.HighlightedItemIndex {
-				drawText(img, 0, 43-(d.State.HighlightedItemIndex-i)*12, d.State.Content[i].Text)
-			} else if i > d.State.HighlightedItemIndex {
-				drawText(img, 0, 43+(i-d.State.HighlightedItemIndex)*12, d.State.Content[i].Text)
+				drawFilledBox(img, 0, layout.HighlightBaseline-layout.TextHeight, dimensions.Dx(), layout.HighlightBaseline+detailHeight+1, palette.Highlight)
+				scroll = itemScroll
+			}
+			drawMenuItemText(img, layout, d.State.Content[i], y, scroll, palette.Text)
+			// The value of a Widget is drawn over the end of the text, in the space the cursor would be in.
+			if value := d.menuItemValue(d.State.Content[i]); value != "" {
+				background := palette.Background
+				if i == d.State.HighlightedItemIndex {
+					background = palette.Highlight
+				}
+				valueLeft := dimensions.Dx() - 1 - textWidth(layout.Face, value)
+				drawFilledBox(img, valueLeft-2, y-layout.TextHeight, dimensions.Dx(), y+1, background)
+				drawTextFace(img, layout.Face, valueLeft, y, value, palette.Text)
+			}
+			if d.State.TwoLineItems && d.State.Content[i].Detail != "" {
+				drawTextFace(img, FaceSmall, IconSize+2, y+detailHeight, d.State.Content[i].Detail, palette.Text)
 			}
 		}
 
 		// Draw the title.
-		drawBlackFilledBox(img, 0, 0, dimensions.Dx(), 16)
-		drawText(img, 0, 13, d.State.Title)
-		drawHLine(img, 0, 15, dimensions.Dx())
-
-		// Draw the cursor. If the cursor is a checkbox, check if the checkbox is checked or not.
-		var cursorData any
-		if d.State.Content[d.State.HighlightedItemIndex].GetCursorData != nil {
-			cursorData, err = d.State.Content[d.State.HighlightedItemIndex].GetCursorData(d)
+		drawScrollingTitleBar(img, layout, palette, d.State.Title, titleScroll)
+
+		// Draw the cursor. If the cursor is a checkbox, check if the checkbox is checked or not. Items with a Widget show its value instead.
+		if highlighted := d.State.Content[d.State.HighlightedItemIndex]; highlighted.CursorIcon != nil {
+			var cursorData any
+			if highlighted.GetCursorData != nil {
+				cursorData, err = highlighted.GetCursorData(d)
+				if err != nil {
+					return err
+				}
+			}
+			err = highlighted.CursorIcon(img, dimensions.Dx()-7, layout.HighlightBaseline-7, cursorData)
 			if err != nil {
-				return nil, err
+				return err
 			}
+			recolor(img, image.Rect(dimensions.Dx()-7, layout.HighlightBaseline-7, dimensions.Dx(), layout.HighlightBaseline), palette.Cursor)
 		}
-		err = d.State.Content[d.State.HighlightedItemIndex].CursorIcon(img, dimensions.Dx()-7, 36, cursorData)
-		if err != nil {
-			return nil, err
-		}
-	} else if d.State == &StateConversationReader {
-		// Draw the conversation with the most recent message at the bottom of the screen.
-		for i := 0; i < len(d.Conversations[d.CurrentConversationIndex].Messages); i++ {
-			if i == d.Conversations[d.CurrentConversationIndex].HighlightedMessageIndex {
-				if d.Conversations[d.CurrentConversationIndex].Messages[i].Person != d.SelfIdentity {
-					drawText(img, 0, 43, "> "+d.Conversations[d.CurrentConversationIndex].Messages[i].Text)
-				} else {
-					drawText(img, dimensions.Dx()-((len(d.Conversations[d.CurrentConversationIndex].Messages[i].Text)+2)*7), 43, d.Conversations[d.CurrentConversationIndex].Messages[i].Text+" <")
-				}
-			} else if i < d.Conversations[d.CurrentConversationIndex].HighlightedMessageIndex {
-				if d.Conversations[d.CurrentConversationIndex].Messages[i].Person != d.SelfIdentity {
-					drawText(img, 0, 43-(d.Conversations[d.CurrentConversationIndex].HighlightedMessageIndex-i)*12, "> "+d.Conversations[d.CurrentConversationIndex].Messages[i].Text)
-				} else {
-					drawText(img, dimensions.Dx()-((len(d.Conversations[d.CurrentConversationIndex].Messages[i].Text)+2)*7), 43-(d.Conversations[d.CurrentConversationIndex].HighlightedMessageIndex-i)*12, d.Conversations[d.CurrentConversationIndex].Messages[i].Text+" <")
-				}
-			} else if i > d.Conversations[d.CurrentConversationIndex].HighlightedMessageIndex {
-				if d.Conversations[d.CurrentConversationIndex].Messages[i].Person != d.SelfIdentity {
-					drawText(img, 0, 43+(i-d.Conversations[d.CurrentConversationIndex].HighlightedMessageIndex)*12, "> "+d.Conversations[d.CurrentConversationIndex].Messages[i].Text)
-				} else {
-					drawText(img, dimensions.Dx()-((len(d.Conversations[d.CurrentConversationIndex].Messages[i].Text)+2)*7), 43+(i-d.Conversations[d.CurrentConversationIndex].HighlightedMessageIndex)*12, d.Conversations[d.CurrentConversationIndex].Messages[i].Text+" <")
+	} else if d.State == &d.StateConversationReader {
+		// Draw the conversation with the most recent message at the bottom of the screen. Only the messages that can be seen are laid out, so long conversations are as quick to draw as short ones.
+		conversation := d.Conversations[d.CurrentConversationIndex]
+		first, last := messageWindow(conversation, layout)
+		for i := first; i <= last; i++ {
+			drawMessage(img, layout, palette, d.MessageText(conversation.Messages[i]), conversation.Messages[i].Person == d.SelfIdentity, conversation.Messages[i].Verified, layout.MessageBaseline+(i-conversation.HighlightedMessageIndex)*layout.LineHeight)
+		}
+		if conversation.Pinned != nil {
+			drawPinnedMessage(img, layout, palette, *conversation.Pinned)
+		}
+		if !layout.Compact {
+			drawScrollingTitleBar(img, layout, palette, conversation.Name, titleScroll)
+			if d.ConversationPrivate(conversation) {
+				drawIcon(img, &IconLocked, dimensions.Dx()-IconSize-1, (layout.TitleHeight-IconSize)/2, palette.TitleText)
+			}
+		}
+		drawFilledBox(img, 0, layout.ComposerTop-1, dimensions.Dx(), dimensions.Dy(), palette.Background)
+		drawHLineCol(img, 0, layout.ComposerTop, dimensions.Dx(), palette.TitleText)
+		if conversation.ListenOnly {
+			drawTextFace(img, layout.Face, 0, layout.ComposerBaseline, "(listen only)", palette.StatusWarning)
+		} else {
+			// An emergency message is marked in the compose bar before it is sent.
+			composerLeft := 0
+			if conversation.Priority == PriorityEmergency {
+				drawTextFace(img, layout.Face, 0, layout.ComposerBaseline, "!", palette.StatusAlert)
+				composerLeft = textWidth(layout.Face, "! ")
+			}
+			drawTextFace(img, layout.Face, composerLeft, layout.ComposerBaseline, d.ComposerText(), palette.Text)
+			// The cursor is only drawn once it has been moved away from the end of the text.
+			if d.Conversations[d.CurrentConversationIndex].KeyboardBufferAfter != "" {
+				cursorX := composerLeft + textWidth(layout.Face, d.composerTextBeforeCursor())
+				drawVLineCol(img, layout.ComposerBaseline-layout.TextHeight, cursorX, layout.ComposerBaseline, palette.Cursor)
+			}
+			// The ComposeMode only changes what multi-tap types, so it is only shown when typing with multi-tap.
+			if d.Settings.InputMethod == InputMethodMultiTap {
+				mode := d.ComposeMode.String()
+				modeLeft := dimensions.Dx() - textWidth(layout.Face, mode) - 1
+				drawFilledBox(img, modeLeft-2, layout.ComposerTop+1, dimensions.Dx(), dimensions.Dy(), palette.Background)
+				drawTextFace(img, layout.Face, modeLeft, layout.ComposerBaseline, mode, palette.TitleText)
+			}
+			// Draw the characters of the key that is being pressed above the compose bar.
+			if hint := d.KeyboardHint(); hint != "" && !d.ReducedAnimation() {
+				hintTop := layout.ComposerTop - layout.Face.Height - 1
+				drawFilledBox(img, 0, hintTop, dimensions.Dx(), layout.ComposerTop-1, palette.Background)
+				drawHLineCol(img, 0, hintTop, dimensions.Dx(), palette.TitleText)
+				drawTextFace(img, layout.Face, 0, layout.ComposerTop-layout.Face.Descent-1, hint, palette.Text)
+			} else if d.ComposerText() != "" && !layout.Compact {
+				// Otherwise, show how much more fits in the message and how long it takes to send in the corner above the compose bar.
+				status := d.ComposerStatus()
+				statusColor := palette.TitleText
+				if d.ComposerRemaining() < 0 {
+					statusColor = palette.StatusAlert
 				}
+				statusLeft := dimensions.Dx() - textWidth(layout.Face, status) - 2
+				statusTop := layout.ComposerTop - layout.Face.Height - 1
+				drawFilledBox(img, statusLeft-1, statusTop, dimensions.Dx(), layout.ComposerTop-1, palette.Background)
+				drawBoxOutline(img, statusLeft-1, statusTop, dimensions.Dx()-1, layout.ComposerTop, palette.TitleText)
+				drawTextFace(img, layout.Face, statusLeft, layout.ComposerTop-layout.Face.Descent-1, status, statusColor)
 			}
 		}
-		drawBlackFilledBox(img, 0, 0, dimensions.Dx(), 16)
-		drawText(img, 0, 13, d.Conversations[d.CurrentConversationIndex].Name)
-		drawHLine(img, 0, 15, dimensions.Dx())
-		drawBlackFilledBox(img, 0, ((dimensions.Dy()*75)/100)-1, dimensions.Dx(), dimensions.Dy())
-		drawHLine(img, 0, (dimensions.Dy()*75)/100, dimensions.Dx())
-		drawText(img, 0, (dimensions.Dy()*75)/100+13, d.Conversations[d.CurrentConversationIndex].KeyboardBuffer+d.CurrentKeyboardButton.Characters[d.CurrentKeyboardButton.CurrentCharacterIndex])
 	}
 
-	return img, nil
+	// Inverting the whole frame last means every State is inverted, however it was drawn.
+	if d.Settings.Inverted {
+		invert(img)
+	}
+	return nil
+}
+
+// drawMessage draws a message in a bubble with its bottom at y. Messages sent by the Device are on the right, and messages from other people are on the left, marked with a "?" if they are not verified.
+func drawMessage(img Canvas, layout Layout, palette Palette, message string, own, verified bool, y int) {
+	if own {
+		text := message + " <"
+		x := img.Bounds().Dx() - textWidth(layout.Face, text)
+		drawFilledBox(img, x, y-layout.TextHeight, img.Bounds().Dx(), y+1, palette.OwnMessage)
+		drawTextFace(img, layout.Face, x, y, text, palette.Text)
+		return
+	}
+	// Messages that could have been sent by anyone are marked, so that a spoofed name is not trusted.
+	text := "> " + message
+	if !verified {
+		text = "?> " + message
+	}
+	drawFilledBox(img, 0, y-layout.TextHeight, textWidth(layout.Face, text), y+1, palette.OtherMessage)
+	drawTextFace(img, layout.Face, 0, y, text, palette.Text)
 }
 
 // GetErrorFrame will take in a string version of an error and return an image with that error in.
 func GetErrorFrame(dimensions image.Rectangle, d *Device, inputErr string) (frame image.Image, err error) {
 	img := image.NewRGBA(dimensions)
-	inputErr = "FATAL ERR: " + inputErr
-	if len(inputErr) < 18 {
-		drawText(img, 0, 13, inputErr)
-	} else if len(inputErr) > 18 && len(inputErr) < 36 {
-		drawText(img, 0, 13, inputErr[:18])
-		drawText(img, 0, 26, inputErr[18:])
-	} else if len(inputErr) > 36 && len(inputErr) < 54 {
-		drawText(img, 0, 13, inputErr[:18])
-		drawText(img, 0, 26, inputErr[18:36])
-		drawText(img, 0, 39, inputErr[36:])
-	} else {
-		drawText(img, 0, 13, inputErr[:18])
-		drawText(img, 0, 26, inputErr[18:36])
-		drawText(img, 0, 39, inputErr[36:54])
-		drawText(img, 0, 52, inputErr[54:])
+	col := MonochromePalette.StatusAlert
+	if d != nil {
+		col = d.Palette().StatusAlert
+	}
+	characters := []rune("FATAL ERR: " + inputErr)
+	// Split the error into as many lines as fit across the screen, and draw as many of them as fit down it.
+	width := dimensions.Dx() / characterWidth
+	if width < 1 {
+		width = 1
+	}
+	for line := 0; len(characters) > 0 && (line+1)*13 <= dimensions.Dy(); line++ {
+		end := width
+		if end > len(characters) {
+			end = len(characters)
+		}
+		drawTextCol(img, dimensions.Min.X, dimensions.Min.Y+(line+1)*13, string(characters[:end]), col)
+		characters = characters[end:]
 	}
 	return img, nil
 }
 
-// drawText will write text in a 7x13 pixel font at a location.
-func drawText(img *image.RGBA, x, y int, text string) {
+// drawText will write white text in a 7x13 pixel font at a location.
+func drawText(img Canvas, x, y int, text string) {
 	col := color.RGBA{255, 255, 255, 255}
-	point := fixed.Point26_6{X: fixed.I(x), Y: fixed.I(y)}
+	drawTextCol(img, x, y, text, col)
+}
+
+// textWidth returns the width in pixels of text drawn in a font face. Every character is as wide as every other, however many bytes it takes.
+func textWidth(face *basicfont.Face, text string) int {
+	return utf8.RuneCountInString(text) * face.Advance
+}
+
+// drawTextCol will write text in a 7x13 pixel font in a color of your choice at a location.
+func drawTextCol(img Canvas, x, y int, text string, col color.RGBA) {
+	drawTextFace(img, FaceNormal, x, y, text, col)
+}
 
-	d := &font.Drawer{
-		Dst:  img,
-		Src:  image.NewUniform(col),
-		Face: basicfont.Face7x13,
-		Dot:  point,
+// drawTextScaled will write text in a font face at a whole number of times its size, with its baseline at y.
+func drawTextScaled(img Canvas, face *basicfont.Face, x, y int, text string, col color.RGBA, scale int) {
+	top := y - face.Ascent*scale
+	for _, r := range text {
+		dr, mask, maskp, _, ok := face.Glyph(fixed.P(0, face.Ascent), r)
+		if ok {
+			alpha := mask.(*image.Alpha)
+			for py := dr.Min.Y; py < dr.Max.Y; py++ {
+				for px := dr.Min.X; px < dr.Max.X; px++ {
+					if alpha.AlphaAt(maskp.X+px-dr.Min.X, maskp.Y+py-dr.Min.Y).A == 0 {
+						continue
+					}
+					drawFilledBox(img, x+px*scale, top+py*scale, x+(px+1)*scale-1, top+(py+1)*scale-1, col)
+				}
+			}
+		}
+		x += face.Advance * scale
+	}
+}
+
+// drawTextFace will write text in a font face in a color of your choice at a location.
+// The glyphs are copied straight into the image rather than with a font.Drawer, so that drawing text does not allocate.
+func drawTextFace(img Canvas, face *basicfont.Face, x, y int, text string, col color.RGBA) {
+	dot := fixed.Point26_6{X: fixed.I(x), Y: fixed.I(y)}
+	for _, r := range text {
+		dr, mask, maskp, advance, ok := face.Glyph(dot, r)
+		if !ok {
+			// Leave a gap, so that the rest of the text stays where textWidth expects it.
+			dot.X += fixed.I(face.Advance)
+			continue
+		}
+		alpha := mask.(*image.Alpha)
+		for py := dr.Min.Y; py < dr.Max.Y; py++ {
+			for px := dr.Min.X; px < dr.Max.X; px++ {
+				if alpha.AlphaAt(maskp.X+px-dr.Min.X, maskp.Y+py-dr.Min.Y).A != 0 {
+					img.SetRGBA(px, py, col)
+				}
+			}
+		}
+		dot.X += advance
 	}
-	d.DrawString(text)
 }
 
 // drawHLine draws a white horizontal line from one X location to another. x2 has to be greater than x1.
-func drawHLine(img *image.RGBA, x1 int, y int, x2 int) {
+func drawHLine(img Canvas, x1 int, y int, x2 int) {
 	col := color.RGBA{255, 255, 255, 255}
 	drawHLineCol(img, x1, y, x2, col)
 }
 
 // drawHLineCol draws a horizontal line in a color of your choice from one X location to another. x2 has to be greater than x1.
-func drawHLineCol(img *image.RGBA, x1 int, y int, x2 int, col color.RGBA) {
+func drawHLineCol(img Canvas, x1 int, y int, x2 int, col color.RGBA) {
 	for ; x1 <= x2; x1++ {
-		img.Set(x1, y, col)
+		img.SetRGBA(x1, y, col)
 	}
 }
 
 // drawVLine draws a verticle line from one Y location to another. y2 has to be greater than y1.
-func drawVLine(img *image.RGBA, y1 int, x int, y2 int) {
+func drawVLine(img Canvas, y1 int, x int, y2 int) {
 	col := color.RGBA{255, 255, 255, 255}
 	drawVLineCol(img, y1, x, y2, col)
 }
 
 // drawVLineCol draws a vertical line in a color of your choice from one Y location to another. y2 has to be greater than y1.
-func drawVLineCol(img *image.RGBA, y1 int, x int, y2 int, col color.RGBA) {
+func drawVLineCol(img Canvas, y1 int, x int, y2 int, col color.RGBA) {
 	for ; y1 <= y2; y1++ {
-		img.Set(x, y1, col)
+		img.SetRGBA(x, y1, col)
 	}
 }
 
 // drawBlackFilledBox draws a filled blacck box from one X and Y location to another.
-func drawBlackFilledBox(img *image.RGBA, x1 int, y1 int, x2 int, y2 int) {
+func drawBlackFilledBox(img Canvas, x1 int, y1 int, x2 int, y2 int) {
 	col := color.RGBA{0, 0, 0, 255}
+	drawFilledBox(img, x1, y1, x2, y2, col)
+}
+
+// drawFilledBox draws a filled box in a color of your choice from one X and Y location to another.
+func drawFilledBox(img Canvas, x1 int, y1 int, x2 int, y2 int, col color.RGBA) {
 	for ; y1 <= y2; y1++ {
 		drawHLineCol(img, x1, y1, x2, col)
 	}
 }
+
+// drawLineCol draws a straight line in a color of your choice between any two points.
+func drawLineCol(img Canvas, x1 int, y1 int, x2 int, y2 int, col color.RGBA) {
+	dx, dy := x2-x1, -(y2 - y1)
+	if dx < 0 {
+		dx = -dx
+	}
+	if dy > 0 {
+		dy = -dy
+	}
+	stepX, stepY := 1, 1
+	if x1 > x2 {
+		stepX = -1
+	}
+	if y1 > y2 {
+		stepY = -1
+	}
+	// Bresenham's line algorithm, which works in every direction.
+	e := dx + dy
+	for {
+		img.SetRGBA(x1, y1, col)
+		if x1 == x2 && y1 == y2 {
+			return
+		}
+		if 2*e >= dy {
+			e += dy
+			x1 += stepX
+		}
+		if 2*e <= dx {
+			e += dx
+			y1 += stepY
+		}
+	}
+}
+
+// drawSparkline draws samples as a line graph that fills a rectangle, oldest on the left. The lowest sample is drawn at the bottom and the highest at the top.
+func drawSparkline(img Canvas, r image.Rectangle, samples []int, col color.RGBA) {
+	if len(samples) == 0 || r.Empty() {
+		return
+	}
+	low, high := samples[0], samples[0]
+	for _, sample := range samples {
+		if sample < low {
+			low = sample
+		}
+		if sample > high {
+			high = sample
+		}
+	}
+	// A single sample, or samples that are all the same, are drawn as a flat line across the middle.
+	if len(samples) == 1 || low == high {
+		drawHLineCol(img, r.Min.X, (r.Min.Y+r.Max.Y-1)/2, r.Max.X-1, col)
+		return
+	}
+	point := func(i int) (x, y int) {
+		x = r.Min.X + i*(r.Dx()-1)/(len(samples)-1)
+		y = r.Max.Y - 1 - (samples[i]-low)*(r.Dy()-1)/(high-low)
+		return x, y
+	}
+	x1, y1 := point(0)
+	for i := 1; i < len(samples); i++ {
+		x2, y2 := point(i)
+		drawLineCol(img, x1, y1, x2, y2, col)
+		x1, y1 = x2, y2
+	}
+}
+
+// drawBarChart draws values as bars side by side that fill a rectangle, each as tall as its value is between low and high. The highlighted bar is drawn in another color, with an outline the full height of the rectangle.
+func drawBarChart(img Canvas, r image.Rectangle, values []int, low, high, highlighted int, col, highlightCol color.RGBA) {
+	if len(values) == 0 || r.Empty() || high <= low {
+		return
+	}
+	step := r.Dx() / len(values)
+	if step < 1 {
+		step = 1
+	}
+	for i, value := range values {
+		if value < low {
+			value = low
+		}
+		if value > high {
+			value = high
+		}
+		x1 := r.Min.X + i*step
+		x2 := x1 + step - 2
+		if x2 < x1 {
+			x2 = x1
+		}
+		height := (value - low) * r.Dy() / (high - low)
+		barCol := col
+		if i == highlighted {
+			barCol = highlightCol
+			drawBoxOutline(img, x1, r.Min.Y, x2, r.Max.Y-1, highlightCol)
+		}
+		if height > 0 {
+			drawFilledBox(img, x1, r.Max.Y-height, x2, r.Max.Y-1, barCol)
+		}
+	}
+}
+
+// drawCircleCol draws the outline of a circle in a color of your choice around a center point.
+func drawCircleCol(img Canvas, cx int, cy int, r int, col color.RGBA) {
+	// The midpoint circle algorithm draws one eighth of the circle and mirrors it.
+	x, y, e := r, 0, 1-r
+	for x >= y {
+		for _, p := range [8][2]int{{x, y}, {y, x}, {-y, x}, {-x, y}, {-x, -y}, {-y, -x}, {y, -x}, {x, -y}} {
+			img.SetRGBA(cx+p[0], cy+p[1], col)
+		}
+		y++
+		if e < 0 {
+			e += 2*y + 1
+		} else {
+			x--
+			e += 2*(y-x) + 1
+		}
+	}
+}
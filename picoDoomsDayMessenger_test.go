@@ -6,7 +6,9 @@ import (
 	"image/color"
 	"image/draw"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestDefaults(t *testing.T) {
@@ -17,8 +19,11 @@ func TestDefaults(t *testing.T) {
 	}
 
 	// Test the default state
-	if device.State != &StateMainMenu {
-		t.Errorf("The default state should be StateMainMenu but is %v", device.State)
+	if device.State != &device.StateSplash {
+		t.Errorf("The default state should be StateSplash but is %v", device.State)
+	}
+	if device.StateHistory[0] != &device.StateMainMenu {
+		t.Errorf("The splash screen should go back to StateMainMenu but goes back to %v", device.StateHistory[0])
 	}
 
 	// Test the default state history
@@ -43,6 +48,60 @@ func TestDefaults(t *testing.T) {
 	}
 }
 
+func TestDevicesDoNotShareStates(t *testing.T) {
+	alice, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	bob, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	alice.State = &alice.StateMainMenu
+	bob.State = &bob.StateMainMenu
+	if err = alice.ProcessInputEvent(InputEventDown); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if alice.StateMainMenu.HighlightedItemIndex != 1 || bob.StateMainMenu.HighlightedItemIndex != 0 {
+		t.Errorf("expected only alice's highlight to move, got %d and %d", alice.StateMainMenu.HighlightedItemIndex, bob.StateMainMenu.HighlightedItemIndex)
+	}
+
+	alice.NewConversation(bob.SelfIdentity)
+	alice.UpdateConversationsMenu()
+	bob.UpdateConversationsMenu()
+	if len(alice.StateConversationsMenu.Content) == len(bob.StateConversationsMenu.Content) {
+		t.Errorf("expected only alice's conversations menu to list the new conversation")
+	}
+}
+
+func TestDevicesDoNotShareKeyboardButtons(t *testing.T) {
+	alice, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	bob, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	for _, d := range []*Device{alice, bob} {
+		d.Conversations = []*Conversation{{}}
+		d.CurrentConversationIndex = 0
+		d.State = &d.StateConversationReader
+	}
+	// Alice presses 2 twice for "b", while bob presses it once in between for "a".
+	for _, d := range []*Device{alice, bob, alice} {
+		if err = d.ProcessInputEvent(InputEventNumber2); err != nil {
+			t.Fatalf("The error should be nil but is %v", err)
+		}
+	}
+	if alice.pendingCharacter() != "b" || bob.pendingCharacter() != "a" {
+		t.Errorf("The pending characters should be b and a but are %q and %q", alice.pendingCharacter(), bob.pendingCharacter())
+	}
+	if KeyboardButton2.CurrentCharacterIndex != 0 {
+		t.Errorf("The shared KeyboardButton2 should not be changed but is on character %d", KeyboardButton2.CurrentCharacterIndex)
+	}
+}
+
 func TestChangeLEDAnimationWithoutContinue(t *testing.T) {
 	// Create a new Machine
 	device, err := NewDevice()
@@ -151,6 +210,96 @@ func TestChangeStateWithoutHistory(t *testing.T) {
 	}
 }
 
+func TestStateLifecycleHooks(t *testing.T) {
+	device, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	var entered, exited, ticked int
+	testState0 := State{
+		LoadAction: func(d *Device) (err error) {
+			entered++
+			return nil
+		},
+		OnExit: func(d *Device) (err error) {
+			exited++
+			return nil
+		},
+		OnTick: func(d *Device, now time.Time) (err error) {
+			ticked++
+			return nil
+		},
+	}
+	testState1 := State{}
+
+	err = device.ChangeStateWithHistory(&testState0)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	err = device.Tick(time.Now())
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	// Changing to the State that is already shown does not leave it.
+	err = device.ChangeStateWithoutHistory(&testState0)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	err = device.ChangeStateWithHistory(&testState1)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	err = device.Tick(time.Now())
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if entered != 2 || exited != 1 || ticked != 1 {
+		t.Errorf("expected 2 entries, 1 exit and 1 tick, got %d, %d and %d", entered, exited, ticked)
+	}
+}
+
+func TestStateHistoryBounded(t *testing.T) {
+	device, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	states := make([]State, MaxStateHistory+4)
+	for i := range states {
+		err = device.ChangeStateWithHistory(&states[i])
+		if err != nil {
+			t.Fatalf("The error should be nil but is %v", err)
+		}
+	}
+	if len(device.StateHistory) != MaxStateHistory || device.StateHistory[0] != &device.StateMainMenu || device.StateHistory[MaxStateHistory-1] != &states[len(states)-1] {
+		t.Errorf("expected the root and the newest States to be kept, got %d States", len(device.StateHistory))
+	}
+
+	// Going back to a State that is already in the StateHistory forgets everything after it.
+	err = device.ChangeStateWithHistory(&states[len(states)-3])
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if len(device.StateHistory) != MaxStateHistory-2 {
+		t.Errorf("expected a repeated State to collapse the StateHistory, got %d States", len(device.StateHistory))
+	}
+
+	// Shortcut keys start again from the main menu.
+	err = device.ProcessInputEvent(InputEventOpenSettings)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if len(device.StateHistory) != 2 || device.StateHistory[0] != &device.StateMainMenu || device.State != &device.StateSettingsMenu {
+		t.Errorf("expected the StateHistory to be the main menu and the settings, got %d States", len(device.StateHistory))
+	}
+	err = device.ProcessInputEvent(InputEventOpenMainMenu)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if len(device.StateHistory) != 1 || device.State != &device.StateMainMenu {
+		t.Errorf("expected only the main menu in the StateHistory, got %d States", len(device.StateHistory))
+	}
+}
+
 func TestGoBackState(t *testing.T) {
 	// Create a new Machine
 	device, err := NewDevice()
@@ -215,7 +364,7 @@ func TestProcessInputEventUp(t *testing.T) {
 		t.Errorf("The highlighted item should be menuItem1 but is %v", device.State.Content[device.State.HighlightedItemIndex])
 	}
 
-	device.State = &StateConversationReader
+	device.State = &device.StateConversationReader
 	device.Conversations = []*Conversation{{Messages: []Message{{Text: "test0"}, {Text: "test1"}}}}
 	device.CurrentConversationIndex = 0
 	device.Conversations[device.CurrentConversationIndex].HighlightedMessageIndex = 1
@@ -267,7 +416,7 @@ func TestProcessInputEventDown(t *testing.T) {
 		t.Errorf("The highlighted item should be menuItem0 but is %v", device.State.Content[device.State.HighlightedItemIndex])
 	}
 
-	device.State = &StateConversationReader
+	device.State = &device.StateConversationReader
 	device.Conversations = []*Conversation{{Messages: []Message{{Text: "test0"}, {Text: "test1"}}}}
 	device.CurrentConversationIndex = 0
 	device.Conversations[device.CurrentConversationIndex].HighlightedMessageIndex = 0
@@ -327,7 +476,7 @@ func TestProcessInputEventOpenSettings(t *testing.T) {
 	if err != nil {
 		t.Errorf("The error should be nil but is %v", err)
 	}
-	if device.State != &StateSettingsMenu {
+	if device.State != &device.StateSettingsMenu {
 		t.Errorf("The state should be StateSettings but is %v", device.State)
 	}
 }
@@ -347,7 +496,7 @@ func TestProcessInputEventOpenPeople(t *testing.T) {
 	if err != nil {
 		t.Errorf("The error should be nil but is %v", err)
 	}
-	if device.State != &StatePeopleMenu {
+	if device.State != &device.StatePeopleMenu {
 		t.Errorf("The state should be StatePeople but is %v", device.State)
 	}
 }
@@ -367,7 +516,7 @@ func TestProcessInputEventOpenMessages(t *testing.T) {
 	if err != nil {
 		t.Errorf("The error should be nil but is %v", err)
 	}
-	if device.State != &StateConversationsMenu {
+	if device.State != &device.StateConversationsMenu {
 		t.Errorf("The state should be StateMessages but is %v", device.State)
 	}
 }
@@ -387,7 +536,7 @@ func TestProcessInputEventOpenMainMenu(t *testing.T) {
 	if err != nil {
 		t.Errorf("The error should be nil but is %v", err)
 	}
-	if device.State != &StateMainMenu {
+	if device.State != &device.StateMainMenu {
 		t.Errorf("The state should be StateMainMenu but is %v", device.State)
 	}
 }
@@ -500,39 +649,104 @@ func TestUpdateConversationsMenu(t *testing.T) {
 	testConversation3 := &Conversation{Name: "Test3"}
 	device.Conversations = []*Conversation{testConversation1, testConversation2}
 	device.UpdateConversationsMenu()
-	if StateConversationsMenu.Content[2].Text != "Test1" {
-		t.Errorf("Content of MessagesMenu item 1 is not correct, have: %v want: %v", StateConversationsMenu.Content[1].Text, "TestPerson1")
+	if device.StateConversationsMenu.Content[2].Text != "Test1" {
+		t.Errorf("Content of MessagesMenu item 1 is not correct, have: %v want: %v", device.StateConversationsMenu.Content[1].Text, "TestPerson1")
 	}
-	if StateConversationsMenu.Content[3].Text != "Test2" {
-		t.Errorf("Content of MessagesMenu item 2 is not correct, have: %v want: %v", StateConversationsMenu.Content[2].Text, "TestPerson2")
+	if device.StateConversationsMenu.Content[3].Text != "Test2" {
+		t.Errorf("Content of MessagesMenu item 2 is not correct, have: %v want: %v", device.StateConversationsMenu.Content[2].Text, "TestPerson2")
 	}
-	err = StateConversationsMenu.Content[2].Action(device)
+	err = device.StateConversationsMenu.Content[2].Action(device)
 	if err != nil {
 		t.Errorf("There was an unexpected error testing the Message Action, err: %s", err)
 	}
 	if device.CurrentConversationIndex != 0 {
 		t.Errorf("The CurrentConversation is not the conversation of the ran action, have: %v want: %v", device.CurrentConversationIndex, testConversation1)
 	}
-	if device.State != &StateConversationReader {
-		t.Errorf("The current State is not the ConversationReader, have %v want: %v", device.State, &StateConversationReader)
+	if device.State != &device.StateConversationReader {
+		t.Errorf("The current State is not the ConversationReader, have %v want: %v", device.State, &device.StateConversationReader)
 	}
 	if len(device.StateHistory) != 2 {
 		t.Errorf("The length of the StateHistory is not 2, have: %d want: %d", len(device.StateHistory), 2)
 	}
-	err = StateConversationsMenu.Content[3].Action(device)
+	err = device.StateConversationsMenu.Content[3].Action(device)
 	if err != nil {
 		t.Errorf("There was an unexpected error testing the Message Action, err: %s", err)
 	}
 	if device.CurrentConversationIndex != 1 {
 		t.Errorf("The CurrentConversation is not the conversation of the ran action, have: %v want: %v", device.CurrentConversationIndex, testConversation2)
 	}
-	if len(device.StateHistory) != 3 {
-		t.Errorf("The length of the StateHistory is not 3, have: %d want: %d", len(device.StateHistory), 3)
+	// Opening the ConversationReader again does not add it to the StateHistory twice.
+	if len(device.StateHistory) != 2 {
+		t.Errorf("The length of the StateHistory is not 2, have: %d want: %d", len(device.StateHistory), 2)
 	}
 	device.Conversations = []*Conversation{testConversation3}
 	device.UpdateConversationsMenu()
-	if len(StateConversationsMenu.Content) != 3 {
-		t.Errorf("The length of the StateMessagesMenu Content is not 3, have: %d want: %d", len(StateConversationsMenu.Content), 2)
+	if len(device.StateConversationsMenu.Content) != 3 {
+		t.Errorf("The length of the StateMessagesMenu Content is not 3, have: %d want: %d", len(device.StateConversationsMenu.Content), 2)
+	}
+}
+
+func TestReceiveFromContact(t *testing.T) {
+	// Create a new Machine
+	device, err := NewDevice()
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	device.Contacts = []Contact{{Person: Person{Name: "Alice", ID: 7}}}
+	for _, packet := range []string{"doom7\xccBob\xcchello", "doom7\xccBob\xccagain", "doom8\xccBob\xcchi"} {
+		err = device.ReceiveFromRadio([]byte(packet))
+		if err != nil {
+			t.Errorf("The error should be nil but is %v", err)
+		}
+	}
+	if len(device.Conversations) != 2 {
+		t.Fatalf("Messages from the same person should share a conversation, have %d conversations want 2", len(device.Conversations))
+	}
+	if device.Conversations[0].Name != "Alice" {
+		t.Errorf("A contact should be named by their saved name, have %q want %q", device.Conversations[0].Name, "Alice")
+	}
+	if len(device.Conversations[0].Messages) != 2 || device.Conversations[0].HighlightedMessageIndex != 1 {
+		t.Errorf("Both messages should be in the conversation with the newest highlighted, have %d messages at %d", len(device.Conversations[0].Messages), device.Conversations[0].HighlightedMessageIndex)
+	}
+	if device.Conversations[1].Name != "8" {
+		t.Errorf("A person who is not a contact should be named by their ID, have %q want %q", device.Conversations[1].Name, "8")
+	}
+	if device.ConversationWith(9) != -1 {
+		t.Errorf("There should be no conversation with a person who has not sent anything")
+	}
+}
+
+func TestConversationPreviewAndUnread(t *testing.T) {
+	device, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	device.State = &device.StateMainMenu
+	for _, packet := range []string{"doom7\xccBob\xcchello", "doom7\xccBob\xccon my\nway"} {
+		if err = device.ReceiveFromRadio([]byte(packet)); err != nil {
+			t.Fatalf("The error should be nil but is %v", err)
+		}
+	}
+	item := device.StateConversationsMenu.Content[len(device.StateConversationsMenu.Content)-1]
+	if item.Text != "7 (2)" || item.Detail != "Bob: on my way" {
+		t.Errorf("The conversation should show 2 unread and a preview of the newest message, have %q and %q", item.Text, item.Detail)
+	}
+	device.State = &device.StateConversationsMenu
+	if _, err = GetFrame(image.Rect(0, 0, 128, 64), device); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if err = item.Action(device); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if device.Conversations[0].Unread != 0 {
+		t.Errorf("Opening a conversation should mark it as read, have %d unread", device.Conversations[0].Unread)
+	}
+	// Messages that arrive while the conversation is open are read straight away.
+	if err = device.ReceiveFromRadio([]byte("doom7\xccBob\xcchere")); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if device.Conversations[0].Unread != 0 {
+		t.Errorf("A message in the open conversation should not be unread, have %d unread", device.Conversations[0].Unread)
 	}
 }
 
@@ -569,3 +783,198 @@ func TestMessageBytesConversion(t *testing.T) {
 		t.Errorf("The error should be ErrInvalidMessage but is %v", err)
 	}
 }
+
+func TestListenOnlyConversation(t *testing.T) {
+	// Create a new Machine
+	device, err := NewDevice()
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	sentPackets := 0
+	device.SendUsingRadio = func(packet []byte) (err error) {
+		sentPackets++
+		return nil
+	}
+	device.Conversations = []*Conversation{{Messages: []Message{{Text: "test0"}}}}
+	device.CurrentConversationIndex = 0
+	device.State = &device.StateConversationReader
+
+	err = device.ProcessInputEvent(InputEventFunction1)
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	if !device.Conversations[0].ListenOnly {
+		t.Errorf("The conversation should be listen only but is not")
+	}
+
+	err = device.ProcessInputEvent(InputEventNumber2)
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	if device.CurrentKeyboardButton == KeyboardButton2 {
+		t.Errorf("The composer should be disabled but the keyboard button changed to %v", device.CurrentKeyboardButton)
+	}
+
+	err = device.ProcessInputEvent(InputEventAccept)
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	err = device.SendPacket(device.Conversations[0], []byte("test"))
+	if err != ErrConversationListenOnly {
+		t.Errorf("The error should be ErrConversationListenOnly but is %v", err)
+	}
+	if sentPackets != 0 {
+		t.Errorf("No packets should have been sent but %d were", sentPackets)
+	}
+
+	err = device.ProcessInputEvent(InputEventFunction1)
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	err = device.SendPacket(device.Conversations[0], []byte("test"))
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	err = sendQueued(device)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if sentPackets != 1 {
+		t.Errorf("1 packet should have been sent but %d were", sentPackets)
+	}
+}
+
+func TestListenOnlySuppressesAutomaticPackets(t *testing.T) {
+	// Create a new Machine
+	device, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	sentPackets := 0
+	device.SendUsingRadio = func(packet []byte) (err error) {
+		sentPackets++
+		return nil
+	}
+	device.State = &device.StateMainMenu
+	device.Settings.Beacon = true
+	device.Settings.AutoReply = true
+	device.Conversations = []*Conversation{{Name: "Monitor", ListenOnly: true}}
+
+	err = device.ReceiveFromRadio(PingToBytes(Ping{From: 7, Sequence: 1}))
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	err = device.ReceiveFromRadio([]byte("doom7\xccBob\xcchello"))
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	err = device.Tick(time.Unix(100000, 0))
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	err = sendQueued(device)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if sentPackets != 0 {
+		t.Errorf("No ping replies, automatic replies or beacons should be sent while listening only but %d packets were", sentPackets)
+	}
+
+	device.ToggleConversationListenOnly(device.Conversations[0])
+	err = device.ReceiveFromRadio(PingToBytes(Ping{From: 7, Sequence: 2}))
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	err = sendQueued(device)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if sentPackets != 1 {
+		t.Errorf("1 ping reply should have been sent but %d packets were", sentPackets)
+	}
+}
+
+func TestReceiveOversizedAndMalformedPackets(t *testing.T) {
+	// Create a new Machine
+	device, err := NewDevice()
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	logged := []string{}
+	device.Log = func(message string) {
+		logged = append(logged, message)
+	}
+
+	err = device.ReceiveFromRadio(make([]byte, MaxPacketLength+1))
+	if err != ErrPacketTooLong {
+		t.Errorf("The error should be ErrPacketTooLong but is %v", err)
+	}
+	err = device.ReceiveFromRadio([]byte("doom123"))
+	if err != ErrMalformedMessage {
+		t.Errorf("The error should be ErrMalformedMessage but is %v", err)
+	}
+	err = device.ReceiveFromRadio([]byte("doomabc\xccname\xcctext"))
+	if err != ErrMalformedMessage {
+		t.Errorf("The error should be ErrMalformedMessage but is %v", err)
+	}
+	if len(logged) != 3 {
+		t.Errorf("3 rejections should have been logged but have: %v", logged)
+	}
+	if len(device.Conversations) != 0 {
+		t.Errorf("Rejected packets should not create conversations but have: %v", device.Conversations)
+	}
+}
+
+func TestBytesToMessageLimits(t *testing.T) {
+	// Create a new Machine
+	device, err := NewDevice()
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	longName := strings.Repeat("n", MaxNameLength+10)
+	longText := strings.Repeat("t", MaxMessageTextLength+10) + "\xcc"
+	packet, err := device.MesageToBytes(Message{Text: longText, Person: Person{Name: longName, ID: 4321}})
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	message, err := device.BytesToMessage(packet)
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	if message.Person.ID != 4321 {
+		t.Errorf("The message person ID is not correct, have: %v want: %v", message.Person.ID, 4321)
+	}
+	if len(message.Person.Name) != MaxNameLength {
+		t.Errorf("The message person name should be %d long but is %d", MaxNameLength, len(message.Person.Name))
+	}
+	if len(message.Text) != MaxMessageTextLength || !message.Truncated {
+		t.Errorf("The message text should be truncated to %d but is %d long", MaxMessageTextLength, len(message.Text))
+	}
+	if !strings.HasSuffix(message.DisplayText(), "...") {
+		t.Errorf("The message display text should show that it was truncated but is %v", message.DisplayText())
+	}
+
+	// Text may contain the seperator byte.
+	packet, err = device.MesageToBytes(Message{Text: "a\xccb", Person: Person{Name: "Test", ID: 1}})
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	message, err = device.BytesToMessage(packet)
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	if message.Text != "a\xccb" || message.Truncated {
+		t.Errorf("The message text is not correct, have: %q want: %q", message.Text, "a\xccb")
+	}
+}
+
+func TestTruncateString(t *testing.T) {
+	output, truncated := truncateString("héllo", 2)
+	if output != "h" || !truncated {
+		t.Errorf("The string should be cut before the multi-byte character but is %q", output)
+	}
+	output, truncated = truncateString("hello", 5)
+	if output != "hello" || truncated {
+		t.Errorf("The string should not be truncated but is %q", output)
+	}
+}
@@ -0,0 +1,197 @@
+package picodoomsdaymessenger
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// ErrInvalidPing is returned when a ping or ping reply packet cannot be decoded.
+var ErrInvalidPing = errors.New("invalid ping packet")
+
+// RSSIUnknown is the RSSI of a packet when the radio did not report it. Real RSSIs are always below 0dBm.
+const RSSIUnknown = 0
+
+// Define the ping packet prefixes
+var (
+	// pingPrefix is the start of every ping packet, ASCII for "ping".
+	pingPrefix = []byte{0x70, 0x69, 0x6E, 0x67}
+	// pingReplyPrefix is the start of every reply to a ping, ASCII for "pack".
+	pingReplyPrefix = []byte{0x70, 0x61, 0x63, 0x6B}
+)
+
+// PingInterval is the time between pings during a range test.
+var PingInterval = 2 * time.Second
+
+// PingTimeout is how long a reply to a ping is waited for before it counts as lost.
+var PingTimeout = 5 * time.Second
+
+// Ping is a ping packet, or a reply to one.
+type Ping struct {
+	// From is the ID of the device that sent the ping, in both the ping and the reply.
+	From     int
+	Sequence int
+	// RSSI is how strongly the ping was heard by the device that replied. It is only set in replies.
+	RSSI int
+}
+
+// rangeTest is the state of the range test tool.
+type rangeTest struct {
+	sent     map[int]time.Time
+	sequence int
+	lastPing time.Time
+	Sent     int
+	Replies  int
+	// RSSI is how strongly the last reply was heard, and TheirRSSI is how strongly they heard the ping.
+	RSSI      int
+	TheirRSSI int
+	RoundTrip time.Duration
+	LastReply time.Time
+}
+
+// PingToBytes creates a ping packet.
+func PingToBytes(p Ping) (output []byte) {
+	output = append(output, pingPrefix...)
+	output = append(output, []byte(fmt.Sprint(p.From))...)
+	output = append(output, 0xcc)
+	output = append(output, []byte(fmt.Sprint(p.Sequence))...)
+	return output
+}
+
+// PingReplyToBytes creates a reply to a ping packet.
+func PingReplyToBytes(p Ping) (output []byte) {
+	output = append(output, pingReplyPrefix...)
+	output = append(output, []byte(fmt.Sprint(p.From))...)
+	output = append(output, 0xcc)
+	output = append(output, []byte(fmt.Sprint(p.Sequence))...)
+	output = append(output, 0xcc)
+	output = append(output, []byte(fmt.Sprint(p.RSSI))...)
+	return output
+}
+
+// BytesToPing decodes a ping packet or a reply to one.
+func BytesToPing(input []byte) (output Ping, err error) {
+	fieldCount := 2
+	if bytes.HasPrefix(input, pingReplyPrefix) {
+		fieldCount = 3
+	} else if !bytes.HasPrefix(input, pingPrefix) {
+		return output, ErrInvalidPing
+	}
+	fields := bytes.Split(input[len(pingPrefix):], []byte{0xcc})
+	if len(fields) != fieldCount {
+		return output, ErrInvalidPing
+	}
+	values := make([]int, fieldCount)
+	for i, field := range fields {
+		values[i], err = strconv.Atoi(string(field))
+		if err != nil {
+			return output, ErrInvalidPing
+		}
+	}
+	output.From, output.Sequence = values[0], values[1]
+	if fieldCount == 3 {
+		output.RSSI = values[2]
+	}
+	return output, nil
+}
+
+// ReceiveFromRadioWithRSSI is ReceiveFromRadio for radios that report how strongly each packet was heard, in dBm.
+func (d *Device) ReceiveFromRadioWithRSSI(packetPayload []byte, rssi int) (err error) {
+	d.lastRSSI = rssi
+	err = d.ReceiveFromRadio(packetPayload)
+	d.lastRSSI = RSSIUnknown
+	return err
+}
+
+// receivePing replies to a ping from another device, with how strongly it was heard. Pings are not answered while the Device is ListeningOnly.
+func (d *Device) receivePing(p Ping) (err error) {
+	if p.From == d.SelfIdentity.ID || d.ListeningOnly() {
+		return nil
+	}
+	p.RSSI = d.lastRSSI
+	return d.sendPacketWithPriority(nil, PingReplyToBytes(p), txPriorityUrgent, nil)
+}
+
+// receivePingReply counts a reply to one of the pings of the range test.
+func (d *Device) receivePingReply(p Ping, now time.Time) (err error) {
+	if p.From != d.SelfIdentity.ID {
+		return nil
+	}
+	sent, ok := d.rangeTest.sent[p.Sequence]
+	if !ok {
+		// The reply is late, or another device has already replied to this ping.
+		return nil
+	}
+	delete(d.rangeTest.sent, p.Sequence)
+	d.rangeTest.Replies++
+	d.rangeTest.RSSI = d.lastRSSI
+	d.rangeTest.TheirRSSI = p.RSSI
+	d.rangeTest.RoundTrip = now.Sub(sent)
+	d.rangeTest.LastReply = now
+	return nil
+}
+
+// StartRangeTest clears the results of the range test.
+func (d *Device) StartRangeTest() {
+	d.rangeTest = rangeTest{sent: map[int]time.Time{}}
+}
+
+// tickRangeTest sends a ping every PingInterval while the range test is open, and forgets pings that have not been replied to within the PingTimeout.
+// Replies are timed with the corrected clock of the Device, so the TimeOffset is added to the time from Tick.
+func (d *Device) tickRangeTest(now time.Time) (err error) {
+	clock := now.Add(d.TimeOffset)
+	if clock.Sub(d.rangeTest.lastPing) < PingInterval {
+		return nil
+	}
+	for sequence, sent := range d.rangeTest.sent {
+		if clock.Sub(sent) > PingTimeout {
+			delete(d.rangeTest.sent, sequence)
+		}
+	}
+	d.rangeTest.lastPing = clock
+	d.rangeTest.sequence++
+	d.rangeTest.sent[d.rangeTest.sequence] = clock
+	d.rangeTest.Sent++
+	return d.SendPacket(nil, PingToBytes(Ping{From: d.SelfIdentity.ID, Sequence: d.rangeTest.sequence}))
+}
+
+// processRangeTestInputEvent leaves the range test with accept, and restarts it with left or right.
+func processRangeTestInputEvent(d *Device, inputEvent InputEvent) (handled bool, err error) {
+	switch inputEvent {
+	case InputEventAccept:
+		return true, d.GoBackState()
+	case InputEventLeft, InputEventRight:
+		d.StartRangeTest()
+		return true, nil
+	case InputEventUp, InputEventDown:
+		return true, nil
+	}
+	return false, nil
+}
+
+// formatRSSI shows an RSSI in dBm, or "?" if it is not known.
+func formatRSSI(rssi int) string {
+	if rssi == RSSIUnknown {
+		return "?"
+	}
+	return fmt.Sprint(rssi)
+}
+
+// drawRangeTest draws how many pings have been replied to and how strong the replies were.
+func drawRangeTest(d *Device, img Canvas, layout Layout) (err error) {
+	r := d.rangeTest
+	percent := 0
+	if r.Sent > 0 {
+		percent = r.Replies * 100 / r.Sent
+	}
+	text := fmt.Sprintf("Got %d/%d %d%%", r.Replies, r.Sent, percent)
+	if r.Replies > 0 {
+		text += fmt.Sprintf("\nRSSI %s them %s\nRTT %dms", formatRSSI(r.RSSI), formatRSSI(r.TheirRSSI), r.RoundTrip.Milliseconds())
+	} else {
+		text += "\nNo replies yet"
+	}
+	drawTextPage(img, layout, d.Palette(), "Range Test", text, 0)
+	return nil
+}
@@ -0,0 +1,99 @@
+package picodoomsdaymessenger
+
+import (
+	"image"
+	"testing"
+	"time"
+)
+
+func TestRangeTest(t *testing.T) {
+	tester, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	other, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	other.SelfIdentity.ID = tester.SelfIdentity.ID + 1
+	replying := true
+	tester.SendUsingRadio = func(packet []byte) (err error) {
+		if !replying {
+			return nil
+		}
+		return other.ReceiveFromRadioWithRSSI(packet, -90)
+	}
+	other.SendUsingRadio = func(packet []byte) (err error) {
+		return tester.ReceiveFromRadioWithRSSI(packet, -85)
+	}
+
+	err = ToolsMenuItemRangeTest.Action(tester)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	now := time.Now()
+	err = tester.Tick(now)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	err = sendQueued(other)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if tester.rangeTest.Sent != 1 || tester.rangeTest.Replies != 1 {
+		t.Fatalf("expected one ping and one reply, got %+v", tester.rangeTest)
+	}
+	if tester.rangeTest.RSSI != -85 || tester.rangeTest.TheirRSSI != -90 {
+		t.Errorf("expected the RSSI of both directions, got %d and %d", tester.rangeTest.RSSI, tester.rangeTest.TheirRSSI)
+	}
+
+	// No ping is sent until the PingInterval has passed.
+	err = tester.Tick(now.Add(PingInterval / 2))
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if tester.rangeTest.Sent != 1 {
+		t.Errorf("expected no ping before the PingInterval, got %d", tester.rangeTest.Sent)
+	}
+
+	// Out of range, pings are not replied to.
+	replying = false
+	err = tester.Tick(now.Add(PingInterval))
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if tester.rangeTest.Sent != 2 || tester.rangeTest.Replies != 1 {
+		t.Errorf("expected two pings and one reply, got %+v", tester.rangeTest)
+	}
+	if _, err := GetFrame(image.Rect(0, 0, 128, 64), tester); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+
+	// Pings stop when the range test is closed.
+	err = tester.ProcessInputEvent(InputEventAccept)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	err = tester.Tick(now.Add(10 * PingInterval))
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if tester.rangeTest.Sent != 2 {
+		t.Errorf("expected no pings after leaving the range test, got %d", tester.rangeTest.Sent)
+	}
+}
+
+func TestBytesToPing(t *testing.T) {
+	ping, err := BytesToPing(PingReplyToBytes(Ping{From: 5, Sequence: 7, RSSI: -100}))
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if ping != (Ping{From: 5, Sequence: 7, RSSI: -100}) {
+		t.Errorf("unexpected reply %+v", ping)
+	}
+	for _, packet := range []string{"ping", "ping1", "pingx\xcc1", "pack1\xcc2"} {
+		if _, err := BytesToPing([]byte(packet)); err != ErrInvalidPing {
+			t.Errorf("%q: expected ErrInvalidPing, got %v", packet, err)
+		}
+	}
+}
@@ -0,0 +1,233 @@
+package picodoomsdaymessenger
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// pongGameName is the name of Pong in GamePackets.
+const pongGameName = "pong"
+
+// Define the size of the Pong court, in court units. The court is scaled to fit the screen.
+const (
+	PongWidth        = 128
+	PongHeight       = 48
+	PongPaddleHeight = 12
+	// PongPaddleStep is how far a paddle moves for each press of up or down.
+	PongPaddleStep = 4
+	// PongBallSpeed is how far the ball moves across the court every second when it is served.
+	PongBallSpeed = 40
+)
+
+// Define the timings of Pong.
+var (
+	// PongSendInterval is the time between the packets that each device sends during a game. It is kept long enough to leave the radio time to receive.
+	PongSendInterval = 250 * time.Millisecond
+	// PongInviteTimeout is how long a game that has been heard from another device can be joined for.
+	PongInviteTimeout = 10 * time.Second
+)
+
+// pongGame holds the state of a game of Pong. The host keeps the left paddle, moves the ball and keeps the score, and the guest keeps the right paddle.
+type pongGame struct {
+	Playing        bool
+	Host           bool
+	Opponent       Person
+	Paddle         int
+	OpponentPaddle int
+	BallX          float64
+	BallY          float64
+	BallVX         float64
+	BallVY         float64
+	Score          int
+	OpponentScore  int
+	// Received is the number of packets heard from the opponent, and Latency is the time between the last two of them, which grows as packets are lost.
+	Received  int
+	Latency   time.Duration
+	lastStep  time.Time
+	lastSent  time.Time
+	lastHeard time.Time
+}
+
+// StartPong starts a game of Pong. If another device has started a game in the last PongInviteTimeout, the Device joins it, otherwise it hosts a new game.
+func (d *Device) StartPong(now time.Time) {
+	if !d.pong.Playing && d.pong.Opponent.ID != 0 && now.Sub(d.pong.lastHeard) < PongInviteTimeout {
+		d.pong.Playing = true
+		d.pong.Paddle = (PongHeight - PongPaddleHeight) / 2
+		return
+	}
+	d.pong = pongGame{
+		Playing:        true,
+		Host:           true,
+		Paddle:         (PongHeight - PongPaddleHeight) / 2,
+		OpponentPaddle: (PongHeight - PongPaddleHeight) / 2,
+		lastStep:       now,
+	}
+	d.pong.serve(1)
+}
+
+// serve puts the ball in the middle of the court and sends it towards a side, -1 for the host and 1 for the guest.
+func (p *pongGame) serve(direction float64) {
+	p.BallX = PongWidth / 2
+	p.BallY = PongHeight / 2
+	p.BallVX = direction * PongBallSpeed
+	p.BallVY = PongBallSpeed / 2
+}
+
+// step moves the ball of a hosted game on by some time, bouncing it off the walls and paddles and scoring when it gets past a paddle.
+func (p *pongGame) step(elapsed time.Duration) {
+	p.BallX += p.BallVX * elapsed.Seconds()
+	p.BallY += p.BallVY * elapsed.Seconds()
+	if p.BallY < 0 {
+		p.BallY = -p.BallY
+		p.BallVY = -p.BallVY
+	}
+	if p.BallY > PongHeight {
+		p.BallY = 2*PongHeight - p.BallY
+		p.BallVY = -p.BallVY
+	}
+	if p.BallX <= 2 {
+		if p.BallY >= float64(p.Paddle) && p.BallY <= float64(p.Paddle+PongPaddleHeight) {
+			p.BallX = 4 - p.BallX
+			p.BallVX = -p.BallVX
+		} else {
+			p.OpponentScore++
+			p.serve(1)
+		}
+	}
+	if p.BallX >= PongWidth-2 {
+		if p.BallY >= float64(p.OpponentPaddle) && p.BallY <= float64(p.OpponentPaddle+PongPaddleHeight) {
+			p.BallX = 2*(PongWidth-2) - p.BallX
+			p.BallVX = -p.BallVX
+		} else {
+			p.Score++
+			p.serve(-1)
+		}
+	}
+}
+
+// tickPong moves the ball and sends the state of the game to the other player.
+func (d *Device) tickPong(now time.Time) (err error) {
+	if !d.pong.Playing {
+		return nil
+	}
+	// The ball waits in the middle until someone has joined the game.
+	if d.pong.Host && d.pong.Opponent.ID != 0 {
+		d.pong.step(now.Sub(d.pong.lastStep))
+	}
+	d.pong.lastStep = now
+	if now.Sub(d.pong.lastSent) < PongSendInterval {
+		return nil
+	}
+	d.pong.lastSent = now
+	data := strconv.Itoa(d.pong.Paddle)
+	if d.pong.Host {
+		data = fmt.Sprintf("%d,%d,%d,%d,%d,%d,%d", d.pong.Paddle, int(d.pong.BallX), int(d.pong.BallY), int(d.pong.BallVX), int(d.pong.BallVY), d.pong.Score, d.pong.OpponentScore)
+	}
+	return d.SendGamePacket(pongGameName, []byte(data))
+}
+
+// stopPong stops the game when the Pong screen is left.
+func (d *Device) stopPong() (err error) {
+	d.pong.Playing = false
+	return nil
+}
+
+// receivePong handles a Pong packet from another device. A packet with the ball in it is from a host, so it can be joined as a guest.
+func (d *Device) receivePong(packet GamePacket, now time.Time) (err error) {
+	fields := strings.Split(string(packet.Data), ",")
+	values := make([]int, len(fields))
+	for i, field := range fields {
+		values[i], err = strconv.Atoi(field)
+		if err != nil {
+			return ErrInvalidGamePacket
+		}
+	}
+	if len(values) != 1 && len(values) != 7 {
+		return ErrInvalidGamePacket
+	}
+	fromHost := len(values) == 7
+
+	if !d.pong.Playing {
+		// Remember the game so that it can be joined from the Games menu.
+		if fromHost {
+			d.pong = pongGame{Opponent: packet.Person, lastHeard: now}
+		}
+		return nil
+	}
+	if d.pong.Opponent.ID == 0 && d.pong.Host && !fromHost {
+		d.pong.Opponent = packet.Person
+	}
+	if packet.Person.ID != d.pong.Opponent.ID || fromHost == d.pong.Host {
+		return nil
+	}
+
+	d.pong.Received++
+	if !d.pong.lastHeard.IsZero() {
+		d.pong.Latency = now.Sub(d.pong.lastHeard)
+	}
+	d.pong.lastHeard = now
+	d.pong.OpponentPaddle = values[0]
+	if fromHost {
+		d.pong.BallX = float64(values[1])
+		d.pong.BallY = float64(values[2])
+		d.pong.BallVX = float64(values[3])
+		d.pong.BallVY = float64(values[4])
+		d.pong.OpponentScore = values[5]
+		d.pong.Score = values[6]
+	}
+	return nil
+}
+
+// processPongInputEvent moves the paddle with up and down, and leaves the game with accept.
+func processPongInputEvent(d *Device, inputEvent InputEvent) (handled bool, err error) {
+	switch inputEvent {
+	case InputEventUp:
+		d.pong.Paddle -= PongPaddleStep
+		if d.pong.Paddle < 0 {
+			d.pong.Paddle = 0
+		}
+		return true, nil
+	case InputEventDown:
+		d.pong.Paddle += PongPaddleStep
+		if d.pong.Paddle > PongHeight-PongPaddleHeight {
+			d.pong.Paddle = PongHeight - PongPaddleHeight
+		}
+		return true, nil
+	case InputEventAccept:
+		d.pong.Playing = false
+		return true, d.GoBackState()
+	}
+	return false, nil
+}
+
+// drawPong draws the court, with the score and the time between packets from the opponent in the title.
+func drawPong(d *Device, img Canvas, layout Layout) (err error) {
+	palette := d.Palette()
+	dimensions := img.Bounds()
+	title := fmt.Sprintf("%d-%d waiting", d.pong.Score, d.pong.OpponentScore)
+	if d.pong.Received > 0 {
+		title = fmt.Sprintf("%d-%d %dms", d.pong.Score, d.pong.OpponentScore, d.pong.Latency.Milliseconds())
+	}
+	drawTitleBar(img, layout, palette, title)
+
+	// Scale the court to fit below the title.
+	courtHeight := dimensions.Dy() - layout.TitleHeight
+	toScreen := func(x float64, y float64) (int, int) {
+		return int(x) * dimensions.Dx() / PongWidth, layout.TitleHeight + int(y)*courtHeight/PongHeight
+	}
+	left, right := d.pong.Paddle, d.pong.OpponentPaddle
+	if !d.pong.Host {
+		left, right = right, left
+	}
+	_, top := toScreen(0, float64(left))
+	_, bottom := toScreen(0, float64(left+PongPaddleHeight))
+	drawFilledBox(img, 0, top, 1, bottom, palette.Text)
+	_, top = toScreen(0, float64(right))
+	_, bottom = toScreen(0, float64(right+PongPaddleHeight))
+	drawFilledBox(img, dimensions.Dx()-2, top, dimensions.Dx()-1, bottom, palette.Text)
+	x, y := toScreen(d.pong.BallX, d.pong.BallY)
+	drawFilledBox(img, x-1, y-1, x+1, y+1, palette.Cursor)
+	return nil
+}
@@ -0,0 +1,94 @@
+package picodoomsdaymessenger
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGamePacketBytesConversion(t *testing.T) {
+	packet := GamePacket{Game: "pong", Person: Person{ID: 1234}, Data: []byte("1,2")}
+	output, err := BytesToGamePacket(GamePacketToBytes(packet))
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	if output.Game != packet.Game || output.Person != packet.Person || string(output.Data) != string(packet.Data) {
+		t.Errorf("The packet is not correct, have: %+v want: %+v", output, packet)
+	}
+	_, err = BytesToGamePacket([]byte("gamepong"))
+	if err != ErrInvalidGamePacket {
+		t.Errorf("The error should be ErrInvalidGamePacket but is %v", err)
+	}
+}
+
+func TestPongOverRadio(t *testing.T) {
+	// Create two Machines that can hear each other.
+	host, err := NewDevice()
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	host.SelfIdentity = Person{Name: "Host", ID: 1}
+	guest, err := NewDevice()
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	guest.SelfIdentity = Person{Name: "Guest", ID: 2}
+	host.SendUsingRadio = func(packet []byte) (err error) {
+		return guest.ReceiveFromRadio(packet)
+	}
+	guest.SendUsingRadio = func(packet []byte) (err error) {
+		return host.ReceiveFromRadio(packet)
+	}
+
+	now := time.Now()
+	host.StartPong(now)
+	host.ChangeStateWithHistory(&host.StatePong)
+	err = host.Tick(now)
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	if guest.pong.Opponent.ID != 1 {
+		t.Errorf("The guest should have heard the game of the host, but the opponent is %v", guest.pong.Opponent)
+	}
+
+	guest.ProcessInputEvent(InputEventOpenMainMenu)
+	guest.StartPong(now)
+	guest.ChangeStateWithHistory(&guest.StatePong)
+	if guest.pong.Host {
+		t.Errorf("The guest should have joined the game of the host, not hosted its own")
+	}
+	guest.ProcessInputEvent(InputEventUp)
+	err = guest.Tick(now)
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	if host.pong.Opponent.ID != 2 {
+		t.Errorf("The host should have heard the guest join, but the opponent is %v", host.pong.Opponent)
+	}
+	if host.pong.OpponentPaddle != guest.pong.Paddle {
+		t.Errorf("The host should see the guest paddle at %v but sees it at %v", guest.pong.Paddle, host.pong.OpponentPaddle)
+	}
+
+	// The ball should move once the guest has joined, and the guest should see it move.
+	later := now.Add(time.Second)
+	err = host.Tick(later)
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	if host.pong.BallX == PongWidth/2 {
+		t.Errorf("The ball should have moved")
+	}
+	if guest.pong.BallX != float64(int(host.pong.BallX)) {
+		t.Errorf("The guest should see the ball at %v but sees it at %v", int(host.pong.BallX), guest.pong.BallX)
+	}
+
+	_, err = GetFrame(DisplaySSD1306.Bounds(), guest)
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+
+	// Leaving the game should stop it.
+	guest.ProcessInputEvent(InputEventAccept)
+	if guest.pong.Playing || guest.State == &guest.StatePong {
+		t.Errorf("The guest should have left the game")
+	}
+}
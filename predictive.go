@@ -0,0 +1,170 @@
+package picodoomsdaymessenger
+
+import "strings"
+
+// InputMethodPredictive types whole words by pressing each number key once, like T9 on an old phone. Up and down choose between the words that match the keys.
+const InputMethodPredictive InputMethod = "Predictive"
+
+// PredictiveWords is the word list of predictive text, most common first, so that the first candidate is usually the right one.
+var PredictiveWords = strings.Fields(`
+i a the to you and is it we are on in of at for me my no yes ok not be go do so if up
+can will have here there what where when who how now come need help safe water food
+with this that all get got going see am was out back home from your our us they them
+he she his her him just one two three four five ten time day night today tomorrow
+soon later wait stay leave left right north south east west near far road bridge
+river camp house town car fuel power radio battery signal message call meet meeting
+point shelter fire medic doctor hurt injured sick cold hot rain snow storm dark
+light open closed blocked clear danger run hide quiet people family friend group
+alone lost found find send sent know think want love good bad fine well sorry
+please thanks thank hello hi bye okay copy over roger
+`)
+
+// predictiveState is the word that is being typed with predictive text.
+type predictiveState struct {
+	// Keys are the number keys that have been pressed for the word, one per letter.
+	Keys []InputEvent
+	// Candidate is the index of the word that is chosen from the candidates that match the Keys.
+	Candidate int
+}
+
+// predictiveLetterKeys returns the number key that types each letter in a KeyboardLayout.
+func predictiveLetterKeys(layout *KeyboardLayout) map[rune]InputEvent {
+	keys := make(map[rune]InputEvent)
+	for inputEvent, button := range layout.Buttons {
+		for _, character := range button.Characters {
+			runes := []rune(character)
+			if len(runes) == 1 && runes[0] >= 'a' && runes[0] <= 'z' {
+				keys[runes[0]] = inputEvent
+			}
+		}
+	}
+	return keys
+}
+
+// PredictiveCandidates returns the words that match the number keys pressed for the word being typed, best first.
+// Words from the PredictiveWords that are exactly as long come first, then the start of longer words. If there are none, the first letter of each key is used, so that something can always be typed.
+func (d *Device) PredictiveCandidates() (words []string) {
+	keys := d.predictive.Keys
+	if len(keys) == 0 {
+		return nil
+	}
+	letterKeys := predictiveLetterKeys(d.KeyboardLayout())
+	exact, prefixes := []string{}, []string{}
+	seen := make(map[string]bool)
+	for _, word := range PredictiveWords {
+		runes := []rune(word)
+		if len(runes) < len(keys) {
+			continue
+		}
+		matches := true
+		for i, key := range keys {
+			if letterKeys[runes[i]] != key {
+				matches = false
+				break
+			}
+		}
+		if !matches {
+			continue
+		}
+		prefix := string(runes[:len(keys)])
+		if seen[prefix] {
+			continue
+		}
+		seen[prefix] = true
+		if len(runes) == len(keys) {
+			exact = append(exact, prefix)
+		} else {
+			prefixes = append(prefixes, prefix)
+		}
+	}
+	words = append(exact, prefixes...)
+	if len(words) == 0 {
+		fallback := ""
+		for _, key := range keys {
+			fallback += d.KeyboardLayout().Buttons[key].Characters[0]
+		}
+		words = []string{fallback}
+	}
+	return words
+}
+
+// predictiveWord returns the candidate that is chosen for the word being typed, in the case of the ComposeMode.
+func (d *Device) predictiveWord() string {
+	words := d.PredictiveCandidates()
+	if len(words) == 0 {
+		return ""
+	}
+	return d.composeModeCase(words[d.predictive.Candidate%len(words)])
+}
+
+// commitPredictiveWord adds the chosen word to a buffer, and starts a new word.
+func (d *Device) commitPredictiveWord(buffer *string) {
+	*buffer += d.predictiveWord()
+	d.predictive = predictiveState{}
+}
+
+// processPredictiveInputEvent handles an InputEvent while typing with predictive text. It returns false if the InputEvent is not used, once the word being typed has been added to the KeyboardBuffer.
+// Keys with letters add to the word, up and down choose between the candidates, left removes the last key, and the other number keys finish the word and type their first character, such as a space with 0.
+func (d *Device) processPredictiveInputEvent(inputEvent InputEvent) (handled bool, err error) {
+	buffer := &d.Conversations[d.CurrentConversationIndex].KeyboardBuffer
+	if button := d.KeyboardLayout().Buttons[inputEvent]; button != nil && d.ComposeMode != ComposeModeNumbers {
+		if predictiveKey(button) {
+			d.predictive.Keys = append(d.predictive.Keys, inputEvent)
+			d.predictive.Candidate = 0
+			return true, nil
+		}
+		d.commitPredictiveWord(buffer)
+		*buffer += d.composeModeCase(button.Characters[0])
+		return true, nil
+	}
+	if len(d.predictive.Keys) > 0 {
+		switch inputEvent {
+		case InputEventUp:
+			count := len(d.PredictiveCandidates())
+			d.predictive.Candidate = (d.predictive.Candidate + count - 1) % count
+			return true, nil
+		case InputEventDown:
+			d.predictive.Candidate = (d.predictive.Candidate + 1) % len(d.PredictiveCandidates())
+			return true, nil
+		case InputEventLeft:
+			d.predictive.Keys = d.predictive.Keys[:len(d.predictive.Keys)-1]
+			d.predictive.Candidate = 0
+			return true, nil
+		case InputEventAccept:
+			// When Accept does not send, it finishes the word.
+			if d.sendKey() != InputEventAccept {
+				d.commitPredictiveWord(buffer)
+				return true, nil
+			}
+		}
+	}
+	d.commitPredictiveWord(buffer)
+	return false, nil
+}
+
+// predictiveKey returns true if a KeyboardButton types letters, so that it is part of a word in predictive text.
+func predictiveKey(button *KeyboardButton) bool {
+	for _, character := range button.Characters {
+		if character >= "a" && character <= "z" && len(character) == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// predictiveHint returns the candidates for the word being typed, with the chosen one in brackets.
+func (d *Device) predictiveHint() string {
+	words := d.PredictiveCandidates()
+	if len(words) <= 1 {
+		return ""
+	}
+	hint := make([]string, len(words))
+	for i, word := range words {
+		word = d.composeModeCase(word)
+		if i == d.predictive.Candidate%len(words) {
+			word = "[" + word + "]"
+		}
+		hint[i] = word
+	}
+	return strings.Join(hint, " ")
+}
@@ -0,0 +1,59 @@
+package picodoomsdaymessenger
+
+import "testing"
+
+func TestPredictiveText(t *testing.T) {
+	device, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	var sent Message
+	device.SendUsingRadio = func(packet []byte) (err error) {
+		sent, err = device.BytesToMessage(packet)
+		return err
+	}
+	device.Settings.InputMethod = InputMethodPredictive
+	c := device.NewConversation(Person{Name: "Bob", ID: 7})
+	device.CurrentConversationIndex = 0
+	device.State = &device.StateConversationReader
+	device.clearPendingCharacter()
+	press := func(inputEvents ...InputEvent) {
+		t.Helper()
+		for _, inputEvent := range inputEvents {
+			if err := device.ProcessInputEvent(inputEvent); err != nil {
+				t.Fatalf("The error should be nil but is %v", err)
+			}
+		}
+	}
+	// 4-6-6-3 is both "home" and "good", and "home" comes first in the word list.
+	press(InputEventNumber4, InputEventNumber6, InputEventNumber6, InputEventNumber3)
+	if text := device.ComposerText(); text != "home" {
+		t.Errorf("expected the first candidate to be %q, got %q", "home", text)
+	}
+	if hint := device.KeyboardHint(); hint != "[home] good" {
+		t.Errorf("expected the hint to list the candidates, got %q", hint)
+	}
+	press(InputEventDown)
+	if text := device.ComposerText(); text != "good" {
+		t.Errorf("expected down to choose %q, got %q", "good", text)
+	}
+	// 0 finishes the word with a space.
+	press(InputEventNumber0, InputEventNumber4, InputEventNumber3, InputEventNumber5, InputEventNumber7)
+	if text := device.ComposerText(); text != "good help" {
+		t.Errorf("expected %q, got %q", "good help", text)
+	}
+	// Keys that match no word still type their first letters.
+	press(InputEventNumber0, InputEventNumber9, InputEventNumber9)
+	if text := device.ComposerText(); text != "good help ww" {
+		t.Errorf("expected %q, got %q", "good help ww", text)
+	}
+	// Left takes back one key at a time.
+	press(InputEventLeft, InputEventLeft, InputEventAccept)
+	err = sendQueued(device)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if sent.Text != "good help " || c.KeyboardBuffer != "" || device.ComposerText() != "" {
+		t.Errorf("expected %q to be sent and the compose bar emptied, got %q and %q", "good help ", sent.Text, device.ComposerText())
+	}
+}
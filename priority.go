@@ -0,0 +1,104 @@
+package picodoomsdaymessenger
+
+import (
+	"image/color"
+	"time"
+)
+
+// Priority is how urgent a Message is.
+type Priority byte
+
+const (
+	// PriorityNormal is the Priority of an everyday Message.
+	PriorityNormal Priority = iota
+	// PriorityEmergency is the Priority of a Message that needs attention straight away. It fills the screen of the devices that receive it.
+	PriorityEmergency
+)
+
+// emergencyMarker comes straight after the "doom" prefix of a Message with PriorityEmergency. Messages with PriorityNormal are sent without it, so they can still be read by older devices.
+const emergencyMarker = '!'
+
+// DeviceEventEmergencyReceived happens when a Message with PriorityEmergency is received from the radio.
+const DeviceEventEmergencyReceived DeviceEvent = "emergency received"
+
+func init() {
+	// Emergency messages alternate red and white, so they are not mistaken for a normal message or an SOS.
+	red := color.RGBA{255, 0, 0, 0}
+	white := color.RGBA{255, 255, 255, 0}
+	animation := &LEDAnimation{FrameDuration: 200 * time.Millisecond}
+	for i := 0; i < 8; i++ {
+		animation.Frames = append(animation.Frames, [6]color.RGBA{red, white, red, white, red, white}, [6]color.RGBA{white, red, white, red, white, red})
+	}
+	LEDNotifications[DeviceEventEmergencyReceived] = animation
+}
+
+// ToggleComposerPriority switches the message being composed in a Conversation between PriorityNormal and PriorityEmergency.
+func (d *Device) ToggleComposerPriority(c *Conversation) {
+	if c.Priority == PriorityEmergency {
+		c.Priority = PriorityNormal
+	} else {
+		c.Priority = PriorityEmergency
+	}
+}
+
+// TogglePinnedMessage pins the highlighted Message of a Conversation to the top of the conversation reader, or unpins it if it is already pinned.
+// Only messages with PriorityEmergency can be pinned.
+func (d *Device) TogglePinnedMessage(c *Conversation) {
+	if c.HighlightedMessageIndex < 0 || c.HighlightedMessageIndex >= len(c.Messages) {
+		return
+	}
+	highlighted := c.Messages[c.HighlightedMessageIndex]
+	if c.Pinned != nil && *c.Pinned == highlighted {
+		c.Pinned = nil
+		return
+	}
+	if highlighted.Priority != PriorityEmergency {
+		return
+	}
+	c.Pinned = &highlighted
+}
+
+// receiveEmergency shows a Message with PriorityEmergency on the whole screen.
+func (d *Device) receiveEmergency(m Message) (err error) {
+	d.emergency = m
+	if d.State != &d.StateEmergencyAlert {
+		err = d.ChangeStateWithHistory(&d.StateEmergencyAlert)
+		if err != nil {
+			return err
+		}
+	}
+	return d.Notify(DeviceEventEmergencyReceived)
+}
+
+// processEmergencyAlertInputEvent dismisses the emergency alert with accept, and ignores the other navigation keys so that it is not dismissed by accident.
+func processEmergencyAlertInputEvent(d *Device, inputEvent InputEvent) (handled bool, err error) {
+	switch inputEvent {
+	case InputEventAccept:
+		return true, d.GoBackState()
+	case InputEventUp, InputEventDown, InputEventLeft, InputEventRight:
+		return true, nil
+	}
+	return false, nil
+}
+
+// drawEmergencyAlert fills the screen with the last emergency Message that was received, with who sent it.
+func drawEmergencyAlert(d *Device, img Canvas, layout Layout) (err error) {
+	palette := d.Palette()
+	drawFilledBox(img, 0, 0, img.Bounds().Dx(), img.Bounds().Dy(), palette.StatusAlert)
+	// Draw the text in the background color, so it shows up on the alert color.
+	palette.Text = palette.Background
+	palette.TitleBar = palette.StatusAlert
+	palette.TitleText = palette.Background
+	drawTextPage(img, layout, palette, "!! EMERGENCY !!", d.PersonName(d.emergency.Person.ID)+":\n"+d.emergency.Text, 0)
+	return nil
+}
+
+// drawPinnedMessage draws the pinned Message of a Conversation in a band at the top of the conversation reader, below the title if there is one.
+func drawPinnedMessage(img Canvas, layout Layout, palette Palette, m Message) {
+	top := layout.TitleHeight
+	if layout.Compact {
+		top = 0
+	}
+	drawFilledBox(img, 0, top, img.Bounds().Dx(), top+layout.LineHeight+1, palette.StatusAlert)
+	drawTextFace(img, layout.Face, 0, top+layout.LineHeight-1, m.DisplayText(), palette.Background)
+}
@@ -0,0 +1,130 @@
+package picodoomsdaymessenger
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestEmergencyMessageBytes(t *testing.T) {
+	device, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	packet, err := device.MesageToBytes(Message{Text: "help", Person: Person{Name: "Bob", ID: 7}, Priority: PriorityEmergency})
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if !bytes.Equal(packet, []byte("doom!7\xccBob\xcchelp")) {
+		t.Errorf("expected the emergency marker after the prefix, got %q", packet)
+	}
+	message, err := device.BytesToMessage(packet)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if message.Priority != PriorityEmergency || message.Person.ID != 7 || message.Text != "help" {
+		t.Errorf("expected the emergency message back, got %+v", message)
+	}
+	message, err = device.BytesToMessage([]byte("doom7\xccBob\xcchello"))
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if message.Priority != PriorityNormal {
+		t.Errorf("expected a message without the marker to have normal priority, got %v", message.Priority)
+	}
+}
+
+func TestSendAndReceiveEmergency(t *testing.T) {
+	sender, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	receiver, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	receiver.State = &receiver.StateMainMenu
+	sender.SendUsingRadio = receiver.ReceiveFromRadio
+	c := sender.NewConversation(Person{Name: "Receiver", ID: receiver.SelfIdentity.ID})
+	sender.CurrentConversationIndex = 0
+	sender.State = &sender.StateConversationReader
+	c.KeyboardBuffer = "help"
+	sender.clearPendingCharacter()
+	err = sender.ProcessInputEvent(InputEventFunction3)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if c.Priority != PriorityEmergency {
+		t.Fatalf("expected function 3 to mark the message as an emergency")
+	}
+	err = sender.ProcessInputEvent(InputEventAccept)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if c.Priority != PriorityNormal {
+		t.Errorf("expected the next message to have normal priority")
+	}
+
+	err = sendQueued(sender)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if receiver.State != &receiver.StateEmergencyAlert {
+		t.Fatalf("expected the emergency to fill the screen, got %q", receiver.State.Title)
+	}
+	if receiver.LEDAnimation != LEDNotifications[DeviceEventEmergencyReceived] {
+		t.Errorf("expected the emergency LED animation to play")
+	}
+	_, err = GetFrame(DisplaySSD1306.Bounds(), receiver)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	err = receiver.ProcessInputEvent(InputEventDown)
+	if err != nil || receiver.State != &receiver.StateEmergencyAlert {
+		t.Errorf("expected the alert to ignore navigation, got %v", err)
+	}
+	err = receiver.ProcessInputEvent(InputEventAccept)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if receiver.State != &receiver.StateMainMenu {
+		t.Errorf("expected accept to dismiss the alert, got %q", receiver.State.Title)
+	}
+	if text := receiver.Conversations[0].Messages[0].DisplayText(); text != "!! help" {
+		t.Errorf("expected the emergency to be marked in the conversation, got %q", text)
+	}
+}
+
+func TestTogglePinnedMessage(t *testing.T) {
+	device, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	c := device.NewConversation(Person{Name: "Bob", ID: 7})
+	c.Messages = []Message{{Text: "help", Priority: PriorityEmergency}, {Text: "hello"}}
+	device.CurrentConversationIndex = 0
+	device.State = &device.StateConversationReader
+
+	c.HighlightedMessageIndex = 1
+	device.TogglePinnedMessage(c)
+	if c.Pinned != nil {
+		t.Errorf("expected a normal message not to be pinned")
+	}
+	c.HighlightedMessageIndex = 0
+	err = device.ProcessInputEvent(InputEventFunction4)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if c.Pinned == nil || c.Pinned.Text != "help" {
+		t.Fatalf("expected the emergency message to be pinned, got %+v", c.Pinned)
+	}
+	c.HighlightedMessageIndex = 1
+	_, err = GetFrame(DisplaySSD1306.Bounds(), device)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	c.HighlightedMessageIndex = 0
+	device.TogglePinnedMessage(c)
+	if c.Pinned != nil {
+		t.Errorf("expected the pinned message to be unpinned")
+	}
+}
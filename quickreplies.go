@@ -0,0 +1,159 @@
+package picodoomsdaymessenger
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// quickRepliesStorageKey is the key that the QuickReplies are saved with.
+const quickRepliesStorageKey = "quickreplies"
+
+// DefaultQuickReplies are the QuickReplies of a new Device.
+var DefaultQuickReplies = []string{"OK", "On my way", "Need help at camp"}
+
+// quickRepliesState is the quick reply that is being edited.
+type quickRepliesState struct {
+	// Draft is the text of the quick reply that is being written.
+	Draft string
+	// Editing is the index of the quick reply that is being changed, or -1 if a new one is being written.
+	Editing int
+}
+
+// SendQuickReply sends one of the QuickReplies to the current Conversation, and goes back to it.
+func (d *Device) SendQuickReply(index int) (err error) {
+	if index < 0 || index >= len(d.QuickReplies) || d.CurrentConversationIndex >= len(d.Conversations) {
+		return nil
+	}
+	err = d.SendMessage(d.Conversations[d.CurrentConversationIndex], d.QuickReplies[index])
+	if err != nil {
+		return err
+	}
+	return d.GoBackState()
+}
+
+// SetQuickReply changes the quick reply at an index, or adds a new one if the index is -1. Setting a quick reply to empty text deletes it.
+func (d *Device) SetQuickReply(index int, text string) (err error) {
+	text = strings.TrimSpace(text)
+	switch {
+	case index < 0 || index >= len(d.QuickReplies):
+		if text == "" {
+			return nil
+		}
+		d.QuickReplies = append(d.QuickReplies, text)
+	case text == "":
+		d.QuickReplies = append(d.QuickReplies[:index], d.QuickReplies[index+1:]...)
+	default:
+		d.QuickReplies[index] = text
+	}
+	return d.SaveQuickReplies()
+}
+
+// SaveQuickReplies writes the QuickReplies to the Storage of the Device. It does nothing if the Device has no Storage.
+func (d *Device) SaveQuickReplies() (err error) {
+	if d.Storage == nil {
+		return nil
+	}
+	data, err := json.Marshal(d.QuickReplies)
+	if err != nil {
+		return err
+	}
+	return d.Storage.Save(quickRepliesStorageKey, data)
+}
+
+// LoadQuickReplies reads the QuickReplies from the Storage of the Device. The DefaultQuickReplies are kept if none have been saved.
+func (d *Device) LoadQuickReplies() (err error) {
+	if d.Storage == nil {
+		return nil
+	}
+	data, err := d.Storage.Load(quickRepliesStorageKey)
+	if err == ErrStorageNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	replies := []string{}
+	err = json.Unmarshal(data, &replies)
+	if err != nil {
+		return err
+	}
+	d.QuickReplies = replies
+	return nil
+}
+
+// The quick reply menus are refreshed every time they are shown, so that they are up to date after a quick reply is edited.
+func init() {
+	stateSetups = append(stateSetups, func(s *States) {
+		s.StateQuickRepliesMenu.LoadAction = func(d *Device) (err error) {
+			d.UpdateQuickRepliesMenu()
+			return nil
+		}
+		s.StateEditQuickRepliesMenu.LoadAction = func(d *Device) (err error) {
+			d.UpdateEditQuickRepliesMenu()
+			return nil
+		}
+	})
+}
+
+// UpdateQuickRepliesMenu lists the QuickReplies to send, followed by an item to edit them.
+func (d *Device) UpdateQuickRepliesMenu() {
+	d.StateQuickRepliesMenu.Content = []MenuItem{GlobalMenuItemGoBack}
+	for i := range d.QuickReplies {
+		j := i
+		d.StateQuickRepliesMenu.Content = append(d.StateQuickRepliesMenu.Content, MenuItem{
+			Text: d.QuickReplies[j],
+			Action: func(d *Device) (err error) {
+				return d.SendQuickReply(j)
+			},
+			CursorIcon: CursorIconRightArrow,
+		})
+	}
+	d.StateQuickRepliesMenu.Content = append(d.StateQuickRepliesMenu.Content, QuickRepliesMenuItemEdit)
+	if d.StateQuickRepliesMenu.HighlightedItemIndex >= len(d.StateQuickRepliesMenu.Content) {
+		d.StateQuickRepliesMenu.HighlightedItemIndex = 0
+	}
+}
+
+// UpdateEditQuickRepliesMenu lists the QuickReplies to change, after an item to write a new one.
+func (d *Device) UpdateEditQuickRepliesMenu() {
+	d.StateEditQuickRepliesMenu.Content = []MenuItem{GlobalMenuItemGoBack, EditQuickRepliesMenuItemNew}
+	for i := range d.QuickReplies {
+		j := i
+		d.StateEditQuickRepliesMenu.Content = append(d.StateEditQuickRepliesMenu.Content, MenuItem{
+			Text: d.QuickReplies[j],
+			Action: func(d *Device) (err error) {
+				d.quickReplies = quickRepliesState{Draft: d.QuickReplies[j], Editing: j}
+				return d.ChangeStateWithHistory(&d.StateQuickReplyEditor)
+			},
+			CursorIcon: CursorIconRightArrow,
+		})
+	}
+	if d.StateEditQuickRepliesMenu.HighlightedItemIndex >= len(d.StateEditQuickRepliesMenu.Content) {
+		d.StateEditQuickRepliesMenu.HighlightedItemIndex = 0
+	}
+}
+
+// processQuickReplyEditorInputEvent types into the draft with the number keys, deletes with left and saves the quick reply with accept.
+func processQuickReplyEditorInputEvent(d *Device, inputEvent InputEvent) (handled bool, err error) {
+	if d.processTextEntryInputEvent(&d.quickReplies.Draft, inputEvent) {
+		return true, nil
+	}
+	if inputEvent != InputEventAccept {
+		return false, nil
+	}
+	err = d.SetQuickReply(d.quickReplies.Editing, d.finishTextEntry(&d.quickReplies.Draft))
+	if err != nil {
+		return true, err
+	}
+	return true, d.GoBackState()
+}
+
+// drawQuickReplyEditor draws the draft quick reply with the character that is being typed. Saving an empty quick reply deletes it.
+func drawQuickReplyEditor(d *Device, img Canvas, layout Layout) (err error) {
+	title := "Edit Reply"
+	if d.quickReplies.Editing < 0 {
+		title = "New Reply"
+	}
+	drawTextPage(img, layout, d.Palette(), title, d.quickReplies.Draft+d.pendingCharacter()+"_", 0)
+	return nil
+}
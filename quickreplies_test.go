@@ -0,0 +1,114 @@
+package picodoomsdaymessenger
+
+import (
+	"image"
+	"testing"
+)
+
+func TestSendQuickReply(t *testing.T) {
+	device, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	sent := [][]byte{}
+	device.SendUsingRadio = func(packet []byte) (err error) {
+		sent = append(sent, packet)
+		return nil
+	}
+	device.NewConversation(Person{Name: "Bob", ID: 7})
+	device.CurrentConversationIndex = 0
+	err = device.ChangeStateWithHistory(&device.StateConversationReader)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	err = device.ProcessInputEvent(InputEventFunction2)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if device.State != &device.StateQuickRepliesMenu {
+		t.Fatalf("expected function 2 to open the quick replies, got %q", device.State.Title)
+	}
+	if len(device.StateQuickRepliesMenu.Content) != len(DefaultQuickReplies)+2 || device.StateQuickRepliesMenu.Content[2].Text != "On my way" {
+		t.Fatalf("expected the default quick replies, got %+v", device.StateQuickRepliesMenu.Content)
+	}
+	err = device.StateQuickRepliesMenu.Content[2].Action(device)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if device.State != &device.StateConversationReader {
+		t.Errorf("expected to go back to the conversation, got %q", device.State.Title)
+	}
+	err = sendQueued(device)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if len(sent) != 1 {
+		t.Fatalf("expected the quick reply to be sent, got %d packets", len(sent))
+	}
+	message, err := device.BytesToMessage(sent[0])
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if message.Text != "On my way" {
+		t.Errorf("expected %q to be sent, got %q", "On my way", message.Text)
+	}
+}
+
+func TestEditQuickReplies(t *testing.T) {
+	device, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	storage := MemoryStorage{}
+	device.Storage = storage
+	err = device.ChangeStateWithHistory(&device.StateEditQuickRepliesMenu)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	err = EditQuickRepliesMenuItemNew.Action(device)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	// Type "hi" with 4 twice and 4 three times, starting a new letter with a different key in between.
+	for _, inputEvent := range []InputEvent{InputEventNumber4, InputEventNumber4, InputEventNumber1, InputEventLeft, InputEventNumber4, InputEventNumber4, InputEventNumber4} {
+		err = device.ProcessInputEvent(inputEvent)
+		if err != nil {
+			t.Fatalf("The error should be nil but is %v", err)
+		}
+	}
+	if _, err := GetFrame(image.Rect(0, 0, 128, 64), device); err != nil {
+		t.Fatalf("The error drawing the editor should be nil but is %v", err)
+	}
+	err = device.ProcessInputEvent(InputEventAccept)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if device.State != &device.StateEditQuickRepliesMenu {
+		t.Errorf("expected to go back to the quick replies, got %q", device.State.Title)
+	}
+	if len(device.QuickReplies) != 4 || device.QuickReplies[3] != "hi" {
+		t.Fatalf("expected a new quick reply saying %q, got %q", "hi", device.QuickReplies)
+	}
+
+	// Saving an empty quick reply deletes it.
+	err = device.SetQuickReply(0, "")
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if len(device.QuickReplies) != 3 || device.QuickReplies[0] != "On my way" {
+		t.Fatalf("expected the first quick reply to be deleted, got %q", device.QuickReplies)
+	}
+
+	restored, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	restored.Storage = storage
+	err = restored.LoadQuickReplies()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if len(restored.QuickReplies) != 3 || restored.QuickReplies[2] != "hi" {
+		t.Errorf("expected the quick replies to be loaded, got %q", restored.QuickReplies)
+	}
+}
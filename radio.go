@@ -0,0 +1,223 @@
+package picodoomsdaymessenger
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// Radio is a LoRa radio that can be configured by the Device. Settings that change how the radio operates are applied through it.
+type Radio interface {
+	// SetFrequency tunes the radio to a frequency in MHz.
+	SetFrequency(frequencyMHz float64) (err error)
+	// SetModemConfig changes the LoRa modem parameters of the radio.
+	SetModemConfig(config ModemConfig) (err error)
+	// SetTxPower changes the power in dBm that the radio sends with.
+	SetTxPower(dBm int) (err error)
+	// ChannelActive uses channel activity detection to find out if another device is sending.
+	ChannelActive() (busy bool, err error)
+	// ChannelRSSI returns the strength of the signal on the frequency that the radio is tuned to, in dBm, or RSSIUnknown if the radio cannot measure it.
+	ChannelRSSI() (dBm int, err error)
+	// SetPreambleLength changes the number of preamble symbols that the radio sends in front of every packet.
+	SetPreambleLength(symbols int) (err error)
+	// Sleep stops the radio from listening, so that it uses as little power as it can. Sending a packet or calling Listen wakes it.
+	Sleep() (err error)
+	// Listen wakes the radio and starts receiving again.
+	Listen() (err error)
+}
+
+// ModemConfig holds the LoRa modem parameters. A higher SpreadingFactor or CodingRate and a lower BandwidthHz give more range, but use more airtime.
+type ModemConfig struct {
+	// SpreadingFactor is between 7 and 12.
+	SpreadingFactor int
+	// BandwidthHz is the signal bandwidth in Hz.
+	BandwidthHz int
+	// CodingRate is the denominator of the 4/x coding rate, between 5 and 8.
+	CodingRate int
+}
+
+// DefaultModemConfig is the default configuration of the RFM9x modem, SF7 at 125kHz with a 4/5 coding rate.
+var DefaultModemConfig = ModemConfig{
+	SpreadingFactor: 7,
+	BandwidthHz:     125000,
+	CodingRate:      5,
+}
+
+// PreambleLength is the number of preamble symbols that the radio sends in front of every packet, unless low power listening needs a longer one.
+const PreambleLength = 8
+
+// Airtime estimates how long a packet of a length in bytes takes to send with the ModemConfig, with an explicit header and a CRC, as worked out in the Semtech SX1276 datasheet.
+func (m ModemConfig) Airtime(length int) time.Duration {
+	return m.airtimeWithPreamble(length, PreambleLength)
+}
+
+// airtimeWithPreamble estimates how long a packet takes to send with the ModemConfig, like Airtime, with a number of preamble symbols.
+func (m ModemConfig) airtimeWithPreamble(length int, preambleLength int) time.Duration {
+	if m.SpreadingFactor <= 0 || m.BandwidthHz <= 0 {
+		return 0
+	}
+	symbol := float64(int(1)<<uint(m.SpreadingFactor)) / float64(m.BandwidthHz)
+	// Low data rate optimization is needed when a symbol takes longer than 16ms.
+	lowDataRate := 0
+	if symbol > 0.016 {
+		lowDataRate = 1
+	}
+	preamble := (float64(preambleLength) + 4.25) * symbol
+	bits := float64(8*length - 4*m.SpreadingFactor + 28 + 16)
+	payloadSymbols := 8 + math.Max(math.Ceil(bits/float64(4*(m.SpreadingFactor-2*lowDataRate)))*float64(m.CodingRate), 0)
+	return time.Duration((preamble + payloadSymbols*symbol) * float64(time.Second))
+}
+
+// FrequencyPreset is a named operating frequency that the user can pick from the Settings.
+type FrequencyPreset struct {
+	Name         string
+	FrequencyMHz float64
+}
+
+// FrequencyPresets is the list of regional frequencies that can be selected. The first preset is the default.
+var FrequencyPresets = []FrequencyPreset{
+	{"EU 868.0", 868.0},
+	{"EU 868.1", 868.1},
+	{"EU 868.3", 868.3},
+	{"EU 868.5", 868.5},
+	{"EU 869.525", 869.525},
+	{"IN 865.0625", 865.0625},
+	{"US 903.9", 903.9},
+	{"US 915.0", 915.0},
+	{"AU 916.8", 916.8},
+	{"AS 923.2", 923.2},
+}
+
+// SpreadingFactors is the list of spreading factors that can be selected.
+var SpreadingFactors = []int{7, 8, 9, 10, 11, 12}
+
+// Bandwidths is the list of signal bandwidths in Hz that can be selected.
+var Bandwidths = []int{7800, 10400, 15600, 20800, 31250, 41700, 62500, 125000, 250000, 500000}
+
+// CodingRates is the list of coding rate denominators that can be selected.
+var CodingRates = []int{5, 6, 7, 8}
+
+// SetRadio attaches a Radio to the Device and applies the current Settings to it.
+func (d *Device) SetRadio(r Radio) (err error) {
+	d.Radio = r
+	return d.ApplyRadioSettings()
+}
+
+// ApplyRadioSettings pushes the radio related Settings to the Radio. It does nothing if no Radio is attached.
+func (d *Device) ApplyRadioSettings() (err error) {
+	if d.Radio == nil {
+		return nil
+	}
+	err = d.Radio.SetFrequency(d.Settings.FrequencyMHz)
+	if err != nil {
+		return err
+	}
+	err = d.Radio.SetModemConfig(d.Settings.Modem)
+	if err != nil {
+		return err
+	}
+	err = d.Radio.SetPreambleLength(d.preambleLength())
+	if err != nil {
+		return err
+	}
+	return d.Radio.SetTxPower(d.radioTxPower())
+}
+
+// SetFrequency stores a new operating frequency in the Settings and applies it to the Radio.
+func (d *Device) SetFrequency(frequencyMHz float64) (err error) {
+	d.Settings.FrequencyMHz = frequencyMHz
+	if d.Radio == nil {
+		return nil
+	}
+	return d.Radio.SetFrequency(frequencyMHz)
+}
+
+// SetModemConfig stores new modem parameters in the Settings and applies them to the Radio. The preamble length is applied again too, as it is counted in symbols, whose length depends on the modem parameters.
+func (d *Device) SetModemConfig(config ModemConfig) (err error) {
+	d.Settings.Modem = config
+	if d.Radio == nil {
+		return nil
+	}
+	err = d.Radio.SetModemConfig(config)
+	if err != nil {
+		return err
+	}
+	return d.Radio.SetPreambleLength(d.preambleLength())
+}
+
+// choiceMenuItems creates a MenuItem for every choice. Selecting an item runs choose with its index, and the item for which isChosen is true is shown as a checked box.
+func choiceMenuItems(names []string, isChosen func(d *Device, i int) bool, choose func(d *Device, i int) (err error)) (items []MenuItem) {
+	for i := 0; i < len(names); i++ {
+		// Define a seperate variable to seperate the increasing i from the functions defined here.
+		j := i
+		items = append(items, MenuItem{
+			Text: names[j],
+			Action: func(d *Device) (err error) {
+				return choose(d, j)
+			},
+			GetCursorData: func(d *Device) (data any, err error) {
+				return isChosen(d, j), nil
+			},
+			CursorIcon: CursorIconBox,
+		})
+	}
+	return items
+}
+
+// frequencyMenuItems creates a MenuItem for every FrequencyPreset.
+func frequencyMenuItems() (items []MenuItem) {
+	names := make([]string, len(FrequencyPresets))
+	for i, preset := range FrequencyPresets {
+		names[i] = preset.Name
+	}
+	return choiceMenuItems(names, func(d *Device, i int) bool {
+		return d.Settings.FrequencyMHz == FrequencyPresets[i].FrequencyMHz
+	}, func(d *Device, i int) (err error) {
+		return d.SetFrequency(FrequencyPresets[i].FrequencyMHz)
+	})
+}
+
+// spreadingFactorMenuItems creates a MenuItem for every spreading factor in SpreadingFactors.
+func spreadingFactorMenuItems() (items []MenuItem) {
+	names := make([]string, len(SpreadingFactors))
+	for i, spreadingFactor := range SpreadingFactors {
+		names[i] = fmt.Sprintf("SF%d", spreadingFactor)
+	}
+	return choiceMenuItems(names, func(d *Device, i int) bool {
+		return d.Settings.Modem.SpreadingFactor == SpreadingFactors[i]
+	}, func(d *Device, i int) (err error) {
+		config := d.Settings.Modem
+		config.SpreadingFactor = SpreadingFactors[i]
+		return d.SetModemConfig(config)
+	})
+}
+
+// bandwidthMenuItems creates a MenuItem for every bandwidth in Bandwidths.
+func bandwidthMenuItems() (items []MenuItem) {
+	names := make([]string, len(Bandwidths))
+	for i, bandwidth := range Bandwidths {
+		names[i] = fmt.Sprintf("%g kHz", float64(bandwidth)/1000)
+	}
+	return choiceMenuItems(names, func(d *Device, i int) bool {
+		return d.Settings.Modem.BandwidthHz == Bandwidths[i]
+	}, func(d *Device, i int) (err error) {
+		config := d.Settings.Modem
+		config.BandwidthHz = Bandwidths[i]
+		return d.SetModemConfig(config)
+	})
+}
+
+// codingRateMenuItems creates a MenuItem for every coding rate in CodingRates.
+func codingRateMenuItems() (items []MenuItem) {
+	names := make([]string, len(CodingRates))
+	for i, codingRate := range CodingRates {
+		names[i] = fmt.Sprintf("4/%d", codingRate)
+	}
+	return choiceMenuItems(names, func(d *Device, i int) bool {
+		return d.Settings.Modem.CodingRate == CodingRates[i]
+	}, func(d *Device, i int) (err error) {
+		config := d.Settings.Modem
+		config.CodingRate = CodingRates[i]
+		return d.SetModemConfig(config)
+	})
+}
@@ -0,0 +1,336 @@
+package picodoomsdaymessenger
+
+import (
+	"errors"
+	"image"
+	"testing"
+	"time"
+)
+
+// testRadio is a Radio that records the settings applied to it.
+type testRadio struct {
+	frequencyMHz   float64
+	modemConfig    ModemConfig
+	txPower        int
+	busy           int
+	rssi           map[float64]int
+	preambleLength int
+	asleep         bool
+	err            error
+}
+
+func (r *testRadio) SetFrequency(frequencyMHz float64) (err error) {
+	r.frequencyMHz = frequencyMHz
+	return r.err
+}
+
+func (r *testRadio) SetModemConfig(config ModemConfig) (err error) {
+	r.modemConfig = config
+	return r.err
+}
+
+func (r *testRadio) SetTxPower(dBm int) (err error) {
+	r.txPower = dBm
+	return r.err
+}
+
+func (r *testRadio) SetPreambleLength(symbols int) (err error) {
+	r.preambleLength = symbols
+	return r.err
+}
+
+func (r *testRadio) Sleep() (err error) {
+	r.asleep = true
+	return r.err
+}
+
+func (r *testRadio) Listen() (err error) {
+	r.asleep = false
+	return r.err
+}
+
+// ChannelActive is busy until it has been checked busy times.
+func (r *testRadio) ChannelActive() (busy bool, err error) {
+	if r.busy > 0 {
+		r.busy--
+		return true, r.err
+	}
+	return false, r.err
+}
+
+// ChannelRSSI returns the RSSI set for the frequency that the testRadio is tuned to.
+func (r *testRadio) ChannelRSSI() (dBm int, err error) {
+	return r.rssi[r.frequencyMHz], r.err
+}
+
+func TestSetRadio(t *testing.T) {
+	// Create a new Machine
+	device, err := NewDevice()
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	radio := &testRadio{}
+	err = device.SetRadio(radio)
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	if radio.frequencyMHz != FrequencyPresets[0].FrequencyMHz {
+		t.Errorf("The radio frequency should be %v but is %v", FrequencyPresets[0].FrequencyMHz, radio.frequencyMHz)
+	}
+	if radio.modemConfig != DefaultModemConfig {
+		t.Errorf("The radio modem config should be %v but is %v", DefaultModemConfig, radio.modemConfig)
+	}
+}
+
+func TestSetFrequency(t *testing.T) {
+	// Create a new Machine
+	device, err := NewDevice()
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+
+	// Without a radio the setting should only be stored.
+	err = device.SetFrequency(915)
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	if device.Settings.FrequencyMHz != 915 {
+		t.Errorf("The frequency setting should be 915 but is %v", device.Settings.FrequencyMHz)
+	}
+
+	errTest := errors.New("test error")
+	radio := &testRadio{err: errTest}
+	device.Radio = radio
+	err = device.SetFrequency(868.1)
+	if err != errTest {
+		t.Errorf("The error should be errTest but is %v", err)
+	}
+	if radio.frequencyMHz != 868.1 {
+		t.Errorf("The radio frequency should be 868.1 but is %v", radio.frequencyMHz)
+	}
+}
+
+func TestFrequencyMenu(t *testing.T) {
+	// Create a new Machine
+	device, err := NewDevice()
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	if len(device.StateFrequencyMenu.Content) != len(FrequencyPresets)+1 {
+		t.Errorf("The frequency menu should contain %d items but contains %d", len(FrequencyPresets)+1, len(device.StateFrequencyMenu.Content))
+	}
+	err = device.StateFrequencyMenu.Content[2].Action(device)
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	if device.Settings.FrequencyMHz != FrequencyPresets[1].FrequencyMHz {
+		t.Errorf("The frequency setting should be %v but is %v", FrequencyPresets[1].FrequencyMHz, device.Settings.FrequencyMHz)
+	}
+	checked, err := device.StateFrequencyMenu.Content[2].GetCursorData(device)
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	if checked != true {
+		t.Errorf("The selected preset should be checked but is not")
+	}
+}
+
+func TestModemConfigMenus(t *testing.T) {
+	// Create a new Machine
+	device, err := NewDevice()
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	radio := &testRadio{}
+	device.Radio = radio
+
+	// Pick SF12, 62.5kHz and 4/8.
+	err = device.StateSpreadingFactorMenu.Content[6].Action(device)
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	err = device.StateBandwidthMenu.Content[7].Action(device)
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	err = device.StateCodingRateMenu.Content[4].Action(device)
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	want := ModemConfig{SpreadingFactor: 12, BandwidthHz: 62500, CodingRate: 8}
+	if device.Settings.Modem != want {
+		t.Errorf("The modem settings should be %v but are %v", want, device.Settings.Modem)
+	}
+	if radio.modemConfig != want {
+		t.Errorf("The radio modem config should be %v but is %v", want, radio.modemConfig)
+	}
+	if device.StateBandwidthMenu.Content[7].Text != "62.5 kHz" {
+		t.Errorf("The bandwidth menu item text should be 62.5 kHz but is %v", device.StateBandwidthMenu.Content[7].Text)
+	}
+}
+
+func TestAirtime(t *testing.T) {
+	tests := []struct {
+		config   ModemConfig
+		length   int
+		expected time.Duration
+	}{
+		{DefaultModemConfig, 10, 41216 * time.Microsecond},
+		{ModemConfig{SpreadingFactor: 12, BandwidthHz: 125000, CodingRate: 5}, 10, 991232 * time.Microsecond},
+	}
+	for _, test := range tests {
+		airtime := test.config.Airtime(test.length)
+		if airtime.Round(time.Microsecond) != test.expected {
+			t.Errorf("expected %d bytes with %+v to take %v, got %v", test.length, test.config, test.expected, airtime)
+		}
+	}
+}
+
+func TestSetRegion(t *testing.T) {
+	device, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	radio := &testRadio{}
+	err = device.SetRadio(radio)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if radio.txPower != Regions[0].MaxTxPower || device.Settings.DutyCycle != Regions[0].DutyCycle {
+		t.Errorf("The Device should start by following the rules of %s, have %d dBm and a duty cycle of %v", Regions[0].Name, radio.txPower, device.Settings.DutyCycle)
+	}
+
+	// Choosing US915 from the menu moves to its frequency plan.
+	err = device.StateRegionMenu.Content[2].Action(device)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if device.Settings.Region != "US915" || radio.frequencyMHz != 903.9 || device.MaxTxPower() != 20 || device.Settings.DutyCycle != 0 {
+		t.Errorf("Choosing US915 should apply its rules, have %q at %v MHz with up to %d dBm and a duty cycle of %v", device.Settings.Region, radio.frequencyMHz, device.MaxTxPower(), device.Settings.DutyCycle)
+	}
+	if radio.txPower != Regions[0].MaxTxPower {
+		t.Errorf("The transmit power should not be turned up by a Region, have %d dBm", radio.txPower)
+	}
+
+	// A frequency that is in the plan of the Region is kept.
+	err = device.SetFrequency(869.525)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	err = device.SetRegion(Regions[0])
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if radio.frequencyMHz != 869.525 {
+		t.Errorf("The frequency should be kept when it is in the plan of the Region, have %v MHz", radio.frequencyMHz)
+	}
+}
+
+func TestListenBeforeTalk(t *testing.T) {
+	device, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	radio := &testRadio{busy: 2}
+	err = device.SetRadio(radio)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	sent := 0
+	device.SendUsingRadio = func(packet []byte) (err error) {
+		sent++
+		return nil
+	}
+	err = device.SendPacket(nil, []byte("doom"))
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	now := time.Now()
+	if sent != 0 || device.QueuedPackets() != 1 {
+		t.Fatalf("A packet should wait while the channel is busy, have %d sent and %d queued", sent, device.QueuedPackets())
+	}
+	if device.backoffUntil.Sub(now) > ListenBackoff+time.Millisecond {
+		t.Errorf("The backoff should be up to %v, have %v", ListenBackoff, device.backoffUntil.Sub(now))
+	}
+	// Nothing is checked again until the backoff is over.
+	err = device.Tick(now)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if radio.busy != 1 {
+		t.Errorf("The channel should not be checked during the backoff")
+	}
+	for i := 1; i <= 2; i++ {
+		err = device.Tick(now.Add(time.Duration(i) * (ListenBackoff + time.Millisecond)))
+		if err != nil {
+			t.Fatalf("The error should be nil but is %v", err)
+		}
+	}
+	if sent != 1 || device.QueuedPackets() != 0 {
+		t.Errorf("The packet should be sent once the channel is clear, have %d sent and %d queued", sent, device.QueuedPackets())
+	}
+
+	radio.busy = ListenAttempts
+	err = device.SendPacket(nil, []byte("doom"))
+	for i := 3; i < 3+2*ListenAttempts && err == nil; i++ {
+		err = device.Tick(now.Add(time.Duration(i) * (ListenBackoff + time.Millisecond)))
+	}
+	if err != ErrChannelBusy {
+		t.Errorf("The error should be ErrChannelBusy but is %v", err)
+	}
+	if sent != 1 || device.QueuedPackets() != 0 || device.Packets.SendFailed != 1 {
+		t.Errorf("A packet should be given up on when the channel stays busy, have %d sent, %d queued and %d failed", sent, device.QueuedPackets(), device.Packets.SendFailed)
+	}
+}
+
+func TestTxPower(t *testing.T) {
+	device, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	radio := &testRadio{}
+	err = device.SetRadio(radio)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	err = device.SetRegion(Regions[1])
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	err = device.ChangeStateWithHistory(&device.StateTxPower)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	for i := 0; i < 10; i++ {
+		err = device.ProcessInputEvent(InputEventRight)
+		if err != nil {
+			t.Fatalf("The error should be nil but is %v", err)
+		}
+	}
+	if device.Settings.TxPower != 20 || radio.txPower != 20 {
+		t.Errorf("The slider should stop at the most that the Region allows, have %d dBm in the Settings and %d dBm on the radio", device.Settings.TxPower, radio.txPower)
+	}
+	_, err = GetFrame(image.Rect(0, 0, 128, 64), device)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+
+	// A Region with a lower limit turns the power down.
+	err = device.SetRegion(Regions[3])
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if device.Settings.TxPower != 16 || radio.txPower != 16 {
+		t.Errorf("The power should be turned down to what %s allows, have %d dBm", Regions[3].Name, radio.txPower)
+	}
+
+	for i := 0; i < 20; i++ {
+		err = device.ProcessInputEvent(InputEventLeft)
+		if err != nil {
+			t.Fatalf("The error should be nil but is %v", err)
+		}
+	}
+	if radio.txPower != MinTxPower {
+		t.Errorf("The slider should stop at %d dBm, have %d dBm", MinTxPower, radio.txPower)
+	}
+}
@@ -0,0 +1,74 @@
+package picodoomsdaymessenger
+
+// DefaultMaxTxPower is the most power in dBm that the RFM9x can send with, used when there is no Region.
+const DefaultMaxTxPower = 20
+
+// Region is a set of radio rules that apply in part of the world.
+type Region struct {
+	Name string
+	// Frequencies is the frequency plan of the Region in MHz. The first is used when the Region is chosen, unless the Device is already on one of them.
+	Frequencies []float64
+	// MaxTxPower is the most power in dBm that can be sent with in the Region.
+	MaxTxPower int
+	// DutyCycle is the fraction of every hour that the radio can send for in the Region, or zero if there is no limit.
+	DutyCycle float64
+}
+
+// Regions are the Regions that can be chosen in the Settings. The first is the default.
+var Regions = []Region{
+	{"EU868", []float64{868.0, 868.1, 868.3, 868.5, 869.525}, 14, 0.01},
+	{"US915", []float64{903.9, 915.0}, 20, 0},
+	{"AU915", []float64{916.8}, 20, 0},
+	{"AS923", []float64{923.2}, 16, 0.01},
+}
+
+// Region returns the Region in the Settings. It returns false if no Region has been chosen.
+func (d *Device) Region() (region Region, ok bool) {
+	for _, region := range Regions {
+		if region.Name == d.Settings.Region {
+			return region, true
+		}
+	}
+	return Region{}, false
+}
+
+// MaxTxPower returns the most power in dBm that the Region allows the radio to send with.
+func (d *Device) MaxTxPower() int {
+	region, ok := d.Region()
+	if !ok {
+		return DefaultMaxTxPower
+	}
+	return region.MaxTxPower
+}
+
+// SetRegion chooses a Region, and changes the frequency, duty cycle and transmit power to follow its rules. The transmit power is only turned down if it is more than the Region allows.
+func (d *Device) SetRegion(region Region) (err error) {
+	d.Settings.Region = region.Name
+	d.Settings.DutyCycle = region.DutyCycle
+	frequency := d.Settings.FrequencyMHz
+	inPlan := false
+	for _, f := range region.Frequencies {
+		if f == frequency {
+			inPlan = true
+		}
+	}
+	if !inPlan && len(region.Frequencies) > 0 {
+		frequency = region.Frequencies[0]
+	}
+	d.Settings.FrequencyMHz = frequency
+	d.Settings.TxPower = d.TxPower()
+	return d.ApplyRadioSettings()
+}
+
+// regionMenuItems creates a MenuItem for every Region in Regions.
+func regionMenuItems() (items []MenuItem) {
+	names := make([]string, len(Regions))
+	for i, region := range Regions {
+		names[i] = region.Name
+	}
+	return choiceMenuItems(names, func(d *Device, i int) bool {
+		return d.Settings.Region == Regions[i].Name
+	}, func(d *Device, i int) (err error) {
+		return d.SetRegion(Regions[i])
+	})
+}
@@ -0,0 +1,136 @@
+package picodoomsdaymessenger
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// namesStorageKey is the key that the Names are saved with.
+const namesStorageKey = "names"
+
+// MaxRenameLength is the longest name in bytes that a Person can be given. It is longer than MaxNameLength, as it is never sent to anyone.
+const MaxRenameLength = 32
+
+// localName returns the name that the user has given the Person with an ID, either as a Contact or in the Names. It returns false if they have not been given one.
+func (d *Device) localName(id int) (name string, ok bool) {
+	for _, contact := range d.Contacts {
+		if contact.Person.ID == id {
+			return contact.Person.Name, true
+		}
+	}
+	name, ok = d.Names[id]
+	return name, ok
+}
+
+// RenamePerson gives the Person with an ID a new name, and saves it. A Contact is renamed in the Contacts, and anyone else in the Names.
+// Every Conversation with the Person, and the Nearby menu, are shown with the new name.
+func (d *Device) RenamePerson(id int, name string) (err error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return ErrEmptyName
+	}
+	name, _ = truncateString(name, MaxRenameLength)
+	for i := range d.Contacts {
+		if d.Contacts[i].Person.ID == id {
+			d.Contacts[i].Person.Name = name
+			err = d.SaveContacts()
+			if err != nil {
+				return err
+			}
+			d.renamed(id, name)
+			return nil
+		}
+	}
+	if d.Names == nil {
+		d.Names = make(map[int]string)
+	}
+	d.Names[id] = name
+	err = d.SaveNames()
+	if err != nil {
+		return err
+	}
+	d.renamed(id, name)
+	return nil
+}
+
+// renamed shows a new name for the Person with an ID everywhere that they are listed.
+func (d *Device) renamed(id int, name string) {
+	for _, c := range d.Conversations {
+		if c.Broadcast || c.Destination() != id {
+			continue
+		}
+		c.Name = name
+		c.People[1].Name = name
+	}
+	for i := range d.Neighbors {
+		if d.Neighbors[i].Person.ID == id {
+			d.Neighbors[i].Person.Name = name
+		}
+	}
+	d.UpdateConversationsMenu()
+}
+
+// SaveNames writes the Names to the Storage of the Device. It does nothing if the Device has no Storage.
+func (d *Device) SaveNames() (err error) {
+	if d.Storage == nil {
+		return nil
+	}
+	data, err := json.Marshal(d.Names)
+	if err != nil {
+		return err
+	}
+	return d.Storage.Save(namesStorageKey, data)
+}
+
+// LoadNames reads the Names from the Storage of the Device.
+func (d *Device) LoadNames() (err error) {
+	if d.Storage == nil {
+		return nil
+	}
+	data, err := d.Storage.Load(namesStorageKey)
+	if err == ErrStorageNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	names := map[int]string{}
+	err = json.Unmarshal(data, &names)
+	if err != nil {
+		return err
+	}
+	d.Names = names
+	return nil
+}
+
+// startRename shows the StateRenameEditor for the Person with an ID, starting from the name they are shown with.
+func (d *Device) startRename(id int, current string) (err error) {
+	d.renaming = id
+	d.renameDraft = current
+	d.clearPendingCharacter()
+	return d.ChangeStateWithHistory(&d.StateRenameEditor)
+}
+
+// processRenameEditorInputEvent types the new name, and saves it with accept.
+func processRenameEditorInputEvent(d *Device, inputEvent InputEvent) (handled bool, err error) {
+	if d.processTextEntryInputEvent(&d.renameDraft, inputEvent) {
+		return true, nil
+	}
+	if inputEvent != InputEventAccept {
+		return false, nil
+	}
+	err = d.RenamePerson(d.renaming, d.finishTextEntry(&d.renameDraft))
+	if err != nil && err != ErrEmptyName {
+		return true, err
+	}
+	// An empty name leaves the name as it was.
+	return true, d.GoBackState()
+}
+
+// drawRenameEditor draws the name that is being typed, with the ID of the Person that it is for.
+func drawRenameEditor(d *Device, img Canvas, layout Layout) (err error) {
+	text := d.renameDraft + d.pendingCharacter() + "_\nID " + strconv.Itoa(d.renaming)
+	drawTextPage(img, layout, d.Palette(), "Rename", text, 0)
+	return nil
+}
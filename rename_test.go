@@ -0,0 +1,81 @@
+package picodoomsdaymessenger
+
+import "testing"
+
+func TestRenamePerson(t *testing.T) {
+	storage := MemoryStorage{}
+	device, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	device.Storage = storage
+	device.State = &device.StateMainMenu
+	if err = device.ReceiveFromRadio([]byte("doom1483729471\xccDave\xcchello")); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if device.Conversations[0].Name != "1483729471" {
+		t.Fatalf("expected the Conversation to be named by ID, got %q", device.Conversations[0].Name)
+	}
+
+	// Rename them from the options of the Conversation.
+	device.CurrentConversationIndex = 0
+	err = ConversationOptionsMenuItemRename.Action(device)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if device.State != &device.StateRenameEditor {
+		t.Fatalf("expected the rename editor, got %q", device.State.Title)
+	}
+	device.renameDraft = "Dave at the north camp"
+	err = device.ProcessInputEvent(InputEventAccept)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	c := device.Conversations[0]
+	if c.Name != "Dave at the north camp" || c.People[1].Name != "Dave at the north camp" {
+		t.Errorf("expected the Conversation to be renamed, got %q", c.Name)
+	}
+	if device.StateConversationsMenu.Content[2].Text != "Dave at the north camp (1)" {
+		t.Errorf("expected the Conversations menu to show the new name, got %q", device.StateConversationsMenu.Content[2].Text)
+	}
+	if device.Neighbors[0].Person.Name != "Dave at the north camp" {
+		t.Errorf("expected the Neighbor to be renamed, got %q", device.Neighbors[0].Person.Name)
+	}
+
+	// The name is kept after a restart, and used for new Conversations.
+	restarted, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	restarted.Storage = storage
+	err = restarted.LoadState()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	restarted.State = &restarted.StateMainMenu
+	if err = restarted.ReceiveFromRadio([]byte("doom1483729471\xccDave\xcchello again")); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if restarted.Conversations[0].Name != "Dave at the north camp" {
+		t.Errorf("expected the saved name, got %q", restarted.Conversations[0].Name)
+	}
+
+	if err = device.RenamePerson(1483729471, "  "); err != ErrEmptyName {
+		t.Errorf("expected ErrEmptyName, got %v", err)
+	}
+}
+
+func TestRenameContact(t *testing.T) {
+	device, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	device.Contacts = []Contact{{Person: Person{ID: 7, Name: "Bob"}}}
+	err = device.RenamePerson(7, "Robert")
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if device.Contacts[0].Person.Name != "Robert" || len(device.Names) != 0 || device.PersonName(7) != "Robert" {
+		t.Errorf("expected the Contact to be renamed, got %q", device.Contacts[0].Person.Name)
+	}
+}
@@ -0,0 +1,38 @@
+package picodoomsdaymessenger
+
+import (
+	"image"
+	"reflect"
+	"testing"
+)
+
+func TestRenderFrame(t *testing.T) {
+	device, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	device.State = &device.StateMainMenu
+	want, err := GetFrame(image.Rect(0, 0, 128, 64), device)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	img := image.NewRGBA(image.Rect(0, 0, 128, 64))
+	// Whatever was in the image before is drawn over.
+	for i := range img.Pix {
+		img.Pix[i] = 0x55
+	}
+	err = RenderFrame(img, device)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if !reflect.DeepEqual(img.Pix, want.(*image.RGBA).Pix) {
+		t.Errorf("expected RenderFrame to draw the same frame as GetFrame")
+	}
+
+	allocations := testing.AllocsPerRun(10, func() {
+		RenderFrame(img, device)
+	})
+	if allocations > 0 {
+		t.Errorf("expected rendering the main menu not to allocate, got %v allocations", allocations)
+	}
+}
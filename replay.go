@@ -0,0 +1,222 @@
+package picodoomsdaymessenger
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"math"
+)
+
+// Define replay protection errors
+var (
+	ErrReplayedPacket       = errors.New("packet has already been received")
+	ErrInvalidReplayCounter = errors.New("saved counter is invalid")
+)
+
+// replayCounterSize is the length of the counter that is encrypted in front of every packet for a Channel or Contact.
+const replayCounterSize = 4
+
+// ReplayWindow is how far behind the newest counter from a sender a counter can be and still be accepted, once. Packets are not always sent in the order they were made, as the transmit queue sends urgent packets first.
+const ReplayWindow = 32
+
+// replayCounterBlock is how many counters are used between each time the counter is saved, so that the Storage is not written for every packet.
+const replayCounterBlock = 64
+
+// replayCounterStorageKey is the key that the counter is saved with.
+const replayCounterStorageKey = "counter"
+
+// replaySeenBlock is how far past the newest counter from a sender the saved counter is put, so that the Storage is not written for every packet that is received. After a restart, up to this many packets from each sender are rejected as if they were replays.
+const replaySeenBlock = 16
+
+// replaySeenStorageKey is the key that the counter saved for each sender is saved with.
+const replaySeenStorageKey = "seen"
+
+// maxReplaySenders is how many senders have their counters remembered. When there are more, the counters from a sender that is not a Contact with a SessionKey are forgotten.
+const maxReplaySenders = 64
+
+// replaySession is the source of the counters in packets sealed with the SessionKey of a Contact.
+const replaySession = "session"
+
+// replayState holds the counter of the Device and the counters that have been received from each sender.
+type replayState struct {
+	// next is the counter of the next encrypted packet that the Device sends.
+	next uint32
+	// reserved is the counter that is saved in the Storage. No counter from it on has been used, so next starts from it when the Device is turned on.
+	reserved uint32
+	// seen is the replayWindow of each sender.
+	seen map[replaySender]replayWindow
+}
+
+// replaySender is who a counter was received from.
+// A packet sealed with the SessionKey of a Contact can only have come from that Contact, and a signed packet can only have come from whoever holds its key, so the Person.ID in them cannot be used by someone else who knows the passphrase of a Channel to have the real packets from that Person rejected. Unsigned packets on a Channel can only be told apart by their Channel.
+type replaySender struct {
+	source string
+	id     int
+}
+
+// replayWindow is the newest counter received from a sender, and which of the ReplayWindow counters before it have been received, one bit each.
+type replayWindow struct {
+	newest   uint32
+	received uint32
+	// saved is the counter that is saved in the Storage for the sender. It is replaySeenBlock past the newest counter when it was saved, and is saved again once the newest counter reaches it.
+	saved uint32
+}
+
+// savedReplayWindow is how the counter saved for a sender is written to the Storage.
+type savedReplayWindow struct {
+	Source  string `json:"source"`
+	ID      int    `json:"id"`
+	Counter uint32 `json:"counter"`
+}
+
+// channelReplaySender returns who a packet received on a Channel is from. Signed packets are told apart by their key, and others by the Channel they were sealed for.
+func channelReplaySender(channel string, id int, packet []byte) replaySender {
+	key, ok := signingKey(packet)
+	if ok {
+		return replaySender{source: "key:" + hex.EncodeToString(key), id: id}
+	}
+	return replaySender{source: "channel:" + channel, id: id}
+}
+
+// appendReplayCounter puts the next counter of the Device in front of a packet that is about to be encrypted.
+// Every time a block of counters has been used, the start of the next block is saved, so that counters are never used twice even if the Device is turned off. Without Storage, the counter starts from 0 each time, and packets are rejected by devices that heard higher counters until it catches up.
+func (d *Device) appendReplayCounter(packet []byte) (output []byte, err error) {
+	if d.replay.next >= d.replay.reserved && d.Storage != nil {
+		reserved := binary.BigEndian.AppendUint32(nil, d.replay.next+replayCounterBlock)
+		err = d.Storage.Save(replayCounterStorageKey, reserved)
+		if err != nil {
+			return nil, err
+		}
+		d.replay.reserved = d.replay.next + replayCounterBlock
+	}
+	output = binary.BigEndian.AppendUint32(make([]byte, 0, replayCounterSize+len(packet)), d.replay.next)
+	d.replay.next++
+	return append(output, packet...), nil
+}
+
+// splitReplayCounter separates the counter from the front of a packet that has been decrypted.
+func splitReplayCounter(input []byte) (counter uint32, packet []byte, err error) {
+	if len(input) < replayCounterSize {
+		return 0, nil, ErrMalformedMessage
+	}
+	return binary.BigEndian.Uint32(input), input[replayCounterSize:], nil
+}
+
+// checkReplayCounter returns ErrReplayedPacket if a counter has already been received from a sender, or is too old to tell. Otherwise, it remembers the counter.
+// A counter past the newest counter from each sender is saved in blocks, so that packets recorded before the Device was turned off are still rejected.
+func (d *Device) checkReplayCounter(sender replaySender, counter uint32) (err error) {
+	if d.replay.seen == nil {
+		d.replay.seen = make(map[replaySender]replayWindow)
+	}
+	window, ok := d.replay.seen[sender]
+	if !ok {
+		d.forgetReplaySender()
+		return d.updateReplayWindow(sender, replayWindow{newest: counter, received: 1})
+	}
+	if counter > window.newest {
+		shift := counter - window.newest
+		if shift >= ReplayWindow {
+			window.received = 0
+		} else {
+			window.received <<= shift
+		}
+		window.received |= 1
+		window.newest = counter
+		return d.updateReplayWindow(sender, window)
+	}
+	behind := window.newest - counter
+	if behind >= ReplayWindow || window.received&(1<<behind) != 0 {
+		return ErrReplayedPacket
+	}
+	window.received |= 1 << behind
+	d.replay.seen[sender] = window
+	return nil
+}
+
+// updateReplayWindow remembers the replayWindow of a sender. If its newest counter has reached the counter saved for the sender, the next block is saved.
+func (d *Device) updateReplayWindow(sender replaySender, window replayWindow) (err error) {
+	if window.newest < window.saved {
+		d.replay.seen[sender] = window
+		return nil
+	}
+	window.saved = math.MaxUint32
+	if window.newest < math.MaxUint32-replaySeenBlock {
+		window.saved = window.newest + replaySeenBlock
+	}
+	d.replay.seen[sender] = window
+	return d.saveReplaySeen()
+}
+
+// forgetReplaySender makes room for the counters from a new sender, by forgetting a sender that is not a Contact with a SessionKey once maxReplaySenders are remembered.
+func (d *Device) forgetReplaySender() {
+	if len(d.replay.seen) < maxReplaySenders {
+		return
+	}
+	for sender := range d.replay.seen {
+		if sender.source != replaySession {
+			delete(d.replay.seen, sender)
+			return
+		}
+	}
+}
+
+// LoadReplayCounter reads the counter from the Storage of the Device, so that packets sent since it was turned on are not taken for replays of older ones.
+func (d *Device) LoadReplayCounter() (err error) {
+	if d.Storage == nil {
+		return nil
+	}
+	data, err := d.Storage.Load(replayCounterStorageKey)
+	if err == ErrStorageNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if len(data) != replayCounterSize {
+		return ErrInvalidReplayCounter
+	}
+	d.replay.next = binary.BigEndian.Uint32(data)
+	d.replay.reserved = d.replay.next
+	return nil
+}
+
+// saveReplaySeen writes the counter saved for each sender to the Storage of the Device. It does nothing if the Device has no Storage.
+func (d *Device) saveReplaySeen() (err error) {
+	if d.Storage == nil {
+		return nil
+	}
+	saved := make([]savedReplayWindow, 0, len(d.replay.seen))
+	for sender, window := range d.replay.seen {
+		saved = append(saved, savedReplayWindow{Source: sender.source, ID: sender.id, Counter: window.saved})
+	}
+	data, err := json.Marshal(saved)
+	if err != nil {
+		return err
+	}
+	return d.Storage.Save(replaySeenStorageKey, data)
+}
+
+// LoadReplaySeen reads the counter saved for each sender from the Storage of the Device. Every counter up to it is rejected.
+func (d *Device) LoadReplaySeen() (err error) {
+	if d.Storage == nil {
+		return nil
+	}
+	data, err := d.Storage.Load(replaySeenStorageKey)
+	if err == ErrStorageNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	saved := []savedReplayWindow{}
+	err = json.Unmarshal(data, &saved)
+	if err != nil {
+		return err
+	}
+	d.replay.seen = make(map[replaySender]replayWindow, len(saved))
+	for _, window := range saved {
+		d.replay.seen[replaySender{source: window.Source, id: window.ID}] = replayWindow{newest: window.Counter, received: ^uint32(0), saved: window.Counter}
+	}
+	return nil
+}
@@ -0,0 +1,257 @@
+package picodoomsdaymessenger
+
+import (
+	"math"
+	"testing"
+)
+
+func TestReplayedChannelMessage(t *testing.T) {
+	sender, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	receiver, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	receiver.State = &receiver.StateMainMenu
+	for _, d := range []*Device{sender, receiver} {
+		if err = d.AddChannel("camp", "secret"); err != nil {
+			t.Fatalf("The error should be nil but is %v", err)
+		}
+	}
+	var recorded [][]byte
+	sender.SendUsingRadio = func(packet []byte) (err error) {
+		recorded = append(recorded, packet)
+		return receiver.ReceiveFromRadio(packet)
+	}
+	sender.Settings.Channel = "camp"
+	c := sender.Conversations[sender.BroadcastConversation()]
+	for _, text := range []string{"first", "second"} {
+		err = sender.SendMessage(c, text)
+		if err != nil {
+			t.Fatalf("The error should be nil but is %v", err)
+		}
+	}
+	err = sendQueued(sender)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+
+	for _, packet := range recorded {
+		if err = receiver.ReceiveFromRadio(packet); err != ErrReplayedPacket {
+			t.Errorf("expected a recorded packet to be rejected, got %v", err)
+		}
+	}
+	if len(receiver.Conversations[0].Messages) != 2 {
+		t.Errorf("expected only the first copy of each message, got %d", len(receiver.Conversations[0].Messages))
+	}
+}
+
+func TestReplayCounterWindow(t *testing.T) {
+	device, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	for _, check := range []struct {
+		counter uint32
+		want    error
+	}{
+		{100, nil},
+		{100, ErrReplayedPacket},
+		{102, nil},
+		// Counters that arrive late are accepted once, as long as they are in the ReplayWindow.
+		{101, nil},
+		{101, ErrReplayedPacket},
+		{102 - ReplayWindow, ErrReplayedPacket},
+		{103 - ReplayWindow, nil},
+		{200, nil},
+		{102, ErrReplayedPacket},
+	} {
+		if err = device.checkReplayCounter(replaySender{source: replaySession, id: 7}, check.counter); err != check.want {
+			t.Errorf("expected %v for counter %d, got %v", check.want, check.counter, err)
+		}
+	}
+	// Each sender has their own counter.
+	if err = device.checkReplayCounter(replaySender{source: replaySession, id: 8}, 1); err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+}
+
+func TestReplayCounterSaved(t *testing.T) {
+	storage := MemoryStorage{}
+	device, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	device.Storage = storage
+	for i := 0; i < 3; i++ {
+		if _, err = device.appendReplayCounter(nil); err != nil {
+			t.Fatalf("The error should be nil but is %v", err)
+		}
+	}
+
+	restarted, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	restarted.Storage = storage
+	err = restarted.LoadReplayCounter()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	packet, err := restarted.appendReplayCounter(nil)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	counter, _, err := splitReplayCounter(packet)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if counter < 3 {
+		t.Errorf("expected the counter to carry on after a restart, got %d", counter)
+	}
+
+	storage[replayCounterStorageKey] = []byte{1}
+	if err = restarted.LoadReplayCounter(); err != ErrInvalidReplayCounter {
+		t.Errorf("expected ErrInvalidReplayCounter, got %v", err)
+	}
+}
+
+func TestReplayAfterRestart(t *testing.T) {
+	sender, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	receiver, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	storage := MemoryStorage{}
+	receiver.Storage = storage
+	for _, d := range []*Device{sender, receiver} {
+		if err = d.AddChannel("camp", "secret"); err != nil {
+			t.Fatalf("The error should be nil but is %v", err)
+		}
+	}
+	var recorded []byte
+	sender.SendUsingRadio = func(packet []byte) (err error) {
+		recorded = packet
+		return nil
+	}
+	sender.Settings.Channel = "camp"
+	c := sender.Conversations[sender.BroadcastConversation()]
+	if err = sender.SendMessage(c, "first"); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if err = sendQueued(sender); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if err = receiver.ReceiveFromRadio(recorded); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+
+	restarted, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	restarted.Storage = storage
+	if err = restarted.LoadState(); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	restarted.State = &restarted.StateMainMenu
+	if err = restarted.ReceiveFromRadio(recorded); err != ErrReplayedPacket {
+		t.Errorf("expected a packet recorded before the restart to be rejected, got %v", err)
+	}
+	// The receiver saved a counter replaySeenBlock past the one it heard, so the sender has to get past it.
+	sender.replay.next += replaySeenBlock
+	if err = sender.SendMessage(c, "second"); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if err = sendQueued(sender); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if err = restarted.ReceiveFromRadio(recorded); err != nil {
+		t.Errorf("expected a new packet to be accepted, got %v", err)
+	}
+}
+
+// countingStorage is a MemoryStorage that counts how many times each key is saved.
+type countingStorage struct {
+	MemoryStorage
+	saves map[string]int
+}
+
+func (s countingStorage) Save(key string, data []byte) (err error) {
+	s.saves[key]++
+	return s.MemoryStorage.Save(key, data)
+}
+
+func TestReplaySeenSavedInBlocks(t *testing.T) {
+	device, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	storage := countingStorage{MemoryStorage: MemoryStorage{}, saves: map[string]int{}}
+	device.Storage = storage
+	sender := replaySender{source: replaySession, id: 7}
+	for counter := uint32(0); counter < 2*replaySeenBlock; counter++ {
+		if err = device.checkReplayCounter(sender, counter); err != nil {
+			t.Fatalf("The error should be nil but is %v", err)
+		}
+	}
+	if storage.saves[replaySeenStorageKey] != 2 {
+		t.Errorf("expected the counters to be saved once per block, got %d saves", storage.saves[replaySeenStorageKey])
+	}
+
+	restarted, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	restarted.Storage = storage
+	if err = restarted.LoadReplaySeen(); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if err = restarted.checkReplayCounter(sender, 2*replaySeenBlock-1); err != ErrReplayedPacket {
+		t.Errorf("expected a counter heard before the restart to be rejected, got %v", err)
+	}
+	if err = restarted.checkReplayCounter(sender, 3*replaySeenBlock); err != nil {
+		t.Errorf("expected a counter past the saved block to be accepted, got %v", err)
+	}
+}
+
+func TestReplayForgedCounterOnChannel(t *testing.T) {
+	victim := newSigningDevice(t, 10)
+	attacker := newSigningDevice(t, 20)
+	receiver := newSigningDevice(t, 30)
+	receiver.State = &receiver.StateMainMenu
+	for _, d := range []*Device{victim, attacker, receiver} {
+		if err := d.AddChannel("camp", "secret"); err != nil {
+			t.Fatalf("The error should be nil but is %v", err)
+		}
+		d.Settings.Channel = "camp"
+		d.SendUsingRadio = receiver.ReceiveFromRadio
+	}
+	// The attacker knows the passphrase, and sends a packet that says it is from the victim with the highest counter.
+	attacker.SelfIdentity = victim.SelfIdentity
+	attacker.replay.next = math.MaxUint32
+	c := attacker.Conversations[attacker.BroadcastConversation()]
+	if err := attacker.SendMessage(c, "forged"); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if err := sendQueued(attacker); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+
+	c = victim.Conversations[victim.BroadcastConversation()]
+	if err := victim.SendMessage(c, "real"); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if err := sendQueued(victim); err != nil {
+		t.Errorf("expected the real packet to be accepted, got %v", err)
+	}
+	messages := receiver.Conversations[0].Messages
+	if len(messages) != 2 || messages[1].Text != "real" {
+		t.Errorf("expected the real message to be received after the forged one, got %v", messages)
+	}
+}
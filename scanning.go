@@ -0,0 +1,44 @@
+package picodoomsdaymessenger
+
+import (
+	"fmt"
+	"time"
+)
+
+// ScanIntervals is the list of scan speeds that can be selected, as the time that each item stays highlighted.
+var ScanIntervals = []time.Duration{500 * time.Millisecond, 1 * time.Second, 1500 * time.Millisecond, 2 * time.Second, 3 * time.Second, 5 * time.Second}
+
+// SetScanning turns the scanning input mode on or off.
+// While scanning, the highlight moves down by itself every ScanInterval and a single button that sends InputEventAccept selects the highlighted item.
+// Text is typed with the character picker, so the input method is changed to it when scanning is turned on. Its BACK entry leaves the Conversation.
+func (d *Device) SetScanning(scanning bool) {
+	d.Settings.Scanning = scanning
+	d.lastScan = time.Now()
+	if scanning {
+		d.Settings.InputMethod = InputMethodPicker
+		d.picker = 0
+	}
+}
+
+// tickScanning moves the highlight on if it has been left for the ScanInterval.
+func (d *Device) tickScanning(now time.Time) (err error) {
+	if !d.Settings.Scanning || now.Sub(d.lastScan) < d.Settings.ScanInterval {
+		return nil
+	}
+	d.lastScan = now
+	return d.ProcessInputEvent(InputEventDown)
+}
+
+// scanIntervalMenuItems creates a MenuItem for every scan speed in ScanIntervals.
+func scanIntervalMenuItems() (items []MenuItem) {
+	names := make([]string, len(ScanIntervals))
+	for i, interval := range ScanIntervals {
+		names[i] = fmt.Sprintf("%.1f sec", interval.Seconds())
+	}
+	return choiceMenuItems(names, func(d *Device, i int) bool {
+		return d.Settings.ScanInterval == ScanIntervals[i]
+	}, func(d *Device, i int) (err error) {
+		d.Settings.ScanInterval = ScanIntervals[i]
+		return nil
+	})
+}
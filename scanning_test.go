@@ -0,0 +1,95 @@
+package picodoomsdaymessenger
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScanning(t *testing.T) {
+	// Create a new Machine
+	device, err := NewDevice()
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	device.SetScanning(true)
+	if device.Settings.InputMethod != InputMethodPicker {
+		t.Errorf("The input method should be the picker but is %v", device.Settings.InputMethod)
+	}
+	now := device.lastScan
+	device.Settings.ScanInterval = time.Second
+
+	err = device.Tick(now.Add(500 * time.Millisecond))
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	if device.State.HighlightedItemIndex != 0 {
+		t.Errorf("The highlight should not have moved yet but is at %v", device.State.HighlightedItemIndex)
+	}
+	err = device.Tick(now.Add(1100 * time.Millisecond))
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	if device.State.HighlightedItemIndex != 1 {
+		t.Errorf("The highlight should have moved to 1 but is at %v", device.State.HighlightedItemIndex)
+	}
+	err = device.Tick(now.Add(2200 * time.Millisecond))
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	if device.State.HighlightedItemIndex != 2 {
+		t.Errorf("The highlight should have moved to 2 but is at %v", device.State.HighlightedItemIndex)
+	}
+	device.State.HighlightedItemIndex = 0
+
+	device.SetScanning(false)
+	err = device.Tick(now.Add(time.Hour))
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	if device.State.HighlightedItemIndex != 0 {
+		t.Errorf("The highlight should not move when scanning is off but is at %v", device.State.HighlightedItemIndex)
+	}
+}
+
+func TestScanningLeavesConversation(t *testing.T) {
+	// Create a new Machine
+	device, err := NewDevice()
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	device.State = &device.StateMainMenu
+	device.Conversations = []*Conversation{{Name: "Test"}}
+	device.SetScanning(true)
+	device.Settings.ScanInterval = time.Second
+	err = device.OpenConversation(0)
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+
+	// The highlight scans from SEND onto BACK, which accept picks.
+	err = device.Tick(device.lastScan.Add(1100 * time.Millisecond))
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	err = device.ProcessInputEvent(InputEventAccept)
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	if device.State != &device.StateMainMenu {
+		t.Errorf("The state should be StateMainMenu but is %v", device.State.Title)
+	}
+
+	// A listen only Conversation has no picker, so accept leaves it straight away.
+	device.Conversations[0].ListenOnly = true
+	err = device.OpenConversation(0)
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	err = device.ProcessInputEvent(InputEventAccept)
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	if device.State != &device.StateMainMenu {
+		t.Errorf("The state should be StateMainMenu but is %v", device.State.Title)
+	}
+}
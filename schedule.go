@@ -0,0 +1,143 @@
+package picodoomsdaymessenger
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// ErrNothingToSchedule is returned when a message with no text is scheduled.
+var ErrNothingToSchedule = errors.New("no message to schedule")
+
+// ScheduledMessage is a message that is waiting to be sent at a time, and sent again every Repeat if Repeat is not zero.
+type ScheduledMessage struct {
+	Conversation *Conversation
+	Text         string
+	Priority     Priority
+	// At is when the message is next sent, by the clock of the Device.
+	At     time.Time
+	Repeat time.Duration
+}
+
+// ScheduleOption is a choice in the menu that schedules the message being composed.
+type ScheduleOption struct {
+	Name   string
+	Delay  time.Duration
+	Repeat time.Duration
+}
+
+// ScheduleOptions are the choices of when to send the message being composed, from the conversation reader.
+var ScheduleOptions = []ScheduleOption{
+	{"In 10 Minutes", 10 * time.Minute, 0},
+	{"In 1 Hour", time.Hour, 0},
+	{"In 8 Hours", 8 * time.Hour, 0},
+	{"Every Hour", time.Hour, time.Hour},
+	{"Every Day", 24 * time.Hour, 24 * time.Hour},
+}
+
+// ScheduleMessage sends text to a Conversation at a time by the clock of the Device, and again every repeat if repeat is not zero.
+// Scheduled messages are sent by Tick, whatever is on the screen.
+func (d *Device) ScheduleMessage(c *Conversation, text string, priority Priority, at time.Time, repeat time.Duration) (err error) {
+	if strings.TrimSpace(text) == "" {
+		return ErrNothingToSchedule
+	}
+	d.Scheduled = append(d.Scheduled, ScheduledMessage{Conversation: c, Text: text, Priority: priority, At: at, Repeat: repeat})
+	return nil
+}
+
+// CancelScheduledMessage stops a ScheduledMessage from being sent.
+func (d *Device) CancelScheduledMessage(index int) {
+	if index < 0 || index >= len(d.Scheduled) {
+		return
+	}
+	d.Scheduled = append(d.Scheduled[:index], d.Scheduled[index+1:]...)
+}
+
+// tickSchedule sends every ScheduledMessage that is due. Emergency messages are sent before the others.
+// A message that repeats is moved on to its next time, and one that does not is removed, even if it could not be sent, so that a failing radio is not tried again every Tick.
+func (d *Device) tickSchedule(now time.Time) (err error) {
+	clock := now.Add(d.TimeOffset)
+	due := []ScheduledMessage{}
+	kept := d.Scheduled[:0]
+	for _, s := range d.Scheduled {
+		if s.At.After(clock) {
+			kept = append(kept, s)
+			continue
+		}
+		if s.Priority == PriorityEmergency {
+			due = append([]ScheduledMessage{s}, due...)
+		} else {
+			due = append(due, s)
+		}
+		if s.Repeat > 0 {
+			for !s.At.After(clock) {
+				s.At = s.At.Add(s.Repeat)
+			}
+			kept = append(kept, s)
+		}
+	}
+	d.Scheduled = kept
+	for _, s := range due {
+		sendErr := d.SendMessageWithPriority(s.Conversation, s.Text, s.Priority)
+		if err == nil {
+			err = sendErr
+		}
+	}
+	return err
+}
+
+// scheduleMenuItems creates a MenuItem for every ScheduleOption, which schedules the message being composed and goes back to the conversation.
+func scheduleMenuItems() (items []MenuItem) {
+	for _, option := range ScheduleOptions {
+		option := option
+		items = append(items, MenuItem{
+			Text: option.Name,
+			Action: func(d *Device) (err error) {
+				c := d.Conversations[d.CurrentConversationIndex]
+				text, priority := d.takeComposerText()
+				err = d.ScheduleMessage(c, text, priority, d.Now().Add(option.Delay), option.Repeat)
+				if err != nil {
+					return err
+				}
+				return d.GoBackState()
+			},
+			CursorIcon: CursorIconRightArrow,
+		})
+	}
+	return items
+}
+
+// The list of scheduled messages is refreshed every time it is shown, so that sent and cancelled messages are not listed.
+func init() {
+	stateSetups = append(stateSetups, func(s *States) {
+		s.StateScheduledMenu.LoadAction = func(d *Device) (err error) {
+			d.UpdateScheduledMenu()
+			return nil
+		}
+	})
+}
+
+// UpdateScheduledMenu lists the ScheduledMessages, with when each is next sent. Choosing one cancels it.
+func (d *Device) UpdateScheduledMenu() {
+	d.StateScheduledMenu.Content = []MenuItem{GlobalMenuItemGoBack}
+	for i, s := range d.Scheduled {
+		j := i
+		text, _ := truncateString(s.Text, 10)
+		when := s.At.Format("15:04")
+		if s.Repeat > 0 {
+			when = "*" + when
+		}
+		d.StateScheduledMenu.Content = append(d.StateScheduledMenu.Content, MenuItem{
+			Text: when + " " + text,
+			Action: func(d *Device) (err error) {
+				d.CancelScheduledMessage(j)
+				d.UpdateScheduledMenu()
+				return nil
+			},
+			CursorIcon: CursorIconNone,
+		})
+	}
+	if d.StateScheduledMenu.HighlightedItemIndex >= len(d.StateScheduledMenu.Content) {
+		d.StateScheduledMenu.HighlightedItemIndex = len(d.StateScheduledMenu.Content) - 1
+	}
+}
@@ -0,0 +1,122 @@
+package picodoomsdaymessenger
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScheduleFromConversation(t *testing.T) {
+	device, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	sent := []Message{}
+	device.SendUsingRadio = func(packet []byte) (err error) {
+		message, err := device.BytesToMessage(packet)
+		sent = append(sent, message)
+		return err
+	}
+	c := device.NewConversation(Person{Name: "Bob", ID: 7})
+	device.CurrentConversationIndex = 0
+	err = device.ChangeStateWithHistory(&device.StateConversationReader)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	c.KeyboardBuffer = "check in"
+	device.clearPendingCharacter()
+	err = device.ProcessInputEvent(InputEventPound)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if device.State != &device.StateScheduleMenu {
+		t.Fatalf("expected pound to open the schedule menu, got %q", device.State.Title)
+	}
+	// Every Day.
+	err = device.StateScheduleMenu.Content[5].Action(device)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if device.State != &device.StateConversationReader || c.KeyboardBuffer != "" {
+		t.Errorf("expected to go back to an empty compose bar")
+	}
+	if len(device.Scheduled) != 1 || device.Scheduled[0].Repeat != 24*time.Hour {
+		t.Fatalf("expected a message repeated every day, got %+v", device.Scheduled)
+	}
+
+	// Leaving the conversation does not stop the message from being sent.
+	err = device.ChangeStateWithHistory(&device.StateMainMenu)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	now := time.Now()
+	err = device.Tick(now.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if len(sent) != 0 {
+		t.Fatalf("expected nothing to be sent before the time, got %d", len(sent))
+	}
+	err = device.Tick(now.Add(25 * time.Hour))
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if len(sent) != 1 || sent[0].Text != "check in" {
+		t.Fatalf("expected the message to be sent, got %+v", sent)
+	}
+	if len(device.Scheduled) != 1 || !device.Scheduled[0].At.After(now.Add(25*time.Hour)) {
+		t.Errorf("expected the message to be scheduled again, got %+v", device.Scheduled)
+	}
+}
+
+func TestTickScheduleSendsEmergenciesFirst(t *testing.T) {
+	device, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	sent := []Message{}
+	device.SendUsingRadio = func(packet []byte) (err error) {
+		message, err := device.BytesToMessage(packet)
+		sent = append(sent, message)
+		return err
+	}
+	c := device.NewConversation(Person{Name: "Bob", ID: 7})
+	now := time.Now()
+	for _, err := range []error{
+		device.ScheduleMessage(c, "later", PriorityNormal, now, 0),
+		device.ScheduleMessage(c, "help", PriorityEmergency, now, 0),
+		device.ScheduleMessage(c, "tomorrow", PriorityNormal, now.Add(24*time.Hour), 0),
+	} {
+		if err != nil {
+			t.Fatalf("The error should be nil but is %v", err)
+		}
+	}
+	if err = device.ScheduleMessage(c, " ", PriorityNormal, now, 0); err != ErrNothingToSchedule {
+		t.Errorf("expected ErrNothingToSchedule, got %v", err)
+	}
+	err = device.tickSchedule(now.Add(time.Minute))
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	err = sendQueued(device)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if len(sent) != 2 || sent[0].Text != "help" || sent[1].Text != "later" {
+		t.Fatalf("expected the emergency to be sent first, got %+v", sent)
+	}
+	if len(device.Scheduled) != 1 || device.Scheduled[0].Text != "tomorrow" {
+		t.Fatalf("expected only the message for tomorrow to be left, got %+v", device.Scheduled)
+	}
+
+	device.UpdateScheduledMenu()
+	if len(device.StateScheduledMenu.Content) != 2 {
+		t.Fatalf("expected the scheduled message to be listed, got %d items", len(device.StateScheduledMenu.Content))
+	}
+	err = device.StateScheduledMenu.Content[1].Action(device)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if len(device.Scheduled) != 0 || len(device.StateScheduledMenu.Content) != 1 {
+		t.Errorf("expected choosing the message to cancel it")
+	}
+}
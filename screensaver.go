@@ -0,0 +1,87 @@
+package picodoomsdaymessenger
+
+import (
+	"fmt"
+	"time"
+)
+
+// ScreensaverTimeouts is the list of times without any input that the screensaver can start after. Zero turns the screensaver off.
+var ScreensaverTimeouts = []time.Duration{0, 30 * time.Second, time.Minute, 2 * time.Minute, 5 * time.Minute, 10 * time.Minute}
+
+// tickScreensaver starts the screensaver if there has been no input for the ScreensaverTimeout.
+func (d *Device) tickScreensaver(now time.Time) (err error) {
+	if d.lastInput.IsZero() {
+		d.lastInput = now
+	}
+	timeout := d.ScreensaverTimeout()
+	if timeout <= 0 || d.State == &d.StateScreensaver || now.Sub(d.lastInput) < timeout {
+		return nil
+	}
+	return d.ChangeStateWithHistory(&d.StateScreensaver)
+}
+
+// processScreensaverInputEvent goes back to whatever was on the screen before the screensaver started. The InputEvent is not passed on.
+// If a message has been received since, accept opens its Conversation instead.
+func processScreensaverInputEvent(d *Device, inputEvent InputEvent) (handled bool, err error) {
+	err = d.GoBackState()
+	if err != nil || inputEvent != InputEventAccept {
+		return true, err
+	}
+	return true, d.openNotifiedConversation()
+}
+
+// drawScreensaver draws a large clock, with the number of days since the day count was started below it.
+// Everything moves by a pixel or two each minute, so that no pixel of an OLED is lit for long enough to burn in.
+func drawScreensaver(d *Device, img Canvas, layout Layout) (err error) {
+	palette := d.Palette()
+	dimensions := img.Bounds()
+	now := d.Now()
+	clock := now.Format("15:04")
+	days := ""
+	if !d.Settings.EventTime.IsZero() {
+		days = fmt.Sprintf("Day %d", int(now.Sub(d.Settings.EventTime)/(24*time.Hour))+1)
+	}
+
+	// Make the clock as large as will fit, leaving room for the day count.
+	face := layout.Face
+	space := dimensions.Dy()
+	if days != "" {
+		space -= face.Height
+	}
+	scale := space / face.Height
+	if across := dimensions.Dx() / textWidth(face, clock); across < scale {
+		scale = across
+	}
+	if scale < 1 {
+		scale = 1
+	}
+	height := face.Height * scale
+	if days != "" {
+		height += face.Height
+	}
+
+	shiftX, shiftY := now.Minute()%5-2, (now.Minute()/5)%3-1
+	top := (dimensions.Dy()-height)/2 + shiftY
+	drawTextScaled(img, face, (dimensions.Dx()-textWidth(face, clock)*scale)/2+shiftX, top+face.Ascent*scale, clock, palette.Text, scale)
+	if days != "" {
+		drawTextFace(img, face, (dimensions.Dx()-textWidth(face, days))/2+shiftX, top+face.Height*scale+face.Ascent, days, palette.Text)
+	}
+	return nil
+}
+
+// screensaverTimeoutMenuItems creates a MenuItem for every ScreensaverTimeout.
+func screensaverTimeoutMenuItems() (items []MenuItem) {
+	names := make([]string, len(ScreensaverTimeouts))
+	for i, timeout := range ScreensaverTimeouts {
+		names[i] = "After " + timeout.String()
+		if timeout == 0 {
+			names[i] = "Off"
+		}
+	}
+	return choiceMenuItems(names, func(d *Device, i int) bool {
+		return d.Settings.ScreensaverTimeout == ScreensaverTimeouts[i]
+	}, func(d *Device, i int) (err error) {
+		d.Settings.ScreensaverTimeout = ScreensaverTimeouts[i]
+		return nil
+	})
+}
@@ -0,0 +1,101 @@
+package picodoomsdaymessenger
+
+import (
+	"image"
+	"testing"
+	"time"
+)
+
+func TestScreensaver(t *testing.T) {
+	// Create a new Machine
+	device, err := NewDevice()
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	device.Settings.ScreensaverTimeout = time.Minute
+	err = device.ChangeStateWithHistory(&device.StateSettingsMenu)
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	now := time.Now()
+	err = device.ProcessInputEvent(InputEventDown)
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	defer func() { device.StateSettingsMenu.HighlightedItemIndex = 0 }()
+	if err = device.Tick(now.Add(30 * time.Second)); err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	if device.State != &device.StateSettingsMenu {
+		t.Errorf("The screensaver should not start before the timeout")
+	}
+	if err = device.Tick(now.Add(2 * time.Minute)); err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	if device.State != &device.StateScreensaver {
+		t.Fatalf("The screensaver should start after the timeout, but the state is %v", device.State.Title)
+	}
+	frame, err := GetFrame(DisplaySSD1306.Bounds(), device)
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	lit := 0
+	for y := 0; y < 64; y++ {
+		for x := 0; x < 128; x++ {
+			if frame.(*image.RGBA).RGBAAt(x, y) == MonochromePalette.Text {
+				lit++
+			}
+		}
+	}
+	if lit < 100 {
+		t.Errorf("The screensaver should draw a large clock, but only %d pixels are lit", lit)
+	}
+
+	// Any button goes back, without doing anything else.
+	err = device.ProcessInputEvent(InputEventDown)
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	if device.State != &device.StateSettingsMenu || device.StateSettingsMenu.HighlightedItemIndex != 1 {
+		t.Errorf("A button should dismiss the screensaver and be used up")
+	}
+}
+
+func TestScreensaverOff(t *testing.T) {
+	// Create a new Machine
+	device, err := NewDevice()
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	device.Settings.ScreensaverTimeout = 0
+	now := time.Now()
+	for _, after := range []time.Duration{0, time.Hour} {
+		if err = device.Tick(now.Add(after)); err != nil {
+			t.Errorf("The error should be nil but is %v", err)
+		}
+	}
+	if device.State != &device.StateMainMenu {
+		t.Errorf("The screensaver should not start when it is off")
+	}
+}
+
+func TestScreensaverDayCount(t *testing.T) {
+	// Create a new Machine
+	device, err := NewDevice()
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	device.State = &device.StateScreensaver
+	without, err := GetFrame(DisplaySSD1306.Bounds(), device)
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	device.Settings.EventTime = device.Now().Add(-50 * time.Hour)
+	with, err := GetFrame(DisplaySSD1306.Bounds(), device)
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	if string(without.(*image.RGBA).Pix) == string(with.(*image.RGBA).Pix) {
+		t.Errorf("The day count should be drawn below the clock")
+	}
+}
@@ -0,0 +1,48 @@
+package picodoomsdaymessenger
+
+// SendKeys are the keys that can be chosen to send the message in the compose bar.
+var SendKeys = []InputEvent{InputEventAccept, InputEventPound}
+
+// sendKeyNames is the name of each of the SendKeys, shown in the Send Key menu.
+var sendKeyNames = []string{"Accept", "Pound (#)"}
+
+// sendKeyMenuItems creates a MenuItem for every key in SendKeys.
+func sendKeyMenuItems() (items []MenuItem) {
+	return choiceMenuItems(sendKeyNames, func(d *Device, i int) bool {
+		return d.sendKey() == SendKeys[i]
+	}, func(d *Device, i int) (err error) {
+		d.Settings.SendKey = SendKeys[i]
+		return nil
+	})
+}
+
+// sendKey returns the key that sends the message in the compose bar, which is Accept unless another key has been chosen in the Settings.
+func (d *Device) sendKey() InputEvent {
+	if d.Settings.SendKey == "" {
+		return InputEventAccept
+	}
+	return d.Settings.SendKey
+}
+
+// sendComposerText sends the text in the compose bar to the current Conversation.
+func (d *Device) sendComposerText() (err error) {
+	text, priority := d.takeComposerText()
+	return d.SendMessageWithPriority(d.Conversations[d.CurrentConversationIndex], text, priority)
+}
+
+// acceptComposerCharacter is what Accept does in the compose bar when it is not the send key. It adds the character that is being typed to the KeyboardBuffer, so that the same key can type the next letter straight away.
+// If nothing is being typed, it opens the schedule menu instead, as Pound is busy sending.
+func (d *Device) acceptComposerCharacter() (err error) {
+	c := d.Conversations[d.CurrentConversationIndex]
+	if d.Settings.InputMethod == InputMethodMorse && d.morse.elements != "" {
+		d.flushMorse()
+		return nil
+	}
+	if d.Settings.InputMethod == InputMethodMultiTap && d.pendingCharacter() != "" {
+		c.KeyboardBuffer += d.pendingCharacter()
+		d.clearPendingCharacter()
+		return nil
+	}
+	d.StateScheduleMenu.HighlightedItemIndex = 0
+	return d.ChangeStateWithHistory(&d.StateScheduleMenu)
+}
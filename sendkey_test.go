@@ -0,0 +1,53 @@
+package picodoomsdaymessenger
+
+import "testing"
+
+func TestSendKeyPound(t *testing.T) {
+	device, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	sent := 0
+	device.SendUsingRadio = func(packet []byte) (err error) {
+		sent++
+		return nil
+	}
+	c := device.NewConversation(Person{Name: "Bob", ID: 7})
+	device.CurrentConversationIndex = 0
+	device.State = &device.StateConversationReader
+	device.clearPendingCharacter()
+	// Choose Pound in the Send Key menu.
+	err = device.StateSendKeyMenu.Content[2].Action(device)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if device.Settings.SendKey != InputEventPound {
+		t.Fatalf("expected the send key to be pound, got %q", device.Settings.SendKey)
+	}
+	// Accept finishes the letter, so that the same key can type the next one.
+	for _, inputEvent := range []InputEvent{InputEventNumber2, InputEventNumber2, InputEventAccept, InputEventNumber2} {
+		if err = device.ProcessInputEvent(inputEvent); err != nil {
+			t.Fatalf("The error should be nil but is %v", err)
+		}
+	}
+	if text := device.ComposerText(); text != "ba" || sent != 0 {
+		t.Errorf("expected %q to be typed and nothing sent, got %q and %d packets", "ba", text, sent)
+	}
+	if err = device.ProcessInputEvent(InputEventPound); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	err = sendQueued(device)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if sent != 1 || c.KeyboardBuffer != "" {
+		t.Errorf("expected pound to send the message, got %d packets and %q left", sent, c.KeyboardBuffer)
+	}
+	// With nothing being typed, Accept opens the schedule menu, as Pound no longer does.
+	if err = device.ProcessInputEvent(InputEventAccept); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if device.State != &device.StateScheduleMenu {
+		t.Errorf("expected accept to open the schedule menu, got %q", device.State.Title)
+	}
+}
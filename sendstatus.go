@@ -0,0 +1,50 @@
+package picodoomsdaymessenger
+
+// MessageStatus is how far a Message that the Device sent has got.
+type MessageStatus string
+
+const (
+	// MessageStatusQueued is a Message that is waiting to be sent.
+	MessageStatusQueued MessageStatus = "queued"
+	// MessageStatusSent is a Message that the radio has sent.
+	MessageStatusSent MessageStatus = "sent"
+	// MessageStatusFailed is a Message that could not be sent.
+	MessageStatusFailed MessageStatus = "failed"
+)
+
+// addOwnMessage adds a Message that the Device is sending to its Conversation, and scrolls to it if the newest message was being read.
+func (d *Device) addOwnMessage(c *Conversation, m Message) {
+	if c == nil {
+		return
+	}
+	following := c.HighlightedMessageIndex >= len(c.Messages)-1
+	c.Messages = append(c.Messages, m)
+	if following {
+		c.HighlightedMessageIndex = len(c.Messages) - 1
+	}
+	d.UpdateConversationsMenu()
+}
+
+// finishMessage changes the Status of a queued Message once the radio has tried to send it, and calls OnMessageStatus.
+func (d *Device) finishMessage(c *Conversation, m Message, err error) {
+	if c == nil {
+		return
+	}
+	status := MessageStatusSent
+	if err != nil {
+		status = MessageStatusFailed
+	}
+	// Identical Messages are sent in the order they were queued, so the oldest one that is still queued is the one that was sent.
+	for i := range c.Messages {
+		queued := c.Messages[i]
+		if queued.Status != MessageStatusQueued || queued.Person != m.Person || queued.Text != m.Text || !queued.TimeSent.Equal(m.TimeSent) {
+			continue
+		}
+		c.Messages[i].Status = status
+		d.MarkDirty()
+		if d.OnMessageStatus != nil {
+			d.OnMessageStatus(c, c.Messages[i])
+		}
+		return
+	}
+}
@@ -0,0 +1,47 @@
+package picodoomsdaymessenger
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestMessageStatus(t *testing.T) {
+	device, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	broken := errors.New("radio is broken")
+	device.SendUsingRadio = func(packet []byte) (err error) {
+		return broken
+	}
+	c := device.NewConversation(Person{Name: "Other", ID: device.SelfIdentity.ID + 1})
+	if err = device.SendMessage(c, "hello"); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if len(c.Messages) != 1 || c.Messages[0].Status != MessageStatusQueued || !strings.HasSuffix(c.Messages[0].DisplayText(), "(sending)") {
+		t.Fatalf("expected the message to be shown as sending before it is sent, got %+v", c.Messages)
+	}
+	if err = sendQueued(device); err != broken {
+		t.Fatalf("expected the radio error, got %v", err)
+	}
+	if c.Messages[0].Status != MessageStatusFailed || !strings.HasSuffix(c.Messages[0].DisplayText(), "(not sent)") {
+		t.Errorf("expected the message to be shown as not sent, got %q", c.Messages[0].DisplayText())
+	}
+
+	device.SendUsingRadio = func(packet []byte) (err error) {
+		return nil
+	}
+	if err = device.SendMessage(c, "hello"); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if err = sendQueued(device); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if c.Messages[1].Status != MessageStatusSent || c.Messages[1].DisplayText() != "hello" {
+		t.Errorf("expected the message to be shown as sent, got %q", c.Messages[1].DisplayText())
+	}
+	if c.Messages[0].Status != MessageStatusFailed {
+		t.Errorf("expected the failed message to stay failed, got %q", c.Messages[0].Status)
+	}
+}
@@ -0,0 +1,77 @@
+package picodoomsdaymessenger
+
+import (
+	"errors"
+)
+
+// ErrSensorExists is returned when a Sensor with the same name has already been registered.
+var ErrSensorExists = errors.New("sensor already registered")
+
+// Sensor is a piece of hardware, such as a thermometer or a Geiger counter, that the firmware has added to the Device.
+type Sensor struct {
+	Name string
+	// Read returns the current value of the Sensor as text, with its units, for example "21.5C".
+	Read func() (value string, err error)
+}
+
+// RegisterSensor adds a Sensor to the Device. Each Sensor gets an item in the Tools menu that shows its value as it changes.
+func (d *Device) RegisterSensor(name string, read func() (value string, err error)) (err error) {
+	for _, sensor := range d.Sensors {
+		if sensor.Name == name {
+			return ErrSensorExists
+		}
+	}
+	d.Sensors = append(d.Sensors, Sensor{Name: name, Read: read})
+	return nil
+}
+
+// UpdateToolsMenu lists the built-in tools, then a MenuItem for every Sensor, and then the LastCrash if there is one.
+func (d *Device) UpdateToolsMenu() {
+	d.StateToolsMenu.Content = append([]MenuItem{}, d.StateToolsMenuOld.Content...)
+	for i := range d.Sensors {
+		j := i
+		d.StateToolsMenu.Content = append(d.StateToolsMenu.Content, MenuItem{
+			Text: d.Sensors[j].Name,
+			Action: func(d *Device) (err error) {
+				d.currentSensor = j
+				return d.ChangeStateWithHistory(&d.StateSensor)
+			},
+			CursorIcon: CursorIconRightArrow,
+		})
+	}
+	if d.LastCrash != nil {
+		d.StateToolsMenu.Content = append(d.StateToolsMenu.Content, ToolsMenuItemLastCrash)
+	}
+	if d.StateToolsMenu.HighlightedItemIndex >= len(d.StateToolsMenu.Content) {
+		d.StateToolsMenu.HighlightedItemIndex = 0
+	}
+}
+
+// processSensorInputEvent leaves the Sensor with accept, and ignores the other navigation keys.
+func processSensorInputEvent(d *Device, inputEvent InputEvent) (handled bool, err error) {
+	switch inputEvent {
+	case InputEventAccept:
+		return true, d.GoBackState()
+	case InputEventUp, InputEventDown, InputEventLeft, InputEventRight:
+		return true, nil
+	}
+	return false, nil
+}
+
+// drawSensor reads the open Sensor and draws its value. The Sensor is read again for every frame, so the value stays up to date.
+func drawSensor(d *Device, img Canvas, layout Layout) (err error) {
+	palette := d.Palette()
+	if d.currentSensor >= len(d.Sensors) {
+		drawTitleBar(img, layout, palette, "Sensor")
+		return nil
+	}
+	sensor := d.Sensors[d.currentSensor]
+	value, err := sensor.Read()
+	if err != nil {
+		// A sensor that cannot be read is shown, rather than stopping the Device.
+		palette.Text = palette.StatusAlert
+		value = "Error: " + err.Error()
+	}
+	drawTextPage(img, layout, palette, sensor.Name, value, 0)
+	return nil
+}
@@ -0,0 +1,67 @@
+package picodoomsdaymessenger
+
+import (
+	"errors"
+	"image"
+	"testing"
+)
+
+func TestRegisterSensor(t *testing.T) {
+	device, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	reads := 0
+	err = device.RegisterSensor("Temperature", func() (value string, err error) {
+		reads++
+		return "21.5C", nil
+	})
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	err = device.RegisterSensor("Temperature", nil)
+	if err != ErrSensorExists {
+		t.Errorf("expected ErrSensorExists, got %v", err)
+	}
+	err = device.RegisterSensor("Radiation", func() (value string, err error) {
+		return "", errors.New("no tube")
+	})
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+
+	err = MainMenuItemTools.Action(device)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	base := len(device.StateToolsMenuOld.Content)
+	if len(device.StateToolsMenu.Content) != base+2 || device.StateToolsMenu.Content[base].Text != "Temperature" {
+		t.Fatalf("expected the sensors to be listed after the tools, got %d items", len(device.StateToolsMenu.Content))
+	}
+
+	err = device.StateToolsMenu.Content[base].Action(device)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := GetFrame(image.Rect(0, 0, 128, 64), device); err != nil {
+			t.Fatalf("The error should be nil but is %v", err)
+		}
+	}
+	if reads != 2 {
+		t.Errorf("expected the sensor to be read for every frame, got %d reads", reads)
+	}
+	err = device.ProcessInputEvent(InputEventAccept)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+
+	// A sensor that fails is shown without stopping the Device.
+	err = device.StateToolsMenu.Content[base+1].Action(device)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if _, err := GetFrame(image.Rect(0, 0, 128, 64), device); err != nil {
+		t.Errorf("expected sensor errors to be drawn, got %v", err)
+	}
+}
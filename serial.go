@@ -0,0 +1,202 @@
+package picodoomsdaymessenger
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Define serial errors
+var (
+	ErrSerialUnknownCommand = errors.New("unknown serial command")
+	ErrSerialBadArguments   = errors.New("wrong arguments for serial command")
+)
+
+// SourceSerial is the default source tag for values pushed over the serial port.
+const SourceSerial = "serial"
+
+// Position is a location on the earth in degrees. Source tags where it came from, for example "serial" or "gps".
+type Position struct {
+	Latitude  float64
+	Longitude float64
+	Source    string
+	UpdatedAt time.Time
+}
+
+// Valid returns true if the Position has been set.
+func (p Position) Valid() bool {
+	return p.Source != ""
+}
+
+// Now returns the current time of the Device, including any correction pushed by SetTime.
+func (d *Device) Now() time.Time {
+	return d.clock().Add(d.TimeOffset)
+}
+
+// clock reads the uncorrected time from the Clock of the Device, or from time.Now if it has none.
+func (d *Device) clock() time.Time {
+	if d.Clock == nil {
+		return time.Now()
+	}
+	return d.Clock()
+}
+
+// SetTime corrects the clock of the Device to an accurate time, and records the source of that time.
+func (d *Device) SetTime(accurate time.Time, source string) {
+	d.TimeOffset = accurate.Sub(d.clock())
+	d.TimeSource = source
+	d.MarkDirty()
+}
+
+// SetPosition records the current position of the Device and the source of that position.
+func (d *Device) SetPosition(latitude, longitude float64, source string) {
+	d.Position = Position{
+		Latitude:  latitude,
+		Longitude: longitude,
+		Source:    source,
+		UpdatedAt: d.Now(),
+	}
+	d.MarkDirty()
+}
+
+// ProcessSerialCommand runs a single line command that was received from a computer or phone connected over the serial port, and returns a response line.
+//
+// The commands are:
+//
+//	time <unix seconds> [source]
+//	position <latitude> <longitude> [source]
+//	conversations
+//	messages <conversation index>
+//	send <conversation index> <text>
+//	export <json|csv>
+//	contacts
+//	import <contact card>...
+//	input <input event>
+//	state
+//
+// The source defaults to "serial". Together, the other commands let a computer use the Device without its screen or keypad.
+func (d *Device) ProcessSerialCommand(line string) (response string, err error) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return "", nil
+	}
+	d.MarkDirty()
+	switch fields[0] {
+	case "time":
+		{
+			if len(fields) < 2 || len(fields) > 3 {
+				return "", ErrSerialBadArguments
+			}
+			seconds, err := strconv.ParseInt(fields[1], 10, 64)
+			if err != nil {
+				return "", ErrSerialBadArguments
+			}
+			d.SetTime(time.Unix(seconds, 0), serialSource(fields, 2))
+			return "ok", nil
+		}
+	case "position":
+		{
+			if len(fields) < 3 || len(fields) > 4 {
+				return "", ErrSerialBadArguments
+			}
+			// NaN is not less or greater than anything, so it is rejected on its own. Infinities are out of range.
+			latitude, err := strconv.ParseFloat(fields[1], 64)
+			if err != nil || math.IsNaN(latitude) || latitude < -90 || latitude > 90 {
+				return "", ErrSerialBadArguments
+			}
+			longitude, err := strconv.ParseFloat(fields[2], 64)
+			if err != nil || math.IsNaN(longitude) || longitude < -180 || longitude > 180 {
+				return "", ErrSerialBadArguments
+			}
+			d.SetPosition(latitude, longitude, serialSource(fields, 3))
+			return "ok", nil
+		}
+	case "conversations":
+		{
+			return d.serialConversations(), nil
+		}
+	case "messages":
+		{
+			if len(fields) != 2 {
+				return "", ErrSerialBadArguments
+			}
+			c, err := d.serialConversation(fields[1])
+			if err != nil {
+				return "", err
+			}
+			return d.serialMessages(c), nil
+		}
+	case "send":
+		{
+			if len(fields) < 3 {
+				return "", ErrSerialBadArguments
+			}
+			c, err := d.serialConversation(fields[1])
+			if err != nil {
+				return "", err
+			}
+			err = d.SendMessage(c, serialText(line, 2))
+			if err != nil {
+				return "", err
+			}
+			return "ok", nil
+		}
+	case "export":
+		{
+			if len(fields) != 2 {
+				return "", ErrSerialBadArguments
+			}
+			output, err := d.ExportHistory(ExportFormat(fields[1]))
+			if err != nil {
+				return "", err
+			}
+			return string(bytes.TrimSuffix(output, []byte("\n"))), nil
+		}
+	case "contacts":
+		{
+			if len(d.Contacts) == 0 {
+				return "none", nil
+			}
+			return d.ExportContacts(), nil
+		}
+	case "import":
+		{
+			if len(fields) < 2 {
+				return "", ErrSerialBadArguments
+			}
+			imported, err := d.ImportContacts(serialText(line, 1))
+			if err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("ok %d", imported), nil
+		}
+	case "input":
+		{
+			if len(fields) != 2 {
+				return "", ErrSerialBadArguments
+			}
+			err = d.serialInput(fields[1])
+			if err != nil {
+				return "", err
+			}
+			return "ok", nil
+		}
+	case "state":
+		{
+			return d.State.Title, nil
+		}
+	}
+	return "", ErrSerialUnknownCommand
+}
+
+// serialSource returns the source tag at index i of the fields, or SourceSerial if there is none.
+func serialSource(fields []string, i int) string {
+	if len(fields) > i {
+		return fields[i]
+	}
+	return SourceSerial
+}
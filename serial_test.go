@@ -0,0 +1,75 @@
+package picodoomsdaymessenger
+
+import (
+	"testing"
+	"time"
+)
+
+func TestProcessSerialCommandTime(t *testing.T) {
+	// Create a new Machine
+	device, err := NewDevice()
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	clock := time.Unix(1000, 0)
+	device.Clock = func() time.Time {
+		return clock
+	}
+	response, err := device.ProcessSerialCommand("time 2000000000 phone")
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	if response != "ok" {
+		t.Errorf("The response should be ok but is %v", response)
+	}
+	if !device.Now().Equal(time.Unix(2000000000, 0)) {
+		t.Errorf("The time should be %v but is %v", time.Unix(2000000000, 0), device.Now())
+	}
+	clock = clock.Add(time.Minute)
+	if !device.Now().Equal(time.Unix(2000000060, 0)) {
+		t.Errorf("The time should be %v but is %v", time.Unix(2000000060, 0), device.Now())
+	}
+	if device.TimeSource != "phone" {
+		t.Errorf("The time source should be phone but is %v", device.TimeSource)
+	}
+
+	_, err = device.ProcessSerialCommand("time soon")
+	if err != ErrSerialBadArguments {
+		t.Errorf("The error should be ErrSerialBadArguments but is %v", err)
+	}
+}
+
+func TestProcessSerialCommandPosition(t *testing.T) {
+	// Create a new Machine
+	device, err := NewDevice()
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	if device.Position.Valid() {
+		t.Errorf("The default position should not be valid but is %v", device.Position)
+	}
+	_, err = device.ProcessSerialCommand("position 51.5 -0.12")
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	if device.Position.Latitude != 51.5 || device.Position.Longitude != -0.12 {
+		t.Errorf("The position should be 51.5, -0.12 but is %v, %v", device.Position.Latitude, device.Position.Longitude)
+	}
+	if device.Position.Source != SourceSerial {
+		t.Errorf("The position source should be serial but is %v", device.Position.Source)
+	}
+
+	for _, command := range []string{"position 91 0", "position 0 -180.5", "position NaN 0", "position 0 nan", "position Inf 0", "position 0 -Inf"} {
+		_, err = device.ProcessSerialCommand(command)
+		if err != ErrSerialBadArguments {
+			t.Errorf("The error for %q should be ErrSerialBadArguments but is %v", command, err)
+		}
+	}
+	if device.Position.Latitude != 51.5 || device.Position.Longitude != -0.12 {
+		t.Errorf("A rejected position should not change the position, but it is %v, %v", device.Position.Latitude, device.Position.Longitude)
+	}
+	_, err = device.ProcessSerialCommand("teleport")
+	if err != ErrSerialUnknownCommand {
+		t.Errorf("The error should be ErrSerialUnknownCommand but is %v", err)
+	}
+}
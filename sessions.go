@@ -0,0 +1,102 @@
+package picodoomsdaymessenger
+
+import (
+	"bytes"
+	"crypto/ecdh"
+	"crypto/sha256"
+)
+
+// exchangeKeySize is the length of the X25519 public key in a pairing packet.
+const exchangeKeySize = 32
+
+// sessionKeyLabel is mixed into every SessionKey, so that the shared secret of a pairing is not used as a key directly.
+var sessionKeyLabel = []byte("doom session")
+
+// IconLocked is a padlock, shown next to Conversations that are encrypted with the SessionKey of a Contact.
+var IconLocked = Icon{
+	0b00111100,
+	0b01000010,
+	0b01000010,
+	0b11111111,
+	0b11100111,
+	0b11100111,
+	0b11111111,
+	0b00000000,
+}
+
+// sessionKey agrees a key with the device that is pairing. Both devices get the same key, and it depends on both pairing packets, so it only matches if the pairing codes did.
+func (p pairingState) sessionKey() (key []byte, err error) {
+	if p.exchange == nil || p.Peer == nil {
+		return nil, ErrInvalidPairing
+	}
+	peerKey, err := ecdh.X25519().NewPublicKey(p.Peer.ExchangeKey)
+	if err != nil {
+		return nil, ErrInvalidPairing
+	}
+	shared, err := p.exchange.ECDH(peerKey)
+	if err != nil {
+		return nil, ErrInvalidPairing
+	}
+	first, second := PairingRequestToBytes(p.own), PairingRequestToBytes(*p.Peer)
+	if bytes.Compare(first, second) > 0 {
+		first, second = second, first
+	}
+	hash := sha256.New()
+	hash.Write(sessionKeyLabel)
+	hash.Write(shared)
+	hash.Write(first)
+	hash.Write(second)
+	return hash.Sum(nil), nil
+}
+
+// contactSession returns a Channel with the SessionKey of the Contact with an ID, so that messages to them can be sealed like messages on a Channel. It returns false if there is no such Contact, or they have no SessionKey.
+func (d *Device) contactSession(id int) (session Channel, ok bool) {
+	for _, contact := range d.Contacts {
+		if contact.Person.ID == id && len(contact.SessionKey) > 0 {
+			return Channel{Key: contact.SessionKey}, true
+		}
+	}
+	return Channel{}, false
+}
+
+// openContactMessage decrypts a packet with the SessionKey of each Contact, and decodes the message inside it. The message must be from the Contact whose key it was sealed with.
+func (d *Device) openContactMessage(input []byte) (output Message, err error) {
+	for _, contact := range d.Contacts {
+		if len(contact.SessionKey) == 0 {
+			continue
+		}
+		inner, err := Channel{Key: contact.SessionKey}.Open(input)
+		if err == ErrUnknownChannel {
+			continue
+		}
+		if err != nil {
+			return output, err
+		}
+		counter, inner, err := splitReplayCounter(inner)
+		if err != nil {
+			return output, err
+		}
+		if bytes.HasPrefix(inner, channelPrefix) {
+			return output, ErrMalformedMessage
+		}
+		output, err = d.BytesToMessage(inner)
+		if err != nil {
+			return output, err
+		}
+		if output.Person.ID != contact.Person.ID {
+			return output, ErrUnknownChannel
+		}
+		output.Private = true
+		return output, d.checkReplayCounter(replaySender{source: replaySession, id: output.Person.ID}, counter)
+	}
+	return output, ErrUnknownChannel
+}
+
+// ConversationPrivate returns true if the messages sent to a Conversation are encrypted with the SessionKey of a Contact.
+func (d *Device) ConversationPrivate(c *Conversation) bool {
+	if c.Broadcast || c.Channel != "" || c.Destination() == BroadcastID {
+		return false
+	}
+	_, ok := d.contactSession(c.Destination())
+	return ok
+}
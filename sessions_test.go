@@ -0,0 +1,79 @@
+package picodoomsdaymessenger
+
+import (
+	"bytes"
+	"image"
+	"testing"
+)
+
+func TestContactSessionKeys(t *testing.T) {
+	alice := newSigningDevice(t, 10)
+	bob := newSigningDevice(t, 20)
+	eve := newSigningDevice(t, 30)
+	alice.SendUsingRadio = func(packet []byte) (err error) {
+		return bob.ReceiveFromRadio(packet)
+	}
+	bob.SendUsingRadio = func(packet []byte) (err error) {
+		return alice.ReceiveFromRadio(packet)
+	}
+	for _, device := range []*Device{alice, bob} {
+		if err := MainMenuItemPeople.Action(device); err != nil {
+			t.Fatalf("The error should be nil but is %v", err)
+		}
+		if err := PeopleMenuItemPair.Action(device); err != nil {
+			t.Fatalf("The error should be nil but is %v", err)
+		}
+		if err := sendQueued(device); err != nil {
+			t.Fatalf("The error should be nil but is %v", err)
+		}
+	}
+	// Alice answers the pairing packet of bob with her Nonce, and bob answers with his.
+	if err := sendQueued(alice, bob); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	for _, device := range []*Device{alice, bob} {
+		if err := device.ConfirmPairing(); err != nil {
+			t.Fatalf("The error should be nil but is %v", err)
+		}
+	}
+	if len(alice.Contacts[0].SessionKey) != 32 || !bytes.Equal(alice.Contacts[0].SessionKey, bob.Contacts[0].SessionKey) {
+		t.Fatalf("expected both devices to agree a session key")
+	}
+
+	var eveErr error
+	alice.SendUsingRadio = func(packet []byte) (err error) {
+		eveErr = eve.ReceiveFromRadio(packet)
+		return bob.ReceiveFromRadio(packet)
+	}
+	bob.State = &bob.StateMainMenu
+	eve.State = &eve.StateMainMenu
+	c := alice.NewConversation(bob.SelfIdentity)
+	if !alice.ConversationPrivate(c) {
+		t.Errorf("expected a conversation with a paired contact to be private")
+	}
+	err := alice.SendMessage(c, "just us")
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	err = sendQueued(alice)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if eveErr != ErrUnknownChannel || len(eve.Conversations) != 0 {
+		t.Errorf("expected a device without the session key not to read the message, got %v", eveErr)
+	}
+	if len(bob.Conversations) != 1 || !bob.Conversations[0].Messages[0].Private || bob.Conversations[0].Messages[0].Text != "just us" {
+		t.Fatalf("expected bob to read the private message, got %+v", bob.Conversations)
+	}
+	if !bob.ConversationPrivate(bob.Conversations[0]) || bob.StateConversationsMenu.Content[2].Icon != &IconLocked {
+		t.Errorf("expected the conversation to be shown with a padlock")
+	}
+	if alice.ConversationPrivate(alice.NewConversation(eve.SelfIdentity)) {
+		t.Errorf("expected a conversation with someone who is not paired not to be private")
+	}
+	alice.State = &alice.StateConversationReader
+	alice.CurrentConversationIndex = 0
+	if _, err := GetFrame(image.Rect(0, 0, 128, 64), alice); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+}
@@ -0,0 +1,186 @@
+package picodoomsdaymessenger
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+)
+
+// ErrInvalidSignature is returned when a signed message has been changed since it was signed.
+var ErrInvalidSignature = errors.New("message signature is invalid")
+
+// signedPrefix is the start of a signed message packet. It is followed by the public key of the sender, the signature, and then the "doom" message that was signed.
+var signedPrefix = []byte{0x64, 0x73, 0x69, 0x67} // ASCII for "dsig"
+
+// signatureOverhead is how many bytes signing adds to a message packet.
+const signatureOverhead = len("dsig") + ed25519.PublicKeySize + ed25519.SignatureSize
+
+// keysStorageKey is the key that the signing keys are saved with.
+const keysStorageKey = "keys"
+
+// MaxPinnedKeys is how many keys heard from people who are not Contacts are remembered. When there are more, one of them is forgotten to make room.
+const MaxPinnedKeys = 64
+
+// signingKeys are the Ed25519 keys of the Device, and the public keys of the other devices that it has heard from.
+type signingKeys struct {
+	private ed25519.PrivateKey
+	// pinned holds the first public key heard for each Person.ID. A later message with the same ID and a different key is not trusted.
+	// Only the keys of Contacts are saved. The others are remembered until the Device is turned off, so that they cannot fill the Storage.
+	pinned map[int]ed25519.PublicKey
+}
+
+// savedKeys is how the signingKeys are written to the Storage.
+type savedKeys struct {
+	Seed   []byte
+	Pinned map[int][]byte
+}
+
+// GenerateKeys makes a new Ed25519 keypair for the Device. Messages sent after this are signed.
+func (d *Device) GenerateKeys() (err error) {
+	_, private, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		return err
+	}
+	d.keys.private = private
+	return d.SaveKeys()
+}
+
+// PublicKey returns the public key that the messages of the Device are signed with, or nil if it has no keys.
+func (d *Device) PublicKey() ed25519.PublicKey {
+	if d.keys.private == nil {
+		return nil
+	}
+	return d.keys.private.Public().(ed25519.PublicKey)
+}
+
+// SaveKeys writes the keypair of the Device and the pinned keys of its Contacts to the Storage. It does nothing if the Device has no Storage.
+func (d *Device) SaveKeys() (err error) {
+	if d.Storage == nil {
+		return nil
+	}
+	saved := savedKeys{Pinned: make(map[int][]byte)}
+	if d.keys.private != nil {
+		saved.Seed = d.keys.private.Seed()
+	}
+	for _, contact := range d.Contacts {
+		if key, ok := d.keys.pinned[contact.Person.ID]; ok {
+			saved.Pinned[contact.Person.ID] = key
+		}
+	}
+	data, err := json.Marshal(saved)
+	if err != nil {
+		return err
+	}
+	return d.Storage.Save(keysStorageKey, data)
+}
+
+// LoadKeys reads the keys from the Storage of the Device. The first time the Device starts there are no keys to load, so a new keypair is generated and saved.
+func (d *Device) LoadKeys() (err error) {
+	if d.Storage == nil {
+		return nil
+	}
+	data, err := d.Storage.Load(keysStorageKey)
+	if err == ErrStorageNotFound {
+		return d.GenerateKeys()
+	}
+	if err != nil {
+		return err
+	}
+	saved := savedKeys{}
+	err = json.Unmarshal(data, &saved)
+	if err != nil {
+		return err
+	}
+	if len(saved.Seed) != ed25519.SeedSize {
+		return d.GenerateKeys()
+	}
+	d.keys.private = ed25519.NewKeyFromSeed(saved.Seed)
+	d.keys.pinned = make(map[int]ed25519.PublicKey)
+	for id, key := range saved.Pinned {
+		if len(key) == ed25519.PublicKeySize {
+			d.keys.pinned[id] = key
+		}
+	}
+	return nil
+}
+
+// signMessageBytes wraps a "doom" packet with the public key of the Device and a signature of the packet.
+func (d *Device) signMessageBytes(message []byte) (output []byte) {
+	output = make([]byte, 0, signatureOverhead+len(message))
+	output = append(output, signedPrefix...)
+	output = append(output, d.PublicKey()...)
+	output = append(output, ed25519.Sign(d.keys.private, message)...)
+	output = append(output, message...)
+	return output
+}
+
+// signingKey returns the public key that a signed packet says it was signed with. It is only the key that signed the packet once the packet has been checked by verifySignedMessage.
+func signingKey(packet []byte) (key ed25519.PublicKey, ok bool) {
+	if !bytes.HasPrefix(packet, signedPrefix) || len(packet) < len(signedPrefix)+ed25519.PublicKeySize {
+		return nil, false
+	}
+	return ed25519.PublicKey(packet[len(signedPrefix) : len(signedPrefix)+ed25519.PublicKeySize]), true
+}
+
+// verifySignedMessage checks the signature of a signed packet and decodes the message inside it.
+// The Message is Verified only if the public key is the one that was first heard from the Person.ID.
+func (d *Device) verifySignedMessage(input []byte) (output Message, err error) {
+	input = input[len(signedPrefix):]
+	if len(input) < ed25519.PublicKeySize+ed25519.SignatureSize {
+		return output, ErrMalformedMessage
+	}
+	publicKey := ed25519.PublicKey(input[:ed25519.PublicKeySize])
+	signature := input[ed25519.PublicKeySize : ed25519.PublicKeySize+ed25519.SignatureSize]
+	message := input[ed25519.PublicKeySize+ed25519.SignatureSize:]
+	if bytes.HasPrefix(message, signedPrefix) {
+		return output, ErrMalformedMessage
+	}
+	if !ed25519.Verify(publicKey, message, signature) {
+		return output, ErrInvalidSignature
+	}
+	output, err = d.BytesToMessage(message)
+	if err != nil {
+		return output, err
+	}
+	pinned, ok := d.keys.pinned[output.Person.ID]
+	if !ok {
+		output.Verified = d.pinHeardKey(output.Person.ID, publicKey)
+		return output, nil
+	}
+	output.Verified = bytes.Equal(pinned, publicKey)
+	return output, nil
+}
+
+// pinHeardKey remembers the first key heard from a Person.ID. Once MaxPinnedKeys are remembered, the key of someone who is not a Contact is forgotten to make room. It returns false if every key is the key of a Contact.
+func (d *Device) pinHeardKey(id int, key ed25519.PublicKey) (pinned bool) {
+	if d.keys.pinned == nil {
+		d.keys.pinned = make(map[int]ed25519.PublicKey)
+	}
+	if len(d.keys.pinned) >= MaxPinnedKeys+len(d.Contacts) && !d.forgetHeardKey() {
+		return false
+	}
+	d.keys.pinned[id] = append(ed25519.PublicKey{}, key...)
+	return true
+}
+
+// forgetHeardKey forgets the pinned key of someone who is not a Contact. It returns false if there is none.
+func (d *Device) forgetHeardKey() (forgotten bool) {
+	for id := range d.keys.pinned {
+		if !d.isContact(id) {
+			delete(d.keys.pinned, id)
+			return true
+		}
+	}
+	return false
+}
+
+// isContact returns true if a Person.ID is one of the Contacts.
+func (d *Device) isContact(id int) bool {
+	for _, contact := range d.Contacts {
+		if contact.Person.ID == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,221 @@
+package picodoomsdaymessenger
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"strings"
+	"testing"
+)
+
+func newSigningDevice(t *testing.T, id int) *Device {
+	device, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	device.SelfIdentity = Person{Name: "Signer", ID: id}
+	device.Storage = MemoryStorage{}
+	err = device.LoadKeys()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	return device
+}
+
+func TestSignedMessageIsVerified(t *testing.T) {
+	sender := newSigningDevice(t, 10)
+	receiver := newSigningDevice(t, 20)
+
+	packet, err := sender.MesageToBytes(Message{Text: "hello", Person: sender.SelfIdentity})
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if !bytes.HasPrefix(packet, signedPrefix) {
+		t.Fatalf("expected the packet to be signed, got %q", packet)
+	}
+	message, err := receiver.BytesToMessage(packet)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if message.Text != "hello" || message.Person != sender.SelfIdentity {
+		t.Errorf("unexpected message: %+v", message)
+	}
+	if !message.Verified {
+		t.Errorf("expected the first message from a key to be verified")
+	}
+}
+
+func TestTamperedMessageIsRejected(t *testing.T) {
+	sender := newSigningDevice(t, 10)
+	receiver := newSigningDevice(t, 20)
+
+	packet, err := sender.MesageToBytes(Message{Text: "hello", Person: sender.SelfIdentity})
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	packet[len(packet)-1] = 'j'
+	_, err = receiver.BytesToMessage(packet)
+	if err != ErrInvalidSignature {
+		t.Errorf("expected ErrInvalidSignature, got %v", err)
+	}
+	_, err = receiver.BytesToMessage(packet[:20])
+	if err != ErrMalformedMessage {
+		t.Errorf("expected ErrMalformedMessage, got %v", err)
+	}
+}
+
+func TestSpoofedSenderIsNotVerified(t *testing.T) {
+	sender := newSigningDevice(t, 10)
+	spoofer := newSigningDevice(t, 30)
+	receiver := newSigningDevice(t, 20)
+
+	packet, err := sender.MesageToBytes(Message{Text: "hello", Person: sender.SelfIdentity})
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	_, err = receiver.BytesToMessage(packet)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+
+	// The spoofer signs with its own key, but claims to be the sender.
+	packet, err = spoofer.MesageToBytes(Message{Text: "trust me", Person: sender.SelfIdentity})
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	message, err := receiver.BytesToMessage(packet)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if message.Verified {
+		t.Errorf("expected a message signed with a different key to be unverified")
+	}
+
+	// Unsigned messages are never verified.
+	unsigned, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	packet, err = unsigned.MesageToBytes(Message{Text: "hi", Person: sender.SelfIdentity})
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	message, err = receiver.BytesToMessage(packet)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if message.Verified {
+		t.Errorf("expected an unsigned message to be unverified")
+	}
+}
+
+func TestKeysAreKept(t *testing.T) {
+	sender := newSigningDevice(t, 10)
+	stranger := newSigningDevice(t, 30)
+	receiver := newSigningDevice(t, 20)
+	receiver.Contacts = []Contact{{Person: sender.SelfIdentity}}
+	for _, d := range []*Device{sender, stranger} {
+		packet, err := d.MesageToBytes(Message{Text: "hello", Person: d.SelfIdentity})
+		if err != nil {
+			t.Fatalf("The error should be nil but is %v", err)
+		}
+		_, err = receiver.BytesToMessage(packet)
+		if err != nil {
+			t.Fatalf("The error should be nil but is %v", err)
+		}
+	}
+	err := receiver.SaveKeys()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+
+	restarted, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	restarted.Storage = receiver.Storage
+	err = restarted.LoadKeys()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if !bytes.Equal(restarted.PublicKey(), receiver.PublicKey()) {
+		t.Errorf("expected the keypair to be kept")
+	}
+	if !bytes.Equal(restarted.keys.pinned[10], sender.PublicKey()) {
+		t.Errorf("expected the pinned key of the contact to be kept")
+	}
+	if _, ok := restarted.keys.pinned[30]; ok {
+		t.Errorf("expected the key of someone who is not a contact not to be saved")
+	}
+}
+
+func TestPinnedKeysAreBounded(t *testing.T) {
+	receiver := newSigningDevice(t, 20)
+	storage := countingStorage{MemoryStorage: receiver.Storage.(MemoryStorage), saves: map[string]int{}}
+	receiver.Storage = storage
+	receiver.Contacts = []Contact{{Person: Person{Name: "Friend", ID: 5}}}
+	receiver.keys.pinned = map[int]ed25519.PublicKey{5: receiver.PublicKey()}
+	sender := newSigningDevice(t, 10)
+	for id := 100; id < 100+2*MaxPinnedKeys; id++ {
+		packet, err := sender.MesageToBytes(Message{Text: "hello", Person: Person{Name: "Signer", ID: id}})
+		if err != nil {
+			t.Fatalf("The error should be nil but is %v", err)
+		}
+		message, err := receiver.BytesToMessage(packet)
+		if err != nil {
+			t.Fatalf("The error should be nil but is %v", err)
+		}
+		if !message.Verified {
+			t.Errorf("expected the first key heard from %d to be trusted", id)
+		}
+	}
+	if len(receiver.keys.pinned) != MaxPinnedKeys+1 {
+		t.Errorf("expected %d keys to be remembered, got %d", MaxPinnedKeys+1, len(receiver.keys.pinned))
+	}
+	if _, ok := receiver.keys.pinned[5]; !ok {
+		t.Errorf("expected the key of a contact never to be forgotten")
+	}
+	if storage.saves[keysStorageKey] != 0 {
+		t.Errorf("expected keys heard from people who are not contacts not to be saved, got %d saves", storage.saves[keysStorageKey])
+	}
+}
+
+func TestSignedMessageFitsInPacket(t *testing.T) {
+	sender := newSigningDevice(t, 10)
+	packet, err := sender.MesageToBytes(Message{Text: string(bytes.Repeat([]byte("t"), MaxMessageTextLength)), Person: sender.SelfIdentity})
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if len(packet) > MaxPacketLength {
+		t.Errorf("expected the signed packet to fit in %d bytes, got %d", MaxPacketLength, len(packet))
+	}
+	receiver := newSigningDevice(t, 20)
+	_, err = receiver.BytesToMessage(packet)
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+
+	// A message that is too long is cut off when it is sent, and the sender keeps the text that was received.
+	var sent []byte
+	sender.SendUsingRadio = func(packet []byte) (err error) {
+		sent = packet
+		return nil
+	}
+	c := sender.NewConversation(receiver.SelfIdentity)
+	err = sender.SendMessage(c, strings.Repeat("t", 180))
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	err = sendQueued(sender)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	err = receiver.ReceiveFromRadio(sent)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	received := receiver.Conversations[0].Messages[len(receiver.Conversations[0].Messages)-1].Text
+	kept := c.Messages[len(c.Messages)-1].Text
+	if received != kept || len(received) >= 180 {
+		t.Errorf("expected the sender to keep the %d characters that were received, got %d", len(received), len(kept))
+	}
+}
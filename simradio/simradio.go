@@ -0,0 +1,211 @@
+// Package simradio simulates a LoRa radio over UDP multicast, so that several copies of the simulator on a network can message each other without any hardware.
+package simradio
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"net"
+	"sync"
+	"time"
+
+	picodoomsdaymessenger "github.com/headblockhead/picoDoomsdayMessenger"
+	"github.com/headblockhead/picoDoomsdayMessenger/firmware"
+)
+
+// DefaultAddress is the multicast group and port that simulated radios use if no other is given.
+const DefaultAddress = "239.77.68.77:7368"
+
+// DefaultRSSI is the RSSI in dBm that every simulated packet is received with.
+const DefaultRSSI = -40
+
+// NoiseFloor is the RSSI in dBm of a channel that no SimRadio is sending on.
+const NoiseFloor = -120
+
+// ErrPacketTooLong is returned when a packet longer than a LoRa radio can send is sent.
+var ErrPacketTooLong = errors.New("packet is too long to send")
+
+// headerLength is the length of the header that is sent before each packet: the ID of the sending radio, its frequency, and its modem config.
+const headerLength = 8 + 8 + 3*4
+
+// SimRadio is a firmware.Radio that sends packets to a UDP multicast group.
+// Like a real radio, it only hears packets sent on the same frequency with the same modem config, and never hears its own packets.
+type SimRadio struct {
+	// RSSI is the RSSI in dBm that packets are received with.
+	RSSI int
+
+	id        uint64
+	send      *net.UDPConn
+	receive   *net.UDPConn
+	mutex     sync.Mutex
+	frequency float64
+	config    picodoomsdaymessenger.ModemConfig
+	handler   func(packet []byte, rssi int)
+	busyUntil time.Time
+}
+
+var _ firmware.Radio = &SimRadio{}
+
+// New joins a multicast group, such as DefaultAddress, and starts receiving packets sent to it.
+// Every SimRadio needs a different id, so that it can ignore its own packets.
+func New(address string, id uint64) (r *SimRadio, err error) {
+	group, err := net.ResolveUDPAddr("udp4", address)
+	if err != nil {
+		return nil, err
+	}
+	receive, err := net.ListenMulticastUDP("udp4", nil, group)
+	if err != nil {
+		return nil, err
+	}
+	// The receiving connection does not loop packets back to this computer, so a separate connection is used to send them.
+	send, err := net.DialUDP("udp4", nil, group)
+	if err != nil {
+		receive.Close()
+		return nil, err
+	}
+	r = &SimRadio{
+		RSSI:      DefaultRSSI,
+		id:        id,
+		send:      send,
+		receive:   receive,
+		frequency: picodoomsdaymessenger.FrequencyPresets[0].FrequencyMHz,
+		config:    picodoomsdaymessenger.DefaultModemConfig,
+	}
+	go r.listen()
+	return r, nil
+}
+
+// Close stops the SimRadio from sending and receiving.
+func (r *SimRadio) Close() (err error) {
+	err = r.receive.Close()
+	if sendErr := r.send.Close(); err == nil {
+		err = sendErr
+	}
+	return err
+}
+
+// SetFrequency tunes the SimRadio to a frequency in MHz.
+func (r *SimRadio) SetFrequency(frequencyMHz float64) (err error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.frequency = frequencyMHz
+	return nil
+}
+
+// SetModemConfig changes the LoRa modem parameters of the SimRadio.
+func (r *SimRadio) SetModemConfig(config picodoomsdaymessenger.ModemConfig) (err error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.config = config
+	return nil
+}
+
+// SetTxPower does nothing, as every SimRadio hears every other one however much power it sends with.
+func (r *SimRadio) SetTxPower(dBm int) (err error) {
+	return nil
+}
+
+// SetPreambleLength does nothing, as a SimRadio never sleeps through the start of a packet.
+func (r *SimRadio) SetPreambleLength(symbols int) (err error) {
+	return nil
+}
+
+// Sleep does nothing, as a SimRadio has no power to save. It keeps hearing packets.
+func (r *SimRadio) Sleep() (err error) {
+	return nil
+}
+
+// Listen does nothing, as a SimRadio is always listening.
+func (r *SimRadio) Listen() (err error) {
+	return nil
+}
+
+// ChannelActive returns true while a packet that was heard would still be on the air, had it been sent by a real radio.
+func (r *SimRadio) ChannelActive() (busy bool, err error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return time.Now().Before(r.busyUntil), nil
+}
+
+// ChannelRSSI returns the RSSI of the SimRadio while a packet that was heard would still be on the air, and NoiseFloor otherwise.
+func (r *SimRadio) ChannelRSSI() (dBm int, err error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if time.Now().Before(r.busyUntil) {
+		return r.RSSI, nil
+	}
+	return NoiseFloor, nil
+}
+
+// Send transmits a packet to every other SimRadio in the multicast group.
+func (r *SimRadio) Send(packet []byte) (err error) {
+	if len(packet) > picodoomsdaymessenger.MaxPacketLength {
+		return ErrPacketTooLong
+	}
+	r.mutex.Lock()
+	datagram := encodeDatagram(r.id, r.frequency, r.config, packet)
+	r.mutex.Unlock()
+	_, err = r.send.Write(datagram)
+	return err
+}
+
+// SetReceiveHandler sets the function that is called with every packet that is received. It is called from the goroutine that receives packets.
+func (r *SimRadio) SetReceiveHandler(handler func(packet []byte, rssi int)) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.handler = handler
+}
+
+// listen passes every packet heard on the same frequency and modem config to the handler, until the SimRadio is closed.
+func (r *SimRadio) listen() {
+	buffer := make([]byte, headerLength+picodoomsdaymessenger.MaxPacketLength+1)
+	for {
+		n, _, err := r.receive.ReadFromUDP(buffer)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			continue
+		}
+		id, frequency, config, packet, ok := decodeDatagram(buffer[:n])
+		if !ok || id == r.id {
+			continue
+		}
+		r.mutex.Lock()
+		handler := r.handler
+		heard := frequency == r.frequency && config == r.config
+		rssi := r.RSSI
+		if heard {
+			r.busyUntil = time.Now().Add(config.Airtime(len(packet)))
+		}
+		r.mutex.Unlock()
+		if heard && handler != nil {
+			handler(packet, rssi)
+		}
+	}
+}
+
+// encodeDatagram puts the header in front of a packet.
+func encodeDatagram(id uint64, frequency float64, config picodoomsdaymessenger.ModemConfig, packet []byte) (datagram []byte) {
+	datagram = make([]byte, headerLength, headerLength+len(packet))
+	binary.BigEndian.PutUint64(datagram[0:], id)
+	binary.BigEndian.PutUint64(datagram[8:], math.Float64bits(frequency))
+	binary.BigEndian.PutUint32(datagram[16:], uint32(config.SpreadingFactor))
+	binary.BigEndian.PutUint32(datagram[20:], uint32(config.BandwidthHz))
+	binary.BigEndian.PutUint32(datagram[24:], uint32(config.CodingRate))
+	return append(datagram, packet...)
+}
+
+// decodeDatagram splits a datagram into its header and packet. It returns false if the datagram is not from a SimRadio.
+func decodeDatagram(datagram []byte) (id uint64, frequency float64, config picodoomsdaymessenger.ModemConfig, packet []byte, ok bool) {
+	if len(datagram) < headerLength || len(datagram) > headerLength+picodoomsdaymessenger.MaxPacketLength {
+		return 0, 0, config, nil, false
+	}
+	id = binary.BigEndian.Uint64(datagram[0:])
+	frequency = math.Float64frombits(binary.BigEndian.Uint64(datagram[8:]))
+	config.SpreadingFactor = int(binary.BigEndian.Uint32(datagram[16:]))
+	config.BandwidthHz = int(binary.BigEndian.Uint32(datagram[20:]))
+	config.CodingRate = int(binary.BigEndian.Uint32(datagram[24:]))
+	packet = append([]byte{}, datagram[headerLength:]...)
+	return id, frequency, config, packet, true
+}
@@ -0,0 +1,126 @@
+package simradio
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	picodoomsdaymessenger "github.com/headblockhead/picoDoomsdayMessenger"
+)
+
+func TestDatagram(t *testing.T) {
+	config := picodoomsdaymessenger.ModemConfig{SpreadingFactor: 9, BandwidthHz: 62500, CodingRate: 6}
+	datagram := encodeDatagram(42, 868.1, config, []byte("doom"))
+	id, frequency, decodedConfig, packet, ok := decodeDatagram(datagram)
+	if !ok {
+		t.Fatalf("expected the datagram to decode")
+	}
+	if id != 42 || frequency != 868.1 || decodedConfig != config || !bytes.Equal(packet, []byte("doom")) {
+		t.Errorf("unexpected datagram: %v %v %+v %q", id, frequency, decodedConfig, packet)
+	}
+	if _, _, _, _, ok := decodeDatagram(datagram[:headerLength-1]); ok {
+		t.Errorf("expected a short datagram to be ignored")
+	}
+}
+
+func TestSimRadio(t *testing.T) {
+	a, err := New(DefaultAddress, 1)
+	if err != nil {
+		t.Skipf("multicast is not available: %v", err)
+	}
+	defer a.Close()
+	b, err := New(DefaultAddress, 2)
+	if err != nil {
+		t.Skipf("multicast is not available: %v", err)
+	}
+	defer b.Close()
+	received := make(chan []byte, 4)
+	b.SetReceiveHandler(func(packet []byte, rssi int) {
+		received <- packet
+	})
+	ownPackets := make(chan []byte, 4)
+	a.SetReceiveHandler(func(packet []byte, rssi int) {
+		ownPackets <- packet
+	})
+
+	err = a.Send([]byte("hello"))
+	if err != nil {
+		t.Skipf("multicast is not available: %v", err)
+	}
+	select {
+	case packet := <-received:
+		if string(packet) != "hello" {
+			t.Errorf("expected hello, got %q", packet)
+		}
+	case <-time.After(time.Second):
+		t.Skip("multicast packets are not delivered on this network")
+	}
+	select {
+	case packet := <-ownPackets:
+		t.Errorf("expected a radio not to hear its own packet, got %q", packet)
+	default:
+	}
+
+	// Radios on another frequency do not hear each other.
+	err = b.SetFrequency(915.0)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	err = a.Send([]byte("missed"))
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	select {
+	case packet := <-received:
+		t.Errorf("expected the packet to be missed, got %q", packet)
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	err = a.Send(make([]byte, picodoomsdaymessenger.MaxPacketLength+1))
+	if err != ErrPacketTooLong {
+		t.Errorf("expected ErrPacketTooLong, got %v", err)
+	}
+}
+
+func TestDevicesOverSimRadio(t *testing.T) {
+	devices := make([]*picodoomsdaymessenger.Device, 2)
+	received := make([]chan []byte, 2)
+	for i := range devices {
+		device, err := picodoomsdaymessenger.NewDevice()
+		if err != nil {
+			t.Fatalf("The error should be nil but is %v", err)
+		}
+		radio, err := New(DefaultAddress, uint64(i+10))
+		if err != nil {
+			t.Skipf("multicast is not available: %v", err)
+		}
+		defer radio.Close()
+		packets := make(chan []byte, 4)
+		radio.SetReceiveHandler(func(packet []byte, rssi int) {
+			packets <- packet
+		})
+		err = device.SetRadio(radio)
+		if err != nil {
+			t.Fatalf("The error should be nil but is %v", err)
+		}
+		device.SendUsingRadio = radio.Send
+		devices[i], received[i] = device, packets
+	}
+
+	err := devices[0].SendPacket(nil, []byte("doom1\xccSim\xcchello"))
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	select {
+	case packet := <-received[1]:
+		err = devices[1].ReceiveFromRadio(packet)
+		if err != nil {
+			t.Fatalf("The error should be nil but is %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Skip("multicast packets are not delivered on this network")
+	}
+	if len(devices[1].Conversations) != 1 || devices[1].Conversations[0].Messages[0].Text != "hello" {
+		t.Errorf("expected the message to be received, got %+v", devices[1].Conversations)
+	}
+}
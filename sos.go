@@ -0,0 +1,159 @@
+package picodoomsdaymessenger
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// ErrInvalidSOS is returned when an SOS packet cannot be decoded.
+var ErrInvalidSOS = errors.New("invalid sos packet")
+
+// sosPrefix is the start of every SOS packet, ASCII for "sos!".
+var sosPrefix = []byte{0x73, 0x6F, 0x73, 0x21}
+
+// SOSInterval is the time between SOS packets while SOS mode is broadcasting over the radio.
+var SOSInterval = 30 * time.Second
+
+// SOSAlert is an SOS that has been received from another device.
+type SOSAlert struct {
+	Person   Person
+	Position Position
+	Received time.Time
+}
+
+// sosState is whether SOS mode is on, and the last SOS that was sent and received.
+type sosState struct {
+	Active bool
+	// Broadcast sends SOS packets whatever SOSBroadcast is set to in the Settings. It is set by Panic.
+	Broadcast bool
+	lastSent  time.Time
+	Alert     SOSAlert
+}
+
+// SOSToBytes creates an SOS packet with the identity of the sender, and their position if it is known.
+func SOSToBytes(person Person, p Position) (output []byte) {
+	seperatorByte := byte(0xcc)
+	output = append(output, sosPrefix...)
+	output = append(output, []byte(fmt.Sprint(person.ID))...)
+	output = append(output, seperatorByte)
+	output = append(output, []byte(person.Name)...)
+	output = append(output, seperatorByte)
+	if p.Valid() {
+		output = strconv.AppendFloat(output, p.Latitude, 'f', 5, 64)
+		output = append(output, seperatorByte)
+		output = strconv.AppendFloat(output, p.Longitude, 'f', 5, 64)
+	} else {
+		output = append(output, seperatorByte)
+	}
+	return output
+}
+
+// BytesToSOSAlert decodes an SOS packet.
+func BytesToSOSAlert(input []byte) (output SOSAlert, err error) {
+	if !bytes.HasPrefix(input, sosPrefix) {
+		return output, ErrInvalidSOS
+	}
+	fields := bytes.Split(input[len(sosPrefix):], []byte{0xcc})
+	if len(fields) != 4 {
+		return output, ErrInvalidSOS
+	}
+	output.Person.ID, err = strconv.Atoi(string(fields[0]))
+	if err != nil {
+		return output, ErrInvalidSOS
+	}
+	output.Person.Name, _ = truncateString(string(fields[1]), MaxNameLength)
+	if len(fields[2]) > 0 {
+		output.Position.Latitude, err = strconv.ParseFloat(string(fields[2]), 64)
+		if err != nil {
+			return output, ErrInvalidSOS
+		}
+		output.Position.Longitude, err = strconv.ParseFloat(string(fields[3]), 64)
+		if err != nil {
+			return output, ErrInvalidSOS
+		}
+		output.Position.Source = "sos"
+	}
+	return output, nil
+}
+
+// SetSOS turns SOS mode on or off. While it is on, the LEDs flash SOS in Morse code, and if SOSBroadcast is turned on in the Settings an SOS packet is sent every SOSInterval.
+func (d *Device) SetSOS(active bool) (err error) {
+	d.sos.Active = active
+	if !active {
+		d.sos.Broadcast = false
+		return d.ChangeLEDAnimationWithoutContinue(&LEDAnimationDefault)
+	}
+	err = d.ChangeLEDAnimationWithoutContinue(&LEDAnimationSOS)
+	if err != nil {
+		return err
+	}
+	// Send the first SOS straight away.
+	d.sos.lastSent = time.Time{}
+	err = d.tickSOS(time.Now())
+	if err != nil {
+		return err
+	}
+	return d.EmitGatewayEvent(GatewayEvent{Type: GatewayEventSOS, PersonID: d.SelfIdentity.ID, PersonName: d.SelfIdentity.Name})
+}
+
+// tickSOS broadcasts an SOS packet if SOS mode is broadcasting and the SOSInterval has passed since the last one.
+func (d *Device) tickSOS(now time.Time) (err error) {
+	if !d.sos.Active || !(d.Settings.SOSBroadcast || d.sos.Broadcast) || now.Sub(d.sos.lastSent) < SOSInterval {
+		return nil
+	}
+	d.sos.lastSent = now
+	position, _ := d.CurrentLocation()
+	return d.sendPacketWithPriority(nil, SOSToBytes(d.SelfIdentity, position), txPriorityUrgent, nil)
+}
+
+// receiveSOS shows an SOS from another device on the whole screen and plays the LED alarm.
+func (d *Device) receiveSOS(alert SOSAlert, now time.Time) (err error) {
+	alert.Received = now
+	d.sos.Alert = alert
+	err = d.heardGatewayNode(alert.Person, now)
+	if err != nil {
+		return err
+	}
+	err = d.EmitGatewayEvent(GatewayEvent{Type: GatewayEventSOS, PersonID: alert.Person.ID, PersonName: alert.Person.Name})
+	if err != nil {
+		return err
+	}
+	if d.State != &d.StateSOSAlert {
+		err = d.ChangeStateWithHistory(&d.StateSOSAlert)
+		if err != nil {
+			return err
+		}
+	}
+	return d.Notify(DeviceEventSOSReceived)
+}
+
+// processSOSAlertInputEvent dismisses the SOS alert with accept, and ignores the other navigation keys so that it is not dismissed by accident.
+func processSOSAlertInputEvent(d *Device, inputEvent InputEvent) (handled bool, err error) {
+	switch inputEvent {
+	case InputEventAccept:
+		return true, d.GoBackState()
+	case InputEventUp, InputEventDown, InputEventLeft, InputEventRight:
+		return true, nil
+	}
+	return false, nil
+}
+
+// drawSOSAlert fills the screen with the last SOS that was received, with where it came from.
+func drawSOSAlert(d *Device, img Canvas, layout Layout) (err error) {
+	palette := d.Palette()
+	alert := d.sos.Alert
+	drawFilledBox(img, 0, 0, img.Bounds().Dx(), img.Bounds().Dy(), palette.StatusAlert)
+	// Draw the text in the background color, so it shows up on the alert color.
+	palette.Text = palette.Background
+	palette.TitleBar = palette.StatusAlert
+	palette.TitleText = palette.Background
+	where := "Location unknown"
+	if alert.Position.Valid() {
+		where = d.MessageText(Message{Location: alert.Position, Text: fmt.Sprintf("at %.5f,%.5f", alert.Position.Latitude, alert.Position.Longitude)})
+	}
+	drawTextPage(img, layout, palette, "!! SOS !!", alert.Person.Name+"\n"+where+"\n"+alert.Received.Format("15:04:05"), 0)
+	return nil
+}
@@ -0,0 +1,109 @@
+package picodoomsdaymessenger
+
+import (
+	"image"
+	"testing"
+	"time"
+)
+
+func TestSOSBroadcast(t *testing.T) {
+	sender, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	receiver, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	sent := 0
+	sender.SendUsingRadio = func(packet []byte) (err error) {
+		sent++
+		return receiver.ReceiveFromRadio(packet)
+	}
+
+	// Without SOSBroadcast, SOS mode only uses the LEDs.
+	err = ToolsMenuItemSOS.Action(sender)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if sender.LEDAnimation != &LEDAnimationSOS || sent != 0 {
+		t.Fatalf("expected only the LEDs to show SOS, sent %d packets", sent)
+	}
+
+	sender.SetPosition(51.5, -0.1, SourceSerial)
+	err = ToolsMenuItemSOSBroadcast.Action(sender)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	err = sendQueued(sender)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if sent != 1 {
+		t.Fatalf("expected an SOS to be sent straight away, sent %d", sent)
+	}
+	if receiver.State != &receiver.StateSOSAlert {
+		t.Errorf("expected the receiver to show the SOS alert")
+	}
+	if receiver.LEDAnimation != LEDNotifications[DeviceEventSOSReceived] {
+		t.Errorf("expected the receiver to play the SOS alarm")
+	}
+	alert := receiver.sos.Alert
+	if alert.Person.ID != sender.SelfIdentity.ID || !alert.Position.Valid() || alert.Position.Latitude != 51.5 {
+		t.Errorf("expected the identity and position of the sender, got %+v", alert)
+	}
+	if _, err := GetFrame(image.Rect(0, 0, 128, 64), receiver); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+
+	// The SOS is repeated every SOSInterval.
+	now := time.Now()
+	err = sender.Tick(now)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if sent != 1 {
+		t.Errorf("expected no SOS before the SOSInterval, sent %d", sent)
+	}
+	err = sender.Tick(now.Add(SOSInterval))
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if sent != 2 {
+		t.Errorf("expected the SOS to be repeated, sent %d", sent)
+	}
+
+	// Turning SOS mode off stops the broadcast.
+	err = ToolsMenuItemSOS.Action(sender)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	err = sender.Tick(now.Add(3 * SOSInterval))
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if sent != 2 {
+		t.Errorf("expected no SOS after SOS mode is turned off, sent %d", sent)
+	}
+
+	err = receiver.ProcessInputEvent(InputEventAccept)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if receiver.State == &receiver.StateSOSAlert {
+		t.Errorf("expected accept to dismiss the alert")
+	}
+}
+
+func TestBytesToSOSAlertWithoutPosition(t *testing.T) {
+	alert, err := BytesToSOSAlert(SOSToBytes(Person{"Test", 42}, Position{}))
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if alert.Person.ID != 42 || alert.Person.Name != "Test" || alert.Position.Valid() {
+		t.Errorf("unexpected alert %+v", alert)
+	}
+	if _, err := BytesToSOSAlert([]byte("sos!bad")); err != ErrInvalidSOS {
+		t.Errorf("expected ErrInvalidSOS, got %v", err)
+	}
+}
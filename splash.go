@@ -0,0 +1,74 @@
+package picodoomsdaymessenger
+
+import (
+	"strconv"
+	"time"
+)
+
+// SplashDuration is how long the splash screen is shown for when the Device starts.
+var SplashDuration = 2 * time.Second
+
+// splashLogo is the 16x16 logo drawn on the splash screen, a radio mast sending out waves.
+var splashLogo = []string{
+	"................",
+	"..#..........#..",
+	".#..#......#..#.",
+	"#..#...##...#..#",
+	"#..#..#..#..#..#",
+	"#..#..#..#..#..#",
+	".#..#..##..#..#.",
+	"..#....##....#..",
+	".......##.......",
+	"......#..#......",
+	"......#..#......",
+	".....#....#.....",
+	".....#.##.#.....",
+	"....#.#..#.#....",
+	"....##....##....",
+	"...#........#...",
+}
+
+// tickSplash goes on to the main menu once the splash screen has been shown for the SplashDuration.
+func (d *Device) tickSplash(now time.Time) (err error) {
+	if d.splashStart.IsZero() {
+		d.splashStart = now
+	}
+	if now.Sub(d.splashStart) < SplashDuration {
+		return nil
+	}
+	return d.ChangeStateWithoutHistory(&d.StateMainMenu)
+}
+
+// processSplashInputEvent skips the splash screen. The InputEvent is passed on to the main menu, so that no button press is lost.
+func processSplashInputEvent(d *Device, inputEvent InputEvent) (handled bool, err error) {
+	return false, d.ChangeStateWithoutHistory(&d.StateMainMenu)
+}
+
+// drawSplash draws the logo, with the name, firmware version and ID of the Device below it. Short screens only show the version and ID.
+func drawSplash(d *Device, img Canvas, layout Layout) (err error) {
+	palette := d.Palette()
+	dimensions := img.Bounds()
+	lines := []string{"Doomsday Messenger", d.Version, "ID " + strconv.Itoa(d.SelfIdentity.ID)}
+	if d.Version == "" {
+		lines[1] = "unknown version"
+	}
+	top := 0
+	if layout.Compact {
+		// There is only room for the version and ID on a short screen.
+		lines = lines[1:]
+	} else {
+		top = len(splashLogo)
+		left := (dimensions.Dx() - len(splashLogo[0])) / 2
+		for y, row := range splashLogo {
+			for x, pixel := range row {
+				if pixel == '#' {
+					img.SetRGBA(left+x, y, palette.Cursor)
+				}
+			}
+		}
+	}
+	for i, line := range lines {
+		drawTextFace(img, layout.Face, (dimensions.Dx()-textWidth(layout.Face, line))/2, top+(i+1)*layout.LineHeight, line, palette.Text)
+	}
+	return nil
+}
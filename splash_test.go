@@ -0,0 +1,65 @@
+package picodoomsdaymessenger
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSplash(t *testing.T) {
+	// Create a new Machine
+	device, err := NewDevice()
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	device.Version = "v1.0.0"
+	frame, err := GetFrame(DisplaySSD1306.Bounds(), device)
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	lit := 0
+	for y := 0; y < len(splashLogo); y++ {
+		for x := 0; x < 128; x++ {
+			if r, _, _, _ := frame.At(x, y).RGBA(); r != 0 {
+				lit++
+			}
+		}
+	}
+	if lit == 0 {
+		t.Errorf("The logo should be drawn at the top of the splash screen")
+	}
+	now := time.Now()
+	if err = device.Tick(now); err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	if device.State != &device.StateSplash {
+		t.Errorf("The splash screen should be shown when the Device starts, but the state is %v", device.State.Title)
+	}
+	if err = device.Tick(now.Add(SplashDuration)); err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	if device.State != &device.StateMainMenu {
+		t.Errorf("The main menu should be shown after the splash screen, but the state is %v", device.State.Title)
+	}
+	if len(device.StateHistory) != 1 {
+		t.Errorf("The splash screen should not be kept in the history, but the history has %d states", len(device.StateHistory))
+	}
+}
+
+func TestSplashSkipped(t *testing.T) {
+	// Create a new Machine
+	device, err := NewDevice()
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	err = device.ProcessInputEvent(InputEventDown)
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	defer func() { device.StateMainMenu.HighlightedItemIndex = 0 }()
+	if device.State != &device.StateMainMenu {
+		t.Errorf("A button press should skip the splash screen, but the state is %v", device.State.Title)
+	}
+	if device.StateMainMenu.HighlightedItemIndex != 1 {
+		t.Errorf("The button press should be passed on to the main menu")
+	}
+}
@@ -0,0 +1,82 @@
+package picodoomsdaymessenger
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// ErrStorageNotFound is returned by a Storage when nothing has been saved with a key.
+var ErrStorageNotFound = errors.New("not found in storage")
+
+// settingsStorageKey is the key that the Settings are saved with.
+const settingsStorageKey = "settings"
+
+// Storage keeps data when the Device is turned off, for example in flash memory.
+type Storage interface {
+	// Load returns the data saved with a key, or ErrStorageNotFound.
+	Load(key string) (data []byte, err error)
+	// Save replaces the data saved with a key.
+	Save(key string, data []byte) (err error)
+}
+
+// MemoryStorage is a Storage that only keeps data in memory, for tests and the simulator.
+type MemoryStorage map[string][]byte
+
+// Load returns a copy of the data saved with a key.
+func (m MemoryStorage) Load(key string) (data []byte, err error) {
+	saved, ok := m[key]
+	if !ok {
+		return nil, ErrStorageNotFound
+	}
+	return append([]byte{}, saved...), nil
+}
+
+// Save keeps a copy of the data with a key.
+func (m MemoryStorage) Save(key string, data []byte) (err error) {
+	m[key] = append([]byte{}, data...)
+	return nil
+}
+
+// SaveSettings writes the Settings to the Storage of the Device. It does nothing if the Device has no Storage.
+func (d *Device) SaveSettings() (err error) {
+	if d.Storage == nil {
+		return nil
+	}
+	data, err := json.Marshal(d.Settings)
+	if err != nil {
+		return err
+	}
+	return d.Storage.Save(settingsStorageKey, data)
+}
+
+// LoadSettings reads the Settings from the Storage of the Device. Settings that have never been saved are left as they are.
+func (d *Device) LoadSettings() (err error) {
+	if d.Storage == nil {
+		return nil
+	}
+	data, err := d.Storage.Load(settingsStorageKey)
+	if err == ErrStorageNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	settings := d.Settings
+	err = json.Unmarshal(data, &settings)
+	if err != nil {
+		return err
+	}
+	d.Settings = settings
+	return nil
+}
+
+// LoadState reads everything that the Device saves from its Storage, apart from the CrashLog.
+func (d *Device) LoadState() (err error) {
+	for _, load := range []func() error{d.LoadSettings, d.LoadIdentity, d.LoadKeys, d.LoadContacts, d.LoadNotes, d.LoadQuickReplies, d.LoadChannels, d.LoadBlocked, d.LoadNames, d.LoadReplayCounter, d.LoadReplaySeen} {
+		err = load()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package picodoomsdaymessenger
+
+import "testing"
+
+func TestSaveAndLoadSettings(t *testing.T) {
+	storage := MemoryStorage{}
+	device, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	device.Storage = storage
+	device.Settings.Gateway = true
+	err = device.SaveSettings()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+
+	restarted, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	restarted.Storage = storage
+	err = restarted.LoadSettings()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if !restarted.Settings.Gateway {
+		t.Error("expected the saved settings to be loaded")
+	}
+}
+
+func TestLoadSettingsWithNothingSaved(t *testing.T) {
+	device, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	defaults := device.Settings
+	device.Storage = MemoryStorage{}
+	err = device.LoadSettings()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if device.Settings != defaults {
+		t.Error("expected the default settings to be kept")
+	}
+}
@@ -0,0 +1,48 @@
+package picodoomsdaymessenger
+
+import (
+	"image/color"
+	"time"
+)
+
+// StrobeFlashDuration is how long the LEDs are lit for each flash of the strobe. Short flashes are easier to see at night than a steady light, and use less of the battery.
+const StrobeFlashDuration = 50 * time.Millisecond
+
+// StrobeIntervals is the list of times between the flashes of the strobe that can be chosen from the Tools menu.
+var StrobeIntervals = []time.Duration{2 * time.Second, time.Second, 500 * time.Millisecond, 250 * time.Millisecond}
+
+// NewStrobeLEDAnimation creates an LED animation that flashes all the LEDs in full white for StrobeFlashDuration, once every interval.
+func NewStrobeLEDAnimation(interval time.Duration) *LEDAnimation {
+	on := [6]color.RGBA{{255, 255, 255, 255}, {255, 255, 255, 255}, {255, 255, 255, 255}, {255, 255, 255, 255}, {255, 255, 255, 255}, {255, 255, 255, 255}}
+	off := [6]color.RGBA{}
+	frames := [][6]color.RGBA{on}
+	for i := StrobeFlashDuration; i < interval; i += StrobeFlashDuration {
+		frames = append(frames, off)
+	}
+	return &LEDAnimation{
+		FrameDuration: StrobeFlashDuration,
+		CurrentFrame:  0,
+		Frames:        frames,
+	}
+}
+
+// strobeMenuItems creates a MenuItem for every StrobeInterval, that toggles flashing the strobe at that rate on the RGB LEDs.
+func strobeMenuItems() (items []MenuItem) {
+	for _, interval := range StrobeIntervals {
+		animation := NewStrobeLEDAnimation(interval)
+		items = append(items, MenuItem{
+			Text: "Every " + interval.String(),
+			Action: func(d *Device) (err error) {
+				if d.LEDAnimation != animation {
+					return d.ChangeLEDAnimationWithoutContinue(animation)
+				}
+				return d.ChangeLEDAnimationWithoutContinue(&LEDAnimationDefault)
+			},
+			GetCursorData: func(d *Device) (data any, err error) {
+				return d.LEDAnimation == animation, nil
+			},
+			CursorIcon: CursorIconBox,
+		})
+	}
+	return items
+}
@@ -0,0 +1,44 @@
+package picodoomsdaymessenger
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewStrobeLEDAnimation(t *testing.T) {
+	animation := NewStrobeLEDAnimation(time.Second)
+	if len(animation.Frames) != 20 || animation.FrameDuration != StrobeFlashDuration {
+		t.Fatalf("expected 20 frames of %v, got %d of %v", StrobeFlashDuration, len(animation.Frames), animation.FrameDuration)
+	}
+	for i, frame := range animation.Frames {
+		lit := frame[0].R == 255
+		if lit != (i == 0) {
+			t.Errorf("expected only the first frame to be lit, frame %d is %v", i, frame)
+		}
+	}
+}
+
+func TestStrobeMenu(t *testing.T) {
+	device, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	err = ToolsMenuItemStrobe.Action(device)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	item := device.StateStrobeMenu.Content[2]
+	if err = item.Action(device); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if on, _ := item.GetCursorData(device); on != true || len(device.LEDAnimation.Frames) != 20 {
+		t.Errorf("expected the strobe to flash every second, got %d frames", len(device.LEDAnimation.Frames))
+	}
+	// Choosing it again turns the strobe off.
+	if err = item.Action(device); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if device.LEDAnimation != &LEDAnimationDefault {
+		t.Errorf("expected the strobe to stop")
+	}
+}
@@ -0,0 +1,80 @@
+package picodoomsdaymessenger
+
+// SymbolPickerEntries are the punctuation marks and pictograms that can be added to a message from the symbol picker.
+var SymbolPickerEntries = []string{
+	".", ",", "?", "!", "'", "\"",
+	"-", ":", ";", "(", ")", "@",
+	"#", "&", "*", "+", "=", "/",
+	"%", "$", "<", ">", "_", "~",
+	":)", ":(", ";)", ":D", ":P", "<3",
+	"o/", "^^",
+}
+
+// symbolPickerColumns is the number of entries in each row of the symbol picker.
+const symbolPickerColumns = 6
+
+// OpenSymbolPicker shows the symbol picker over the compose bar of the current Conversation. It is opened by pressing Star after the 123 ComposeMode.
+func (d *Device) OpenSymbolPicker() (err error) {
+	d.symbol = 0
+	return d.ChangeStateWithHistory(&d.StateSymbolPicker)
+}
+
+// processSymbolPickerInputEvent moves around the grid of symbols with the arrow keys, and adds the chosen symbol to the KeyboardBuffer with accept.
+// Left from the first column goes back without adding anything.
+func processSymbolPickerInputEvent(d *Device, inputEvent InputEvent) (handled bool, err error) {
+	count := len(SymbolPickerEntries)
+	switch inputEvent {
+	case InputEventUp:
+		d.symbol = (d.symbol - symbolPickerColumns + count) % count
+	case InputEventDown:
+		d.symbol = (d.symbol + symbolPickerColumns) % count
+	case InputEventLeft:
+		if d.symbol%symbolPickerColumns == 0 {
+			return true, d.GoBackState()
+		}
+		d.symbol--
+	case InputEventRight:
+		if d.symbol < count-1 {
+			d.symbol++
+		}
+	case InputEventAccept:
+		if d.CurrentConversationIndex < len(d.Conversations) {
+			d.Conversations[d.CurrentConversationIndex].KeyboardBuffer += SymbolPickerEntries[d.symbol]
+		}
+		return true, d.GoBackState()
+	default:
+		return false, nil
+	}
+	return true, nil
+}
+
+// drawSymbolPicker draws the symbols in a grid, scrolled so that the row with the chosen symbol can be seen.
+func drawSymbolPicker(d *Device, img Canvas, layout Layout) (err error) {
+	palette := d.Palette()
+	dimensions := img.Bounds()
+	drawTitleBar(img, layout, palette, "Symbols")
+	cell := dimensions.Dx() / symbolPickerColumns
+	visibleRows := (dimensions.Dy() - layout.TitleHeight) / layout.LineHeight
+	if visibleRows < 1 {
+		visibleRows = 1
+	}
+	firstRow := 0
+	if row := d.symbol / symbolPickerColumns; row >= visibleRows {
+		firstRow = row - visibleRows + 1
+	}
+	for i, entry := range SymbolPickerEntries {
+		row := i/symbolPickerColumns - firstRow
+		if row < 0 || row >= visibleRows {
+			continue
+		}
+		x := (i % symbolPickerColumns) * cell
+		y := layout.TitleHeight + row*layout.LineHeight
+		if i == d.symbol {
+			drawFilledBox(img, x, y, x+cell-1, y+layout.LineHeight-1, palette.Highlight)
+			drawBoxOutline(img, x, y, x+cell-1, y+layout.LineHeight-1, palette.Cursor)
+		}
+		left := x + (cell-textWidth(layout.Face, entry))/2
+		drawTextFace(img, layout.Face, left, y+layout.LineHeight-layout.Face.Descent-1, entry, palette.Text)
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package picodoomsdaymessenger
+
+import (
+	"image"
+	"testing"
+)
+
+func TestSymbolPicker(t *testing.T) {
+	device, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	c := device.NewConversation(Person{Name: "Bob", ID: 7})
+	device.CurrentConversationIndex = 0
+	err = device.ChangeStateWithHistory(&device.StateConversationReader)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	device.clearPendingCharacter()
+	c.KeyboardBuffer = "hi"
+	device.ComposeMode = ComposeModeNumbers
+	for _, inputEvent := range []InputEvent{InputEventStar, InputEventDown, InputEventDown, InputEventDown, InputEventDown, InputEventRight, InputEventRight} {
+		if err = device.ProcessInputEvent(inputEvent); err != nil {
+			t.Fatalf("The error should be nil but is %v", err)
+		}
+	}
+	if device.State != &device.StateSymbolPicker {
+		t.Fatalf("expected star after 123 to open the symbol picker, got %q", device.State.Title)
+	}
+	// The picker scrolls to keep the chosen row on the screen.
+	if _, err := GetFrame(image.Rect(0, 0, 128, 64), device); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if err = device.ProcessInputEvent(InputEventAccept); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if device.State != &device.StateConversationReader || c.KeyboardBuffer != "hi;)" {
+		t.Errorf("expected %q to be added to the compose bar, got %q in %q", "hi;)", c.KeyboardBuffer, device.State.Title)
+	}
+	if device.ComposeMode != ComposeModeLower {
+		t.Errorf("expected to be typing in abc again, got %v", device.ComposeMode)
+	}
+}
@@ -0,0 +1,229 @@
+package picodoomsdaymessenger
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"hash/crc32"
+)
+
+// Define sync errors
+var (
+	ErrSyncFrameTooLong   = errors.New("sync frame is too long")
+	ErrSyncFrameCorrupted = errors.New("sync frame checksum does not match")
+	ErrSyncUnknownFrame   = errors.New("unknown sync frame type")
+	ErrSyncMalformedFrame = errors.New("malformed sync frame")
+	ErrSyncNoHost         = errors.New("no SendToHost is defined")
+)
+
+// SyncFrameStart is the first byte of every sync frame. It is never the first byte of a serial command, so frames and commands can share a serial port.
+const SyncFrameStart = 0xAA
+
+// MaxSyncFrameLength is the longest payload that a sync frame can carry.
+const MaxSyncFrameLength = 1024
+
+// SyncOutboxSize is how many messages are kept for the host until it acknowledges them. The oldest are dropped first.
+var SyncOutboxSize = 64
+
+// SyncFrameType is the kind of a sync frame.
+type SyncFrameType byte
+
+const (
+	// SyncFrameHello is sent by the host when it connects. The Device starts streaming, and resends every message that has not been acknowledged.
+	SyncFrameHello SyncFrameType = 'H'
+	// SyncFrameMessage is sent by the Device with a SyncMessage, for every message that is received or sent.
+	SyncFrameMessage SyncFrameType = 'M'
+	// SyncFrameAck is sent by the host with a SyncAck, once it has stored the messages up to a sequence number.
+	SyncFrameAck SyncFrameType = 'A'
+	// SyncFrameSend is sent by the host with a SyncSend, to send a message from the Device.
+	SyncFrameSend SyncFrameType = 'S'
+	// SyncFrameResult is sent by the Device with a SyncResult, in reply to every SyncFrameSend.
+	SyncFrameResult SyncFrameType = 'R'
+	// SyncFrameBye is sent by the host when it disconnects. The Device stops streaming, but keeps messages for the next SyncFrameHello.
+	SyncFrameBye SyncFrameType = 'B'
+)
+
+// SyncMessage is a message streamed to the host.
+type SyncMessage struct {
+	Sequence     int    `json:"seq"`
+	Conversation int    `json:"conversation"`
+	PersonID     int    `json:"id"`
+	PersonName   string `json:"name"`
+	Text         string `json:"text"`
+	Time         int64  `json:"time"`
+	Own          bool   `json:"own,omitempty"`
+	Verified     bool   `json:"verified,omitempty"`
+}
+
+// SyncAck acknowledges every SyncMessage up to and including a sequence number.
+type SyncAck struct {
+	Sequence int `json:"seq"`
+}
+
+// SyncSend asks the Device to send text to a Conversation. Reference is chosen by the host, and returned in the SyncResult.
+type SyncSend struct {
+	Reference    int    `json:"ref"`
+	Conversation int    `json:"conversation"`
+	Text         string `json:"text"`
+}
+
+// SyncResult tells the host whether a SyncSend was sent. Error is empty if it was.
+type SyncResult struct {
+	Reference int    `json:"ref"`
+	Error     string `json:"error,omitempty"`
+}
+
+// syncState is the connection to the host.
+type syncState struct {
+	connected bool
+	sequence  int
+	outbox    []SyncMessage
+}
+
+// EncodeSyncFrame creates a sync frame. It is the SyncFrameStart byte, the type, the length of the payload as two bytes, the payload, and then the CRC-32 of everything after the start byte.
+func EncodeSyncFrame(frameType SyncFrameType, payload []byte) (frame []byte, err error) {
+	if len(payload) > MaxSyncFrameLength {
+		return nil, ErrSyncFrameTooLong
+	}
+	frame = make([]byte, 4, 4+len(payload)+4)
+	frame[0] = SyncFrameStart
+	frame[1] = byte(frameType)
+	binary.BigEndian.PutUint16(frame[2:], uint16(len(payload)))
+	frame = append(frame, payload...)
+	return binary.BigEndian.AppendUint32(frame, crc32.ChecksumIEEE(frame[1:])), nil
+}
+
+// SyncDecoder collects the bytes read from a serial port into sync frames.
+type SyncDecoder struct {
+	buffer []byte
+}
+
+// Active returns true if the SyncDecoder is part way through a frame, so the next byte belongs to it.
+func (s *SyncDecoder) Active() bool {
+	return len(s.buffer) > 0
+}
+
+// Decode adds a byte to the frame being decoded. Once the frame is complete, its type and payload are returned with done set to true.
+// Bytes before a SyncFrameStart are ignored. If the frame is corrupted, it is thrown away and an error is returned.
+func (s *SyncDecoder) Decode(b byte) (frameType SyncFrameType, payload []byte, done bool, err error) {
+	if len(s.buffer) == 0 && b != SyncFrameStart {
+		return 0, nil, false, nil
+	}
+	s.buffer = append(s.buffer, b)
+	if len(s.buffer) < 4 {
+		return 0, nil, false, nil
+	}
+	length := int(binary.BigEndian.Uint16(s.buffer[2:]))
+	if length > MaxSyncFrameLength {
+		s.buffer = s.buffer[:0]
+		return 0, nil, false, ErrSyncFrameTooLong
+	}
+	if len(s.buffer) < 4+length+4 {
+		return 0, nil, false, nil
+	}
+	frame := s.buffer
+	s.buffer = nil
+	if crc32.ChecksumIEEE(frame[1:4+length]) != binary.BigEndian.Uint32(frame[4+length:]) {
+		return 0, nil, false, ErrSyncFrameCorrupted
+	}
+	return SyncFrameType(frame[1]), frame[4 : 4+length], true, nil
+}
+
+// ProcessSyncFrame runs a sync frame that was received from the host.
+func (d *Device) ProcessSyncFrame(frameType SyncFrameType, payload []byte) (err error) {
+	d.MarkDirty()
+	switch frameType {
+	case SyncFrameHello:
+		d.sync.connected = true
+		for _, m := range d.sync.outbox {
+			err = d.sendSyncFrame(SyncFrameMessage, m)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	case SyncFrameBye:
+		d.sync.connected = false
+		return nil
+	case SyncFrameAck:
+		ack := SyncAck{}
+		err = json.Unmarshal(payload, &ack)
+		if err != nil {
+			return ErrSyncMalformedFrame
+		}
+		kept := d.sync.outbox[:0]
+		for _, m := range d.sync.outbox {
+			if m.Sequence > ack.Sequence {
+				kept = append(kept, m)
+			}
+		}
+		d.sync.outbox = kept
+		return nil
+	case SyncFrameSend:
+		send := SyncSend{}
+		err = json.Unmarshal(payload, &send)
+		if err != nil {
+			return ErrSyncMalformedFrame
+		}
+		result := SyncResult{Reference: send.Reference}
+		if send.Conversation < 0 || send.Conversation >= len(d.Conversations) {
+			result.Error = ErrNoSuchConversation.Error()
+		} else if err = d.SendMessage(d.Conversations[send.Conversation], send.Text); err != nil {
+			result.Error = err.Error()
+		}
+		return d.sendSyncFrame(SyncFrameResult, result)
+	}
+	return ErrSyncUnknownFrame
+}
+
+// syncMessage keeps a Message for the host, and streams it straight away if the host is connected.
+func (d *Device) syncMessage(conversation int, m Message) (err error) {
+	d.sync.sequence++
+	synced := SyncMessage{
+		Sequence:     d.sync.sequence,
+		Conversation: conversation,
+		PersonID:     m.Person.ID,
+		PersonName:   m.Person.Name,
+		Text:         m.Text,
+		Time:         m.TimeSent.Unix(),
+		Own:          m.Person == d.SelfIdentity,
+		Verified:     m.Verified,
+	}
+	if m.TimeSent.IsZero() {
+		synced.Time = d.Now().Unix()
+	}
+	d.sync.outbox = append(d.sync.outbox, synced)
+	if len(d.sync.outbox) > SyncOutboxSize {
+		d.sync.outbox = d.sync.outbox[len(d.sync.outbox)-SyncOutboxSize:]
+	}
+	if !d.sync.connected {
+		return nil
+	}
+	return d.sendSyncFrame(SyncFrameMessage, synced)
+}
+
+// sendSyncFrame encodes a value as JSON and sends it to the host in a sync frame.
+func (d *Device) sendSyncFrame(frameType SyncFrameType, value any) (err error) {
+	if d.SendToHost == nil {
+		return ErrSyncNoHost
+	}
+	payload, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	frame, err := EncodeSyncFrame(frameType, payload)
+	if err != nil {
+		return err
+	}
+	return d.SendToHost(frame)
+}
+
+// conversationIndex returns the index of a Conversation in the Device, or -1 if it is not one of them.
+func (d *Device) conversationIndex(c *Conversation) int {
+	for i := range d.Conversations {
+		if d.Conversations[i] == c {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,143 @@
+package picodoomsdaymessenger
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// syncHost collects the frames that a Device sends to its host.
+type syncHost struct {
+	decoder SyncDecoder
+	types   []SyncFrameType
+	frames  [][]byte
+}
+
+func (h *syncHost) write(frame []byte) (err error) {
+	for _, b := range frame {
+		frameType, payload, done, err := h.decoder.Decode(b)
+		if err != nil {
+			return err
+		}
+		if done {
+			h.types = append(h.types, frameType)
+			h.frames = append(h.frames, payload)
+		}
+	}
+	return nil
+}
+
+func TestSyncDecoder(t *testing.T) {
+	frame, err := EncodeSyncFrame(SyncFrameAck, []byte(`{"seq":3}`))
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	decoder := SyncDecoder{}
+	// Bytes before the start of a frame are ignored.
+	for _, b := range append([]byte("noise"), frame...) {
+		frameType, payload, done, err := decoder.Decode(b)
+		if err != nil {
+			t.Fatalf("The error should be nil but is %v", err)
+		}
+		if done && (frameType != SyncFrameAck || string(payload) != `{"seq":3}`) {
+			t.Errorf("unexpected frame %c %q", frameType, payload)
+		}
+	}
+	frame[5] ^= 0xff
+	for i, b := range frame {
+		_, _, done, err := decoder.Decode(b)
+		if i < len(frame)-1 && (err != nil || done) {
+			t.Fatalf("expected the frame to be incomplete")
+		}
+		if i == len(frame)-1 && err != ErrSyncFrameCorrupted {
+			t.Errorf("expected ErrSyncFrameCorrupted, got %v", err)
+		}
+	}
+	if decoder.Active() {
+		t.Errorf("expected the corrupted frame to be thrown away")
+	}
+	_, err = EncodeSyncFrame(SyncFrameMessage, make([]byte, MaxSyncFrameLength+1))
+	if err != ErrSyncFrameTooLong {
+		t.Errorf("expected ErrSyncFrameTooLong, got %v", err)
+	}
+}
+
+func TestSync(t *testing.T) {
+	device, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	var sent []byte
+	device.SendUsingRadio = func(packet []byte) (err error) {
+		sent = packet
+		return nil
+	}
+	host := &syncHost{}
+	device.SendToHost = host.write
+
+	// Messages are kept until the host connects.
+	err = device.ReceiveFromRadio([]byte("doom7\xccBob\xcchello"))
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if len(host.frames) != 0 {
+		t.Fatalf("expected nothing to be streamed before the host connects")
+	}
+	err = device.ProcessSyncFrame(SyncFrameHello, nil)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if len(host.frames) != 1 || host.types[0] != SyncFrameMessage {
+		t.Fatalf("expected the kept message to be streamed, got %q", host.frames)
+	}
+	message := SyncMessage{}
+	err = json.Unmarshal(host.frames[0], &message)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if message.Sequence != 1 || message.PersonID != 7 || message.Text != "hello" || message.Own {
+		t.Errorf("unexpected message: %+v", message)
+	}
+
+	err = device.ProcessSyncFrame(SyncFrameSend, []byte(`{"ref":5,"conversation":0,"text":"hi bob"}`))
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	err = sendQueued(device)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if decoded, err := device.BytesToMessage(sent); err != nil || decoded.Text != "hi bob" {
+		t.Errorf("expected the message to be sent, got %+v %v", decoded, err)
+	}
+	if len(host.frames) != 3 || host.types[1] != SyncFrameMessage || host.types[2] != SyncFrameResult {
+		t.Fatalf("expected the sent message to be streamed and then the result, got %c", host.types)
+	}
+	if string(host.frames[2]) != `{"ref":5}` {
+		t.Errorf("expected the send to succeed, got %s", host.frames[2])
+	}
+	err = device.ProcessSyncFrame(SyncFrameSend, []byte(`{"ref":6,"conversation":9,"text":"lost"}`))
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if string(host.frames[3]) != `{"ref":6,"error":"no such conversation"}` {
+		t.Errorf("expected the send to fail, got %s", host.frames[3])
+	}
+
+	// Acknowledged messages are not sent again when the host reconnects.
+	err = device.ProcessSyncFrame(SyncFrameAck, []byte(`{"seq":1}`))
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	host.frames, host.types = nil, nil
+	err = device.ProcessSyncFrame(SyncFrameHello, nil)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if len(host.frames) != 1 {
+		t.Errorf("expected only the unacknowledged message to be resent, got %q", host.frames)
+	}
+	err = device.ProcessSyncFrame('?', nil)
+	if err != ErrSyncUnknownFrame {
+		t.Errorf("expected ErrSyncUnknownFrame, got %v", err)
+	}
+}
@@ -0,0 +1,120 @@
+package picodoomsdaymessenger
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// characterWidth is the width in pixels of a character in the 7x13 font.
+const characterWidth = 7
+
+// wrapText splits text into lines of at most width characters, breaking between words where it can.
+// Newlines in the text always start a new line, and words that are longer than a line are split. Characters are counted as runes, not bytes.
+func wrapText(text string, width int) (lines []string) {
+	if width < 1 {
+		width = 1
+	}
+	for _, paragraph := range strings.Split(text, "\n") {
+		line := []rune{}
+		for _, field := range strings.Fields(paragraph) {
+			word := []rune(field)
+			for len(word) > width {
+				if len(line) > 0 {
+					lines = append(lines, string(line))
+					line = []rune{}
+				}
+				lines = append(lines, string(word[:width]))
+				word = word[width:]
+			}
+			if len(line) == 0 {
+				line = word
+			} else if len(line)+1+len(word) <= width {
+				line = append(append(line, ' '), word...)
+			} else {
+				lines = append(lines, string(line))
+				line = word
+			}
+		}
+		lines = append(lines, string(line))
+	}
+	return lines
+}
+
+// drawTitleBar draws the title of a screen at the top of it.
+func drawTitleBar(img Canvas, layout Layout, palette Palette, title string) {
+	drawScrollingTitleBar(img, layout, palette, title, 0)
+}
+
+// drawScrollingTitleBar draws the title bar with the first scroll characters of the title left out, so that the end of a long title can be shown.
+func drawScrollingTitleBar(img Canvas, layout Layout, palette Palette, title string, scroll int) {
+	drawFilledBox(img, 0, 0, img.Bounds().Dx(), layout.TitleHeight, palette.TitleBar)
+	drawTextFace(img, layout.Face, 0, layout.TitleBaseline, marqueeText(title, scroll), palette.TitleText)
+	drawHLineCol(img, 0, layout.TitleHeight-1, img.Bounds().Dx(), palette.TitleText)
+}
+
+// drawTextPage draws text below a title, word wrapped to the width of the screen and starting from the line at scroll.
+func drawTextPage(img Canvas, layout Layout, palette Palette, title string, text string, scroll int) {
+	lines := wrapText(text, layout.Columns)
+	for i := scroll; i < len(lines); i++ {
+		y := layout.TitleHeight + (i-scroll+1)*layout.LineHeight
+		if y > img.Bounds().Dy() {
+			break
+		}
+		drawTextFace(img, layout.Face, 0, y, lines[i], palette.Text)
+	}
+	drawTitleBar(img, layout, palette, title)
+}
+
+// scrollTextPage moves the first visible line of a text page up or down by one, without going past either end of the text.
+func scrollTextPage(scroll *int, inputEvent InputEvent, text string, layout Layout) (handled bool) {
+	switch inputEvent {
+	case InputEventUp:
+		if *scroll > 0 {
+			*scroll--
+		}
+		return true
+	case InputEventDown:
+		if *scroll < len(wrapText(text, layout.Columns))-1 {
+			*scroll++
+		}
+		return true
+	}
+	return false
+}
+
+// processTextEntryInputEvent types into a buffer with the number keys of the multi-tap keyboard, changes the ComposeMode with star and deletes with left.
+// Up and down are used up, as there is nothing to move between while typing. It returns false for any other InputEvent.
+func (d *Device) processTextEntryInputEvent(buffer *string, inputEvent InputEvent) (handled bool) {
+	switch inputEvent {
+	case InputEventNumber1, InputEventNumber2, InputEventNumber3, InputEventNumber4, InputEventNumber5, InputEventNumber6, InputEventNumber7, InputEventNumber8, InputEventNumber9, InputEventNumber0:
+		d.typeNumberKey(buffer, inputEvent)
+	case InputEventStar:
+		d.CycleComposeMode(buffer)
+	case InputEventLeft:
+		if d.pendingCharacter() != "" {
+			d.clearPendingCharacter()
+		} else if *buffer != "" {
+			_, size := utf8.DecodeLastRuneInString(*buffer)
+			*buffer = (*buffer)[:len(*buffer)-size]
+		}
+	case InputEventUp, InputEventDown:
+	default:
+		return false
+	}
+	return true
+}
+
+// finishTextEntry returns the typed text, including the character that is still being typed, and empties the buffer.
+func (d *Device) finishTextEntry(buffer *string) (text string) {
+	text = *buffer + d.pendingCharacter()
+	*buffer = ""
+	d.clearPendingCharacter()
+	return text
+}
+
+// clearPendingCharacter stops typing with the current key, without adding its character to anything.
+func (d *Device) clearPendingCharacter() {
+	d.CurrentKeyboardButton = &KeyboardButton{Characters: []string{""}, CurrentCharacterIndex: 0}
+	d.pressedButton = nil
+	d.pendingBuffer = nil
+}
@@ -0,0 +1,43 @@
+package picodoomsdaymessenger
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWrapText(t *testing.T) {
+	tests := []struct {
+		text     string
+		width    int
+		expected []string
+	}{
+		{"", 10, []string{""}},
+		{"hello world", 20, []string{"hello world"}},
+		{"hello world", 8, []string{"hello", "world"}},
+		{"one two three", 7, []string{"one two", "three"}},
+		{"abcdefghij", 4, []string{"abcd", "efgh", "ij"}},
+		{"first\nsecond", 20, []string{"first", "second"}},
+		{"café crème brûlée", 10, []string{"café crème", "brûlée"}},
+		{"żółćżółć", 5, []string{"żółćż", "ółć"}},
+	}
+	for _, test := range tests {
+		actual := wrapText(test.text, test.width)
+		if !reflect.DeepEqual(actual, test.expected) {
+			t.Errorf("wrapText(%q, %d): expected %q, got %q", test.text, test.width, test.expected, actual)
+		}
+	}
+}
+
+func TestTextEntryDeletesWholeCharacters(t *testing.T) {
+	// Create a new Machine
+	device, err := NewDevice()
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	device.clearPendingCharacter()
+	buffer := "Zoë"
+	device.processTextEntryInputEvent(&buffer, InputEventLeft)
+	if buffer != "Zo" {
+		t.Errorf("Deleting should remove the whole ë, but the buffer is %q", buffer)
+	}
+}
@@ -0,0 +1,264 @@
+package picodoomsdaymessenger
+
+import (
+	"fmt"
+	"image/color"
+	"strconv"
+	"strings"
+)
+
+// ticTacToeGameName is the name of tic-tac-toe in GamePackets.
+const ticTacToeGameName = "ttt"
+
+// ticTacToeLines is every row, column and diagonal of the board that wins the game.
+var ticTacToeLines = [8][3]int{{0, 1, 2}, {3, 4, 5}, {6, 7, 8}, {0, 3, 6}, {1, 4, 7}, {2, 5, 8}, {0, 4, 8}, {2, 4, 6}}
+
+// ticTacToeGame holds the state of a game of tic-tac-toe.
+// The Person who sends the invite plays X and goes first, and the Person who accepts it plays O.
+type ticTacToeGame struct {
+	Opponent Person
+	// Started is true once the invite has been accepted.
+	Started bool
+	Board   [9]string
+	Mark    string
+	Turn    string
+	Cursor  int
+	// Invites is the list of people who have asked to play.
+	Invites []Person
+}
+
+// opponentMark returns the mark of the opponent.
+func (g *ticTacToeGame) opponentMark() string {
+	if g.Mark == "X" {
+		return "O"
+	}
+	return "X"
+}
+
+// Winner returns the mark that has won the game, "draw" if the board is full, or "" if the game is still being played.
+func (g *ticTacToeGame) Winner() string {
+	for _, line := range ticTacToeLines {
+		if g.Board[line[0]] != "" && g.Board[line[0]] == g.Board[line[1]] && g.Board[line[1]] == g.Board[line[2]] {
+			return g.Board[line[0]]
+		}
+	}
+	for _, cell := range g.Board {
+		if cell == "" {
+			return ""
+		}
+	}
+	return "draw"
+}
+
+// sendTicTacToe sends a tic-tac-toe packet to a Person. The first field is always the ID of the Person that the packet is for.
+func (d *Device) sendTicTacToe(to Person, fields ...string) (err error) {
+	return d.SendGamePacket(ticTacToeGameName, []byte(strings.Join(append([]string{strconv.Itoa(to.ID)}, fields...), ",")))
+}
+
+// InviteTicTacToe asks a Person to play tic-tac-toe. The game starts when they accept.
+func (d *Device) InviteTicTacToe(p Person) (err error) {
+	d.ticTacToe = ticTacToeGame{Opponent: p, Mark: "X", Turn: "X", Cursor: 4, Invites: d.ticTacToe.Invites}
+	return d.sendTicTacToe(p, "invite")
+}
+
+// AcceptTicTacToe accepts an invite from a Person and starts the game.
+func (d *Device) AcceptTicTacToe(p Person) (err error) {
+	invites := []Person{}
+	for _, invite := range d.ticTacToe.Invites {
+		if invite.ID != p.ID {
+			invites = append(invites, invite)
+		}
+	}
+	d.ticTacToe = ticTacToeGame{Opponent: p, Started: true, Mark: "O", Turn: "X", Cursor: 4, Invites: invites}
+	return d.sendTicTacToe(p, "accept")
+}
+
+// PlayTicTacToe puts the mark of the Device in a cell and sends the move to the opponent. It does nothing if it is not the turn of the Device or the cell is taken.
+func (d *Device) PlayTicTacToe(cell int) (err error) {
+	g := &d.ticTacToe
+	if !g.Started || g.Turn != g.Mark || g.Winner() != "" || cell < 0 || cell > 8 || g.Board[cell] != "" {
+		return nil
+	}
+	g.Board[cell] = g.Mark
+	g.Turn = g.opponentMark()
+	return d.sendTicTacToe(g.Opponent, "move", strconv.Itoa(cell))
+}
+
+// receiveTicTacToe handles a tic-tac-toe packet from another device.
+func (d *Device) receiveTicTacToe(packet GamePacket) (err error) {
+	fields := strings.Split(string(packet.Data), ",")
+	if len(fields) < 2 {
+		return ErrInvalidGamePacket
+	}
+	to, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return ErrInvalidGamePacket
+	}
+	if to != d.SelfIdentity.ID {
+		return nil
+	}
+	from := d.personByID(packet.Person.ID)
+	g := &d.ticTacToe
+	switch fields[1] {
+	case "invite":
+		for _, invite := range g.Invites {
+			if invite.ID == from.ID {
+				return nil
+			}
+		}
+		g.Invites = append(g.Invites, from)
+		return d.Notify(DeviceEventMessageReceived)
+	case "accept":
+		if g.Opponent.ID == from.ID && !g.Started {
+			g.Started = true
+		}
+	case "move":
+		if len(fields) != 3 {
+			return ErrInvalidGamePacket
+		}
+		cell, err := strconv.Atoi(fields[2])
+		if err != nil || cell < 0 || cell > 8 {
+			return ErrInvalidGamePacket
+		}
+		if !g.Started || g.Opponent.ID != from.ID || g.Turn != g.opponentMark() || g.Board[cell] != "" {
+			return nil
+		}
+		g.Board[cell] = g.opponentMark()
+		g.Turn = g.Mark
+	default:
+		return ErrInvalidGamePacket
+	}
+	return nil
+}
+
+// personByID finds a Person that the Device has a Conversation with. If there is none, the Person is named after their ID.
+func (d *Device) personByID(id int) Person {
+	for _, c := range d.Conversations {
+		for _, p := range c.People {
+			if p.ID == id {
+				return p
+			}
+		}
+	}
+	return Person{Name: fmt.Sprint(id), ID: id}
+}
+
+// UpdateTicTacToeMenu lists the invites that can be accepted, then everyone that the Device has a Conversation with so that they can be invited.
+func (d *Device) UpdateTicTacToeMenu() {
+	d.StateTicTacToeMenu.Content = []MenuItem{GlobalMenuItemGoBack}
+	d.StateTicTacToeMenu.HighlightedItemIndex = 0
+	for _, invite := range d.ticTacToe.Invites {
+		p := invite
+		d.StateTicTacToeMenu.Content = append(d.StateTicTacToeMenu.Content, MenuItem{
+			Text: "Play " + p.Name,
+			Action: func(d *Device) (err error) {
+				err = d.AcceptTicTacToe(p)
+				if err != nil {
+					return err
+				}
+				return d.ChangeStateWithHistory(&d.StateTicTacToe)
+			},
+			CursorIcon: CursorIconRightArrow,
+		})
+	}
+	invited := map[int]bool{d.SelfIdentity.ID: true}
+	for _, c := range d.Conversations {
+		for _, person := range c.People {
+			if invited[person.ID] {
+				continue
+			}
+			invited[person.ID] = true
+			p := person
+			d.StateTicTacToeMenu.Content = append(d.StateTicTacToeMenu.Content, MenuItem{
+				Text: "Invite " + p.Name,
+				Action: func(d *Device) (err error) {
+					err = d.InviteTicTacToe(p)
+					if err != nil {
+						return err
+					}
+					return d.ChangeStateWithHistory(&d.StateTicTacToe)
+				},
+				CursorIcon: CursorIconRightArrow,
+			})
+		}
+	}
+}
+
+// processTicTacToeInputEvent moves the cursor with the arrows and plays with accept or a number key. Once the game is over, accept leaves it.
+func processTicTacToeInputEvent(d *Device, inputEvent InputEvent) (handled bool, err error) {
+	g := &d.ticTacToe
+	switch inputEvent {
+	case InputEventUp:
+		g.Cursor = (g.Cursor + 6) % 9
+	case InputEventDown:
+		g.Cursor = (g.Cursor + 3) % 9
+	case InputEventLeft:
+		g.Cursor = g.Cursor/3*3 + (g.Cursor+2)%3
+	case InputEventRight:
+		g.Cursor = g.Cursor/3*3 + (g.Cursor+1)%3
+	case InputEventAccept:
+		if !g.Started || g.Winner() != "" {
+			return true, d.GoBackState()
+		}
+		return true, d.PlayTicTacToe(g.Cursor)
+	case InputEventNumber1, InputEventNumber2, InputEventNumber3, InputEventNumber4, InputEventNumber5, InputEventNumber6, InputEventNumber7, InputEventNumber8, InputEventNumber9:
+		g.Cursor = int(inputEvent[len(inputEvent)-1]-'0') - 1
+		return true, d.PlayTicTacToe(g.Cursor)
+	default:
+		return false, nil
+	}
+	return true, nil
+}
+
+// drawTicTacToe draws the board, with what is happening in the title.
+func drawTicTacToe(d *Device, img Canvas, layout Layout) (err error) {
+	palette := d.Palette()
+	dimensions := img.Bounds()
+	g := &d.ticTacToe
+
+	title := "Waiting for " + g.Opponent.Name
+	if g.Started {
+		switch g.Winner() {
+		case "":
+			if g.Turn == g.Mark {
+				title = "Your turn (" + g.Mark + ")"
+			} else {
+				title = "Their turn"
+			}
+		case "draw":
+			title = "Draw"
+		case g.Mark:
+			title = "You win"
+		default:
+			title = "You lose"
+		}
+	}
+	drawTitleBar(img, layout, palette, title)
+
+	// Fit the board in the space below the title, in the middle of the screen.
+	cell := (dimensions.Dy() - layout.TitleHeight - 1) / 3
+	left := (dimensions.Dx() - cell*3) / 2
+	top := layout.TitleHeight + 1
+	for i := 1; i < 3; i++ {
+		drawVLineCol(img, top, left+i*cell, top+cell*3-1, palette.Text)
+		drawHLineCol(img, left, top+i*cell, left+cell*3-1, palette.Text)
+	}
+	for i, mark := range g.Board {
+		x, y := left+(i%3)*cell, top+(i/3)*cell
+		if mark != "" {
+			drawTextCol(img, x+(cell-7)/2, y+(cell+9)/2, mark, palette.Text)
+		}
+		if i == g.Cursor && g.Started && g.Winner() == "" {
+			drawBoxOutline(img, x+2, y+2, x+cell-2, y+cell-2, palette.Cursor)
+		}
+	}
+	return nil
+}
+
+// drawBoxOutline draws the outline of a box from one X and Y location to another.
+func drawBoxOutline(img Canvas, x1 int, y1 int, x2 int, y2 int, col color.RGBA) {
+	drawHLineCol(img, x1, y1, x2, col)
+	drawHLineCol(img, x1, y2, x2, col)
+	drawVLineCol(img, y1, x1, y2, col)
+	drawVLineCol(img, y1, x2, y2, col)
+}
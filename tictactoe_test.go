@@ -0,0 +1,101 @@
+package picodoomsdaymessenger
+
+import (
+	"testing"
+)
+
+func TestTicTacToeOverRadio(t *testing.T) {
+	// Create two Machines that can hear each other and know each other.
+	alice, err := NewDevice()
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	alice.SelfIdentity = Person{Name: "Alice", ID: 1}
+	bob, err := NewDevice()
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	bob.SelfIdentity = Person{Name: "Bob", ID: 2}
+	alice.SendUsingRadio = func(packet []byte) (err error) {
+		return bob.ReceiveFromRadio(packet)
+	}
+	bob.SendUsingRadio = func(packet []byte) (err error) {
+		return alice.ReceiveFromRadio(packet)
+	}
+	alice.NewConversation(bob.SelfIdentity)
+	bob.NewConversation(alice.SelfIdentity)
+
+	alice.UpdateTicTacToeMenu()
+	if len(alice.StateTicTacToeMenu.Content) != 2 || alice.StateTicTacToeMenu.Content[1].Text != "Invite Bob" {
+		t.Errorf("The menu should offer to invite Bob but is %v", alice.StateTicTacToeMenu.Content)
+	}
+	err = alice.StateTicTacToeMenu.Content[1].Action(alice)
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	err = sendQueued(alice)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if len(bob.ticTacToe.Invites) != 1 || bob.ticTacToe.Invites[0].Name != "Alice" {
+		t.Errorf("Bob should have an invite from Alice but has %v", bob.ticTacToe.Invites)
+	}
+
+	bob.UpdateTicTacToeMenu()
+	if bob.StateTicTacToeMenu.Content[1].Text != "Play Alice" {
+		t.Errorf("The menu should offer to play Alice but is %v", bob.StateTicTacToeMenu.Content[1].Text)
+	}
+	err = bob.StateTicTacToeMenu.Content[1].Action(bob)
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+	err = sendQueued(bob)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if !alice.ticTacToe.Started || !bob.ticTacToe.Started {
+		t.Errorf("The game should have started")
+	}
+
+	// Bob cannot go first.
+	bob.ProcessInputEvent(InputEventNumber1)
+	if bob.ticTacToe.Board[0] != "" {
+		t.Errorf("Bob should not be able to play before Alice")
+	}
+	// Alice wins down the left column.
+	for i, move := range []InputEvent{InputEventNumber1, InputEventNumber2, InputEventNumber4, InputEventNumber5, InputEventNumber7} {
+		player := alice
+		if i%2 == 1 {
+			player = bob
+		}
+		err = player.ProcessInputEvent(move)
+		if err != nil {
+			t.Errorf("The error should be nil but is %v", err)
+		}
+		err = sendQueued(player)
+		if err != nil {
+			t.Errorf("The error should be nil but is %v", err)
+		}
+	}
+	if alice.ticTacToe.Board != bob.ticTacToe.Board {
+		t.Errorf("Both boards should be the same, have: %v and %v", alice.ticTacToe.Board, bob.ticTacToe.Board)
+	}
+	if alice.ticTacToe.Winner() != "X" || bob.ticTacToe.Winner() != "X" {
+		t.Errorf("X should have won but the winner is %v", alice.ticTacToe.Winner())
+	}
+	_, err = GetFrame(DisplaySSD1306.Bounds(), bob)
+	if err != nil {
+		t.Errorf("The error should be nil but is %v", err)
+	}
+}
+
+func TestTicTacToeWinner(t *testing.T) {
+	g := ticTacToeGame{Board: [9]string{"X", "O", "X", "X", "O", "O", "O", "X", "X"}}
+	if g.Winner() != "draw" {
+		t.Errorf("The game should be a draw but the winner is %v", g.Winner())
+	}
+	g.Board[8] = ""
+	if g.Winner() != "" {
+		t.Errorf("The game should not be over but the winner is %v", g.Winner())
+	}
+}
@@ -0,0 +1,96 @@
+package picodoomsdaymessenger
+
+import "time"
+
+// Tone is a note played on the buzzer. A Tone with a FrequencyHz of zero is a rest, where nothing is played.
+type Tone struct {
+	FrequencyHz int
+	Duration    time.Duration
+}
+
+// MorseToneHz is the pitch that Morse code is played at.
+const MorseToneHz = 700
+
+// DeviceEventError happens when the firmware shows an error. It only plays a Tone.
+const DeviceEventError DeviceEvent = "error"
+
+// ToneNotifications maps each DeviceEvent to the Tones that are played when it happens, if alert tones are turned on in the Settings.
+// Events that are not in the map are silent.
+var ToneNotifications = map[DeviceEvent][]Tone{
+	DeviceEventMessageReceived: {{2000, 60 * time.Millisecond}, {0, 40 * time.Millisecond}, {2600, 60 * time.Millisecond}},
+	DeviceEventSendFailed:      {{400, 150 * time.Millisecond}, {0, 50 * time.Millisecond}, {300, 250 * time.Millisecond}},
+	DeviceEventLowBattery:      {{1000, 100 * time.Millisecond}, {0, 100 * time.Millisecond}, {800, 100 * time.Millisecond}},
+	DeviceEventSOSReceived:     {{2500, 200 * time.Millisecond}, {1800, 200 * time.Millisecond}, {2500, 200 * time.Millisecond}, {1800, 200 * time.Millisecond}},
+	DeviceEventError:           {{250, 400 * time.Millisecond}},
+}
+
+// tonePlayer holds the Tones that are being played, and when the next one starts.
+type tonePlayer struct {
+	tones []Tone
+	next  int
+	at    time.Time
+}
+
+// PlayTones plays a sequence of Tones on the buzzer, replacing any that are still playing. The first Tone starts straight away, and the rest are started by Tick.
+// It does nothing if the Device has no PlayTone hook.
+func (d *Device) PlayTones(tones []Tone) (err error) {
+	if d.PlayTone == nil {
+		return nil
+	}
+	d.tones = tonePlayer{tones: tones}
+	return d.tickTones(d.clock())
+}
+
+// stopTones stops any Tones that have not been played yet. The Tone that is playing finishes.
+func (d *Device) stopTones() {
+	d.tones = tonePlayer{}
+}
+
+// tickTones starts the next Tone once the one before it has finished.
+func (d *Device) tickTones(now time.Time) (err error) {
+	if d.tones.next >= len(d.tones.tones) || now.Before(d.tones.at) {
+		return nil
+	}
+	tone := d.tones.tones[d.tones.next]
+	d.tones.next++
+	d.tones.at = now.Add(tone.Duration)
+	if tone.FrequencyHz <= 0 || d.PlayTone == nil {
+		return nil
+	}
+	return d.PlayTone(tone.FrequencyHz, tone.Duration)
+}
+
+// NewMorseTones converts text to the Tones that play it in Morse code, with the same timing as NewMorseLEDAnimation.
+func NewMorseTones(text string) (tones []Tone) {
+	animation := NewMorseLEDAnimation(text)
+	for _, frame := range animation.Frames {
+		frequency := 0
+		if frame[0].A != 0 {
+			frequency = MorseToneHz
+		}
+		if len(tones) > 0 && tones[len(tones)-1].FrequencyHz == frequency {
+			tones[len(tones)-1].Duration += animation.FrameDuration
+			continue
+		}
+		tones = append(tones, Tone{FrequencyHz: frequency, Duration: animation.FrameDuration})
+	}
+	return tones
+}
+
+// The items of the Sound menu turn each kind of Tone on or off.
+var (
+	// SoundMenuItemAlerts is a MenuItem that toggles the Tones played by notifications and errors.
+	SoundMenuItemAlerts = MenuItem{
+		Text: "Alert Tones",
+		Widget: SettingToggle(func(d *Device) *bool {
+			return &d.Settings.AlertTones
+		}),
+	}
+	// SoundMenuItemMorse is a MenuItem that toggles playing the Morse light messages on the buzzer as well as the LEDs.
+	SoundMenuItemMorse = MenuItem{
+		Text: "Morse Tones",
+		Widget: SettingToggle(func(d *Device) *bool {
+			return &d.Settings.MorseTones
+		}),
+	}
+)
@@ -0,0 +1,67 @@
+package picodoomsdaymessenger
+
+import (
+	"testing"
+	"time"
+)
+
+func TestToneNotifications(t *testing.T) {
+	device, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	played := []int{}
+	device.PlayTone = func(frequencyHz int, duration time.Duration) (err error) {
+		played = append(played, frequencyHz)
+		return nil
+	}
+
+	err = device.Notify(DeviceEventMessageReceived)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if len(played) != 1 {
+		t.Fatalf("expected the first tone to play straight away, got %v", played)
+	}
+	// The rest is played by Tick, one tone after another, skipping the rest between them.
+	now := time.Now()
+	for i := 1; i <= 4; i++ {
+		err = device.Tick(now.Add(time.Duration(i) * 100 * time.Millisecond))
+		if err != nil {
+			t.Fatalf("The error should be nil but is %v", err)
+		}
+	}
+	if len(played) != 2 || played[0] != 2000 || played[1] != 2600 {
+		t.Errorf("expected the message chirp, got %v", played)
+	}
+
+	played = played[:0]
+	device.Settings.AlertTones = false
+	err = device.Notify(DeviceEventSendFailed)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if len(played) != 0 {
+		t.Errorf("expected no tones with alert tones off, got %v", played)
+	}
+}
+
+func TestNewMorseTones(t *testing.T) {
+	tones := NewMorseTones("et")
+	// A dot, a letter gap, a dash, and a word gap.
+	want := []Tone{
+		{MorseToneHz, MorseFrameDuration},
+		{0, 3 * MorseFrameDuration},
+		{MorseToneHz, 3 * MorseFrameDuration},
+		{0, 7 * MorseFrameDuration},
+	}
+	if len(tones) != len(want) {
+		t.Fatalf("expected %v, got %v", want, tones)
+	}
+	for i := range want {
+		if tones[i] != want[i] {
+			t.Errorf("expected %v, got %v", want, tones)
+			break
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package picodoomsdaymessenger
+
+import "fmt"
+
+// MinTxPower is the least power in dBm that the radio can send with.
+const MinTxPower = 2
+
+// TxPower returns the power in dBm that the radio sends with, which is the TxPower in the Settings kept within what the radio and the Region allow.
+// If it has not been set, the most that the Region allows is used.
+func (d *Device) TxPower() int {
+	power := d.Settings.TxPower
+	if power == 0 || power > d.MaxTxPower() {
+		power = d.MaxTxPower()
+	}
+	if power < MinTxPower {
+		power = MinTxPower
+	}
+	return power
+}
+
+// SetTxPower stores a new transmit power in the Settings and applies it to the Radio, as far as the Region and the BatteryPolicy allow.
+func (d *Device) SetTxPower(dBm int) (err error) {
+	d.Settings.TxPower = dBm
+	d.Settings.TxPower = d.TxPower()
+	if d.Radio == nil {
+		return nil
+	}
+	return d.Radio.SetTxPower(d.radioTxPower())
+}
+
+// processTxPowerInputEvent turns the transmit power up one dBm with up or right, and down with down or left. It goes back with accept.
+// Each change is sent to the Radio straight away, so that its effect on range can be tried out.
+func processTxPowerInputEvent(d *Device, inputEvent InputEvent) (handled bool, err error) {
+	switch inputEvent {
+	case InputEventAccept:
+		return true, d.GoBackState()
+	case InputEventUp, InputEventRight:
+		return true, d.SetTxPower(d.TxPower() + 1)
+	case InputEventDown, InputEventLeft:
+		return true, d.SetTxPower(d.TxPower() - 1)
+	}
+	return false, nil
+}
+
+// drawTxPower draws the transmit power as a slider between MinTxPower and the most that the Region allows.
+func drawTxPower(d *Device, img Canvas, layout Layout) (err error) {
+	palette := d.Palette()
+	power, max := d.TxPower(), d.MaxTxPower()
+	text := fmt.Sprintf("%d dBm\nMax %d dBm", power, max)
+	if region, ok := d.Region(); ok {
+		text += " " + region.Name
+	}
+	drawTextPage(img, layout, palette, "TX Power", text, 0)
+	width := img.Bounds().Dx() - 1
+	middle := layout.TitleHeight + 2*layout.LineHeight + 6
+	knob := 2
+	if max > MinTxPower {
+		knob = 2 + (width-4)*(power-MinTxPower)/(max-MinTxPower)
+	}
+	drawHLineCol(img, 0, middle, width, palette.Text)
+	drawFilledBox(img, knob-2, middle-3, knob+3, middle+4, palette.Cursor)
+	return nil
+}
@@ -0,0 +1,128 @@
+package picodoomsdaymessenger
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrTxQueueFull is returned when a packet is sent while MaxTxQueue packets are already waiting.
+var ErrTxQueueFull = errors.New("too many packets waiting to be sent")
+
+// MaxTxQueue is the most packets that can wait to be sent.
+const MaxTxQueue = 16
+
+// txPriority is the order that queued packets are sent in, lowest first.
+type txPriority int
+
+const (
+	// txPriorityUrgent is for replies that another device is waiting for, and for emergencies.
+	txPriorityUrgent txPriority = iota
+	// txPriorityMessage is for messages, and every other packet.
+	txPriorityMessage
+	// txPriorityBeacon is for beacons, which are sent again later anyway.
+	txPriorityBeacon
+)
+
+// queuedPacket is a packet that is waiting to be sent.
+type queuedPacket struct {
+	packet   []byte
+	priority txPriority
+	// attempts is how many times the channel has been found busy when the packet was next.
+	attempts int
+	// done is called with the result of sending the packet, once it has been sent or given up on. It can be nil.
+	done func(err error)
+}
+
+// QueuedPackets returns how many packets are waiting to be sent.
+func (d *Device) QueuedPackets() int {
+	return len(d.txQueue)
+}
+
+// queuePacket puts a packet in the queue after every packet with the same or a higher priority. It returns straight away, and the packet is sent by Tick.
+// A packet that would use more airtime than the whole DutyCycle allows is rejected, as it could never be sent.
+func (d *Device) queuePacket(packet []byte, priority txPriority, done func(err error)) (err error) {
+	if d.DutyCycleWait(d.packetAirtime(len(packet)), time.Now()) < 0 {
+		return d.dropPacket(ErrDutyCycleExceeded)
+	}
+	if len(d.txQueue) >= MaxTxQueue {
+		return d.dropPacket(ErrTxQueueFull)
+	}
+	i := len(d.txQueue)
+	for i > 0 && d.txQueue[i-1].priority > priority {
+		i--
+	}
+	d.txQueue = append(d.txQueue, queuedPacket{})
+	copy(d.txQueue[i+1:], d.txQueue[i:])
+	d.txQueue[i] = queuedPacket{packet: packet, priority: priority, done: done}
+	return nil
+}
+
+// sendQueuedPackets sends the packets in the queue in order, until one has to wait for the DutyCycle or for the channel to be clear. It never waits itself, so it is called again on the next Tick.
+// A packet that finds the channel busy ListenAttempts times is given up on.
+func (d *Device) sendQueuedPackets(now time.Time) (err error) {
+	// The channel scanner has the Radio tuned to other frequencies.
+	if d.channelScan.active {
+		return nil
+	}
+	for len(d.txQueue) > 0 {
+		next := &d.txQueue[0]
+		airtime := d.packetAirtime(len(next.packet))
+		if d.DutyCycleWait(airtime, now) != 0 || now.Before(d.backoffUntil) {
+			return nil
+		}
+		busy, err := d.channelBusy(now)
+		if err != nil {
+			return err
+		}
+		if busy {
+			next.attempts++
+			if next.attempts < ListenAttempts {
+				return nil
+			}
+		}
+		given := d.txQueue[0]
+		d.txQueue = d.txQueue[1:]
+		if busy {
+			err = d.dropPacket(ErrChannelBusy)
+		} else {
+			err = d.transmit(given.packet, airtime, now)
+		}
+		given.finish(err)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// finish calls done, if the packet has one.
+func (q queuedPacket) finish(err error) {
+	if q.done != nil {
+		q.done(err)
+	}
+}
+
+// transmit gives a packet to the radio, and counts it against the DutyCycle if it was sent.
+func (d *Device) transmit(packet []byte, airtime time.Duration, now time.Time) (err error) {
+	err = d.SendUsingRadio(packet)
+	d.MarkDirty()
+	if d.OnSendResult != nil {
+		d.OnSendResult(packet, err)
+	}
+	if err != nil {
+		return d.dropPacket(err)
+	}
+	d.Packets.Sent++
+	d.recordAirtime(airtime, now)
+	return nil
+}
+
+// dropPacket counts a packet that could not be sent, tells the user, and returns why. If the user could not be told, that error is returned as well.
+func (d *Device) dropPacket(reason error) (err error) {
+	d.Packets.SendFailed++
+	err = d.Notify(DeviceEventSendFailed)
+	if err != nil {
+		return errors.Join(reason, err)
+	}
+	return reason
+}
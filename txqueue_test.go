@@ -0,0 +1,92 @@
+package picodoomsdaymessenger
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTxQueueOrder(t *testing.T) {
+	device, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	radio := &testRadio{busy: 1}
+	err = device.SetRadio(radio)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	sent := []string{}
+	device.SendUsingRadio = func(packet []byte) (err error) {
+		sent = append(sent, string(packet))
+		return nil
+	}
+	for _, packet := range []struct {
+		text     string
+		priority txPriority
+	}{
+		{"beacon", txPriorityBeacon},
+		{"first message", txPriorityMessage},
+		{"reply", txPriorityUrgent},
+		{"second message", txPriorityMessage},
+		{"emergency", txPriorityUrgent},
+	} {
+		err = device.sendPacketWithPriority(nil, []byte(packet.text), packet.priority, nil)
+		if err != nil {
+			t.Fatalf("The error should be nil but is %v", err)
+		}
+	}
+	now := time.Now()
+	err = device.Tick(now)
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if len(sent) != 0 || device.QueuedPackets() != 5 {
+		t.Fatalf("Every packet should wait while the channel is busy, have %v sent and %d queued", sent, device.QueuedPackets())
+	}
+	err = device.Tick(now.Add(ListenBackoff + time.Millisecond))
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	want := []string{"reply", "emergency", "first message", "second message", "beacon"}
+	if len(sent) != len(want) {
+		t.Fatalf("Every packet should be sent once the channel is clear, have %v", sent)
+	}
+	for i := range want {
+		if sent[i] != want[i] {
+			t.Errorf("The packets should be sent in the order %v, have %v", want, sent)
+			break
+		}
+	}
+}
+
+func TestTxQueueFull(t *testing.T) {
+	device, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	// Only the first packet fits in the duty cycle, so the rest wait.
+	device.Settings.DutyCycle = 0.00001
+	device.SendUsingRadio = func(packet []byte) (err error) {
+		return nil
+	}
+	for i := 0; i < MaxTxQueue+2; i++ {
+		err = device.SendPacket(nil, []byte("doom"))
+		if err != nil {
+			break
+		}
+	}
+	if err != ErrTxQueueFull || device.QueuedPackets() != MaxTxQueue {
+		t.Errorf("The queue should stop at %d packets, have %d queued and the error %v", MaxTxQueue, device.QueuedPackets(), err)
+	}
+}
+
+// sendQueued sends the packets that are waiting in the queue of each Device in turn, as their next Tick would.
+func sendQueued(devices ...*Device) (err error) {
+	for _, d := range devices {
+		sendErr := d.sendQueuedPackets(time.Now())
+		if err == nil {
+			err = sendErr
+		}
+	}
+	return err
+}
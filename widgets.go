@@ -0,0 +1,144 @@
+package picodoomsdaymessenger
+
+import "strconv"
+
+// MenuWidget is a control that a MenuItem shows a setting with. Its value is drawn at the end of the line, and it is changed in place instead of from a menu of its own.
+type MenuWidget interface {
+	// Value returns the text that the setting is shown as.
+	Value(d *Device) string
+	// Accept changes the setting when the MenuItem is accepted, if the MenuItem has no Action of its own.
+	Accept(d *Device) (err error)
+	// Adjust changes the setting by a number of steps with left and right. Negative steps go down.
+	Adjust(d *Device, steps int) (err error)
+}
+
+// Toggle is a MenuWidget that turns a setting on and off. Accept switches it, right turns it on and left turns it off.
+type Toggle struct {
+	Get func(d *Device) bool
+	Set func(d *Device, on bool) (err error)
+}
+
+// SettingToggle creates a Toggle for a bool that is changed directly, such as one of the Settings.
+func SettingToggle(setting func(d *Device) *bool) Toggle {
+	return Toggle{
+		Get: func(d *Device) bool {
+			return *setting(d)
+		},
+		Set: func(d *Device, on bool) (err error) {
+			*setting(d) = on
+			return nil
+		},
+	}
+}
+
+// Value returns "On" or "Off".
+func (t Toggle) Value(d *Device) string {
+	if t.Get(d) {
+		return "On"
+	}
+	return "Off"
+}
+
+// Accept switches the setting on if it is off, and off if it is on.
+func (t Toggle) Accept(d *Device) (err error) {
+	return t.Set(d, !t.Get(d))
+}
+
+// Adjust turns the setting on for positive steps, and off for negative ones.
+func (t Toggle) Adjust(d *Device, steps int) (err error) {
+	if steps == 0 {
+		return nil
+	}
+	return t.Set(d, steps > 0)
+}
+
+// Selector is a MenuWidget that chooses one of a list of Names. Left and right move through the list and accept moves to the next name, wrapping around at the ends.
+type Selector struct {
+	Names []string
+	// Get returns the index of the name that is chosen, or -1 if the setting is none of them.
+	Get func(d *Device) int
+	Set func(d *Device, i int) (err error)
+}
+
+// Value returns the name that is chosen, or "?" if the setting is none of the Names.
+func (s Selector) Value(d *Device) string {
+	i := s.Get(d)
+	if i < 0 || i >= len(s.Names) {
+		return "?"
+	}
+	return s.Names[i]
+}
+
+// Accept chooses the next name.
+func (s Selector) Accept(d *Device) (err error) {
+	return s.Adjust(d, 1)
+}
+
+// Adjust moves through the Names by a number of steps, wrapping around at the ends. A setting that is none of the Names moves from the start of the list.
+func (s Selector) Adjust(d *Device, steps int) (err error) {
+	if len(s.Names) == 0 {
+		return nil
+	}
+	i := s.Get(d)
+	if i < 0 {
+		i = 0
+		if steps > 0 {
+			steps--
+		}
+	}
+	i = ((i+steps)%len(s.Names) + len(s.Names)) % len(s.Names)
+	return s.Set(d, i)
+}
+
+// Slider is a MenuWidget that changes a number from Min to Max, Step at a time. Unlike a Selector, it stops at the ends instead of wrapping around.
+type Slider struct {
+	Min  int
+	Max  int
+	Step int
+	// Unit is written after the number, such as "dBm".
+	Unit string
+	Get  func(d *Device) int
+	Set  func(d *Device, value int) (err error)
+}
+
+// Value returns the number with its Unit.
+func (s Slider) Value(d *Device) string {
+	value := strconv.Itoa(s.Get(d))
+	if s.Unit == "" {
+		return value
+	}
+	return value + " " + s.Unit
+}
+
+// Accept moves the number up by one Step.
+func (s Slider) Accept(d *Device) (err error) {
+	return s.Adjust(d, 1)
+}
+
+// Adjust moves the number by a number of Steps, keeping it from Min to Max.
+func (s Slider) Adjust(d *Device, steps int) (err error) {
+	value := s.Get(d) + steps*s.Step
+	if value > s.Max {
+		value = s.Max
+	}
+	if value < s.Min {
+		value = s.Min
+	}
+	return s.Set(d, value)
+}
+
+// menuItemValue returns the text that the Widget of a MenuItem shows, or nothing if it has no Widget.
+func (d *Device) menuItemValue(item MenuItem) string {
+	if item.Widget == nil {
+		return ""
+	}
+	return item.Widget.Value(d)
+}
+
+// highlightedWidget returns the Widget of the highlighted item of a menu, or nil if the State is not a menu or the item has no Widget.
+func (d *Device) highlightedWidget() MenuWidget {
+	if d.State.Draw != nil || d.State == &d.StateConversationReader || d.State.HighlightedItemIndex >= len(d.State.Content) {
+		return nil
+	}
+	return d.State.Content[d.State.HighlightedItemIndex].Widget
+}
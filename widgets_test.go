@@ -0,0 +1,97 @@
+package picodoomsdaymessenger
+
+import (
+	"testing"
+	"time"
+)
+
+func TestToggleWidget(t *testing.T) {
+	device, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	state := State{Content: []MenuItem{SettingsMenuItemGateway}}
+	device.State = &state
+	if err = device.ProcessInputEvent(InputEventAccept); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if !device.Settings.Gateway || SettingsMenuItemGateway.Widget.Value(device) != "On" {
+		t.Errorf("expected accept to turn gateway mode on")
+	}
+	if err = device.ProcessInputEvent(InputEventLeft); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if device.Settings.Gateway {
+		t.Errorf("expected left to turn gateway mode off")
+	}
+	if _, err = GetFrame(device.Display.Bounds(), device); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+}
+
+func TestSelectorWidget(t *testing.T) {
+	device, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	device.InputTiming.Debounce = 0
+	state := State{Content: []MenuItem{SettingsMenuItemMultiTapTimeout}}
+	device.State = &state
+	device.Settings.MultiTapTimeout = MultiTapTimeouts[len(MultiTapTimeouts)-1]
+	if err = device.ProcessInputEvent(InputEventRight); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if device.Settings.MultiTapTimeout != 0 || SettingsMenuItemMultiTapTimeout.Widget.Value(device) != "Off" {
+		t.Errorf("expected right to wrap around to Off, got %v", device.Settings.MultiTapTimeout)
+	}
+	if err = device.ProcessInputEvent(InputEventLeft); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if device.Settings.MultiTapTimeout != MultiTapTimeouts[len(MultiTapTimeouts)-1] {
+		t.Errorf("expected left to wrap around to the longest timeout, got %v", device.Settings.MultiTapTimeout)
+	}
+	// A setting that is none of the choices starts again from the first.
+	device.Settings.MultiTapTimeout = 123 * time.Millisecond
+	if value := SettingsMenuItemMultiTapTimeout.Widget.Value(device); value != "?" {
+		t.Errorf("expected an unknown value to be shown as ?, got %q", value)
+	}
+	if err = device.ProcessInputEvent(InputEventAccept); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if device.Settings.MultiTapTimeout != MultiTapTimeouts[0] {
+		t.Errorf("expected accept to choose the first timeout, got %v", device.Settings.MultiTapTimeout)
+	}
+}
+
+func TestSliderWidget(t *testing.T) {
+	device, err := NewDevice()
+	if err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	device.InputTiming.Debounce = 0
+	state := State{Content: []MenuItem{RadioMenuItemTxPower}}
+	device.State = &state
+	if err = device.SetTxPower(10); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if err = device.ProcessInputEvent(InputEventRight); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if value := RadioMenuItemTxPower.Widget.Value(device); value != "11 dBm" {
+		t.Errorf("expected right to turn the power up to 11 dBm, got %q", value)
+	}
+	slider := RadioMenuItemTxPower.Widget.(Slider)
+	if err = slider.Adjust(device, -100); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if device.TxPower() != MinTxPower {
+		t.Errorf("expected the slider to stop at %d dBm, got %d", MinTxPower, device.TxPower())
+	}
+	// The TX Power item has an Action of its own, so accept still opens the full slider.
+	if err = device.ProcessInputEvent(InputEventAccept); err != nil {
+		t.Fatalf("The error should be nil but is %v", err)
+	}
+	if device.State != &device.StateTxPower {
+		t.Errorf("expected accept to open the TX power slider, got %q", device.State.Title)
+	}
+}